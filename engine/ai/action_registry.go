@@ -0,0 +1,615 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// Action errors. Decoding and transaction errors wrap these.
+var (
+	ErrActionDenied       = errors.New("action not permitted")
+	ErrActionPrecondition = errors.New("action precondition failed")
+	ErrActionParams       = errors.New("invalid action params")
+)
+
+// Permission scopes of the built-in verbs.
+const (
+	ScopeMovement = "movement" // move, set_position, set_velocity
+	ScopeState    = "state"    // set_state
+	ScopeWorld    = "world"    // remove
+)
+
+// ============================================================================
+// Parameter Schemas
+// ============================================================================
+
+// ParamSchema is the subset of JSON Schema used to describe action
+// parameters. It marshals to plain JSON Schema, so agents can read it
+// directly.
+type ParamSchema struct {
+	Type                 string                  `json:"type"` // object, number, integer, string, boolean, array
+	Description          string                  `json:"description,omitempty"`
+	Properties           map[string]*ParamSchema `json:"properties,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	AdditionalProperties *bool                   `json:"additionalProperties,omitempty"`
+	Items                *ParamSchema            `json:"items,omitempty"`
+	Enum                 []any                   `json:"enum,omitempty"`
+	Minimum              *float64                `json:"minimum,omitempty"`
+	Maximum              *float64                `json:"maximum,omitempty"`
+}
+
+// ObjectParams describes a parameter object. Unknown keys are rejected.
+func ObjectParams(props map[string]*ParamSchema, required ...string) *ParamSchema {
+	closed := false
+
+	return &ParamSchema{Type: "object", Properties: props, Required: required, AdditionalProperties: &closed}
+}
+
+// NumberParam describes a number parameter.
+func NumberParam(description string) *ParamSchema {
+	return &ParamSchema{Type: "number", Description: description}
+}
+
+// IntegerParam describes an integer parameter.
+func IntegerParam(description string) *ParamSchema {
+	return &ParamSchema{Type: "integer", Description: description}
+}
+
+// StringParam describes a string parameter, optionally limited to values.
+func StringParam(description string, values ...string) *ParamSchema {
+	s := &ParamSchema{Type: "string", Description: description}
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+
+	return s
+}
+
+// Range limits a number parameter to [lo, hi] and returns the schema.
+func (s *ParamSchema) Range(lo, hi float64) *ParamSchema {
+	s.Minimum, s.Maximum = &lo, &hi
+
+	return s
+}
+
+// Validate checks a decoded JSON value against the schema.
+func (s *ParamSchema) Validate(v any) error {
+	return s.validate("", v)
+}
+
+func (s *ParamSchema) validate(path string, v any) error {
+	at := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = path + ": " + msg
+		}
+
+		return fmt.Errorf("%w: %s", ErrActionParams, msg)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return at("expected object")
+		}
+
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return at("missing %q", name)
+			}
+		}
+
+		for _, name := range sortedKeys(obj) {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return at("unknown parameter %q", name)
+				}
+
+				continue
+			}
+
+			if err := prop.validate(paramPath(path, name), obj[name]); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return at("expected array")
+		}
+
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return at("expected %s", s.Type)
+		}
+
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return at("expected integer")
+		}
+
+		if s.Minimum != nil && n < *s.Minimum {
+			return at("%g is below %g", n, *s.Minimum)
+		}
+
+		if s.Maximum != nil && n > *s.Maximum {
+			return at("%g is above %g", n, *s.Maximum)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return at("expected string")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return at("expected boolean")
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, v) {
+		return at("%v is not one of %v", v, s.Enum)
+	}
+
+	return nil
+}
+
+func paramPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ============================================================================
+// Registry
+// ============================================================================
+
+// ActionSpec declares a verb agents can use.
+type ActionSpec struct {
+	Verb        string       `json:"verb"`
+	Description string       `json:"description"`
+	Scope       string       `json:"scope"`
+	Params      *ParamSchema `json:"params"`
+
+	// Precondition reports why the verb cannot apply to a live entity right
+	// now; nil means it can. It is checked when decoding and again when the
+	// action executes.
+	Precondition func(world *ecs.World, entity ecs.Entity) error `json:"-"`
+
+	// Build creates the action from params that passed Params.
+	Build func(entity ecs.Entity, params map[string]any) (Action, error) `json:"-"`
+}
+
+// ActionRegistry maps verbs to their specs.
+type ActionRegistry struct {
+	specs map[string]ActionSpec
+	order []string
+}
+
+// NewActionRegistry creates an empty registry.
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{specs: make(map[string]ActionSpec)}
+}
+
+// DefaultActionRegistry creates a registry with the built-in verbs.
+func DefaultActionRegistry() *ActionRegistry {
+	r := NewActionRegistry()
+	for _, spec := range builtinActionSpecs() {
+		_ = r.Register(spec)
+	}
+
+	return r
+}
+
+// Register adds a verb, replacing one with the same name.
+func (r *ActionRegistry) Register(spec ActionSpec) error {
+	if spec.Verb == "" {
+		return errors.New("action spec has no verb")
+	}
+
+	if spec.Build == nil {
+		return fmt.Errorf("action %q has no Build func", spec.Verb)
+	}
+
+	if spec.Params == nil {
+		spec.Params = ObjectParams(nil)
+	}
+
+	if _, ok := r.specs[spec.Verb]; !ok {
+		r.order = append(r.order, spec.Verb)
+	}
+
+	r.specs[spec.Verb] = spec
+
+	return nil
+}
+
+// Spec returns the spec for verb.
+func (r *ActionRegistry) Spec(verb string) (ActionSpec, bool) {
+	spec, ok := r.specs[verb]
+
+	return spec, ok
+}
+
+// Specs returns the specs the permissions allow, in registration order.
+// Nil permissions allow everything.
+func (r *ActionRegistry) Specs(perms *ActionPermissions) []ActionSpec {
+	specs := make([]ActionSpec, 0, len(r.order))
+
+	for _, verb := range r.order {
+		if spec := r.specs[verb]; perms.AllowsScope(spec.Scope) {
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs
+}
+
+// Available lists the verbs an agent may use on entity right now.
+func (r *ActionRegistry) Available(world *ecs.World, entity ecs.Entity, perms *ActionPermissions) []ActionSpec {
+	specs := make([]ActionSpec, 0, len(r.order))
+
+	if !world.Alive(entity) || !perms.AllowsEntity(entity) {
+		return specs
+	}
+
+	for _, spec := range r.Specs(perms) {
+		if spec.Precondition == nil || spec.Precondition(world, entity) == nil {
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs
+}
+
+// Bind checks permissions, params and preconditions and builds the action.
+func (r *ActionRegistry) Bind(
+	world *ecs.World, entity ecs.Entity, verb string, params map[string]any, perms *ActionPermissions,
+) (Action, error) {
+	spec, ok := r.specs[verb]
+	if !ok {
+		return nil, fmt.Errorf("unknown verb %q", verb)
+	}
+
+	if !perms.AllowsScope(spec.Scope) || !perms.AllowsEntity(entity) {
+		return nil, fmt.Errorf("%w: %s on entity %d", ErrActionDenied, verb, entity.ID())
+	}
+
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	if err := spec.Params.Validate(params); err != nil {
+		return nil, fmt.Errorf("%s: %w", verb, err)
+	}
+
+	bound := &boundAction{spec: spec, entity: entity}
+	if err := bound.Check(world); err != nil {
+		return nil, err
+	}
+
+	action, err := spec.Build(entity, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", verb, err)
+	}
+
+	bound.action = action
+
+	return bound, nil
+}
+
+// boundAction is a registry action that rechecks its precondition before
+// running, since the world may change between decoding and execution.
+type boundAction struct {
+	spec   ActionSpec
+	entity ecs.Entity
+	action Action
+}
+
+func (a *boundAction) Name() string { return a.spec.Verb }
+
+// Check reports whether the action can still run.
+func (a *boundAction) Check(world *ecs.World) error {
+	if !world.Alive(a.entity) {
+		return fmt.Errorf("%w: %s: entity %d is gone", ErrActionPrecondition, a.spec.Verb, a.entity.ID())
+	}
+
+	if a.spec.Precondition != nil {
+		if err := a.spec.Precondition(world, a.entity); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrActionPrecondition, a.spec.Verb, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *boundAction) Execute(world *ecs.World) error {
+	if err := a.Check(world); err != nil {
+		return err
+	}
+
+	return a.action.Execute(world)
+}
+
+// Unwrap returns the built action.
+func (a *boundAction) Unwrap() Action { return a.action }
+
+// ============================================================================
+// Permissions
+// ============================================================================
+
+// ActionPermissions limits what one agent may do. A nil *ActionPermissions
+// allows everything.
+type ActionPermissions struct {
+	Agent string `json:"agent,omitempty"`
+	// Scopes lists allowed verb scopes; "*" allows all.
+	Scopes []string `json:"scopes"`
+	// Entities lists the entity ids the agent may act on; empty allows all.
+	Entities []uint32 `json:"entities,omitempty"`
+}
+
+// AllowsScope reports whether verbs of scope are allowed.
+func (p *ActionPermissions) AllowsScope(scope string) bool {
+	return p == nil || slices.Contains(p.Scopes, "*") || slices.Contains(p.Scopes, scope)
+}
+
+// AllowsEntity reports whether the agent may act on entity.
+func (p *ActionPermissions) AllowsEntity(entity ecs.Entity) bool {
+	return p == nil || len(p.Entities) == 0 || slices.Contains(p.Entities, entity.ID())
+}
+
+// ============================================================================
+// JSON Requests
+// ============================================================================
+
+// ActionRequest is the wire form of an action:
+//
+//	{"verb": "move", "entity": 3, "params": {"dx": 1, "dy": 0}}
+//
+// Params may also be given inline: {"verb": "move", "entity": 3, "dx": 1}.
+type ActionRequest struct {
+	Verb   string         `json:"verb"`
+	Entity uint32         `json:"entity"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// UnmarshalJSON accepts both the nested and the inline params form.
+func (r *ActionRequest) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = ActionRequest{}
+
+	if verb, ok := raw["verb"].(string); ok {
+		r.Verb = verb
+	} else {
+		return errors.New("action has no verb")
+	}
+
+	if id, ok := raw["entity"].(float64); ok {
+		r.Entity = uint32(id)
+	}
+
+	if params, ok := raw["params"].(map[string]any); ok {
+		r.Params = params
+	}
+
+	for k, v := range raw {
+		if k == "verb" || k == "entity" || k == "params" {
+			continue
+		}
+
+		if r.Params == nil {
+			r.Params = make(map[string]any)
+		}
+
+		r.Params[k] = v
+	}
+
+	return nil
+}
+
+// DecodeActionRequests parses a JSON array of actions, or an object with an
+// "actions" array.
+func DecodeActionRequests(data []byte) ([]ActionRequest, error) {
+	var reqs []ActionRequest
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var batch struct {
+			Actions []ActionRequest `json:"actions"`
+		}
+
+		err := json.Unmarshal(trimmed, &batch)
+
+		return batch.Actions, err
+	}
+
+	err := json.Unmarshal(data, &reqs)
+
+	return reqs, err
+}
+
+// ============================================================================
+// Transactions
+// ============================================================================
+
+// Reversible is implemented by actions that can undo themselves. Capture
+// is called right before Execute and returns a function restoring the
+// state the action is about to change.
+type Reversible interface {
+	Action
+	Capture(world *ecs.World) (undo func())
+}
+
+// TransactionError reports the action that aborted a transaction.
+// Applied lists the non-reversible actions that already ran and could
+// not be rolled back.
+type TransactionError struct {
+	Index   int
+	Action  string
+	Err     error
+	Applied []int
+}
+
+func (e *TransactionError) Error() string {
+	return fmt.Sprintf("action %d (%s): %v", e.Index, e.Action, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error { return e.Err }
+
+func asReversible(action Action) (Reversible, bool) {
+	if bound, ok := action.(*boundAction); ok {
+		if _, ok := bound.action.(Reversible); !ok {
+			return nil, false
+		}
+
+		return boundReversible{bound}, true
+	}
+
+	r, ok := action.(Reversible)
+
+	return r, ok
+}
+
+type boundReversible struct{ *boundAction }
+
+func (b boundReversible) Capture(world *ecs.World) func() {
+	return b.action.(Reversible).Capture(world)
+}
+
+// captureComponent snapshots a component and returns a function restoring
+// it. Entities without the component restore to nothing.
+func captureComponent[T any](world *ecs.World, entity ecs.Entity) func() {
+	m := ecs.NewMap[T](world)
+	if !world.Alive(entity) || !m.Has(entity) {
+		return func() {}
+	}
+
+	saved := *m.Get(entity)
+
+	return func() {
+		if world.Alive(entity) && m.Has(entity) {
+			*m.Get(entity) = saved
+		}
+	}
+}
+
+// ============================================================================
+// Built-in Specs
+// ============================================================================
+
+func builtinActionSpecs() []ActionSpec {
+	hasPosition := requireComponent[components.Position]("Position")
+
+	return []ActionSpec{
+		{
+			Verb:        "move",
+			Description: "Move the entity by a delta.",
+			Scope:       ScopeMovement,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"dx": NumberParam("X delta in pixels"),
+				"dy": NumberParam("Y delta in pixels"),
+			}),
+			Precondition: hasPosition,
+			Build: func(e ecs.Entity, p map[string]any) (Action, error) {
+				return MoveAction{Entity: e, DX: paramFloat(p, "dx"), DY: paramFloat(p, "dy")}, nil
+			},
+		},
+		{
+			Verb:        "set_position",
+			Description: "Place the entity at an absolute position.",
+			Scope:       ScopeMovement,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"x": NumberParam("X in pixels"),
+				"y": NumberParam("Y in pixels"),
+			}, "x", "y"),
+			Precondition: hasPosition,
+			Build: func(e ecs.Entity, p map[string]any) (Action, error) {
+				return SetPositionAction{Entity: e, X: paramFloat(p, "x"), Y: paramFloat(p, "y")}, nil
+			},
+		},
+		{
+			Verb:        "set_velocity",
+			Description: "Set the entity's velocity.",
+			Scope:       ScopeMovement,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"vx": NumberParam("X velocity in pixels per tick"),
+				"vy": NumberParam("Y velocity in pixels per tick"),
+			}, "vx", "vy"),
+			Precondition: requireComponent[components.Velocity]("Velocity"),
+			Build: func(e ecs.Entity, p map[string]any) (Action, error) {
+				return SetVelocityAction{Entity: e, VX: paramFloat(p, "vx"), VY: paramFloat(p, "vy")}, nil
+			},
+		},
+		{
+			Verb:        "remove",
+			Description: "Remove the entity from the world.",
+			Scope:       ScopeWorld,
+			Build: func(e ecs.Entity, _ map[string]any) (Action, error) {
+				return RemoveEntityAction{Entity: e}, nil
+			},
+		},
+		{
+			Verb:        "set_state",
+			Description: "Set the entity's visual state, e.g. idle, moving, attacking.",
+			Scope:       ScopeState,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"state": StringParam("New visual state"),
+			}, "state"),
+			Precondition: requireComponent[components.AIMetadata]("AIMetadata"),
+			Build: func(e ecs.Entity, p map[string]any) (Action, error) {
+				state, _ := p["state"].(string)
+
+				return SetStateAction{Entity: e, State: state}, nil
+			},
+		},
+	}
+}
+
+// requireComponent returns a precondition that the entity has T.
+func requireComponent[T any](name string) func(*ecs.World, ecs.Entity) error {
+	return func(world *ecs.World, entity ecs.Entity) error {
+		if !ecs.NewMap[T](world).Has(entity) {
+			return fmt.Errorf("entity %d has no %s", entity.ID(), name)
+		}
+
+		return nil
+	}
+}
+
+func paramFloat(params map[string]any, name string) float64 {
+	v, _ := params[name].(float64)
+
+	return v
+}
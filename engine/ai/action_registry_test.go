@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+func TestParamSchemaValidate(t *testing.T) {
+	s := ObjectParams(map[string]*ParamSchema{
+		"speed": NumberParam("").Range(0, 10),
+		"count": IntegerParam(""),
+		"mode":  StringParam("", "walk", "run"),
+	}, "speed")
+
+	tests := []struct {
+		params map[string]any
+		want   string
+	}{
+		{map[string]any{"speed": 5.0, "count": 2.0, "mode": "run"}, ""},
+		{map[string]any{}, `missing "speed"`},
+		{map[string]any{"speed": 11.0}, "speed: 11 is above 10"},
+		{map[string]any{"speed": 1.0, "count": 1.5}, "count: expected integer"},
+		{map[string]any{"speed": 1.0, "mode": "fly"}, "mode: fly is not one of"},
+		{map[string]any{"speed": "fast"}, "speed: expected number"},
+		{map[string]any{"speed": 1.0, "extra": true}, `unknown parameter "extra"`},
+	}
+
+	for _, tt := range tests {
+		err := s.Validate(tt.params)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%v) = %v", tt.params, err)
+			}
+
+			continue
+		}
+
+		if err == nil || !errors.Is(err, ErrActionParams) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%v) = %v, want %q", tt.params, err, tt.want)
+		}
+	}
+}
+
+func TestActionRegistryAvailable(t *testing.T) {
+	world := ecs.NewWorld()
+	executor := NewActionExecutor(&world)
+
+	mover := ecs.NewMap2[components.Position, components.Velocity](&world).
+		NewEntity(&components.Position{}, &components.Velocity{})
+	label := ecs.NewMap1[components.AIMetadata](&world).NewEntity(&components.AIMetadata{EntityType: "ui"})
+
+	verbs := func(specs []ActionSpec) string {
+		names := make([]string, len(specs))
+		for i, s := range specs {
+			names[i] = s.Verb
+		}
+
+		return strings.Join(names, ",")
+	}
+
+	if got := verbs(executor.Available(mover.ID(), nil)); got != "move,set_position,set_velocity,remove" {
+		t.Errorf("mover verbs = %s", got)
+	}
+
+	if got := verbs(executor.Available(label.ID(), nil)); got != "remove,set_state" {
+		t.Errorf("label verbs = %s", got)
+	}
+
+	perms := &ActionPermissions{Scopes: []string{ScopeMovement}, Entities: []uint32{mover.ID()}}
+	if got := verbs(executor.Available(mover.ID(), perms)); got != "move,set_position,set_velocity" {
+		t.Errorf("scoped mover verbs = %s", got)
+	}
+
+	if got := executor.Available(label.ID(), perms); len(got) != 0 {
+		t.Errorf("label is outside the agent's entities, got %s", verbs(got))
+	}
+}
+
+func TestActionExecutorDecodeBatch(t *testing.T) {
+	world := ecs.NewWorld()
+	executor := NewActionExecutor(&world)
+
+	e := ecs.NewMap1[components.Position](&world).NewEntity(&components.Position{X: 1, Y: 1})
+	id := strconv.Itoa(int(e.ID()))
+
+	actions, err := executor.DecodeBatch([]byte(`[
+		{"verb": "move", "entity": `+id+`, "params": {"dx": 2}},
+		{"verb": "set_position", "entity": `+id+`, "x": 7, "y": 8}
+	]`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := executor.ExecuteBatch(actions); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if pos := ecs.NewMap[components.Position](&world).Get(e); pos.X != 7 || pos.Y != 8 {
+		t.Errorf("position = %+v, want (7, 8)", *pos)
+	}
+
+	_, err = executor.DecodeBatch([]byte(`{"actions": [
+		{"verb": "set_velocity", "entity": `+id+`, "vx": 1, "vy": 1},
+		{"verb": "set_position", "entity": `+id+`, "x": 1},
+		{"verb": "remove", "entity": `+id+`}
+	]}`), &ActionPermissions{Scopes: []string{ScopeMovement}})
+
+	for _, want := range []string{
+		"action 0: " + ErrActionPrecondition.Error() + ": set_velocity: entity " + id + " has no Velocity",
+		`action 1: set_position: invalid action params: missing "y"`,
+		"action 2: action not permitted: remove",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("DecodeBatch error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestActionExecutorTransaction(t *testing.T) {
+	world := ecs.NewWorld()
+	executor := NewActionExecutor(&world)
+
+	e := ecs.NewMap2[components.Position, components.AIMetadata](&world).
+		NewEntity(&components.Position{X: 1, Y: 1}, &components.AIMetadata{VisualState: "idle"})
+	other := ecs.NewMap1[components.Position](&world).NewEntity(&components.Position{})
+
+	bind := func(entity ecs.Entity, verb string, params map[string]any) Action {
+		action, err := executor.Registry.Bind(&world, entity, verb, params, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return action
+	}
+
+	remove := bind(other, "remove", nil)
+	move := bind(e, "move", nil)
+	// Invalidate move after binding; the transaction must notice.
+	ecs.NewMap[components.Position](&world).Remove(e)
+
+	err := executor.ExecuteTransaction([]Action{
+		SetPositionAction{Entity: other, X: 5, Y: 5},
+		remove,
+		bind(e, "set_state", map[string]any{"state": "running"}),
+		move,
+	})
+
+	var txErr *TransactionError
+	if !errors.As(err, &txErr) || txErr.Index != 3 || !errors.Is(err, ErrActionPrecondition) {
+		t.Fatalf("ExecuteTransaction = %v, want precondition failure at 3", err)
+	}
+
+	if !world.Alive(other) {
+		t.Error("deferred removal ran despite the rollback")
+	}
+
+	if pos := ecs.NewMap[components.Position](&world).Get(other); pos.X != 0 {
+		t.Errorf("position not rolled back: %+v", *pos)
+	}
+
+	if meta := ecs.NewMap[components.AIMetadata](&world).Get(e); meta.VisualState != "idle" {
+		t.Errorf("state not rolled back: %s", meta.VisualState)
+	}
+
+	if err := executor.ExecuteTransaction([]Action{remove, SetPositionAction{Entity: other, X: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if world.Alive(other) {
+		t.Error("removal should run on commit")
+	}
+}
+
+func TestAgentServerActionRegistry(t *testing.T) {
+	game, player := newAgentTestGame()
+	server := NewAgentServer(game)
+	server.Permissions = func(s *AgentSession) *ActionPermissions {
+		if s.Client == "viewer" {
+			return &ActionPermissions{Agent: s.Client, Scopes: []string{ScopeState}}
+		}
+
+		return nil
+	}
+
+	id := jsonInt(player.ID())
+	call := func(client string, requests ...string) []rpcResponse {
+		init := `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"clientInfo":{"name":"` + client + `"}}}`
+
+		return agentCall(t, server, append([]string{init}, requests...)...)[1:]
+	}
+
+	responses := call("planner",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_actions","arguments":{"entity":`+id+`}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"execute","arguments":{"atomic":true,"actions":[`+
+			`{"verb":"set_position","entity":`+id+`,"params":{"x":40,"y":40}},{"verb":"move","entity":`+id+`,"dx":"far"}]}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"execute","arguments":{"atomic":true,"actions":[`+
+			`{"verb":"set_position","entity":`+id+`,"params":{"x":40,"y":40}},{"verb":"set_state","entity":`+id+`,"state":"hiding"}]}}}`,
+	)
+
+	text, _ := toolText(t, responses[0])
+	if !strings.Contains(text, `"verb":"set_velocity"`) || !strings.Contains(text, `"required":["x","y"]`) {
+		t.Errorf("list_actions = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[1]); !strings.Contains(text, `["rolled back","error: move: invalid action params: dx: expected number"]`) {
+		t.Errorf("atomic failure = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[2]); !strings.Contains(text, `["ok","ok"]`) {
+		t.Errorf("atomic success = %s", text)
+	}
+
+	responses = call("viewer",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_actions"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"execute","arguments":{"actions":[`+
+			`{"verb":"move","entity":`+id+`,"dx":1}]}}}`,
+	)
+
+	if text, _ := toolText(t, responses[0]); strings.Contains(text, `"move"`) || !strings.Contains(text, `"set_state"`) {
+		t.Errorf("viewer list_actions = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[1]); !strings.Contains(text, "action not permitted") {
+		t.Errorf("viewer execute = %s", text)
+	}
+
+	if pos := ecs.NewMap[components.Position](&game.World).Get(player); pos.X != 40 || pos.Y != 40 {
+		t.Errorf("player at %+v, want (40, 40)", *pos)
+	}
+
+	// A failing non-reversible action cannot undo a removal that already ran.
+	_ = server.Registry().Register(ActionSpec{
+		Verb:  "jam",
+		Scope: ScopeWorld,
+		Build: func(ecs.Entity, map[string]any) (Action, error) { return jamAction{}, nil },
+	})
+
+	spare := ecs.NewMap[components.Position](&game.World).NewEntity(&components.Position{})
+
+	responses = call("planner",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"execute","arguments":{"atomic":true,"actions":[`+
+			`{"verb":"set_position","entity":`+id+`,"params":{"x":0,"y":0}},{"verb":"remove","entity":`+jsonInt(spare.ID())+`},`+
+			`{"verb":"jam","entity":`+id+`}]}}}`,
+	)
+
+	if text, _ := toolText(t, responses[0]); !strings.Contains(text, `["rolled back","applied (not reversible)","error: jammed"]`) {
+		t.Errorf("irreversible failure = %s", text)
+	}
+
+	if pos := ecs.NewMap[components.Position](&game.World).Get(player); pos.X != 40 || game.World.Alive(spare) {
+		t.Errorf("player at %+v, spare alive %v", *pos, game.World.Alive(spare))
+	}
+}
+
+type jamAction struct{}
+
+func (jamAction) Name() string { return "jam" }
+
+func (jamAction) Execute(*ecs.World) error { return errors.New("jammed") }
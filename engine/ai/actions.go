@@ -1,6 +1,9 @@
 package ai
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/mlange-42/ark/ecs"
 	"github.com/skyrocket-qy/NeuralWay/engine/components"
 )
@@ -15,17 +18,24 @@ type Action interface {
 
 // ActionExecutor handles execution of AI actions.
 type ActionExecutor struct {
+	// Registry decodes verbs for Decode and DecodeBatch. It starts as
+	// DefaultActionRegistry.
+	Registry *ActionRegistry
+
 	world  *ecs.World
 	posMap *ecs.Map[components.Position]
 	velMap *ecs.Map[components.Velocity]
+	all    *ecs.Filter0
 }
 
 // NewActionExecutor creates an action executor for the given world.
 func NewActionExecutor(world *ecs.World) *ActionExecutor {
 	return &ActionExecutor{
-		world:  world,
-		posMap: ecs.NewMap[components.Position](world),
-		velMap: ecs.NewMap[components.Velocity](world),
+		Registry: DefaultActionRegistry(),
+		world:    world,
+		posMap:   ecs.NewMap[components.Position](world),
+		velMap:   ecs.NewMap[components.Velocity](world),
+		all:      ecs.NewFilter0(world),
 	}
 }
 
@@ -47,6 +57,128 @@ func (e *ActionExecutor) ExecuteBatch(actions []Action) []error {
 	return errors
 }
 
+// ExecuteTransaction runs actions as one unit: if any fails, the changes
+// made so far are undone and a *TransactionError is returned.
+//
+// Reversible actions run in order. Other actions, such as removal, cannot
+// be undone, so they run after every reversible action succeeded; if one
+// of them fails, those that already ran are listed in Applied. Registry
+// actions have their preconditions checked before anything runs.
+func (e *ActionExecutor) ExecuteTransaction(actions []Action) error {
+	var (
+		undo     []func()
+		deferred []int
+	)
+
+	rollback := func(i int, err error, applied []int) error {
+		for j := len(undo) - 1; j >= 0; j-- {
+			undo[j]()
+		}
+
+		return &TransactionError{Index: i, Action: actions[i].Name(), Err: err, Applied: applied}
+	}
+
+	for i, action := range actions {
+		if bound, ok := action.(*boundAction); ok {
+			if err := bound.Check(e.world); err != nil {
+				return rollback(i, err, nil)
+			}
+		}
+
+		r, ok := asReversible(action)
+		if !ok {
+			deferred = append(deferred, i)
+
+			continue
+		}
+
+		undo = append(undo, r.Capture(e.world))
+
+		if err := action.Execute(e.world); err != nil {
+			return rollback(i, err, nil)
+		}
+	}
+
+	for k, i := range deferred {
+		if err := actions[i].Execute(e.world); err != nil {
+			return rollback(i, err, deferred[:k])
+		}
+	}
+
+	return nil
+}
+
+// Entity finds the live entity with the given id, as used in exported
+// state and ActionRequest.
+func (e *ActionExecutor) Entity(id uint32) (ecs.Entity, bool) {
+	query := e.all.Query()
+	for query.Next() {
+		if entity := query.Entity(); entity.ID() == id {
+			query.Close()
+
+			return entity, true
+		}
+	}
+
+	return ecs.Entity{}, false
+}
+
+// Decode builds the action for a request, checking it against the
+// registry and perms. Nil perms allow everything.
+func (e *ActionExecutor) Decode(req ActionRequest, perms *ActionPermissions) (Action, error) {
+	if _, ok := e.Registry.Spec(req.Verb); !ok {
+		return nil, fmt.Errorf("unknown verb %q", req.Verb)
+	}
+
+	entity, ok := e.Entity(req.Entity)
+	if !ok {
+		return nil, fmt.Errorf("no entity %d", req.Entity)
+	}
+
+	return e.Registry.Bind(e.world, entity, req.Verb, req.Params, perms)
+}
+
+// DecodeBatch parses a JSON batch (see DecodeActionRequests) and decodes
+// every action. The errors are joined, each prefixed with its index.
+func (e *ActionExecutor) DecodeBatch(data []byte, perms *ActionPermissions) ([]Action, error) {
+	reqs, err := DecodeActionRequests(data)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]Action, 0, len(reqs))
+
+	var errs []error
+
+	for i, req := range reqs {
+		action, err := e.Decode(req, perms)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("action %d: %w", i, err))
+
+			continue
+		}
+
+		actions = append(actions, action)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return actions, nil
+}
+
+// Available lists the verbs perms allow on the entity with the given id
+// whose preconditions currently hold.
+func (e *ActionExecutor) Available(id uint32, perms *ActionPermissions) []ActionSpec {
+	entity, ok := e.Entity(id)
+	if !ok {
+		return nil
+	}
+
+	return e.Registry.Available(e.world, entity, perms)
+}
+
 // ============================================================================
 // Built-in Actions
 // ============================================================================
@@ -59,6 +191,10 @@ type MoveAction struct {
 
 func (a MoveAction) Name() string { return "move" }
 
+func (a MoveAction) Capture(world *ecs.World) func() {
+	return captureComponent[components.Position](world, a.Entity)
+}
+
 func (a MoveAction) Execute(world *ecs.World) error {
 	posMap := ecs.NewMap[components.Position](world)
 	if !posMap.Has(a.Entity) {
@@ -80,6 +216,10 @@ type SetPositionAction struct {
 
 func (a SetPositionAction) Name() string { return "set_position" }
 
+func (a SetPositionAction) Capture(world *ecs.World) func() {
+	return captureComponent[components.Position](world, a.Entity)
+}
+
 func (a SetPositionAction) Execute(world *ecs.World) error {
 	posMap := ecs.NewMap[components.Position](world)
 	if !posMap.Has(a.Entity) {
@@ -101,6 +241,10 @@ type SetVelocityAction struct {
 
 func (a SetVelocityAction) Name() string { return "set_velocity" }
 
+func (a SetVelocityAction) Capture(world *ecs.World) func() {
+	return captureComponent[components.Velocity](world, a.Entity)
+}
+
 func (a SetVelocityAction) Execute(world *ecs.World) error {
 	velMap := ecs.NewMap[components.Velocity](world)
 	if !velMap.Has(a.Entity) {
@@ -137,6 +281,10 @@ type SetStateAction struct {
 
 func (a SetStateAction) Name() string { return "set_state" }
 
+func (a SetStateAction) Capture(world *ecs.World) func() {
+	return captureComponent[components.AIMetadata](world, a.Entity)
+}
+
 func (a SetStateAction) Execute(world *ecs.World) error {
 	metaMap := ecs.NewMap[components.AIMetadata](world)
 	if !metaMap.Has(a.Entity) {
@@ -0,0 +1,812 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/engine"
+)
+
+// AgentProtocolVersion is the MCP protocol revision the server speaks.
+const AgentProtocolVersion = "2024-11-05"
+
+// AgentServer lets external agents inspect and drive a live HeadlessGame
+// over JSON-RPC 2.0 with the Model Context Protocol tool methods
+// (initialize, tools/list, tools/call, ping). Messages are one JSON object
+// per line, on stdio or TCP.
+//
+// Every connection is a session. Several sessions can attach to the same
+// game; calls are serialized, so each tool sees a consistent world.
+// Built-in tools:
+//
+//	get_state       {"format": "json" | "compact" | "markdown" | "view" | "delta",
+//	                 "entity": 3, "radius": 200, "max_tokens": 500, "fields": ["position"]}
+//	state_schema    {}
+//	list_actions    {"entity": 3}
+//	execute         {"actions": [{"verb": "move", "entity": 3, "dx": 1, "dy": 0}, ...], "atomic": false}
+//	step            {"n": 10}
+//	query_entities  {"type": "enemy", "tag": "boss", "limit": 20}
+//	screenshot      {}
+//	list_sessions   {}
+//	task_status     {"id": 2}  (after UseTasks)
+//
+// Action verbs come from the executor's ActionRegistry (see Registry);
+// list_actions describes them with their parameter schemas. Entities are
+// the ids in exported state.
+type AgentServer struct {
+	Name    string
+	Version string
+
+	// Screenshot renders the current frame, e.g. from visual.Screenshot. The
+	// screenshot tool fails when it is nil.
+	Screenshot func() (image.Image, error)
+
+	// Fog hides what sessions cannot see in "view" and "delta" state, e.g.
+	// a game.FogOfWar. Nil shows everything.
+	Fog Visibility
+
+	// Permissions returns the action permissions of a session, e.g. by
+	// Client name. Nil, or a nil result, allows every action.
+	Permissions func(session *AgentSession) *ActionPermissions
+
+	game     *engine.HeadlessGame
+	state    *StateExporter
+	compact  *CompactExporter
+	views    *ViewExporter
+	executor *ActionExecutor
+	tags     *ecs.Map[components.Tag]
+	tasks    *TaskRunner
+
+	mu       sync.Mutex
+	tools    []AgentTool
+	sessions map[int]*AgentSession
+	nextID   int
+}
+
+// AgentTool is a tool agents can call.
+type AgentTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	// Handler returns a value sent as JSON text, a string sent as is, or
+	// an AgentContent list. It runs with the game locked.
+	Handler func(session *AgentSession, args json.RawMessage) (any, error) `json:"-"`
+}
+
+// AgentContent is one item of a tool result.
+type AgentContent struct {
+	Type     string `json:"type"` // "text" or "image"
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // Base64
+	MIMEType string `json:"mimeType,omitempty"`
+}
+
+// AgentSession is one attached agent.
+type AgentSession struct {
+	ID       int       `json:"id"`
+	Client   string    `json:"client"`
+	Attached time.Time `json:"attached"`
+	Calls    int       `json:"calls"`
+	Actions  int       `json:"actions"`
+	Steps    int       `json:"steps"`
+
+	stream *ViewStream
+}
+
+// NewAgentServer creates a server for game with the built-in tools.
+func NewAgentServer(game *engine.HeadlessGame) *AgentServer {
+	world := &game.World
+
+	s := &AgentServer{
+		Name:     "neuralway",
+		Version:  "1.0",
+		game:     game,
+		state:    NewStateExporter(world),
+		compact:  NewCompactExporter(world),
+		views:    NewViewExporter(world),
+		executor: NewActionExecutor(world),
+		tags:     ecs.NewMap[components.Tag](world),
+		sessions: make(map[int]*AgentSession),
+	}
+
+	s.registerBuiltins()
+
+	return s
+}
+
+// Exporters returns the field registry used by "view" and "delta" state.
+// Fields registered on it appear in views and in state_schema.
+func (s *AgentServer) Exporters() *ExporterRegistry {
+	return s.views.Registry
+}
+
+// Registry returns the action registry used by list_actions and execute.
+// Verbs registered on it are available to agents immediately.
+func (s *AgentServer) Registry() *ActionRegistry {
+	return s.executor.Registry
+}
+
+// UseTasks enables the multi-tick task verbs (move_to, follow, attack,
+// pick_up, use, talk) and the task_status tool. It adds runner to the
+// game loop.
+func (s *AgentServer) UseTasks(runner *TaskRunner) {
+	runner.RegisterTasks(s.executor.Registry)
+	s.game.AddSystem(runner)
+
+	s.mu.Lock()
+	s.tasks = runner
+	s.mu.Unlock()
+
+	s.AddTool(AgentTool{
+		Name: "task_status",
+		Description: "Report multi-tick tasks started by execute: status (running, succeeded, failed, canceled), " +
+			"progress 0..1 and a message. Filter by id or entity; cancel stops a running task.",
+		InputSchema: schema(map[string]any{
+			"id":     map[string]any{"type": "integer"},
+			"entity": map[string]any{"type": "integer"},
+			"cancel": map[string]any{"type": "boolean"},
+		}),
+		Handler: s.toolTaskStatus,
+	})
+}
+
+// AddTool registers a tool, replacing one with the same name.
+func (s *AgentServer) AddTool(tool AgentTool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tools = slices.DeleteFunc(s.tools, func(t AgentTool) bool { return t.Name == tool.Name })
+	s.tools = append(s.tools, tool)
+}
+
+// Sessions returns the attached sessions ordered by id.
+func (s *AgentServer) Sessions() []AgentSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessionList()
+}
+
+func (s *AgentServer) sessionList() []AgentSession {
+	out := make([]AgentSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		out = append(out, *session)
+	}
+
+	slices.SortFunc(out, func(a, b AgentSession) int { return a.ID - b.ID })
+
+	return out
+}
+
+// ============================================================================
+// Transport
+// ============================================================================
+
+// Serve runs one session until r ends.
+func (s *AgentServer) Serve(r io.Reader, w io.Writer) error {
+	session := s.attach()
+	defer s.detach(session)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		resp := s.handle(session, line)
+		if resp == nil {
+			continue // Notification
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ServeStdio serves one session on standard input and output, for agents
+// that launch the game as a subprocess.
+func (s *AgentServer) ServeStdio() error {
+	return s.Serve(os.Stdin, os.Stdout)
+}
+
+// ServeListener accepts connections and serves each as a session.
+func (s *AgentServer) ServeListener(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			_ = s.Serve(conn, conn)
+		}()
+	}
+}
+
+// ListenAndServe serves TCP sessions on addr. Use a loopback address such
+// as "127.0.0.1:7777": the protocol has no authentication.
+func (s *AgentServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	return s.ServeListener(l)
+}
+
+func (s *AgentServer) attach() *AgentSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	session := &AgentSession{ID: s.nextID, Client: "unknown", Attached: time.Now()}
+	s.sessions[session.ID] = session
+
+	return session
+}
+
+func (s *AgentServer) detach(session *AgentSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, session.ID)
+}
+
+// ============================================================================
+// JSON-RPC
+// ============================================================================
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handle answers one message, or returns nil for notifications.
+func (s *AgentServer) handle(session *AgentSession, line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}}
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{rpcInvalidRequest, "invalid request"}
+
+		return resp
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, rerr := s.dispatch(session, &req)
+	if rerr != nil {
+		resp.Error = rerr
+	} else {
+		resp.Result = result
+	}
+
+	return resp
+}
+
+func (s *AgentServer) dispatch(session *AgentSession, req *rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ClientInfo struct {
+				Name string `json:"name"`
+			} `json:"clientInfo"`
+		}
+
+		_ = json.Unmarshal(req.Params, &params)
+
+		if params.ClientInfo.Name != "" {
+			session.Client = params.ClientInfo.Name
+		}
+
+		return map[string]any{
+			"protocolVersion": AgentProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": s.Name, "version": s.Version},
+			"sessionId":       session.ID,
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		return map[string]any{"tools": s.tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+
+		i := slices.IndexFunc(s.tools, func(t AgentTool) bool { return t.Name == params.Name })
+		if i < 0 {
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+
+		session.Calls++
+
+		return s.callTool(session, s.tools[i], params.Arguments), nil
+	}
+
+	return nil, &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+}
+
+// callTool runs a tool. Tool failures are results with isError set, so
+// the agent sees them, rather than protocol errors.
+func (s *AgentServer) callTool(session *AgentSession, tool AgentTool, args json.RawMessage) map[string]any {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	out, err := tool.Handler(session, args)
+	if err != nil {
+		return map[string]any{"content": []AgentContent{{Type: "text", Text: err.Error()}}, "isError": true}
+	}
+
+	var content []AgentContent
+
+	switch v := out.(type) {
+	case []AgentContent:
+		content = v
+	case string:
+		content = []AgentContent{{Type: "text", Text: v}}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return map[string]any{"content": []AgentContent{{Type: "text", Text: err.Error()}}, "isError": true}
+		}
+
+		content = []AgentContent{{Type: "text", Text: string(data)}}
+	}
+
+	return map[string]any{"content": content, "isError": false}
+}
+
+// ============================================================================
+// Built-in Tools
+// ============================================================================
+
+func schema(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+func (s *AgentServer) registerBuiltins() {
+	s.tools = []AgentTool{
+		{
+			Name: "get_state",
+			Description: "Export the world. json: full entity snapshots; compact: token-efficient line; " +
+				"markdown: tables; view: entities keyed by id, limited to what entity sees within radius " +
+				"and max_tokens (see state_schema); delta: JSON Patch since your last delta call.",
+			InputSchema: schema(map[string]any{
+				"format": map[string]any{
+					"type": "string",
+					"enum": []string{"json", "compact", "markdown", "view", "delta"},
+				},
+				"entity":     map[string]any{"type": "integer"},
+				"radius":     map[string]any{"type": "number"},
+				"max_tokens": map[string]any{"type": "integer"},
+				"fields":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			}),
+			Handler: s.toolGetState,
+		},
+		{
+			Name:        "state_schema",
+			Description: "JSON Schema of view state, stable for a given version.",
+			InputSchema: schema(map[string]any{}),
+			Handler: func(*AgentSession, json.RawMessage) (any, error) {
+				return s.views.Registry.Schema(), nil
+			},
+		},
+		{
+			Name: "list_actions",
+			Description: "Describe action verbs with their parameter schemas and scopes. " +
+				"With entity, only verbs usable on that entity right now.",
+			InputSchema: schema(map[string]any{"entity": map[string]any{"type": "integer"}}),
+			Handler:     s.toolListActions,
+		},
+		{
+			Name: "execute",
+			Description: "Execute actions in order; see list_actions for verbs and params. " +
+				"With atomic, all actions succeed or none take effect.",
+			InputSchema: schema(map[string]any{
+				"actions": map[string]any{"type": "array", "items": schema(map[string]any{
+					"verb":   map[string]any{"type": "string"},
+					"entity": map[string]any{"type": "integer"},
+					"params": map[string]any{"type": "object"},
+				}, "verb", "entity")},
+				"atomic": map[string]any{"type": "boolean"},
+			}, "actions"),
+			Handler: s.toolExecute,
+		},
+		{
+			Name:        "step",
+			Description: "Advance the game n ticks (default 1). Affects every attached agent.",
+			InputSchema: schema(map[string]any{"n": map[string]any{"type": "integer", "minimum": 1}}),
+			Handler:     s.toolStep,
+		},
+		{
+			Name:        "query_entities",
+			Description: "List entities by AIMetadata type and/or tag (AIMetadata tags or Tag component).",
+			InputSchema: schema(map[string]any{
+				"type":  map[string]any{"type": "string"},
+				"tag":   map[string]any{"type": "string"},
+				"limit": map[string]any{"type": "integer"},
+			}),
+			Handler: s.toolQuery,
+		},
+		{
+			Name:        "screenshot",
+			Description: "Render the current frame as a PNG image.",
+			InputSchema: schema(map[string]any{}),
+			Handler:     s.toolScreenshot,
+		},
+		{
+			Name:        "list_sessions",
+			Description: "List agents attached to this game.",
+			InputSchema: schema(map[string]any{}),
+			Handler: func(*AgentSession, json.RawMessage) (any, error) {
+				return s.sessionList(), nil
+			},
+		},
+	}
+}
+
+func (s *AgentServer) toolGetState(session *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		Format    string   `json:"format"`
+		Entity    uint32   `json:"entity"`
+		Radius    float64  `json:"radius"`
+		MaxTokens int      `json:"max_tokens"`
+		Fields    []string `json:"fields"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	world, tick := &s.game.World, s.game.CurrentTick()
+
+	switch in.Format {
+	case "", "json":
+		return s.state.ExportJSON(world, tick), nil
+	case "compact":
+		return s.compact.Export(world, tick), nil
+	case "markdown":
+		return s.state.ExportMarkdown(world, tick), nil
+	case "view", "delta":
+		opts := ViewOptions{Radius: in.Radius, Fog: s.Fog, MaxTokens: in.MaxTokens, Fields: in.Fields}
+
+		if in.Entity != 0 {
+			viewer, ok := s.entity(in.Entity)
+			if !ok {
+				return nil, fmt.Errorf("no entity %d", in.Entity)
+			}
+
+			opts.Viewer = viewer
+		}
+
+		if in.Format == "view" {
+			return s.views.Export(tick, opts), nil
+		}
+
+		// A delta is relative to the session's last delta call with the
+		// same options; changing them starts over with a full view.
+		if session.stream == nil || !sameViewOptions(session.stream.Options, opts) {
+			session.stream = &ViewStream{Exporter: s.views, Options: opts}
+		}
+
+		return session.stream.Next(tick)
+	}
+
+	return nil, fmt.Errorf("unknown format %q", in.Format)
+}
+
+func sameViewOptions(a, b ViewOptions) bool {
+	return a.Viewer == b.Viewer && a.Radius == b.Radius && a.MaxTokens == b.MaxTokens &&
+		slices.Equal(a.Fields, b.Fields)
+}
+
+func (s *AgentServer) toolExecute(session *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		Actions []ActionRequest `json:"actions"`
+		Atomic  bool            `json:"atomic"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	perms := s.permissions(session)
+	results := make([]string, len(in.Actions))
+	actions := make([]Action, len(in.Actions))
+	failed := false
+
+	for i, req := range in.Actions {
+		action, err := s.executor.Decode(req, perms)
+		if err == nil && !in.Atomic {
+			err = s.executor.Execute(action)
+		}
+
+		if err != nil {
+			results[i] = "error: " + err.Error()
+			failed = true
+
+			continue
+		}
+
+		actions[i] = action
+		results[i] = actionResult(action)
+	}
+
+	if in.Atomic && !failed {
+		var txErr *TransactionError
+
+		err := s.executor.ExecuteTransaction(actions)
+		if errors.As(err, &txErr) {
+			results[txErr.Index] = "error: " + txErr.Err.Error()
+			failed = true
+
+			for _, i := range txErr.Applied {
+				results[i] = resultNotReversible
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		for i, action := range actions {
+			if results[i] == "ok" {
+				results[i] = actionResult(action)
+			}
+		}
+	}
+
+	if in.Atomic && failed {
+		for i, r := range results {
+			if strings.HasPrefix(r, "ok") {
+				results[i] = "rolled back"
+			}
+		}
+	}
+
+	for _, r := range results {
+		if strings.HasPrefix(r, "ok") || r == resultNotReversible {
+			session.Actions++
+		}
+	}
+
+	return map[string]any{"tick": s.game.CurrentTick(), "results": results}, nil
+}
+
+// resultNotReversible marks an action of a failed atomic batch that ran
+// and could not be rolled back.
+const resultNotReversible = "applied (not reversible)"
+
+// actionResult is "ok", or "ok: task N" for an action that started a task.
+func actionResult(action Action) string {
+	if bound, ok := action.(*boundAction); ok {
+		action = bound.Unwrap()
+	}
+
+	if task, ok := action.(*TaskAction); ok && task.ID > 0 {
+		return fmt.Sprintf("ok: task %d", task.ID)
+	}
+
+	return "ok"
+}
+
+func (s *AgentServer) toolTaskStatus(_ *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		ID     int    `json:"id"`
+		Entity uint32 `json:"entity"`
+		Cancel bool   `json:"cancel"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	if in.ID == 0 {
+		return s.tasks.Tasks(in.Entity), nil
+	}
+
+	if in.Cancel {
+		s.tasks.Cancel(in.ID)
+	}
+
+	progress, ok := s.tasks.Progress(in.ID)
+	if !ok {
+		return nil, fmt.Errorf("no task %d", in.ID)
+	}
+
+	return progress, nil
+}
+
+func (s *AgentServer) toolListActions(session *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		Entity *uint32 `json:"entity"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	perms := s.permissions(session)
+	if in.Entity == nil {
+		return s.executor.Registry.Specs(perms), nil
+	}
+
+	if _, ok := s.entity(*in.Entity); !ok {
+		return nil, fmt.Errorf("no entity %d", *in.Entity)
+	}
+
+	return s.executor.Available(*in.Entity, perms), nil
+}
+
+func (s *AgentServer) permissions(session *AgentSession) *ActionPermissions {
+	if s.Permissions == nil {
+		return nil
+	}
+
+	return s.Permissions(session)
+}
+
+// entity finds the live entity with the given id.
+func (s *AgentServer) entity(id uint32) (ecs.Entity, bool) {
+	return s.executor.Entity(id)
+}
+
+func (s *AgentServer) toolStep(session *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		N int `json:"n"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	if in.N <= 0 {
+		in.N = 1
+	}
+
+	s.game.StepN(in.N)
+	session.Steps += in.N
+
+	return map[string]any{"tick": s.game.CurrentTick()}, nil
+}
+
+func (s *AgentServer) toolQuery(_ *AgentSession, args json.RawMessage) (any, error) {
+	var in struct {
+		Type  string `json:"type"`
+		Tag   string `json:"tag"`
+		Limit int    `json:"limit"`
+	}
+
+	if err := json.Unmarshal(args, &in); err != nil {
+		return nil, err
+	}
+
+	snapshot := s.state.ExportWorld(&s.game.World, s.game.CurrentTick())
+	out := make([]EntitySnapshot, 0)
+
+	for _, e := range snapshot.Entities {
+		if in.Type != "" && !strings.EqualFold(e.EntityType, in.Type) {
+			continue
+		}
+
+		if in.Tag != "" && !s.hasTag(e, in.Tag) {
+			continue
+		}
+
+		out = append(out, e)
+
+		if in.Limit > 0 && len(out) == in.Limit {
+			break
+		}
+	}
+
+	return out, nil
+}
+
+// hasTag checks AIMetadata tags and the Tag component.
+func (s *AgentServer) hasTag(e EntitySnapshot, tag string) bool {
+	if slices.Contains(e.Tags, tag) {
+		return true
+	}
+
+	entity, ok := s.entity(e.ID)
+
+	return ok && s.tags.Has(entity) && s.tags.Get(entity).Name == tag
+}
+
+func (s *AgentServer) toolScreenshot(*AgentSession, json.RawMessage) (any, error) {
+	if s.Screenshot == nil {
+		return nil, errors.New("screenshots are not configured for this game")
+	}
+
+	img, err := s.Screenshot()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return []AgentContent{{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+		MIMEType: "image/png",
+	}}, nil
+}
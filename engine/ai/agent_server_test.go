@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"image"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/engine"
+)
+
+// driftSystem moves every positioned entity by its velocity.
+type driftSystem struct{}
+
+func (driftSystem) Update(world *ecs.World) {
+	query := ecs.NewFilter2[components.Position, components.Velocity](world).Query()
+	for query.Next() {
+		pos, vel := query.Get()
+		pos.X += vel.X
+		pos.Y += vel.Y
+	}
+}
+
+func newAgentTestGame() (*engine.HeadlessGame, ecs.Entity) {
+	game := engine.NewHeadlessGame()
+	game.AddSystem(driftSystem{})
+
+	actors := ecs.NewMap3[components.Position, components.Velocity, components.AIMetadata](&game.World)
+	player := actors.NewEntity(&components.Position{X: 10, Y: 10}, &components.Velocity{},
+		&components.AIMetadata{EntityType: "player", Tags: []string{"controllable"}})
+	actors.NewEntity(&components.Position{X: 50}, &components.Velocity{X: 1},
+		&components.AIMetadata{EntityType: "enemy", Tags: []string{"hostile"}})
+
+	boss := ecs.NewMap3[components.Position, components.AIMetadata, components.Tag](&game.World)
+	boss.NewEntity(&components.Position{X: 90}, &components.AIMetadata{EntityType: "enemy"}, &components.Tag{Name: "boss"})
+
+	return game, player
+}
+
+// agentCall sends requests through one session and decodes the responses.
+func agentCall(t *testing.T, server *AgentServer, requests ...string) []rpcResponse {
+	t.Helper()
+
+	var out strings.Builder
+	if err := server.Serve(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []rpcResponse
+
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var resp rpcResponse
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		responses = append(responses, resp)
+	}
+
+	return responses
+}
+
+// toolText returns the text of a tools/call result.
+func toolText(t *testing.T, resp rpcResponse) (string, bool) {
+	t.Helper()
+
+	if resp.Error != nil {
+		t.Fatalf("rpc error: %+v", resp.Error)
+	}
+
+	result := resp.Result.(map[string]any)
+	content := result["content"].([]any)[0].(map[string]any)
+	text, _ := content["text"].(string)
+
+	return text, result["isError"].(bool)
+}
+
+func TestAgentServerTools(t *testing.T) {
+	game, player := newAgentTestGame()
+	server := NewAgentServer(game)
+	server.Screenshot = func() (image.Image, error) { return image.NewRGBA(image.Rect(0, 0, 4, 4)), nil }
+
+	id := player.ID()
+	responses := agentCall(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"tester"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"execute","arguments":{"actions":[`+
+			`{"verb":"move","entity":`+jsonInt(id)+`,"dx":5},{"verb":"set_state","entity":`+jsonInt(id)+`,"state":"running"},`+
+			`{"verb":"fly","entity":`+jsonInt(id)+`},{"verb":"move","entity":999}]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"step","arguments":{"n":3}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"query_entities","arguments":{"type":"enemy"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"query_entities","arguments":{"tag":"boss"}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"get_state","arguments":{"format":"compact"}}}`,
+		`{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"get_state"}}`,
+		`{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"screenshot"}}`,
+		`{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"get_state","arguments":{"format":"xml"}}}`,
+		`{"jsonrpc":"2.0","id":11,"method":"nope"}`,
+		`not json`,
+	)
+
+	if len(responses) != 12 {
+		t.Fatalf("got %d responses, want 12 (notifications get none)", len(responses))
+	}
+
+	if info := responses[0].Result.(map[string]any); info["protocolVersion"] != AgentProtocolVersion {
+		t.Errorf("initialize = %v", info)
+	}
+
+	if tools := responses[1].Result.(map[string]any)["tools"].([]any); len(tools) != 8 {
+		t.Errorf("tools/list = %d tools", len(tools))
+	}
+
+	text, _ := toolText(t, responses[2])
+	if !strings.Contains(text, `"results":["ok","ok","error: unknown verb \"fly\"","error: no entity 999"]`) {
+		t.Errorf("execute = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[3]); text != `{"tick":3}` {
+		t.Errorf("step = %s", text)
+	}
+
+	var enemies []EntitySnapshot
+	text, _ = toolText(t, responses[4])
+	if err := json.Unmarshal([]byte(text), &enemies); err != nil || len(enemies) != 2 || enemies[0].Position[0] != 53 {
+		t.Errorf("query by type = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[5]); strings.Count(text, `"id"`) != 1 || !strings.Contains(text, `"position":[90,0]`) {
+		t.Errorf("query by tag = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[6]); !strings.HasPrefix(text, "T3|") || !strings.Contains(text, "15,10") {
+		t.Errorf("compact state = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[7]); !strings.Contains(text, `"state": "running"`) {
+		t.Errorf("json state = %s", text)
+	}
+
+	image := responses[8].Result.(map[string]any)["content"].([]any)[0].(map[string]any)
+	if image["type"] != "image" || image["mimeType"] != "image/png" || image["data"] == "" {
+		t.Errorf("screenshot = %v", image)
+	}
+
+	if _, isError := toolText(t, responses[9]); !isError {
+		t.Error("Unknown format should be a tool error")
+	}
+
+	if responses[10].Error == nil || responses[10].Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method = %+v", responses[10])
+	}
+
+	if responses[11].Error == nil || responses[11].Error.Code != rpcParseError {
+		t.Errorf("bad json = %+v", responses[11])
+	}
+}
+
+func TestAgentServerSessions(t *testing.T) {
+	game, _ := newAgentTestGame()
+	server := NewAgentServer(game)
+
+	// First agent stays attached on a pipe
+	in, feed := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error)
+
+	go func() {
+		done <- server.Serve(in, outW)
+		outW.Close()
+	}()
+
+	replies := bufio.NewScanner(outR)
+
+	io.WriteString(feed, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"player-bot"}}}`+"\n")
+	replies.Scan()
+
+	io.WriteString(feed, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"step","arguments":{"n":2}}}`+"\n")
+	replies.Scan()
+
+	// Second agent sees the first and the shared tick
+	responses := agentCall(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"qa-bot"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"list_sessions"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"step"}}`,
+	)
+
+	var sessions []AgentSession
+
+	text, _ := toolText(t, responses[1])
+	if err := json.Unmarshal([]byte(text), &sessions); err != nil || len(sessions) != 2 {
+		t.Fatalf("list_sessions = %s", text)
+	}
+
+	if sessions[0].Client != "player-bot" || sessions[0].Steps != 2 || sessions[1].Client != "qa-bot" {
+		t.Errorf("sessions = %+v", sessions)
+	}
+
+	if text, _ := toolText(t, responses[2]); text != `{"tick":3}` {
+		t.Errorf("shared step = %s", text)
+	}
+
+	feed.Close()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(server.Sessions()); n != 0 {
+		t.Errorf("%d sessions after disconnect, want 0", n)
+	}
+}
+
+func jsonInt(id uint32) string {
+	data, _ := json.Marshal(id)
+
+	return string(data)
+}
@@ -1,7 +1,14 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -394,3 +401,993 @@ func TestDifficultyBalancerReset(t *testing.T) {
 		t.Error("Difficulty level should reset to 1.0")
 	}
 }
+
+func TestResponseCurves(t *testing.T) {
+	if v := NewLinearCurve(1, 0).Evaluate(0.25); v != 0.25 {
+		t.Errorf("Linear(0.25) = %v, want 0.25", v)
+	}
+
+	if v := NewPolynomialCurve(2).Evaluate(0.5); v != 0.25 {
+		t.Errorf("Polynomial(0.5) = %v, want 0.25", v)
+	}
+
+	if v := NewLogisticCurve(10, 0.5).Evaluate(0.5); v != 0.5 {
+		t.Errorf("Logistic(midpoint) = %v, want 0.5", v)
+	}
+
+	if NewStepCurve(0.5).Evaluate(0.4) != 0 || NewStepCurve(0.5).Evaluate(0.6) != 1 {
+		t.Error("Step curve should jump at threshold")
+	}
+
+	if v := NewLinearCurve(1, 0).Inverted().Evaluate(0.25); v != 0.75 {
+		t.Errorf("Inverted linear(0.25) = %v, want 0.75", v)
+	}
+
+	if v := NewLinearCurve(2, 0).Evaluate(5); v != 1 {
+		t.Errorf("Output should clamp to 1, got %v", v)
+	}
+}
+
+func TestUtilitySelector(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+	ctx.Data["health"] = 80.0
+
+	health := func(ctx *BTContext) float64 { return ctx.Data["health"].(float64) }
+
+	var chosen string
+
+	action := func(name string, status NodeStatus) Node {
+		return NewActionNode(func(ctx *BTContext) NodeStatus {
+			chosen = name
+
+			return status
+		})
+	}
+
+	sel := NewUtilitySelector(
+		NewUtilityOption("attack", action("attack", Running),
+			NewConsideration("health", health, 0, 100, NewLinearCurve(1, 0))),
+		NewUtilityOption("heal", action("heal", Running),
+			NewConsideration("health", health, 0, 100, NewLinearCurve(1, 0).Inverted())),
+	)
+
+	if sel.Tick(ctx) != Running || chosen != "attack" {
+		t.Errorf("Healthy agent should attack, chose %q", chosen)
+	}
+
+	// Slightly below the switch point: inertia keeps the current option
+	ctx.Data["health"] = 46.0
+	sel.Tick(ctx)
+
+	if chosen != "attack" {
+		t.Errorf("Inertia should keep attacking, chose %q", chosen)
+	}
+
+	ctx.Data["health"] = 20.0
+	sel.Tick(ctx)
+
+	if chosen != "heal" || sel.Current() != "heal" {
+		t.Errorf("Wounded agent should heal, chose %q", chosen)
+	}
+
+	if sel.LastScores["heal"] <= sel.LastScores["attack"] {
+		t.Errorf("LastScores = %v, heal should score higher", sel.LastScores)
+	}
+
+	sel.Threshold = 1
+	if sel.Tick(ctx) != Failure {
+		t.Error("Selector should fail when no option beats the threshold")
+	}
+}
+
+func TestUtilitySelectorResetsInterruptedOption(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+	ctx.Data["health"] = 80.0
+
+	health := func(ctx *BTContext) float64 { return ctx.Data["health"].(float64) }
+
+	var steps []string
+
+	step := func(name string) Node {
+		return NewActionNode(func(*BTContext) NodeStatus {
+			steps = append(steps, name)
+
+			return Running
+		})
+	}
+
+	// Attack is a two-step sequence that stays Running on its second step.
+	sel := NewUtilitySelector(
+		NewUtilityOption("attack", NewSequence(NewActionNode(func(*BTContext) NodeStatus {
+			steps = append(steps, "wind_up")
+
+			return Success
+		}), step("strike")), NewConsideration("health", health, 0, 100, NewLinearCurve(1, 0))),
+		NewUtilityOption("heal", step("heal"),
+			NewConsideration("health", health, 0, 100, NewLinearCurve(1, 0).Inverted())),
+	)
+
+	sel.Tick(ctx)
+
+	ctx.Data["health"] = 10.0
+	sel.Tick(ctx)
+
+	ctx.Data["health"] = 90.0
+	sel.Tick(ctx)
+
+	if got := strings.Join(steps, ","); got != "wind_up,strike,heal,wind_up,strike" {
+		t.Errorf("steps = %s, attack should start over after healing", got)
+	}
+}
+
+func TestGOAPPlanner(t *testing.T) {
+	actions := []*GOAPAction{
+		NewGOAPAction("get_axe", nil, WorldState{"has_axe": true}, nil),
+		NewGOAPAction("chop", WorldState{"has_axe": true}, WorldState{"has_wood": true}, nil),
+		NewGOAPAction("gather", nil, WorldState{"has_wood": true}, nil),
+		NewGOAPAction("build", WorldState{"has_wood": true}, WorldState{"has_house": true}, nil),
+	}
+	actions[2].Cost = 5
+
+	plan, ok := NewGOAPPlanner().Plan(WorldState{}, WorldState{"has_house": true}, actions, nil)
+	if !ok {
+		t.Fatal("Plan should succeed")
+	}
+
+	var names []string
+	for _, a := range plan {
+		names = append(names, a.Name)
+	}
+
+	if strings.Join(names, ",") != "get_axe,chop,build" {
+		t.Errorf("Plan = %v, want cheapest get_axe,chop,build", names)
+	}
+
+	_, ok = NewGOAPPlanner().Plan(WorldState{}, WorldState{"has_castle": true}, actions, nil)
+	if ok {
+		t.Error("Unreachable goal should not produce a plan")
+	}
+
+	// "combo" meets both facts at once, so counting unmet facts overestimates
+	// the path through it and the planner would settle for "rush".
+	split := []*GOAPAction{
+		NewGOAPAction("prepare", nil, WorldState{"ready": true}, nil),
+		NewGOAPAction("combo", WorldState{"ready": true}, WorldState{"a": true, "b": true}, nil),
+		NewGOAPAction("rush", nil, WorldState{"a": true, "b": true}, nil),
+	}
+	split[2].Cost = 2.5
+
+	plan, _ = NewGOAPPlanner().Plan(WorldState{}, WorldState{"a": true, "b": true}, split, nil)
+
+	names = names[:0]
+	for _, a := range plan {
+		names = append(names, a.Name)
+	}
+
+	if strings.Join(names, ",") != "prepare,combo" {
+		t.Errorf("Plan = %v, want prepare,combo (cost 2) over rush (cost 2.5)", names)
+	}
+}
+
+func TestGOAPNode(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+
+	chopTicks := 0
+	actions := []*GOAPAction{
+		NewGOAPAction("get_axe", nil, WorldState{"has_axe": true}, &SucceedNode{}),
+		NewGOAPAction("chop", WorldState{"has_axe": true}, WorldState{"has_wood": true},
+			NewActionNode(func(ctx *BTContext) NodeStatus {
+				chopTicks++
+				if chopTicks < 3 {
+					return Running
+				}
+
+				return Success
+			})),
+	}
+
+	node := NewGOAPNode(actions, NewGOAPGoal("wood", WorldState{"has_wood": true}, 1))
+	tree := NewBehaviorTree(NewSequence(node))
+
+	if tree.Tick(ctx) != Running {
+		t.Fatal("GOAP node should be running while executing its plan")
+	}
+
+	if got := node.CurrentPlan(); len(got) != 1 || got[0] != "chop" {
+		t.Errorf("CurrentPlan = %v, want [chop]", got)
+	}
+
+	status := tree.Tick(ctx)
+	for i := 0; status == Running && i < 10; i++ {
+		status = tree.Tick(ctx)
+	}
+
+	if status != Success {
+		t.Errorf("GOAP node should succeed, got %v", status)
+	}
+
+	state := ctx.Data["goap_state"].(WorldState)
+	if state["has_wood"] != true {
+		t.Errorf("Effects should be written to the blackboard, state = %v", state)
+	}
+
+	if node.Tick(ctx) != Success {
+		t.Error("Satisfied goal should succeed immediately")
+	}
+}
+
+func TestBlackboardTeamFallback(t *testing.T) {
+	team := NewBlackboard(nil)
+	bb := NewBlackboard(team)
+
+	target := NewBBKey[int]("target")
+	target.Set(team, 7)
+
+	if v, ok := target.Get(bb); !ok || v != 7 {
+		t.Errorf("Entity blackboard should read team value, got %v %v", v, ok)
+	}
+
+	target.Set(bb, 3)
+
+	if target.GetOr(bb, 0) != 3 || target.GetOr(team, 0) != 7 {
+		t.Error("Local writes should shadow the team value without changing it")
+	}
+
+	if _, ok := NewBBKey[string]("target").Get(bb); ok {
+		t.Error("Typed get should fail on a type mismatch")
+	}
+
+	if snap := bb.Snapshot(); snap["target"] != 3 {
+		t.Errorf("Snapshot = %v, want local value", snap)
+	}
+}
+
+func TestBlackboardConditionUncomparable(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+	ctx.Data["path"] = []any{"a", "b"}
+
+	// Values decoded from data-driven trees may be slices or maps.
+	if NewBlackboardCondition("path", "==", []any{"a", "b"}).Tick(ctx) != Success {
+		t.Error("Equal slices should match")
+	}
+
+	if NewBlackboardCondition("path", "!=", map[string]any{"a": 1}).Tick(ctx) != Success {
+		t.Error("A slice should differ from a map")
+	}
+}
+
+func TestBehaviorTreeResumesRunningChild(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+
+	firstRuns, secondRuns := 0, 0
+	seq := NewSequence(
+		NewActionNode(func(ctx *BTContext) NodeStatus {
+			firstRuns++
+
+			return Success
+		}),
+		NewActionNode(func(ctx *BTContext) NodeStatus {
+			secondRuns++
+			if secondRuns < 3 {
+				return Running
+			}
+
+			return Success
+		}),
+	)
+
+	for seq.Tick(ctx) == Running {
+	}
+
+	if firstRuns != 1 || secondRuns != 3 {
+		t.Errorf("Sequence should resume the running child, first=%d second=%d", firstRuns, secondRuns)
+	}
+}
+
+func TestReactiveSelectorInterrupts(t *testing.T) {
+	ctx := NewBTContext(nil, ecs.Entity{})
+	wait := NewWait(10)
+
+	sel := NewReactiveSelector(
+		NewSequence(NewBlackboardCondition("alert", "==", true), &SucceedNode{}),
+		wait,
+	)
+
+	ctx.Advance(1)
+
+	if sel.Tick(ctx) != Running || !wait.running {
+		t.Fatal("Low priority wait should be running")
+	}
+
+	ctx.Data["alert"] = true
+	ctx.Advance(1)
+
+	if sel.Tick(ctx) != Success {
+		t.Error("High priority branch should take over")
+	}
+
+	if wait.running {
+		t.Error("Interrupted wait should be reset")
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	t.Run("cooldown", func(t *testing.T) {
+		ctx := NewBTContext(nil, ecs.Entity{})
+		cd := NewCooldown(&SucceedNode{}, 2)
+
+		if cd.Tick(ctx) != Success {
+			t.Fatal("First tick should run the child")
+		}
+
+		ctx.Advance(1)
+
+		if cd.Tick(ctx) != Failure {
+			t.Error("Cooldown should block the child")
+		}
+
+		ctx.Advance(1)
+
+		if cd.Tick(ctx) != Success {
+			t.Error("Cooldown should expire after 2 seconds")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx := NewBTContext(nil, ecs.Entity{})
+		to := NewTimeout(NewWait(5), 1)
+
+		if to.Tick(ctx) != Running {
+			t.Fatal("Timeout should run the child")
+		}
+
+		ctx.Advance(1.5)
+
+		if to.Tick(ctx) != Failure {
+			t.Error("Timeout should fail a child running too long")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		ctx := NewBTContext(nil, ecs.Entity{})
+		attempts := 0
+		retry := NewRetry(NewActionNode(func(ctx *BTContext) NodeStatus {
+			attempts++
+			if attempts < 3 {
+				return Failure
+			}
+
+			return Success
+		}), 3)
+
+		status := retry.Tick(ctx)
+		for status == Running {
+			status = retry.Tick(ctx)
+		}
+
+		if status != Success || attempts != 3 {
+			t.Errorf("Retry status=%v attempts=%d, want Success after 3", status, attempts)
+		}
+	})
+
+	t.Run("until fail", func(t *testing.T) {
+		ctx := NewBTContext(nil, ecs.Entity{})
+		n := 0
+		uf := NewUntilFail(NewActionNode(func(ctx *BTContext) NodeStatus {
+			n++
+			if n > 2 {
+				return Failure
+			}
+
+			return Success
+		}))
+
+		if uf.Tick(ctx) != Running || uf.Tick(ctx) != Running || uf.Tick(ctx) != Success {
+			t.Error("UntilFail should run until the child fails")
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		ctx := NewBTContext(nil, ecs.Entity{})
+		par := NewParallel(&SucceedNode{}, NewWait(1))
+
+		if par.Tick(ctx) != Running {
+			t.Fatal("Parallel should wait for all children")
+		}
+
+		ctx.Advance(1)
+
+		if par.Tick(ctx) != Success {
+			t.Error("Parallel should succeed when all children succeed")
+		}
+
+		if NewParallel(&SucceedNode{}, &FailNode{}).Tick(ctx) != Failure {
+			t.Error("Parallel should fail when a child fails")
+		}
+	})
+}
+
+func TestNodeRegistryBuild(t *testing.T) {
+	reg := NewNodeRegistry()
+	attacks := 0
+	reg.RegisterAction("attack", func(ctx *BTContext) NodeStatus {
+		attacks++
+
+		return Success
+	})
+
+	yamlDef := []byte(`
+name: guard
+blackboard:
+  alert: true
+root:
+  type: selector
+  children:
+    - type: sequence
+      children:
+        - type: bb_check
+          params: {key: alert, op: "==", value: true}
+        - type: cooldown
+          params: {seconds: 1}
+          children: [{type: attack}]
+    - type: wait
+`)
+
+	def, err := ParseTreeYAML(yamlDef)
+	if err != nil {
+		t.Fatalf("ParseTreeYAML: %v", err)
+	}
+
+	data, _ := json.Marshal(def)
+
+	jsonDef, err := ParseTreeJSON(data)
+	if err != nil {
+		t.Fatalf("ParseTreeJSON round trip: %v", err)
+	}
+
+	tree, err := reg.Build(jsonDef)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if tree.Name != "guard" || tree.Defaults["alert"] != true {
+		t.Errorf("Tree metadata not copied: %q %v", tree.Name, tree.Defaults)
+	}
+
+	ctx := NewBTContext(nil, ecs.Entity{})
+	ctx.Data["alert"] = true
+
+	if tree.Tick(ctx) != Success || attacks != 1 {
+		t.Errorf("Tree should attack, attacks=%d", attacks)
+	}
+
+	t.Run("errors name the bad node", func(t *testing.T) {
+		bad := &TreeDefinition{Root: &NodeDefinition{
+			Type:     "sequence",
+			Children: []*NodeDefinition{{Type: "succeed"}, {Type: "jump"}},
+		}}
+
+		err := reg.Validate(bad)
+		if err == nil || !strings.Contains(err.Error(), "root.children[1]") {
+			t.Errorf("Validate error = %v, want path to unknown node", err)
+		}
+
+		bad.Root = &NodeDefinition{Type: "inverter"}
+		if reg.Validate(bad) == nil {
+			t.Error("Decorator without child should fail")
+		}
+	})
+}
+
+func TestBehaviorTreeSystem(t *testing.T) {
+	world := ecs.NewWorld()
+	sys := NewBehaviorTreeSystem(&world)
+	reg := NewNodeRegistry()
+
+	def := &TreeDefinition{
+		Name:       "scout",
+		Blackboard: map[string]any{"seen": false},
+		Root: &NodeDefinition{Type: "selector", Children: []*NodeDefinition{
+			{Type: "bb_check", Params: NodeParams{"key": "enemy", "op": "exists"}},
+			{Type: "bb_set", Params: NodeParams{"key": "seen", "value": true}},
+		}},
+	}
+
+	mapper := ecs.NewMap[BehaviorTreeComponent](&world)
+	seen := NewBBKey[bool]("seen")
+
+	var entities []ecs.Entity
+
+	for range 2 {
+		tree, err := reg.Build(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := NewBehaviorTreeComponent(tree, "red")
+		entities = append(entities, mapper.NewEntity(&c))
+	}
+
+	sys.Update(&world, 0.1)
+
+	for _, e := range entities {
+		c := mapper.Get(e)
+		if c.Status != Success || !seen.GetOr(c.Blackboard(), false) {
+			t.Errorf("Tree should set seen, status=%v", c.Status)
+		}
+	}
+
+	sys.TeamBlackboard("red").Set("enemy", 1)
+	mapper.Get(entities[0]).Blackboard().Set("seen", false)
+	sys.Update(&world, 0.1)
+
+	if seen.GetOr(mapper.Get(entities[0]).Blackboard(), true) {
+		t.Error("Team blackboard value should be visible to members")
+	}
+}
+
+func newTestLoreDatabase() *LoreDatabase {
+	db := NewLoreDatabase()
+	db.ChunkWords = 20
+	db.ChunkOverlap = 4
+
+	db.AddEntry(LoreEntry{
+		ID: "dragon", Title: "The Ember Dragon", Category: "character", Tags: []string{"boss", "fire"},
+		Content: "An ancient dragon sleeps beneath the volcano, guarding a hoard of molten gold.",
+	})
+	db.AddEntry(LoreEntry{
+		ID: "village", Title: "Millbrook Village", Category: "location", Tags: []string{"safe"},
+		Content: "A quiet farming village by the river. Travelers rest at the inn and trade grain. " +
+			"The miller tells stories every evening. Children play near the water wheel. " +
+			"Long ago the village survived a terrible flood that destroyed the old bridge.",
+	})
+	db.AddEntry(LoreEntry{
+		ID: "sword", Title: "Frostbrand", Category: "item", Tags: []string{"weapon", "ice"},
+		Content: "A blade of everfrost ice forged to slay the ember dragon.",
+	})
+
+	return db
+}
+
+func TestChunkText(t *testing.T) {
+	text := "One two three. Four five six seven. Eight nine ten eleven twelve."
+
+	chunks := ChunkText(text, 6, 1)
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %q, want several", chunks)
+	}
+
+	if !strings.HasSuffix(chunks[0], "three.") {
+		t.Errorf("first chunk %q should end at a sentence boundary", chunks[0])
+	}
+
+	for _, c := range chunks {
+		if n := len(strings.Fields(c)); n > 6 {
+			t.Errorf("chunk %q has %d words, max 6", c, n)
+		}
+	}
+
+	if got := ChunkText("short text", 50, 5); len(got) != 1 {
+		t.Errorf("short text chunks = %q", got)
+	}
+}
+
+func TestHashingEmbedder(t *testing.T) {
+	emb := NewHashingEmbedder(256)
+
+	vecs, _ := emb.Embed([]string{"the fire dragon attacks", "a dragon of fire", "wheat fields and farms"})
+	if len(vecs[0]) != 256 {
+		t.Fatalf("dims = %d, want 256", len(vecs[0]))
+	}
+
+	if CosineSimilarity(vecs[0], vecs[1]) <= CosineSimilarity(vecs[0], vecs[2]) {
+		t.Error("Related texts should be more similar than unrelated ones")
+	}
+
+	again, _ := emb.Embed([]string{"the fire dragon attacks"})
+	if CosineSimilarity(vecs[0], again[0]) < 0.9999 {
+		t.Error("Embedding should be deterministic")
+	}
+
+	idx := NewVectorIndex(emb)
+	if err := idx.Add(&IndexedChunk{DocID: "a", Vector: vecs[0]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := idx.Add(&IndexedChunk{DocID: "b", Vector: vecs[1][:64]}); err == nil || idx.Len() != 1 {
+		t.Errorf("Mismatched dimensions should be rejected, got %v with %d chunks", err, idx.Len())
+	}
+}
+
+func TestLoreDatabaseSearch(t *testing.T) {
+	db := newTestLoreDatabase()
+
+	results := db.Query("dragon volcano", 2)
+	if len(results) == 0 || results[0].ID != "dragon" {
+		t.Fatalf("Top result should be the dragon, got %v", results)
+	}
+
+	if results[0].Relevance <= 0 {
+		t.Error("Query should set Relevance")
+	}
+
+	t.Run("long content matches the relevant chunk", func(t *testing.T) {
+		res := db.Search("flood destroyed the bridge", LoreSearchOptions{Limit: 1})
+		if len(res) == 0 || res[0].Entry.ID != "village" || !strings.Contains(res[0].Chunk, "flood") {
+			t.Errorf("Search = %+v", res)
+		}
+	})
+
+	t.Run("category and tag filters", func(t *testing.T) {
+		res := db.Search("dragon", LoreSearchOptions{Category: "item"})
+		if len(res) != 1 || res[0].Entry.ID != "sword" {
+			t.Errorf("Category filter = %+v", res)
+		}
+
+		res = db.Search("dragon", LoreSearchOptions{Tags: []string{"BOSS"}})
+		if len(res) != 1 || res[0].Entry.ID != "dragon" {
+			t.Errorf("Tag filter = %+v", res)
+		}
+	})
+
+	t.Run("replacing an entry does not duplicate it", func(t *testing.T) {
+		db.AddEntry(LoreEntry{ID: "sword", Title: "Frostbrand", Category: "item", Content: "Now a rusty blade."})
+
+		if n := len(db.QueryByCategory("item")); n != 1 {
+			t.Errorf("item entries = %d, want 1", n)
+		}
+
+		if len(db.QueryByTag("ice")) != 0 {
+			t.Error("Old tags should be removed")
+		}
+	})
+}
+
+func TestLoreDatabaseSaveLoad(t *testing.T) {
+	db := newTestLoreDatabase()
+	path := t.TempDir() + "/lore.json"
+
+	if err := db.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewLoreDatabase()
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Count() != 3 || loaded.vectors.Len() != db.vectors.Len() {
+		t.Fatalf("Loaded %d entries, %d chunks", loaded.Count(), loaded.vectors.Len())
+	}
+
+	want := db.Search("river inn", LoreSearchOptions{Limit: 1})
+	got := loaded.Search("river inn", LoreSearchOptions{Limit: 1})
+
+	if len(got) != 1 || got[0].Entry.ID != want[0].Entry.ID || got[0].Score != want[0].Score {
+		t.Errorf("Loaded search = %+v, want %+v", got, want)
+	}
+
+	t.Run("different embedder re-embeds", func(t *testing.T) {
+		other := NewLoreDatabase()
+		if err := other.SetEmbedder(NewHashingEmbedder(64)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := other.Load(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if other.vectors.Dims != 64 || other.vectors.Len() == 0 {
+			t.Errorf("index dims = %d, chunks = %d", other.vectors.Dims, other.vectors.Len())
+		}
+	})
+}
+
+func TestLoreDatabaseSwitchEmbedder(t *testing.T) {
+	db := NewLoreDatabase()
+	db.AddEntry(LoreEntry{ID: "inn", Title: "The Inn", Content: "A warm inn by the river."})
+
+	supplied := make([]float32, 512)
+	supplied[0] = 1
+	db.AddEntry(LoreEntry{ID: "tower", Title: "Tower", Content: "A ruined tower.", Embedding: supplied})
+
+	if db.entries["inn"].Embedding != nil {
+		t.Error("computed vector was stored as the entry's Embedding")
+	}
+
+	// Same dimensions, different model: computed vectors must be redone,
+	// supplied ones kept.
+	unigrams := &HashingEmbedder{Dims: 512}
+	if err := db.SetEmbedder(unigrams); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := unigrams.Embed([]string{"The Inn. A warm inn by the river."})
+
+	for _, c := range db.vectors.Chunks {
+		switch c.DocID {
+		case "inn":
+			if !slices.Equal(c.Vector, want[0]) {
+				t.Error("inn kept the old embedder's vector")
+			}
+		case "tower":
+			if !slices.Equal(c.Vector, supplied) {
+				t.Error("tower lost its supplied embedding")
+			}
+		}
+	}
+}
+
+func TestSceneSpecRoundTrip(t *testing.T) {
+	spec := NewSceneGenerator().ParsePrompt("A platformer with a player, enemies and coins")
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("ParsePrompt spec should validate: %v", err)
+	}
+
+	want, _ := spec.JSON()
+
+	for _, ext := range []string{".json", ".yaml"} {
+		path := filepath.Join(t.TempDir(), "scene"+ext)
+		if err := spec.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadSceneSpecFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := loaded.JSON(); string(got) != string(want) {
+			t.Errorf("%s round trip changed the spec:\n%s\nwant\n%s", ext, got, want)
+		}
+	}
+
+	legacy, err := ParseSceneSpecJSON([]byte(`{"name": "Old", "width": 10, "height": 10, "entities": []}`))
+	if err != nil || legacy.Version != SceneSpecVersion {
+		t.Errorf("Unversioned spec should upgrade, got %+v, %v", legacy, err)
+	}
+
+	if _, err := ParseSceneSpecJSON([]byte(`{"version": 99, "name": "Future"}`)); err == nil {
+		t.Error("Newer versions should be rejected")
+	}
+}
+
+func TestSceneSpecValidate(t *testing.T) {
+	spec, err := ParseSceneSpecYAML([]byte(`
+name: Broken
+width: 100
+height: 0
+entities:
+  - name: Hero
+    type: player
+    sprite: missing
+    components:
+      - {type: jetpack}
+      - {type: velocity, params: {z: 1}}
+  - name: Hero
+systems: [movement, teleport]
+input:
+  - {action: jump, keys: [NotAKey]}
+win:
+  - {type: tag_count, op: "~", value: 0}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = spec.Validate()
+	if err == nil {
+		t.Fatal("Validate should fail")
+	}
+
+	for _, want := range []string{
+		"size:",
+		`entities[0]: unknown image asset "missing"`,
+		`entities[0].components[0]: unknown component type "jetpack"`,
+		`entities[0].components[1]: unknown velocity param "z"`,
+		`entities[1]: duplicate entity "Hero"`,
+		`systems[1]: unknown system "teleport"`,
+		`input[0]: unknown key "NotAKey"`,
+		`win[0]: unknown operator "~"`,
+		"win[0]: tag_count condition needs a tag",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validation errors should contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestSceneSpecSchema(t *testing.T) {
+	schema := SceneSpecSchema()
+	field := func(v any, keys ...string) any {
+		for _, k := range keys {
+			v = v.(map[string]any)[k]
+		}
+
+		return v
+	}
+
+	types := field(map[string]any(schema), "properties", "entities", "items", "properties",
+		"components", "items", "properties", "type", "enum").([]any)
+
+	if len(types) != len(SceneComponentTypes()) {
+		t.Errorf("Component type enum = %v", types)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil || !strings.Contains(string(data), "player_control") {
+		t.Errorf("Schema should list systems: %s, %v", data, err)
+	}
+}
+
+func TestSceneLoader(t *testing.T) {
+	spec, err := ParseSceneSpecYAML([]byte(`
+version: 1
+name: Coin Run
+width: 320
+height: 240
+background: "#102030"
+entities:
+  - name: Player
+    type: player
+    position: [10, 20]
+    components:
+      - {type: velocity, params: {x: 2}}
+      - {type: health, params: {max: 3}}
+      - {type: sprite, params: {color: "#00ff00", width: 4, height: 4}}
+  - name: Coin
+    type: coin
+    position: [100, 20]
+systems: [movement, render]
+input:
+  - {action: move_left, keys: [ArrowLeft, A]}
+win:
+  - {type: tag_count, tag: coin, op: "==", value: 0}
+lose:
+  - {type: ticks, op: ">=", value: 100}
+  - {type: health, tag: player, op: "<=", value: 0}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world := ecs.NewWorld()
+
+	inst, err := NewSceneLoader(nil).Instantiate(&world, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inst.Systems) != 1 || len(inst.DrawSystems) != 1 {
+		t.Errorf("Systems = %d update, %d draw", len(inst.Systems), len(inst.DrawSystems))
+	}
+
+	player := inst.Entities["Player"]
+	sprite := ecs.NewMap[components.Sprite](&world).Get(player)
+
+	if sprite == nil || sprite.Image.Bounds().Dx() != 4 {
+		t.Error("Player should get a 4px placeholder sprite")
+	}
+
+	if tag := ecs.NewMap[components.Tag](&world).Get(player); tag.Name != "player" {
+		t.Errorf("Player tag = %q", tag.Name)
+	}
+
+	inst.Update()
+
+	if pos := ecs.NewMap[components.Position](&world).Get(player); pos.X != 12 {
+		t.Errorf("Movement system should move the player to x=12, got %v", pos.X)
+	}
+
+	if inst.Outcome != "" {
+		t.Errorf("Outcome = %q before any condition holds", inst.Outcome)
+	}
+
+	world.RemoveEntity(inst.Entities["Coin"])
+	inst.Update()
+
+	if inst.Outcome != "win" {
+		t.Errorf("Outcome = %q after collecting the coin, want win", inst.Outcome)
+	}
+
+	ecs.NewMap[components.Health](&world).Get(player).Current = 0
+
+	if got := inst.Evaluate(); got != "lose" {
+		t.Errorf("Evaluate = %q with a dead player, want lose", got)
+	}
+
+	scene := NewSpecScene(NewSceneLoader(nil), spec)
+	if err := scene.Load(); err != nil || scene.Instance == nil {
+		t.Fatalf("SpecScene.Load: %v", err)
+	}
+
+	if err := scene.Update(); err != nil || scene.Instance.Ticks != 1 {
+		t.Errorf("SpecScene.Update: %v, ticks %d", err, scene.Instance.Ticks)
+	}
+}
+
+func TestSceneGeneratorSource(t *testing.T) {
+	gen := NewSceneGenerator()
+	spec := gen.ParsePrompt("player collects coins while avoiding enemies")
+
+	src, err := gen.GenerateSource(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0); err != nil {
+		t.Fatalf("Generated code should parse: %v\n%s", err, src)
+	}
+
+	// Type-check it against this module where the go command is available.
+	if goTool, err := exec.LookPath("go"); err == nil && !testing.Short() {
+		path := filepath.Join(t.TempDir(), "main.go")
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if out, err := exec.Command(goTool, "vet", path).CombinedOutput(); err != nil {
+			t.Fatalf("Generated code should compile: %v\n%s\n%s", err, out, src)
+		}
+	}
+
+	for _, want := range []string{
+		"archetypes.NewArchetype4[components.Position, components.Tag, components.Sprite, components.Collider](w)",
+		`input.BindKey("move_left", ebiten.KeyArrowLeft)`,
+		"game.AddSystem(newPlayerControl(w, input))",
+		"game.AddDrawSystem(systems.NewRenderSystem(w))",
+		`if countTag(w, "coin") == 0 {`,
+		`if minHealth(w, "player") <= 0 {`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Generated code should contain %q", want)
+		}
+	}
+
+	bad := spec
+	bad.Systems = []string{"teleport"}
+
+	if _, err := gen.GenerateSource(bad); err == nil {
+		t.Error("Invalid specs should not generate code")
+	}
+
+	if code := gen.GenerateCode(bad); !strings.HasPrefix(code, "// Code generation failed") {
+		t.Errorf("GenerateCode should explain failures, got %q", code)
+	}
+}
+
+func TestSceneGeneratorLLM(t *testing.T) {
+	calls := 0
+	fake := NewFakeLLM()
+	fake.Handler = func(req ChatRequest) (*ChatResponse, error) {
+		calls++
+
+		reply := `{"version": 1, "name": "Arena", "width": 320, "height": 240,
+			"entities": [{"name": "Hero", "type": "player", "position": [1, 2]}], "systems": ["warp"]}`
+		if calls > 1 {
+			if !strings.Contains(req.Messages[len(req.Messages)-1].Content, `unknown system "warp"`) {
+				t.Error("Repair request should include the validation error")
+			}
+
+			reply = strings.Replace(reply, "warp", "movement", 1)
+		}
+
+		return &ChatResponse{Message: ChatMessage{Role: RoleAssistant, Content: reply}}, nil
+	}
+
+	gen := NewSceneGenerator()
+	gen.Client = fake
+
+	spec, err := gen.GenerateSpec(context.Background(), "an arena")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 || spec.Name != "Arena" || spec.Systems[0] != "movement" {
+		t.Errorf("calls = %d, spec = %+v", calls, spec)
+	}
+
+	if fake.Requests()[0].Schema == nil {
+		t.Error("GenerateSpec should request structured output")
+	}
+}
@@ -0,0 +1,772 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Decode JPEG replies from image APIs
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+	"github.com/skyrocket-qy/NeuralWay/engine/graphics"
+	"github.com/skyrocket-qy/NeuralWay/engine/tools"
+)
+
+// ============================================================================
+// Backends
+// ============================================================================
+
+// SpriteRequest asks a backend for a sprite sheet: Frames frames of
+// Width x Height in one row.
+type SpriteRequest struct {
+	Name   string
+	Spec   SpritePrompt
+	Prompt string // From AssetGenerator.GenerateSpritePrompt
+	Width  int
+	Height int
+	Frames int
+	Seed   int64
+}
+
+// SFXRequest asks a backend for a sound effect.
+type SFXRequest struct {
+	Name     string
+	Spec     SFXPrompt
+	Prompt   string // From AssetGenerator.GenerateSFXPrompt
+	Duration float64
+	Seed     int64
+}
+
+// AssetBackend turns prompts into images and encoded audio (WAV, OGG or
+// MP3 bytes).
+type AssetBackend interface {
+	Name() string
+	GenerateSprite(ctx context.Context, req SpriteRequest) (image.Image, error)
+	GenerateSFX(ctx context.Context, req SFXRequest) ([]byte, error)
+}
+
+// ============================================================================
+// Procedural Backend
+// ============================================================================
+
+// ProceduralBackend synthesizes placeholder assets offline: mirrored pixel
+// creatures on the magenta chroma key and sfxr-style WAV effects, both
+// seeded so the same request always gives the same asset.
+type ProceduralBackend struct {
+	SampleRate int // Default assets.DefaultSampleRate
+}
+
+// NewProceduralBackend creates an offline backend.
+func NewProceduralBackend() *ProceduralBackend {
+	return &ProceduralBackend{SampleRate: assets.DefaultSampleRate}
+}
+
+func (b *ProceduralBackend) Name() string { return "procedural" }
+
+// spriteGrid is the logical resolution of procedural sprites.
+const spriteGrid = 16
+
+// chromaMagenta is the primary key from docs/standards/chroma-key.md.
+var chromaMagenta = color.RGBA{255, 0, 255, 255}
+
+// GenerateSprite implements AssetBackend.
+func (b *ProceduralBackend) GenerateSprite(_ context.Context, req SpriteRequest) (image.Image, error) {
+	rng := rand.New(rand.NewSource(req.Seed))
+	frames := max(req.Frames, 1)
+
+	// Fill the left half of the grid, denser toward the spine, then mirror
+	// it and outline the result.
+	var cells [spriteGrid][spriteGrid]uint8
+
+	for y := 2; y < spriteGrid-2; y++ {
+		for x := 1; x < spriteGrid/2; x++ {
+			p := 0.85 - 0.08*float64(spriteGrid/2-x) - 0.03*math.Abs(float64(y-spriteGrid/2))
+			if x == spriteGrid/2-1 && y > 3 && y < spriteGrid-4 || rng.Float64() < p {
+				cells[y][x], cells[y][spriteGrid-1-x] = 1, 1
+			}
+		}
+	}
+
+	for y := range spriteGrid {
+		for x := range spriteGrid {
+			if cells[y][x] != 0 {
+				continue
+			}
+
+			for _, d := range [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx >= 0 && ny >= 0 && nx < spriteGrid && ny < spriteGrid && cells[ny][nx] == 1 {
+					cells[y][x] = 2
+
+					break
+				}
+			}
+		}
+	}
+
+	hue := rng.Float64()
+	palette := [4]color.RGBA{
+		hsvColor(hue, 0.5, 0.2),                    // Outline
+		hsvColor(hue, 0.45, 1),                     // Highlight
+		hsvColor(hue, 0.65, 0.85),                  // Body
+		hsvColor(math.Mod(hue+0.05, 1), 0.75, 0.6), // Shade
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, req.Width*frames, req.Height))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(chromaMagenta), image.Point{}, draw.Src)
+
+	scale := max(min(req.Width, req.Height)/spriteGrid, 1)
+	offX, offY := (req.Width-spriteGrid*scale)/2, (req.Height-spriteGrid*scale)/2
+
+	for f := range frames {
+		dx, dy, cut, tint := spritePose(req.Spec.Animation, f, frames)
+
+		for y := cut; y < spriteGrid; y++ {
+			for x := range spriteGrid {
+				if cells[y][x] == 0 {
+					continue
+				}
+
+				c := palette[0]
+				if cells[y][x] == 1 {
+					c = palette[1+min(3*y/spriteGrid, 2)]
+				}
+
+				if tint {
+					c = color.RGBA{uint8(min(int(c.R)+120, 255)), c.G / 2, c.B / 2, 255}
+				}
+
+				px, py := f*req.Width+offX+(x+dx)*scale, offY+(y+dy)*scale
+				rect := image.Rect(px, py, px+scale, py+scale).Intersect(image.Rect(f*req.Width, 0, (f+1)*req.Width, req.Height))
+				draw.Draw(sheet, rect, image.NewUniform(c), image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// spritePose offsets frame f of an animation in grid cells; cut hides
+// rows from the top and tint flashes the sprite.
+func spritePose(animation string, f, frames int) (dx, dy, cut int, tint bool) {
+	switch animation {
+	case "idle", "jump", "fall":
+		dy = f % 2
+	case "walk", "run":
+		dy = f % 2
+		dx = []int{0, 1, 0, -1}[f%4]
+	case "attack":
+		dx = min(f, 1)
+	case "hurt":
+		tint = f%2 == 0
+	case "death":
+		cut = f * spriteGrid / (frames + 1)
+		dy = cut / 2
+	}
+
+	return dx, dy, cut, tint
+}
+
+func hsvColor(h, s, v float64) color.RGBA {
+	i := math.Floor(h * 6)
+	f := h*6 - i
+	p, q, t := v*(1-s), v*(1-f*s), v*(1-(1-f)*s)
+
+	var r, g, b float64
+
+	switch int(i) % 6 {
+	case 0:
+		r, g, b = v, t, p
+	case 1:
+		r, g, b = q, v, p
+	case 2:
+		r, g, b = p, v, t
+	case 3:
+		r, g, b = p, q, v
+	case 4:
+		r, g, b = t, p, v
+	default:
+		r, g, b = v, p, q
+	}
+
+	return color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 255}
+}
+
+// GenerateSFX implements AssetBackend with an assets.NewSFXPreset sound
+// picked from keywords in the description, stretched to Duration.
+func (b *ProceduralBackend) GenerateSFX(_ context.Context, req SFXRequest) ([]byte, error) {
+	desc := strings.ToLower(req.Spec.Description)
+	preset := assets.PresetPickup
+
+	switch {
+	case containsAny(desc, "laser", "shoot", "zap", "shot"):
+		preset = assets.PresetLaser
+	case containsAny(desc, "explosion", "explode", "boom"):
+		preset = assets.PresetExplosion
+	case containsAny(desc, "hit", "hurt", "damage", "punch"):
+		preset = assets.PresetHit
+	case containsAny(desc, "jump", "bounce"):
+		preset = assets.PresetJump
+	}
+
+	p, err := assets.NewSFXPreset(preset, req.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	if d := p.Duration(); req.Duration > 0 && d > 0 {
+		k := req.Duration / d
+		p.Attack, p.Sustain, p.Decay = p.Attack*k, p.Sustain*k, p.Decay*k
+		p.Slide, p.DeltaSlide, p.ArpTime = p.Slide/k, p.DeltaSlide/(k*k), p.ArpTime*k
+	}
+
+	p.Volume = map[string]float64{"quiet": 0.3, "loud": 0.8}[req.Spec.Intensity]
+	if p.Volume == 0 {
+		p.Volume = 0.5
+	}
+
+	rate := b.SampleRate
+	if rate <= 0 {
+		rate = assets.DefaultSampleRate
+	}
+
+	var buf bytes.Buffer
+	if err := assets.WriteWAV(&buf, p.PCM(rate), rate); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func containsAny(s string, words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ============================================================================
+// HTTP Backend
+// ============================================================================
+
+// HTTPAssetBackend calls image and sound generation APIs. Images use the
+// OpenAI images API shape (prompt in, b64_json or raw image out); sounds
+// POST {"text", "duration_seconds"} and expect audio bytes, as
+// ElevenLabs-style sound generation endpoints do.
+type HTTPAssetBackend struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	ImagePath  string // Default "/images/generations"
+	SoundPath  string // Default "/sound-generation"
+	HTTPClient *http.Client
+	Headers    map[string]string
+}
+
+// NewHTTPAssetBackend creates a backend for baseURL.
+func NewHTTPAssetBackend(baseURL, apiKey, model string) *HTTPAssetBackend {
+	return &HTTPAssetBackend{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		Model:      model,
+		ImagePath:  "/images/generations",
+		SoundPath:  "/sound-generation",
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (b *HTTPAssetBackend) Name() string { return "http" }
+
+// GenerateSprite implements AssetBackend.
+func (b *HTTPAssetBackend) GenerateSprite(ctx context.Context, req SpriteRequest) (image.Image, error) {
+	prompt := req.Prompt
+	if req.Frames > 1 {
+		prompt += fmt.Sprintf(", %d animation frames side by side in one row", req.Frames)
+	}
+
+	body := map[string]any{"prompt": prompt, "n": 1, "response_format": "b64_json", "seed": req.Seed}
+	if b.Model != "" {
+		body["model"] = b.Model
+	}
+
+	data, contentType, err := b.post(ctx, b.ImagePath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(contentType, "json") {
+		var wire struct {
+			Data []struct {
+				B64JSON string `json:"b64_json"`
+			} `json:"data"`
+		}
+
+		if err := json.Unmarshal(data, &wire); err != nil || len(wire.Data) == 0 {
+			return nil, fmt.Errorf("asset: image response has no data: %.200s", data)
+		}
+
+		if data, err = base64.StdEncoding.DecodeString(wire.Data[0].B64JSON); err != nil {
+			return nil, fmt.Errorf("asset: decode b64_json: %w", err)
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("asset: decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+// GenerateSFX implements AssetBackend.
+func (b *HTTPAssetBackend) GenerateSFX(ctx context.Context, req SFXRequest) ([]byte, error) {
+	body := map[string]any{"text": req.Prompt}
+	if req.Duration > 0 {
+		body["duration_seconds"] = req.Duration
+	}
+
+	if b.Model != "" {
+		body["model_id"] = b.Model
+	}
+
+	data, _, err := b.post(ctx, b.SoundPath, body)
+
+	return data, err
+}
+
+func (b *HTTPAssetBackend) post(ctx context.Context, path string, body map[string]any) ([]byte, string, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if b.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.APIKey)
+	}
+
+	for k, v := range b.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("asset: %w", err)
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, "", &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data[:min(len(data), 4096)]))}
+	}
+
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// ============================================================================
+// Pipeline
+// ============================================================================
+
+// AssetMetadata is the sidecar format of docs/standards/metadata.md.
+type AssetMetadata struct {
+	Type       string                    `json:"type"`
+	Tags       []string                  `json:"tags,omitempty"`
+	Origin     [2]int                    `json:"origin"`
+	Hitbox     *AssetHitbox              `json:"hitbox,omitempty"`
+	Animations map[string]AssetAnimation `json:"animations,omitempty"`
+}
+
+// AssetHitbox is a collision rectangle in frame pixels.
+type AssetHitbox struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// AssetAnimation lists frame indices of a sheet.
+type AssetAnimation struct {
+	Frames []int `json:"frames"`
+	FPS    int   `json:"fps"`
+	Loop   bool  `json:"loop"`
+}
+
+// GeneratedSprite records one sprite written by the pipeline. Paths are
+// relative to OutDir.
+type GeneratedSprite struct {
+	Name     string   `json:"name"`
+	Prompt   string   `json:"prompt"`
+	Sheet    string   `json:"sheet"`
+	Metadata string   `json:"metadata"`
+	Frames   []string `json:"frames"`
+}
+
+// GeneratedSound records one sound written by the pipeline.
+type GeneratedSound struct {
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+	File   string `json:"file"`
+}
+
+// AssetManifest lists everything a pipeline run wrote; it is saved as
+// manifest.json.
+type AssetManifest struct {
+	Backend string            `json:"backend"`
+	Sprites []GeneratedSprite `json:"sprites"`
+	Sounds  []GeneratedSound  `json:"sounds"`
+	Atlas   string            `json:"atlas,omitempty"`
+}
+
+// AssetPipeline turns prompt lists into files under OutDir:
+//
+//	sprites/<name>.png    sheet, chroma keyed to transparency
+//	sprites/<name>.json   metadata sidecar
+//	frames/<name>_01.png  sliced frames of this run, packed into <AtlasName>.png/.json
+//	sfx/<name>.wav        sound effects
+//	manifest.json
+//
+// Names follow docs/standards/naming.md, e.g. "knight_walk_right".
+type AssetPipeline struct {
+	Backend   AssetBackend
+	Generator *AssetGenerator // Builds prompts
+	OutDir    string
+	Seed      int64
+	Atlas     *tools.AtlasPacker // Nil skips packing
+	AtlasName string             // Default "atlas"
+	FPS       int                // Default 8
+	// Frames overrides the frame count per animation name.
+	Frames map[string]int
+}
+
+// NewAssetPipeline creates a pipeline writing to outDir with a 1024x1024
+// atlas.
+func NewAssetPipeline(backend AssetBackend, outDir string) *AssetPipeline {
+	return &AssetPipeline{
+		Backend:   backend,
+		Generator: NewAssetGenerator(StylePixelArt),
+		OutDir:    outDir,
+		Atlas:     tools.NewAtlasPacker(1024, 1024, 1),
+		AtlasName: "atlas",
+		FPS:       8,
+	}
+}
+
+// defaultFrames is the frame count of each standard animation state.
+var defaultFrames = map[string]int{
+	"idle": 2, "walk": 4, "run": 4, "attack": 3, "hurt": 2, "death": 4, "jump": 2, "fall": 2,
+}
+
+// Run generates every sprite and sound, then packs the atlas.
+func (p *AssetPipeline) Run(ctx context.Context, sprites []SpritePrompt, sounds []SFXPrompt) (*AssetManifest, error) {
+	manifest := &AssetManifest{Backend: p.Backend.Name()}
+
+	// The atlas packs the whole frames directory, so drop earlier runs.
+	if err := os.RemoveAll(filepath.Join(p.OutDir, "frames")); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{"sprites", "frames", "sfx"} {
+		if err := os.MkdirAll(filepath.Join(p.OutDir, dir), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+
+	for _, spec := range sprites {
+		for v := range max(spec.Variations, 1) {
+			name := uniqueAssetName(seen, SpriteAssetName(spec), v)
+
+			sprite, err := p.sprite(ctx, name, spec)
+			if err != nil {
+				return nil, fmt.Errorf("sprite %s: %w", name, err)
+			}
+
+			manifest.Sprites = append(manifest.Sprites, sprite)
+		}
+	}
+
+	for _, spec := range sounds {
+		name := uniqueAssetName(seen, assetSlug(spec.Description), 0)
+
+		sound, err := p.sound(ctx, name, spec)
+		if err != nil {
+			return nil, fmt.Errorf("sfx %s: %w", name, err)
+		}
+
+		manifest.Sounds = append(manifest.Sounds, sound)
+	}
+
+	if p.Atlas != nil && len(manifest.Sprites) > 0 {
+		name := p.AtlasName
+		if name == "" {
+			name = "atlas"
+		}
+
+		if err := p.Atlas.PackDirectory(filepath.Join(p.OutDir, "frames"), filepath.Join(p.OutDir, name)); err != nil {
+			return nil, fmt.Errorf("atlas: %w", err)
+		}
+
+		manifest.Atlas = name + ".png"
+	}
+
+	return manifest, writeJSONFile(filepath.Join(p.OutDir, "manifest.json"), manifest)
+}
+
+func (p *AssetPipeline) sprite(ctx context.Context, name string, spec SpritePrompt) (GeneratedSprite, error) {
+	w, h := parseAssetSize(spec.Size)
+
+	frames := defaultFrames[spec.Animation]
+	if n, ok := p.Frames[spec.Animation]; ok {
+		frames = n
+	}
+
+	frames = max(frames, 1)
+	req := SpriteRequest{
+		Name: name, Spec: spec, Prompt: p.Generator.GenerateSpritePrompt(spec),
+		Width: w, Height: h, Frames: frames, Seed: p.assetSeed(name),
+	}
+
+	img, err := p.Backend.GenerateSprite(ctx, req)
+	if err != nil {
+		return GeneratedSprite{}, err
+	}
+
+	// Remote models answer at their own resolution; snap back to the grid.
+	if b := img.Bounds(); b.Dx() != w*frames || b.Dy() != h {
+		img = graphics.ScaleNearest(img, w*frames, h)
+	}
+
+	if spec.Transparent {
+		img = graphics.RemoveBackground(img)
+	}
+
+	out := GeneratedSprite{
+		Name: name, Prompt: req.Prompt,
+		Sheet: filepath.Join("sprites", name+".png"), Metadata: filepath.Join("sprites", name+".json"),
+	}
+
+	if err := writePNG(filepath.Join(p.OutDir, out.Sheet), img); err != nil {
+		return out, err
+	}
+
+	sliced := graphics.SliceSheet(img, w, h)
+	for i, frame := range sliced {
+		file := filepath.Join("frames", fmt.Sprintf("%s_%02d.png", name, i+1))
+		if err := writePNG(filepath.Join(p.OutDir, file), frame); err != nil {
+			return out, err
+		}
+
+		out.Frames = append(out.Frames, file)
+	}
+
+	return out, writeJSONFile(filepath.Join(p.OutDir, out.Metadata), p.metadata(spec, sliced))
+}
+
+func (p *AssetPipeline) metadata(spec SpritePrompt, frames []*image.RGBA) AssetMetadata {
+	w, h := parseAssetSize(spec.Size)
+	meta := AssetMetadata{Type: spriteAssetType(spec.Subject), Origin: [2]int{w / 2, h}}
+
+	meta.Tags = append(meta.Tags, strings.Fields(strings.ToLower(spec.Subject))...)
+	if spec.Style != "" {
+		meta.Tags = append(meta.Tags, string(spec.Style))
+	}
+
+	meta.Tags = append(meta.Tags, "generated")
+
+	if len(frames) > 0 {
+		if box := graphics.OpaqueBounds(frames[0]); !box.Empty() {
+			meta.Hitbox = &AssetHitbox{X: box.Min.X, Y: box.Min.Y, W: box.Dx(), H: box.Dy()}
+		}
+	}
+
+	if spec.Animation != "" {
+		meta.Tags = append(meta.Tags, "animated")
+		anim := AssetAnimation{FPS: p.FPS, Loop: spec.Animation == "idle" || spec.Animation == "walk" || spec.Animation == "run"}
+
+		if anim.FPS <= 0 {
+			anim.FPS = 8
+		}
+
+		for i := range frames {
+			anim.Frames = append(anim.Frames, i)
+		}
+
+		meta.Animations = map[string]AssetAnimation{spec.Animation: anim}
+	}
+
+	return meta
+}
+
+func (p *AssetPipeline) sound(ctx context.Context, name string, spec SFXPrompt) (GeneratedSound, error) {
+	req := SFXRequest{
+		Name: name, Spec: spec, Prompt: p.Generator.GenerateSFXPrompt(spec),
+		Duration: spec.Duration, Seed: p.assetSeed(name),
+	}
+
+	data, err := p.Backend.GenerateSFX(ctx, req)
+	if err != nil {
+		return GeneratedSound{}, err
+	}
+
+	out := GeneratedSound{Name: name, Prompt: req.Prompt, File: filepath.Join("sfx", name+audioExt(data))}
+
+	return out, os.WriteFile(filepath.Join(p.OutDir, out.File), data, 0o644)
+}
+
+// assetSeed derives a per-asset seed so assets don't change when others
+// are added.
+func (p *AssetPipeline) assetSeed(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(p.Seed, 10) + "/" + name))
+
+	return int64(h.Sum64() >> 1)
+}
+
+// SpriteAssetName names a sprite per docs/standards/naming.md:
+// subject, animation state and direction suffix.
+func SpriteAssetName(spec SpritePrompt) string {
+	parts := []string{assetSlug(spec.Subject)}
+	if spec.Animation != "" {
+		parts = append(parts, assetSlug(spec.Animation))
+	}
+
+	dir := map[string]string{"front": "down", "back": "up", "side": "right"}[spec.Direction]
+	if dir == "" {
+		dir = assetSlug(spec.Direction)
+	}
+
+	if dir != "" {
+		parts = append(parts, dir)
+	}
+
+	return strings.Join(parts, "_")
+}
+
+func uniqueAssetName(seen map[string]bool, name string, variation int) string {
+	if name == "" {
+		name = "asset"
+	}
+
+	if variation > 0 {
+		name += fmt.Sprintf("_v%d", variation+1)
+	}
+
+	base := name
+	for i := 2; seen[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	seen[name] = true
+
+	return name
+}
+
+func assetSlug(s string) string {
+	var sb strings.Builder
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}) {
+		if sb.Len() > 0 {
+			sb.WriteByte('_')
+		}
+
+		sb.WriteString(word)
+	}
+
+	return sb.String()
+}
+
+// spriteAssetType picks a metadata type from the subject.
+func spriteAssetType(subject string) string {
+	s := strings.ToLower(subject)
+
+	switch {
+	case containsAny(s, "tile", "ground", "wall", "floor", "platform"):
+		return "tile"
+	case containsAny(s, "coin", "potion", "gem", "key", "item", "sword", "chest", "collectible"):
+		return "item"
+	case containsAny(s, "explosion", "effect", "spark", "smoke", "particle", "fire"):
+		return "effect"
+	}
+
+	return "character"
+}
+
+// parseAssetSize reads "32x32"; the default is 32x32.
+func parseAssetSize(size string) (int, int) {
+	ws, hs, ok := strings.Cut(strings.ToLower(size), "x")
+	w, errW := strconv.Atoi(strings.TrimSpace(ws))
+	h, errH := strconv.Atoi(strings.TrimSpace(hs))
+
+	if !ok || errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 32, 32
+	}
+
+	return w, h
+}
+
+// audioExt sniffs encoded audio.
+func audioExt(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")):
+		return ".wav"
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return ".ogg"
+	default:
+		return ".mp3"
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/tools"
+)
+
+func TestAssetPipelineProcedural(t *testing.T) {
+	dir := t.TempDir()
+	pipeline := NewAssetPipeline(NewProceduralBackend(), dir)
+	pipeline.Seed = 7
+
+	sprites := []SpritePrompt{
+		{Subject: "Knight", Animation: "walk", Direction: "side", Size: "32x32", Transparent: true},
+		{Subject: "gold coin", Size: "16x16", Variations: 2, Transparent: true},
+	}
+	sounds := []SFXPrompt{{Description: "coin pickup", Intensity: "loud"}, {Description: "explosion"}}
+
+	manifest, err := pipeline.Run(context.Background(), sprites, sounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, s := range manifest.Sprites {
+		names = append(names, s.Name)
+	}
+
+	if got := strings.Join(names, ","); got != "knight_walk_right,gold_coin,gold_coin_v2" {
+		t.Fatalf("sprites = %s", got)
+	}
+
+	knight := manifest.Sprites[0]
+	if len(knight.Frames) != 4 || knight.Frames[0] != filepath.Join("frames", "knight_walk_right_01.png") {
+		t.Fatalf("frames = %v", knight.Frames)
+	}
+
+	sheet := readPNG(t, filepath.Join(dir, knight.Sheet))
+	if b := sheet.Bounds(); b.Dx() != 128 || b.Dy() != 32 {
+		t.Errorf("sheet bounds = %v", b)
+	}
+
+	if _, _, _, a := sheet.At(0, 0).RGBA(); a != 0 {
+		t.Error("chroma key not removed")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, knight.Metadata))
+
+	var meta AssetMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+
+	walk := meta.Animations["walk"]
+	if meta.Type != "character" || meta.Origin != [2]int{16, 32} || meta.Hitbox == nil || meta.Hitbox.W == 0 ||
+		len(walk.Frames) != 4 || !walk.Loop || walk.FPS != 8 {
+		t.Errorf("metadata = %s", data)
+	}
+
+	if coin, _ := os.ReadFile(filepath.Join(dir, "sprites", "gold_coin.json")); !strings.Contains(string(coin), `"type": "item"`) {
+		t.Errorf("coin metadata = %s", coin)
+	}
+
+	var atlas tools.AtlasMetadata
+
+	data, _ = os.ReadFile(filepath.Join(dir, "atlas.json"))
+	if err := json.Unmarshal(data, &atlas); err != nil || len(atlas.Entries) != 6 {
+		t.Fatalf("atlas = %s, %v", data, err)
+	}
+
+	if manifest.Sounds[0].File != filepath.Join("sfx", "coin_pickup.wav") {
+		t.Fatalf("sounds = %+v", manifest.Sounds)
+	}
+
+	wav, _ := os.ReadFile(filepath.Join(dir, manifest.Sounds[1].File))
+	if !bytes.HasPrefix(wav, []byte("RIFF")) || string(wav[8:12]) != "WAVE" || len(wav) < 44+44100 {
+		t.Errorf("explosion wav: %d bytes", len(wav))
+	}
+
+	// Same seed, same assets.
+	again := t.TempDir()
+	pipeline.OutDir = again
+	_, _ = pipeline.Run(context.Background(), sprites[:1], nil)
+
+	first, _ := os.ReadFile(filepath.Join(dir, knight.Sheet))
+	second, _ := os.ReadFile(filepath.Join(again, knight.Sheet))
+
+	if !bytes.Equal(first, second) {
+		t.Error("procedural sprite is not deterministic")
+	}
+
+	// A second run into the same directory packs only its own frames.
+	pipeline.OutDir = dir
+	if _, err := pipeline.Run(context.Background(), sprites[1:], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ = os.ReadFile(filepath.Join(dir, "atlas.json"))
+	if err := json.Unmarshal(data, &atlas); err != nil || len(atlas.Entries) != 2 {
+		t.Errorf("rerun atlas = %s, %v", data, err)
+	}
+}
+
+func TestHTTPAssetBackend(t *testing.T) {
+	var prompts []string
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/images/generations":
+			prompts = append(prompts, body["prompt"].(string))
+
+			// A 64x32 reply for a 2-frame 16x16 request, on green.
+			img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+			for i := range img.Pix {
+				img.Pix[i] = []uint8{0, 255, 0, 255}[i%4]
+			}
+
+			img.Set(20, 20, color.RGBA{200, 10, 10, 255})
+
+			var buf bytes.Buffer
+			_ = png.Encode(&buf, img)
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"data": []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(buf.Bytes())}},
+			})
+		case "/v1/sound-generation":
+			prompts = append(prompts, body["text"].(string))
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = io.WriteString(w, "ID3fake")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	backend := NewHTTPAssetBackend("http://api.test/v1/", "key", "")
+	backend.HTTPClient = &http.Client{Transport: handlerTransport{handler}}
+
+	dir := t.TempDir()
+	pipeline := NewAssetPipeline(backend, dir)
+	pipeline.Atlas = nil
+	pipeline.Frames = map[string]int{"idle": 2}
+
+	manifest, err := pipeline.Run(context.Background(),
+		[]SpritePrompt{{Subject: "slime", Animation: "idle", Size: "16x16", Transparent: true}},
+		[]SFXPrompt{{Description: "slime squish", Duration: 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "2 animation frames") || !strings.Contains(prompts[1], "slime squish") {
+		t.Errorf("prompts = %q", prompts)
+	}
+
+	frame := readPNG(t, filepath.Join(dir, "frames", "slime_idle_01.png"))
+	if _, _, _, a := frame.At(0, 0).RGBA(); a != 0 || frame.Bounds().Dx() != 16 {
+		t.Errorf("frame not scaled and keyed: %v", frame.Bounds())
+	}
+
+	if r, _, _, _ := frame.At(10, 10).RGBA(); r>>8 != 200 {
+		t.Error("sprite pixel lost when scaling")
+	}
+
+	if manifest.Sounds[0].File != filepath.Join("sfx", "slime_squish.mp3") || manifest.Atlas != "" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	backend.APIKey = "wrong"
+
+	_, err = pipeline.Run(context.Background(), nil, []SFXPrompt{{Description: "beep"}})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad key = %v", err)
+	}
+}
+
+func readPNG(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
@@ -0,0 +1,1016 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+)
+
+// ErrBalanceConfig is returned for a simulator or search that can't run.
+var ErrBalanceConfig = errors.New("ai: invalid balance config")
+
+// Stats understood by the simulator in upgrade and shop effects, as a
+// fraction added per level or purchase.
+const (
+	StatKillRate = "kill_rate"
+	StatGoldGain = "gold_gain"
+	StatXPGain   = "xp_gain"
+)
+
+// ============================================================================
+// Economy Spec
+// ============================================================================
+
+// EconomySpec is the economy and progression under test. Every simulated
+// player gets a fresh copy.
+type EconomySpec struct {
+	Currency    components.CurrencyType // Default gold
+	Experience  components.Experience
+	MaxLevel    int
+	GoldPerKill int64
+	XPPerKill   int64
+
+	Upgrades []components.Upgrade
+	Shop     components.Shop
+	// ShopEffects gives shop items permanent stat effects per purchase,
+	// keyed by shop item ID.
+	ShopEffects map[string]map[string]float64
+
+	Loot       components.LootTable // Rolled once per kill
+	ItemValues map[string]int64     // Sell value of looted items
+}
+
+// Clone deep-copies the spec so knobs can change it safely.
+func (s EconomySpec) Clone() EconomySpec {
+	clone := s
+
+	clone.Upgrades = make([]components.Upgrade, len(s.Upgrades))
+	for i, u := range s.Upgrades {
+		u.Effects = cloneStats(u.Effects)
+		clone.Upgrades[i] = u
+	}
+
+	clone.Shop = cloneShop(s.Shop)
+
+	clone.ShopEffects = make(map[string]map[string]float64, len(s.ShopEffects))
+	for id, effects := range s.ShopEffects {
+		clone.ShopEffects[id] = cloneStats(effects)
+	}
+
+	clone.Loot.Entries = slices.Clone(s.Loot.Entries)
+	clone.Loot.Guaranteed = slices.Clone(s.Loot.Guaranteed)
+
+	clone.ItemValues = make(map[string]int64, len(s.ItemValues))
+	for id, v := range s.ItemValues {
+		clone.ItemValues[id] = v
+	}
+
+	return clone
+}
+
+// Upgrade returns the upgrade with the given ID, or nil.
+func (s *EconomySpec) Upgrade(id string) *components.Upgrade {
+	for i := range s.Upgrades {
+		if s.Upgrades[i].ID == id {
+			return &s.Upgrades[i]
+		}
+	}
+
+	return nil
+}
+
+func cloneStats(stats map[string]float64) map[string]float64 {
+	clone := make(map[string]float64, len(stats))
+	for k, v := range stats {
+		clone[k] = v
+	}
+
+	return clone
+}
+
+func cloneShop(shop components.Shop) components.Shop {
+	clone := shop
+
+	clone.Items = make(map[string]*components.ShopItem, len(shop.Items))
+	for id, item := range shop.Items {
+		copied := *item
+		clone.Items[id] = &copied
+	}
+
+	return clone
+}
+
+// ============================================================================
+// Play Styles
+// ============================================================================
+
+// BalanceOffer is something a player can spend currency on.
+type BalanceOffer struct {
+	ID      string
+	Upgrade bool // false for shop items
+	Cost    int64
+}
+
+// PlayStyle is a parameterized simulated player.
+type PlayStyle struct {
+	Name     string
+	KillRate float64 // Mean kills per step before upgrades
+	Variance float64 // Relative standard deviation of kills per step
+	SellLoot bool    // Sell looted items for currency
+
+	// Priority lists offer IDs to buy first; anything else is bought
+	// cheapest first. Use Only to buy nothing off the list.
+	Priority []string
+	Only     bool
+	// Reserve is the share of the wallet never spent.
+	Reserve float64
+
+	// Choose overrides the purchase policy. It returns the offer to buy, or
+	// "" to stop spending this step.
+	Choose func(balance int64, offers []BalanceOffer) string
+}
+
+// choose picks the next purchase from the affordable offers.
+func (p *PlayStyle) choose(balance int64, offers []BalanceOffer) string {
+	if p.Choose != nil {
+		return p.Choose(balance, offers)
+	}
+
+	budget := int64(float64(balance) * (1 - clamp(p.Reserve, 0, 1)))
+
+	for _, id := range p.Priority {
+		for _, o := range offers {
+			if o.ID == id && o.Cost <= budget {
+				return id
+			}
+		}
+	}
+
+	if p.Only {
+		return ""
+	}
+
+	best := ""
+	cost := int64(math.MaxInt64)
+
+	for _, o := range offers {
+		if o.Cost <= budget && o.Cost < cost {
+			best, cost = o.ID, o.Cost
+		}
+	}
+
+	return best
+}
+
+// ============================================================================
+// Simulator
+// ============================================================================
+
+// BalanceOutcome is one simulated player's end state.
+type BalanceOutcome struct {
+	Style     string
+	Level     int
+	TotalXP   int64
+	Earned    int64
+	Spent     int64
+	Balance   int64
+	Purchases map[string]int
+	LevelUps  []int // Step each level was first reached; index 0 is level 2
+}
+
+// BalanceSimulator runs many players of each style through EconomySystem and
+// ProgressionSystem and aggregates the results.
+type BalanceSimulator struct {
+	Spec   EconomySpec
+	Styles []PlayStyle
+
+	Players     int // Per style (0 = 1000)
+	Steps       int // Steps per player (0 = 300)
+	SampleEvery int // Curve resolution in steps (0 = 10)
+	Seed        int64
+	Workers     int // 0 = GOMAXPROCS
+
+	// Score ranks outcomes when looking for dominant strategies; by
+	// default total XP earned.
+	Score func(BalanceOutcome) float64
+	// DominanceMargin is how far ahead the best style must be to be
+	// reported as dominant (0 = 0.15).
+	DominanceMargin float64
+	// DominantShare flags an offer taking this share of the top decile's
+	// spending (0 = 0.6).
+	DominantShare float64
+}
+
+// NewBalanceSimulator creates a simulator with default sizes.
+func NewBalanceSimulator(spec EconomySpec, styles ...PlayStyle) *BalanceSimulator {
+	return &BalanceSimulator{Spec: spec, Styles: styles}
+}
+
+func (s *BalanceSimulator) defaults() BalanceSimulator {
+	c := *s
+	if c.Players <= 0 {
+		c.Players = 1000
+	}
+
+	if c.Steps <= 0 {
+		c.Steps = 300
+	}
+
+	if c.SampleEvery <= 0 {
+		c.SampleEvery = 10
+	}
+
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	if c.Score == nil {
+		c.Score = func(o BalanceOutcome) float64 { return float64(o.TotalXP) }
+	}
+
+	if c.DominanceMargin <= 0 {
+		c.DominanceMargin = 0.15
+	}
+
+	if c.DominantShare <= 0 {
+		c.DominantShare = 0.6
+	}
+
+	if c.Spec.Currency == "" {
+		c.Spec.Currency = components.CurrencyGold
+	}
+
+	return c
+}
+
+// balanceRun is one player's trajectory.
+type balanceRun struct {
+	outcome   BalanceOutcome
+	balance   []float64
+	purchases map[string][]float64
+	spend     map[string]int64
+}
+
+// Run simulates every style. Players are seeded by index, so results are
+// identical for any worker count.
+func (s *BalanceSimulator) Run() (*BalanceReport, error) {
+	c := s.defaults()
+	if len(c.Styles) == 0 {
+		return nil, fmt.Errorf("%w: no play styles", ErrBalanceConfig)
+	}
+
+	if c.Spec.GoldPerKill <= 0 && c.Spec.XPPerKill <= 0 && len(c.Spec.Loot.Entries) == 0 {
+		return nil, fmt.Errorf("%w: players earn nothing", ErrBalanceConfig)
+	}
+
+	total := len(c.Styles) * c.Players
+	runs := make([]balanceRun, total)
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(c.Workers, total) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sim := newBalanceWorld()
+			for i := range next {
+				style := &c.Styles[i/c.Players]
+				runs[i] = sim.play(&c, style, c.Seed+int64(i))
+			}
+		}()
+	}
+
+	for i := range total {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	return c.report(runs), nil
+}
+
+// balanceWorld is a reusable world holding one simulated player at a time.
+type balanceWorld struct {
+	world       ecs.World
+	players     *ecs.Map4[components.Currency, components.Experience, components.Level, components.UpgradeManager]
+	wallets     *ecs.Map[components.Currency]
+	upgrades    *ecs.Map[components.UpgradeManager]
+	economy     *systems.EconomySystem
+	progression *systems.ProgressionSystem
+	levelUps    []int
+	step        int
+}
+
+func newBalanceWorld() *balanceWorld {
+	b := &balanceWorld{world: ecs.NewWorld()}
+	b.players = ecs.NewMap4[components.Currency, components.Experience, components.Level, components.UpgradeManager](&b.world)
+	b.wallets = ecs.NewMap[components.Currency](&b.world)
+	b.upgrades = ecs.NewMap[components.UpgradeManager](&b.world)
+	b.economy = systems.NewEconomySystem(&b.world)
+	b.progression = systems.NewProgressionSystem(&b.world)
+	b.progression.SetOnLevelUp(func(systems.LevelUpEvent) {
+		b.levelUps = append(b.levelUps, b.step)
+	})
+
+	return b
+}
+
+func (b *balanceWorld) play(c *BalanceSimulator, style *PlayStyle, seed int64) balanceRun {
+	spec := &c.Spec
+	rng := rand.New(rand.NewSource(seed))
+
+	b.economy.RegisterShop(cloneShop(spec.Shop))
+	shop := b.economy.GetShop(spec.Shop.ID)
+
+	upgrades := components.NewUpgradeManager()
+	for _, u := range spec.Upgrades {
+		upgrades.Add(u)
+	}
+
+	currency := components.NewCurrency()
+	exp := spec.Experience
+	level := components.NewLevel(spec.MaxLevel)
+
+	player := b.players.NewEntity(&currency, &exp, &level, &upgrades)
+	defer b.world.RemoveEntity(player)
+
+	wallet := b.wallets.Get(player)
+	manager := b.upgrades.Get(player)
+
+	b.levelUps = b.levelUps[:0]
+
+	run := balanceRun{
+		outcome:   BalanceOutcome{Style: style.Name, Purchases: make(map[string]int)},
+		purchases: make(map[string][]float64),
+		spend:     make(map[string]int64),
+	}
+
+	for step := range c.Steps {
+		b.step = step + 1
+		effects := manager.GetAllEffects()
+
+		for id, n := range run.outcome.Purchases {
+			for stat, v := range spec.ShopEffects[id] {
+				effects[stat] += v * float64(n)
+			}
+		}
+
+		kills := style.KillRate * (1 + effects[StatKillRate]) * (1 + style.Variance*rng.NormFloat64())
+		n := int(math.Max(kills, 0))
+
+		if rng.Float64() < kills-float64(n) {
+			n++
+		}
+
+		if xp := int64(float64(int64(n)*spec.XPPerKill) * (1 + effects[StatXPGain])); xp > 0 {
+			b.progression.AddExperience(&b.world, player, xp)
+			run.outcome.TotalXP += xp
+		}
+
+		gold := int64(float64(int64(n)*spec.GoldPerKill) * (1 + effects[StatGoldGain]))
+		if style.SellLoot && len(spec.Loot.Entries)+len(spec.Loot.Guaranteed) > 0 {
+			for range n {
+				for id, count := range spec.Loot.Roll(rng) {
+					gold += spec.ItemValues[id] * int64(count)
+				}
+			}
+		}
+
+		if gold > 0 {
+			b.economy.AddCurrency(&b.world, player, spec.Currency, gold)
+			run.outcome.Earned += gold
+		}
+
+		b.spend(c, style, player, wallet, manager, shop, &run)
+
+		// Only the outcome matters; don't let the logs grow.
+		b.economy.ClearTransactionLog()
+		b.progression.ClearLevelUps()
+
+		if (step+1)%c.SampleEvery == 0 {
+			run.balance = append(run.balance, float64(wallet.Get(spec.Currency)))
+			for _, id := range offerIDs(spec) {
+				run.purchases[id] = append(run.purchases[id], float64(run.outcome.Purchases[id]))
+			}
+		}
+	}
+
+	run.outcome.Level = b.progression.GetLevel(&b.world, player)
+	run.outcome.Balance = wallet.Get(spec.Currency)
+	run.outcome.LevelUps = slices.Clone(b.levelUps)
+
+	return run
+}
+
+// spend buys offers until the style stops or nothing is affordable.
+func (b *balanceWorld) spend(
+	c *BalanceSimulator,
+	style *PlayStyle,
+	player ecs.Entity,
+	wallet *components.Currency,
+	manager *components.UpgradeManager,
+	shop *components.Shop,
+	run *balanceRun,
+) {
+	spec := &c.Spec
+	level := b.progression.GetLevel(&b.world, player)
+
+	for range 32 {
+		var offers []BalanceOffer
+
+		for _, u := range spec.Upgrades {
+			if up := manager.Upgrades[u.ID]; up.CanUpgrade(wallet) && up.Currency == spec.Currency {
+				offers = append(offers, BalanceOffer{ID: u.ID, Upgrade: true, Cost: up.GetCost()})
+			}
+		}
+
+		for _, id := range sortedShopItems(shop) {
+			if item := shop.Items[id]; item.Currency == spec.Currency && item.CanPurchase(wallet, level) {
+				offers = append(offers, BalanceOffer{ID: id, Cost: item.GetFinalPrice()})
+			}
+		}
+
+		id := style.choose(wallet.Get(spec.Currency), offers)
+		if id == "" {
+			return
+		}
+
+		before := wallet.Get(spec.Currency)
+
+		var ok bool
+		if manager.Upgrades[id] != nil {
+			ok = b.economy.PurchaseUpgrade(&b.world, player, id)
+		} else {
+			ok = b.economy.Purchase(&b.world, player, shop.ID, id, level)
+		}
+
+		if !ok {
+			return
+		}
+
+		cost := before - wallet.Get(spec.Currency)
+		run.outcome.Purchases[id]++
+		run.outcome.Spent += cost
+		run.spend[id] += cost
+	}
+}
+
+func sortedShopItems(shop *components.Shop) []string {
+	ids := make([]string, 0, len(shop.Items))
+	for id := range shop.Items {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// offerIDs lists upgrades in spec order, then shop items by ID.
+func offerIDs(spec *EconomySpec) []string {
+	ids := make([]string, 0, len(spec.Upgrades)+len(spec.Shop.Items))
+	for _, u := range spec.Upgrades {
+		ids = append(ids, u.ID)
+	}
+
+	return append(ids, sortedShopItems(&spec.Shop)...)
+}
+
+// ============================================================================
+// Report
+// ============================================================================
+
+// StyleReport aggregates the players of one style. Curves are sampled every
+// SampleEvery steps.
+type StyleReport struct {
+	Style      string  `json:"style"`
+	Players    int     `json:"players"`
+	Score      float64 `json:"score"`
+	FinalLevel float64 `json:"final_level"`
+	Earned     float64 `json:"earned"`
+	Spent      float64 `json:"spent"`
+
+	// TimeToLevel is the mean step each level was reached, indexed by level.
+	// Players who never got there count as Steps.
+	TimeToLevel []float64 `json:"time_to_level"`
+	// Reached is the share of players reaching each level.
+	Reached []float64 `json:"reached"`
+
+	// Balance is the mean wallet over time.
+	Balance []float64 `json:"balance"`
+	// Inflation is the share of late-game income left unspent. Near 1, sinks
+	// have run dry and currency piles up.
+	Inflation float64 `json:"inflation"`
+
+	// Purchases is the mean cumulative purchase count of each offer.
+	Purchases map[string][]float64 `json:"purchases"`
+	// SpendShare is each offer's share of total spending.
+	SpendShare map[string]float64 `json:"spend_share"`
+}
+
+// BalanceFinding flags a strategy that crowds out the others.
+type BalanceFinding struct {
+	Kind   string `json:"kind"` // "style" or "offer"
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// BalanceReport is the result of a simulation.
+type BalanceReport struct {
+	Steps       int              `json:"steps"`
+	SampleEvery int              `json:"sample_every"`
+	Styles      []StyleReport    `json:"styles"`
+	Dominant    []BalanceFinding `json:"dominant,omitempty"`
+}
+
+// Style returns the report for a style, or nil.
+func (r *BalanceReport) Style(name string) *StyleReport {
+	for i := range r.Styles {
+		if r.Styles[i].Style == name {
+			return &r.Styles[i]
+		}
+	}
+
+	return nil
+}
+
+// TimeToLevel returns when a style reached level on average, or Steps if
+// the level doesn't exist.
+func (r *BalanceReport) TimeToLevel(style string, level int) float64 {
+	s := r.Style(style)
+	if s == nil || level >= len(s.TimeToLevel) {
+		return float64(r.Steps)
+	}
+
+	return s.TimeToLevel[level]
+}
+
+func (s *BalanceSimulator) report(runs []balanceRun) *BalanceReport {
+	report := &BalanceReport{Steps: s.Steps, SampleEvery: s.SampleEvery}
+	maxLevel := 1
+
+	for _, run := range runs {
+		maxLevel = max(maxLevel, run.outcome.Level)
+	}
+
+	ids := offerIDs(&s.Spec)
+	samples := s.Steps / s.SampleEvery
+
+	for si, style := range s.Styles {
+		group := runs[si*s.Players : (si+1)*s.Players]
+		n := float64(len(group))
+
+		sr := StyleReport{
+			Style:       style.Name,
+			Players:     len(group),
+			TimeToLevel: make([]float64, maxLevel+1),
+			Reached:     make([]float64, maxLevel+1),
+			Balance:     make([]float64, samples),
+			Purchases:   make(map[string][]float64, len(ids)),
+			SpendShare:  make(map[string]float64, len(ids)),
+		}
+
+		for _, id := range ids {
+			sr.Purchases[id] = make([]float64, samples)
+		}
+
+		var lateIncome, lateGrowth float64
+
+		for _, run := range group {
+			o := run.outcome
+			sr.Score += s.Score(o) / n
+			sr.FinalLevel += float64(o.Level) / n
+			sr.Earned += float64(o.Earned) / n
+			sr.Spent += float64(o.Spent) / n
+
+			for lvl := 2; lvl <= maxLevel; lvl++ {
+				at := float64(s.Steps)
+				if lvl-2 < len(o.LevelUps) {
+					at = float64(o.LevelUps[lvl-2])
+					sr.Reached[lvl] += 1 / n
+				}
+
+				sr.TimeToLevel[lvl] += at / n
+			}
+
+			for i, v := range run.balance {
+				sr.Balance[i] += v / n
+			}
+
+			for id, curve := range run.purchases {
+				for i, v := range curve {
+					sr.Purchases[id][i] += v / n
+				}
+			}
+
+			for id, spent := range run.spend {
+				sr.SpendShare[id] += float64(spent)
+			}
+
+			if mid := len(run.balance) / 2; mid > 0 {
+				lateGrowth += run.balance[len(run.balance)-1] - run.balance[mid-1]
+				lateIncome += float64(o.Earned) * float64(len(run.balance)-mid) / float64(len(run.balance))
+			}
+		}
+
+		if len(sr.Reached) > 1 {
+			sr.Reached[1] = 1
+		}
+
+		if lateIncome > 0 {
+			sr.Inflation = clamp(lateGrowth/lateIncome, 0, 1)
+		}
+
+		totalSpend := 0.0
+		for _, v := range sr.SpendShare {
+			totalSpend += v
+		}
+
+		for id := range sr.SpendShare {
+			if totalSpend > 0 {
+				sr.SpendShare[id] /= totalSpend
+			}
+		}
+
+		report.Styles = append(report.Styles, sr)
+	}
+
+	report.Dominant = s.findDominant(report, runs, ids)
+
+	return report
+}
+
+// findDominant flags a style that beats all others by the margin, and offers
+// that the best players spend most of their currency on.
+func (s *BalanceSimulator) findDominant(report *BalanceReport, runs []balanceRun, ids []string) []BalanceFinding {
+	var findings []BalanceFinding
+
+	if len(report.Styles) > 1 {
+		ranked := slices.Clone(report.Styles)
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+		best, second := ranked[0], ranked[1]
+		if second.Score > 0 && best.Score > second.Score*(1+s.DominanceMargin) {
+			findings = append(findings, BalanceFinding{
+				Kind: "style",
+				Name: best.Style,
+				Detail: fmt.Sprintf("scores %.0f%% above %s (%.0f vs %.0f)",
+					(best.Score/second.Score-1)*100, second.Style, best.Score, second.Score),
+			})
+		}
+	}
+
+	if len(ids) < 2 {
+		return findings
+	}
+
+	ranked := slices.Clone(runs)
+	sort.SliceStable(ranked, func(i, j int) bool { return s.Score(ranked[i].outcome) > s.Score(ranked[j].outcome) })
+
+	top := ranked[:max(len(ranked)/10, 1)]
+	spend := make(map[string]float64)
+	total := 0.0
+
+	for _, run := range top {
+		for id, v := range run.spend {
+			spend[id] += float64(v)
+			total += float64(v)
+		}
+	}
+
+	if total == 0 {
+		return findings
+	}
+
+	for _, id := range ids {
+		if share := spend[id] / total; share >= s.DominantShare {
+			findings = append(findings, BalanceFinding{
+				Kind:   "offer",
+				Name:   id,
+				Detail: fmt.Sprintf("takes %.0f%% of the top decile's spending", share*100),
+			})
+		}
+	}
+
+	return findings
+}
+
+// GenerateMarkdown renders the report as tables.
+func (r *BalanceReport) GenerateMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Balance Report\n\n")
+	sb.WriteString(fmt.Sprintf("**Steps**: %d\n\n", r.Steps))
+	sb.WriteString("## Styles\n\n")
+	sb.WriteString("| Style | Players | Score | Final Level | Earned | Spent | Inflation |\n")
+	sb.WriteString("|-------|---------|-------|-------------|--------|-------|-----------|\n")
+
+	for _, s := range r.Styles {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %.0f | %.1f | %.0f | %.0f | %.2f |\n",
+			s.Style, s.Players, s.Score, s.FinalLevel, s.Earned, s.Spent, s.Inflation))
+	}
+
+	sb.WriteString("\n## Time to Level\n\n")
+
+	for _, s := range r.Styles {
+		sb.WriteString("- " + s.Style + ":")
+
+		for lvl := 2; lvl < len(s.TimeToLevel); lvl++ {
+			sb.WriteString(fmt.Sprintf(" L%d=%.0f (%.0f%%)", lvl, s.TimeToLevel[lvl], s.Reached[lvl]*100))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n## Spending\n\n")
+
+	for _, s := range r.Styles {
+		ids := make([]string, 0, len(s.SpendShare))
+		for id := range s.SpendShare {
+			ids = append(ids, id)
+		}
+
+		sort.Strings(ids)
+
+		sb.WriteString("- " + s.Style + ":")
+
+		for _, id := range ids {
+			final := 0.0
+			if curve := s.Purchases[id]; len(curve) > 0 {
+				final = curve[len(curve)-1]
+			}
+
+			sb.WriteString(fmt.Sprintf(" %s %.0f%% (%.1f bought)", id, s.SpendShare[id]*100, final))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	if len(r.Dominant) > 0 {
+		sb.WriteString("\n## Dominant Strategies\n\n")
+
+		for _, f := range r.Dominant {
+			sb.WriteString(fmt.Sprintf("- **%s %s** %s\n", f.Kind, f.Name, f.Detail))
+		}
+	}
+
+	return sb.String()
+}
+
+// ============================================================================
+// Parameter Search
+// ============================================================================
+
+// BalanceKnob is a tunable parameter of an EconomySpec.
+type BalanceKnob struct {
+	Name     string
+	Min, Max float64
+	Integer  bool
+	Apply    func(spec *EconomySpec, v float64)
+}
+
+// UpgradeCostKnob tunes an upgrade's base cost.
+func UpgradeCostKnob(id string, minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: id + ".base_cost", Min: minVal, Max: maxVal, Integer: true,
+		Apply: func(spec *EconomySpec, v float64) {
+			if u := spec.Upgrade(id); u != nil {
+				u.BaseCost = int64(v)
+			}
+		},
+	}
+}
+
+// UpgradeScalingKnob tunes an upgrade's cost growth per level.
+func UpgradeScalingKnob(id string, minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: id + ".cost_scaling", Min: minVal, Max: maxVal,
+		Apply: func(spec *EconomySpec, v float64) {
+			if u := spec.Upgrade(id); u != nil {
+				u.CostScaling = v
+			}
+		},
+	}
+}
+
+// XPBaseKnob tunes the XP needed for the first level.
+func XPBaseKnob(minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: "xp.base_to_next", Min: minVal, Max: maxVal, Integer: true,
+		Apply: func(spec *EconomySpec, v float64) { spec.Experience.BaseToNext = int64(v) },
+	}
+}
+
+// XPScalingKnob tunes XP growth per level.
+func XPScalingKnob(minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: "xp.scaling", Min: minVal, Max: maxVal,
+		Apply: func(spec *EconomySpec, v float64) { spec.Experience.Scaling = v },
+	}
+}
+
+// ShopPriceKnob tunes a shop item's price.
+func ShopPriceKnob(id string, minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: id + ".price", Min: minVal, Max: maxVal, Integer: true,
+		Apply: func(spec *EconomySpec, v float64) {
+			if item := spec.Shop.Items[id]; item != nil {
+				item.Price = int64(v)
+			}
+		},
+	}
+}
+
+// LootWeightKnob tunes the weight of an item in the loot table.
+func LootWeightKnob(itemID string, minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: itemID + ".drop_weight", Min: minVal, Max: maxVal,
+		Apply: func(spec *EconomySpec, v float64) {
+			for i := range spec.Loot.Entries {
+				if spec.Loot.Entries[i].ItemID == itemID {
+					spec.Loot.Entries[i].Weight = v
+				}
+			}
+		},
+	}
+}
+
+// LootNothingKnob tunes the chance a kill drops nothing.
+func LootNothingKnob(minVal, maxVal float64) BalanceKnob {
+	return BalanceKnob{
+		Name: "loot.nothing_chance", Min: minVal, Max: maxVal,
+		Apply: func(spec *EconomySpec, v float64) { spec.Loot.NothingChance = v },
+	}
+}
+
+// BalanceTarget is a designer goal, e.g. "casual players reach level 5 by
+// step 100".
+type BalanceTarget struct {
+	Name      string
+	Metric    func(*BalanceReport) float64
+	Target    float64
+	Tolerance float64 // Miss that counts as one unit of loss (0 = 1)
+	Weight    float64 // 0 = 1
+}
+
+// TimeToLevelTarget aims for a style to reach level at step.
+func TimeToLevelTarget(style string, level int, step, tolerance float64) BalanceTarget {
+	return BalanceTarget{
+		Name:      fmt.Sprintf("%s reaches L%d", style, level),
+		Metric:    func(r *BalanceReport) float64 { return r.TimeToLevel(style, level) },
+		Target:    step,
+		Tolerance: tolerance,
+	}
+}
+
+// InflationTarget aims for a style's inflation.
+func InflationTarget(style string, inflation, tolerance float64) BalanceTarget {
+	return BalanceTarget{
+		Name: style + " inflation",
+		Metric: func(r *BalanceReport) float64 {
+			if s := r.Style(style); s != nil {
+				return s.Inflation
+			}
+
+			return 1
+		},
+		Target:    inflation,
+		Tolerance: tolerance,
+	}
+}
+
+// BalanceSearch looks for knob settings that meet the targets: a random
+// sweep of the space, then a local search around the best point with a
+// shrinking radius. Every evaluation reuses the same seed, so candidates
+// are compared on identical luck.
+type BalanceSearch struct {
+	Simulator *BalanceSimulator // Template; Players should be small
+	Knobs     []BalanceKnob
+	Targets   []BalanceTarget
+
+	Iterations int // Total evaluations (0 = 60)
+	Seed       int64
+}
+
+// BalanceCandidate is one evaluated point.
+type BalanceCandidate struct {
+	Values  map[string]float64 `json:"values"`
+	Metrics map[string]float64 `json:"metrics"`
+	Loss    float64            `json:"loss"`
+}
+
+// BalanceSearchResult holds the best point and the search history.
+type BalanceSearchResult struct {
+	Best    BalanceCandidate   `json:"best"`
+	Spec    EconomySpec        `json:"-"`
+	Report  *BalanceReport     `json:"-"`
+	History []BalanceCandidate `json:"history"`
+}
+
+// Run performs the search.
+func (bs *BalanceSearch) Run() (*BalanceSearchResult, error) {
+	if bs.Simulator == nil || len(bs.Knobs) == 0 || len(bs.Targets) == 0 {
+		return nil, fmt.Errorf("%w: search needs a simulator, knobs and targets", ErrBalanceConfig)
+	}
+
+	iterations := bs.Iterations
+	if iterations <= 0 {
+		iterations = 60
+	}
+
+	rng := rand.New(rand.NewSource(bs.Seed))
+	result := &BalanceSearchResult{}
+
+	var best []float64
+
+	evaluate := func(point []float64) error {
+		spec := bs.Simulator.Spec.Clone()
+		values := make(map[string]float64, len(bs.Knobs))
+
+		for i, k := range bs.Knobs {
+			v := clamp(point[i], k.Min, k.Max)
+			if k.Integer {
+				v = math.Round(v)
+			}
+
+			point[i] = v
+			values[k.Name] = v
+			k.Apply(&spec, v)
+		}
+
+		sim := *bs.Simulator
+		sim.Spec = spec
+
+		report, err := sim.Run()
+		if err != nil {
+			return err
+		}
+
+		candidate := BalanceCandidate{Values: values, Metrics: make(map[string]float64, len(bs.Targets))}
+
+		for _, t := range bs.Targets {
+			got := t.Metric(report)
+			candidate.Metrics[t.Name] = got
+
+			tolerance, weight := t.Tolerance, t.Weight
+			if tolerance == 0 {
+				tolerance = 1
+			}
+
+			if weight == 0 {
+				weight = 1
+			}
+
+			miss := (got - t.Target) / tolerance
+			candidate.Loss += weight * miss * miss
+		}
+
+		result.History = append(result.History, candidate)
+
+		if best == nil || candidate.Loss < result.Best.Loss {
+			best = slices.Clone(point)
+			result.Best, result.Spec, result.Report = candidate, spec, report
+		}
+
+		return nil
+	}
+
+	sweep := max(iterations/3, 1)
+
+	for i := range iterations {
+		point := make([]float64, len(bs.Knobs))
+
+		for k, knob := range bs.Knobs {
+			if i < sweep || best == nil {
+				point[k] = knob.Min + rng.Float64()*(knob.Max-knob.Min)
+
+				continue
+			}
+
+			// Shrink from a quarter of the range to a few percent.
+			radius := 0.25 * math.Pow(0.1, float64(i-sweep)/float64(max(iterations-sweep, 1)))
+			point[k] = best[k] + rng.NormFloat64()*radius*(knob.Max-knob.Min)
+		}
+
+		if err := evaluate(point); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
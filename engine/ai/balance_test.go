@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+func newBalanceSpec() EconomySpec {
+	sharpen := components.NewUpgrade("sharpen", "Sharpen", 10, 50, components.CurrencyGold)
+	sharpen.Effects[StatKillRate] = 0.2
+	greed := components.NewUpgrade("greed", "Greed", 10, 40, components.CurrencyGold)
+	greed.Effects[StatGoldGain] = 0.5
+	trinket := components.NewUpgrade("trinket", "Trinket", 20, 20, components.CurrencyGold)
+
+	shop := components.NewShop("store", "Store")
+	shop.AddItem(components.NewShopItem("hat", "Hat", "hat", 30, components.CurrencyGold))
+
+	loot := components.NewLootTable(1, 1)
+	loot.NothingChance = 0.7
+	loot.AddEntry(components.LootEntry{ItemID: "gem", Weight: 1})
+
+	return EconomySpec{
+		Experience:  components.NewExperience(100, 1.3),
+		MaxLevel:    20,
+		GoldPerKill: 5,
+		XPPerKill:   10,
+		Upgrades:    []components.Upgrade{sharpen, greed, trinket},
+		Shop:        shop,
+		Loot:        loot,
+		ItemValues:  map[string]int64{"gem": 10},
+	}
+}
+
+func newBalanceStyles() []PlayStyle {
+	return []PlayStyle{
+		{Name: "casual", KillRate: 1, Variance: 0.3},
+		{Name: "looter", KillRate: 1, Variance: 0.3, SellLoot: true},
+		{Name: "grinder", KillRate: 2, Variance: 0.3, Priority: []string{"sharpen"}, Only: true},
+		{Name: "hoarder", KillRate: 1, Variance: 0.3, Reserve: 1},
+	}
+}
+
+func TestBalanceSimulator(t *testing.T) {
+	sim := NewBalanceSimulator(newBalanceSpec(), newBalanceStyles()...)
+	sim.Players = 200
+	sim.Steps = 200
+
+	report, err := sim.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	casual, looter, grinder, hoarder := report.Style("casual"), report.Style("looter"),
+		report.Style("grinder"), report.Style("hoarder")
+
+	if hoarder.Spent != 0 || hoarder.Inflation < 0.99 || casual.Inflation > 0.5 {
+		t.Errorf("inflation: hoarder %.2f (spent %.0f), casual %.2f", hoarder.Inflation, hoarder.Spent, casual.Inflation)
+	}
+
+	if looter.Earned <= casual.Earned*1.3 {
+		t.Errorf("selling loot earned %.0f vs %.0f", looter.Earned, casual.Earned)
+	}
+
+	if report.TimeToLevel("grinder", 5) >= report.TimeToLevel("casual", 5) || casual.Reached[5] < 0.999 {
+		t.Errorf("time to L5: grinder %.0f, casual %.0f (%.2f reached)",
+			report.TimeToLevel("grinder", 5), report.TimeToLevel("casual", 5), casual.Reached[5])
+	}
+
+	if grinder.SpendShare["sharpen"] != 1 || grinder.Purchases["greed"][len(grinder.Purchases["greed"])-1] != 0 {
+		t.Errorf("grinder spending = %v", grinder.SpendShare)
+	}
+
+	// The hat never gets pricier, so it soaks up casual spending.
+	curve := casual.Purchases["hat"]
+	for i := 1; i < len(curve); i++ {
+		if curve[i] < curve[i-1] {
+			t.Fatalf("purchase curve decreases: %v", curve)
+		}
+	}
+
+	if len(curve) != 20 || curve[len(curve)-1] < 10 || casual.Purchases["trinket"][19] < 1.99 {
+		t.Errorf("hat curve = %v", curve)
+	}
+
+	var found []string
+	for _, f := range report.Dominant {
+		found = append(found, f.Kind+":"+f.Name)
+	}
+
+	if strings.Join(found, ",") != "style:grinder,offer:sharpen" {
+		t.Errorf("dominant = %+v", report.Dominant)
+	}
+
+	if md := report.GenerateMarkdown(); !strings.Contains(md, "**style grinder** scores") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
+
+func TestBalanceSimulatorDeterministic(t *testing.T) {
+	run := func(workers int) *BalanceReport {
+		sim := NewBalanceSimulator(newBalanceSpec(), newBalanceStyles()[:2]...)
+		sim.Players, sim.Steps, sim.Workers, sim.Seed = 40, 50, workers, 9
+
+		report, err := sim.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return report
+	}
+
+	if a, b := run(1), run(4); !reflect.DeepEqual(a, b) {
+		t.Error("reports differ between 1 and 4 workers")
+	}
+
+	if _, err := NewBalanceSimulator(newBalanceSpec()).Run(); !errors.Is(err, ErrBalanceConfig) {
+		t.Errorf("no styles = %v", err)
+	}
+}
+
+func TestBalanceSearch(t *testing.T) {
+	sim := NewBalanceSimulator(newBalanceSpec(), newBalanceStyles()[0])
+	sim.Players, sim.Steps = 40, 150
+
+	search := BalanceSearch{
+		Simulator:  sim,
+		Knobs:      []BalanceKnob{XPBaseKnob(50, 400), XPScalingKnob(1.05, 1.6), UpgradeCostKnob("trinket", 10, 100)},
+		Targets:    []BalanceTarget{TimeToLevelTarget("casual", 5, 60, 3), InflationTarget("casual", 0.2, 0.05)},
+		Iterations: 40,
+		Seed:       3,
+	}
+
+	result, err := search.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.History) != 40 || result.Best.Loss > result.History[0].Loss {
+		t.Fatalf("best loss %.2f of %d candidates", result.Best.Loss, len(result.History))
+	}
+
+	if got := result.Best.Metrics["casual reaches L5"]; math.Abs(got-60) > 6 {
+		t.Errorf("best time to L5 = %.1f, want ~60 (values %v)", got, result.Best.Values)
+	}
+
+	if result.Spec.Experience.BaseToNext != int64(result.Best.Values["xp.base_to_next"]) ||
+		result.Spec.Upgrade("trinket").BaseCost != int64(result.Best.Values["trinket.base_cost"]) {
+		t.Errorf("spec doesn't match best values %v", result.Best.Values)
+	}
+
+	// The base spec is untouched.
+	if sim.Spec.Experience.BaseToNext != 100 || sim.Spec.Upgrade("trinket").BaseCost != 20 {
+		t.Error("search modified the base spec")
+	}
+}
@@ -19,28 +19,63 @@ type BTContext struct {
 	World  *ecs.World
 	Entity ecs.Entity
 	Data   map[string]any // Blackboard for sharing data
+
+	// Blackboard is the typed view of Data with an optional team parent.
+	Blackboard *Blackboard
+
+	DeltaTime float64 // Seconds since the previous tick
+	Time      float64 // Seconds since the tree started ticking
 }
 
 // NewBTContext creates a new behavior tree context.
 func NewBTContext(world *ecs.World, entity ecs.Entity) *BTContext {
+	return NewBTContextWithBlackboard(world, entity, NewBlackboard(nil))
+}
+
+// NewBTContextWithBlackboard creates a context backed by an existing blackboard.
+// Data and the blackboard's local values are the same map.
+func NewBTContextWithBlackboard(world *ecs.World, entity ecs.Entity, bb *Blackboard) *BTContext {
 	return &BTContext{
-		World:  world,
-		Entity: entity,
-		Data:   make(map[string]any),
+		World:      world,
+		Entity:     entity,
+		Data:       bb.values,
+		Blackboard: bb,
 	}
 }
 
+// Advance moves the context clock forward by dt seconds.
+func (c *BTContext) Advance(dt float64) {
+	c.DeltaTime = dt
+	c.Time += dt
+}
+
 // Node is the interface for all behavior tree nodes.
 type Node interface {
 	Tick(ctx *BTContext) NodeStatus
 }
 
+// Resetter is implemented by nodes that keep state between ticks.
+// Reset is called when a running node is interrupted so it starts fresh
+// the next time it is ticked.
+type Resetter interface {
+	Reset()
+}
+
+// ResetNode resets n if it keeps state.
+func ResetNode(n Node) {
+	if r, ok := n.(Resetter); ok {
+		r.Reset()
+	}
+}
+
 // ============================================================================
 // Composite Nodes
 // ============================================================================
 
 // Sequence runs children in order until one fails.
 // Returns Success if all succeed, Failure if any fails.
+// A Running child is resumed on the next tick without re-running earlier
+// children; see ReactiveSequence for the re-evaluating variant.
 type Sequence struct {
 	Children []Node
 	current  int
@@ -72,8 +107,18 @@ func (n *Sequence) Tick(ctx *BTContext) NodeStatus {
 	return Success
 }
 
+// Reset restarts the sequence from its first child.
+func (n *Sequence) Reset() {
+	n.current = 0
+	for _, c := range n.Children {
+		ResetNode(c)
+	}
+}
+
 // Selector runs children until one succeeds.
 // Returns Success if any succeeds, Failure if all fail.
+// A Running child is resumed on the next tick; see ReactiveSelector for the
+// variant that re-checks higher priority children every tick.
 type Selector struct {
 	Children []Node
 	current  int
@@ -105,6 +150,14 @@ func (n *Selector) Tick(ctx *BTContext) NodeStatus {
 	return Failure
 }
 
+// Reset restarts the selector from its first child.
+func (n *Selector) Reset() {
+	n.current = 0
+	for _, c := range n.Children {
+		ResetNode(c)
+	}
+}
+
 // ============================================================================
 // Decorator Nodes
 // ============================================================================
@@ -172,6 +225,12 @@ func (n *Repeater) Tick(ctx *BTContext) NodeStatus {
 	return Running
 }
 
+// Reset clears the repeat count.
+func (n *Repeater) Reset() {
+	n.count = 0
+	ResetNode(n.Child)
+}
+
 // ============================================================================
 // Leaf Nodes
 // ============================================================================
@@ -228,7 +287,9 @@ func (n *FailNode) Tick(ctx *BTContext) NodeStatus {
 
 // BehaviorTree wraps a root node for execution.
 type BehaviorTree struct {
-	Root Node
+	Root     Node
+	Name     string
+	Defaults map[string]any // Initial blackboard values, applied by BehaviorTreeSystem
 }
 
 // NewBehaviorTree creates a behavior tree with the given root.
@@ -240,3 +301,8 @@ func NewBehaviorTree(root Node) *BehaviorTree {
 func (bt *BehaviorTree) Tick(ctx *BTContext) NodeStatus {
 	return bt.Root.Tick(ctx)
 }
+
+// Reset interrupts any running nodes.
+func (bt *BehaviorTree) Reset() {
+	ResetNode(bt.Root)
+}
@@ -0,0 +1,130 @@
+package ai
+
+import "maps"
+
+// ============================================================================
+// Blackboard
+// ============================================================================
+
+// Blackboard stores values shared between behavior tree nodes.
+// Lookups that miss fall through to the parent, so an entity blackboard can
+// read its team's blackboard while writes stay local.
+type Blackboard struct {
+	values map[string]any
+	parent *Blackboard
+}
+
+// NewBlackboard creates a blackboard with an optional parent (e.g. a team).
+func NewBlackboard(parent *Blackboard) *Blackboard {
+	return &Blackboard{values: make(map[string]any), parent: parent}
+}
+
+// Parent returns the parent blackboard, or nil.
+func (b *Blackboard) Parent() *Blackboard {
+	return b.parent
+}
+
+// SetParent changes the parent blackboard.
+func (b *Blackboard) SetParent(parent *Blackboard) {
+	b.parent = parent
+}
+
+// Get returns a value, searching parents if it is not set locally.
+func (b *Blackboard) Get(key string) (any, bool) {
+	for bb := b; bb != nil; bb = bb.parent {
+		if v, ok := bb.values[key]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// Set stores a value on this blackboard.
+func (b *Blackboard) Set(key string, value any) {
+	b.values[key] = value
+}
+
+// Has returns true if the key is set here or on a parent.
+func (b *Blackboard) Has(key string) bool {
+	_, ok := b.Get(key)
+
+	return ok
+}
+
+// Delete removes a local value.
+func (b *Blackboard) Delete(key string) {
+	delete(b.values, key)
+}
+
+// Clear removes all local values.
+func (b *Blackboard) Clear() {
+	clear(b.values)
+}
+
+// Snapshot returns a copy of the visible values, local values taking
+// precedence over parents.
+func (b *Blackboard) Snapshot() map[string]any {
+	var chain []*Blackboard
+	for bb := b; bb != nil; bb = bb.parent {
+		chain = append(chain, bb)
+	}
+
+	out := make(map[string]any)
+	for i := len(chain) - 1; i >= 0; i-- {
+		maps.Copy(out, chain[i].values)
+	}
+
+	return out
+}
+
+// ============================================================================
+// Typed Keys
+// ============================================================================
+
+// BBKey is a typed blackboard key.
+//
+//	var Target = ai.NewBBKey[ecs.Entity]("target")
+//	Target.Set(ctx.Blackboard, enemy)
+//	e, ok := Target.Get(ctx.Blackboard)
+type BBKey[T any] struct {
+	Name string
+}
+
+// NewBBKey creates a typed key.
+func NewBBKey[T any](name string) BBKey[T] {
+	return BBKey[T]{Name: name}
+}
+
+// Get returns the value if it is set and has type T.
+func (k BBKey[T]) Get(b *Blackboard) (T, bool) {
+	v, ok := b.Get(k.Name)
+	if !ok {
+		var zero T
+
+		return zero, false
+	}
+
+	t, ok := v.(T)
+
+	return t, ok
+}
+
+// GetOr returns the value or fallback if it is unset or the wrong type.
+func (k BBKey[T]) GetOr(b *Blackboard, fallback T) T {
+	if v, ok := k.Get(b); ok {
+		return v
+	}
+
+	return fallback
+}
+
+// Set stores the value on the blackboard.
+func (k BBKey[T]) Set(b *Blackboard, value T) {
+	b.Set(k.Name, value)
+}
+
+// Delete removes the local value.
+func (k BBKey[T]) Delete(b *Blackboard) {
+	b.Delete(k.Name)
+}
@@ -0,0 +1,320 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Tree Definitions
+// ============================================================================
+
+// TreeDefinition describes a behavior tree in data.
+//
+//	name: guard
+//	blackboard: {alert: false}
+//	root:
+//	  type: reactive_selector
+//	  children:
+//	    - type: sequence
+//	      children:
+//	        - {type: bb_check, params: {key: alert, op: "==", value: true}}
+//	        - {type: attack}
+//	    - {type: patrol}
+type TreeDefinition struct {
+	Name       string          `json:"name"                 yaml:"name"`
+	Blackboard map[string]any  `json:"blackboard,omitempty" yaml:"blackboard,omitempty"` // Initial values
+	Root       *NodeDefinition `json:"root"                 yaml:"root"`
+}
+
+// NodeDefinition describes one node and its children.
+type NodeDefinition struct {
+	Type     string            `json:"type"               yaml:"type"`
+	Name     string            `json:"name,omitempty"     yaml:"name,omitempty"`
+	Params   NodeParams        `json:"params,omitempty"   yaml:"params,omitempty"`
+	Children []*NodeDefinition `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// NodeParams holds node parameters decoded from JSON or YAML.
+type NodeParams map[string]any
+
+// Float returns a numeric parameter or fallback.
+func (p NodeParams) Float(key string, fallback float64) float64 {
+	if v, ok := toFloat(p[key]); ok {
+		return v
+	}
+
+	return fallback
+}
+
+// Int returns an integer parameter or fallback.
+func (p NodeParams) Int(key string, fallback int) int {
+	if v, ok := toFloat(p[key]); ok {
+		return int(v)
+	}
+
+	return fallback
+}
+
+// String returns a string parameter or fallback.
+func (p NodeParams) String(key, fallback string) string {
+	if v, ok := p[key].(string); ok {
+		return v
+	}
+
+	return fallback
+}
+
+// Bool returns a boolean parameter or fallback.
+func (p NodeParams) Bool(key string, fallback bool) bool {
+	if v, ok := p[key].(bool); ok {
+		return v
+	}
+
+	return fallback
+}
+
+// ParseTreeJSON decodes a JSON tree definition.
+func ParseTreeJSON(data []byte) (*TreeDefinition, error) {
+	var def TreeDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("parse behavior tree: %w", err)
+	}
+
+	return &def, nil
+}
+
+// ParseTreeYAML decodes a YAML tree definition.
+func ParseTreeYAML(data []byte) (*TreeDefinition, error) {
+	var def TreeDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("parse behavior tree: %w", err)
+	}
+
+	return &def, nil
+}
+
+// LoadTreeFile reads a tree definition, choosing the format by extension
+// (.yaml/.yml or .json).
+func LoadTreeFile(path string) (*TreeDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseTreeYAML(data)
+	default:
+		return ParseTreeJSON(data)
+	}
+}
+
+// ============================================================================
+// Node Registry
+// ============================================================================
+
+// NodeFactory creates a node from its parameters and already built children.
+type NodeFactory func(params NodeParams, children []Node) (Node, error)
+
+// NodeRegistry maps node type names to factories.
+type NodeRegistry struct {
+	factories map[string]NodeFactory
+}
+
+// NewNodeRegistry creates a registry with the built-in node types:
+//
+//	sequence, selector, reactive_sequence, reactive_selector,
+//	parallel {success, failure}, inverter, repeater {times},
+//	retry {attempts}, until_fail, cooldown {seconds}, timeout {seconds},
+//	wait {seconds}, succeed, fail, bb_check {key, op, value}, bb_set {key, value}
+func NewNodeRegistry() *NodeRegistry {
+	r := &NodeRegistry{factories: make(map[string]NodeFactory)}
+
+	r.Register("sequence", func(_ NodeParams, c []Node) (Node, error) {
+		return NewSequence(c...), nil
+	})
+	r.Register("selector", func(_ NodeParams, c []Node) (Node, error) {
+		return NewSelector(c...), nil
+	})
+	r.Register("reactive_sequence", func(_ NodeParams, c []Node) (Node, error) {
+		return NewReactiveSequence(c...), nil
+	})
+	r.Register("reactive_selector", func(_ NodeParams, c []Node) (Node, error) {
+		return NewReactiveSelector(c...), nil
+	})
+	r.Register("parallel", func(p NodeParams, c []Node) (Node, error) {
+		n := NewParallel(c...)
+		n.SuccessThreshold = p.Int("success", 0)
+		n.FailureThreshold = p.Int("failure", 1)
+
+		return n, nil
+	})
+	r.Register("inverter", decorator(func(_ NodeParams, c Node) Node { return NewInverter(c) }))
+	r.Register("repeater", decorator(func(p NodeParams, c Node) Node {
+		return NewRepeater(c, p.Int("times", 1))
+	}))
+	r.Register("retry", decorator(func(p NodeParams, c Node) Node {
+		return NewRetry(c, p.Int("attempts", 3))
+	}))
+	r.Register("until_fail", decorator(func(_ NodeParams, c Node) Node { return NewUntilFail(c) }))
+	r.Register("cooldown", decorator(func(p NodeParams, c Node) Node {
+		return NewCooldown(c, p.Float("seconds", 1))
+	}))
+	r.Register("timeout", decorator(func(p NodeParams, c Node) Node {
+		return NewTimeout(c, p.Float("seconds", 1))
+	}))
+	r.Register("wait", leaf(func(p NodeParams) (Node, error) {
+		return NewWait(p.Float("seconds", 1)), nil
+	}))
+	r.Register("succeed", leaf(func(NodeParams) (Node, error) { return &SucceedNode{}, nil }))
+	r.Register("fail", leaf(func(NodeParams) (Node, error) { return &FailNode{}, nil }))
+	r.Register("bb_check", leaf(func(p NodeParams) (Node, error) {
+		key, op := p.String("key", ""), p.String("op", "exists")
+		if key == "" {
+			return nil, errors.New("missing key")
+		}
+
+		if err := validOp(op); err != nil {
+			return nil, err
+		}
+
+		return NewBlackboardCondition(key, op, p["value"]), nil
+	}))
+	r.Register("bb_set", leaf(func(p NodeParams) (Node, error) {
+		key := p.String("key", "")
+		if key == "" {
+			return nil, errors.New("missing key")
+		}
+
+		return NewBlackboardSet(key, p["value"]), nil
+	}))
+
+	return r
+}
+
+// Register adds or replaces a node type.
+func (r *NodeRegistry) Register(typ string, factory NodeFactory) {
+	r.factories[typ] = factory
+}
+
+// RegisterAction registers a leaf node type that runs fn.
+func (r *NodeRegistry) RegisterAction(typ string, fn func(ctx *BTContext) NodeStatus) {
+	r.Register(typ, leaf(func(NodeParams) (Node, error) { return NewActionNode(fn), nil }))
+}
+
+// RegisterCondition registers a leaf node type that checks fn.
+func (r *NodeRegistry) RegisterCondition(typ string, fn func(ctx *BTContext) bool) {
+	r.Register(typ, leaf(func(NodeParams) (Node, error) { return NewCondition(fn), nil }))
+}
+
+// Has returns true if the type is registered.
+func (r *NodeRegistry) Has(typ string) bool {
+	_, ok := r.factories[typ]
+
+	return ok
+}
+
+// Types returns the registered type names, sorted.
+func (r *NodeRegistry) Types() []string {
+	return slices.Sorted(maps.Keys(r.factories))
+}
+
+// Build creates a new tree instance from a definition. Nodes keep running
+// state, so build one tree per entity rather than sharing instances.
+func (r *NodeRegistry) Build(def *TreeDefinition) (*BehaviorTree, error) {
+	if def == nil || def.Root == nil {
+		return nil, fmt.Errorf("behavior tree %q: missing root", treeName(def))
+	}
+
+	root, err := r.buildNode(def.Root, "root")
+	if err != nil {
+		return nil, fmt.Errorf("behavior tree %q: %w", def.Name, err)
+	}
+
+	tree := NewBehaviorTree(root)
+	tree.Name = def.Name
+	tree.Defaults = maps.Clone(def.Blackboard)
+
+	return tree, nil
+}
+
+// Validate checks that a definition builds without keeping the result.
+func (r *NodeRegistry) Validate(def *TreeDefinition) error {
+	_, err := r.Build(def)
+
+	return err
+}
+
+// buildNode builds a node and its children recursively.
+func (r *NodeRegistry) buildNode(def *NodeDefinition, path string) (Node, error) {
+	if def == nil {
+		return nil, fmt.Errorf("%s: empty node", path)
+	}
+
+	factory, ok := r.factories[def.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown node type %q", path, def.Type)
+	}
+
+	children := make([]Node, 0, len(def.Children))
+
+	for i, c := range def.Children {
+		child, err := r.buildNode(c, fmt.Sprintf("%s.children[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, child)
+	}
+
+	params := def.Params
+	if params == nil {
+		params = NodeParams{}
+	}
+
+	node, err := factory(params, children)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", path, def.Type, err)
+	}
+
+	return node, nil
+}
+
+// decorator wraps a single-child constructor as a factory.
+func decorator(build func(p NodeParams, child Node) Node) NodeFactory {
+	return func(p NodeParams, children []Node) (Node, error) {
+		if len(children) != 1 {
+			return nil, fmt.Errorf("needs exactly 1 child, got %d", len(children))
+		}
+
+		return build(p, children[0]), nil
+	}
+}
+
+// leaf wraps a childless constructor as a factory.
+func leaf(build func(p NodeParams) (Node, error)) NodeFactory {
+	return func(p NodeParams, children []Node) (Node, error) {
+		if len(children) != 0 {
+			return nil, errors.New("leaf node cannot have children")
+		}
+
+		return build(p)
+	}
+}
+
+func treeName(def *TreeDefinition) string {
+	if def == nil {
+		return ""
+	}
+
+	return def.Name
+}
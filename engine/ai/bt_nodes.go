@@ -0,0 +1,496 @@
+package ai
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ============================================================================
+// Reactive Composites
+// ============================================================================
+
+// ReactiveSequence re-evaluates all children from the first every tick, so a
+// condition earlier in the sequence can abort a running action. The aborted
+// child is reset.
+type ReactiveSequence struct {
+	Children []Node
+	running  int
+}
+
+// NewReactiveSequence creates a reactive sequence node.
+func NewReactiveSequence(children ...Node) *ReactiveSequence {
+	return &ReactiveSequence{Children: children, running: -1}
+}
+
+func (n *ReactiveSequence) Tick(ctx *BTContext) NodeStatus {
+	for i, child := range n.Children {
+		status := child.Tick(ctx)
+		if status == Success {
+			continue
+		}
+
+		n.interrupt(i)
+
+		if status == Running {
+			n.running = i
+		}
+
+		return status
+	}
+
+	n.interrupt(len(n.Children))
+
+	return Success
+}
+
+// interrupt resets the previously running child if it is not index i.
+func (n *ReactiveSequence) interrupt(i int) {
+	if n.running >= 0 && n.running != i {
+		ResetNode(n.Children[n.running])
+	}
+
+	n.running = -1
+}
+
+// Reset resets all children.
+func (n *ReactiveSequence) Reset() {
+	n.running = -1
+	for _, c := range n.Children {
+		ResetNode(c)
+	}
+}
+
+// ReactiveSelector re-evaluates children in priority order every tick. When a
+// higher priority child succeeds or starts running, the lower priority
+// running child is reset.
+type ReactiveSelector struct {
+	Children []Node
+	running  int
+}
+
+// NewReactiveSelector creates a reactive selector node.
+func NewReactiveSelector(children ...Node) *ReactiveSelector {
+	return &ReactiveSelector{Children: children, running: -1}
+}
+
+func (n *ReactiveSelector) Tick(ctx *BTContext) NodeStatus {
+	for i, child := range n.Children {
+		status := child.Tick(ctx)
+		if status == Failure {
+			continue
+		}
+
+		if n.running >= 0 && n.running != i {
+			ResetNode(n.Children[n.running])
+		}
+
+		n.running = -1
+		if status == Running {
+			n.running = i
+		}
+
+		return status
+	}
+
+	n.running = -1
+
+	return Failure
+}
+
+// Reset resets all children.
+func (n *ReactiveSelector) Reset() {
+	n.running = -1
+	for _, c := range n.Children {
+		ResetNode(c)
+	}
+}
+
+// Parallel ticks all unfinished children every tick.
+// It succeeds once SuccessThreshold children succeed and fails once
+// FailureThreshold children fail. A threshold of 0 means all children.
+// Children still running when the node finishes are reset.
+type Parallel struct {
+	Children         []Node
+	SuccessThreshold int
+	FailureThreshold int
+	results          []NodeStatus
+}
+
+// NewParallel creates a parallel node that succeeds when all children succeed
+// and fails as soon as one fails.
+func NewParallel(children ...Node) *Parallel {
+	return &Parallel{Children: children, FailureThreshold: 1}
+}
+
+func (n *Parallel) Tick(ctx *BTContext) NodeStatus {
+	if len(n.results) != len(n.Children) {
+		n.results = make([]NodeStatus, len(n.Children))
+	}
+
+	successes, failures := 0, 0
+
+	for i, child := range n.Children {
+		if n.results[i] == Running {
+			n.results[i] = child.Tick(ctx)
+		}
+
+		switch n.results[i] {
+		case Success:
+			successes++
+		case Failure:
+			failures++
+		case Running:
+		}
+	}
+
+	needSuccess := n.SuccessThreshold
+	if needSuccess <= 0 {
+		needSuccess = len(n.Children)
+	}
+
+	needFailure := n.FailureThreshold
+	if needFailure <= 0 {
+		needFailure = len(n.Children)
+	}
+
+	switch {
+	case successes >= needSuccess:
+		n.Reset()
+
+		return Success
+	case failures >= needFailure:
+		n.Reset()
+
+		return Failure
+	case successes+failures == len(n.Children):
+		// Every child finished without meeting either threshold
+		n.Reset()
+
+		return Failure
+	}
+
+	return Running
+}
+
+// Reset resets unfinished children and clears results.
+func (n *Parallel) Reset() {
+	for i, c := range n.Children {
+		if i >= len(n.results) || n.results[i] == Running {
+			ResetNode(c)
+		}
+	}
+
+	n.results = nil
+}
+
+// ============================================================================
+// Decorators
+// ============================================================================
+
+// Cooldown fails without ticking its child until Duration seconds have
+// passed since the child last finished. Time is read from BTContext.Time.
+type Cooldown struct {
+	Child    Node
+	Duration float64
+	readyAt  float64
+	running  bool
+}
+
+// NewCooldown creates a cooldown decorator.
+func NewCooldown(child Node, seconds float64) *Cooldown {
+	return &Cooldown{Child: child, Duration: seconds}
+}
+
+func (n *Cooldown) Tick(ctx *BTContext) NodeStatus {
+	if !n.running && ctx.Time < n.readyAt {
+		return Failure
+	}
+
+	status := n.Child.Tick(ctx)
+
+	n.running = status == Running
+	if !n.running {
+		n.readyAt = ctx.Time + n.Duration
+	}
+
+	return status
+}
+
+// Ready returns true if the child may run at time t.
+func (n *Cooldown) Ready(t float64) bool {
+	return n.running || t >= n.readyAt
+}
+
+// Reset interrupts the child. The cooldown timer is kept.
+func (n *Cooldown) Reset() {
+	n.running = false
+	ResetNode(n.Child)
+}
+
+// Timeout fails and resets its child if it runs longer than Duration seconds.
+type Timeout struct {
+	Child    Node
+	Duration float64
+	started  float64
+	running  bool
+}
+
+// NewTimeout creates a timeout decorator.
+func NewTimeout(child Node, seconds float64) *Timeout {
+	return &Timeout{Child: child, Duration: seconds}
+}
+
+func (n *Timeout) Tick(ctx *BTContext) NodeStatus {
+	if !n.running {
+		n.started = ctx.Time
+		n.running = true
+	}
+
+	status := n.Child.Tick(ctx)
+	if status != Running {
+		n.running = false
+
+		return status
+	}
+
+	if ctx.Time-n.started >= n.Duration {
+		n.Reset()
+
+		return Failure
+	}
+
+	return Running
+}
+
+// Reset interrupts the child.
+func (n *Timeout) Reset() {
+	n.running = false
+	ResetNode(n.Child)
+}
+
+// Retry re-runs a failing child up to Attempts times in total.
+// Each retry happens on the following tick.
+type Retry struct {
+	Child    Node
+	Attempts int
+	failures int
+}
+
+// NewRetry creates a retry decorator.
+func NewRetry(child Node, attempts int) *Retry {
+	return &Retry{Child: child, Attempts: attempts}
+}
+
+func (n *Retry) Tick(ctx *BTContext) NodeStatus {
+	status := n.Child.Tick(ctx)
+
+	switch status {
+	case Success:
+		n.failures = 0
+	case Failure:
+		n.failures++
+		if n.failures < n.Attempts {
+			ResetNode(n.Child)
+
+			return Running
+		}
+
+		n.failures = 0
+	case Running:
+	}
+
+	return status
+}
+
+// Reset clears the failure count.
+func (n *Retry) Reset() {
+	n.failures = 0
+	ResetNode(n.Child)
+}
+
+// UntilFail runs its child repeatedly until it fails, then succeeds.
+type UntilFail struct {
+	Child Node
+}
+
+// NewUntilFail creates an until-fail decorator.
+func NewUntilFail(child Node) *UntilFail {
+	return &UntilFail{Child: child}
+}
+
+func (n *UntilFail) Tick(ctx *BTContext) NodeStatus {
+	if n.Child.Tick(ctx) == Failure {
+		return Success
+	}
+
+	return Running
+}
+
+// Reset resets the child.
+func (n *UntilFail) Reset() {
+	ResetNode(n.Child)
+}
+
+// ============================================================================
+// Leaves
+// ============================================================================
+
+// Wait returns Running for Duration seconds, then Success.
+type Wait struct {
+	Duration float64
+	started  float64
+	running  bool
+}
+
+// NewWait creates a wait node.
+func NewWait(seconds float64) *Wait {
+	return &Wait{Duration: seconds}
+}
+
+func (n *Wait) Tick(ctx *BTContext) NodeStatus {
+	if !n.running {
+		n.started = ctx.Time
+		n.running = true
+	}
+
+	if ctx.Time-n.started >= n.Duration {
+		n.running = false
+
+		return Success
+	}
+
+	return Running
+}
+
+// Reset restarts the wait.
+func (n *Wait) Reset() {
+	n.running = false
+}
+
+// BlackboardCondition compares a blackboard value.
+// Op is one of "exists", "==", "!=", "<", "<=", ">", ">=". Numbers of any
+// type are compared as float64.
+type BlackboardCondition struct {
+	Key   string
+	Op    string
+	Value any
+}
+
+// NewBlackboardCondition creates a blackboard comparison node.
+func NewBlackboardCondition(key, op string, value any) *BlackboardCondition {
+	return &BlackboardCondition{Key: key, Op: op, Value: value}
+}
+
+func (n *BlackboardCondition) Tick(ctx *BTContext) NodeStatus {
+	v, ok := ctx.Blackboard.Get(n.Key)
+
+	if n.Op == "exists" {
+		return statusOf(ok)
+	}
+
+	if !ok {
+		return statusOf(n.Op == "!=")
+	}
+
+	a, aNum := toFloat(v)
+	b, bNum := toFloat(n.Value)
+
+	switch n.Op {
+	case "==":
+		if aNum && bNum {
+			return statusOf(a == b)
+		}
+
+		return statusOf(reflect.DeepEqual(v, n.Value))
+	case "!=":
+		if aNum && bNum {
+			return statusOf(a != b)
+		}
+
+		return statusOf(!reflect.DeepEqual(v, n.Value))
+	}
+
+	if !aNum || !bNum {
+		return Failure
+	}
+
+	switch n.Op {
+	case "<":
+		return statusOf(a < b)
+	case "<=":
+		return statusOf(a <= b)
+	case ">":
+		return statusOf(a > b)
+	case ">=":
+		return statusOf(a >= b)
+	}
+
+	return Failure
+}
+
+// BlackboardSet writes a value to the blackboard and succeeds.
+type BlackboardSet struct {
+	Key   string
+	Value any
+}
+
+// NewBlackboardSet creates a blackboard write node.
+func NewBlackboardSet(key string, value any) *BlackboardSet {
+	return &BlackboardSet{Key: key, Value: value}
+}
+
+func (n *BlackboardSet) Tick(ctx *BTContext) NodeStatus {
+	ctx.Blackboard.Set(n.Key, n.Value)
+
+	return Success
+}
+
+func statusOf(ok bool) NodeStatus {
+	if ok {
+		return Success
+	}
+
+	return Failure
+}
+
+// toFloat converts numeric values to float64.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+// validOp reports whether op is a supported BlackboardCondition operator.
+func validOp(op string) error {
+	switch op {
+	case "exists", "==", "!=", "<", "<=", ">", ">=":
+		return nil
+	}
+
+	return fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,131 @@
+package ai
+
+import "github.com/mlange-42/ark/ecs"
+
+// ============================================================================
+// Behavior Tree Component and System
+// ============================================================================
+
+// BehaviorTreeComponent attaches a behavior tree to an entity.
+// Each entity needs its own tree instance; build one per entity with
+// NodeRegistry.Build.
+type BehaviorTreeComponent struct {
+	Tree     *BehaviorTree
+	Team     string     // Entities with the same team share a parent blackboard
+	Interval float64    // Seconds between ticks (0 = every update)
+	Paused   bool       // Skip ticking while true
+	Status   NodeStatus // Result of the last tick
+
+	ctx     *BTContext
+	sinceAt float64
+}
+
+// NewBehaviorTreeComponent creates a tree component ticked every update.
+func NewBehaviorTreeComponent(tree *BehaviorTree, team string) BehaviorTreeComponent {
+	return BehaviorTreeComponent{Tree: tree, Team: team}
+}
+
+// Context returns the entity's tree context, or nil before the first update.
+func (c *BehaviorTreeComponent) Context() *BTContext {
+	return c.ctx
+}
+
+// Blackboard returns the entity's blackboard, or nil before the first update.
+func (c *BehaviorTreeComponent) Blackboard() *Blackboard {
+	if c.ctx == nil {
+		return nil
+	}
+
+	return c.ctx.Blackboard
+}
+
+// BehaviorTreeSystem ticks the trees of all entities with a
+// BehaviorTreeComponent. Each entity gets a blackboard whose parent is its
+// team blackboard, so team members can share targets and alerts.
+//
+// Trees are ticked after the query finishes, so actions may create or remove
+// entities.
+type BehaviorTreeSystem struct {
+	filter  *ecs.Filter1[BehaviorTreeComponent]
+	treeMap *ecs.Map[BehaviorTreeComponent]
+	teams   map[string]*Blackboard
+	pending []ecs.Entity
+}
+
+// NewBehaviorTreeSystem creates a behavior tree system.
+func NewBehaviorTreeSystem(world *ecs.World) *BehaviorTreeSystem {
+	return &BehaviorTreeSystem{
+		filter:  ecs.NewFilter1[BehaviorTreeComponent](world),
+		treeMap: ecs.NewMap[BehaviorTreeComponent](world),
+		teams:   make(map[string]*Blackboard),
+	}
+}
+
+// TeamBlackboard returns the shared blackboard for a team, creating it if needed.
+func (s *BehaviorTreeSystem) TeamBlackboard(team string) *Blackboard {
+	bb, ok := s.teams[team]
+	if !ok {
+		bb = NewBlackboard(nil)
+		s.teams[team] = bb
+	}
+
+	return bb
+}
+
+// Update advances and ticks every tree.
+func (s *BehaviorTreeSystem) Update(world *ecs.World, dt float64) {
+	s.pending = s.pending[:0]
+
+	query := s.filter.Query()
+	for query.Next() {
+		s.pending = append(s.pending, query.Entity())
+	}
+
+	for _, e := range s.pending {
+		if !world.Alive(e) || !s.treeMap.Has(e) {
+			continue
+		}
+
+		c := s.treeMap.Get(e)
+		if c.Tree == nil || c.Paused {
+			continue
+		}
+
+		ctx := s.context(world, e, c)
+		ctx.Advance(dt)
+
+		c.sinceAt += dt
+		if c.Interval > 0 && c.sinceAt < c.Interval && ctx.Time > dt {
+			continue
+		}
+
+		// Expose the time since this tree last ran to its nodes
+		ctx.DeltaTime = c.sinceAt
+		c.sinceAt = 0
+		c.Status = c.Tree.Tick(ctx)
+	}
+}
+
+// context returns the entity's context, creating it on first use and keeping
+// its team parent in sync.
+func (s *BehaviorTreeSystem) context(world *ecs.World, e ecs.Entity, c *BehaviorTreeComponent) *BTContext {
+	var team *Blackboard
+	if c.Team != "" {
+		team = s.TeamBlackboard(c.Team)
+	}
+
+	if c.ctx == nil {
+		bb := NewBlackboard(team)
+		for k, v := range c.Tree.Defaults {
+			bb.Set(k, v)
+		}
+
+		c.ctx = NewBTContextWithBlackboard(world, e, bb)
+
+		return c.ctx
+	}
+
+	c.ctx.Blackboard.SetParent(team)
+
+	return c.ctx
+}
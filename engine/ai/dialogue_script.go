@@ -0,0 +1,1507 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Dialogue script errors.
+var (
+	ErrDialogueSyntax = errors.New("ai: dialogue syntax error")
+	ErrDialogueState  = errors.New("ai: invalid dialogue state")
+)
+
+// ============================================================================
+// Script Model
+// ============================================================================
+
+// DialogueScript is a set of dialogue nodes parsed from a Yarn-style text
+// format, so writers can author branching dialogue without Go closures:
+//
+//	title: Gate
+//	tags: entry
+//	---
+//	<<declare $gold = 0>>
+//	Guard: Halt! The toll is 5 gold. #line:gate_halt
+//	-> Pay the toll. <<if $gold >= 5>> #line:gate_pay
+//	    <<set $gold -= 5>>
+//	    <<jump Town>>
+//	-> Turn back. #line:gate_leave
+//	    Guard: Come back with coin, {$name}.
+//	    <<stop>>
+//	===
+//
+// Node bodies hold lines ("Speaker: text"), option groups ("->" with an
+// indented body), <<if>>/<<elseif>>/<<else>>/<<endif>>, <<set>>,
+// <<declare>>, <<jump>>, <<stop>> and any other <<command args>>. Trailing
+// #hashtags tag a line; #line:id gives it a localization ID. Expressions
+// use the invariant language with $-prefixed variables, which live in
+// NarrativeController variables without the $.
+type DialogueScript struct {
+	Nodes        map[string]*ScriptNode
+	Order        []string       // Node titles in source order
+	Declarations map[string]any // <<declare>> defaults by variable name
+}
+
+// ScriptNode is one titled node.
+type ScriptNode struct {
+	Title   string
+	Tags    []string
+	Headers map[string]string
+	Body    []ScriptStmt
+	Line    int // Source line of the title header
+}
+
+// HasTag reports whether the node carries tag.
+func (n *ScriptNode) HasTag(tag string) bool {
+	return slices.Contains(n.Tags, tag)
+}
+
+// ScriptStmtKind identifies a statement.
+type ScriptStmtKind int
+
+const (
+	StmtLine ScriptStmtKind = iota
+	StmtOptions
+	StmtIf
+	StmtSet
+	StmtJump
+	StmtStop
+	StmtCommand
+)
+
+// ScriptStmt is one statement of a node body. Which fields are used
+// depends on Kind.
+type ScriptStmt struct {
+	Kind     ScriptStmtKind
+	Line     int      // Source line
+	Speaker  string   // StmtLine
+	Text     string   // StmtLine, with {expr} interpolations
+	LineID   string   // StmtLine: from #line:id
+	Tags     []string // StmtLine, StmtCommand
+	Options  []ScriptOption
+	Branches []ScriptBranch
+	Var      string   // StmtSet: variable name without $
+	Op       string   // StmtSet: "=", "+=", "-=", "*=" or "/="
+	Expr     string   // StmtSet: value expression
+	Target   string   // StmtJump: node title
+	Command  string   // StmtCommand: name
+	Args     []string // StmtCommand: raw arguments
+
+	text dialogueText
+	expr invExpr
+	args []dialogueText
+}
+
+// ScriptOption is one choice of an option group.
+type ScriptOption struct {
+	Text      string
+	LineID    string
+	Tags      []string
+	Condition string // Empty when always available
+	Body      []ScriptStmt
+	Line      int
+
+	text dialogueText
+	cond invExpr
+}
+
+// ScriptBranch is one arm of an <<if>>; the <<else>> arm has no Condition.
+type ScriptBranch struct {
+	Condition string
+	Body      []ScriptStmt
+	Line      int
+
+	cond invExpr
+}
+
+// Strings returns the text of every line and option with a localization
+// ID, as a base table for translators.
+func (s *DialogueScript) Strings() map[string]string {
+	out := map[string]string{}
+
+	for _, title := range s.Order {
+		walkScript(s.Nodes[title].Body, func(st *ScriptStmt) {
+			if st.Kind == StmtLine && st.LineID != "" {
+				out[st.LineID] = st.Text
+			}
+
+			for _, opt := range st.Options {
+				if opt.LineID != "" {
+					out[opt.LineID] = opt.Text
+				}
+			}
+		})
+	}
+
+	return out
+}
+
+// walkScript calls fn for every statement, depth first.
+func walkScript(body []ScriptStmt, fn func(st *ScriptStmt)) {
+	for i := range body {
+		st := &body[i]
+		fn(st)
+
+		for _, opt := range st.Options {
+			walkScript(opt.Body, fn)
+		}
+
+		for _, br := range st.Branches {
+			walkScript(br.Body, fn)
+		}
+	}
+}
+
+// ============================================================================
+// Loading
+// ============================================================================
+
+// LoadDialogue parses and merges dialogue files. Node titles must be
+// unique across files.
+func LoadDialogue(paths ...string) (*DialogueScript, error) {
+	script := &DialogueScript{Nodes: map[string]*ScriptNode{}, Declarations: map[string]any{}}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		part, err := ParseDialogue(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, title := range part.Order {
+			if _, dup := script.Nodes[title]; dup {
+				return nil, fmt.Errorf("%s: %w: duplicate node %q", path, ErrDialogueSyntax, title)
+			}
+
+			script.Nodes[title] = part.Nodes[title]
+			script.Order = append(script.Order, title)
+		}
+
+		for name, v := range part.Declarations {
+			script.Declarations[name] = v
+		}
+	}
+
+	return script, nil
+}
+
+// ParseDialogue parses dialogue source into nodes.
+func ParseDialogue(src string) (*DialogueScript, error) {
+	script := &DialogueScript{Nodes: map[string]*ScriptNode{}, Declarations: map[string]any{}}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); {
+		text := strings.TrimSpace(lines[i])
+		if text == "" || strings.HasPrefix(text, "//") {
+			i++
+
+			continue
+		}
+
+		node := &ScriptNode{Headers: map[string]string{}, Line: i + 1}
+
+		// Headers up to "---".
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "---"; i++ {
+			text := strings.TrimSpace(lines[i])
+			if text == "" || strings.HasPrefix(text, "//") {
+				continue
+			}
+
+			key, value, ok := strings.Cut(text, ":")
+			if !ok {
+				return nil, dialogueErrorf(i+1, "expected header or \"---\", got %q", text)
+			}
+
+			node.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+
+		if i == len(lines) {
+			return nil, dialogueErrorf(node.Line, "node has no \"---\"")
+		}
+
+		node.Title = node.Headers["title"]
+		node.Tags = strings.Fields(node.Headers["tags"])
+
+		if node.Title == "" || strings.ContainsAny(node.Title, " \t") {
+			return nil, dialogueErrorf(node.Line, "node needs a one-word title, got %q", node.Title)
+		}
+
+		if _, dup := script.Nodes[node.Title]; dup {
+			return nil, dialogueErrorf(node.Line, "duplicate node %q", node.Title)
+		}
+
+		// Body up to "===".
+		i++
+		start := i
+
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "===" {
+			i++
+		}
+
+		if i == len(lines) {
+			return nil, dialogueErrorf(node.Line, "node %q has no \"===\"", node.Title)
+		}
+
+		p := &dialogueParser{script: script}
+		p.scan(lines[start:i], start+1)
+
+		body, err := p.parseBlock(0)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.pos < len(p.lines) {
+			return nil, dialogueErrorf(p.lines[p.pos].num, "unexpected %s", p.lines[p.pos].text)
+		}
+
+		node.Body = body
+		script.Nodes[node.Title] = node
+		script.Order = append(script.Order, node.Title)
+		i++
+	}
+
+	return script, nil
+}
+
+func dialogueErrorf(line int, format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrDialogueSyntax, line, fmt.Sprintf(format, args...))
+}
+
+// ============================================================================
+// Parser
+// ============================================================================
+
+type dialogueLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// dialogueParser parses one node body. Blocks are delimited by
+// indentation under options and by keywords under <<if>>.
+type dialogueParser struct {
+	script *DialogueScript
+	lines  []dialogueLine
+	pos    int
+}
+
+func (p *dialogueParser) scan(lines []string, first int) {
+	for i, raw := range lines {
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		indent := 0
+
+		for _, c := range raw {
+			if c == ' ' {
+				indent++
+			} else if c == '\t' {
+				indent += 4
+			} else {
+				break
+			}
+		}
+
+		p.lines = append(p.lines, dialogueLine{num: first + i, indent: indent, text: text})
+	}
+}
+
+// parseBlock parses statements indented at least indent, stopping before
+// <<elseif>>, <<else>> or <<endif>>.
+func (p *dialogueParser) parseBlock(indent int) ([]ScriptStmt, error) {
+	var body []ScriptStmt
+
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+
+		cmd, isCmd := dialogueCommand(l.text)
+		word, rest, _ := strings.Cut(cmd, " ")
+
+		if isCmd && (word == "elseif" || word == "else" || word == "endif") {
+			break
+		}
+
+		var (
+			st  ScriptStmt
+			err error
+		)
+
+		switch {
+		case strings.HasPrefix(l.text, "->"):
+			st, err = p.parseOptions(l.indent)
+		case isCmd && word == "if":
+			st, err = p.parseIf(l, rest)
+		case isCmd && word == "declare":
+			p.pos++
+			err = p.parseDeclare(l, rest)
+
+			if err == nil {
+				continue
+			}
+		case isCmd:
+			p.pos++
+			st, err = parseDialogueCommand(l, word, strings.TrimSpace(rest))
+		default:
+			p.pos++
+			st, err = parseDialogueLine(l)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = append(body, st)
+	}
+
+	return body, nil
+}
+
+// parseOptions parses consecutive options at one indent into a group.
+func (p *dialogueParser) parseOptions(indent int) (ScriptStmt, error) {
+	group := ScriptStmt{Kind: StmtOptions, Line: p.lines[p.pos].num}
+
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !strings.HasPrefix(l.text, "->") {
+			break
+		}
+
+		p.pos++
+
+		text, tags := splitDialogueTags(strings.TrimSpace(l.text[2:]))
+		opt := ScriptOption{Line: l.num}
+		opt.LineID, opt.Tags = lineIDFromTags(tags)
+
+		if i := strings.LastIndex(text, "<<if "); i >= 0 && strings.HasSuffix(text, ">>") {
+			opt.Condition = strings.TrimSpace(text[i+5 : len(text)-2])
+			text = strings.TrimSpace(text[:i])
+
+			cond, err := compileDialogueExpr(opt.Condition)
+			if err != nil {
+				return group, dialogueErrorf(l.num, "%v", err)
+			}
+
+			opt.cond = cond
+		}
+
+		if text == "" {
+			return group, dialogueErrorf(l.num, "option has no text")
+		}
+
+		compiled, err := parseDialogueText(text)
+		if err != nil {
+			return group, dialogueErrorf(l.num, "%v", err)
+		}
+
+		opt.Text, opt.text = text, compiled
+
+		if opt.Body, err = p.parseBlock(indent + 1); err != nil {
+			return group, err
+		}
+
+		group.Options = append(group.Options, opt)
+	}
+
+	return group, nil
+}
+
+// parseIf parses <<if>> through <<endif>>.
+func (p *dialogueParser) parseIf(start dialogueLine, cond string) (ScriptStmt, error) {
+	st := ScriptStmt{Kind: StmtIf, Line: start.num}
+	p.pos++
+
+	for {
+		br := ScriptBranch{Condition: strings.TrimSpace(cond), Line: p.lines[p.pos-1].num}
+
+		if br.Condition != "" {
+			expr, err := compileDialogueExpr(br.Condition)
+			if err != nil {
+				return st, dialogueErrorf(br.Line, "%v", err)
+			}
+
+			br.cond = expr
+		}
+
+		body, err := p.parseBlock(start.indent)
+		if err != nil {
+			return st, err
+		}
+
+		br.Body = body
+		st.Branches = append(st.Branches, br)
+
+		if p.pos == len(p.lines) || p.lines[p.pos].indent < start.indent {
+			return st, dialogueErrorf(start.num, "<<if>> has no <<endif>>")
+		}
+
+		l := p.lines[p.pos]
+		cmd, _ := dialogueCommand(l.text)
+		word, rest, _ := strings.Cut(cmd, " ")
+		p.pos++
+
+		switch {
+		case word == "endif":
+			return st, nil
+		case br.Condition == "":
+			return st, dialogueErrorf(l.num, "<<%s>> after <<else>>", word)
+		case word == "elseif" && strings.TrimSpace(rest) == "":
+			return st, dialogueErrorf(l.num, "<<elseif>> needs a condition")
+		case word == "else":
+			cond = ""
+		default:
+			cond = rest
+		}
+	}
+}
+
+func (p *dialogueParser) parseDeclare(l dialogueLine, rest string) error {
+	name, value, ok := strings.Cut(rest, "=")
+	name = strings.TrimSpace(name)
+
+	if !ok || !strings.HasPrefix(name, "$") {
+		return dialogueErrorf(l.num, "expected <<declare $name = value>>")
+	}
+
+	expr, err := compileDialogueExpr(value)
+	if err != nil {
+		return dialogueErrorf(l.num, "%v", err)
+	}
+
+	v, err := expr.eval(&invContext{values: map[string]any{}, set: &InvariantSet{}})
+	if err != nil {
+		return dialogueErrorf(l.num, "declare needs a constant: %v", err)
+	}
+
+	p.script.Declarations[name[1:]] = v
+
+	return nil
+}
+
+func parseDialogueCommand(l dialogueLine, word, rest string) (ScriptStmt, error) {
+	st := ScriptStmt{Line: l.num}
+
+	switch word {
+	case "set":
+		st.Kind = StmtSet
+
+		name, expr, op := "", "", ""
+
+		for _, candidate := range []string{"+=", "-=", "*=", "/=", "=", " to "} {
+			if before, after, ok := strings.Cut(rest, candidate); ok {
+				name, expr, op = strings.TrimSpace(before), after, strings.TrimSpace(candidate)
+
+				break
+			}
+		}
+
+		if !strings.HasPrefix(name, "$") || len(name) < 2 {
+			return st, dialogueErrorf(l.num, "expected <<set $name = value>>")
+		}
+
+		if op == "to" {
+			op = "="
+		}
+
+		compiled, err := compileDialogueExpr(expr)
+		if err != nil {
+			return st, dialogueErrorf(l.num, "%v", err)
+		}
+
+		st.Var, st.Op, st.Expr, st.expr = name[1:], op, strings.TrimSpace(expr), compiled
+	case "jump":
+		st.Kind, st.Target = StmtJump, rest
+
+		if rest == "" || strings.ContainsAny(rest, " \t") {
+			return st, dialogueErrorf(l.num, "expected <<jump Node>>")
+		}
+	case "stop":
+		st.Kind = StmtStop
+	default:
+		st.Kind, st.Command = StmtCommand, word
+
+		if word == "" {
+			return st, dialogueErrorf(l.num, "empty command")
+		}
+
+		args, err := splitDialogueArgs(rest)
+		if err != nil {
+			return st, dialogueErrorf(l.num, "%v", err)
+		}
+
+		st.Args = args
+
+		for _, arg := range args {
+			compiled, err := parseDialogueText(arg)
+			if err != nil {
+				return st, dialogueErrorf(l.num, "%v", err)
+			}
+
+			st.args = append(st.args, compiled)
+		}
+	}
+
+	return st, nil
+}
+
+func parseDialogueLine(l dialogueLine) (ScriptStmt, error) {
+	text, tags := splitDialogueTags(l.text)
+	st := ScriptStmt{Kind: StmtLine, Line: l.num}
+	st.LineID, st.Tags = lineIDFromTags(tags)
+
+	if i := strings.Index(text, ": "); i > 0 && !strings.ContainsAny(text[:i], "{}<>\"") {
+		st.Speaker, text = text[:i], strings.TrimSpace(text[i+2:])
+	}
+
+	compiled, err := parseDialogueText(text)
+	if err != nil {
+		return st, dialogueErrorf(l.num, "%v", err)
+	}
+
+	st.Text, st.text = text, compiled
+
+	return st, nil
+}
+
+// dialogueCommand returns the inside of a "<<...>>" line.
+func dialogueCommand(text string) (string, bool) {
+	if !strings.HasPrefix(text, "<<") || !strings.HasSuffix(text, ">>") || len(text) < 4 {
+		return "", false
+	}
+
+	return strings.TrimSpace(text[2 : len(text)-2]), true
+}
+
+// splitDialogueTags strips trailing #hashtags.
+func splitDialogueTags(s string) (string, []string) {
+	var tags []string
+
+	for {
+		i := strings.LastIndexAny(s, " \t")
+		if i < 0 || !strings.HasPrefix(s[i+1:], "#") {
+			return s, tags
+		}
+
+		tags = append([]string{s[i+2:]}, tags...)
+		s = strings.TrimSpace(s[:i])
+	}
+}
+
+// lineIDFromTags separates the line:id tag from the others.
+func lineIDFromTags(tags []string) (string, []string) {
+	var (
+		id   string
+		rest []string
+	)
+
+	for _, tag := range tags {
+		if v, ok := strings.CutPrefix(tag, "line:"); ok {
+			id = v
+		} else {
+			rest = append(rest, tag)
+		}
+	}
+
+	return id, rest
+}
+
+// splitDialogueArgs splits command arguments on spaces, keeping quoted
+// strings together.
+func splitDialogueArgs(s string) ([]string, error) {
+	var args []string
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+
+			args = append(args, s[1:end+1])
+			s = s[end+2:]
+
+			continue
+		}
+
+		arg, rest, _ := strings.Cut(s, " ")
+		args = append(args, arg)
+		s = rest
+	}
+
+	return args, nil
+}
+
+// ============================================================================
+// Expressions and Interpolation
+// ============================================================================
+
+// compileDialogueExpr parses an invariant expression after dropping the $
+// from variable names.
+func compileDialogueExpr(src string) (invExpr, error) {
+	var sb strings.Builder
+
+	quote := byte(0)
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '$':
+			continue
+		}
+
+		sb.WriteByte(c)
+	}
+
+	tokens, err := lexInvariant(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+
+	p := &invParser{tokens: tokens}
+
+	expr, err := p.parseExpr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected token")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", strings.TrimSpace(src), err)
+	}
+
+	return expr, nil
+}
+
+// dialogueText is text split into literal and {expr} segments.
+type dialogueText []dialogueSegment
+
+type dialogueSegment struct {
+	lit  string
+	expr invExpr
+}
+
+func parseDialogueText(s string) (dialogueText, error) {
+	var text dialogueText
+
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			text = append(text, dialogueSegment{lit: s})
+
+			break
+		}
+
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return nil, errors.New("unclosed {")
+		}
+
+		expr, err := compileDialogueExpr(s[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+
+		if open > 0 {
+			text = append(text, dialogueSegment{lit: s[:open]})
+		}
+
+		text = append(text, dialogueSegment{expr: expr})
+		s = s[open+end+1:]
+	}
+
+	return text, nil
+}
+
+func (t dialogueText) render(ctx *invContext) (string, error) {
+	var sb strings.Builder
+
+	for _, seg := range t {
+		if seg.expr == nil {
+			sb.WriteString(seg.lit)
+
+			continue
+		}
+
+		v, err := seg.expr.eval(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		if f, ok := v.(float64); ok {
+			sb.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		} else {
+			fmt.Fprint(&sb, v)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// idents lists the variables the text reads.
+func (t dialogueText) idents(out []string) []string {
+	for _, seg := range t {
+		if seg.expr != nil {
+			out = invIdents(seg.expr, out)
+		}
+	}
+
+	return out
+}
+
+// ============================================================================
+// Validation
+// ============================================================================
+
+// DialogueValidateOptions tunes Validate.
+type DialogueValidateOptions struct {
+	// Entries are the nodes the game starts; default: nodes tagged
+	// "entry", else the first node.
+	Entries []string
+	// Commands lists the commands the game handles; nil skips the check.
+	Commands []string
+	// Variables lists variables the game sets from code.
+	Variables []string
+	// RequireLineIDs reports lines and options without #line:id.
+	RequireLineIDs bool
+}
+
+// Issue severities.
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// DialogueIssue is one problem Validate found.
+type DialogueIssue struct {
+	Node     string
+	Line     int
+	Severity string
+	Message  string
+}
+
+func (i DialogueIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", i.Node, i.Line, i.Severity, i.Message)
+}
+
+// Validate reports jumps to missing nodes, unreachable nodes, dead ends
+// (nodes that can run off their end without <<jump>> or <<stop>>, unless
+// tagged "end"), unreachable statements, option groups that may offer
+// nothing, duplicate line IDs, unknown commands and variables that are
+// read but never set.
+func (s *DialogueScript) Validate(opts DialogueValidateOptions) []DialogueIssue {
+	v := &dialogueValidator{
+		script:  s,
+		opts:    opts,
+		lineIDs: map[string]string{},
+		written: map[string]bool{},
+	}
+
+	for name := range s.Declarations {
+		v.written[name] = true
+	}
+
+	for _, name := range opts.Variables {
+		v.written[name] = true
+	}
+
+	edges := map[string][]string{}
+
+	for _, title := range s.Order {
+		node := s.Nodes[title]
+		v.node = title
+
+		v.block(node.Body, func(target string) { edges[title] = append(edges[title], target) })
+
+		if !node.HasTag("end") && !dialogueTerminates(node.Body) {
+			v.add(node.Line, IssueWarning, "dead end: can reach the end of the node without <<jump>> or <<stop>>")
+		}
+	}
+
+	for _, r := range v.reads {
+		if !v.written[r.name] {
+			v.issues = append(v.issues, DialogueIssue{r.node, r.line, IssueWarning,
+				fmt.Sprintf("$%s is read but never set or declared", r.name)})
+		}
+	}
+
+	v.reachability(edges)
+
+	slices.SortStableFunc(v.issues, func(a, b DialogueIssue) int {
+		if c := slices.Index(s.Order, a.Node) - slices.Index(s.Order, b.Node); c != 0 {
+			return c
+		}
+
+		return a.Line - b.Line
+	})
+
+	return v.issues
+}
+
+type dialogueRead struct {
+	node, name string
+	line       int
+}
+
+type dialogueValidator struct {
+	script  *DialogueScript
+	opts    DialogueValidateOptions
+	node    string
+	issues  []DialogueIssue
+	lineIDs map[string]string // ID -> node
+	written map[string]bool
+	reads   []dialogueRead
+}
+
+func (v *dialogueValidator) add(line int, severity, format string, args ...any) {
+	v.issues = append(v.issues, DialogueIssue{v.node, line, severity, fmt.Sprintf(format, args...)})
+}
+
+func (v *dialogueValidator) read(line int, names []string) {
+	for _, name := range names {
+		v.reads = append(v.reads, dialogueRead{v.node, name, line})
+	}
+}
+
+func (v *dialogueValidator) lineID(id string, line int) {
+	switch prev, dup := v.lineIDs[id]; {
+	case id == "":
+		if v.opts.RequireLineIDs {
+			v.add(line, IssueWarning, "missing #line: ID")
+		}
+	case dup:
+		v.add(line, IssueError, "line ID %q is also used in %s", id, prev)
+	default:
+		v.lineIDs[id] = v.node
+	}
+}
+
+func (v *dialogueValidator) block(body []ScriptStmt, jump func(string)) {
+	for i := range body {
+		st := &body[i]
+
+		if i > 0 && dialogueStmtTerminates(&body[i-1]) {
+			v.add(st.Line, IssueWarning, "unreachable statement")
+		}
+
+		switch st.Kind {
+		case StmtLine:
+			v.lineID(st.LineID, st.Line)
+			v.read(st.Line, st.text.idents(nil))
+		case StmtOptions:
+			conditional := 0
+
+			for _, opt := range st.Options {
+				v.lineID(opt.LineID, opt.Line)
+				v.read(opt.Line, opt.text.idents(nil))
+
+				if opt.cond != nil {
+					conditional++
+					v.read(opt.Line, invIdents(opt.cond, nil))
+				}
+
+				v.block(opt.Body, jump)
+			}
+
+			if conditional == len(st.Options) {
+				v.add(st.Line, IssueWarning, "every option is conditional; the group may be skipped")
+			}
+		case StmtIf:
+			for _, br := range st.Branches {
+				if br.cond != nil {
+					v.read(br.Line, invIdents(br.cond, nil))
+				}
+
+				v.block(br.Body, jump)
+			}
+		case StmtSet:
+			v.written[st.Var] = true
+			if st.Op != "=" {
+				v.read(st.Line, []string{st.Var})
+			}
+
+			v.read(st.Line, invIdents(st.expr, nil))
+		case StmtJump:
+			if _, ok := v.script.Nodes[st.Target]; !ok {
+				v.add(st.Line, IssueError, "jump to unknown node %q", st.Target)
+			} else {
+				jump(st.Target)
+			}
+		case StmtCommand:
+			if v.opts.Commands != nil && !slices.Contains(v.opts.Commands, st.Command) {
+				v.add(st.Line, IssueWarning, "unknown command <<%s>>", st.Command)
+			}
+
+			for _, arg := range st.args {
+				v.read(st.Line, arg.idents(nil))
+			}
+		}
+	}
+}
+
+func (v *dialogueValidator) reachability(edges map[string][]string) {
+	roots := v.opts.Entries
+	if len(roots) == 0 {
+		for _, title := range v.script.Order {
+			if v.script.Nodes[title].HasTag("entry") {
+				roots = append(roots, title)
+			}
+		}
+	}
+
+	if len(roots) == 0 && len(v.script.Order) > 0 {
+		roots = v.script.Order[:1]
+	}
+
+	seen := map[string]bool{}
+	queue := []string{}
+
+	for _, root := range roots {
+		if _, ok := v.script.Nodes[root]; !ok {
+			v.issues = append(v.issues, DialogueIssue{root, 0, IssueError, "entry node does not exist"})
+
+			continue
+		}
+
+		seen[root] = true
+		queue = append(queue, root)
+	}
+
+	for len(queue) > 0 {
+		title := queue[0]
+		queue = queue[1:]
+
+		for _, next := range edges[title] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, title := range v.script.Order {
+		if !seen[title] {
+			v.node = title
+			v.add(v.script.Nodes[title].Line, IssueWarning, "unreachable node")
+		}
+	}
+}
+
+// dialogueTerminates reports whether every path through body ends in
+// <<jump>> or <<stop>>.
+func dialogueTerminates(body []ScriptStmt) bool {
+	for i := range body {
+		if dialogueStmtTerminates(&body[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func dialogueStmtTerminates(st *ScriptStmt) bool {
+	switch st.Kind {
+	case StmtJump, StmtStop:
+		return true
+	case StmtIf:
+		if st.Branches[len(st.Branches)-1].Condition != "" {
+			return false
+		}
+
+		for _, br := range st.Branches {
+			if !dialogueTerminates(br.Body) {
+				return false
+			}
+		}
+
+		return true
+	case StmtOptions:
+		// A group where every option is conditional may be skipped.
+		always := false
+
+		for _, opt := range st.Options {
+			if opt.Condition == "" {
+				always = true
+			}
+
+			if !dialogueTerminates(opt.Body) {
+				return false
+			}
+		}
+
+		return always
+	}
+
+	return false
+}
+
+// ============================================================================
+// Runtime
+// ============================================================================
+
+// DialogueEventKind identifies what Next produced.
+type DialogueEventKind int
+
+const (
+	DialogueEventLine DialogueEventKind = iota
+	DialogueEventOptions
+	DialogueEventCommand
+	DialogueEventEnd
+)
+
+// DialogueOption is an available choice; pass its Index to Choose.
+type DialogueOption struct {
+	Index  int
+	Text   string
+	LineID string
+	Tags   []string
+}
+
+// DialogueEvent is one step of a running dialogue.
+type DialogueEvent struct {
+	Kind    DialogueEventKind
+	Node    string
+	Speaker string
+	Text    string
+	LineID  string
+	Tags    []string
+	Options []DialogueOption
+	Command string
+	Args    []string
+}
+
+// DialogueRunner executes a DialogueScript against a NarrativeController's
+// variables. Call Start, then Next until it returns DialogueEventEnd,
+// calling Choose whenever it returns DialogueEventOptions.
+//
+//	runner := ai.NewDialogueRunner(script, narrative)
+//	runner.Commands["give"] = func(args []string) error { ... }
+//	runner.Start("Gate")
+//	for ev, err := runner.Next(); err == nil && ev.Kind != ai.DialogueEventEnd; ev, err = runner.Next() {
+//	    ...
+//	}
+type DialogueRunner struct {
+	Script    *DialogueScript
+	Narrative *NarrativeController
+	// Strings maps line IDs to translated text, which may use {$var}.
+	Strings map[string]string
+	// Commands handle <<command>> statements inline; others are returned
+	// from Next as DialogueEventCommand.
+	Commands map[string]func(args []string) error
+	// MaxSteps bounds statements run per Next, catching <<jump>> loops
+	// that never produce output (default 10000).
+	MaxSteps int
+	// OnNode is called whenever a node is entered by Start or <<jump>>.
+	OnNode func(node string)
+
+	node    string
+	frames  []DialogueFrame
+	choices []int // Available option indexes while waiting on Choose
+	done    bool
+}
+
+// DialogueFrame is a position in a node: Block is a path of (statement,
+// branch or option) index pairs from the node body, PC the next statement.
+type DialogueFrame struct {
+	Block []int `json:"block,omitempty"`
+	PC    int   `json:"pc"`
+}
+
+// DialogueState is a saved dialogue position with the narrative
+// variables; it round-trips through JSON.
+type DialogueState struct {
+	Node      string          `json:"node,omitempty"`
+	Frames    []DialogueFrame `json:"frames,omitempty"`
+	Choices   []int           `json:"choices,omitempty"`
+	Variables map[string]any  `json:"variables,omitempty"`
+	Done      bool            `json:"done,omitempty"`
+}
+
+// NewDialogueRunner creates a runner; it starts out done.
+func NewDialogueRunner(script *DialogueScript, narrative *NarrativeController) *DialogueRunner {
+	return &DialogueRunner{
+		Script:    script,
+		Narrative: narrative,
+		Commands:  map[string]func(args []string) error{},
+		MaxSteps:  10000,
+		done:      true,
+	}
+}
+
+// Start begins node, first applying declared defaults for variables that
+// have no value yet.
+func (r *DialogueRunner) Start(node string) error {
+	if _, ok := r.Script.Nodes[node]; !ok {
+		return fmt.Errorf("%w: unknown node %q", ErrDialogueState, node)
+	}
+
+	for name, v := range r.Script.Declarations {
+		if r.Narrative.GetVariable(name) == nil {
+			r.Narrative.SetVariable(name, v)
+		}
+	}
+
+	r.enter(node)
+
+	return nil
+}
+
+// Node returns the current node title.
+func (r *DialogueRunner) Node() string {
+	return r.node
+}
+
+// Done reports whether the dialogue has ended.
+func (r *DialogueRunner) Done() bool {
+	return r.done
+}
+
+func (r *DialogueRunner) enter(node string) {
+	r.node, r.frames, r.choices, r.done = node, []DialogueFrame{{}}, nil, false
+
+	if r.OnNode != nil {
+		r.OnNode(node)
+	}
+}
+
+func (r *DialogueRunner) end() DialogueEvent {
+	r.frames, r.choices, r.done = nil, nil, true
+
+	return DialogueEvent{Kind: DialogueEventEnd, Node: r.node}
+}
+
+// Next runs to the next line, option group, unhandled command or end.
+// While options are pending it returns them again.
+func (r *DialogueRunner) Next() (DialogueEvent, error) {
+	if r.done {
+		return DialogueEvent{Kind: DialogueEventEnd, Node: r.node}, nil
+	}
+
+	if r.choices != nil {
+		block, _ := r.block(r.frames[len(r.frames)-1].Block)
+
+		return r.optionsEvent(&block[r.frames[len(r.frames)-1].PC-1])
+	}
+
+	maxSteps := r.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 10000
+	}
+
+	for range maxSteps {
+		if len(r.frames) == 0 {
+			return r.end(), nil
+		}
+
+		f := &r.frames[len(r.frames)-1]
+
+		block, err := r.block(f.Block)
+		if err != nil {
+			return DialogueEvent{}, err
+		}
+
+		if f.PC >= len(block) {
+			r.frames = r.frames[:len(r.frames)-1]
+
+			continue
+		}
+
+		st := &block[f.PC]
+		f.PC++
+
+		switch st.Kind {
+		case StmtLine:
+			return r.lineEvent(st)
+		case StmtOptions:
+			ev, err := r.optionsEvent(st)
+			if err != nil || len(ev.Options) > 0 {
+				return ev, err
+			}
+
+			r.choices = nil
+		case StmtIf:
+			for i, br := range st.Branches {
+				ok := br.cond == nil
+
+				if !ok {
+					if ok, err = r.test(br.cond); err != nil {
+						return DialogueEvent{}, r.errorf(st.Line, err)
+					}
+				}
+
+				if ok {
+					r.push(f, i)
+
+					break
+				}
+			}
+		case StmtSet:
+			if err := r.set(st); err != nil {
+				return DialogueEvent{}, r.errorf(st.Line, err)
+			}
+		case StmtJump:
+			if _, ok := r.Script.Nodes[st.Target]; !ok {
+				return DialogueEvent{}, r.errorf(st.Line, fmt.Errorf("unknown node %q", st.Target))
+			}
+
+			r.enter(st.Target)
+		case StmtStop:
+			return r.end(), nil
+		case StmtCommand:
+			args := make([]string, len(st.args))
+			for i, arg := range st.args {
+				if args[i], err = arg.render(r.context()); err != nil {
+					return DialogueEvent{}, r.errorf(st.Line, err)
+				}
+			}
+
+			handler, ok := r.Commands[st.Command]
+			if !ok {
+				return DialogueEvent{Kind: DialogueEventCommand, Node: r.node, Command: st.Command, Args: args, Tags: st.Tags}, nil
+			}
+
+			if err := handler(args); err != nil {
+				return DialogueEvent{}, r.errorf(st.Line, err)
+			}
+		}
+	}
+
+	return DialogueEvent{}, fmt.Errorf("%w: %s: no output after %d statements", ErrDialogueState, r.node, maxSteps)
+}
+
+// Choose picks one of the pending options by DialogueOption.Index.
+func (r *DialogueRunner) Choose(index int) error {
+	if r.choices == nil {
+		return fmt.Errorf("%w: no options pending", ErrDialogueState)
+	}
+
+	if !slices.Contains(r.choices, index) {
+		return fmt.Errorf("%w: option %d is not available", ErrDialogueState, index)
+	}
+
+	r.push(&r.frames[len(r.frames)-1], index)
+	r.choices = nil
+
+	return nil
+}
+
+// push enters sub-block sub of the statement before f.PC.
+func (r *DialogueRunner) push(f *DialogueFrame, sub int) {
+	block := append(slices.Clone(f.Block), f.PC-1, sub)
+	r.frames = append(r.frames, DialogueFrame{Block: block})
+}
+
+// block resolves a frame path against the current node.
+func (r *DialogueRunner) block(path []int) ([]ScriptStmt, error) {
+	node, ok := r.Script.Nodes[r.node]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown node %q", ErrDialogueState, r.node)
+	}
+
+	block := node.Body
+
+	for i := 0; i+1 < len(path); i += 2 {
+		if path[i] < 0 || path[i] >= len(block) {
+			return nil, fmt.Errorf("%w: bad frame %v in %s", ErrDialogueState, path, r.node)
+		}
+
+		st, sub := &block[path[i]], path[i+1]
+
+		switch {
+		case st.Kind == StmtIf && sub >= 0 && sub < len(st.Branches):
+			block = st.Branches[sub].Body
+		case st.Kind == StmtOptions && sub >= 0 && sub < len(st.Options):
+			block = st.Options[sub].Body
+		default:
+			return nil, fmt.Errorf("%w: bad frame %v in %s", ErrDialogueState, path, r.node)
+		}
+	}
+
+	if len(path)%2 != 0 {
+		return nil, fmt.Errorf("%w: bad frame %v in %s", ErrDialogueState, path, r.node)
+	}
+
+	return block, nil
+}
+
+func (r *DialogueRunner) lineEvent(st *ScriptStmt) (DialogueEvent, error) {
+	text, err := r.localize(st.LineID, st.text)
+	if err != nil {
+		return DialogueEvent{}, r.errorf(st.Line, err)
+	}
+
+	return DialogueEvent{
+		Kind: DialogueEventLine, Node: r.node, Speaker: st.Speaker,
+		Text: text, LineID: st.LineID, Tags: st.Tags,
+	}, nil
+}
+
+// optionsEvent lists the available options of st and marks them pending.
+func (r *DialogueRunner) optionsEvent(st *ScriptStmt) (DialogueEvent, error) {
+	ev := DialogueEvent{Kind: DialogueEventOptions, Node: r.node}
+	r.choices = []int{}
+
+	for i, opt := range st.Options {
+		if opt.cond != nil {
+			ok, err := r.test(opt.cond)
+			if err != nil {
+				return DialogueEvent{}, r.errorf(opt.Line, err)
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		text, err := r.localize(opt.LineID, opt.text)
+		if err != nil {
+			return DialogueEvent{}, r.errorf(opt.Line, err)
+		}
+
+		ev.Options = append(ev.Options, DialogueOption{Index: i, Text: text, LineID: opt.LineID, Tags: opt.Tags})
+		r.choices = append(r.choices, i)
+	}
+
+	return ev, nil
+}
+
+func (r *DialogueRunner) localize(id string, text dialogueText) (string, error) {
+	if translated, ok := r.Strings[id]; ok && id != "" {
+		compiled, err := parseDialogueText(translated)
+		if err != nil {
+			return "", fmt.Errorf("translation %q: %w", id, err)
+		}
+
+		text = compiled
+	}
+
+	return text.render(r.context())
+}
+
+func (r *DialogueRunner) context() *invContext {
+	return &invContext{values: r.Narrative.variables, set: &InvariantSet{}}
+}
+
+// test evaluates a condition; unset variables make it false.
+func (r *DialogueRunner) test(cond invExpr) (bool, error) {
+	v, err := cond.eval(r.context())
+	if errors.Is(err, errNoValue) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return invBool(v)
+}
+
+func (r *DialogueRunner) set(st *ScriptStmt) error {
+	v, err := st.expr.eval(r.context())
+	if err != nil {
+		return err
+	}
+
+	if st.Op != "=" {
+		cur, ok := r.Narrative.variables[st.Var]
+		if !ok {
+			return fmt.Errorf("$%s: %w", st.Var, errNoValue)
+		}
+
+		v, err = (&invBinary{op: st.Op[:1], l: &invLiteral{invValue(cur)}, r: &invLiteral{v}}).eval(r.context())
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Narrative.SetVariable(st.Var, v)
+
+	return nil
+}
+
+func (r *DialogueRunner) errorf(line int, err error) error {
+	return fmt.Errorf("%s line %d: %w", r.node, line, err)
+}
+
+// Save captures the dialogue position and narrative variables.
+func (r *DialogueRunner) Save() DialogueState {
+	state := DialogueState{Node: r.node, Done: r.done, Variables: r.Narrative.Variables()}
+
+	for _, f := range r.frames {
+		state.Frames = append(state.Frames, DialogueFrame{Block: slices.Clone(f.Block), PC: f.PC})
+	}
+
+	if r.choices != nil {
+		state.Choices = append([]int{}, r.choices...)
+	}
+
+	return state
+}
+
+// Restore resumes a saved position, replacing the narrative variables. It
+// fails if the script has changed so that the position no longer exists.
+func (r *DialogueRunner) Restore(state DialogueState) error {
+	restored := &DialogueRunner{Script: r.Script, node: state.Node, done: state.Done}
+
+	if !state.Done {
+		if len(state.Frames) == 0 {
+			return fmt.Errorf("%w: no frames", ErrDialogueState)
+		}
+
+		for _, f := range state.Frames {
+			block, err := restored.block(f.Block)
+			if err != nil {
+				return err
+			}
+
+			if f.PC < 0 || f.PC > len(block) {
+				return fmt.Errorf("%w: pc %d out of range in %s", ErrDialogueState, f.PC, state.Node)
+			}
+		}
+
+		if state.Choices != nil {
+			top := state.Frames[len(state.Frames)-1]
+			block, _ := restored.block(top.Block)
+
+			if top.PC == 0 || block[top.PC-1].Kind != StmtOptions {
+				return fmt.Errorf("%w: choices saved away from options", ErrDialogueState)
+			}
+
+			for _, i := range state.Choices {
+				if i < 0 || i >= len(block[top.PC-1].Options) {
+					return fmt.Errorf("%w: option %d out of range", ErrDialogueState, i)
+				}
+			}
+		}
+	}
+
+	r.node, r.done, r.frames, r.choices = state.Node, state.Done, nil, nil
+
+	for _, f := range state.Frames {
+		r.frames = append(r.frames, DialogueFrame{Block: slices.Clone(f.Block), PC: f.PC})
+	}
+
+	if state.Choices != nil {
+		r.choices = append([]int{}, state.Choices...)
+	}
+
+	r.Narrative.SetVariables(state.Variables)
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gateDialogue = `
+// The town gate.
+title: Gate
+tags: entry
+---
+<<declare $gold = 0>>
+<<declare $visits = 0>>
+<<set $visits += 1>>
+Guard: Halt! The toll is 5 gold. #line:gate_halt
+<<if $visits > 1>>
+    Guard: You again, {$name}? #line:gate_again
+<<endif>>
+-> Pay the toll. <<if $gold >= 5>> #line:gate_pay
+    <<set $gold -= 5>>
+    <<play_sound coin "small purse">>
+    <<jump Town>>
+-> Bribe him. <<if $gold >= 20>>
+    <<jump Town>>
+-> Turn back. #line:gate_leave
+    Guard: Come back with coin. #line:gate_bye #sad
+    <<stop>>
+===
+
+title: Town
+tags: end
+---
+<<if $gold == 0>>
+    Narrator: Your purse is empty.
+<<elseif $gold < 10>>
+    Narrator: You have {$gold} gold left.
+<<else>>
+    Narrator: Rich!
+<<endif>>
+===
+`
+
+func runDialogue(t *testing.T, r *DialogueRunner, choices ...int) []string {
+	t.Helper()
+
+	var out []string
+
+	for {
+		ev, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch ev.Kind {
+		case DialogueEventLine:
+			out = append(out, ev.Speaker+": "+ev.Text)
+		case DialogueEventCommand:
+			out = append(out, "<<"+ev.Command+" "+strings.Join(ev.Args, "|")+">>")
+		case DialogueEventOptions:
+			var texts []string
+			for _, opt := range ev.Options {
+				texts = append(texts, opt.Text)
+			}
+
+			out = append(out, "-> "+strings.Join(texts, " / "))
+
+			if err := r.Choose(ev.Options[choices[0]].Index); err != nil {
+				t.Fatal(err)
+			}
+
+			choices = choices[1:]
+		case DialogueEventEnd:
+			return out
+		}
+	}
+}
+
+func TestDialogueRunner(t *testing.T) {
+	script, err := ParseDialogue(gateDialogue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	narrative := NewNarrativeController()
+	narrative.SetVariable("name", "Ada")
+
+	runner := NewDialogueRunner(script, narrative)
+	if err := runner.Start("Gate"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(runDialogue(t, runner, 0), "\n")
+	want := "Guard: Halt! The toll is 5 gold.\n-> Turn back.\nGuard: Come back with coin."
+
+	if got != want {
+		t.Errorf("first visit:\n%s", got)
+	}
+
+	narrative.SetVariable("gold", 7)
+
+	var played []string
+
+	runner.Commands["play_sound"] = func(args []string) error {
+		played = args
+
+		return nil
+	}
+
+	_ = runner.Start("Gate")
+	got = strings.Join(runDialogue(t, runner, 0), "\n")
+	want = "Guard: Halt! The toll is 5 gold.\nGuard: You again, Ada?\n-> Pay the toll / Turn back.\n" +
+		"Narrator: You have 2 gold left."
+
+	if got != strings.Replace(want, "toll / ", "toll. / ", 1) || strings.Join(played, "|") != "coin|small purse" {
+		t.Errorf("second visit:\n%s\nplayed %v", got, played)
+	}
+
+	if narrative.GetVariable("visits") != 2.0 {
+		t.Errorf("visits = %v", narrative.GetVariable("visits"))
+	}
+}
+
+func TestDialogueLocalization(t *testing.T) {
+	script, _ := ParseDialogue(gateDialogue)
+
+	strs := script.Strings()
+	if len(strs) != 5 || strs["gate_again"] != "You again, {$name}?" {
+		t.Fatalf("Strings = %v", strs)
+	}
+
+	narrative := NewNarrativeController()
+	narrative.SetVariable("visits", 1)
+	narrative.SetVariable("name", "Ada")
+
+	runner := NewDialogueRunner(script, narrative)
+	runner.Strings = map[string]string{"gate_halt": "Halte !", "gate_again": "Encore toi, {$name} ?"}
+	_ = runner.Start("Gate")
+
+	ev, _ := runner.Next()
+	if ev.Text != "Halte !" || ev.LineID != "gate_halt" {
+		t.Errorf("line = %+v", ev)
+	}
+
+	if ev, _ = runner.Next(); ev.Text != "Encore toi, Ada ?" {
+		t.Errorf("interpolated = %q", ev.Text)
+	}
+
+	if ev, _ = runner.Next(); ev.Options[0].Text != "Turn back." || ev.Options[0].Index != 2 {
+		t.Errorf("options = %+v", ev.Options)
+	}
+}
+
+func TestDialogueSaveRestore(t *testing.T) {
+	script, _ := ParseDialogue(gateDialogue)
+	narrative := NewNarrativeController()
+	narrative.SetVariable("gold", 30)
+
+	runner := NewDialogueRunner(script, narrative)
+	_ = runner.Start("Gate")
+	_, _ = runner.Next()
+
+	options, _ := runner.Next()
+	if len(options.Options) != 3 {
+		t.Fatalf("options = %+v", options.Options)
+	}
+
+	data, err := json.Marshal(runner.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load into a fresh controller, as after restarting the game.
+	var state DialogueState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewDialogueRunner(script, NewNarrativeController())
+	if err := loaded.Restore(state); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev, _ := loaded.Next(); ev.Kind != DialogueEventOptions || len(ev.Options) != 3 {
+		t.Fatalf("restored = %+v", ev)
+	}
+
+	got := strings.Join(runDialogue(t, loaded, 1), "\n")
+	if got != "-> Pay the toll. / Bribe him. / Turn back.\nNarrator: Rich!" || loaded.Node() != "Town" {
+		t.Errorf("after restore:\n%s", got)
+	}
+
+	if !loaded.Done() || loaded.Choose(0) == nil {
+		t.Error("finished dialogue accepted a choice")
+	}
+
+	state.Frames[0].PC = 99
+	if err := loaded.Restore(state); !errors.Is(err, ErrDialogueState) {
+		t.Errorf("bad frame = %v", err)
+	}
+
+	state.Frames[0].PC, state.Node = 0, "Missing"
+	if err := loaded.Restore(state); !errors.Is(err, ErrDialogueState) {
+		t.Errorf("bad node = %v", err)
+	}
+}
+
+func TestDialogueValidate(t *testing.T) {
+	script, err := ParseDialogue(`
+title: Start
+---
+Mira: Hello. #line:a
+-> Ask about {$rumor}. <<if $met>>
+    <<jump Rumors>>
+-> Leave. <<if true>>
+    <<stop>>
+===
+title: Rumors
+---
+Mira: Nothing new. #line:a
+<<jump Nowhere>>
+Mira: Never said.
+===
+title: Orphan
+---
+<<shake 3>>
+Mira: Lonely.
+===
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range script.Validate(DialogueValidateOptions{Commands: []string{"wave"}, Variables: []string{"met"}}) {
+		got = append(got, issue.String())
+	}
+
+	want := []string{
+		"Start:2: warning: dead end: can reach the end of the node without <<jump>> or <<stop>>",
+		"Start:5: warning: every option is conditional; the group may be skipped",
+		"Start:5: warning: $rumor is read but never set or declared",
+		"Rumors:12: error: line ID \"a\" is also used in Start",
+		"Rumors:13: error: jump to unknown node \"Nowhere\"",
+		"Rumors:14: warning: unreachable statement",
+		"Orphan:16: warning: dead end: can reach the end of the node without <<jump>> or <<stop>>",
+		"Orphan:16: warning: unreachable node",
+		"Orphan:18: warning: unknown command <<shake>>",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s", strings.Join(got, "\n"))
+	}
+
+	gate, _ := ParseDialogue(gateDialogue)
+	if issues := gate.Validate(DialogueValidateOptions{Variables: []string{"name"}}); len(issues) != 0 {
+		t.Errorf("gate issues = %v", issues)
+	}
+}
+
+func TestDialogueSyntaxErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"title: A\n---\n<<if $x>>\nHi\n===":                                 "line 3: <<if>> has no <<endif>>",
+		"title: A\n---\n<<endif>>\n===":                                     "line 3: unexpected <<endif>>",
+		"title: A\n---\n<<set gold = 1>>\n===":                              "line 3: expected <<set $name = value>>",
+		"title: A\n---\n-> Go <<if $x >>= 1>>\n===":                         "line 3: expression",
+		"title: A\n---\nHi {$name\n===":                                     "line 3: unclosed {",
+		"title: A\n---\nHi\n":                                               "line 1: node \"A\" has no \"===\"",
+		"title: Two words\n---\n===":                                        "line 1: node needs a one-word title",
+		"title: A\n---\n===\ntitle: A\n---\n===":                            "line 4: duplicate node \"A\"",
+		"title: A\n---\n<<declare $x = $y>>\n===":                           "line 3: declare needs a constant",
+		"title: A\n---\n<<if $x>>\n<<else>>\n<<elseif $y>>\n<<endif>>\n===": "line 5: <<elseif>> after <<else>>",
+	} {
+		_, err := ParseDialogue(src)
+		if !errors.Is(err, ErrDialogueSyntax) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: %v, want %q", src, err, want)
+		}
+	}
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a.yarn"), []byte("title: A\n---\n<<jump B>>\n==="), 0o600)
+	_ = os.WriteFile(filepath.Join(dir, "b.yarn"), []byte("title: B\n---\n<<stop>>\n==="), 0o600)
+
+	script, err := LoadDialogue(filepath.Join(dir, "a.yarn"), filepath.Join(dir, "b.yarn"))
+	if err != nil || len(script.Validate(DialogueValidateOptions{})) != 0 {
+		t.Errorf("LoadDialogue = %v, %v", script, err)
+	}
+
+	if _, err := LoadDialogue(filepath.Join(dir, "a.yarn"), filepath.Join(dir, "a.yarn")); !errors.Is(err, ErrDialogueSyntax) {
+		t.Errorf("duplicate across files = %v", err)
+	}
+}
+
+func TestDialogueJumpLoop(t *testing.T) {
+	script, _ := ParseDialogue("title: A\n---\n<<jump B>>\n===\ntitle: B\n---\n<<jump A>>\n===")
+	runner := NewDialogueRunner(script, NewNarrativeController())
+	_ = runner.Start("A")
+
+	if _, err := runner.Next(); !errors.Is(err, ErrDialogueState) {
+		t.Errorf("jump loop = %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package ai
 import "math"
 
 // DifficultyBalancer tracks player performance and adjusts game parameters.
+// It keeps a single global level; DifficultyEngine models each player.
 type DifficultyBalancer struct {
 	// Tracked metrics (e.g., "win_rate", "deaths", "completion_time")
 	metrics map[string]*MetricTracker
@@ -25,6 +26,28 @@ type MetricTracker struct {
 	tolerance  float64 // Acceptable deviation from target
 }
 
+// add records a sample, dropping the oldest beyond maxSamples.
+func (t *MetricTracker) add(value float64) {
+	t.values = append(t.values, value)
+	if len(t.values) > t.maxSamples {
+		t.values = t.values[1:]
+	}
+}
+
+// mean returns the rolling average, or 0 without samples.
+func (t *MetricTracker) mean() float64 {
+	if len(t.values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range t.values {
+		sum += v
+	}
+
+	return sum / float64(len(t.values))
+}
+
 // Parameter represents an adjustable game parameter.
 type Parameter struct {
 	Value    float64
@@ -76,25 +99,17 @@ func (d *DifficultyBalancer) RecordMetric(name string, value float64) {
 		return
 	}
 
-	tracker.values = append(tracker.values, value)
-	if len(tracker.values) > tracker.maxSamples {
-		tracker.values = tracker.values[1:]
-	}
+	tracker.add(value)
 }
 
 // GetMetricAverage returns the rolling average of a metric.
 func (d *DifficultyBalancer) GetMetricAverage(name string) float64 {
 	tracker, ok := d.metrics[name]
-	if !ok || len(tracker.values) == 0 {
+	if !ok {
 		return 0
 	}
 
-	sum := 0.0
-	for _, v := range tracker.values {
-		sum += v
-	}
-
-	return sum / float64(len(tracker.values))
+	return tracker.mean()
 }
 
 // Update adjusts difficulty based on tracked metrics.
@@ -108,12 +123,7 @@ func (d *DifficultyBalancer) Update() {
 			continue // Need enough samples
 		}
 
-		avg := 0.0
-		for _, v := range tracker.values {
-			avg += v
-		}
-
-		avg /= float64(len(tracker.values))
+		avg := tracker.mean()
 
 		// How far from target? Positive = doing too well
 		deviation := (avg - tracker.target) / tracker.tolerance
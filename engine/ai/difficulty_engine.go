@@ -0,0 +1,910 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+)
+
+// ErrUnknownCohort is returned when assigning a player to a cohort that was
+// never added.
+var ErrUnknownCohort = errors.New("ai: unknown difficulty cohort")
+
+// ============================================================================
+// Skill Models
+// ============================================================================
+
+// SkillRating is a player's estimated skill on the Elo scale, where 1500 is
+// average and 400 points is roughly 10:1 odds.
+type SkillRating struct {
+	Mu    float64 `json:"mu"`
+	Sigma float64 `json:"sigma"` // Uncertainty; 0 for models that don't track it
+	Games int     `json:"games"`
+}
+
+// SkillModel estimates player skill from session outcomes. Each session is
+// a match against the challenge rating of the difficulty it was played at.
+type SkillModel interface {
+	// Name identifies the model in reports.
+	Name() string
+	// Initial returns the rating of a new player.
+	Initial() SkillRating
+	// Update returns r after a session against challenge. outcome is 1 for a
+	// win, 0 for a loss and in between for partial success.
+	Update(r SkillRating, challenge, outcome float64) SkillRating
+	// WinProbability predicts the chance of beating challenge.
+	WinProbability(r SkillRating, challenge float64) float64
+}
+
+// EloModel is the classic Elo rating with a fixed K-factor.
+type EloModel struct {
+	K float64 // Points moved per fully surprising result
+}
+
+// NewEloModel creates an Elo model with K = 32.
+func NewEloModel() *EloModel {
+	return &EloModel{K: 32}
+}
+
+// Name implements SkillModel.
+func (m *EloModel) Name() string { return "elo" }
+
+// Initial implements SkillModel.
+func (m *EloModel) Initial() SkillRating { return SkillRating{Mu: 1500} }
+
+// WinProbability implements SkillModel.
+func (m *EloModel) WinProbability(r SkillRating, challenge float64) float64 {
+	return 1 / (1 + math.Pow(10, (challenge-r.Mu)/400))
+}
+
+// Update implements SkillModel.
+func (m *EloModel) Update(r SkillRating, challenge, outcome float64) SkillRating {
+	r.Mu += m.K * (outcome - m.WinProbability(r, challenge))
+	r.Games++
+
+	return r
+}
+
+// GlickoModel is Glicko-1: Elo with a rating deviation that shrinks as
+// evidence accumulates and grows again between sessions, so new players
+// move fast and veterans settle.
+type GlickoModel struct {
+	InitialRD float64 // Deviation of a new player
+	MinRD     float64 // Floor so ratings keep tracking improvement
+	Drift     float64 // Deviation added before each session
+}
+
+// NewGlickoModel creates a Glicko model with the standard 350 initial
+// deviation.
+func NewGlickoModel() *GlickoModel {
+	return &GlickoModel{InitialRD: 350, MinRD: 50, Drift: 20}
+}
+
+// glickoQ converts Elo points to natural log odds.
+const glickoQ = math.Ln10 / 400
+
+// Name implements SkillModel.
+func (m *GlickoModel) Name() string { return "glicko" }
+
+// Initial implements SkillModel.
+func (m *GlickoModel) Initial() SkillRating {
+	return SkillRating{Mu: 1500, Sigma: m.InitialRD}
+}
+
+// WinProbability implements SkillModel. Uncertain ratings predict results
+// closer to a coin flip.
+func (m *GlickoModel) WinProbability(r SkillRating, challenge float64) float64 {
+	g := 1 / math.Sqrt(1+3*glickoQ*glickoQ*r.Sigma*r.Sigma/(math.Pi*math.Pi))
+
+	return 1 / (1 + math.Pow(10, -g*(r.Mu-challenge)/400))
+}
+
+// Update implements SkillModel. The challenge is treated as a fixed-rated
+// opponent.
+func (m *GlickoModel) Update(r SkillRating, challenge, outcome float64) SkillRating {
+	rd := math.Min(math.Sqrt(r.Sigma*r.Sigma+m.Drift*m.Drift), m.InitialRD)
+
+	expected := 1 / (1 + math.Pow(10, -(r.Mu-challenge)/400))
+	d2 := 1 / (glickoQ * glickoQ * expected * (1 - expected))
+	precision := 1/(rd*rd) + 1/d2
+
+	r.Mu += glickoQ / precision * (outcome - expected)
+	r.Sigma = math.Max(math.Sqrt(1/precision), m.MinRD)
+	r.Games++
+
+	return r
+}
+
+// BayesianModel keeps a Gaussian belief over skill and updates it by
+// moment matching against a probit likelihood, as in TrueSkill with a single
+// fixed opponent.
+type BayesianModel struct {
+	PriorSigma float64 // Belief width of a new player
+	Beta       float64 // Per-session performance noise
+	Drift      float64 // Belief widening before each session
+	MinSigma   float64
+}
+
+// NewBayesianModel creates a Bayesian model on the Elo scale.
+func NewBayesianModel() *BayesianModel {
+	return &BayesianModel{PriorSigma: 350, Beta: 200, Drift: 15, MinSigma: 40}
+}
+
+// Name implements SkillModel.
+func (m *BayesianModel) Name() string { return "bayesian" }
+
+// Initial implements SkillModel.
+func (m *BayesianModel) Initial() SkillRating {
+	return SkillRating{Mu: 1500, Sigma: m.PriorSigma}
+}
+
+// WinProbability implements SkillModel.
+func (m *BayesianModel) WinProbability(r SkillRating, challenge float64) float64 {
+	return normalCDF((r.Mu - challenge) / math.Sqrt(r.Sigma*r.Sigma+m.Beta*m.Beta))
+}
+
+// Update implements SkillModel. Partial outcomes blend the win and loss
+// posteriors.
+func (m *BayesianModel) Update(r SkillRating, challenge, outcome float64) SkillRating {
+	variance := r.Sigma*r.Sigma + m.Drift*m.Drift
+	c := math.Sqrt(variance + m.Beta*m.Beta)
+	t := (r.Mu - challenge) / c
+
+	posterior := func(sign float64) (float64, float64) {
+		x := sign * t
+		v := normalPDF(x) / math.Max(normalCDF(x), 1e-300)
+		w := v * (v + x)
+
+		return r.Mu + sign*variance/c*v, variance * (1 - variance/(c*c)*w)
+	}
+
+	winMu, winVar := posterior(1)
+	lossMu, lossVar := posterior(-1)
+
+	r.Mu = outcome*winMu + (1-outcome)*lossMu
+	r.Sigma = math.Max(math.Sqrt(outcome*winVar+(1-outcome)*lossVar), m.MinSigma)
+	r.Games++
+
+	return r
+}
+
+func normalPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// ============================================================================
+// Control
+// ============================================================================
+
+// PIDController steers a signal toward zero error. The derivative is
+// low-pass filtered and the output slew-limited so noisy session results
+// don't make difficulty oscillate.
+type PIDController struct {
+	Kp, Ki, Kd float64
+
+	IntegralLimit float64 // Anti-windup bound on the integral (0 = none)
+	Smoothing     float64 // Derivative filter in [0,1); higher is smoother
+	MaxStep       float64 // Largest output change per update (0 = none)
+	Limit         float64 // Output bound, symmetric (0 = none)
+
+	integral   float64
+	prevError  float64
+	derivative float64
+	output     float64
+	primed     bool
+}
+
+// Update feeds one error sample taken dt after the previous one and returns
+// the new output.
+func (p *PIDController) Update(err, dt float64) float64 {
+	if dt <= 0 {
+		dt = 1
+	}
+
+	p.integral += err * dt
+	if p.IntegralLimit > 0 {
+		p.integral = clamp(p.integral, -p.IntegralLimit, p.IntegralLimit)
+	}
+
+	raw := 0.0
+	if p.primed {
+		raw = (err - p.prevError) / dt
+	}
+
+	p.derivative = p.Smoothing*p.derivative + (1-p.Smoothing)*raw
+	p.prevError = err
+	p.primed = true
+
+	out := p.Kp*err + p.Ki*p.integral + p.Kd*p.derivative
+	if p.Limit > 0 {
+		out = clamp(out, -p.Limit, p.Limit)
+	}
+
+	if p.MaxStep > 0 {
+		out = clamp(out, p.output-p.MaxStep, p.output+p.MaxStep)
+	}
+
+	p.output = out
+
+	return out
+}
+
+// Output returns the last output.
+func (p *PIDController) Output() float64 {
+	return p.output
+}
+
+// Reset clears the controller state, keeping its gains.
+func (p *PIDController) Reset() {
+	p.integral, p.prevError, p.derivative, p.output, p.primed = 0, 0, 0, 0, false
+}
+
+// RubberBand maps a difficulty in [0,1] to a parameter value. 0.5 gives
+// Neutral; with Stiffness above 1 the value stays close to Neutral for
+// moderate difficulties and pulls hard toward Min or Max at the extremes,
+// like a stretched band.
+type RubberBand struct {
+	Min, Neutral, Max float64
+	Stiffness         float64 // Power applied to the distance from 0.5 (0 = 1)
+
+	// Shape, when set, replaces the power curve. It receives the distance
+	// from 0.5 scaled to [0,1].
+	Shape ResponseCurve
+}
+
+// NewRubberBand creates a power-shaped rubber band.
+func NewRubberBand(minVal, neutral, maxVal, stiffness float64) RubberBand {
+	return RubberBand{Min: minVal, Neutral: neutral, Max: maxVal, Stiffness: stiffness}
+}
+
+// At returns the parameter value at difficulty d.
+func (r RubberBand) At(d float64) float64 {
+	x := 2*clamp(d, 0, 1) - 1
+	stretch := math.Abs(x)
+
+	if r.Shape != nil {
+		stretch = clamp(r.Shape.Evaluate(stretch), 0, 1)
+	} else if r.Stiffness > 0 {
+		stretch = math.Pow(stretch, r.Stiffness)
+	}
+
+	if x >= 0 {
+		return r.Neutral + (r.Max-r.Neutral)*stretch
+	}
+
+	return r.Neutral - (r.Neutral-r.Min)*stretch
+}
+
+// ============================================================================
+// Configuration
+// ============================================================================
+
+// DifficultyMetric is a performance metric the controller steers toward a
+// target, e.g. deaths per level or seconds per wave.
+type DifficultyMetric struct {
+	Name      string
+	Target    float64
+	Tolerance float64 // Deviation that counts as one unit of error
+	Weight    float64 // Relative weight (0 = 1)
+	Samples   int     // Rolling window (0 = Window)
+	Inverted  bool    // Higher values mean the player is struggling
+}
+
+// DifficultyConfig configures how a cohort's difficulty is computed.
+//
+// The skill model picks the difficulty at which the player is predicted to
+// win TargetWinRate of sessions. The PID controller then adds a bounded
+// correction from the observed win rate and metrics, absorbing whatever the
+// model gets wrong.
+type DifficultyConfig struct {
+	Model         SkillModel
+	TargetWinRate float64
+	RatingRange   [2]float64 // Challenge ratings at difficulty 0 and 1
+	Window        int        // Sessions in the observed win rate
+	WinRateWeight float64    // Weight of the win rate error (0 = ignore)
+	Metrics       []DifficultyMetric
+	PID           PIDController // Gains; each player gets a copy
+	Parameters    map[string]RubberBand
+	Scale         RubberBand // Enemy multiplier for game.DifficultyManager
+}
+
+// DefaultDifficultyConfig returns a Glicko-based config aiming for even odds.
+func DefaultDifficultyConfig() DifficultyConfig {
+	return DifficultyConfig{
+		Model:         NewGlickoModel(),
+		TargetWinRate: 0.5,
+		RatingRange:   [2]float64{1000, 2000},
+		Window:        10,
+		WinRateWeight: 1,
+		PID: PIDController{
+			Kp: 0.15, Ki: 0.03, Kd: 0.05,
+			IntegralLimit: 4, Smoothing: 0.5, MaxStep: 0.05, Limit: 0.25,
+		},
+		Parameters: make(map[string]RubberBand),
+		Scale:      NewRubberBand(0.5, 1, 2, 1.5),
+	}
+}
+
+// ratingAt converts a difficulty to a challenge rating.
+func (c *DifficultyConfig) ratingAt(d float64) float64 {
+	return c.RatingRange[0] + d*(c.RatingRange[1]-c.RatingRange[0])
+}
+
+// skillDifficulty finds the difficulty where r wins TargetWinRate of the
+// time. Win probability falls as the challenge rises, so bisect.
+func (c *DifficultyConfig) skillDifficulty(r SkillRating) float64 {
+	lo, hi := 0.0, 1.0
+	if c.Model.WinProbability(r, c.ratingAt(lo)) <= c.TargetWinRate {
+		return lo
+	}
+
+	if c.Model.WinProbability(r, c.ratingAt(hi)) >= c.TargetWinRate {
+		return hi
+	}
+
+	for range 40 {
+		mid := (lo + hi) / 2
+		if c.Model.WinProbability(r, c.ratingAt(mid)) > c.TargetWinRate {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
+
+// ============================================================================
+// Engine
+// ============================================================================
+
+// DifficultyCohort is an A/B test arm.
+type DifficultyCohort struct {
+	Name   string
+	Weight float64 // Share of new players, relative to other cohorts
+	Config DifficultyConfig
+}
+
+// DifficultyEngine models each player's skill and difficulty separately.
+// Players are split deterministically between cohorts, each with its own
+// config, so tuning changes can be compared on live or simulated players.
+type DifficultyEngine struct {
+	// Salt changes the cohort split without renaming players.
+	Salt string
+
+	cohorts []*DifficultyCohort
+	players map[string]*PlayerDifficulty
+	order   []string
+}
+
+// NewDifficultyEngine creates an engine with a single "control" cohort.
+func NewDifficultyEngine(config DifficultyConfig) *DifficultyEngine {
+	e := &DifficultyEngine{players: make(map[string]*PlayerDifficulty)}
+	e.AddCohort("control", 1, config)
+
+	return e
+}
+
+// AddCohort adds an A/B arm, or replaces the config of an existing one.
+// Players already assigned keep their cohort.
+func (e *DifficultyEngine) AddCohort(name string, weight float64, config DifficultyConfig) {
+	defaults := DefaultDifficultyConfig()
+	if config.Model == nil {
+		config.Model = defaults.Model
+	}
+
+	if config.RatingRange == [2]float64{} {
+		config.RatingRange = defaults.RatingRange
+	}
+
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+
+	if config.TargetWinRate <= 0 {
+		config.TargetWinRate = defaults.TargetWinRate
+	}
+
+	if config.Scale.Shape == nil && config.Scale.Min == 0 && config.Scale.Max == 0 {
+		config.Scale = defaults.Scale
+	}
+
+	for _, c := range e.cohorts {
+		if c.Name == name {
+			c.Weight, c.Config = weight, config
+
+			return
+		}
+	}
+
+	e.cohorts = append(e.cohorts, &DifficultyCohort{Name: name, Weight: weight, Config: config})
+}
+
+// Cohorts returns the cohort names in the order they were added.
+func (e *DifficultyEngine) Cohorts() []string {
+	names := make([]string, len(e.cohorts))
+	for i, c := range e.cohorts {
+		names[i] = c.Name
+	}
+
+	return names
+}
+
+// CohortFor returns the cohort a player is or would be assigned to. The
+// split hashes the player id, so it is stable across runs and machines.
+func (e *DifficultyEngine) CohortFor(playerID string) string {
+	if p, ok := e.players[playerID]; ok {
+		return p.Cohort
+	}
+
+	total := 0.0
+	for _, c := range e.cohorts {
+		total += math.Max(c.Weight, 0)
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(e.Salt + ":" + playerID))
+	point := float64(h.Sum32()) / (1 << 32) * total
+
+	for _, c := range e.cohorts {
+		point -= math.Max(c.Weight, 0)
+		if point < 0 {
+			return c.Name
+		}
+	}
+
+	return e.cohorts[len(e.cohorts)-1].Name
+}
+
+// Assign puts a new player in a specific cohort, overriding the split.
+func (e *DifficultyEngine) Assign(playerID, cohort string) (*PlayerDifficulty, error) {
+	for _, c := range e.cohorts {
+		if c.Name == cohort {
+			return e.track(playerID, c), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCohort, cohort)
+}
+
+// Player returns a player's model, creating it on first use.
+func (e *DifficultyEngine) Player(playerID string) *PlayerDifficulty {
+	if p, ok := e.players[playerID]; ok {
+		return p
+	}
+
+	name := e.CohortFor(playerID)
+	for _, c := range e.cohorts {
+		if c.Name == name {
+			return e.track(playerID, c)
+		}
+	}
+
+	return nil
+}
+
+func (e *DifficultyEngine) track(playerID string, cohort *DifficultyCohort) *PlayerDifficulty {
+	p := &PlayerDifficulty{
+		ID:      playerID,
+		Cohort:  cohort.Name,
+		Skill:   cohort.Config.Model.Initial(),
+		cohort:  cohort,
+		pid:     cohort.Config.PID,
+		metrics: make(map[string]*MetricTracker),
+	}
+	p.pid.Reset()
+
+	if _, ok := e.players[playerID]; !ok {
+		e.order = append(e.order, playerID)
+	}
+
+	e.players[playerID] = p
+
+	return p
+}
+
+// RecordOutcome records a session result for a player.
+func (e *DifficultyEngine) RecordOutcome(playerID string, outcome float64) {
+	e.Player(playerID).RecordOutcome(outcome)
+}
+
+// RecordMetric records a metric sample for a player.
+func (e *DifficultyEngine) RecordMetric(playerID, name string, value float64) {
+	e.Player(playerID).RecordMetric(name, value)
+}
+
+// Difficulty returns a player's current difficulty in [0,1].
+func (e *DifficultyEngine) Difficulty(playerID string) float64 {
+	return e.Player(playerID).Difficulty()
+}
+
+// Parameters returns a player's game parameters at their difficulty.
+func (e *DifficultyEngine) Parameters(playerID string) map[string]float64 {
+	return e.Player(playerID).Parameters()
+}
+
+// CohortStats summarizes one A/B arm.
+type CohortStats struct {
+	Name           string  `json:"name"`
+	Players        int     `json:"players"`
+	Sessions       int     `json:"sessions"`
+	WinRate        float64 `json:"win_rate"`
+	TargetWinRate  float64 `json:"target_win_rate"`
+	MeanDifficulty float64 `json:"mean_difficulty"` // Difficulty played, per session
+	MeanSkill      float64 `json:"mean_skill"`      // Current rating, per player
+}
+
+// Stats summarizes every cohort.
+func (e *DifficultyEngine) Stats() []CohortStats {
+	stats := make([]CohortStats, len(e.cohorts))
+	index := make(map[string]int, len(e.cohorts))
+
+	for i, c := range e.cohorts {
+		stats[i] = CohortStats{Name: c.Name, TargetWinRate: c.Config.TargetWinRate}
+		index[c.Name] = i
+	}
+
+	wins := make([]float64, len(stats))
+	played := make([]float64, len(stats))
+
+	for _, id := range e.order {
+		p := e.players[id]
+		i := index[p.Cohort]
+
+		stats[i].Players++
+		stats[i].Sessions += p.Sessions
+		stats[i].MeanSkill += p.Skill.Mu
+		wins[i] += p.Wins
+		played[i] += p.played
+	}
+
+	for i := range stats {
+		if stats[i].Players > 0 {
+			stats[i].MeanSkill /= float64(stats[i].Players)
+		}
+
+		if stats[i].Sessions > 0 {
+			stats[i].WinRate = wins[i] / float64(stats[i].Sessions)
+			stats[i].MeanDifficulty = played[i] / float64(stats[i].Sessions)
+		}
+	}
+
+	return stats
+}
+
+// PlayerDifficulty is one player's skill estimate and controller state.
+// It satisfies game.DifficultyModel, so a game.DifficultyManager can use it
+// in place of its win/loss counter.
+type PlayerDifficulty struct {
+	ID       string
+	Cohort   string
+	Skill    SkillRating
+	Sessions int
+	Wins     float64 // Sum of outcomes
+
+	cohort  *DifficultyCohort
+	pid     PIDController
+	metrics map[string]*MetricTracker
+	results []float64
+	played  float64 // Sum of difficulties played
+}
+
+// Difficulty returns the current difficulty in [0,1]: the skill model's
+// estimate plus the controller's correction.
+func (p *PlayerDifficulty) Difficulty() float64 {
+	return clamp(p.cohort.Config.skillDifficulty(p.Skill)+p.pid.Output(), 0, 1)
+}
+
+// WinProbability predicts the outcome of the next session.
+func (p *PlayerDifficulty) WinProbability() float64 {
+	config := &p.cohort.Config
+
+	return config.Model.WinProbability(p.Skill, config.ratingAt(p.Difficulty()))
+}
+
+// Parameter returns a named parameter at the current difficulty, or 0 if
+// the cohort doesn't define it.
+func (p *PlayerDifficulty) Parameter(name string) float64 {
+	band, ok := p.cohort.Config.Parameters[name]
+	if !ok {
+		return 0
+	}
+
+	return band.At(p.Difficulty())
+}
+
+// Parameters returns every parameter at the current difficulty.
+func (p *PlayerDifficulty) Parameters() map[string]float64 {
+	d := p.Difficulty()
+
+	params := make(map[string]float64, len(p.cohort.Config.Parameters))
+	for name, band := range p.cohort.Config.Parameters {
+		params[name] = band.At(d)
+	}
+
+	return params
+}
+
+// RecordOutcome records a session played at the current difficulty,
+// updates the skill estimate and steps the controller.
+func (p *PlayerDifficulty) RecordOutcome(outcome float64) {
+	outcome = clamp(outcome, 0, 1)
+	config := &p.cohort.Config
+	d := p.Difficulty()
+
+	p.Skill = config.Model.Update(p.Skill, config.ratingAt(d), outcome)
+	p.Sessions++
+	p.Wins += outcome
+	p.played += d
+
+	p.results = append(p.results, outcome)
+	if len(p.results) > config.Window {
+		p.results = p.results[1:]
+	}
+
+	p.Update()
+}
+
+// RecordMetric records a metric sample. Unknown metrics are ignored.
+func (p *PlayerDifficulty) RecordMetric(name string, value float64) {
+	for _, m := range p.cohort.Config.Metrics {
+		if m.Name != name {
+			continue
+		}
+
+		tracker, ok := p.metrics[name]
+		if !ok {
+			samples := m.Samples
+			if samples <= 0 {
+				samples = p.cohort.Config.Window
+			}
+
+			tracker = &MetricTracker{maxSamples: samples, target: m.Target, tolerance: m.Tolerance}
+			p.metrics[name] = tracker
+		}
+
+		tracker.add(value)
+	}
+}
+
+// Update steps the controller with the current error. Positive error means
+// the player is doing better than targeted. RecordOutcome calls it; call it
+// directly for metric-only games.
+func (p *PlayerDifficulty) Update() {
+	config := &p.cohort.Config
+	sum, weights := 0.0, 0.0
+
+	if config.WinRateWeight > 0 && len(p.results) > 0 {
+		rate := 0.0
+		for _, r := range p.results {
+			rate += r
+		}
+
+		sum += config.WinRateWeight * (rate/float64(len(p.results)) - config.TargetWinRate)
+		weights += config.WinRateWeight
+	}
+
+	for _, m := range config.Metrics {
+		tracker, ok := p.metrics[m.Name]
+		if !ok || len(tracker.values) == 0 {
+			continue
+		}
+
+		weight := m.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		tolerance := m.Tolerance
+		if tolerance == 0 {
+			tolerance = 1
+		}
+
+		deviation := (tracker.mean() - m.Target) / tolerance
+		if m.Inverted {
+			deviation = -deviation
+		}
+
+		sum += weight * deviation
+		weights += weight
+	}
+
+	if weights == 0 {
+		return
+	}
+
+	p.pid.Update(sum/weights, 1)
+}
+
+// Reset forgets the player's history and returns them to the initial rating.
+func (p *PlayerDifficulty) Reset() {
+	p.Skill = p.cohort.Config.Model.Initial()
+	p.Sessions, p.Wins, p.played = 0, 0, 0
+	p.results = nil
+	p.metrics = make(map[string]*MetricTracker)
+	p.pid.Reset()
+}
+
+// RecordWin implements game.DifficultyModel.
+func (p *PlayerDifficulty) RecordWin() { p.RecordOutcome(1) }
+
+// RecordLoss implements game.DifficultyModel.
+func (p *PlayerDifficulty) RecordLoss() { p.RecordOutcome(0) }
+
+// Scale implements game.DifficultyModel.
+func (p *PlayerDifficulty) Scale() float64 {
+	return p.cohort.Config.Scale.At(p.Difficulty())
+}
+
+// ============================================================================
+// Offline Evaluation
+// ============================================================================
+
+// DifficultyBot is a simulated player of fixed ability.
+type DifficultyBot struct {
+	Name   string
+	Skill  float64 // Reported only; the player decides how it plays
+	Player func(seed int64) Player
+}
+
+// SkillBots returns SkilledPlayer bots following expert at each skill level.
+func SkillBots(expert func(seed int64) Player, skills ...float64) []DifficultyBot {
+	bots := make([]DifficultyBot, len(skills))
+	for i, skill := range skills {
+		bots[i] = DifficultyBot{
+			Name:  fmt.Sprintf("skill-%.2f", skill),
+			Skill: skill,
+			Player: func(seed int64) Player {
+				return NewSkilledPlayer(expert(seed), skill, seed)
+			},
+		}
+	}
+
+	return bots
+}
+
+// DifficultyEval replays bots of different skill through an engine with
+// QASession, checking that each converges to its cohort's target win rate.
+type DifficultyEval struct {
+	Engine *DifficultyEngine
+	// NewGame builds a game tuned by the player's current parameters.
+	NewGame func(params map[string]float64, seed int64) GameAdapter
+	Bots    []DifficultyBot
+
+	Sessions int // Sessions per bot and cohort (0 = 40)
+	MaxTicks int // Ticks per session (0 = 3600)
+	Seed     int64
+
+	// Outcome scores a run; by default surviving to MaxTicks is a win.
+	Outcome func(RunResult) float64
+	// Metrics extracts metric samples from a run, if any.
+	Metrics func(RunResult) map[string]float64
+}
+
+// DifficultyTrace is one bot's path through one cohort.
+type DifficultyTrace struct {
+	Bot        string      `json:"bot"`
+	BotSkill   float64     `json:"bot_skill"`
+	Cohort     string      `json:"cohort"`
+	Difficulty []float64   `json:"difficulty"` // Per session, before playing
+	Outcomes   []float64   `json:"outcomes"`
+	Skill      SkillRating `json:"skill"`
+
+	// RecentWinRate covers the second half of the sessions, after the
+	// engine has had time to converge.
+	RecentWinRate   float64 `json:"recent_win_rate"`
+	FinalDifficulty float64 `json:"final_difficulty"`
+}
+
+// DifficultyReport is the result of an offline evaluation.
+type DifficultyReport struct {
+	Traces  []DifficultyTrace `json:"traces"`
+	Cohorts []CohortStats     `json:"cohorts"`
+}
+
+// Run plays every bot in every cohort. Player ids are "cohort/bot".
+func (ev *DifficultyEval) Run() (DifficultyReport, error) {
+	sessions := ev.Sessions
+	if sessions <= 0 {
+		sessions = 40
+	}
+
+	maxTicks := ev.MaxTicks
+	if maxTicks <= 0 {
+		maxTicks = 3600
+	}
+
+	outcome := ev.Outcome
+	if outcome == nil {
+		outcome = func(run RunResult) float64 {
+			if run.GameOver {
+				return 0
+			}
+
+			return 1
+		}
+	}
+
+	var report DifficultyReport
+
+	seed := ev.Seed
+
+	for _, cohort := range ev.Engine.Cohorts() {
+		for _, bot := range ev.Bots {
+			player, err := ev.Engine.Assign(cohort+"/"+bot.Name, cohort)
+			if err != nil {
+				return report, err
+			}
+
+			trace := DifficultyTrace{Bot: bot.Name, BotSkill: bot.Skill, Cohort: cohort}
+
+			for range sessions {
+				seed++
+
+				trace.Difficulty = append(trace.Difficulty, player.Difficulty())
+
+				session := NewQASession(ev.NewGame(player.Parameters(), seed))
+				session.SetPlayer(bot.Player(seed))
+				session.SetConfig(SessionConfig{Runs: 1, MaxTicks: maxTicks, RecordEvery: 1})
+
+				run := session.Run().Runs[0]
+
+				if ev.Metrics != nil {
+					for name, v := range ev.Metrics(run) {
+						player.RecordMetric(name, v)
+					}
+				}
+
+				result := outcome(run)
+				player.RecordOutcome(result)
+				trace.Outcomes = append(trace.Outcomes, result)
+			}
+
+			recent := trace.Outcomes[len(trace.Outcomes)/2:]
+			for _, o := range recent {
+				trace.RecentWinRate += o
+			}
+
+			trace.RecentWinRate /= float64(len(recent))
+			trace.Skill = player.Skill
+			trace.FinalDifficulty = player.Difficulty()
+			report.Traces = append(report.Traces, trace)
+		}
+	}
+
+	report.Cohorts = ev.Engine.Stats()
+
+	return report, nil
+}
+
+// GenerateMarkdown renders the report as tables.
+func (r *DifficultyReport) GenerateMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Difficulty Evaluation\n\n")
+	sb.WriteString("## Cohorts\n\n")
+	sb.WriteString("| Cohort | Players | Sessions | Win Rate | Target | Mean Difficulty | Mean Skill |\n")
+	sb.WriteString("|--------|---------|----------|----------|--------|-----------------|------------|\n")
+
+	for _, c := range r.Cohorts {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | %.2f | %.2f | %.2f | %.0f |\n",
+			c.Name, c.Players, c.Sessions, c.WinRate, c.TargetWinRate, c.MeanDifficulty, c.MeanSkill))
+	}
+
+	sb.WriteString("\n## Bots\n\n")
+	sb.WriteString("| Cohort | Bot | Skill | Rating | Final Difficulty | Recent Win Rate |\n")
+	sb.WriteString("|--------|-----|-------|--------|------------------|-----------------|\n")
+
+	for _, t := range r.Traces {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %.2f | %.0f ± %.0f | %.2f | %.2f |\n",
+			t.Cohort, t.Bot, t.BotSkill, t.Skill.Mu, t.Skill.Sigma, t.FinalDifficulty, t.RecentWinRate))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,276 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/game"
+)
+
+// duelGame is a race: the player must land enemyHealth attacks before the
+// enemy's random hits take their 10 HP.
+type duelGame struct {
+	rng         *rand.Rand
+	enemyHealth int
+	enemy, hp   int
+	won         bool
+}
+
+func newDuelGame(params map[string]float64, seed int64) GameAdapter {
+	return &duelGame{rng: rand.New(rand.NewSource(seed)), enemyHealth: int(math.Round(params["enemy_health"]))}
+}
+
+func (g *duelGame) Name() string     { return "duel" }
+func (g *duelGame) IsGameOver() bool { return g.hp <= 0 || g.enemy <= 0 }
+func (g *duelGame) GetState() GameState {
+	return GameState{PlayerHealth: [2]int{g.hp, 10}}
+}
+
+func (g *duelGame) GetScore() int {
+	if g.won {
+		return 1
+	}
+
+	return 0
+}
+
+func (g *duelGame) AvailableActions() []ActionType {
+	return []ActionType{ActionAttack, ActionNone, ActionMoveLeft, ActionMoveRight}
+}
+
+func (g *duelGame) PerformAction(action ActionType) error {
+	if action == ActionAttack {
+		g.enemy--
+	}
+
+	return nil
+}
+
+func (g *duelGame) Step() error {
+	if g.enemy <= 0 {
+		g.won = true
+	} else if g.rng.Float64() < 0.1 {
+		g.hp--
+	}
+
+	return nil
+}
+
+func (g *duelGame) Reset() error {
+	g.enemy, g.hp, g.won = g.enemyHealth, 10, false
+
+	return nil
+}
+
+// attacker always attacks.
+type attacker struct{}
+
+func (attacker) DecideAction(GameState, []ActionType) ActionType { return ActionAttack }
+
+func TestSkillModels(t *testing.T) {
+	for _, model := range []SkillModel{NewEloModel(), NewGlickoModel(), NewBayesianModel()} {
+		r := model.Initial()
+		if p := model.WinProbability(r, r.Mu); math.Abs(p-0.5) > 1e-9 {
+			t.Errorf("%s: even match = %.3f", model.Name(), p)
+		}
+
+		// A player who wins 80% against 1500 should end up rated above it,
+		// with the model predicting roughly that rate.
+		for i := range 200 {
+			outcome := 0.0
+			if i%5 != 0 {
+				outcome = 1
+			}
+
+			r = model.Update(r, 1500, outcome)
+		}
+
+		if p := model.WinProbability(r, 1500); r.Mu < 1600 || math.Abs(p-0.8) > 0.08 {
+			t.Errorf("%s: rating %.0f predicts %.2f, want ~0.8", model.Name(), r.Mu, p)
+		}
+
+		if r.Games != 200 || (model.Name() != "elo" && r.Sigma >= model.Initial().Sigma/2) {
+			t.Errorf("%s: after 200 games %+v", model.Name(), r)
+		}
+	}
+}
+
+func TestPIDController(t *testing.T) {
+	pid := PIDController{Kp: 0.5, Ki: 0.2, Kd: 0.1, IntegralLimit: 5, Smoothing: 0.5, MaxStep: 0.3}
+
+	// Hold a lagging plant at its setpoint against a constant push; like
+	// difficulty, raising the output lowers the measurement.
+	state := 0.0
+	for range 60 {
+		state = 0.7*state + 0.3*(1-pid.Output())
+		pid.Update(state-0.5, 1)
+	}
+
+	if math.Abs(state-0.5) > 0.01 {
+		t.Errorf("plant settled at %.3f, want 0.5", state)
+	}
+
+	prev := pid.Output()
+	if out := pid.Update(100, 1); out-prev > 0.3+1e-9 {
+		t.Errorf("step %.2f exceeds MaxStep", out-prev)
+	}
+
+	pid.Reset()
+
+	if pid.Output() != 0 || pid.Kp != 0.5 {
+		t.Errorf("Reset = %+v", pid)
+	}
+}
+
+func TestRubberBand(t *testing.T) {
+	band := NewRubberBand(20, 60, 140, 2)
+
+	for d, want := range map[float64]float64{0: 20, 0.25: 50, 0.5: 60, 0.75: 80, 1: 140, 2: 140} {
+		if got := band.At(d); math.Abs(got-want) > 1e-9 {
+			t.Errorf("At(%v) = %v, want %v", d, got, want)
+		}
+	}
+
+	band.Shape = NewStepCurve(0.5)
+	if band.At(0.7) != 60 || band.At(0.8) != 140 {
+		t.Errorf("step shape: At(0.7) = %v, At(0.8) = %v", band.At(0.7), band.At(0.8))
+	}
+}
+
+func TestDifficultyCohorts(t *testing.T) {
+	engine := NewDifficultyEngine(DefaultDifficultyConfig())
+
+	variant := DefaultDifficultyConfig()
+	variant.TargetWinRate = 0.7
+	engine.AddCohort("easy", 1, variant)
+
+	counts := map[string]int{}
+
+	for i := range 1000 {
+		id := "player-" + string(rune('a'+i%26)) + strings.Repeat("x", i/26)
+		counts[engine.CohortFor(id)]++
+
+		if engine.Player(id).Cohort != engine.CohortFor(id) {
+			t.Fatalf("%s changed cohort", id)
+		}
+	}
+
+	if counts["control"] < 400 || counts["easy"] < 400 {
+		t.Errorf("split = %v", counts)
+	}
+
+	// The easier arm starts new players lower.
+	if _, err := engine.Assign("new", "easy"); err != nil || engine.Difficulty("new") >= 0.5 {
+		t.Errorf("easy cohort difficulty = %.2f, %v", engine.Difficulty("new"), err)
+	}
+
+	if _, err := engine.Assign("x", "hard"); !errors.Is(err, ErrUnknownCohort) {
+		t.Errorf("Assign to missing cohort = %v", err)
+	}
+
+	ids := make([]string, 100)
+	before := make([]string, 100)
+
+	for i := range ids {
+		ids[i] = "salted-" + strings.Repeat("y", i+1)
+		before[i] = engine.CohortFor(ids[i])
+	}
+
+	engine.Salt = "round-2"
+	moved := 0
+
+	for i, id := range ids {
+		if engine.CohortFor(id) != before[i] {
+			moved++
+		}
+	}
+
+	if moved < 20 || moved > 80 {
+		t.Errorf("a new salt moved %d of 100 players", moved)
+	}
+}
+
+func TestDifficultyEngineMetrics(t *testing.T) {
+	config := DefaultDifficultyConfig()
+	config.WinRateWeight = 0
+	config.Metrics = []DifficultyMetric{{Name: "deaths", Target: 3, Tolerance: 1, Inverted: true}}
+	config.Parameters["spawn_rate"] = NewRubberBand(0.5, 1, 3, 1)
+
+	engine := NewDifficultyEngine(config)
+	player := engine.Player("p1")
+	start := player.Parameter("spawn_rate")
+
+	// Dying once per level is too easy; the controller ramps spawns up.
+	for range 20 {
+		player.RecordMetric("deaths", 1)
+		player.Update()
+	}
+
+	if got := player.Parameter("spawn_rate"); got <= start || engine.Parameters("p1")["spawn_rate"] != got {
+		t.Errorf("spawn_rate %.2f -> %.2f, want higher", start, got)
+	}
+
+	manager := game.NewDifficultyManager()
+	manager.EnableDynamic(true)
+	manager.Model = engine.Player("p2")
+
+	for range 10 {
+		manager.RecordWin()
+	}
+
+	if manager.GetCurrentScale() <= 1 || engine.Player("p2").Sessions != 10 || manager.WinsRecent != 0 {
+		t.Errorf("scale after 10 wins = %.2f, wins counted = %d", manager.GetCurrentScale(), manager.WinsRecent)
+	}
+}
+
+func TestDifficultyEval(t *testing.T) {
+	config := DefaultDifficultyConfig()
+	config.Parameters["enemy_health"] = NewRubberBand(20, 60, 140, 1)
+
+	bayes := config
+	bayes.Model = NewBayesianModel()
+
+	engine := NewDifficultyEngine(config)
+	engine.AddCohort("bayes", 1, bayes)
+
+	eval := DifficultyEval{
+		Engine:   engine,
+		NewGame:  newDuelGame,
+		Bots:     SkillBots(func(int64) Player { return attacker{} }, 0.3, 0.9),
+		Sessions: 60,
+		MaxTicks: 600,
+		Seed:     7,
+		Outcome:  func(run RunResult) float64 { return float64(run.FinalScore) },
+	}
+
+	report, err := eval.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Traces) != 4 || len(report.Cohorts) != 2 || report.Cohorts[1].Sessions != 120 {
+		t.Fatalf("report = %+v", report.Cohorts)
+	}
+
+	for i := 0; i < len(report.Traces); i += 2 {
+		weak, strong := report.Traces[i], report.Traces[i+1]
+		if strong.FinalDifficulty <= weak.FinalDifficulty+0.2 || strong.Skill.Mu <= weak.Skill.Mu {
+			t.Errorf("%s: weak %.2f (%.0f), strong %.2f (%.0f)", weak.Cohort,
+				weak.FinalDifficulty, weak.Skill.Mu, strong.FinalDifficulty, strong.Skill.Mu)
+		}
+
+		for _, tr := range []DifficultyTrace{weak, strong} {
+			if math.Abs(tr.RecentWinRate-0.5) > 0.25 {
+				t.Errorf("%s/%s recent win rate %.2f", tr.Cohort, tr.Bot, tr.RecentWinRate)
+			}
+		}
+	}
+
+	md := report.GenerateMarkdown()
+	if !strings.Contains(md, "| bayes | skill-0.90 | 0.90 |") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
@@ -0,0 +1,413 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// Embeddings
+// ============================================================================
+
+// Embedder turns text into fixed-size vectors for similarity search.
+type Embedder interface {
+	// Name identifies the model so saved indexes can detect a mismatch.
+	Name() string
+	// Dimensions returns the vector size.
+	Dimensions() int
+	// Embed returns one vector per text.
+	Embed(texts []string) ([][]float32, error)
+}
+
+// HashingEmbedder is an offline embedder using the hashing trick: word
+// unigrams and bigrams are hashed into a fixed number of signed buckets with
+// sublinear term frequency, then L2-normalized. It needs no training and
+// gives stable vectors, so entries can be added incrementally.
+type HashingEmbedder struct {
+	Dims    int
+	Bigrams bool
+}
+
+// NewHashingEmbedder creates a hashing embedder with dims buckets.
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{Dims: dims, Bigrams: true}
+}
+
+// Name implements Embedder.
+func (e *HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing-%d-bigrams=%v", e.Dims, e.Bigrams)
+}
+
+// Dimensions implements Embedder.
+func (e *HashingEmbedder) Dimensions() int {
+	return e.Dims
+}
+
+// Embed implements Embedder.
+func (e *HashingEmbedder) Embed(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = e.embed(text)
+	}
+
+	return out, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	counts := make(map[string]int)
+	tokens := Tokenize(text)
+
+	for i, tok := range tokens {
+		counts[tok]++
+
+		if e.Bigrams && i > 0 {
+			counts[tokens[i-1]+" "+tok]++
+		}
+	}
+
+	vec := make([]float32, e.Dims)
+
+	for term, n := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(term))
+		sum := h.Sum64()
+
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+
+		vec[sum%uint64(e.Dims)] += sign * float32(1+math.Log(float64(n)))
+	}
+
+	normalizeVector(vec)
+
+	return vec
+}
+
+// stopWords are skipped by Tokenize.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "to": true, "was": true, "were": true,
+	"with": true, "who": true, "what": true, "where": true, "which": true, "its": true,
+}
+
+// Tokenize lowercases text and splits it into words, dropping stop words.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	out := fields[:0]
+
+	for _, f := range fields {
+		if !stopWords[f] {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b.
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, na, nb float64
+
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+func normalizeVector(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+
+	if sum == 0 {
+		return
+	}
+
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+// ChunkText splits text into chunks of at most maxWords words, breaking at
+// sentence ends where possible. Consecutive chunks share overlap words.
+func ChunkText(text string, maxWords, overlap int) []string {
+	words := strings.Fields(text)
+	if maxWords <= 0 || len(words) <= maxWords {
+		if len(words) == 0 {
+			return nil
+		}
+
+		return []string{strings.Join(words, " ")}
+	}
+
+	overlap = max(0, min(overlap, maxWords/2))
+
+	var chunks []string
+
+	for start := 0; start < len(words); {
+		end := min(start+maxWords, len(words))
+
+		// Prefer ending on a sentence boundary in the second half of the chunk
+		if end < len(words) {
+			for i := end; i > start && i >= start+maxWords/2; i-- {
+				if strings.ContainsAny(words[i-1][len(words[i-1])-1:], ".!?") {
+					end = i
+
+					break
+				}
+			}
+		}
+
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+
+		if end == len(words) {
+			break
+		}
+
+		start = max(end-overlap, start+1)
+	}
+
+	return chunks
+}
+
+// ============================================================================
+// Vector Index
+// ============================================================================
+
+// IndexedChunk is one embedded piece of a document.
+type IndexedChunk struct {
+	DocID  string    `json:"doc"`
+	Chunk  int       `json:"chunk"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// VectorHit is a search result.
+type VectorHit struct {
+	*IndexedChunk
+
+	Score float32
+}
+
+// VectorIndex stores embedded chunks and finds the nearest by cosine similarity.
+type VectorIndex struct {
+	Model  string          `json:"model"`
+	Dims   int             `json:"dims"`
+	Chunks []*IndexedChunk `json:"chunks"`
+}
+
+// NewVectorIndex creates an empty index for an embedder.
+func NewVectorIndex(embedder Embedder) *VectorIndex {
+	return &VectorIndex{Model: embedder.Name(), Dims: embedder.Dimensions()}
+}
+
+// Add appends a chunk. Its vector is normalized in place. Vectors must have
+// as many dimensions as those already in the index.
+func (idx *VectorIndex) Add(chunk *IndexedChunk) error {
+	if len(idx.Chunks) > 0 && len(chunk.Vector) != len(idx.Chunks[0].Vector) {
+		return fmt.Errorf("vector index: chunk %s/%d has %d dims, want %d",
+			chunk.DocID, chunk.Chunk, len(chunk.Vector), len(idx.Chunks[0].Vector))
+	}
+
+	normalizeVector(chunk.Vector)
+	idx.Chunks = append(idx.Chunks, chunk)
+
+	return nil
+}
+
+// Remove deletes all chunks of a document.
+func (idx *VectorIndex) Remove(docID string) {
+	kept := idx.Chunks[:0]
+
+	for _, c := range idx.Chunks {
+		if c.DocID != docID {
+			kept = append(kept, c)
+		}
+	}
+
+	clear(idx.Chunks[len(kept):])
+	idx.Chunks = kept
+}
+
+// Len returns the number of chunks.
+func (idx *VectorIndex) Len() int {
+	return len(idx.Chunks)
+}
+
+// Search returns the k chunks most similar to query. filter, if set, limits
+// the candidate documents.
+func (idx *VectorIndex) Search(query []float32, k int, filter func(docID string) bool) []VectorHit {
+	q := append([]float32(nil), query...)
+	normalizeVector(q)
+
+	hits := make([]VectorHit, 0, len(idx.Chunks))
+
+	for _, c := range idx.Chunks {
+		if filter != nil && !filter(c.DocID) {
+			continue
+		}
+
+		var dot float32
+		for i := range q {
+			dot += q[i] * c.Vector[i]
+		}
+
+		hits = append(hits, VectorHit{IndexedChunk: c, Score: dot})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+
+	return hits
+}
+
+// Save writes the index to a JSON file.
+func (idx *VectorIndex) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadVectorIndex reads an index written by Save.
+func LoadVectorIndex(path string) (*VectorIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var idx VectorIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("load vector index: %w", err)
+	}
+
+	for _, c := range idx.Chunks {
+		if len(c.Vector) != idx.Dims {
+			return nil, fmt.Errorf("load vector index: chunk %s/%d has %d dims, want %d",
+				c.DocID, c.Chunk, len(c.Vector), idx.Dims)
+		}
+	}
+
+	return &idx, nil
+}
+
+// ============================================================================
+// BM25 Keyword Index
+// ============================================================================
+
+// bm25Index scores chunks by keyword relevance with Okapi BM25.
+type bm25Index struct {
+	k1, b  float64
+	docs   map[*IndexedChunk]map[string]int
+	lens   map[*IndexedChunk]int
+	df     map[string]int
+	totLen int
+}
+
+func newBM25Index() *bm25Index {
+	return &bm25Index{
+		k1:   1.2,
+		b:    0.75,
+		docs: make(map[*IndexedChunk]map[string]int),
+		lens: make(map[*IndexedChunk]int),
+		df:   make(map[string]int),
+	}
+}
+
+func (x *bm25Index) add(c *IndexedChunk, text string) {
+	tf := make(map[string]int)
+	tokens := Tokenize(text)
+
+	for _, t := range tokens {
+		tf[t]++
+	}
+
+	for t := range tf {
+		x.df[t]++
+	}
+
+	x.docs[c] = tf
+	x.lens[c] = len(tokens)
+	x.totLen += len(tokens)
+}
+
+func (x *bm25Index) remove(c *IndexedChunk) {
+	tf, ok := x.docs[c]
+	if !ok {
+		return
+	}
+
+	for t := range tf {
+		x.df[t]--
+		if x.df[t] == 0 {
+			delete(x.df, t)
+		}
+	}
+
+	x.totLen -= x.lens[c]
+	delete(x.docs, c)
+	delete(x.lens, c)
+}
+
+// score returns the BM25 score of every chunk containing a query term.
+func (x *bm25Index) score(query string) map[*IndexedChunk]float64 {
+	scores := make(map[*IndexedChunk]float64)
+	n := float64(len(x.docs))
+
+	if n == 0 {
+		return scores
+	}
+
+	avg := float64(x.totLen) / n
+
+	for _, term := range Tokenize(query) {
+		df := float64(x.df[term])
+		if df == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for c, tf := range x.docs {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+
+			norm := f * (x.k1 + 1) / (f + x.k1*(1-x.b+x.b*float64(x.lens[c])/avg))
+			scores[c] += idf * norm
+		}
+	}
+
+	return scores
+}
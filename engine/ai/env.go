@@ -0,0 +1,676 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// ErrEpisodeDone is returned by Env.Step after the episode ended.
+var ErrEpisodeDone = errors.New("env: episode is done, call Reset")
+
+// Seeder is implemented by adapters whose randomness can be seeded.
+// Env.Reset seeds such adapters before resetting them.
+type Seeder interface {
+	Seed(seed int64)
+}
+
+// ============================================================================
+// Observations
+// ============================================================================
+
+// EnvObservation is what an environment reports after Reset and Step.
+// Numeric encoders fill Vector (row-major in Shape); text encoders fill Text.
+type EnvObservation struct {
+	Vector []float64 `json:"vector,omitempty"`
+	Shape  []int     `json:"shape,omitempty"`
+	Text   string    `json:"text,omitempty"`
+}
+
+// EnvSpace describes the observations an encoder produces.
+type EnvSpace struct {
+	Shape  []int    `json:"shape,omitempty"` // Vector shape; empty for text only
+	Labels []string `json:"labels,omitempty"`
+	Text   bool     `json:"text,omitempty"`
+}
+
+// ObservationEncoder turns the game state into an observation.
+type ObservationEncoder interface {
+	Space() EnvSpace
+	Encode(state GameState) EnvObservation
+}
+
+// StateVectorEncoder encodes GameState as numbers: score, player x and y,
+// player health fraction, entity count, then the listed CustomData keys.
+// Each feature is multiplied by the matching Scale entry, if any.
+type StateVectorEncoder struct {
+	CustomKeys []string
+	Scale      []float64
+}
+
+// NewStateVectorEncoder creates a state vector encoder.
+func NewStateVectorEncoder(customKeys ...string) *StateVectorEncoder {
+	return &StateVectorEncoder{CustomKeys: customKeys}
+}
+
+// Space implements ObservationEncoder.
+func (e *StateVectorEncoder) Space() EnvSpace {
+	labels := append([]string{"score", "x", "y", "health", "entities"}, e.CustomKeys...)
+
+	return EnvSpace{Shape: []int{len(labels)}, Labels: labels}
+}
+
+// Encode implements ObservationEncoder.
+func (e *StateVectorEncoder) Encode(state GameState) EnvObservation {
+	keys := e.Space().Labels
+	v := make([]float64, len(keys))
+
+	for i, key := range keys {
+		v[i] = stateFeature(state, key)
+		if i < len(e.Scale) {
+			v[i] *= e.Scale[i]
+		}
+	}
+
+	return EnvObservation{Vector: v, Shape: []int{len(v)}}
+}
+
+// FeatureEncoder encodes selected named features. Keys are "tick",
+// "score", "x", "y", "health" (fraction), "entities" or CustomData keys;
+// booleans encode as 0 or 1 and missing keys as 0.
+type FeatureEncoder struct {
+	Keys []string
+}
+
+// NewFeatureEncoder creates a feature encoder.
+func NewFeatureEncoder(keys ...string) *FeatureEncoder {
+	return &FeatureEncoder{Keys: keys}
+}
+
+// Space implements ObservationEncoder.
+func (e *FeatureEncoder) Space() EnvSpace {
+	return EnvSpace{Shape: []int{len(e.Keys)}, Labels: e.Keys}
+}
+
+// Encode implements ObservationEncoder.
+func (e *FeatureEncoder) Encode(state GameState) EnvObservation {
+	v := make([]float64, len(e.Keys))
+	for i, key := range e.Keys {
+		v[i] = stateFeature(state, key)
+	}
+
+	return EnvObservation{Vector: v, Shape: []int{len(v)}}
+}
+
+func stateFeature(state GameState, key string) float64 {
+	switch key {
+	case "tick":
+		return float64(state.Tick)
+	case "score":
+		return float64(state.Score)
+	case "x":
+		return state.PlayerPos[0]
+	case "y":
+		return state.PlayerPos[1]
+	case "health":
+		if state.PlayerHealth[1] <= 0 {
+			return 0
+		}
+
+		return float64(state.PlayerHealth[0]) / float64(state.PlayerHealth[1])
+	case "entities":
+		return float64(state.EntityCount)
+	}
+
+	if b, ok := state.CustomData[key].(bool); ok {
+		if b {
+			return 1
+		}
+
+		return 0
+	}
+
+	f, _ := toFloat(state.CustomData[key])
+
+	return f
+}
+
+// GridEncoder rasterizes the world into a [channels, height, width] grid.
+// Each channel marks the cells holding entities of one type, taken from
+// AIMetadata.EntityType or else Tag.Name. With FollowPlayer the grid is
+// centered on the player, otherwise its corner is at Origin.
+type GridEncoder struct {
+	World        *ecs.World
+	Types        []string // One channel per type
+	Width        int      // Cells
+	Height       int      // Cells
+	CellSize     float64  // World units per cell
+	Origin       [2]float64
+	FollowPlayer bool
+
+	filter *ecs.Filter1[components.Position]
+	meta   *ecs.Map[components.AIMetadata]
+	tags   *ecs.Map[components.Tag]
+}
+
+// NewGridEncoder creates a grid encoder with one channel per entity type.
+func NewGridEncoder(world *ecs.World, width, height int, cellSize float64, types ...string) *GridEncoder {
+	return &GridEncoder{
+		World:    world,
+		Types:    types,
+		Width:    width,
+		Height:   height,
+		CellSize: cellSize,
+		filter:   ecs.NewFilter1[components.Position](world),
+		meta:     ecs.NewMap[components.AIMetadata](world),
+		tags:     ecs.NewMap[components.Tag](world),
+	}
+}
+
+// Space implements ObservationEncoder.
+func (e *GridEncoder) Space() EnvSpace {
+	return EnvSpace{Shape: []int{len(e.Types), e.Height, e.Width}, Labels: e.Types}
+}
+
+// Encode implements ObservationEncoder.
+func (e *GridEncoder) Encode(state GameState) EnvObservation {
+	grid := make([]float64, len(e.Types)*e.Height*e.Width)
+
+	origin := e.Origin
+	if e.FollowPlayer {
+		origin = [2]float64{
+			state.PlayerPos[0] - float64(e.Width)*e.CellSize/2,
+			state.PlayerPos[1] - float64(e.Height)*e.CellSize/2,
+		}
+	}
+
+	query := e.filter.Query()
+	for query.Next() {
+		entity := query.Entity()
+
+		var typ string
+
+		switch {
+		case e.meta.Has(entity):
+			typ = e.meta.Get(entity).EntityType
+		case e.tags.Has(entity):
+			typ = e.tags.Get(entity).Name
+		}
+
+		channel := slices.Index(e.Types, typ)
+		if channel < 0 {
+			continue
+		}
+
+		pos := query.Get()
+		cx := int(math.Floor((pos.X - origin[0]) / e.CellSize))
+		cy := int(math.Floor((pos.Y - origin[1]) / e.CellSize))
+
+		if cx < 0 || cy < 0 || cx >= e.Width || cy >= e.Height {
+			continue
+		}
+
+		grid[(channel*e.Height+cy)*e.Width+cx] = 1
+	}
+
+	return EnvObservation{Vector: grid, Shape: []int{len(e.Types), e.Height, e.Width}}
+}
+
+// TextEncoder describes the state as compact text: the CompactExporter
+// format when World is set, otherwise a one-line GameState summary.
+type TextEncoder struct {
+	World *ecs.World
+
+	exporter *CompactExporter
+}
+
+// NewTextEncoder creates a text encoder. world may be nil.
+func NewTextEncoder(world *ecs.World) *TextEncoder {
+	e := &TextEncoder{World: world}
+	if world != nil {
+		e.exporter = NewCompactExporter(world)
+	}
+
+	return e
+}
+
+// Space implements ObservationEncoder.
+func (e *TextEncoder) Space() EnvSpace {
+	return EnvSpace{Text: true}
+}
+
+// Encode implements ObservationEncoder.
+func (e *TextEncoder) Encode(state GameState) EnvObservation {
+	if e.exporter != nil {
+		return EnvObservation{Text: e.exporter.Export(e.World, state.Tick)}
+	}
+
+	return EnvObservation{Text: fmt.Sprintf("T%d|S:%d|P:%.0f,%.0f;H:%d/%d|N:%d",
+		state.Tick, state.Score, state.PlayerPos[0], state.PlayerPos[1],
+		state.PlayerHealth[0], state.PlayerHealth[1], state.EntityCount)}
+}
+
+// CombinedEncoder concatenates the vectors and texts of several encoders.
+// The combined vector is flat.
+type CombinedEncoder []ObservationEncoder
+
+// Space implements ObservationEncoder.
+func (c CombinedEncoder) Space() EnvSpace {
+	var space EnvSpace
+
+	size := 0
+
+	for _, e := range c {
+		s := e.Space()
+		size += shapeSize(s.Shape)
+		space.Labels = append(space.Labels, s.Labels...)
+		space.Text = space.Text || s.Text
+	}
+
+	if size > 0 {
+		space.Shape = []int{size}
+	}
+
+	return space
+}
+
+// Encode implements ObservationEncoder.
+func (c CombinedEncoder) Encode(state GameState) EnvObservation {
+	var (
+		obs   EnvObservation
+		texts []string
+	)
+
+	for _, e := range c {
+		o := e.Encode(state)
+		obs.Vector = append(obs.Vector, o.Vector...)
+
+		if o.Text != "" {
+			texts = append(texts, o.Text)
+		}
+	}
+
+	if len(obs.Vector) > 0 {
+		obs.Shape = []int{len(obs.Vector)}
+	}
+
+	obs.Text = strings.Join(texts, "\n")
+
+	return obs
+}
+
+func shapeSize(shape []int) int {
+	if len(shape) == 0 {
+		return 0
+	}
+
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+
+	return n
+}
+
+// ============================================================================
+// Rewards
+// ============================================================================
+
+// RewardFunc scores one adapter tick from the states before and after it.
+type RewardFunc func(prev, next GameState, action ActionType, done bool) float64
+
+// ScoreReward rewards score gained.
+func ScoreReward(prev, next GameState, _ ActionType, _ bool) float64 {
+	return float64(next.Score - prev.Score)
+}
+
+// SurvivalBonus rewards every tick the game is not over.
+func SurvivalBonus(bonus float64) RewardFunc {
+	return func(_, _ GameState, _ ActionType, done bool) float64 {
+		if done {
+			return 0
+		}
+
+		return bonus
+	}
+}
+
+// HealthPenalty penalizes health lost, scaled by scale per point.
+func HealthPenalty(scale float64) RewardFunc {
+	return func(prev, next GameState, _ ActionType, _ bool) float64 {
+		return -scale * float64(max(0, prev.PlayerHealth[0]-next.PlayerHealth[0]))
+	}
+}
+
+// TerminalPenalty is added once when the game ends.
+func TerminalPenalty(penalty float64) RewardFunc {
+	return func(_, _ GameState, _ ActionType, done bool) float64 {
+		if done {
+			return -penalty
+		}
+
+		return 0
+	}
+}
+
+// PotentialShaping adds gamma*phi(next) - phi(prev), which speeds up learning
+// without changing the optimal policy. phi is taken as 0 once the game is
+// over, as the policy-invariance guarantee requires.
+func PotentialShaping(phi func(GameState) float64, gamma float64) RewardFunc {
+	return func(prev, next GameState, _ ActionType, done bool) float64 {
+		if done {
+			return -phi(prev)
+		}
+
+		return gamma*phi(next) - phi(prev)
+	}
+}
+
+// ============================================================================
+// Environment
+// ============================================================================
+
+// EnvConfig configures an Env.
+type EnvConfig struct {
+	Actions   []ActionType       // Discrete action set (default: available actions after the first Reset)
+	Encoder   ObservationEncoder // Default: StateVectorEncoder
+	Reward    RewardFunc         // Base reward per tick (default: ScoreReward)
+	Shaping   []RewardFunc       // Added to the base reward
+	FrameSkip int                // Ticks per Step with the action repeated (default 1)
+	MaxSteps  int                // Steps before truncation (0 = unlimited)
+}
+
+// DefaultEnvConfig returns sensible defaults.
+func DefaultEnvConfig() EnvConfig {
+	return EnvConfig{FrameSkip: 1, MaxSteps: 1000}
+}
+
+// StepResult is the outcome of one Env.Step, mirroring Gym's
+// (obs, reward, terminated, truncated, info) tuple.
+type StepResult struct {
+	Observation EnvObservation `json:"obs"`
+	Reward      float64        `json:"reward"`
+	Done        bool           `json:"done"`      // The game ended
+	Truncated   bool           `json:"truncated"` // MaxSteps reached
+	Info        map[string]any `json:"info"`
+}
+
+// Env exposes a GameAdapter as a Gym-style reinforcement learning
+// environment with discrete actions.
+type Env struct {
+	Adapter GameAdapter
+	Config  EnvConfig
+
+	state         GameState
+	steps         int
+	episodeReward float64
+	done          bool
+	started       bool
+}
+
+// NewEnv wraps an adapter.
+func NewEnv(adapter GameAdapter, config EnvConfig) *Env {
+	if config.FrameSkip <= 0 {
+		config.FrameSkip = 1
+	}
+
+	if config.Encoder == nil {
+		config.Encoder = NewStateVectorEncoder()
+	}
+
+	if config.Reward == nil {
+		config.Reward = ScoreReward
+	}
+
+	return &Env{Adapter: adapter, Config: config}
+}
+
+// Actions returns the discrete action set. It is fixed on the first Reset
+// unless configured.
+func (e *Env) Actions() []ActionType {
+	return e.Config.Actions
+}
+
+// ObservationSpace describes the observations.
+func (e *Env) ObservationSpace() EnvSpace {
+	return e.Config.Encoder.Space()
+}
+
+// Reset seeds (when the adapter is a Seeder) and restarts the game.
+func (e *Env) Reset(seed int64) (EnvObservation, error) {
+	if s, ok := e.Adapter.(Seeder); ok {
+		s.Seed(seed)
+	}
+
+	if err := e.Adapter.Reset(); err != nil {
+		return EnvObservation{}, err
+	}
+
+	if len(e.Config.Actions) == 0 {
+		e.Config.Actions = slices.Clone(e.Adapter.AvailableActions())
+	}
+
+	e.state = e.Adapter.GetState()
+	e.steps = 0
+	e.episodeReward = 0
+	e.done = false
+	e.started = true
+
+	return e.Config.Encoder.Encode(e.state), nil
+}
+
+// Step performs action for FrameSkip ticks (fewer if the game ends) and
+// returns the summed reward. Actions not currently available are skipped
+// and reported as info["invalid_action"].
+func (e *Env) Step(action ActionType) (StepResult, error) {
+	if !e.started || e.done {
+		return StepResult{}, ErrEpisodeDone
+	}
+
+	var (
+		reward  float64
+		invalid bool
+		over    bool
+		ticks   int
+	)
+
+	for range e.Config.FrameSkip {
+		if contains(e.Adapter.AvailableActions(), action) {
+			if err := e.Adapter.PerformAction(action); err != nil {
+				return StepResult{}, err
+			}
+		} else {
+			invalid = true
+		}
+
+		if err := e.Adapter.Step(); err != nil {
+			return StepResult{}, err
+		}
+
+		ticks++
+		next := e.Adapter.GetState()
+		over = e.Adapter.IsGameOver()
+
+		reward += e.Config.Reward(e.state, next, action, over)
+		for _, shape := range e.Config.Shaping {
+			reward += shape(e.state, next, action, over)
+		}
+
+		e.state = next
+
+		if over {
+			break
+		}
+	}
+
+	e.steps++
+	e.episodeReward += reward
+	truncated := !over && e.Config.MaxSteps > 0 && e.steps >= e.Config.MaxSteps
+	e.done = over || truncated
+
+	info := map[string]any{
+		"tick":        e.state.Tick,
+		"score":       e.state.Score,
+		"ticks":       ticks,
+		"action_mask": e.ActionMask(),
+	}
+
+	if invalid {
+		info["invalid_action"] = true
+	}
+
+	if e.done {
+		info["episode"] = map[string]any{"reward": e.episodeReward, "steps": e.steps}
+	}
+
+	return StepResult{
+		Observation: e.Config.Encoder.Encode(e.state),
+		Reward:      reward,
+		Done:        over,
+		Truncated:   truncated,
+		Info:        info,
+	}, nil
+}
+
+// StepIndex performs the action at index i of Actions.
+func (e *Env) StepIndex(i int) (StepResult, error) {
+	if i < 0 || i >= len(e.Config.Actions) {
+		return StepResult{}, fmt.Errorf("env: action index %d out of range [0, %d)", i, len(e.Config.Actions))
+	}
+
+	return e.Step(e.Config.Actions[i])
+}
+
+// ActionMask reports which of Actions are currently available.
+func (e *Env) ActionMask() []bool {
+	available := e.Adapter.AvailableActions()
+	mask := make([]bool, len(e.Config.Actions))
+
+	for i, a := range e.Config.Actions {
+		mask[i] = contains(available, a)
+	}
+
+	return mask
+}
+
+// State returns the latest game state.
+func (e *Env) State() GameState {
+	return e.state
+}
+
+// Done reports whether the episode has ended.
+func (e *Env) Done() bool {
+	return e.done
+}
+
+// ============================================================================
+// Vectorized Environment
+// ============================================================================
+
+// VecEnv steps N independent environments in parallel. Environments that
+// finish are reset automatically; the observation that ended the episode
+// is kept in info["final_obs"].
+type VecEnv struct {
+	Envs []*Env
+
+	seeds []int64
+}
+
+// NewVecEnv creates n environments from a factory. Each call must return
+// an adapter with its own game instance.
+func NewVecEnv(n int, factory func(index int) GameAdapter, config EnvConfig) *VecEnv {
+	v := &VecEnv{Envs: make([]*Env, n), seeds: make([]int64, n)}
+
+	for i := range n {
+		c := config
+		c.Actions = slices.Clone(config.Actions)
+		v.Envs[i] = NewEnv(factory(i), c)
+	}
+
+	return v
+}
+
+// Len returns the number of environments.
+func (v *VecEnv) Len() int {
+	return len(v.Envs)
+}
+
+// Reset resets every environment, seeding environment i with seed+i.
+func (v *VecEnv) Reset(seed int64) ([]EnvObservation, error) {
+	obs := make([]EnvObservation, len(v.Envs))
+
+	err := v.parallel(func(i int, env *Env) error {
+		v.seeds[i] = seed + int64(i)
+
+		o, err := env.Reset(v.seeds[i])
+		obs[i] = o
+
+		return err
+	})
+
+	return obs, err
+}
+
+// Step performs one action per environment.
+func (v *VecEnv) Step(actions []ActionType) ([]StepResult, error) {
+	if len(actions) != len(v.Envs) {
+		return nil, fmt.Errorf("env: got %d actions for %d environments", len(actions), len(v.Envs))
+	}
+
+	results := make([]StepResult, len(v.Envs))
+
+	err := v.parallel(func(i int, env *Env) error {
+		r, err := env.Step(actions[i])
+		if err != nil {
+			return err
+		}
+
+		if r.Done || r.Truncated {
+			// Fresh seed per episode keeps auto-reset runs reproducible
+			v.seeds[i] += int64(len(v.Envs))
+
+			obs, err := env.Reset(v.seeds[i])
+			if err != nil {
+				return err
+			}
+
+			r.Info = maps.Clone(r.Info)
+			r.Info["final_obs"] = r.Observation
+			r.Observation = obs
+		}
+
+		results[i] = r
+
+		return nil
+	})
+
+	return results, err
+}
+
+// parallel runs fn for every environment concurrently.
+func (v *VecEnv) parallel(fn func(i int, env *Env) error) error {
+	errs := make([]error, len(v.Envs))
+	done := make(chan struct{})
+
+	for i, env := range v.Envs {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			if err := fn(i, env); err != nil {
+				errs[i] = fmt.Errorf("env %d: %w", i, err)
+			}
+		}()
+	}
+
+	for range v.Envs {
+		<-done
+	}
+
+	return errors.Join(errs...)
+}
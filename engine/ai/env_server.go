@@ -0,0 +1,205 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// EnvServer exposes vectorized environments to an external trainer over a
+// line-delimited JSON protocol, on stdio or TCP. Each request is one JSON
+// object per line and gets exactly one response line:
+//
+//	{"cmd": "spec"}                          -> {"ok": true, "num_envs": 4, "actions": [...], "observation_space": {...}}
+//	{"cmd": "reset", "seed": 42}             -> {"ok": true, "obs": [...]}
+//	{"cmd": "step", "actions": [0, "fire"]}  -> {"ok": true, "results": [{"obs": ..., "reward": 1, ...}]}
+//	{"cmd": "close"}                         -> {"ok": true}
+//
+// Actions are indices into the action list or action names. With a single
+// environment "action" may be given instead of "actions". Failures are
+// reported as {"ok": false, "error": "..."} and keep the connection open.
+type EnvServer struct {
+	// NewEnv creates the environments for one connection.
+	NewEnv func() (*VecEnv, error)
+}
+
+// NewEnvServer creates a server giving every connection n environments.
+func NewEnvServer(n int, factory func(index int) GameAdapter, config EnvConfig) *EnvServer {
+	return &EnvServer{NewEnv: func() (*VecEnv, error) {
+		return NewVecEnv(n, factory, config), nil
+	}}
+}
+
+// EnvRequest is one protocol request.
+type EnvRequest struct {
+	Cmd     string            `json:"cmd"`
+	Seed    int64             `json:"seed,omitempty"`
+	Action  json.RawMessage   `json:"action,omitempty"`
+	Actions []json.RawMessage `json:"actions,omitempty"`
+}
+
+// EnvResponse is one protocol response.
+type EnvResponse struct {
+	OK               bool             `json:"ok"`
+	Error            string           `json:"error,omitempty"`
+	NumEnvs          int              `json:"num_envs,omitempty"`
+	Actions          []ActionType     `json:"actions,omitempty"`
+	ObservationSpace *EnvSpace        `json:"observation_space,omitempty"`
+	Obs              []EnvObservation `json:"obs,omitempty"`
+	Results          []StepResult     `json:"results,omitempty"`
+}
+
+// Serve handles one session until "close" or the end of r. Use it with
+// os.Stdin and os.Stdout to run as a trainer's subprocess.
+func (s *EnvServer) Serve(r io.Reader, w io.Writer) error {
+	vec, err := s.NewEnv()
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var req EnvRequest
+
+		resp := EnvResponse{OK: true}
+
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = EnvResponse{Error: "bad request: " + err.Error()}
+		} else if err := handleEnvRequest(vec, &req, &resp); err != nil {
+			resp = EnvResponse{Error: err.Error()}
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+
+		if req.Cmd == "close" {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ServeListener accepts connections and serves each in its own goroutine.
+func (s *EnvServer) ServeListener(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			_ = s.Serve(conn, conn)
+		}()
+	}
+}
+
+// ListenAndServe serves TCP connections on addr.
+func (s *EnvServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	return s.ServeListener(l)
+}
+
+func handleEnvRequest(vec *VecEnv, req *EnvRequest, resp *EnvResponse) error {
+	switch req.Cmd {
+	case "spec":
+		if vec.Len() == 0 {
+			return errors.New("server has no environments")
+		}
+
+		env := vec.Envs[0]
+		if len(env.Actions()) == 0 {
+			// Actions default to the adapter's, known after a reset
+			if _, err := vec.Reset(0); err != nil {
+				return err
+			}
+		}
+
+		space := env.ObservationSpace()
+		resp.NumEnvs = vec.Len()
+		resp.Actions = env.Actions()
+		resp.ObservationSpace = &space
+
+		return nil
+
+	case "reset":
+		obs, err := vec.Reset(req.Seed)
+		resp.Obs = obs
+
+		return err
+
+	case "step":
+		raw := req.Actions
+		if len(raw) == 0 && len(req.Action) > 0 {
+			raw = []json.RawMessage{req.Action}
+		}
+
+		actions := make([]ActionType, len(raw))
+
+		for i, r := range raw {
+			if i >= vec.Len() {
+				break
+			}
+
+			a, err := decodeEnvAction(vec.Envs[i], r)
+			if err != nil {
+				return fmt.Errorf("actions[%d]: %w", i, err)
+			}
+
+			actions[i] = a
+		}
+
+		results, err := vec.Step(actions)
+		resp.Results = results
+
+		return err
+
+	case "close":
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q", req.Cmd)
+}
+
+// decodeEnvAction accepts an action index or name.
+func decodeEnvAction(env *Env, raw json.RawMessage) (ActionType, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return ActionType(name), nil
+	}
+
+	i, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return "", fmt.Errorf("action must be an index or name, got %s", raw)
+	}
+
+	if i < 0 || i >= len(env.Actions()) {
+		return "", fmt.Errorf("action index %d out of range [0, %d)", i, len(env.Actions()))
+	}
+
+	return env.Actions()[i], nil
+}
@@ -0,0 +1,289 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// countdownAdapter is a MockGameAdapter that ends after a seeded number of
+// ticks and loses health when moving left.
+type countdownAdapter struct {
+	*MockGameAdapter
+
+	seed  int64
+	limit int64
+}
+
+func newCountdownAdapter() *countdownAdapter {
+	return &countdownAdapter{MockGameAdapter: NewMockGameAdapter(), limit: 10}
+}
+
+func (c *countdownAdapter) Seed(seed int64) { c.seed = seed }
+
+func (c *countdownAdapter) Reset() error {
+	c.limit = 10 + c.seed%5
+
+	return c.MockGameAdapter.Reset()
+}
+
+func (c *countdownAdapter) PerformAction(action ActionType) error {
+	if action == ActionMoveLeft {
+		c.health[0] -= 10
+	}
+
+	return c.MockGameAdapter.PerformAction(action)
+}
+
+func (c *countdownAdapter) Step() error {
+	_ = c.MockGameAdapter.Step()
+	c.gameOver = c.tick >= c.limit
+
+	return nil
+}
+
+func TestEnvStep(t *testing.T) {
+	env := NewEnv(newCountdownAdapter(), EnvConfig{
+		FrameSkip: 3,
+		Shaping:   []RewardFunc{HealthPenalty(0.5), TerminalPenalty(100)},
+	})
+
+	if _, err := env.Step(ActionMoveUp); !errors.Is(err, ErrEpisodeDone) {
+		t.Errorf("Step before Reset should fail, got %v", err)
+	}
+
+	obs, err := env.Reset(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(obs.Vector) != 5 || obs.Vector[1] != 100 || obs.Vector[3] != 1 {
+		t.Errorf("Reset obs = %v", obs.Vector)
+	}
+
+	if len(env.Actions()) != 4 {
+		t.Fatalf("Actions should default to the adapter's, got %v", env.Actions())
+	}
+
+	r, err := env.Step(ActionMoveRight)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three ticks of +10 score, moving right 5 each
+	if r.Reward != 30 || r.Observation.Vector[1] != 115 || r.Info["ticks"] != 3 {
+		t.Errorf("Step = reward %v, obs %v, info %v", r.Reward, r.Observation.Vector, r.Info)
+	}
+
+	r, _ = env.StepIndex(2) // move_left: 3 ticks, -10 health each
+	if r.Reward != 30-15 {
+		t.Errorf("Health penalty reward = %v, want 15", r.Reward)
+	}
+
+	r, _ = env.Step("jump")
+	if r.Info["invalid_action"] != true {
+		t.Error("Unavailable actions should be reported")
+	}
+
+	// Seed 2 ends the game at tick 12, in the middle of the fourth step
+	r, _ = env.Step(ActionMoveDown)
+	if !r.Done || r.Truncated || r.Info["ticks"] != 3 || r.Reward != 30-100 {
+		t.Errorf("Final step = %+v", r)
+	}
+
+	if _, err := env.Step(ActionMoveUp); !errors.Is(err, ErrEpisodeDone) {
+		t.Errorf("Step after done should fail, got %v", err)
+	}
+
+	if _, err := env.StepIndex(9); err == nil {
+		t.Error("Out of range index should fail")
+	}
+}
+
+func TestEnvTruncation(t *testing.T) {
+	env := NewEnv(newCountdownAdapter(), EnvConfig{MaxSteps: 2, Reward: SurvivalBonus(1)})
+	_, _ = env.Reset(0)
+	_, _ = env.Step(ActionMoveUp)
+
+	r, _ := env.Step(ActionMoveUp)
+	if r.Done || !r.Truncated || !env.Done() {
+		t.Errorf("Expected truncation, got %+v", r)
+	}
+
+	if ep, _ := r.Info["episode"].(map[string]any); ep["reward"] != 2.0 || ep["steps"] != 2 {
+		t.Errorf("Episode info = %v", r.Info["episode"])
+	}
+}
+
+func TestEnvEncoders(t *testing.T) {
+	world := ecs.NewWorld()
+	pos := ecs.NewMap2[components.Position, components.AIMetadata](&world)
+	tags := ecs.NewMap2[components.Position, components.Tag](&world)
+
+	pos.NewEntity(&components.Position{X: 100, Y: 100}, &components.AIMetadata{EntityType: "player"})
+	pos.NewEntity(&components.Position{X: 115, Y: 90}, &components.AIMetadata{EntityType: "enemy"})
+	tags.NewEntity(&components.Position{X: 50, Y: 50}, &components.Tag{Name: "enemy"})
+	tags.NewEntity(&components.Position{X: 101, Y: 101}, &components.Tag{Name: "wall"})
+
+	grid := NewGridEncoder(&world, 4, 4, 10, "player", "enemy")
+	grid.FollowPlayer = true
+
+	state := GameState{PlayerPos: [2]float64{100, 100}, PlayerHealth: [2]int{5, 10}, Score: 7}
+
+	obs := grid.Encode(state)
+	if len(obs.Vector) != 32 || obs.Shape[0] != 2 {
+		t.Fatalf("Grid shape = %v", obs.Shape)
+	}
+
+	at := func(c, x, y int) float64 { return obs.Vector[(c*4+y)*4+x] }
+	if at(0, 2, 2) != 1 || at(1, 3, 1) != 1 {
+		t.Errorf("Grid = %v", obs.Vector)
+	}
+
+	sum := 0.0
+	for _, v := range obs.Vector {
+		sum += v
+	}
+
+	if sum != 2 {
+		t.Errorf("Off-grid and untracked entities should be ignored, got %v cells", sum)
+	}
+
+	vec := NewStateVectorEncoder("lives")
+	state.CustomData = map[string]any{"lives": 3}
+
+	if v := vec.Encode(state).Vector; v[3] != 0.5 || v[5] != 3 {
+		t.Errorf("State vector = %v", v)
+	}
+
+	combined := CombinedEncoder{vec, grid, NewTextEncoder(&world)}
+	if space := combined.Space(); space.Shape[0] != 6+32 || !space.Text {
+		t.Errorf("Combined space = %+v", space)
+	}
+
+	if o := combined.Encode(state); len(o.Vector) != 38 || !strings.Contains(o.Text, "P") {
+		t.Errorf("Combined obs = %d values, text %q", len(o.Vector), o.Text)
+	}
+
+	if text := NewTextEncoder(nil).Encode(state).Text; !strings.HasPrefix(text, "T0|S:7") {
+		t.Errorf("Text = %q", text)
+	}
+}
+
+func TestVecEnv(t *testing.T) {
+	vec := NewVecEnv(3, func(int) GameAdapter { return newCountdownAdapter() }, EnvConfig{})
+
+	obs, err := vec.Reset(0)
+	if err != nil || len(obs) != 3 {
+		t.Fatalf("Reset = %d obs, %v", len(obs), err)
+	}
+
+	if _, err := vec.Step([]ActionType{ActionMoveUp}); err == nil {
+		t.Error("Wrong action count should fail")
+	}
+
+	actions := []ActionType{ActionMoveUp, ActionMoveDown, ActionMoveRight}
+
+	// Env i ends after 10+i ticks and auto-resets
+	for step := 1; step <= 11; step++ {
+		results, err := vec.Step(actions)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, r := range results {
+			final, reset := r.Info["final_obs"].(EnvObservation)
+			if reset != (step == 10+i) {
+				t.Fatalf("step %d env %d: reset = %v", step, i, reset)
+			}
+
+			if reset && (final.Vector[0] != float64(10*step) || r.Observation.Vector[0] != 0) {
+				t.Errorf("env %d: final %v, new %v", i, final.Vector, r.Observation.Vector)
+			}
+		}
+	}
+}
+
+func TestEnvServer(t *testing.T) {
+	server := NewEnvServer(2, func(int) GameAdapter { return newCountdownAdapter() }, EnvConfig{})
+
+	in := strings.Join([]string{
+		`{"cmd": "spec"}`,
+		`{"cmd": "reset", "seed": 1}`,
+		`{"cmd": "step", "actions": [3, "move_up"]}`,
+		`{"cmd": "step", "actions": [7, 0]}`,
+		`not json`,
+		`{"cmd": "close"}`,
+		`{"cmd": "spec"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := server.Serve(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 responses (none after close), got %d:\n%s", len(lines), out.String())
+	}
+
+	resp := make([]EnvResponse, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &resp[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if resp[0].NumEnvs != 2 || len(resp[0].Actions) != 4 || resp[0].ObservationSpace.Shape[0] != 5 {
+		t.Errorf("spec = %s", lines[0])
+	}
+
+	if len(resp[1].Obs) != 2 {
+		t.Errorf("reset = %s", lines[1])
+	}
+
+	if r := resp[2].Results; len(r) != 2 || r[0].Observation.Vector[1] != 105 || r[1].Observation.Vector[2] != 95 {
+		t.Errorf("step = %s", lines[2])
+	}
+
+	if resp[3].OK || !strings.Contains(resp[3].Error, "out of range") {
+		t.Errorf("Bad index should be an error, got %s", lines[3])
+	}
+
+	if resp[4].OK || !resp[5].OK {
+		t.Errorf("Bad JSON should fail without closing: %s / %s", lines[4], lines[5])
+	}
+
+	out.Reset()
+
+	empty := NewEnvServer(0, func(int) GameAdapter { return newCountdownAdapter() }, EnvConfig{})
+	if err := empty.Serve(strings.NewReader(`{"cmd": "spec"}`), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var spec EnvResponse
+	if err := json.Unmarshal(out.Bytes(), &spec); err != nil || spec.OK || !strings.Contains(spec.Error, "no environments") {
+		t.Errorf("spec without envs = %s", out.String())
+	}
+}
+
+func TestPotentialShaping(t *testing.T) {
+	phi := func(s GameState) float64 { return float64(s.Score) }
+	shape := PotentialShaping(phi, 0.5)
+
+	prev, next := GameState{Score: 10}, GameState{Score: 30}
+
+	if r := shape(prev, next, ActionNone, false); r != 0.5*30-10 {
+		t.Errorf("shaped reward = %v, want 5", r)
+	}
+
+	// Terminal states have zero potential.
+	if r := shape(prev, next, ActionNone, true); r != -10 {
+		t.Errorf("terminal shaped reward = %v, want -10", r)
+	}
+}
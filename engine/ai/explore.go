@@ -0,0 +1,596 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snapshotter is implemented by adapters that can save and restore their
+// whole game state, including random number generators. Exploration then
+// branches from snapshots instead of replaying traces from the start.
+type Snapshotter interface {
+	Snapshot() (any, error)
+	Restore(snapshot any) error
+}
+
+// ============================================================================
+// Replay
+// ============================================================================
+
+// ReplayTrace seeds (when the adapter is a Seeder) and resets the game, then
+// plays trace the way QASession does: record the state, perform the action,
+// step. It stops early if the game ends. Observation ticks are step indices.
+func ReplayTrace(adapter GameAdapter, seed int64, trace []ActionType) ([]Observation, error) {
+	if s, ok := adapter.(Seeder); ok {
+		s.Seed(seed)
+	}
+
+	if err := adapter.Reset(); err != nil {
+		return nil, err
+	}
+
+	observer := NewObserver(len(trace) + 1)
+
+	for i, action := range trace {
+		if adapter.IsGameOver() {
+			break
+		}
+
+		if err := playStep(adapter, observer, int64(i), action); err != nil {
+			return observer.History(), err
+		}
+	}
+
+	return observer.History(), nil
+}
+
+// playStep records the current state and performs one action.
+func playStep(adapter GameAdapter, observer *Observer, tick int64, action ActionType) error {
+	observer.Record(tick, adapter.GetState(), action)
+
+	if err := adapter.PerformAction(action); err != nil {
+		return err
+	}
+
+	return adapter.Step()
+}
+
+// ============================================================================
+// Cells
+// ============================================================================
+
+// DefaultCellDiscretizer groups states by player position in cells of
+// cellSize, score magnitude and health quarter.
+func DefaultCellDiscretizer(cellSize float64) Discretizer {
+	return DiscretizerFunc(func(s GameState) string {
+		health := 0
+		if s.PlayerHealth[1] > 0 {
+			health = 4 * s.PlayerHealth[0] / s.PlayerHealth[1]
+		}
+
+		return fmt.Sprintf("%d,%d|s%d|h%d",
+			int(math.Floor(s.PlayerPos[0]/cellSize)),
+			int(math.Floor(s.PlayerPos[1]/cellSize)),
+			bits.Len(uint(max(0, s.Score))),
+			health)
+	})
+}
+
+// exploreCell is an archived state and the shortest known way to reach it.
+type exploreCell struct {
+	key      string
+	trace    []ActionType
+	score    int
+	snapshot any
+	tail     []Observation // Observations leading here, for detection
+	chosen   int
+	terminal bool
+}
+
+// ============================================================================
+// Explorer
+// ============================================================================
+
+// ExploreConfig configures an Explorer.
+type ExploreConfig struct {
+	Iterations      int         // Branches to explore
+	StepsPerBranch  int         // Actions per branch
+	MaxTraceLength  int         // Cells deeper than this are not explored further
+	Seed            int64       // Game seed for every trace, and exploration seed
+	Cell            Discretizer // Default: DefaultCellDiscretizer(32)
+	Player          Player      // Chooses exploration actions (default: random)
+	HistoryWindow   int         // Observations kept per cell for detection
+	MinimizeReplays int         // Replay budget per failure minimization
+}
+
+// DefaultExploreConfig returns sensible defaults.
+func DefaultExploreConfig() ExploreConfig {
+	return ExploreConfig{
+		Iterations:      200,
+		StepsPerBranch:  50,
+		MaxTraceLength:  5000,
+		HistoryWindow:   200,
+		MinimizeReplays: 300,
+	}
+}
+
+// ExploreFailure is an anomaly with a trace that reproduces it from Reset.
+type ExploreFailure struct {
+	Anomaly   Anomaly      `json:"anomaly"`
+	Seed      int64        `json:"seed"`
+	Trace     []ActionType `json:"trace"`     // As found
+	Minimized []ActionType `json:"minimized"` // Shortest reproducing trace found
+}
+
+// ExploreReport summarizes an exploration.
+type ExploreReport struct {
+	GameName   string           `json:"game_name"`
+	Seed       int64            `json:"seed"`
+	Iterations int              `json:"iterations"`
+	Steps      int              `json:"steps"`
+	Cells      int              `json:"cells"`
+	Coverage   []int            `json:"coverage"` // Cells after each iteration
+	MaxDepth   int              `json:"max_depth"`
+	BestScore  int              `json:"best_score"`
+	Failures   []ExploreFailure `json:"failures"`
+	Duration   time.Duration    `json:"duration"`
+}
+
+// Explorer is a coverage-guided, go-explore style QA fuzzer. It archives
+// novel states, repeatedly returns to rarely chosen ones and explores from
+// there, and turns anomalies into minimized, replayable traces.
+type Explorer struct {
+	adapter  GameAdapter
+	detector *AnomalyDetector
+	config   ExploreConfig
+
+	cells map[string]*exploreCell
+	order []string // Archive insertion order, for deterministic selection
+	rng   *rand.Rand
+	steps int
+}
+
+// NewExplorer creates an explorer for the given game.
+func NewExplorer(adapter GameAdapter, config ExploreConfig) *Explorer {
+	if config.Cell == nil {
+		config.Cell = DefaultCellDiscretizer(32)
+	}
+
+	if config.StepsPerBranch <= 0 {
+		config.StepsPerBranch = 50
+	}
+
+	if config.HistoryWindow <= 0 {
+		config.HistoryWindow = 200
+	}
+
+	return &Explorer{
+		adapter:  adapter,
+		detector: NewAnomalyDetector(),
+		config:   config,
+	}
+}
+
+// SetDetector replaces the anomaly detector.
+func (e *Explorer) SetDetector(detector *AnomalyDetector) {
+	e.detector = detector
+}
+
+// Coverage returns the archived cell keys and how deep each is.
+func (e *Explorer) Coverage() map[string]int {
+	out := make(map[string]int, len(e.cells))
+	for k, c := range e.cells {
+		out[k] = len(c.trace)
+	}
+
+	return out
+}
+
+// Run explores and returns the report. Each anomaly type is reported once,
+// with the first trace that reproduced it.
+func (e *Explorer) Run() (ExploreReport, error) {
+	start := time.Now()
+	e.cells = make(map[string]*exploreCell)
+	e.order = nil
+	e.rng = rand.New(rand.NewSource(e.config.Seed))
+	e.steps = 0
+
+	report := ExploreReport{GameName: e.adapter.Name(), Seed: e.config.Seed}
+
+	if _, err := ReplayTrace(e.adapter, e.config.Seed, nil); err != nil {
+		return report, err
+	}
+
+	if err := e.archive(nil, nil); err != nil {
+		return report, err
+	}
+
+	found := make(map[AnomalyType]bool)
+
+	for range e.config.Iterations {
+		cell := e.choose()
+		if cell == nil {
+			break
+		}
+
+		anomalies, trace, err := e.branch(cell)
+		if err != nil {
+			return report, err
+		}
+
+		for _, a := range anomalies {
+			if found[a.Type] {
+				continue
+			}
+
+			failure, ok, err := e.confirm(a, trace)
+			if err != nil {
+				return report, err
+			}
+
+			if ok {
+				found[a.Type] = true
+				report.Failures = append(report.Failures, failure)
+			}
+		}
+
+		report.Iterations++
+		report.Coverage = append(report.Coverage, len(e.cells))
+	}
+
+	for _, c := range e.cells {
+		report.MaxDepth = max(report.MaxDepth, len(c.trace))
+		report.BestScore = max(report.BestScore, c.score)
+	}
+
+	report.Steps = e.steps
+	report.Cells = len(e.cells)
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+// choose picks a cell to explore from, favouring rarely chosen ones.
+func (e *Explorer) choose() *exploreCell {
+	var (
+		candidates []*exploreCell
+		weights    []float64
+		total      float64
+	)
+
+	for _, key := range e.order {
+		c := e.cells[key]
+		if c.terminal || (e.config.MaxTraceLength > 0 && len(c.trace) >= e.config.MaxTraceLength) {
+			continue
+		}
+
+		w := 1 / math.Sqrt(float64(c.chosen)+1)
+		candidates = append(candidates, c)
+		weights = append(weights, w)
+		total += w
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	r := e.rng.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return candidates[i]
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// branch returns to cell and plays StepsPerBranch actions from it,
+// archiving new cells. It returns the anomalies seen and the full trace.
+func (e *Explorer) branch(cell *exploreCell) ([]Anomaly, []ActionType, error) {
+	cell.chosen++
+
+	history, err := e.restore(cell)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	observer := NewObserver(len(history) + e.config.StepsPerBranch)
+	for _, obs := range history {
+		observer.Record(obs.Tick, obs.State, obs.Action)
+	}
+
+	trace := slices.Clone(cell.trace)
+
+	for range e.config.StepsPerBranch {
+		if e.adapter.IsGameOver() {
+			break
+		}
+
+		action := e.nextAction()
+
+		if err := playStep(e.adapter, observer, int64(len(trace)), action); err != nil {
+			return nil, trace, err
+		}
+
+		trace = append(trace, action)
+		e.steps++
+
+		if err := e.archive(trace, observer.History()); err != nil {
+			return nil, trace, err
+		}
+	}
+
+	return e.detector.Analyze(observer.History()), trace, nil
+}
+
+// restore puts the game back into cell's state and returns the recent
+// observations leading there.
+func (e *Explorer) restore(cell *exploreCell) ([]Observation, error) {
+	if s, ok := e.adapter.(Snapshotter); ok && cell.snapshot != nil {
+		return cell.tail, s.Restore(cell.snapshot)
+	}
+
+	history, err := ReplayTrace(e.adapter, e.config.Seed, cell.trace)
+	if err != nil {
+		return nil, err
+	}
+
+	return lastN(history, e.config.HistoryWindow), nil
+}
+
+// archive records the current state's cell if it is new, or if this trace
+// reaches it with a higher score or in fewer steps.
+func (e *Explorer) archive(trace []ActionType, history []Observation) error {
+	state := e.adapter.GetState()
+	key := e.config.Cell.Key(state)
+
+	if old, ok := e.cells[key]; ok {
+		better := state.Score > old.score || (state.Score == old.score && len(trace) < len(old.trace))
+		if !better {
+			return nil
+		}
+	}
+
+	cell := &exploreCell{
+		key:      key,
+		trace:    slices.Clone(trace),
+		score:    state.Score,
+		terminal: e.adapter.IsGameOver(),
+		tail:     slices.Clone(lastN(history, e.config.HistoryWindow)),
+	}
+
+	if old, ok := e.cells[key]; ok {
+		cell.chosen = old.chosen
+	} else {
+		e.order = append(e.order, key)
+	}
+
+	if s, ok := e.adapter.(Snapshotter); ok && !cell.terminal {
+		snap, err := s.Snapshot()
+		if err != nil {
+			return err
+		}
+
+		cell.snapshot = snap
+	}
+
+	e.cells[key] = cell
+
+	return nil
+}
+
+func (e *Explorer) nextAction() ActionType {
+	available := e.adapter.AvailableActions()
+
+	if e.config.Player != nil {
+		return e.config.Player.DecideAction(e.adapter.GetState(), available)
+	}
+
+	if len(available) == 0 {
+		return ActionNone
+	}
+
+	return available[e.rng.Intn(len(available))]
+}
+
+// confirm replays trace from Reset and, if the anomaly reproduces,
+// minimizes the trace.
+func (e *Explorer) confirm(a Anomaly, trace []ActionType) (ExploreFailure, bool, error) {
+	failure := ExploreFailure{Anomaly: a, Seed: e.config.Seed, Trace: slices.Clone(trace)}
+
+	fails := func(candidate []ActionType) (bool, error) {
+		history, err := ReplayTrace(e.adapter, e.config.Seed, candidate)
+		if err != nil {
+			return false, err
+		}
+
+		for _, found := range e.detector.Analyze(history) {
+			if found.Type == a.Type {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	ok, err := fails(trace)
+	if err != nil || !ok {
+		return failure, false, err
+	}
+
+	failure.Minimized, err = MinimizeTrace(trace, fails, e.config.MinimizeReplays)
+	if err != nil {
+		return failure, false, err
+	}
+
+	// Report the anomaly as the minimized trace shows it
+	history, err := ReplayTrace(e.adapter, e.config.Seed, failure.Minimized)
+	if err != nil {
+		return failure, false, err
+	}
+
+	for _, found := range e.detector.Analyze(history) {
+		if found.Type == a.Type {
+			failure.Anomaly = found
+
+			break
+		}
+	}
+
+	return failure, true, nil
+}
+
+// ============================================================================
+// Minimization
+// ============================================================================
+
+// errBudget stops minimization when the replay budget is spent.
+var errBudget = errors.New("minimize: budget spent")
+
+// MinimizeTrace shrinks a failing trace with delta debugging: it first
+// finds the shortest failing prefix, then removes ever smaller chunks
+// while the trace still fails. fails reports whether a trace reproduces
+// the failure; budget caps its calls (0 = unlimited).
+func MinimizeTrace(trace []ActionType, fails func([]ActionType) (bool, error), budget int) ([]ActionType, error) {
+	calls := 0
+	check := func(candidate []ActionType) (bool, error) {
+		if budget > 0 && calls >= budget {
+			return false, errBudget
+		}
+
+		calls++
+
+		return fails(candidate)
+	}
+
+	best := slices.Clone(trace)
+
+	// Shortest failing prefix by binary search
+	lo, hi := 0, len(best)
+	for lo < hi {
+		mid := (lo + hi) / 2
+
+		ok, err := check(best[:mid])
+		if err != nil {
+			return finishMinimize(best, err)
+		}
+
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	best = best[:hi]
+
+	// ddmin: try removing each of n chunks, refining n when nothing helps
+	n := 2
+	for len(best) >= 2 && n <= len(best) {
+		chunk := (len(best) + n - 1) / n
+		reduced := false
+
+		for start := 0; start < len(best); start += chunk {
+			candidate := slices.Concat(best[:start], best[min(start+chunk, len(best)):])
+
+			ok, err := check(candidate)
+			if err != nil {
+				return finishMinimize(best, err)
+			}
+
+			if ok {
+				best = candidate
+				n = max(n-1, 2)
+				reduced = true
+
+				break
+			}
+		}
+
+		if !reduced {
+			if n == len(best) {
+				break
+			}
+
+			n = min(n*2, len(best))
+		}
+	}
+
+	return best, nil
+}
+
+func finishMinimize(best []ActionType, err error) ([]ActionType, error) {
+	if errors.Is(err, errBudget) {
+		return best, nil
+	}
+
+	return best, err
+}
+
+func lastN(history []Observation, n int) []Observation {
+	if len(history) <= n {
+		return history
+	}
+
+	return history[len(history)-n:]
+}
+
+// ============================================================================
+// Reporting
+// ============================================================================
+
+// GenerateMarkdown creates a markdown report.
+func (r *ExploreReport) GenerateMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Exploration Report\n\n")
+	sb.WriteString(fmt.Sprintf("**Game**: %s\n", r.GameName))
+	sb.WriteString(fmt.Sprintf("**Seed**: %d\n\n", r.Seed))
+
+	sb.WriteString("| Metric | Value |\n")
+	sb.WriteString("|--------|-------|\n")
+	sb.WriteString(fmt.Sprintf("| Iterations | %d |\n", r.Iterations))
+	sb.WriteString(fmt.Sprintf("| Steps | %d |\n", r.Steps))
+	sb.WriteString(fmt.Sprintf("| Cells | %d |\n", r.Cells))
+	sb.WriteString(fmt.Sprintf("| Max Depth | %d |\n", r.MaxDepth))
+	sb.WriteString(fmt.Sprintf("| Best Score | %d |\n", r.BestScore))
+	sb.WriteString(fmt.Sprintf("| Failures | %d |\n\n", len(r.Failures)))
+
+	for _, f := range r.Failures {
+		sb.WriteString(fmt.Sprintf("## [%s] %s\n\n", f.Anomaly.Severity.String(), f.Anomaly.Type))
+		sb.WriteString(fmt.Sprintf("%s @ tick %d\n\n", f.Anomaly.Description, f.Anomaly.Tick))
+		sb.WriteString(fmt.Sprintf("Minimized from %d to %d actions:\n\n", len(f.Trace), len(f.Minimized)))
+		sb.WriteString("```\n" + formatTrace(f.Minimized) + "\n```\n\n")
+	}
+
+	return sb.String()
+}
+
+// formatTrace writes a trace compactly, collapsing repeats: "move_up x3".
+func formatTrace(trace []ActionType) string {
+	var parts []string
+
+	for i := 0; i < len(trace); {
+		j := i
+		for j < len(trace) && trace[j] == trace[i] {
+			j++
+		}
+
+		if j-i > 1 {
+			parts = append(parts, fmt.Sprintf("%s x%d", trace[i], j-i))
+		} else {
+			parts = append(parts, string(trace[i]))
+		}
+
+		i = j
+	}
+
+	return strings.Join(parts, ", ")
+}
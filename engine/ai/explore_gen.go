@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// GoTestOptions controls generated regression tests.
+type GoTestOptions struct {
+	Package      string // Package clause of the generated file
+	AdapterExpr  string // Go expression creating a fresh adapter
+	DetectorExpr string // Default: "ai.NewAnomalyDetector()"
+	Imports      []string
+	NamePrefix   string // Default: "TestExplore"
+}
+
+// GenerateGoTests renders failures as Go tests that replay each minimized
+// trace and fail while the anomaly still reproduces. Commit the output next
+// to the game's other tests to keep found bugs fixed.
+func GenerateGoTests(failures []ExploreFailure, opts GoTestOptions) ([]byte, error) {
+	if opts.Package == "" || opts.AdapterExpr == "" {
+		return nil, errors.New("explore: package and adapter expression are required")
+	}
+
+	if opts.DetectorExpr == "" {
+		opts.DetectorExpr = "ai.NewAnomalyDetector()"
+	}
+
+	if opts.NamePrefix == "" {
+		opts.NamePrefix = "TestExplore"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("// Code generated by ai.GenerateGoTests. DO NOT EDIT.\n\n")
+	sb.WriteString(fmt.Sprintf("package %s\n\n", opts.Package))
+	sb.WriteString("import (\n\t\"testing\"\n\n")
+	sb.WriteString("\t\"github.com/skyrocket-qy/NeuralWay/engine/ai\"\n")
+
+	for _, imp := range opts.Imports {
+		sb.WriteString(fmt.Sprintf("\t%q\n", imp))
+	}
+
+	sb.WriteString(")\n")
+
+	for i, f := range failures {
+		trace := f.Minimized
+		if trace == nil {
+			trace = f.Trace
+		}
+
+		desc := commentLines.Replace(strings.TrimSpace(f.Anomaly.Description))
+		sb.WriteString(fmt.Sprintf("\n// %s: %s\n", f.Anomaly.Type, desc))
+		sb.WriteString(fmt.Sprintf("func %s%s%d(t *testing.T) {\n", opts.NamePrefix, exportedName(string(f.Anomaly.Type)), i+1))
+		sb.WriteString("\ttrace := []ai.ActionType{")
+
+		for j, action := range trace {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+
+			sb.WriteString(fmt.Sprintf("%q", action))
+		}
+
+		sb.WriteString("}\n\n")
+		sb.WriteString(fmt.Sprintf("\thistory, err := ai.ReplayTrace(%s, %d, trace)\n", opts.AdapterExpr, f.Seed))
+		sb.WriteString("\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n\n")
+		sb.WriteString(fmt.Sprintf("\tfor _, a := range %s.Analyze(history) {\n", opts.DetectorExpr))
+		sb.WriteString(fmt.Sprintf("\t\tif a.Type == %q {\n", f.Anomaly.Type))
+		sb.WriteString("\t\t\tt.Errorf(\"%s @ tick %d\", a.Description, a.Tick)\n")
+		sb.WriteString("\t\t}\n\t}\n}\n")
+	}
+
+	return format.Source([]byte(sb.String()))
+}
+
+// commentLines keeps every line of a multi-line text inside a // comment.
+var commentLines = strings.NewReplacer("\r\n", "\n// ", "\n", "\n// ", "\r", "\n// ")
+
+// WriteGoTests generates tests for failures and writes them to path.
+func WriteGoTests(path string, failures []ExploreFailure, opts GoTestOptions) error {
+	src, err := GenerateGoTests(failures, opts)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, src, 0o644)
+}
+
+// exportedName turns "boundary_violation" into "BoundaryViolation".
+func exportedName(s string) string {
+	var sb strings.Builder
+
+	upper := true
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
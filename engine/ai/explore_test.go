@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// roomAdapter is a 21x21 room. Stepping onto the trap cell at (15, 15)
+// drops the player out of the world, a bug random play rarely reaches.
+type roomAdapter struct {
+	x, y int
+	tick int64
+	lost bool
+}
+
+func (r *roomAdapter) Name() string     { return "Room" }
+func (r *roomAdapter) GetScore() int    { return 0 }
+func (r *roomAdapter) IsGameOver() bool { return false }
+
+func (r *roomAdapter) GetState() GameState {
+	pos := [2]float64{float64(r.x), float64(r.y)}
+	if r.lost {
+		pos = [2]float64{-100, -100}
+	}
+
+	return GameState{Tick: r.tick, PlayerPos: pos, PlayerHealth: [2]int{1, 1}}
+}
+
+func (r *roomAdapter) AvailableActions() []ActionType {
+	return []ActionType{ActionMoveUp, ActionMoveDown, ActionMoveLeft, ActionMoveRight}
+}
+
+func (r *roomAdapter) PerformAction(action ActionType) error {
+	switch action {
+	case ActionMoveUp:
+		r.y = max(r.y-1, 0)
+	case ActionMoveDown:
+		r.y = min(r.y+1, 20)
+	case ActionMoveLeft:
+		r.x = max(r.x-1, 0)
+	case ActionMoveRight:
+		r.x = min(r.x+1, 20)
+	}
+
+	return nil
+}
+
+func (r *roomAdapter) Step() error {
+	r.tick++
+	r.lost = r.lost || (r.x == 15 && r.y == 15)
+
+	return nil
+}
+
+func (r *roomAdapter) Reset() error {
+	*r = roomAdapter{}
+
+	return nil
+}
+
+// snapshotRoom adds snapshot support to roomAdapter.
+type snapshotRoom struct{ roomAdapter }
+
+func (r *snapshotRoom) Snapshot() (any, error) { return r.roomAdapter, nil }
+
+func (r *snapshotRoom) Restore(snapshot any) error {
+	r.roomAdapter = snapshot.(roomAdapter)
+
+	return nil
+}
+
+func TestExplorerFindsAndMinimizes(t *testing.T) {
+	adapters := map[string]GameAdapter{"snapshot": &snapshotRoom{}, "replay": &roomAdapter{}}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultExploreConfig()
+			config.Iterations = 400
+			config.StepsPerBranch = 10
+			config.Seed = 7
+			config.Cell = DefaultCellDiscretizer(1)
+
+			report, err := NewExplorer(adapter, config).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Cells < 100 || len(report.Coverage) != report.Iterations {
+				t.Errorf("coverage = %d cells over %d iterations", report.Cells, report.Iterations)
+			}
+
+			if len(report.Failures) != 1 || report.Failures[0].Anomaly.Type != AnomalyBoundaryViolation {
+				t.Fatalf("failures = %+v, want one boundary violation", report.Failures)
+			}
+
+			f := report.Failures[0]
+			if len(f.Minimized) > len(f.Trace) || len(f.Minimized) > 40 {
+				t.Errorf("minimized %d -> %d actions, want at most 40", len(f.Trace), len(f.Minimized))
+			}
+
+			history, err := ReplayTrace(&roomAdapter{}, f.Seed, f.Minimized)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(NewAnomalyDetector().Analyze(history)) == 0 {
+				t.Error("Minimized trace should reproduce the anomaly")
+			}
+
+			if !strings.Contains(report.GenerateMarkdown(), "boundary_violation") {
+				t.Error("Report should list the failure")
+			}
+		})
+	}
+}
+
+func TestMinimizeTrace(t *testing.T) {
+	// Fails when the trace contains jump followed later by attack
+	fails := func(trace []ActionType) (bool, error) {
+		jumped := false
+
+		for _, a := range trace {
+			jumped = jumped || a == ActionJump
+			if jumped && a == ActionAttack {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	trace := []ActionType{
+		ActionMoveLeft, ActionJump, ActionMoveUp, ActionNone, ActionMoveDown,
+		ActionAttack, ActionMoveRight, ActionAttack,
+	}
+
+	got, err := MinimizeTrace(trace, fails, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0] != ActionJump || got[1] != ActionAttack {
+		t.Errorf("MinimizeTrace = %v, want [jump attack]", got)
+	}
+}
+
+func TestGenerateGoTests(t *testing.T) {
+	failures := []ExploreFailure{{
+		Anomaly:   Anomaly{Type: AnomalyBoundaryViolation, Description: "Player out of bounds\r\nat x = -3\n"},
+		Seed:      3,
+		Minimized: []ActionType{ActionMoveRight, ActionJump},
+	}}
+
+	src, err := GenerateGoTests(failures, GoTestOptions{Package: "game", AdapterExpr: "NewAdapter(NewGame())"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "gen_test.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{"// at x = -3\nfunc TestExploreBoundaryViolation1(", `"move_right", "jump"`, "ai.ReplayTrace(NewAdapter(NewGame()), 3, trace)"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code missing %q:\n%s", want, src)
+		}
+	}
+
+	if _, err := GenerateGoTests(failures, GoTestOptions{}); err == nil {
+		t.Error("Missing options should fail")
+	}
+}
@@ -0,0 +1,447 @@
+package ai
+
+import (
+	"container/heap"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ============================================================================
+// World State
+// ============================================================================
+
+// WorldState is a set of named facts used by the GOAP planner.
+// Values must be comparable (bool, int, string, ...).
+type WorldState map[string]any
+
+// Clone returns a copy of the state.
+func (s WorldState) Clone() WorldState {
+	return maps.Clone(s)
+}
+
+// Satisfies returns true if every fact in other has the same value in s.
+// Missing boolean facts count as false.
+func (s WorldState) Satisfies(other WorldState) bool {
+	for k, want := range other {
+		if !factEqual(s[k], want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply returns a new state with effects applied.
+func (s WorldState) Apply(effects WorldState) WorldState {
+	next := s.Clone()
+	if next == nil {
+		next = make(WorldState, len(effects))
+	}
+
+	maps.Copy(next, effects)
+
+	return next
+}
+
+// Unsatisfied counts facts in goal that s does not meet.
+func (s WorldState) Unsatisfied(goal WorldState) int {
+	n := 0
+
+	for k, want := range goal {
+		if !factEqual(s[k], want) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// key returns a canonical string for the state.
+func (s WorldState) key() string {
+	var sb strings.Builder
+
+	for _, k := range slices.Sorted(maps.Keys(s)) {
+		if s[k] == false {
+			continue // false and missing are equivalent
+		}
+
+		fmt.Fprintf(&sb, "%s=%v;", k, s[k])
+	}
+
+	return sb.String()
+}
+
+func factEqual(have, want any) bool {
+	if have == nil {
+		have = false
+	}
+
+	if want == nil {
+		want = false
+	}
+
+	return have == want
+}
+
+// ============================================================================
+// Actions, Goals and Planner
+// ============================================================================
+
+// GOAPAction is an action the planner can sequence.
+type GOAPAction struct {
+	Name          string
+	Cost          float64
+	Preconditions WorldState
+	Effects       WorldState
+
+	// CheckProcedural is an optional runtime precondition (e.g. target in range).
+	CheckProcedural func(ctx *BTContext) bool
+
+	// Perform executes the action. It is ticked until it stops Running.
+	Perform Node
+}
+
+// NewGOAPAction creates an action with cost 1.
+func NewGOAPAction(name string, pre, effects WorldState, perform Node) *GOAPAction {
+	return &GOAPAction{
+		Name:          name,
+		Cost:          1,
+		Preconditions: pre,
+		Effects:       effects,
+		Perform:       perform,
+	}
+}
+
+// usable returns true if the action can run from state.
+func (a *GOAPAction) usable(state WorldState, ctx *BTContext) bool {
+	if !state.Satisfies(a.Preconditions) {
+		return false
+	}
+
+	return a.CheckProcedural == nil || ctx == nil || a.CheckProcedural(ctx)
+}
+
+// GOAPGoal is a desired world state with a priority.
+type GOAPGoal struct {
+	Name     string
+	State    WorldState
+	Priority func(ctx *BTContext) float64 // Higher is more important
+}
+
+// NewGOAPGoal creates a goal with a fixed priority.
+func NewGOAPGoal(name string, state WorldState, priority float64) *GOAPGoal {
+	return &GOAPGoal{
+		Name:     name,
+		State:    state,
+		Priority: func(*BTContext) float64 { return priority },
+	}
+}
+
+// GOAPPlanner finds the cheapest action sequence reaching a goal with A*.
+type GOAPPlanner struct {
+	MaxNodes int // Maximum states to expand per plan
+}
+
+// NewGOAPPlanner creates a planner.
+func NewGOAPPlanner() *GOAPPlanner {
+	return &GOAPPlanner{MaxNodes: 2000}
+}
+
+type goapNode struct {
+	state  WorldState
+	g, f   float64
+	action *GOAPAction
+	parent *goapNode
+	index  int
+}
+
+type goapHeap []*goapNode
+
+func (h goapHeap) Len() int           { return len(h) }
+func (h goapHeap) Less(i, j int) bool { return h[i].f < h[j].f }
+func (h goapHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *goapHeap) Push(x any) {
+	n, ok := x.(*goapNode)
+	if !ok {
+		return
+	}
+
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+
+func (h *goapHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+
+	return item
+}
+
+// Plan returns the cheapest action sequence from start to goal.
+// ctx is passed to procedural preconditions and may be nil.
+func (p *GOAPPlanner) Plan(
+	start, goal WorldState,
+	actions []*GOAPAction,
+	ctx *BTContext,
+) ([]*GOAPAction, bool) {
+	if start.Satisfies(goal) {
+		return nil, true
+	}
+
+	minCost := 0.0
+	for i, a := range actions {
+		if i == 0 || a.Cost < minCost {
+			minCost = max(a.Cost, 0)
+		}
+	}
+
+	// An unmet goal needs at least one more action. Counting unmet facts
+	// would overestimate, since one action can satisfy several of them.
+	h := func(s WorldState) float64 {
+		if s.Unsatisfied(goal) == 0 {
+			return 0
+		}
+
+		return minCost
+	}
+
+	open := &goapHeap{}
+	best := make(map[string]*goapNode)
+	closed := make(map[string]bool)
+
+	root := &goapNode{state: start.Clone(), f: h(start)}
+	heap.Push(open, root)
+	best[root.state.key()] = root
+
+	for expanded := 0; open.Len() > 0 && expanded < p.MaxNodes; expanded++ {
+		current, ok := heap.Pop(open).(*goapNode)
+		if !ok {
+			continue
+		}
+
+		if current.state.Satisfies(goal) {
+			var plan []*GOAPAction
+			for n := current; n.parent != nil; n = n.parent {
+				plan = append(plan, n.action)
+			}
+
+			slices.Reverse(plan)
+
+			return plan, true
+		}
+
+		key := current.state.key()
+		closed[key] = true
+
+		for _, a := range actions {
+			if !a.usable(current.state, ctx) {
+				continue
+			}
+
+			next := current.state.Apply(a.Effects)
+
+			nkey := next.key()
+			if closed[nkey] {
+				continue
+			}
+
+			g := current.g + a.Cost
+			if existing, ok := best[nkey]; ok {
+				if g >= existing.g {
+					continue
+				}
+
+				existing.g = g
+				existing.f = g + h(next)
+				existing.action = a
+				existing.parent = current
+				heap.Fix(open, existing.index)
+
+				continue
+			}
+
+			node := &goapNode{state: next, g: g, f: g + h(next), action: a, parent: current}
+			best[nkey] = node
+			heap.Push(open, node)
+		}
+	}
+
+	return nil, false
+}
+
+// ============================================================================
+// GOAP Behavior Tree Node
+// ============================================================================
+
+// GOAPNode plans toward the highest priority unmet goal and executes the
+// plan one action per tick, so it can be embedded in a behavior tree.
+//
+// The current world state comes from Sense if set, otherwise from the
+// blackboard entry StateKey (a WorldState). When using the blackboard, the
+// effects of completed actions are written back to it.
+type GOAPNode struct {
+	Planner  *GOAPPlanner
+	Actions  []*GOAPAction
+	Goals    []*GOAPGoal
+	StateKey string
+	Sense    func(ctx *BTContext) WorldState
+
+	plan []*GOAPAction
+	step int
+	goal *GOAPGoal
+}
+
+// NewGOAPNode creates a GOAP node reading state from ctx.Data["goap_state"].
+func NewGOAPNode(actions []*GOAPAction, goals ...*GOAPGoal) *GOAPNode {
+	return &GOAPNode{
+		Planner:  NewGOAPPlanner(),
+		Actions:  actions,
+		Goals:    goals,
+		StateKey: "goap_state",
+	}
+}
+
+// CurrentPlan returns the names of the remaining planned actions.
+func (n *GOAPNode) CurrentPlan() []string {
+	names := make([]string, 0, len(n.plan)-n.step)
+	for _, a := range n.plan[n.step:] {
+		names = append(names, a.Name)
+	}
+
+	return names
+}
+
+// CurrentGoal returns the goal being pursued, or nil.
+func (n *GOAPNode) CurrentGoal() *GOAPGoal {
+	return n.goal
+}
+
+// Reset interrupts the current action and drops the plan.
+func (n *GOAPNode) Reset() {
+	if n.step < len(n.plan) && n.plan[n.step].Perform != nil {
+		ResetNode(n.plan[n.step].Perform)
+	}
+
+	n.plan, n.step, n.goal = nil, 0, nil
+}
+
+// state reads the current world state.
+func (n *GOAPNode) state(ctx *BTContext) WorldState {
+	if n.Sense != nil {
+		return n.Sense(ctx)
+	}
+
+	if s, ok := ctx.Data[n.StateKey].(WorldState); ok {
+		return s
+	}
+
+	s := make(WorldState)
+	ctx.Data[n.StateKey] = s
+
+	return s
+}
+
+// replan selects the highest priority unmet goal that has a plan.
+// Returns false if no goal is achievable.
+func (n *GOAPNode) replan(ctx *BTContext, state WorldState) bool {
+	n.plan, n.step, n.goal = nil, 0, nil
+
+	goals := slices.Clone(n.Goals)
+	slices.SortStableFunc(goals, func(a, b *GOAPGoal) int {
+		pa, pb := a.Priority(ctx), b.Priority(ctx)
+		switch {
+		case pa > pb:
+			return -1
+		case pa < pb:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for _, g := range goals {
+		if state.Satisfies(g.State) {
+			continue
+		}
+
+		if plan, ok := n.Planner.Plan(state, g.State, n.Actions, ctx); ok {
+			n.plan, n.goal = plan, g
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *GOAPNode) Tick(ctx *BTContext) NodeStatus {
+	state := n.state(ctx)
+
+	if n.step >= len(n.plan) {
+		allMet := true
+
+		for _, g := range n.Goals {
+			if !state.Satisfies(g.State) {
+				allMet = false
+
+				break
+			}
+		}
+
+		if allMet {
+			return Success
+		}
+
+		if !n.replan(ctx, state) {
+			return Failure
+		}
+	}
+
+	action := n.plan[n.step]
+
+	// The world changed under us: replan from the current state
+	if !action.usable(state, ctx) {
+		if !n.replan(ctx, state) || len(n.plan) == 0 {
+			return Failure
+		}
+
+		action = n.plan[n.step]
+	}
+
+	status := Success
+	if action.Perform != nil {
+		status = action.Perform.Tick(ctx)
+	}
+
+	switch status {
+	case Running:
+		return Running
+	case Failure:
+		n.plan, n.step, n.goal = nil, 0, nil
+
+		return Failure
+	case Success:
+	}
+
+	if n.Sense == nil {
+		maps.Copy(state, action.Effects)
+	}
+
+	n.step++
+	if n.step >= len(n.plan) {
+		n.plan, n.step, n.goal = nil, 0, nil
+
+		return Success
+	}
+
+	return Running
+}
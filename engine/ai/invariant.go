@@ -0,0 +1,669 @@
+package ai
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// AnomalyInvariant marks a violated declarative invariant.
+const AnomalyInvariant AnomalyType = "invariant_violation"
+
+// InvariantKind is the temporal shape of an invariant.
+type InvariantKind string
+
+const (
+	InvariantAlways     InvariantKind = "always"     // Holds at every tick
+	InvariantMonotonic  InvariantKind = "monotonic"  // Never decreases (or increases), optionally except on reset
+	InvariantEventually InvariantKind = "eventually" // Holds within N ticks of the run start
+	InvariantResponse   InvariantKind = "response"   // Whenever A, B within N ticks
+)
+
+// Invariant is a parsed property. The language is:
+//
+//	[always] EXPR
+//	never EXPR
+//	EXPR never decreases|increases [except on reset]
+//	eventually EXPR within N [ticks]
+//	whenever EXPR [then] eventually EXPR within N [ticks]
+//
+// Expressions use && || ! (or and, or, not), comparisons, + - * / % and
+// parentheses over numbers, "strings" and true/false. Identifiers are
+// tick, score, x, y, health, max_health, entities, action and reset (true
+// at the first tick and whenever the game tick goes backwards), then
+// CustomData keys, then InvariantSet.Vars. Functions: prev(e), delta(e),
+// has(ident), abs, floor, min, max, and the ECS queries count([type]) and
+// outside(type, x0, y0, x1, y1). Entity types come from
+// AIMetadata.EntityType or else Tag.Name.
+type Invariant struct {
+	Name   string        `json:"name"`
+	Source string        `json:"source"`
+	Kind   InvariantKind `json:"kind"`
+	Within int64         `json:"within,omitempty"` // Ticks, for eventually and response
+
+	cond        invExpr // Checked condition; the response in InvariantResponse
+	trigger     invExpr // InvariantResponse only
+	decreasing  bool    // InvariantMonotonic: "never increases"
+	exceptReset bool    // InvariantMonotonic: "except on reset"
+	idents      []string
+}
+
+// ParseInvariant parses one invariant statement.
+func ParseInvariant(name, src string) (*Invariant, error) {
+	tokens, err := lexInvariant(src)
+	if err != nil {
+		return nil, fmt.Errorf("invariant %q: %w", name, err)
+	}
+
+	inv := &Invariant{Name: name, Source: src, Kind: InvariantAlways}
+	p := &invParser{tokens: tokens}
+
+	if err := inv.parse(p); err != nil {
+		return nil, fmt.Errorf("invariant %q: %w", name, err)
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("invariant %q: %w", name, p.errorf("unexpected token"))
+	}
+
+	inv.idents = invIdents(inv.cond, nil)
+	if inv.trigger != nil {
+		inv.idents = invIdents(inv.trigger, inv.idents)
+	}
+
+	return inv, nil
+}
+
+func (inv *Invariant) parse(p *invParser) error {
+	var err error
+
+	switch {
+	case p.accept("always"):
+		inv.cond, err = p.parseExpr()
+
+		return err
+	case p.accept("never"):
+		x, err := p.parseExpr()
+		inv.cond = &invUnary{op: "!", x: x}
+
+		return err
+	case p.accept("eventually"):
+		inv.Kind = InvariantEventually
+		if inv.cond, err = p.parseExpr(); err != nil {
+			return err
+		}
+
+		return inv.parseWithin(p)
+	case p.accept("whenever"):
+		inv.Kind = InvariantResponse
+		if inv.trigger, err = p.parseExpr(); err != nil {
+			return err
+		}
+
+		p.accept(",")
+		p.accept("then")
+
+		if err := p.expect("eventually"); err != nil {
+			return err
+		}
+
+		if inv.cond, err = p.parseExpr(); err != nil {
+			return err
+		}
+
+		return inv.parseWithin(p)
+	}
+
+	if inv.cond, err = p.parseExpr(); err != nil {
+		return err
+	}
+
+	if !p.accept("never") {
+		return nil
+	}
+
+	inv.Kind = InvariantMonotonic
+
+	switch {
+	case p.accept("decreases"):
+	case p.accept("increases"):
+		inv.decreasing = true
+	default:
+		return p.errorf("expected decreases or increases")
+	}
+
+	if p.accept("except") {
+		if err := p.expect("on"); err != nil {
+			return err
+		}
+
+		inv.exceptReset = true
+
+		return p.expect("reset")
+	}
+
+	return nil
+}
+
+func (inv *Invariant) parseWithin(p *invParser) error {
+	if err := p.expect("within"); err != nil {
+		return err
+	}
+
+	t := p.next()
+
+	n, err := strconv.ParseInt(t.text, 10, 64)
+	if t.kind != tokNumber || err != nil || n < 0 {
+		return fmt.Errorf("expected tick count after within, got %q", t.text)
+	}
+
+	inv.Within = n
+	p.accept("ticks")
+
+	return nil
+}
+
+// check evaluates the per-tick part of the invariant.
+func (inv *Invariant) check(ctx *invContext) (bool, error) {
+	v, err := inv.cond.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if inv.Kind != InvariantMonotonic {
+		return invBool(v)
+	}
+
+	if ctx.prev == nil || ctx.reset && inv.exceptReset {
+		return true, nil
+	}
+
+	now, err := invNumber(v)
+	if err != nil {
+		return false, err
+	}
+
+	before, err := invPrev(ctx, []invExpr{inv.cond})
+	if err != nil {
+		return false, err
+	}
+
+	b, err := invNumber(before)
+	if inv.decreasing {
+		return now <= b, err
+	}
+
+	return now >= b, err
+}
+
+// CheckMap evaluates an always invariant against a flat map of values;
+// temporal invariants pass. Its signature matches security.IntegrityRule:
+//
+//	checker.AddRule(inv.CheckMap)
+func (inv *Invariant) CheckMap(values map[string]any) (bool, string) {
+	if inv.Kind != InvariantAlways {
+		return true, ""
+	}
+
+	ctx := &invContext{values: values, reset: true, set: &InvariantSet{}}
+
+	v, err := inv.cond.eval(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("%s: %v", inv.Name, err)
+	}
+
+	if ok, _ := v.(bool); ok {
+		return true, ""
+	}
+
+	return false, fmt.Sprintf("%s: %s", inv.Name, inv.Source)
+}
+
+// ============================================================================
+// Invariant Set
+// ============================================================================
+
+// InvariantSet is a named collection of invariants and the context they
+// are evaluated in.
+type InvariantSet struct {
+	World       *ecs.World     // For count and outside; may be nil
+	Vars        map[string]any // Constants such as level bounds
+	TraceLength int            // Ticks kept in counterexamples (default 10)
+
+	invariants []*Invariant
+
+	filter    *ecs.Filter0
+	meta      *ecs.Map[components.AIMetadata]
+	tags      *ecs.Map[components.Tag]
+	positions *ecs.Map[components.Position]
+}
+
+// NewInvariantSet creates an empty set. world may be nil when no
+// invariant queries entities.
+func NewInvariantSet(world *ecs.World) *InvariantSet {
+	return &InvariantSet{World: world, Vars: make(map[string]any), TraceLength: 10}
+}
+
+// Add parses and adds an invariant.
+func (s *InvariantSet) Add(name, src string) error {
+	inv, err := ParseInvariant(name, src)
+	if err != nil {
+		return err
+	}
+
+	s.invariants = append(s.invariants, inv)
+
+	return nil
+}
+
+// MustAdd is Add that panics on parse errors, for invariants in code.
+func (s *InvariantSet) MustAdd(name, src string) *InvariantSet {
+	if err := s.Add(name, src); err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Load reads invariants one per line as "name: statement" or a bare
+// statement named after itself. Blank lines and # comments are skipped.
+func (s *InvariantSet) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, src := text, text
+		if before, after, ok := strings.Cut(text, ":"); ok && !strings.ContainsAny(before, " \t\"'") {
+			name, src = before, strings.TrimSpace(after)
+		}
+
+		if err := s.Add(name, src); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Invariants returns the invariants in the set.
+func (s *InvariantSet) Invariants() []*Invariant {
+	return s.invariants
+}
+
+// Check runs a monitor over a recorded history. Entity queries see the
+// world as it is now, so use a live monitor for those.
+func (s *InvariantSet) Check(history []Observation) []InvariantViolation {
+	m := s.NewMonitor()
+	for _, obs := range history {
+		m.Observe(obs.Tick, obs.State, obs.Action)
+	}
+
+	return m.Violations()
+}
+
+// Rule adapts the set to an AnomalyDetector rule. Each violation becomes
+// an anomaly whose evidence is its counterexample.
+func (s *InvariantSet) Rule() DetectionRule {
+	return func(history []Observation) []Anomaly {
+		var anomalies []Anomaly
+
+		for _, v := range s.Check(history) {
+			evidence := make([]Observation, len(v.Trace))
+			for i, step := range v.Trace {
+				evidence[i] = Observation{Tick: step.Tick, State: step.State, Action: step.Action}
+			}
+
+			anomalies = append(anomalies, Anomaly{
+				Type:        AnomalyInvariant,
+				Severity:    SeverityHigh,
+				Tick:        v.Tick,
+				Description: v.Name + ": " + v.Reason,
+				Evidence:    evidence,
+			})
+		}
+
+		return anomalies
+	}
+}
+
+// countEntities counts live entities of typ ("" = all) that pass where.
+func (s *InvariantSet) countEntities(typ string, where func(x, y float64) bool) (int, error) {
+	if s.World == nil {
+		return 0, errors.New("entity query needs a world")
+	}
+
+	if s.filter == nil {
+		s.filter = ecs.NewFilter0(s.World)
+		s.meta = ecs.NewMap[components.AIMetadata](s.World)
+		s.tags = ecs.NewMap[components.Tag](s.World)
+		s.positions = ecs.NewMap[components.Position](s.World)
+	}
+
+	n := 0
+
+	query := s.filter.Query()
+	for query.Next() {
+		entity := query.Entity()
+
+		if typ != "" {
+			var t string
+
+			switch {
+			case s.meta.Has(entity):
+				t = s.meta.Get(entity).EntityType
+			case s.tags.Has(entity):
+				t = s.tags.Get(entity).Name
+			}
+
+			if t != typ {
+				continue
+			}
+		}
+
+		if where != nil {
+			if !s.positions.Has(entity) {
+				continue
+			}
+
+			if pos := s.positions.Get(entity); !where(pos.X, pos.Y) {
+				continue
+			}
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// ============================================================================
+// Monitor
+// ============================================================================
+
+// TraceStep is one tick of a counterexample, with the values the
+// invariant reads.
+type TraceStep struct {
+	Tick   int64          `json:"tick"`
+	Action ActionType     `json:"action"`
+	Values map[string]any `json:"values"`
+	State  GameState      `json:"-"`
+}
+
+// InvariantViolation is the first failure of an invariant in a run, with
+// the ticks leading up to it.
+type InvariantViolation struct {
+	Name   string      `json:"name"`
+	Source string      `json:"source"`
+	Tick   int64       `json:"tick"`
+	Reason string      `json:"reason"`
+	Count  int         `json:"count"` // Failing ticks in the run
+	Trace  []TraceStep `json:"trace"`
+}
+
+type monitorEntry struct {
+	tick   int64
+	state  GameState
+	action ActionType
+}
+
+// invariantState tracks temporal obligations of one invariant.
+type invariantState struct {
+	satisfied bool  // InvariantEventually
+	start     int64 // Run start, or pending trigger tick; -1 when none
+	failing   bool  // Violated at the previous tick
+	violation int   // Index into violations, -1 until the first
+}
+
+// InvariantMonitor evaluates an InvariantSet tick by tick. Create one per
+// run. Obligations still open when the run ends are not violations.
+type InvariantMonitor struct {
+	set        *InvariantSet
+	states     []invariantState
+	window     []monitorEntry
+	violations []InvariantViolation
+}
+
+// NewMonitor creates a monitor for a new run.
+func (s *InvariantSet) NewMonitor() *InvariantMonitor {
+	m := &InvariantMonitor{set: s, states: make([]invariantState, len(s.invariants))}
+	for i := range m.states {
+		m.states[i] = invariantState{start: -1, violation: -1}
+	}
+
+	return m
+}
+
+// Observe checks all invariants against the state at tick, before action
+// is performed. It returns the violation records of invariants that
+// started failing at this tick.
+func (m *InvariantMonitor) Observe(tick int64, state GameState, action ActionType) []InvariantViolation {
+	ctx := &invContext{state: state, action: action, set: m.set, reset: true}
+	if n := len(m.window); n > 0 {
+		last := m.window[n-1]
+		ctx.prev = &last.state
+		ctx.reset = state.Tick < last.state.Tick
+	}
+
+	m.window = append(m.window, monitorEntry{tick: tick, state: state, action: action})
+	if limit := max(m.set.TraceLength, 1); len(m.window) > limit {
+		m.window = m.window[len(m.window)-limit:]
+	}
+
+	var found []InvariantViolation
+
+	for i, inv := range m.set.invariants {
+		reason := m.step(inv, &m.states[i], ctx, tick)
+
+		st := &m.states[i]
+		if reason == "" {
+			st.failing = false
+
+			continue
+		}
+
+		if st.violation >= 0 {
+			m.violations[st.violation].Count++
+		} else {
+			st.violation = len(m.violations)
+			m.violations = append(m.violations, InvariantViolation{
+				Name:   inv.Name,
+				Source: inv.Source,
+				Tick:   tick,
+				Reason: reason,
+				Count:  1,
+				Trace:  m.trace(inv, ctx),
+			})
+		}
+
+		if !st.failing {
+			found = append(found, m.violations[st.violation])
+		}
+
+		st.failing = true
+	}
+
+	return found
+}
+
+// step advances one invariant and returns why it failed, or "".
+func (m *InvariantMonitor) step(inv *Invariant, st *invariantState, ctx *invContext, tick int64) string {
+	if ctx.reset && inv.Kind == InvariantEventually {
+		st.start, st.satisfied = tick, false
+	}
+
+	if ctx.reset && inv.Kind == InvariantResponse {
+		st.start = -1
+	}
+
+	switch inv.Kind {
+	case InvariantEventually:
+		if st.satisfied {
+			return ""
+		}
+
+		ok, err := inv.check(ctx)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+
+		if ok {
+			st.satisfied = true
+
+			return ""
+		}
+
+		if tick-st.start >= inv.Within {
+			st.satisfied = true // Report once per run
+
+			return fmt.Sprintf("not reached within %d ticks of tick %d", inv.Within, st.start)
+		}
+
+		return ""
+	case InvariantResponse:
+		triggered, err := inv.trigger.eval(ctx)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+
+		if t, _ := triggered.(bool); t && st.start < 0 {
+			st.start = tick
+		}
+
+		if st.start < 0 {
+			return ""
+		}
+
+		ok, err := inv.check(ctx)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+
+		if ok {
+			st.start = -1
+
+			return ""
+		}
+
+		if tick-st.start >= inv.Within {
+			start := st.start
+			st.start = -1
+
+			return fmt.Sprintf("no response within %d ticks of trigger at tick %d", inv.Within, start)
+		}
+
+		return ""
+	}
+
+	ok, err := inv.check(ctx)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	if ok {
+		return ""
+	}
+
+	if inv.Kind == InvariantMonotonic {
+		return "value moved the wrong way"
+	}
+
+	return "condition is false"
+}
+
+// trace builds the counterexample from the window.
+func (m *InvariantMonitor) trace(inv *Invariant, ctx *invContext) []TraceStep {
+	steps := make([]TraceStep, len(m.window))
+
+	for i, entry := range m.window {
+		stepCtx := &invContext{state: entry.state, action: entry.action, set: ctx.set, reset: i == 0}
+		values := make(map[string]any, len(inv.idents))
+
+		for _, name := range inv.idents {
+			if v, err := (&invIdent{name}).eval(stepCtx); err == nil {
+				values[name] = v
+			}
+		}
+
+		steps[i] = TraceStep{Tick: entry.tick, Action: entry.action, Values: values, State: entry.state}
+	}
+
+	return steps
+}
+
+// Violations returns the first violation of each failing invariant.
+func (m *InvariantMonitor) Violations() []InvariantViolation {
+	return slices.Clone(m.violations)
+}
+
+// ============================================================================
+// Reporting
+// ============================================================================
+
+// WriteMarkdown writes the violation with its counterexample as a table.
+func (v *InvariantViolation) WriteMarkdown(sb *strings.Builder) {
+	sb.WriteString(fmt.Sprintf("- **%s** `%s` @ tick %d: %s (%d failing ticks)\n\n",
+		v.Name, v.Source, v.Tick, v.Reason, v.Count))
+
+	if len(v.Trace) == 0 {
+		return
+	}
+
+	var names []string
+	for _, step := range v.Trace {
+		for name := range step.Values {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	slices.Sort(names)
+
+	sb.WriteString("  | Tick | Action |")
+
+	for _, name := range names {
+		sb.WriteString(" " + name + " |")
+	}
+
+	sb.WriteString("\n  |------|--------|" + strings.Repeat("---|", len(names)) + "\n")
+
+	for _, step := range v.Trace {
+		sb.WriteString(fmt.Sprintf("  | %d | %s |", step.Tick, step.Action))
+
+		for _, name := range names {
+			sb.WriteString(" " + formatInvValue(step.Values[name]) + " |")
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+}
+
+func formatInvValue(v any) string {
+	if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+
+	if v == nil {
+		return "-"
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,720 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// Lexer
+// ============================================================================
+
+type invTokenKind int
+
+const (
+	tokEOF invTokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type invToken struct {
+	kind invTokenKind
+	text string
+	pos  int
+}
+
+// lexInvariant splits src into tokens. Identifiers may contain dots so
+// CustomData keys like "boss.phase" read naturally.
+func lexInvariant(src string) ([]invToken, error) {
+	var tokens []invToken
+
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, invToken{tokNumber, src[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) ||
+				src[i] == '_' || src[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, invToken{tokIdent, src[start:i], start})
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], src[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+
+			tokens = append(tokens, invToken{tokString, src[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			op := src[i : i+1]
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+
+			if !invOps[op] {
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+
+			tokens = append(tokens, invToken{tokOp, op, i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, invToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+var invOps = map[string]bool{
+	"&&": true, "||": true, "==": true, "!=": true, "<=": true, ">=": true, "<": true, ">": true,
+	"!": true, "+": true, "-": true, "*": true, "/": true, "%": true, "(": true, ")": true, ",": true,
+}
+
+// ============================================================================
+// AST
+// ============================================================================
+
+// invExpr is a parsed invariant expression. Values are float64, bool or
+// string.
+type invExpr interface {
+	eval(ctx *invContext) (any, error)
+}
+
+type (
+	invLiteral struct{ value any }
+	invIdent   struct{ name string }
+	invUnary   struct {
+		op string
+		x  invExpr
+	}
+	invBinary struct {
+		op   string
+		l, r invExpr
+	}
+	invCall struct {
+		name string
+		args []invExpr
+	}
+)
+
+// ============================================================================
+// Parser
+// ============================================================================
+
+// invParser is a recursive-descent parser over lexed tokens.
+type invParser struct {
+	tokens []invToken
+	pos    int
+}
+
+func (p *invParser) peek() invToken {
+	return p.tokens[p.pos]
+}
+
+func (p *invParser) next() invToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// accept consumes the next token if it is op or keyword w.
+func (p *invParser) accept(texts ...string) bool {
+	t := p.peek()
+	for _, text := range texts {
+		if (t.kind == tokOp && t.text == text) || (t.kind == tokIdent && strings.EqualFold(t.text, text)) {
+			p.pos++
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *invParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %q", text)
+	}
+
+	return nil
+}
+
+func (p *invParser) errorf(format string, args ...any) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("%s at end", fmt.Sprintf(format, args...))
+	}
+
+	return fmt.Errorf("%s at %d near %q", fmt.Sprintf(format, args...), t.pos, t.text)
+}
+
+func (p *invParser) parseExpr() (invExpr, error) {
+	return p.parseBinary(0)
+}
+
+// invLevels lists binary operators by increasing precedence; "and",
+// "or" and "not" are accepted as words.
+var invLevels = [][]string{
+	{"||", "or"},
+	{"&&", "and"},
+	{"==", "!=", "<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *invParser) parseBinary(level int) (invExpr, error) {
+	if level == len(invLevels) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+
+		for _, candidate := range invLevels[level] {
+			if p.accept(candidate) {
+				op = candidate
+
+				break
+			}
+		}
+
+		switch op {
+		case "":
+			return left, nil
+		case "or":
+			op = "||"
+		case "and":
+			op = "&&"
+		}
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &invBinary{op: op, l: left, r: right}
+	}
+}
+
+func (p *invParser) parseUnary() (invExpr, error) {
+	if p.accept("!", "not") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &invUnary{op: "!", x: x}, nil
+	}
+
+	if p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &invUnary{op: "-", x: x}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *invParser) parsePrimary() (invExpr, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokNumber:
+		p.next()
+
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at %d", t.text, t.pos)
+		}
+
+		return &invLiteral{v}, nil
+	case tokString:
+		p.next()
+
+		return &invLiteral{t.text}, nil
+	case tokIdent:
+		if invKeywords[strings.ToLower(t.text)] {
+			return nil, p.errorf("unexpected keyword")
+		}
+
+		p.next()
+
+		switch t.text {
+		case "true":
+			return &invLiteral{true}, nil
+		case "false":
+			return &invLiteral{false}, nil
+		}
+
+		if !p.accept("(") {
+			return &invIdent{t.text}, nil
+		}
+
+		return p.parseCall(t.text)
+	case tokOp:
+		if p.accept("(") {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			return x, p.expect(")")
+		}
+	}
+
+	return nil, p.errorf("unexpected token")
+}
+
+func (p *invParser) parseCall(name string) (invExpr, error) {
+	if _, ok := invFuncs[name]; !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	call := &invCall{name: name}
+
+	if p.accept(")") {
+		return call, nil
+	}
+
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		call.args = append(call.args, arg)
+
+		if p.accept(")") {
+			return call, nil
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// invKeywords are statement words that cannot be identifiers.
+var invKeywords = map[string]bool{
+	"always": true, "never": true, "eventually": true, "within": true,
+	"whenever": true, "then": true, "and": true, "or": true, "not": true,
+}
+
+// ============================================================================
+// Evaluation
+// ============================================================================
+
+// invContext is what an expression sees at one tick.
+type invContext struct {
+	state  GameState
+	action ActionType
+	prev   *GameState // nil at the first tick
+	reset  bool
+	set    *InvariantSet
+	values map[string]any // Replaces state when checking flat maps
+}
+
+// errNoValue marks identifiers that are missing from the current state.
+var errNoValue = errors.New("no value")
+
+func (e *invLiteral) eval(*invContext) (any, error) { return e.value, nil }
+
+func (e *invIdent) eval(ctx *invContext) (any, error) {
+	if ctx.values != nil {
+		if v, ok := ctx.values[e.name]; ok {
+			return invValue(v), nil
+		}
+
+		return nil, fmt.Errorf("%s: %w", e.name, errNoValue)
+	}
+
+	s := ctx.state
+
+	switch e.name {
+	case "tick":
+		return float64(s.Tick), nil
+	case "score":
+		return float64(s.Score), nil
+	case "x":
+		return s.PlayerPos[0], nil
+	case "y":
+		return s.PlayerPos[1], nil
+	case "health":
+		return float64(s.PlayerHealth[0]), nil
+	case "max_health":
+		return float64(s.PlayerHealth[1]), nil
+	case "entities":
+		return float64(s.EntityCount), nil
+	case "action":
+		return string(ctx.action), nil
+	case "reset":
+		return ctx.reset, nil
+	}
+
+	if v, ok := s.CustomData[e.name]; ok {
+		return invValue(v), nil
+	}
+
+	if v, ok := ctx.set.Vars[e.name]; ok {
+		return invValue(v), nil
+	}
+
+	return nil, fmt.Errorf("%s: %w", e.name, errNoValue)
+}
+
+// invValue normalizes Go values to float64, bool or string.
+func invValue(v any) any {
+	switch v := v.(type) {
+	case bool, string:
+		return v
+	case ActionType:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (e *invUnary) eval(ctx *invContext) (any, error) {
+	v, err := e.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "!" {
+		b, err := invBool(v)
+
+		return !b, err
+	}
+
+	f, err := invNumber(v)
+
+	return -f, err
+}
+
+func (e *invBinary) eval(ctx *invContext) (any, error) {
+	l, err := e.l.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit so guards like "has(k) && k > 0" work
+	if e.op == "&&" || e.op == "||" {
+		lb, err := invBool(l)
+		if err != nil || lb == (e.op == "||") {
+			return lb, err
+		}
+
+		r, err := e.r.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return invBool(r)
+	}
+
+	r, err := e.r.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return invEqual(l, r), nil
+	case "!=":
+		return !invEqual(l, r), nil
+	}
+
+	a, err := invNumber(l)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := invNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, errors.New("division by zero")
+		}
+
+		return a / b, nil
+	default:
+		if b == 0 {
+			return nil, errors.New("division by zero")
+		}
+
+		return math.Mod(a, b), nil
+	}
+}
+
+func (e *invCall) eval(ctx *invContext) (any, error) {
+	return invFuncs[e.name](ctx, e.args)
+}
+
+func invEqual(a, b any) bool {
+	if x, ok := a.(float64); ok {
+		y, ok := b.(float64)
+
+		return ok && x == y
+	}
+
+	return a == b
+}
+
+func invNumber(v any) (float64, error) {
+	if f, ok := v.(float64); ok {
+		return f, nil
+	}
+
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func invBool(v any) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+
+	return false, fmt.Errorf("%v is not a boolean", v)
+}
+
+// invFunc is a built-in function. Arguments are passed unevaluated so
+// prev and has can control how they are evaluated.
+type invFunc func(ctx *invContext, args []invExpr) (any, error)
+
+var invFuncs map[string]invFunc
+
+func init() {
+	invFuncs = map[string]invFunc{
+		"prev":    invPrev,
+		"delta":   invDelta,
+		"has":     invHas,
+		"abs":     invMath(1, math.Abs),
+		"floor":   invMath(1, math.Floor),
+		"min":     invMath2(math.Min),
+		"max":     invMath2(math.Max),
+		"count":   invCount,
+		"outside": invOutside,
+	}
+}
+
+// invPrev evaluates its argument at the previous tick, or now at the first.
+func invPrev(ctx *invContext, args []invExpr) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("prev takes 1 argument")
+	}
+
+	if ctx.prev == nil {
+		return args[0].eval(ctx)
+	}
+
+	prevCtx := *ctx
+	prevCtx.state, prevCtx.prev, prevCtx.action = *ctx.prev, nil, ""
+
+	return args[0].eval(&prevCtx)
+}
+
+// invDelta is x - prev(x).
+func invDelta(ctx *invContext, args []invExpr) (any, error) {
+	now, err := invMath(1, func(f float64) float64 { return f })(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := invPrev(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := invNumber(before)
+
+	return now.(float64) - b, err
+}
+
+// invHas reports whether an identifier has a value at this tick.
+func invHas(ctx *invContext, args []invExpr) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("has takes 1 argument")
+	}
+
+	_, err := args[0].eval(ctx)
+	if errors.Is(err, errNoValue) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func invArgs(ctx *invContext, args []invExpr, n int) ([]any, error) {
+	if n >= 0 && len(args) != n {
+		return nil, fmt.Errorf("want %d arguments, got %d", n, len(args))
+	}
+
+	values := make([]any, len(args))
+
+	for i, arg := range args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+func invNumbers(ctx *invContext, args []invExpr, n int) ([]float64, error) {
+	values, err := invArgs(ctx, args, n)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(values))
+	for i, v := range values {
+		if out[i], err = invNumber(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func invMath(n int, f func(float64) float64) invFunc {
+	return func(ctx *invContext, args []invExpr) (any, error) {
+		v, err := invNumbers(ctx, args, n)
+		if err != nil {
+			return nil, err
+		}
+
+		return f(v[0]), nil
+	}
+}
+
+func invMath2(f func(a, b float64) float64) invFunc {
+	return func(ctx *invContext, args []invExpr) (any, error) {
+		v, err := invNumbers(ctx, args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		return f(v[0], v[1]), nil
+	}
+}
+
+// invCount counts live entities, optionally of one type.
+func invCount(ctx *invContext, args []invExpr) (any, error) {
+	values, err := invArgs(ctx, args, -1)
+	if err != nil || len(values) > 1 {
+		return nil, errors.Join(err, errors.New("count takes at most 1 argument"))
+	}
+
+	typ := ""
+	if len(values) == 1 {
+		typ = fmt.Sprint(values[0])
+	}
+
+	n, err := ctx.set.countEntities(typ, nil)
+
+	return float64(n), err
+}
+
+// invOutside counts entities of a type whose position lies outside the
+// rectangle x0, y0, x1, y1.
+func invOutside(ctx *invContext, args []invExpr) (any, error) {
+	if len(args) != 5 {
+		return nil, errors.New("outside takes 5 arguments")
+	}
+
+	typ, err := args[0].eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rect, err := invNumbers(ctx, args[1:], 4)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := ctx.set.countEntities(fmt.Sprint(typ), func(x, y float64) bool {
+		return x < rect[0] || y < rect[1] || x > rect[2] || y > rect[3]
+	})
+
+	return float64(n), err
+}
+
+// invIdents lists the identifiers an expression reads, for traces.
+func invIdents(e invExpr, out []string) []string {
+	switch e := e.(type) {
+	case *invIdent:
+		for _, name := range out {
+			if name == e.name {
+				return out
+			}
+		}
+
+		return append(out, e.name)
+	case *invUnary:
+		return invIdents(e.x, out)
+	case *invBinary:
+		return invIdents(e.r, invIdents(e.l, out))
+	case *invCall:
+		for _, arg := range e.args {
+			out = invIdents(arg, out)
+		}
+	}
+
+	return out
+}
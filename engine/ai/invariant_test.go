@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/security"
+)
+
+func invHistory(states ...GameState) []Observation {
+	history := make([]Observation, len(states))
+	for i, s := range states {
+		history[i] = Observation{Tick: int64(i), State: s, Action: ActionNone}
+	}
+
+	return history
+}
+
+func TestParseInvariant(t *testing.T) {
+	valid := []string{
+		"health <= max_health",
+		"always x >= 0 and x <= width && !(y < 0)",
+		"never entities > 500",
+		"score never decreases except on reset",
+		"eventually game_over within 600 ticks",
+		"whenever health < 20, eventually health >= 20 within 30",
+		"abs(delta(x)) <= 10 || reset",
+		"has(boss.phase) && boss.phase != 'dead'",
+		"count('enemy') <= 10 and outside('enemy', 0, 0, 100, 100) == 0",
+	}
+
+	for _, src := range valid {
+		if _, err := ParseInvariant("t", src); err != nil {
+			t.Errorf("ParseInvariant(%q): %v", src, err)
+		}
+	}
+
+	invalid := []string{"", "health <=", "score never grows", "eventually x within soon", "nope(1)", "x & y", "(x > 1"}
+	for _, src := range invalid {
+		if _, err := ParseInvariant("t", src); err == nil {
+			t.Errorf("ParseInvariant(%q) should fail", src)
+		}
+	}
+}
+
+func TestInvariantCheck(t *testing.T) {
+	set := NewInvariantSet(nil)
+	set.Vars["width"] = 100
+	set.MustAdd("health", "health <= max_health").
+		MustAdd("bounds", "x >= 0 && x <= width").
+		MustAdd("score", "score never decreases except on reset").
+		MustAdd("over", "eventually game_over within 3 ticks").
+		MustAdd("heal", "whenever health < 20 then eventually health >= 20 within 1")
+
+	history := invHistory(
+		GameState{Tick: 0, Score: 5, PlayerHealth: [2]int{10, 100}, CustomData: map[string]any{"game_over": false}},
+		GameState{Tick: 1, Score: 3, PlayerHealth: [2]int{10, 100}, CustomData: map[string]any{"game_over": false}},
+		GameState{Tick: 0, Score: 0, PlayerHealth: [2]int{120, 100}, CustomData: map[string]any{"game_over": false}},
+		GameState{Tick: 1, Score: 1, PlayerPos: [2]float64{150, 0}, PlayerHealth: [2]int{50, 100}, CustomData: map[string]any{"game_over": true}},
+	)
+
+	violations := set.Check(history)
+
+	got := make(map[string]InvariantViolation)
+	for _, v := range violations {
+		got[v.Name] = v
+	}
+
+	want := map[string]int64{"score": 1, "health": 2, "bounds": 3, "heal": 1}
+	for name, tick := range want {
+		if v, ok := got[name]; !ok || v.Tick != tick {
+			t.Errorf("%s: violation %+v, want tick %d", name, v, tick)
+		}
+	}
+
+	// game_over became true within 3 ticks of the reset at tick 2
+	if _, ok := got["over"]; ok || len(violations) != len(want) {
+		t.Errorf("violations = %+v", violations)
+	}
+
+	score := got["score"]
+	if len(score.Trace) != 2 || score.Trace[0].Values["score"] != 5.0 || score.Trace[1].Values["score"] != 3.0 {
+		t.Errorf("score counterexample = %+v", score.Trace)
+	}
+
+	anomalies := set.Rule()(history)
+	if len(anomalies) != len(want) || anomalies[0].Type != AnomalyInvariant || len(anomalies[0].Evidence) == 0 {
+		t.Errorf("Rule anomalies = %+v", anomalies)
+	}
+}
+
+func TestInvariantMonotonicReset(t *testing.T) {
+	set := NewInvariantSet(nil).
+		MustAdd("strict", "score never decreases").
+		MustAdd("lenient", "score never decreases except on reset")
+
+	violations := set.Check(invHistory(
+		GameState{Tick: 0, Score: 5},
+		GameState{Tick: 1, Score: 7},
+		GameState{Tick: 0, Score: 0},
+	))
+
+	if len(violations) != 1 || violations[0].Name != "strict" || violations[0].Tick != 2 {
+		t.Errorf("violations = %+v", violations)
+	}
+}
+
+func TestInvariantWorldQueries(t *testing.T) {
+	world := ecs.NewWorld()
+	enemies := ecs.NewMap2[components.Position, components.AIMetadata](&world)
+
+	for _, x := range []float64{10, 50, 500} {
+		enemies.NewEntity(&components.Position{X: x}, &components.AIMetadata{EntityType: "enemy"})
+	}
+
+	set := NewInvariantSet(&world).
+		MustAdd("cap", "count('enemy') <= 2").
+		MustAdd("arena", "outside('enemy', 0, 0, 100, 100) == 0").
+		MustAdd("total", "count() == 3")
+
+	violations := set.Check(invHistory(GameState{}))
+	if len(violations) != 2 || violations[0].Name != "cap" || violations[1].Name != "arena" {
+		t.Errorf("violations = %+v", violations)
+	}
+}
+
+func TestInvariantLoadAndIntegrity(t *testing.T) {
+	set := NewInvariantSet(nil)
+
+	src := "# player rules\nhealth: health <= max_health\n\nscore >= 0\n"
+	if err := set.Load(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(set.Invariants()); n != 2 || set.Invariants()[1].Name != "score >= 0" {
+		t.Fatalf("Load = %d invariants", n)
+	}
+
+	if err := set.Load(strings.NewReader("bad: health <")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Load error = %v", err)
+	}
+
+	checker := security.NewStateIntegrityChecker()
+	checker.AddRule(set.Invariants()[0].CheckMap)
+
+	if got := checker.Check(map[string]any{"health": 50, "max_health": 100}); len(got) != 0 {
+		t.Errorf("valid state violations = %v", got)
+	}
+
+	if got := checker.Check(map[string]any{"health": 150, "max_health": 100}); len(got) != 1 {
+		t.Errorf("invalid state violations = %v", got)
+	}
+}
+
+func TestSessionInvariants(t *testing.T) {
+	set := NewInvariantSet(nil).MustAdd("inside", "x <= 120")
+
+	session := NewQASession(NewMockGameAdapter())
+	session.SetPlayer(NewReplayPlayer(slices.Repeat([]ActionType{ActionMoveRight}, 20)))
+	session.SetConfig(SessionConfig{Runs: 1, MaxTicks: 20, RecordEvery: 1})
+	session.SetInvariants(set)
+
+	report := session.Run()
+	if report.TotalViolations != 1 || !strings.HasPrefix(report.Conclusion, "FAIL") {
+		t.Fatalf("report = %d violations, %s", report.TotalViolations, report.Conclusion)
+	}
+
+	v := report.Runs[0].Violations[0]
+	// Ticks 5-19 before each step, plus the state after the last step.
+	if v.Tick != 5 || v.Count != 16 {
+		t.Errorf("violation tick %d count %d, want 5 and 16", v.Tick, v.Count)
+	}
+
+	md := report.GenerateMarkdown()
+	if !strings.Contains(md, "**inside**") || !strings.Contains(md, "| 5 | move_right | 125 |") {
+		t.Errorf("markdown missing counterexample:\n%s", md)
+	}
+}
+
+func TestSessionInvariantsFinalStep(t *testing.T) {
+	set := NewInvariantSet(nil).
+		MustAdd("alive", "health >= 0").
+		MustAdd("inside", "x <= 120")
+
+	t.Run("killing blow", func(t *testing.T) {
+		adapter := NewMockGameAdapter()
+		session := NewQASession(adapter)
+		session.SetPlayer(NewReplayPlayer(slices.Repeat([]ActionType{ActionNone}, 20)))
+		session.SetConfig(SessionConfig{Runs: 1, MaxTicks: 20, RecordEvery: 1})
+		session.SetInvariants(set)
+		session.OnStep(func(_ int, tick int64) {
+			if tick == 3 {
+				adapter.health[0], adapter.gameOver = -5, true
+			}
+		})
+
+		report := session.Run()
+
+		violations := report.Runs[0].Violations
+		if len(violations) != 1 || violations[0].Name != "alive" || violations[0].Tick != 4 {
+			t.Errorf("violations = %+v", violations)
+		}
+	})
+
+	t.Run("last tick", func(t *testing.T) {
+		session := NewQASession(NewMockGameAdapter())
+		session.SetPlayer(NewReplayPlayer(slices.Repeat([]ActionType{ActionMoveRight}, 5)))
+		session.SetConfig(SessionConfig{Runs: 1, MaxTicks: 5, RecordEvery: 1})
+		session.SetInvariants(set)
+
+		violations := session.Run().Runs[0].Violations
+		if len(violations) != 1 || violations[0].Name != "inside" || violations[0].Tick != 5 {
+			t.Errorf("violations = %+v", violations)
+		}
+	})
+}
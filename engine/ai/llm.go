@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// LLM Client Interface
+// ============================================================================
+
+// Role identifies the author of a chat message.
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// ChatMessage is one message in a conversation.
+type ChatMessage struct {
+	Role       Role       `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Calls requested by the assistant
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Call this tool message answers
+}
+
+// JSONSchema is a JSON Schema document.
+type JSONSchema map[string]any
+
+// ToolDefinition describes a function the model may call.
+type ToolDefinition struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Parameters  JSONSchema `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON encoded arguments
+}
+
+// DecodeArguments unmarshals the call arguments into v.
+func (c ToolCall) DecodeArguments(v any) error {
+	return json.Unmarshal([]byte(c.Arguments), v)
+}
+
+// ResponseSchema constrains the reply to JSON matching Schema.
+type ResponseSchema struct {
+	Name   string     `json:"name"`
+	Schema JSONSchema `json:"schema"`
+	Strict bool       `json:"strict,omitempty"`
+}
+
+// ChatRequest is a chat completion request.
+type ChatRequest struct {
+	Model       string           `json:"model,omitempty"`
+	Messages    []ChatMessage    `json:"messages"`
+	Tools       []ToolDefinition `json:"tools,omitempty"`
+	ToolChoice  string           `json:"tool_choice,omitempty"` // "auto", "none", "required" or a tool name
+	Schema      *ResponseSchema  `json:"schema,omitempty"`
+	Temperature float64          `json:"temperature,omitempty"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Seed        int              `json:"seed,omitempty"`
+}
+
+// Usage counts tokens for one request.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse is a completed chat reply.
+type ChatResponse struct {
+	Model        string      `json:"model,omitempty"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Usage        Usage       `json:"usage"`
+	Cached       bool        `json:"-"` // Served from a ResponseCache
+}
+
+// StreamChunk is an incremental piece of a streamed reply.
+type StreamChunk struct {
+	Delta     string     // New content text
+	ToolCalls []ToolCall // Tool calls completed in this chunk
+}
+
+// LLMClient sends chat requests to a language model.
+type LLMClient interface {
+	// Chat returns the complete reply.
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	// ChatStream calls onChunk as content arrives and returns the assembled
+	// reply. Returning an error from onChunk aborts the stream.
+	ChatStream(ctx context.Context, req ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error)
+}
+
+// ErrNoJSON is returned when a reply expected to be JSON contains none.
+var ErrNoJSON = errors.New("llm: reply contains no JSON")
+
+// Ask sends a single user prompt with an optional system prompt and returns
+// the reply text. It is the simplest way to send the prompts built by
+// NarrativeController, LoreDatabase, AssetGenerator and SceneGenerator.
+func Ask(ctx context.Context, client LLMClient, system, prompt string) (string, error) {
+	resp, err := client.Chat(ctx, ChatRequest{Messages: Messages(system, prompt)})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Message.Content, nil
+}
+
+// AskJSON sends a prompt constrained to schema and decodes the reply into out.
+func AskJSON(ctx context.Context, client LLMClient, req ChatRequest, schema JSONSchema, out any) error {
+	if schema != nil {
+		req.Schema = &ResponseSchema{Name: "response", Schema: schema, Strict: true}
+	}
+
+	resp, err := client.Chat(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return DecodeJSONReply(resp.Message.Content, out)
+}
+
+// Messages builds a system + user message pair. An empty system prompt is omitted.
+func Messages(system, user string) []ChatMessage {
+	var msgs []ChatMessage
+	if system != "" {
+		msgs = append(msgs, ChatMessage{Role: RoleSystem, Content: system})
+	}
+
+	return append(msgs, ChatMessage{Role: RoleUser, Content: user})
+}
+
+// DecodeJSONReply decodes the first JSON object or array in text into out.
+// Models often wrap JSON in prose or code fences, so those are skipped.
+func DecodeJSONReply(text string, out any) error {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ErrNoJSON
+	}
+
+	dec := json.NewDecoder(strings.NewReader(text[start:]))
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("llm: decode reply: %w", err)
+	}
+
+	return nil
+}
+
+// lastUserMessage returns the content of the final user message.
+func lastUserMessage(req ChatRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == RoleUser {
+			return req.Messages[i].Content
+		}
+	}
+
+	return ""
+}
+
+// estimateUsage approximates token counts when a backend does not report them.
+func estimateUsage(req ChatRequest, resp *ChatResponse) Usage {
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += TokenEstimate(m.Content) + 4
+	}
+
+	completion := TokenEstimate(resp.Message.Content)
+	for _, c := range resp.Message.ToolCalls {
+		completion += TokenEstimate(c.Name + c.Arguments)
+	}
+
+	return Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
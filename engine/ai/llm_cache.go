@@ -0,0 +1,288 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"sync"
+)
+
+// ============================================================================
+// Response Cache
+// ============================================================================
+
+// PromptHash returns a stable hash of everything in a request that affects
+// the reply.
+func PromptHash(req ChatRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// CachedClient serves repeated requests from memory instead of the backend.
+// Responses are keyed on PromptHash, so only identical requests hit.
+type CachedClient struct {
+	Inner      LLMClient
+	MaxEntries int // Oldest entries are evicted beyond this (0 = unlimited)
+
+	mu      sync.Mutex
+	entries map[string]*ChatResponse
+	order   []string
+	hits    int
+	misses  int
+}
+
+// NewCachedClient wraps a client with a response cache.
+func NewCachedClient(inner LLMClient) *CachedClient {
+	return &CachedClient{Inner: inner, entries: make(map[string]*ChatResponse)}
+}
+
+// Chat implements LLMClient.
+func (c *CachedClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	key := PromptHash(req)
+	if resp, ok := c.lookup(key); ok {
+		return resp, nil
+	}
+
+	resp, err := c.Inner.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, resp)
+
+	return resp, nil
+}
+
+// ChatStream implements LLMClient. Cached replies arrive as a single chunk.
+func (c *CachedClient) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	key := PromptHash(req)
+	if resp, ok := c.lookup(key); ok {
+		err := onChunk(StreamChunk{Delta: resp.Message.Content, ToolCalls: resp.Message.ToolCalls})
+		if err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	}
+
+	resp, err := c.Inner.ChatStream(ctx, req, onChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, resp)
+
+	return resp, nil
+}
+
+// Stats returns cache hits and misses.
+func (c *CachedClient) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached responses.
+func (c *CachedClient) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Save writes the cache to a JSON file.
+func (c *CachedClient) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load merges cached responses from a JSON file written by Save.
+func (c *CachedClient) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var entries map[string]*ChatResponse
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	for k, v := range entries {
+		c.store(k, v)
+	}
+
+	return nil
+}
+
+func (c *CachedClient) lookup(key string) (*ChatResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, ok := c.entries[key]
+	if !ok {
+		c.misses++
+
+		return nil, false
+	}
+
+	c.hits++
+	hit := *resp
+	hit.Cached = true
+
+	return &hit, true
+}
+
+func (c *CachedClient) store(key string, resp *ChatResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+
+	c.entries[key] = resp
+
+	for c.MaxEntries > 0 && len(c.order) > c.MaxEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// ============================================================================
+// Token Accounting
+// ============================================================================
+
+// ErrTokenBudget is returned once a client's token budget is spent.
+var ErrTokenBudget = errors.New("llm: token budget exceeded")
+
+// TokenUsage summarizes token use across requests.
+type TokenUsage struct {
+	Requests         int
+	CachedRequests   int // Served from a cache; they cost no tokens
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	ByModel          map[string]Usage
+}
+
+// AccountingClient counts tokens and enforces an optional budget.
+type AccountingClient struct {
+	Inner     LLMClient
+	MaxTokens int // Requests fail with ErrTokenBudget once exceeded (0 = unlimited)
+
+	mu    sync.Mutex
+	usage TokenUsage
+}
+
+// NewAccountingClient wraps a client with token accounting.
+func NewAccountingClient(inner LLMClient) *AccountingClient {
+	return &AccountingClient{Inner: inner, usage: TokenUsage{ByModel: make(map[string]Usage)}}
+}
+
+// Chat implements LLMClient.
+func (c *AccountingClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Inner.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.record(resp)
+
+	return resp, nil
+}
+
+// ChatStream implements LLMClient.
+func (c *AccountingClient) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Inner.ChatStream(ctx, req, onChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	c.record(resp)
+
+	return resp, nil
+}
+
+// Usage returns a snapshot of token use so far.
+func (c *AccountingClient) Usage() TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u := c.usage
+	u.ByModel = maps.Clone(c.usage.ByModel)
+
+	return u
+}
+
+// Reset clears the counters.
+func (c *AccountingClient) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usage = TokenUsage{ByModel: make(map[string]Usage)}
+}
+
+func (c *AccountingClient) checkBudget() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxTokens > 0 && c.usage.TotalTokens >= c.MaxTokens {
+		return ErrTokenBudget
+	}
+
+	return nil
+}
+
+func (c *AccountingClient) record(resp *ChatResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usage.Requests++
+
+	if resp.Cached {
+		c.usage.CachedRequests++
+
+		return
+	}
+
+	c.usage.PromptTokens += resp.Usage.PromptTokens
+	c.usage.CompletionTokens += resp.Usage.CompletionTokens
+	c.usage.TotalTokens += resp.Usage.TotalTokens
+
+	m := c.usage.ByModel[resp.Model]
+	m.PromptTokens += resp.Usage.PromptTokens
+	m.CompletionTokens += resp.Usage.CompletionTokens
+	m.TotalTokens += resp.Usage.TotalTokens
+	c.usage.ByModel[resp.Model] = m
+}
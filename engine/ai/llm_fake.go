@@ -0,0 +1,503 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// Fake Backend
+// ============================================================================
+
+// FakeRule replies to requests whose last user message contains Match.
+// An empty Match matches every request.
+type FakeRule struct {
+	Match     string
+	Reply     string
+	ToolCalls []ToolCall
+}
+
+// FakeLLM is a deterministic offline LLMClient for tests.
+//
+// Rules are checked in order. Unmatched requests with a Schema get a minimal
+// JSON value satisfying it; anything else gets a reply derived from the
+// prompt hash, so the same prompt always gets the same answer.
+type FakeLLM struct {
+	Model   string
+	Rules   []FakeRule
+	Handler func(req ChatRequest) (*ChatResponse, error) // Overrides Rules when set
+
+	mu       sync.Mutex
+	requests []ChatRequest
+}
+
+// NewFakeLLM creates a fake client.
+func NewFakeLLM() *FakeLLM {
+	return &FakeLLM{Model: "fake"}
+}
+
+// On adds a text reply rule.
+func (f *FakeLLM) On(match, reply string) *FakeLLM {
+	f.Rules = append(f.Rules, FakeRule{Match: match, Reply: reply})
+
+	return f
+}
+
+// OnToolCall adds a rule replying with a single tool call. args is encoded as JSON.
+func (f *FakeLLM) OnToolCall(match, name string, args any) *FakeLLM {
+	data, _ := json.Marshal(args)
+	call := ToolCall{ID: fmt.Sprintf("call_%d", len(f.Rules)), Name: name, Arguments: string(data)}
+	f.Rules = append(f.Rules, FakeRule{Match: match, ToolCalls: []ToolCall{call}})
+
+	return f
+}
+
+// Requests returns the requests received so far.
+func (f *FakeLLM) Requests() []ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.requests)
+}
+
+// Chat implements LLMClient.
+func (f *FakeLLM) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	if f.Handler != nil {
+		return f.Handler(req)
+	}
+
+	resp := &ChatResponse{Model: f.Model, Message: ChatMessage{Role: RoleAssistant}, FinishReason: "stop"}
+	prompt := lastUserMessage(req)
+
+	switch rule := f.match(prompt); {
+	case rule != nil:
+		resp.Message.Content = rule.Reply
+		resp.Message.ToolCalls = rule.ToolCalls
+
+		if len(rule.ToolCalls) > 0 {
+			resp.FinishReason = "tool_calls"
+		}
+	case req.Schema != nil:
+		data, _ := json.Marshal(SampleFromSchema(req.Schema.Schema))
+		resp.Message.Content = string(data)
+	default:
+		resp.Message.Content = "fake reply " + PromptHash(req)[:8]
+	}
+
+	resp.Usage = estimateUsage(req, resp)
+
+	return resp, nil
+}
+
+// ChatStream implements LLMClient, streaming the reply word by word.
+func (f *FakeLLM) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	resp, err := f.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := streamResponse(resp, onChunk); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (f *FakeLLM) match(prompt string) *FakeRule {
+	for i := range f.Rules {
+		if strings.Contains(prompt, f.Rules[i].Match) {
+			return &f.Rules[i]
+		}
+	}
+
+	return nil
+}
+
+// streamResponse replays a complete response as word chunks.
+func streamResponse(resp *ChatResponse, onChunk func(StreamChunk) error) error {
+	text := resp.Message.Content
+	for text != "" {
+		i := strings.IndexByte(text[1:], ' ') + 1
+		if i == 0 {
+			i = len(text)
+		}
+
+		if err := onChunk(StreamChunk{Delta: text[:i]}); err != nil {
+			return err
+		}
+
+		text = text[i:]
+	}
+
+	if len(resp.Message.ToolCalls) > 0 {
+		return onChunk(StreamChunk{ToolCalls: resp.Message.ToolCalls})
+	}
+
+	return nil
+}
+
+// SampleFromSchema returns the simplest value satisfying a JSON schema:
+// enums use their first value, numbers their minimum, arrays their minItems.
+func SampleFromSchema(schema JSONSchema) any {
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	if c, ok := schema["const"]; ok {
+		return c
+	}
+
+	typ, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]any); ok && len(types) > 0 {
+		typ, _ = types[0].(string)
+	}
+
+	switch typ {
+	case "object":
+		out := map[string]any{}
+		props, _ := schemaMap(schema["properties"])
+
+		for name, p := range props {
+			if sub, ok := schemaMap(p); ok {
+				out[name] = SampleFromSchema(sub)
+			}
+		}
+
+		return out
+	case "array":
+		n, _ := toFloat(schema["minItems"])
+		items, _ := schemaMap(schema["items"])
+		out := make([]any, int(n))
+
+		for i := range out {
+			out[i] = SampleFromSchema(items)
+		}
+
+		return out
+	case "string":
+		return ""
+	case "integer", "number":
+		if v, ok := toFloat(schema["minimum"]); ok {
+			return v
+		}
+
+		return 0
+	case "boolean":
+		return false
+	}
+
+	return nil
+}
+
+// schemaMap accepts both JSONSchema and decoded map[string]any values.
+func schemaMap(v any) (JSONSchema, bool) {
+	switch m := v.(type) {
+	case JSONSchema:
+		return m, true
+	case map[string]any:
+		return m, true
+	}
+
+	return nil, false
+}
+
+// ============================================================================
+// Recorded Responses
+// ============================================================================
+
+// ErrNoRecording is returned by ReplayClient for requests it has not seen.
+var ErrNoRecording = errors.New("llm: no recorded response for request")
+
+// RecordedExchange is one request and its response.
+type RecordedExchange struct {
+	Hash     string        `json:"hash"`
+	Request  ChatRequest   `json:"request"`
+	Response *ChatResponse `json:"response"`
+}
+
+// RecordingClient records every exchange with a real backend so it can be
+// replayed offline with ReplayClient.
+type RecordingClient struct {
+	Inner LLMClient
+
+	mu        sync.Mutex
+	exchanges []RecordedExchange
+}
+
+// NewRecordingClient wraps a client with recording.
+func NewRecordingClient(inner LLMClient) *RecordingClient {
+	return &RecordingClient{Inner: inner}
+}
+
+// Chat implements LLMClient.
+func (c *RecordingClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := c.Inner.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.record(req, resp)
+
+	return resp, nil
+}
+
+// ChatStream implements LLMClient.
+func (c *RecordingClient) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	resp, err := c.Inner.ChatStream(ctx, req, onChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	c.record(req, resp)
+
+	return resp, nil
+}
+
+// Exchanges returns the recorded exchanges.
+func (c *RecordingClient) Exchanges() []RecordedExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.exchanges)
+}
+
+// Save writes the recording to a JSON file.
+func (c *RecordingClient) Save(path string) error {
+	data, err := json.MarshalIndent(c.Exchanges(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (c *RecordingClient) record(req ChatRequest, resp *ChatResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exchanges = append(c.exchanges, RecordedExchange{Hash: PromptHash(req), Request: req, Response: resp})
+}
+
+// ReplayClient answers requests from a recording. Identical requests
+// recorded several times are answered in recorded order, repeating the last.
+type ReplayClient struct {
+	Fallback LLMClient // Used for unrecorded requests; nil returns ErrNoRecording
+
+	mu        sync.Mutex
+	responses map[string][]*ChatResponse
+	next      map[string]int
+}
+
+// NewReplayClient creates a replay client from exchanges.
+func NewReplayClient(exchanges []RecordedExchange) *ReplayClient {
+	c := &ReplayClient{
+		responses: make(map[string][]*ChatResponse),
+		next:      make(map[string]int),
+	}
+
+	for _, ex := range exchanges {
+		hash := ex.Hash
+		if hash == "" {
+			hash = PromptHash(ex.Request)
+		}
+
+		c.responses[hash] = append(c.responses[hash], ex.Response)
+	}
+
+	return c
+}
+
+// LoadReplayClient reads a recording written by RecordingClient.Save.
+func LoadReplayClient(path string) (*ReplayClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchanges []RecordedExchange
+	if err := json.Unmarshal(data, &exchanges); err != nil {
+		return nil, fmt.Errorf("llm: load recording: %w", err)
+	}
+
+	return NewReplayClient(exchanges), nil
+}
+
+// Chat implements LLMClient.
+func (c *ReplayClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	hash := PromptHash(req)
+
+	c.mu.Lock()
+	list := c.responses[hash]
+
+	var resp *ChatResponse
+
+	if len(list) > 0 {
+		i := min(c.next[hash], len(list)-1)
+		c.next[hash]++
+		resp = list[i]
+	}
+	c.mu.Unlock()
+
+	if resp != nil {
+		return resp, nil
+	}
+
+	if c.Fallback != nil {
+		return c.Fallback.Chat(ctx, req)
+	}
+
+	return nil, fmt.Errorf("%w (hash %s)", ErrNoRecording, hash[:12])
+}
+
+// ChatStream implements LLMClient.
+func (c *ReplayClient) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	resp, err := c.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := streamResponse(resp, onChunk); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ============================================================================
+// Local Mock Server
+// ============================================================================
+
+// NewMockLLMServer serves any LLMClient over the OpenAI chat completions API,
+// so OpenAIClient and external tools can be tested against a FakeLLM or a
+// ReplayClient. Mount it with httptest.NewServer or http.ListenAndServe.
+func NewMockLLMServer(client LLMClient) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var wire oaiRequest
+		if err := json.NewDecoder(r.Body).Decode(&wire); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		req := fromWire(wire)
+
+		if !wire.Stream {
+			resp, err := client.Chat(r.Context(), req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(toWireResponse(resp))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+
+		send := func(v any) {
+			data, _ := json.Marshal(v)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		resp, err := client.ChatStream(r.Context(), req, func(chunk StreamChunk) error {
+			if chunk.Delta != "" {
+				send(oaiResponse{Choices: []oaiChoice{{Delta: oaiMessage{Content: chunk.Delta}}}})
+			}
+
+			return nil
+		})
+		if err != nil {
+			send(oaiResponse{Error: &oaiError{Message: err.Error()}})
+
+			return
+		}
+
+		final := toWireResponse(resp)
+		final.Choices[0].Delta = final.Choices[0].Message
+		final.Choices[0].Delta.Content = ""
+		final.Choices[0].Message = oaiMessage{}
+		send(final)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	return mux
+}
+
+// fromWire converts an OpenAI wire request back to a ChatRequest.
+func fromWire(wire oaiRequest) ChatRequest {
+	req := ChatRequest{
+		Model:       wire.Model,
+		Temperature: wire.Temperature,
+		MaxTokens:   wire.MaxTokens,
+		Seed:        wire.Seed,
+	}
+
+	for _, m := range wire.Messages {
+		req.Messages = append(req.Messages, m.toMessage())
+	}
+
+	for _, t := range wire.Tools {
+		req.Tools = append(req.Tools, t.Function)
+	}
+
+	switch choice := wire.ToolChoice.(type) {
+	case string:
+		req.ToolChoice = choice
+	case map[string]any:
+		if fn, ok := choice["function"].(map[string]any); ok {
+			req.ToolChoice, _ = fn["name"].(string)
+		}
+	}
+
+	if wire.ResponseFormat != nil {
+		req.Schema = wire.ResponseFormat.JSONSchema
+	}
+
+	return req
+}
+
+func toWireResponse(resp *ChatResponse) oaiResponse {
+	usage := resp.Usage
+
+	return oaiResponse{
+		Model:   resp.Model,
+		Choices: []oaiChoice{{Message: fromMessage(resp.Message), FinishReason: resp.FinishReason}},
+		Usage:   &usage,
+	}
+}
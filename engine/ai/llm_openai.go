@@ -0,0 +1,391 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// OpenAI-Compatible HTTP Client
+// ============================================================================
+
+// OpenAIClient talks to any server implementing the OpenAI chat completions
+// API (OpenAI, Ollama, llama.cpp, vLLM, LM Studio, ...).
+type OpenAIClient struct {
+	BaseURL    string // e.g. "https://api.openai.com/v1" or "http://localhost:11434/v1"
+	APIKey     string
+	Model      string // Used when a request does not set Model
+	HTTPClient *http.Client
+	MaxRetries int // Retries for 429 and 5xx responses
+	Headers    map[string]string
+}
+
+// NewOpenAIClient creates a client for an OpenAI-compatible endpoint.
+func NewOpenAIClient(baseURL, apiKey, model string) *OpenAIClient {
+	return &OpenAIClient{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		Model:      model,
+		HTTPClient: &http.Client{Timeout: 2 * time.Minute},
+		MaxRetries: 2,
+	}
+}
+
+var errLLMNoChoices = errors.New("llm: response has no choices")
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm: server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Chat implements LLMClient.
+func (c *OpenAIClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	body, err := c.do(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var wire oaiResponse
+	if err := json.NewDecoder(body).Decode(&wire); err != nil {
+		return nil, fmt.Errorf("llm: decode response: %w", err)
+	}
+
+	if len(wire.Choices) == 0 {
+		return nil, errLLMNoChoices
+	}
+
+	choice := wire.Choices[0]
+	resp := &ChatResponse{
+		Model:        wire.Model,
+		Message:      choice.Message.toMessage(),
+		FinishReason: choice.FinishReason,
+	}
+
+	if wire.Usage != nil {
+		resp.Usage = *wire.Usage
+	} else {
+		resp.Usage = estimateUsage(req, resp)
+	}
+
+	return resp, nil
+}
+
+// ChatStream implements LLMClient using server-sent events.
+func (c *OpenAIClient) ChatStream(
+	ctx context.Context,
+	req ChatRequest,
+	onChunk func(StreamChunk) error,
+) (*ChatResponse, error) {
+	body, err := c.do(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	resp := &ChatResponse{Message: ChatMessage{Role: RoleAssistant}}
+
+	var (
+		content strings.Builder
+		calls   []ToolCall
+		usage   *Usage
+	)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk oaiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("llm: decode stream chunk: %w", err)
+		}
+
+		if chunk.Error != nil {
+			return nil, &APIError{StatusCode: http.StatusOK, Message: chunk.Error.Message}
+		}
+
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			resp.FinishReason = choice.FinishReason
+		}
+
+		for _, tc := range choice.Delta.ToolCalls {
+			for len(calls) <= tc.Index {
+				calls = append(calls, ToolCall{})
+			}
+
+			if tc.ID != "" {
+				calls[tc.Index].ID = tc.ID
+			}
+
+			calls[tc.Index].Name += tc.Function.Name
+			calls[tc.Index].Arguments += tc.Function.Arguments
+		}
+
+		var out StreamChunk
+
+		out.Delta = choice.Delta.Content
+		content.WriteString(out.Delta)
+
+		// Tool call arguments arrive in pieces; report them once complete
+		if choice.FinishReason != "" {
+			out.ToolCalls = calls
+		}
+
+		if out.Delta == "" && len(out.ToolCalls) == 0 {
+			continue
+		}
+
+		if err := onChunk(out); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("llm: read stream: %w", err)
+	}
+
+	resp.Message.Content = content.String()
+	resp.Message.ToolCalls = calls
+
+	if usage != nil {
+		resp.Usage = *usage
+	} else {
+		resp.Usage = estimateUsage(req, resp)
+	}
+
+	return resp, nil
+}
+
+// do sends the request, retrying transient failures, and returns the body.
+func (c *OpenAIClient) do(ctx context.Context, req ChatRequest, stream bool) (io.ReadCloser, error) {
+	payload, err := json.Marshal(c.toWire(req, stream))
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt*attempt) * 500 * time.Millisecond):
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(
+			ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(payload),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		httpReq.Header.Set("Content-Type", "application/json")
+
+		if c.APIKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+		}
+
+		for k, v := range c.Headers {
+			httpReq.Header.Set(k, v)
+		}
+
+		httpResp, err := c.HTTPClient.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("llm: %w", err)
+		}
+
+		if httpResp.StatusCode/100 == 2 {
+			return httpResp.Body, nil
+		}
+
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+		httpResp.Body.Close()
+
+		lastErr = &APIError{StatusCode: httpResp.StatusCode, Message: strings.TrimSpace(string(msg))}
+
+		if httpResp.StatusCode != http.StatusTooManyRequests && httpResp.StatusCode < 500 {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// toWire converts a request to the OpenAI wire format.
+func (c *OpenAIClient) toWire(req ChatRequest, stream bool) oaiRequest {
+	model := req.Model
+	if model == "" {
+		model = c.Model
+	}
+
+	wire := oaiRequest{
+		Model:       model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Seed:        req.Seed,
+		Stream:      stream,
+	}
+
+	if stream {
+		wire.StreamOptions = &oaiStreamOptions{IncludeUsage: true}
+	}
+
+	for _, m := range req.Messages {
+		wire.Messages = append(wire.Messages, fromMessage(m))
+	}
+
+	for _, t := range req.Tools {
+		wire.Tools = append(wire.Tools, oaiTool{Type: "function", Function: t})
+	}
+
+	switch req.ToolChoice {
+	case "":
+	case "auto", "none", "required":
+		wire.ToolChoice = req.ToolChoice
+	default:
+		wire.ToolChoice = map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": req.ToolChoice},
+		}
+	}
+
+	if req.Schema != nil {
+		wire.ResponseFormat = &oaiResponseFormat{Type: "json_schema", JSONSchema: req.Schema}
+	}
+
+	return wire
+}
+
+// ============================================================================
+// Wire Format
+// ============================================================================
+
+type oaiRequest struct {
+	Model          string             `json:"model"`
+	Messages       []oaiMessage       `json:"messages"`
+	Tools          []oaiTool          `json:"tools,omitempty"`
+	ToolChoice     any                `json:"tool_choice,omitempty"`
+	ResponseFormat *oaiResponseFormat `json:"response_format,omitempty"`
+	Temperature    float64            `json:"temperature,omitempty"`
+	MaxTokens      int                `json:"max_tokens,omitempty"`
+	Seed           int                `json:"seed,omitempty"`
+	Stream         bool               `json:"stream,omitempty"`
+	StreamOptions  *oaiStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type oaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type oaiResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *ResponseSchema `json:"json_schema,omitempty"`
+}
+
+type oaiTool struct {
+	Type     string         `json:"type"`
+	Function ToolDefinition `json:"function"`
+}
+
+type oaiMessage struct {
+	Role       Role          `json:"role"`
+	Content    string        `json:"content"`
+	Name       string        `json:"name,omitempty"`
+	ToolCalls  []oaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+type oaiToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+type oaiChoice struct {
+	Message      oaiMessage `json:"message"`
+	Delta        oaiMessage `json:"delta"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+}
+
+type oaiResponse struct {
+	Model   string      `json:"model,omitempty"`
+	Choices []oaiChoice `json:"choices"`
+	Usage   *Usage      `json:"usage,omitempty"`
+	Error   *oaiError   `json:"error,omitempty"`
+}
+
+type oaiError struct {
+	Message string `json:"message"`
+}
+
+func fromMessage(m ChatMessage) oaiMessage {
+	out := oaiMessage{Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallID}
+
+	for i, tc := range m.ToolCalls {
+		call := oaiToolCall{Index: i, ID: tc.ID, Type: "function"}
+		call.Function.Name = tc.Name
+		call.Function.Arguments = tc.Arguments
+		out.ToolCalls = append(out.ToolCalls, call)
+	}
+
+	return out
+}
+
+func (m oaiMessage) toMessage() ChatMessage {
+	out := ChatMessage{Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallID}
+	if out.Role == "" {
+		out.Role = RoleAssistant
+	}
+
+	for _, tc := range m.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+
+	return out
+}
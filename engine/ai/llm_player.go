@@ -0,0 +1,286 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// AgentDecision records one model call made by a reasoning player.
+type AgentDecision struct {
+	Tick      int64        `json:"tick"`
+	Actions   []ActionType `json:"actions"`
+	Reasoning string       `json:"reasoning,omitempty"`
+	Note      string       `json:"note,omitempty"`
+	Tokens    int          `json:"tokens"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// ReasoningPlayer is a Player that explains its decisions.
+// QASession copies the decisions into each RunResult.
+type ReasoningPlayer interface {
+	Player
+	// TakeDecisions returns and clears the decisions made since the last call.
+	TakeDecisions() []AgentDecision
+}
+
+// ObserveFunc describes the game for a model prompt.
+type ObserveFunc func(state GameState) string
+
+// CompactObservation observes the world with a CompactExporter.
+func CompactObservation(world *ecs.World) ObserveFunc {
+	exporter := NewCompactExporter(world)
+
+	return func(state GameState) string {
+		return exporter.Export(world, state.Tick)
+	}
+}
+
+// SnapshotObservation observes the world as StateExporter JSON.
+func SnapshotObservation(world *ecs.World) ObserveFunc {
+	exporter := NewStateExporter(world)
+
+	return func(state GameState) string {
+		return exporter.ExportJSON(world, state.Tick)
+	}
+}
+
+// LLMPlayerConfig configures an LLMPlayer.
+type LLMPlayerConfig struct {
+	Goal           string        // What the agent should try to do
+	DecideEvery    int           // Ticks between model calls; each call plans this many actions
+	ScratchpadSize int           // Notes kept in the rolling memory
+	Timeout        time.Duration // Per-call timeout (0 = none)
+	Observe        ObserveFunc   // Optional world description added to the prompt
+}
+
+// DefaultLLMPlayerConfig returns sensible defaults.
+func DefaultLLMPlayerConfig() LLMPlayerConfig {
+	return LLMPlayerConfig{
+		Goal:           "Survive as long as possible and maximize the score.",
+		DecideEvery:    10,
+		ScratchpadSize: 8,
+		Timeout:        30 * time.Second,
+	}
+}
+
+// llmReply is the structured reply requested from the model.
+type llmReply struct {
+	Reasoning string       `json:"reasoning"`
+	Actions   []ActionType `json:"actions"`
+	Note      string       `json:"note"`
+}
+
+// LLMPlayer asks a language model which actions to take.
+//
+// Every DecideEvery ticks it sends the game state, optional world
+// observation, available actions and its scratchpad to the model, and asks
+// for a JSON plan of up to DecideEvery actions. Planned actions are played
+// one per tick; invalid actions are skipped. If the model fails, the
+// Fallback player decides instead.
+type LLMPlayer struct {
+	Client   LLMClient
+	Config   LLMPlayerConfig
+	Fallback Player
+
+	plan       []ActionType
+	last       ActionType
+	sinceCall  int
+	scratchpad []string
+	decisions  []AgentDecision
+}
+
+// NewLLMPlayer creates an LLM-driven player.
+func NewLLMPlayer(client LLMClient, config LLMPlayerConfig) *LLMPlayer {
+	if config.DecideEvery <= 0 {
+		config.DecideEvery = 1
+	}
+
+	return &LLMPlayer{
+		Client:   client,
+		Config:   config,
+		Fallback: NewRandomPlayer(1),
+		last:     ActionNone,
+	}
+}
+
+// DecideAction implements Player.
+func (p *LLMPlayer) DecideAction(state GameState, available []ActionType) ActionType {
+	if len(available) == 0 {
+		return ActionNone
+	}
+
+	if p.sinceCall <= 0 || p.sinceCall >= p.Config.DecideEvery {
+		p.decide(state, available)
+	}
+
+	p.sinceCall++
+
+	for len(p.plan) > 0 {
+		action := p.plan[0]
+		p.plan = p.plan[1:]
+
+		if contains(available, action) {
+			p.last = action
+
+			return action
+		}
+	}
+
+	// Plan exhausted: keep doing the last valid action until the next call
+	if contains(available, p.last) {
+		return p.last
+	}
+
+	return p.Fallback.DecideAction(state, available)
+}
+
+// Scratchpad returns the agent's rolling notes, oldest first.
+func (p *LLMPlayer) Scratchpad() []string {
+	return slices.Clone(p.scratchpad)
+}
+
+// Remember adds a note to the scratchpad.
+func (p *LLMPlayer) Remember(note string) {
+	if note == "" {
+		return
+	}
+
+	p.scratchpad = append(p.scratchpad, note)
+	if n := p.Config.ScratchpadSize; n > 0 && len(p.scratchpad) > n {
+		p.scratchpad = p.scratchpad[len(p.scratchpad)-n:]
+	}
+}
+
+// TakeDecisions implements ReasoningPlayer.
+func (p *LLMPlayer) TakeDecisions() []AgentDecision {
+	d := p.decisions
+	p.decisions = nil
+
+	return d
+}
+
+// decide calls the model and replaces the plan.
+func (p *LLMPlayer) decide(state GameState, available []ActionType) {
+	p.sinceCall = 0
+	p.plan = nil
+
+	ctx := context.Background()
+
+	if p.Config.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, p.Config.Timeout)
+		defer cancel()
+	}
+
+	req := ChatRequest{
+		Messages: Messages(p.systemPrompt(), p.userPrompt(state, available)),
+		Schema: &ResponseSchema{
+			Name:   "actions",
+			Schema: llmReplySchema(available, p.Config.DecideEvery),
+			Strict: true,
+		},
+	}
+
+	decision := AgentDecision{Tick: state.Tick}
+
+	resp, err := p.Client.Chat(ctx, req)
+	if err == nil {
+		decision.Tokens = resp.Usage.TotalTokens
+
+		var reply llmReply
+		if err = DecodeJSONReply(resp.Message.Content, &reply); err == nil {
+			p.plan = reply.Actions
+			if len(p.plan) > p.Config.DecideEvery {
+				p.plan = p.plan[:p.Config.DecideEvery]
+			}
+
+			decision.Actions = p.plan
+			decision.Reasoning = reply.Reasoning
+			decision.Note = reply.Note
+			p.Remember(reply.Note)
+		}
+	}
+
+	if err != nil {
+		decision.Error = err.Error()
+	}
+
+	p.decisions = append(p.decisions, decision)
+}
+
+func (p *LLMPlayer) systemPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("You are an AI agent playing a video game through a list of discrete actions.\n")
+	sb.WriteString("Goal: " + p.Config.Goal + "\n\n")
+	sb.WriteString(fmt.Sprintf("Plan the next %d actions, one per tick, using only the listed actions.\n",
+		p.Config.DecideEvery))
+	sb.WriteString(`Reply with JSON: {"reasoning": "...", "actions": ["..."], "note": "..."}` + "\n")
+	sb.WriteString("Use note for anything you want to remember on later turns.")
+
+	return sb.String()
+}
+
+func (p *LLMPlayer) userPrompt(state GameState, available []ActionType) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Tick: %d\nScore: %d\n", state.Tick, state.Score))
+	sb.WriteString(fmt.Sprintf("Player: pos=(%.0f,%.0f) health=%d/%d\n",
+		state.PlayerPos[0], state.PlayerPos[1], state.PlayerHealth[0], state.PlayerHealth[1]))
+	sb.WriteString(fmt.Sprintf("Entities: %d\n", state.EntityCount))
+
+	if len(state.CustomData) > 0 {
+		custom, _ := json.Marshal(state.CustomData)
+		sb.WriteString("Custom: " + string(custom) + "\n")
+	}
+
+	if p.Config.Observe != nil {
+		sb.WriteString("World: " + p.Config.Observe(state) + "\n")
+	}
+
+	names := make([]string, len(available))
+	for i, a := range available {
+		names[i] = string(a)
+	}
+
+	sb.WriteString("Available actions: " + strings.Join(names, ", ") + "\n")
+
+	if len(p.scratchpad) > 0 {
+		sb.WriteString("Scratchpad:\n")
+
+		for _, note := range p.scratchpad {
+			sb.WriteString("- " + note + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// llmReplySchema constrains replies to the available actions.
+func llmReplySchema(available []ActionType, maxActions int) JSONSchema {
+	enum := make([]any, len(available))
+	for i, a := range available {
+		enum[i] = string(a)
+	}
+
+	return JSONSchema{
+		"type": "object",
+		"properties": map[string]any{
+			"reasoning": map[string]any{"type": "string"},
+			"actions": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"type": "string", "enum": enum},
+				"minItems": 1,
+				"maxItems": maxActions,
+			},
+			"note": map[string]any{"type": "string"},
+		},
+		"required":             []any{"reasoning", "actions", "note"},
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,286 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// handlerTransport routes HTTP requests straight to a handler, so the
+// OpenAI client can be tested without opening sockets.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, req)
+
+	return rec.Result(), nil
+}
+
+func newMockOpenAIClient(backend LLMClient) *OpenAIClient {
+	client := NewOpenAIClient("http://mock/v1", "test-key", "mock-model")
+	client.HTTPClient = &http.Client{Transport: handlerTransport{NewMockLLMServer(backend)}}
+
+	return client
+}
+
+var questSchema = JSONSchema{
+	"type": "object",
+	"properties": map[string]any{
+		"title":      map[string]any{"type": "string"},
+		"difficulty": map[string]any{"type": "string", "enum": []any{"easy", "hard"}},
+		"objectives": map[string]any{"type": "array", "minItems": 2, "items": map[string]any{"type": "string"}},
+		"reward":     map[string]any{"type": "integer", "minimum": 10},
+	},
+}
+
+type quest struct {
+	Title      string   `json:"title"`
+	Difficulty string   `json:"difficulty"`
+	Objectives []string `json:"objectives"`
+	Reward     int      `json:"reward"`
+}
+
+func TestFakeLLM(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeLLM().On("greet", "Hello, traveler.")
+
+	reply, err := Ask(ctx, fake, "You are a shopkeeper.", "Please greet the player")
+	if err != nil || reply != "Hello, traveler." {
+		t.Errorf("Ask = %q, %v", reply, err)
+	}
+
+	a, _ := Ask(ctx, fake, "", "something else")
+	b, _ := Ask(ctx, fake, "", "something else")
+
+	if a != b || !strings.HasPrefix(a, "fake reply") {
+		t.Errorf("Unmatched prompts should get stable replies, got %q and %q", a, b)
+	}
+
+	var q quest
+	if err := AskJSON(ctx, fake, ChatRequest{Messages: Messages("", "make a quest")}, questSchema, &q); err != nil {
+		t.Fatalf("AskJSON: %v", err)
+	}
+
+	if q.Difficulty != "easy" || len(q.Objectives) != 2 || q.Reward != 10 {
+		t.Errorf("Schema sample = %+v", q)
+	}
+
+	if len(fake.Requests()) != 4 {
+		t.Errorf("Requests = %d, want 4", len(fake.Requests()))
+	}
+}
+
+func TestDecodeJSONReply(t *testing.T) {
+	var v struct{ Move string }
+
+	if err := DecodeJSONReply("Sure!\n```json\n{\"Move\": \"left\"}\n```", &v); err != nil || v.Move != "left" {
+		t.Errorf("DecodeJSONReply = %+v, %v", v, err)
+	}
+
+	if err := DecodeJSONReply("no json here", &v); !errors.Is(err, ErrNoJSON) {
+		t.Errorf("err = %v, want ErrNoJSON", err)
+	}
+}
+
+func TestCachedAndAccountingClients(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeLLM()
+	cache := NewCachedClient(fake)
+	acct := NewAccountingClient(cache)
+
+	req := ChatRequest{Messages: Messages("", "describe the castle")}
+
+	first, _ := acct.Chat(ctx, req)
+	second, _ := acct.Chat(ctx, req)
+
+	if !second.Cached || second.Message.Content != first.Message.Content {
+		t.Error("Second identical request should be served from cache")
+	}
+
+	if len(fake.Requests()) != 1 {
+		t.Errorf("Backend calls = %d, want 1", len(fake.Requests()))
+	}
+
+	usage := acct.Usage()
+	if usage.Requests != 2 || usage.CachedRequests != 1 || usage.TotalTokens != first.Usage.TotalTokens {
+		t.Errorf("Usage = %+v", usage)
+	}
+
+	if usage.ByModel["fake"].TotalTokens != first.Usage.TotalTokens {
+		t.Errorf("ByModel = %+v", usage.ByModel)
+	}
+
+	acct.MaxTokens = usage.TotalTokens
+	if _, err := acct.Chat(ctx, ChatRequest{Messages: Messages("", "new")}); !errors.Is(err, ErrTokenBudget) {
+		t.Errorf("err = %v, want ErrTokenBudget", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := cache.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewCachedClient(NewFakeLLM().On("", "different"))
+	if err := reloaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, _ := reloaded.Chat(ctx, req); resp.Message.Content != first.Message.Content {
+		t.Error("Loaded cache should serve the saved response")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	rec := NewRecordingClient(NewFakeLLM().On("", "recorded answer"))
+
+	req := ChatRequest{Messages: Messages("sys", "question")}
+	if _, err := rec.Chat(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := LoadReplayClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var streamed strings.Builder
+
+	resp, err := replay.ChatStream(ctx, req, func(c StreamChunk) error {
+		streamed.WriteString(c.Delta)
+
+		return nil
+	})
+	if err != nil || resp.Message.Content != "recorded answer" || streamed.String() != "recorded answer" {
+		t.Errorf("Replay = %v, %v, streamed %q", resp, err, streamed.String())
+	}
+
+	if _, err := replay.Chat(ctx, ChatRequest{Messages: Messages("", "unseen")}); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("err = %v, want ErrNoRecording", err)
+	}
+}
+
+func TestOpenAIClientAgainstMockServer(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeLLM().
+		On("weather", "It is raining in the valley.").
+		OnToolCall("attack", "attack", map[string]any{"target": "goblin"})
+	client := newMockOpenAIClient(fake)
+
+	t.Run("chat", func(t *testing.T) {
+		resp, err := client.Chat(ctx, ChatRequest{Messages: Messages("sys", "what is the weather")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Message.Content != "It is raining in the valley." || resp.Usage.TotalTokens == 0 {
+			t.Errorf("resp = %+v", resp)
+		}
+
+		got := fake.Requests()[len(fake.Requests())-1]
+		if got.Model != "mock-model" || len(got.Messages) != 2 || got.Messages[0].Role != RoleSystem {
+			t.Errorf("Server received %+v", got)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var chunks []string
+
+		resp, err := client.ChatStream(ctx, ChatRequest{Messages: Messages("", "weather?")}, func(c StreamChunk) error {
+			chunks = append(chunks, c.Delta)
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(chunks) < 2 || strings.Join(chunks, "") != resp.Message.Content {
+			t.Errorf("chunks = %q, content = %q", chunks, resp.Message.Content)
+		}
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		req := ChatRequest{
+			Messages: Messages("", "attack now"),
+			Tools: []ToolDefinition{{
+				Name:       "attack",
+				Parameters: JSONSchema{"type": "object", "properties": map[string]any{"target": map[string]any{"type": "string"}}},
+			}},
+			ToolChoice: "attack",
+		}
+
+		for _, stream := range []bool{false, true} {
+			var (
+				resp *ChatResponse
+				err  error
+			)
+
+			if stream {
+				resp, err = client.ChatStream(ctx, req, func(StreamChunk) error { return nil })
+			} else {
+				resp, err = client.Chat(ctx, req)
+			}
+
+			if err != nil || len(resp.Message.ToolCalls) != 1 {
+				t.Fatalf("stream=%v: resp = %+v, err = %v", stream, resp, err)
+			}
+
+			var args struct{ Target string }
+			if err := resp.Message.ToolCalls[0].DecodeArguments(&args); err != nil || args.Target != "goblin" {
+				t.Errorf("stream=%v: args = %+v, %v", stream, args, err)
+			}
+		}
+
+		got := fake.Requests()[len(fake.Requests())-1]
+		if got.ToolChoice != "attack" || len(got.Tools) != 1 {
+			t.Errorf("Tools not forwarded: %+v", got)
+		}
+	})
+
+	t.Run("json schema", func(t *testing.T) {
+		var q quest
+		if err := AskJSON(ctx, client, ChatRequest{Messages: Messages("", "quest")}, questSchema, &q); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(q.Objectives) != 2 {
+			t.Errorf("quest = %+v", q)
+		}
+	})
+
+	t.Run("temperature only when set", func(t *testing.T) {
+		for temp, want := range map[float64]bool{0: false, 0.7: true} {
+			body, _ := json.Marshal(client.toWire(ChatRequest{Messages: Messages("", "x"), Temperature: temp}, false))
+			if got := strings.Contains(string(body), `"temperature"`); got != want {
+				t.Errorf("temperature %v: body = %s", temp, body)
+			}
+		}
+	})
+
+	t.Run("server errors surface as APIError", func(t *testing.T) {
+		failing := NewFakeLLM()
+		failing.Handler = func(ChatRequest) (*ChatResponse, error) { return nil, errors.New("boom") }
+
+		c := newMockOpenAIClient(failing)
+		c.MaxRetries = 0
+
+		var apiErr *APIError
+		if _, err := c.Chat(ctx, ChatRequest{Messages: Messages("", "x")}); !errors.As(err, &apiErr) {
+			t.Errorf("err = %v, want *APIError", err)
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package ai
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 
@@ -19,88 +24,290 @@ type LoreEntry struct {
 	Relevance float32   // Computed similarity score
 }
 
-// LoreDatabase is a simple RAG-style lore database.
+// LoreDatabase is a RAG-style lore database.
+//
+// Entry content is split into chunks, embedded with an Embedder (an offline
+// HashingEmbedder by default) and indexed for both vector and BM25 keyword
+// search. Query and Search rank entries by a weighted mix of the two.
 type LoreDatabase struct {
 	entries map[string]*LoreEntry
 	index   map[string][]*LoreEntry // Tag -> entries index
+
+	ChunkWords   int     // Max words per chunk
+	ChunkOverlap int     // Words shared by consecutive chunks
+	VectorWeight float32 // Weight of vector similarity in hybrid ranking (rest is keyword score)
+
+	embedder Embedder
+	vectors  *VectorIndex
+	keywords *bm25Index
 }
 
 // NewLoreDatabase creates a new lore database.
 func NewLoreDatabase() *LoreDatabase {
+	embedder := NewHashingEmbedder(512)
+
 	return &LoreDatabase{
-		entries: make(map[string]*LoreEntry),
-		index:   make(map[string][]*LoreEntry),
+		entries:      make(map[string]*LoreEntry),
+		index:        make(map[string][]*LoreEntry),
+		ChunkWords:   120,
+		ChunkOverlap: 20,
+		VectorWeight: 0.5,
+		embedder:     embedder,
+		vectors:      NewVectorIndex(embedder),
+		keywords:     newBM25Index(),
 	}
 }
 
-// AddEntry adds a lore entry to the database.
+// AddEntry adds a lore entry to the database, replacing any entry with the
+// same ID. Embedding errors leave the entry keyword-searchable only; use
+// IndexEntry to see them.
 func (db *LoreDatabase) AddEntry(entry LoreEntry) {
-	db.entries[entry.ID] = &entry
+	_ = db.IndexEntry(entry)
+}
+
+// IndexEntry adds a lore entry and returns any embedding error.
+func (db *LoreDatabase) IndexEntry(entry LoreEntry) error {
+	db.RemoveEntry(entry.ID)
+
+	e := &entry
+	db.entries[entry.ID] = e
 
 	// Index by category
-	db.index[entry.Category] = append(db.index[entry.Category], &entry)
+	db.index[entry.Category] = append(db.index[entry.Category], e)
 
 	// Index by tags
 	for _, tag := range entry.Tags {
 		tag = strings.ToLower(tag)
-		db.index[tag] = append(db.index[tag], &entry)
+		db.index[tag] = append(db.index[tag], e)
 	}
+
+	return db.indexChunks(e)
 }
 
-// Query searches for relevant lore entries.
-func (db *LoreDatabase) Query(query string, limit int) []*LoreEntry {
-	queryLower := strings.ToLower(query)
-	words := strings.Fields(queryLower)
+// RemoveEntry deletes an entry and its index data.
+func (db *LoreDatabase) RemoveEntry(id string) {
+	old, ok := db.entries[id]
+	if !ok {
+		return
+	}
 
-	// Score all entries based on keyword matches
-	scored := make([]*LoreEntry, 0)
+	delete(db.entries, id)
 
-	for _, entry := range db.entries {
-		score := db.computeRelevance(entry, words)
-		if score > 0 {
-			entry.Relevance = score
-			scored = append(scored, entry)
+	for key, list := range db.index {
+		db.index[key] = slices.DeleteFunc(list, func(e *LoreEntry) bool { return e == old })
+		if len(db.index[key]) == 0 {
+			delete(db.index, key)
 		}
 	}
 
-	// Sort by relevance
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].Relevance > scored[j].Relevance
-	})
+	for _, c := range db.vectors.Chunks {
+		if c.DocID == id {
+			db.keywords.remove(c)
+		}
+	}
+
+	db.vectors.Remove(id)
+}
+
+// SetEmbedder switches the embedding model and re-indexes every entry.
+func (db *LoreDatabase) SetEmbedder(embedder Embedder) error {
+	db.embedder = embedder
+	db.vectors = NewVectorIndex(embedder)
+	db.keywords = newBM25Index()
+
+	var firstErr error
+
+	for _, id := range slices.Sorted(maps.Keys(db.entries)) {
+		if err := db.indexChunks(db.entries[id]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Embedder returns the embedding model in use.
+func (db *LoreDatabase) Embedder() Embedder {
+	return db.embedder
+}
+
+// indexChunks chunks, embeds and indexes an entry.
+func (db *LoreDatabase) indexChunks(e *LoreEntry) error {
+	texts := ChunkText(e.Content, db.ChunkWords, db.ChunkOverlap)
+	if len(texts) == 0 {
+		texts = []string{e.Title}
+	}
+
+	chunks := make([]*IndexedChunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = &IndexedChunk{DocID: e.ID, Chunk: i, Text: text}
+	}
 
-	// Return top results
-	if len(scored) > limit {
-		scored = scored[:limit]
+	// A caller-supplied embedding is used for single-chunk entries
+	if len(chunks) == 1 && len(e.Embedding) == db.embedder.Dimensions() {
+		chunks[0].Vector = append([]float32(nil), e.Embedding...)
+
+		return db.addChunks(e, chunks)
+	}
+
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		inputs[i] = e.Title + ". " + text
+	}
+
+	vectors, err := db.embedder.Embed(inputs)
+	if err != nil {
+		return fmt.Errorf("embed lore entry %q: %w", e.ID, err)
+	}
+
+	// Computed vectors live only in the index. Writing them back to
+	// e.Embedding would pass them off as caller-supplied on the next
+	// SetEmbedder or Load.
+	for i, c := range chunks {
+		c.Vector = vectors[i]
 	}
 
-	return scored
+	return db.addChunks(e, chunks)
+}
+
+// addChunks adds an entry's embedded chunks to the vector and keyword
+// indexes.
+func (db *LoreDatabase) addChunks(e *LoreEntry, chunks []*IndexedChunk) error {
+	for _, c := range chunks {
+		if err := db.vectors.Add(c); err != nil {
+			return fmt.Errorf("index lore entry %q: %w", e.ID, err)
+		}
+
+		db.keywords.add(c, db.keywordText(e, c.Text))
+	}
+
+	return nil
+}
+
+// keywordText is the text BM25 sees for a chunk. Titles and tags are
+// repeated so they weigh more than body text.
+func (db *LoreDatabase) keywordText(e *LoreEntry, chunk string) string {
+	tags := strings.Join(e.Tags, " ")
+
+	return strings.Join([]string{e.Title, e.Title, tags, tags, e.Category, chunk}, " ")
+}
+
+// LoreSearchOptions filters and limits a lore search.
+type LoreSearchOptions struct {
+	Limit    int      // Max results (0 = all)
+	Category string   // Only entries in this category
+	Tags     []string // Only entries with all of these tags
+	MinScore float32  // Drop results scoring at or below this
 }
 
-// computeRelevance calculates relevance score based on keyword matching.
-func (db *LoreDatabase) computeRelevance(entry *LoreEntry, queryWords []string) float32 {
-	var score float32
+// LoreResult is a ranked lore search result.
+type LoreResult struct {
+	Entry        *LoreEntry
+	Score        float32 // Hybrid score
+	VectorScore  float32 // Cosine similarity of the best chunk
+	KeywordScore float32 // BM25 score of the best chunk, normalized to [0,1]
+	Chunk        string  // Best matching chunk text
+}
+
+// Search ranks entries by hybrid vector + keyword relevance.
+func (db *LoreDatabase) Search(query string, opts LoreSearchOptions) []LoreResult {
+	filter := func(id string) bool {
+		e := db.entries[id]
+		if e == nil || (opts.Category != "" && e.Category != opts.Category) {
+			return false
+		}
+
+		for _, want := range opts.Tags {
+			if !slices.ContainsFunc(e.Tags, func(t string) bool { return strings.EqualFold(t, want) }) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	var hits []VectorHit
+
+	if vecs, err := db.embedder.Embed([]string{query}); err == nil {
+		hits = db.vectors.Search(vecs[0], 0, filter)
+	}
+
+	keyword := db.keywords.score(query)
+
+	var maxKeyword float64
+	for _, s := range keyword {
+		maxKeyword = max(maxKeyword, s)
+	}
 
-	contentLower := strings.ToLower(entry.Content)
-	titleLower := strings.ToLower(entry.Title)
+	best := make(map[string]LoreResult)
 
-	for _, word := range queryWords {
-		// Title match is worth more
-		if strings.Contains(titleLower, word) {
-			score += 2.0
+	consider := func(c *IndexedChunk, vecScore float32) {
+		var kw float32
+		if maxKeyword > 0 {
+			kw = float32(keyword[c] / maxKeyword)
 		}
-		// Content match
-		if strings.Contains(contentLower, word) {
-			score += 1.0
+
+		score := db.VectorWeight*max(vecScore, 0) + (1-db.VectorWeight)*kw
+		if cur, ok := best[c.DocID]; ok && cur.Score >= score {
+			return
+		}
+
+		best[c.DocID] = LoreResult{
+			Entry:        db.entries[c.DocID],
+			Score:        score,
+			VectorScore:  vecScore,
+			KeywordScore: kw,
+			Chunk:        c.Text,
 		}
-		// Tag match is worth even more
-		for _, tag := range entry.Tags {
-			if strings.Contains(strings.ToLower(tag), word) {
-				score += 3.0
+	}
+
+	for _, h := range hits {
+		consider(h.IndexedChunk, h.Score)
+	}
+
+	// Without vectors (embedder error) keyword matches still count
+	if len(hits) == 0 {
+		for c := range keyword {
+			if filter(c.DocID) {
+				consider(c, 0)
 			}
 		}
 	}
 
-	return score
+	results := make([]LoreResult, 0, len(best))
+
+	for _, r := range best {
+		if r.Score > opts.MinScore {
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].Entry.ID < results[j].Entry.ID
+	})
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results
+}
+
+// Query searches for relevant lore entries using hybrid ranking.
+func (db *LoreDatabase) Query(query string, limit int) []*LoreEntry {
+	results := db.Search(query, LoreSearchOptions{Limit: limit})
+
+	entries := make([]*LoreEntry, len(results))
+	for i, r := range results {
+		r.Entry.Relevance = r.Score
+		entries[i] = r.Entry
+	}
+
+	return entries
 }
 
 // QueryByCategory returns all entries in a category.
@@ -182,3 +389,78 @@ func (db *LoreDatabase) ExportMarkdown() string {
 func (db *LoreDatabase) Count() int {
 	return len(db.entries)
 }
+
+// loreFile is the on-disk format written by Save.
+type loreFile struct {
+	Entries []*LoreEntry `json:"entries"`
+	Index   *VectorIndex `json:"index"`
+}
+
+// Save writes all entries and the vector index to a JSON file.
+func (db *LoreDatabase) Save(path string) error {
+	file := loreFile{Index: db.vectors}
+	for _, id := range slices.Sorted(maps.Keys(db.entries)) {
+		file.Entries = append(file.Entries, db.entries[id])
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load replaces the database contents with a file written by Save. The saved
+// vectors are reused when they came from the current embedder; otherwise
+// every entry is embedded again.
+func (db *LoreDatabase) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file loreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("load lore: %w", err)
+	}
+
+	db.entries = make(map[string]*LoreEntry, len(file.Entries))
+	db.index = make(map[string][]*LoreEntry)
+	db.vectors = NewVectorIndex(db.embedder)
+	db.keywords = newBM25Index()
+
+	reuse := file.Index != nil && file.Index.Model == db.embedder.Name() &&
+		file.Index.Dims == db.embedder.Dimensions()
+
+	for _, e := range file.Entries {
+		db.entries[e.ID] = e
+		db.index[e.Category] = append(db.index[e.Category], e)
+
+		for _, tag := range e.Tags {
+			tag = strings.ToLower(tag)
+			db.index[tag] = append(db.index[tag], e)
+		}
+
+		if !reuse {
+			if err := db.indexChunks(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	if reuse {
+		for _, c := range file.Index.Chunks {
+			e, ok := db.entries[c.DocID]
+			if !ok || len(c.Vector) != db.vectors.Dims {
+				continue
+			}
+
+			if err := db.addChunks(e, []*IndexedChunk{c}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package ai
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -51,6 +54,33 @@ const (
 	QuestFailed
 )
 
+var questStatusNames = [...]string{"not_started", "active", "completed", "failed"}
+
+func (s QuestStatus) String() string {
+	if s >= 0 && int(s) < len(questStatusNames) {
+		return questStatusNames[s]
+	}
+
+	return "unknown"
+}
+
+// MarshalText encodes the status by name for save files.
+func (s QuestStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name.
+func (s *QuestStatus) UnmarshalText(text []byte) error {
+	i := slices.Index(questStatusNames[:], string(text))
+	if i < 0 {
+		return fmt.Errorf("unknown quest status %q", text)
+	}
+
+	*s = QuestStatus(i)
+
+	return nil
+}
+
 // NarrativeController manages dynamic storytelling and dialogue.
 type NarrativeController struct {
 	dialogues      map[string]*DialogueNode
@@ -232,3 +262,16 @@ func (n *NarrativeController) GenerateQuestPrompt(theme, difficulty string) stri
 
 	return sb.String()
 }
+
+// Variables returns a copy of all narrative variables.
+func (n *NarrativeController) Variables() map[string]any {
+	return maps.Clone(n.variables)
+}
+
+// SetVariables replaces all narrative variables, as when loading a save.
+func (n *NarrativeController) SetVariables(vars map[string]any) {
+	n.variables = maps.Clone(vars)
+	if n.variables == nil {
+		n.variables = make(map[string]any)
+	}
+}
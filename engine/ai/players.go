@@ -162,6 +162,37 @@ func (p *ReplayPlayer) Reset() {
 	p.index = 0
 }
 
+// SkilledPlayer follows an expert policy with probability Skill and acts
+// randomly otherwise, simulating players of varying ability.
+type SkilledPlayer struct {
+	Expert Player
+	Skill  float64 // 0 = fully random, 1 = always the expert's choice
+
+	rng *rand.Rand
+}
+
+// NewSkilledPlayer creates a player that follows expert with probability skill.
+func NewSkilledPlayer(expert Player, skill float64, seed int64) *SkilledPlayer {
+	return &SkilledPlayer{
+		Expert: expert,
+		Skill:  skill,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// DecideAction picks the expert's action or a random one.
+func (p *SkilledPlayer) DecideAction(state GameState, available []ActionType) ActionType {
+	if len(available) == 0 {
+		return ActionNone
+	}
+
+	if p.rng.Float64() < p.Skill {
+		return p.Expert.DecideAction(state, available)
+	}
+
+	return available[p.rng.Intn(len(available))]
+}
+
 // Helper functions.
 func abs(x float64) float64 {
 	if x < 0 {
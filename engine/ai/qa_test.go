@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -317,3 +318,87 @@ func contains2(s, substr string) bool {
 	return len(s) >= len(substr) &&
 		(s == substr || len(s) > 0 && (s[:len(substr)] == substr || contains2(s[1:], substr)))
 }
+
+// TestLLMPlayer tests the model-driven player with a fake backend.
+func TestLLMPlayer(t *testing.T) {
+	t.Run("plays planned actions and batches calls", func(t *testing.T) {
+		fake := NewFakeLLM().On("",
+			`{"reasoning": "head right", "actions": ["move_right", "jump", "move_up"], "note": "right is safe"}`)
+
+		config := DefaultLLMPlayerConfig()
+		config.DecideEvery = 4
+		player := NewLLMPlayer(fake, config)
+
+		adapter := NewMockGameAdapter()
+
+		var got []ActionType
+
+		for range 8 {
+			got = append(got, player.DecideAction(adapter.GetState(), adapter.AvailableActions()))
+			adapter.Step()
+		}
+
+		// jump is not available and is skipped; the last action repeats
+		want := []ActionType{ActionMoveRight, ActionMoveUp, ActionMoveUp, ActionMoveUp}
+		for i, a := range want {
+			if got[i] != a {
+				t.Fatalf("actions = %v, want prefix %v", got, want)
+			}
+		}
+
+		if n := len(fake.Requests()); n != 2 {
+			t.Errorf("model calls = %d, want 2 for 8 ticks", n)
+		}
+
+		if pad := player.Scratchpad(); len(pad) != 2 || pad[0] != "right is safe" {
+			t.Errorf("Scratchpad = %v", pad)
+		}
+
+		last := fake.Requests()[1]
+		if !strings.Contains(last.Messages[1].Content, "- right is safe") {
+			t.Error("Scratchpad should be included in later prompts")
+		}
+
+		if last.Schema == nil {
+			t.Error("Requests should carry a JSON schema")
+		}
+	})
+
+	t.Run("falls back when the model fails", func(t *testing.T) {
+		fake := NewFakeLLM().On("", "I refuse to answer in JSON")
+		player := NewLLMPlayer(fake, DefaultLLMPlayerConfig())
+
+		adapter := NewMockGameAdapter()
+
+		action := player.DecideAction(adapter.GetState(), adapter.AvailableActions())
+		if !contains(adapter.AvailableActions(), action) {
+			t.Errorf("Fallback action %q is not available", action)
+		}
+
+		if d := player.TakeDecisions(); len(d) != 1 || d[0].Error == "" {
+			t.Errorf("Decision should record the error, got %+v", d)
+		}
+	})
+
+	t.Run("reasoning appears in the QA report", func(t *testing.T) {
+		fake := NewFakeLLM().On("", `{"reasoning": "explore the left side", "actions": ["move_left"], "note": ""}`)
+		config := DefaultLLMPlayerConfig()
+		config.DecideEvery = 5
+
+		session := NewQASession(NewMockGameAdapter())
+		session.SetPlayer(NewLLMPlayer(fake, config))
+		session.SetConfig(SessionConfig{Runs: 2, MaxTicks: 20})
+
+		report := session.Run()
+
+		for _, run := range report.Runs {
+			if len(run.Decisions) != 4 {
+				t.Errorf("run %d decisions = %d, want 4", run.RunIndex, len(run.Decisions))
+			}
+		}
+
+		if !strings.Contains(report.GenerateMarkdown(), "explore the left side") {
+			t.Error("Markdown report should include agent reasoning")
+		}
+	})
+}
@@ -0,0 +1,883 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/game"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+	"gopkg.in/yaml.v3"
+)
+
+// Quest engine errors.
+var (
+	ErrQuestConfig      = errors.New("ai: invalid quest config")
+	ErrUnknownQuest     = errors.New("ai: unknown quest")
+	ErrQuestUnavailable = errors.New("ai: quest unavailable")
+)
+
+// Built-in objective types. Any other type is a custom objective advanced
+// by Notify with a QuestEvent of that type.
+const (
+	ObjectiveKill    = "kill"    // Target: Tag name or AIMetadata.EntityType
+	ObjectiveCollect = "collect" // Target: item ID held in the player's Inventory
+	ObjectiveReach   = "reach"   // Target: Checkpoint ID
+	ObjectiveTalk    = "talk"    // Target: dialogue node ID or script node title
+)
+
+// ============================================================================
+// Definitions
+// ============================================================================
+
+// QuestDef defines a quest in data. LoadQuests reads lists of them:
+//
+//	# quests.yaml
+//	- id: wolves
+//	  title: Wolf Problem
+//	  requires: [arrival]
+//	  time_limit: 300
+//	  fail_on: [{type: kill, target: shepherd}]
+//	  objectives:
+//	    - {type: kill, target: wolf, count: 5}
+//	    - {type: collect, target: pelt, count: 3, consume: true}
+//	    - {type: talk, target: shepherd_thanks}
+//	  rewards: {currency: {gold: 50}, xp: 100, items: [{id: cloak}]}
+type QuestDef struct {
+	ID          string         `json:"id"                   yaml:"id"`
+	Title       string         `json:"title,omitempty"      yaml:"title,omitempty"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Requires    []string       `json:"requires,omitempty"   yaml:"requires,omitempty"`   // Quests that must be completed first
+	MinLevel    int            `json:"min_level,omitempty"  yaml:"min_level,omitempty"`  // Player level needed to start
+	AutoStart   bool           `json:"auto_start,omitempty" yaml:"auto_start,omitempty"` // Start as soon as available
+	Ordered     bool           `json:"ordered,omitempty"    yaml:"ordered,omitempty"`    // Objectives advance one at a time
+	TimeLimit   float64        `json:"time_limit,omitempty" yaml:"time_limit,omitempty"` // Seconds; 0 is untimed
+	FailOn      []QuestTrigger `json:"fail_on,omitempty"    yaml:"fail_on,omitempty"`    // Events that fail the quest
+	Objectives  []ObjectiveDef `json:"objectives"           yaml:"objectives"`
+	Rewards     QuestRewards   `json:"rewards,omitempty"    yaml:"rewards,omitempty"`
+}
+
+// ObjectiveDef is one objective of a quest.
+type ObjectiveDef struct {
+	Type        string `json:"type"                  yaml:"type"`
+	Target      string `json:"target,omitempty"      yaml:"target,omitempty"` // Empty matches any
+	Count       int    `json:"count,omitempty"       yaml:"count,omitempty"`  // Default 1
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Optional    bool   `json:"optional,omitempty"    yaml:"optional,omitempty"` // Not needed to complete
+	Consume     bool   `json:"consume,omitempty"     yaml:"consume,omitempty"`  // Collect: take the items on completion
+}
+
+// QuestTrigger matches events.
+type QuestTrigger struct {
+	Type   string `json:"type"             yaml:"type"`
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// QuestRewards are granted when a quest completes.
+type QuestRewards struct {
+	Currency map[components.CurrencyType]int64 `json:"currency,omitempty" yaml:"currency,omitempty"`
+	XP       int64                             `json:"xp,omitempty"       yaml:"xp,omitempty"`
+	Items    []QuestItem                       `json:"items,omitempty"    yaml:"items,omitempty"`
+}
+
+// QuestItem is an item reward.
+type QuestItem struct {
+	ID    string `json:"id"              yaml:"id"`
+	Name  string `json:"name,omitempty"  yaml:"name,omitempty"`
+	Count int    `json:"count,omitempty" yaml:"count,omitempty"` // Default 1
+}
+
+// list describes rewards for Quest.Rewards.
+func (r QuestRewards) list() []string {
+	var out []string
+
+	for _, c := range slices.Sorted(maps.Keys(r.Currency)) {
+		out = append(out, fmt.Sprintf("%d %s", r.Currency[c], c))
+	}
+
+	if r.XP > 0 {
+		out = append(out, fmt.Sprintf("%d xp", r.XP))
+	}
+
+	for _, item := range r.Items {
+		out = append(out, fmt.Sprintf("%dx %s", max(item.Count, 1), item.ID))
+	}
+
+	return out
+}
+
+// LoadQuests reads quest definitions from JSON or YAML files, each holding
+// a list of QuestDef.
+func LoadQuests(paths ...string) ([]QuestDef, error) {
+	var defs []QuestDef
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var part []QuestDef
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &part)
+		default:
+			err = json.Unmarshal(data, &part)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		defs = append(defs, part...)
+	}
+
+	return defs, nil
+}
+
+// ============================================================================
+// Engine
+// ============================================================================
+
+// QuestEvent is something that happened in the game. Targets name the
+// subject, e.g. the tag and entity type of a killed entity.
+type QuestEvent struct {
+	Type    string
+	Targets []string
+	Count   int // Default 1
+}
+
+// NewQuestEvent creates an event.
+func NewQuestEvent(typ string, targets ...string) QuestEvent {
+	return QuestEvent{Type: typ, Targets: targets, Count: 1}
+}
+
+func (ev QuestEvent) matches(typ, target string) bool {
+	return ev.Type == typ && (target == "" || slices.Contains(ev.Targets, target))
+}
+
+// QuestChange reports a status or objective change. Objective is -1 for
+// status changes.
+type QuestChange struct {
+	Quest     string
+	Status    QuestStatus
+	Objective int
+	Progress  int
+	Reason    string
+}
+
+// QuestProgress is the saved state of one quest.
+type QuestProgress struct {
+	Status   QuestStatus `json:"status"`
+	Progress []int       `json:"progress,omitempty"`
+	Elapsed  float64     `json:"elapsed,omitempty"`
+}
+
+type questRun struct {
+	def *QuestDef
+	QuestProgress
+}
+
+// QuestEngine runs quests for one player entity. Objectives advance from
+// events: wire HandleDeath to HealthSystem.SetOnDeath and TrackDialogue or
+// TrackNarrative for talks; collect and reach objectives are polled by
+// Update from the player's Inventory and Checkpoints. Quest status is
+// mirrored into Narrative quests and the "quest.<id>" variable so
+// dialogue can branch on it.
+type QuestEngine struct {
+	World       *ecs.World
+	Player      ecs.Entity
+	Economy     *systems.EconomySystem     // Grants currency rewards
+	Progression *systems.ProgressionSystem // Grants XP and checks MinLevel
+	Narrative   *NarrativeController       // Optional mirror
+	Checkpoints *components.CheckpointManager
+	OnChange    func(QuestChange)
+
+	runs  map[string]*questRun
+	order []string
+
+	inv  *ecs.Map[components.Inventory]
+	tags *ecs.Map[components.Tag]
+	meta *ecs.Map[components.AIMetadata]
+
+	checkpoint string // Last seen active checkpoint
+	activated  float64
+}
+
+// NewQuestEngine creates a quest engine for player.
+func NewQuestEngine(world *ecs.World, player ecs.Entity) *QuestEngine {
+	return &QuestEngine{
+		World:  world,
+		Player: player,
+		runs:   map[string]*questRun{},
+		inv:    ecs.NewMap[components.Inventory](world),
+		tags:   ecs.NewMap[components.Tag](world),
+		meta:   ecs.NewMap[components.AIMetadata](world),
+	}
+}
+
+// AddQuests registers definitions. It rejects duplicate IDs, empty
+// objectives, unknown prerequisites and prerequisite cycles.
+func (e *QuestEngine) AddQuests(defs ...QuestDef) error {
+	added := map[string]*QuestDef{}
+
+	for i := range defs {
+		def := defs[i]
+
+		switch {
+		case def.ID == "":
+			return fmt.Errorf("%w: quest %d has no id", ErrQuestConfig, i)
+		case e.runs[def.ID] != nil || added[def.ID] != nil:
+			return fmt.Errorf("%w: duplicate quest %q", ErrQuestConfig, def.ID)
+		case len(def.Objectives) == 0:
+			return fmt.Errorf("%w: quest %q has no objectives", ErrQuestConfig, def.ID)
+		}
+
+		def.Objectives = slices.Clone(def.Objectives)
+		for j := range def.Objectives {
+			if def.Objectives[j].Type == "" {
+				return fmt.Errorf("%w: quest %q objective %d has no type", ErrQuestConfig, def.ID, j)
+			}
+
+			def.Objectives[j].Count = max(def.Objectives[j].Count, 1)
+		}
+
+		added[def.ID] = &def
+	}
+
+	for _, def := range added {
+		for _, req := range def.Requires {
+			if e.runs[req] == nil && added[req] == nil {
+				return fmt.Errorf("%w: quest %q requires unknown quest %q", ErrQuestConfig, def.ID, req)
+			}
+		}
+	}
+
+	if cycle := questCycle(added); cycle != nil {
+		return fmt.Errorf("%w: prerequisite cycle %s", ErrQuestConfig, strings.Join(cycle, " -> "))
+	}
+
+	for i := range defs {
+		def := added[defs[i].ID]
+		run := &questRun{def: def, QuestProgress: QuestProgress{Progress: make([]int, len(def.Objectives))}}
+		e.runs[def.ID] = run
+		e.order = append(e.order, def.ID)
+
+		e.sync(run)
+	}
+
+	e.autoStart()
+
+	return nil
+}
+
+// questCycle finds a prerequisite cycle among new quests; existing quests
+// cannot depend on them, so they cannot close one.
+func questCycle(defs map[string]*QuestDef) []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := map[string]int{}
+
+	var visit func(id string, path []string) []string
+
+	visit = func(id string, path []string) []string {
+		switch state[id] {
+		case visiting:
+			return append(path[slices.Index(path, id):], id)
+		case done:
+			return nil
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+
+		if def := defs[id]; def != nil {
+			for _, req := range def.Requires {
+				if cycle := visit(req, path); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		state[id] = done
+
+		return nil
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(defs)) {
+		if cycle := visit(id, nil); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// Def returns a quest definition.
+func (e *QuestEngine) Def(id string) (QuestDef, bool) {
+	run := e.runs[id]
+	if run == nil {
+		return QuestDef{}, false
+	}
+
+	return *run.def, true
+}
+
+// Status returns a quest's status.
+func (e *QuestEngine) Status(id string) QuestStatus {
+	if run := e.runs[id]; run != nil {
+		return run.Status
+	}
+
+	return QuestNotStarted
+}
+
+// Progress returns the progress of each objective.
+func (e *QuestEngine) Progress(id string) []int {
+	if run := e.runs[id]; run != nil {
+		return slices.Clone(run.Progress)
+	}
+
+	return nil
+}
+
+// Remaining returns the seconds left on an active timed quest, or -1.
+func (e *QuestEngine) Remaining(id string) float64 {
+	run := e.runs[id]
+	if run == nil || run.Status != QuestActive || run.def.TimeLimit <= 0 {
+		return -1
+	}
+
+	return max(run.def.TimeLimit-run.Elapsed, 0)
+}
+
+// Quest returns a snapshot in NarrativeController's Quest form.
+func (e *QuestEngine) Quest(id string) (Quest, bool) {
+	run := e.runs[id]
+	if run == nil {
+		return Quest{}, false
+	}
+
+	q := Quest{
+		ID: id, Title: run.def.Title, Description: run.def.Description,
+		Status: run.Status, Rewards: run.def.Rewards.list(),
+	}
+
+	for i, obj := range run.def.Objectives {
+		q.Objectives = append(q.Objectives, QuestObjective{
+			Description: obj.describe(),
+			Current:     run.Progress[i],
+			Target:      obj.Count,
+			Completed:   run.Progress[i] >= obj.Count,
+		})
+	}
+
+	return q, true
+}
+
+func (o ObjectiveDef) describe() string {
+	if o.Description != "" {
+		return o.Description
+	}
+
+	return strings.TrimSpace(o.Type + " " + strconv.Itoa(o.Count) + " " + o.Target)
+}
+
+// Available lists quests that can be started now.
+func (e *QuestEngine) Available() []string {
+	var out []string
+
+	for _, id := range e.order {
+		if e.CanStart(id) == nil {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
+
+// Active lists active quests.
+func (e *QuestEngine) Active() []string {
+	var out []string
+
+	for _, id := range e.order {
+		if e.runs[id].Status == QuestActive {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
+
+// CanStart explains why a quest cannot be started, or returns nil.
+func (e *QuestEngine) CanStart(id string) error {
+	run := e.runs[id]
+	if run == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownQuest, id)
+	}
+
+	if run.Status != QuestNotStarted {
+		return fmt.Errorf("%w: %s is %s", ErrQuestUnavailable, id, run.Status)
+	}
+
+	for _, req := range run.def.Requires {
+		if e.runs[req].Status != QuestCompleted {
+			return fmt.Errorf("%w: %s requires %s", ErrQuestUnavailable, id, req)
+		}
+	}
+
+	if run.def.MinLevel > 0 {
+		if e.Progression == nil {
+			return fmt.Errorf("%w: %s has min_level but no ProgressionSystem", ErrQuestConfig, id)
+		}
+
+		if level := e.Progression.GetLevel(e.World, e.Player); level < run.def.MinLevel {
+			return fmt.Errorf("%w: %s needs level %d, player is %d", ErrQuestUnavailable, id, run.def.MinLevel, level)
+		}
+	}
+
+	rewards := run.def.Rewards
+
+	switch {
+	case len(rewards.Currency) > 0 && e.Economy == nil:
+		return fmt.Errorf("%w: %s rewards currency but there is no EconomySystem", ErrQuestConfig, id)
+	case rewards.XP > 0 && e.Progression == nil:
+		return fmt.Errorf("%w: %s rewards XP but there is no ProgressionSystem", ErrQuestConfig, id)
+	case len(rewards.Items) > 0 && !e.inv.Has(e.Player):
+		return fmt.Errorf("%w: %s rewards items but the player has no Inventory", ErrQuestConfig, id)
+	}
+
+	for _, obj := range run.def.Objectives {
+		if obj.Type == ObjectiveCollect && !e.inv.Has(e.Player) {
+			return fmt.Errorf("%w: %s collects items but the player has no Inventory", ErrQuestConfig, id)
+		}
+	}
+
+	return nil
+}
+
+// Start activates a quest.
+func (e *QuestEngine) Start(id string) error {
+	if err := e.CanStart(id); err != nil {
+		return err
+	}
+
+	run := e.runs[id]
+	run.Status, run.Elapsed = QuestActive, 0
+	clear(run.Progress)
+
+	e.changed(run, -1, "started")
+	e.poll(run)
+	e.check(run)
+
+	return nil
+}
+
+// Fail fails an active quest.
+func (e *QuestEngine) Fail(id, reason string) error {
+	run := e.runs[id]
+	if run == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownQuest, id)
+	}
+
+	if run.Status != QuestActive {
+		return fmt.Errorf("%w: %s is %s", ErrQuestUnavailable, id, run.Status)
+	}
+
+	run.Status = QuestFailed
+	e.changed(run, -1, reason)
+
+	return nil
+}
+
+// Reset returns a finished or active quest to not started, e.g. to retry
+// a failed quest.
+func (e *QuestEngine) Reset(id string) error {
+	run := e.runs[id]
+	if run == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownQuest, id)
+	}
+
+	run.Status, run.Elapsed = QuestNotStarted, 0
+	clear(run.Progress)
+	e.changed(run, -1, "reset")
+	e.autoStart()
+
+	return nil
+}
+
+// Notify advances objectives and fail triggers of active quests.
+func (e *QuestEngine) Notify(ev QuestEvent) {
+	count := max(ev.Count, 1)
+
+	for _, id := range e.order {
+		run := e.runs[id]
+		if run.Status != QuestActive {
+			continue
+		}
+
+		if i := slices.IndexFunc(run.def.FailOn, func(t QuestTrigger) bool { return ev.matches(t.Type, t.Target) }); i >= 0 {
+			run.Status = QuestFailed
+			e.changed(run, -1, strings.TrimSpace(ev.Type+" "+run.def.FailOn[i].Target))
+
+			continue
+		}
+
+		for i, obj := range run.def.Objectives {
+			if obj.Type == ObjectiveCollect || !e.tracking(run, i) || !ev.matches(obj.Type, obj.Target) {
+				continue
+			}
+
+			if run.Progress[i] < obj.Count {
+				run.Progress[i] = min(run.Progress[i]+count, obj.Count)
+				e.changed(run, i, ev.Type)
+			}
+		}
+
+		e.check(run)
+	}
+
+	e.autoStart()
+}
+
+// tracking reports whether objective i can advance; in ordered quests
+// every earlier required objective must be done.
+func (e *QuestEngine) tracking(run *questRun, i int) bool {
+	if !run.def.Ordered {
+		return true
+	}
+
+	for j := range i {
+		if obj := run.def.Objectives[j]; !obj.Optional && run.Progress[j] < obj.Count {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Update advances timers and polls collect and reach objectives.
+func (e *QuestEngine) Update(dt float64) {
+	if cm := e.Checkpoints; cm != nil && cm.ActiveID != "" {
+		if cp := cm.Checkpoints[cm.ActiveID]; cp != nil && (cp.ID != e.checkpoint || cp.Timestamp != e.activated) {
+			e.checkpoint, e.activated = cp.ID, cp.Timestamp
+			e.Notify(NewQuestEvent(ObjectiveReach, cp.ID))
+		}
+	}
+
+	for _, id := range e.order {
+		run := e.runs[id]
+		if run.Status != QuestActive {
+			continue
+		}
+
+		run.Elapsed += dt
+		if run.def.TimeLimit > 0 && run.Elapsed >= run.def.TimeLimit {
+			run.Status = QuestFailed
+			e.changed(run, -1, "timed out")
+
+			continue
+		}
+
+		e.poll(run)
+		e.check(run)
+	}
+
+	e.autoStart()
+}
+
+// poll mirrors collect objectives from the player's Inventory.
+func (e *QuestEngine) poll(run *questRun) {
+	for i, obj := range run.def.Objectives {
+		if obj.Type != ObjectiveCollect || !e.tracking(run, i) || !e.inv.Has(e.Player) {
+			continue
+		}
+
+		if n := min(e.inv.Get(e.Player).GetItemCount(obj.Target), obj.Count); n != run.Progress[i] {
+			run.Progress[i] = n
+			e.changed(run, i, ObjectiveCollect)
+		}
+	}
+}
+
+// check completes a quest whose required objectives are done.
+func (e *QuestEngine) check(run *questRun) {
+	if run.Status != QuestActive {
+		return
+	}
+
+	for i, obj := range run.def.Objectives {
+		if !obj.Optional && run.Progress[i] < obj.Count {
+			return
+		}
+	}
+
+	for i, obj := range run.def.Objectives {
+		if obj.Type == ObjectiveCollect && obj.Consume && run.Progress[i] > 0 {
+			e.inv.Get(e.Player).RemoveItem(obj.Target, run.Progress[i])
+		}
+	}
+
+	run.Status = QuestCompleted
+	e.changed(run, -1, e.grant(run.def.Rewards))
+}
+
+// grant gives rewards and describes any that could not be given.
+func (e *QuestEngine) grant(r QuestRewards) string {
+	var failed []string
+
+	for _, c := range slices.Sorted(maps.Keys(r.Currency)) {
+		if !e.Economy.AddCurrency(e.World, e.Player, c, r.Currency[c]) {
+			failed = append(failed, string(c))
+		}
+	}
+
+	if r.XP > 0 {
+		e.Progression.AddExperience(e.World, e.Player, r.XP)
+	}
+
+	for _, reward := range r.Items {
+		name := reward.Name
+		if name == "" {
+			name = reward.ID
+		}
+
+		item := components.NewItem(reward.ID, name, components.RarityCommon)
+		item.Count = max(reward.Count, 1)
+		item.StackSize = max(item.Count, item.StackSize)
+
+		if !e.inv.Get(e.Player).AddItem(item) {
+			failed = append(failed, reward.ID)
+		}
+	}
+
+	if len(failed) > 0 {
+		return "completed; not granted: " + strings.Join(failed, ", ")
+	}
+
+	return "completed"
+}
+
+// autoStart starts available auto-start quests.
+func (e *QuestEngine) autoStart() {
+	for _, id := range e.order {
+		if e.runs[id].def.AutoStart && e.CanStart(id) == nil {
+			_ = e.Start(id)
+		}
+	}
+}
+
+func (e *QuestEngine) changed(run *questRun, objective int, reason string) {
+	e.sync(run)
+
+	if e.OnChange == nil {
+		return
+	}
+
+	change := QuestChange{Quest: run.def.ID, Status: run.Status, Objective: objective, Reason: reason}
+	if objective >= 0 {
+		change.Progress = run.Progress[objective]
+	}
+
+	e.OnChange(change)
+}
+
+// sync mirrors a quest into the NarrativeController.
+func (e *QuestEngine) sync(run *questRun) {
+	if e.Narrative == nil {
+		return
+	}
+
+	snapshot, _ := e.Quest(run.def.ID)
+	if q := e.Narrative.quests[run.def.ID]; q != nil {
+		q.Status, q.Objectives = snapshot.Status, snapshot.Objectives
+	} else {
+		e.Narrative.AddQuest(snapshot)
+	}
+
+	e.Narrative.SetVariable("quest."+run.def.ID, run.Status.String())
+}
+
+// ============================================================================
+// Event Sources
+// ============================================================================
+
+// HandleDeath turns a HealthSystem death into a kill event, named by the
+// entity's Tag and AIMetadata type. Kills by entities other than the
+// player are ignored unless the killer is unknown.
+//
+//	health.SetOnDeath(quests.HandleDeath)
+func (e *QuestEngine) HandleDeath(death systems.DeathEvent) {
+	if !death.Killer.IsZero() && death.Killer != e.Player {
+		return
+	}
+
+	e.Notify(NewQuestEvent(ObjectiveKill, e.names(death.Entity)...))
+}
+
+func (e *QuestEngine) names(entity ecs.Entity) []string {
+	var names []string
+
+	if !e.World.Alive(entity) {
+		return nil
+	}
+
+	if e.tags.Has(entity) {
+		names = append(names, e.tags.Get(entity).Name)
+	}
+
+	if e.meta.Has(entity) {
+		names = append(names, e.meta.Get(entity).EntityType)
+	}
+
+	return names
+}
+
+// TrackNarrative reports dialogues started on n as talk events, keeping
+// any existing OnDialogueChange callback.
+func (e *QuestEngine) TrackNarrative(n *NarrativeController) {
+	prev := n.onDialogChange
+	n.OnDialogueChange(func(node *DialogueNode) {
+		if prev != nil {
+			prev(node)
+		}
+
+		e.Notify(NewQuestEvent(ObjectiveTalk, node.ID))
+	})
+}
+
+// TrackDialogue reports script nodes entered by runner as talk events and
+// adds quest commands:
+//
+//	<<start_quest id>>  <<fail_quest id>>  <<quest_event type target [count]>>
+func (e *QuestEngine) TrackDialogue(runner *DialogueRunner) {
+	prev := runner.OnNode
+	runner.OnNode = func(node string) {
+		if prev != nil {
+			prev(node)
+		}
+
+		e.Notify(NewQuestEvent(ObjectiveTalk, node))
+	}
+
+	runner.Commands["start_quest"] = func(args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: <<start_quest id>>")
+		}
+
+		return e.Start(args[0])
+	}
+	runner.Commands["fail_quest"] = func(args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: <<fail_quest id>>")
+		}
+
+		return e.Fail(args[0], "failed by dialogue")
+	}
+	runner.Commands["quest_event"] = func(args []string) error {
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("usage: <<quest_event type target [count]>>")
+		}
+
+		ev := NewQuestEvent(args[0], args[1])
+		if len(args) == 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("quest_event count: %w", err)
+			}
+
+			ev.Count = n
+		}
+
+		e.Notify(ev)
+
+		return nil
+	}
+}
+
+// ============================================================================
+// Save and Load
+// ============================================================================
+
+// questSaveKey is the game.SaveData key used by SaveTo and LoadFrom.
+const questSaveKey = "quests"
+
+// Save returns the state of every quest.
+func (e *QuestEngine) Save() map[string]QuestProgress {
+	out := make(map[string]QuestProgress, len(e.runs))
+
+	for id, run := range e.runs {
+		p := run.QuestProgress
+		p.Progress = slices.Clone(p.Progress)
+		out[id] = p
+	}
+
+	return out
+}
+
+// Load restores saved quest state. Rewards are not granted again. Quests
+// missing from the save are reset; saved quests that no longer exist, or
+// whose objectives changed, are an error.
+func (e *QuestEngine) Load(saved map[string]QuestProgress) error {
+	for id, p := range saved {
+		run := e.runs[id]
+		if run == nil {
+			return fmt.Errorf("%w: saved quest %q", ErrUnknownQuest, id)
+		}
+
+		if len(p.Progress) != 0 && len(p.Progress) != len(run.def.Objectives) {
+			return fmt.Errorf("%w: saved quest %q has %d objectives, definition has %d",
+				ErrQuestConfig, id, len(p.Progress), len(run.def.Objectives))
+		}
+	}
+
+	for _, id := range e.order {
+		run := e.runs[id]
+		p := saved[id]
+
+		run.Status, run.Elapsed = p.Status, p.Elapsed
+		clear(run.Progress)
+		copy(run.Progress, p.Progress)
+		e.sync(run)
+	}
+
+	return nil
+}
+
+// SaveTo stores quest state in a save file.
+func (e *QuestEngine) SaveTo(save *game.SaveData) {
+	save.Set(questSaveKey, e.Save())
+}
+
+// LoadFrom restores quest state stored by SaveTo, either in memory or
+// after the save went through JSON on disk.
+func (e *QuestEngine) LoadFrom(save *game.SaveData) error {
+	v, ok := save.Get(questSaveKey)
+	if !ok {
+		return fmt.Errorf("%w: save has no %q", ErrQuestConfig, questSaveKey)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var saved map[string]QuestProgress
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%w: %w", ErrQuestConfig, err)
+	}
+
+	return e.Load(saved)
+}
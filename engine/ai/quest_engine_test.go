@@ -0,0 +1,335 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/game"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+)
+
+const questYAML = `
+- id: arrival
+  title: Arrival
+  auto_start: true
+  objectives:
+    - {type: reach, target: village}
+- id: wolves
+  title: Wolf Problem
+  requires: [arrival]
+  fail_on: [{type: kill, target: shepherd}]
+  objectives:
+    - {type: kill, target: wolf, count: 3}
+    - {type: collect, target: pelt, count: 2, consume: true}
+    - {type: talk, target: Shepherd, optional: true}
+  rewards: {currency: {gold: 50}, xp: 100, items: [{id: cloak, name: Wool Cloak}]}
+- id: courier
+  requires: [arrival]
+  min_level: 2
+  ordered: true
+  time_limit: 10
+  objectives:
+    - {type: deliver, target: letter}
+    - {type: reach, target: castle}
+`
+
+type questWorld struct {
+	world    ecs.World
+	player   ecs.Entity
+	engine   *QuestEngine
+	health   *systems.HealthSystem
+	changes  []string
+	villager func(tag string) ecs.Entity
+}
+
+func newQuestWorld(t *testing.T) *questWorld {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "quests.yaml")
+	if err := os.WriteFile(path, []byte(questYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := LoadQuests(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &questWorld{world: ecs.NewWorld()}
+	w := &q.world
+
+	currency, bag := components.NewCurrency(), components.NewInventory(8)
+	exp, level := components.NewExperience(100, 1), components.NewLevel(10)
+	q.player = ecs.NewMap4[components.Currency, components.Inventory, components.Experience, components.Level](w).
+		NewEntity(&currency, &bag, &exp, &level)
+
+	mobs := ecs.NewMap2[components.Health, components.Tag](w)
+	q.villager = func(tag string) ecs.Entity {
+		return mobs.NewEntity(&components.Health{Current: 1, Max: 1}, &components.Tag{Name: tag})
+	}
+
+	q.health = systems.NewHealthSystem(w)
+	q.engine = NewQuestEngine(w, q.player)
+	q.engine.Economy = systems.NewEconomySystem(w)
+	q.engine.Progression = systems.NewProgressionSystem(w)
+	q.engine.Narrative = NewNarrativeController()
+	cm := components.NewCheckpointManager()
+	q.engine.Checkpoints = &cm
+	q.engine.OnChange = func(c QuestChange) {
+		q.changes = append(q.changes, c.Quest+":"+c.Status.String()+":"+c.Reason)
+	}
+	q.health.SetOnDeath(q.engine.HandleDeath)
+
+	if err := q.engine.AddQuests(defs...); err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+func (q *questWorld) kill(tag string, killer ecs.Entity) {
+	q.health.QueueDamage(q.villager(tag), killer, 10, components.DamagePhysical, false)
+	q.health.Update(&q.world)
+}
+
+func (q *questWorld) give(id string, n int) {
+	item := components.NewItem(id, id, components.RarityCommon)
+	item.Count, item.StackSize = n, 99
+	ecs.NewMap[components.Inventory](&q.world).Get(q.player).AddItem(item)
+}
+
+func TestQuestEngine(t *testing.T) {
+	q := newQuestWorld(t)
+	e := q.engine
+
+	if e.Status("arrival") != QuestActive || strings.Join(e.Available(), ",") != "" {
+		t.Fatalf("arrival %s, available %v", e.Status("arrival"), e.Available())
+	}
+
+	if err := e.Start("wolves"); !errors.Is(err, ErrQuestUnavailable) {
+		t.Errorf("start before prerequisite = %v", err)
+	}
+
+	e.Checkpoints.Add(components.NewCheckpoint("village", 10, 10))
+	e.Checkpoints.SetActive("village", 3)
+	e.Update(0.1)
+
+	if e.Status("arrival") != QuestCompleted || strings.Join(e.Available(), ",") != "wolves" {
+		t.Fatalf("after reaching village: %s, available %v", e.Status("arrival"), e.Available())
+	}
+
+	if err := e.CanStart("courier"); err == nil || !strings.Contains(err.Error(), "needs level 2") {
+		t.Errorf("courier = %v", err)
+	}
+
+	if err := e.Start("wolves"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the player's kills of wolves count.
+	q.kill("wolf", q.player)
+	q.kill("wolf", q.villager("hunter"))
+	q.kill("boar", q.player)
+	q.kill("wolf", ecs.Entity{})
+
+	q.give("pelt", 3)
+	e.Update(0.1)
+
+	if got := e.Progress("wolves"); got[0] != 2 || got[1] != 2 || got[2] != 0 {
+		t.Fatalf("progress = %v", got)
+	}
+
+	if narrative := e.Narrative.quests["wolves"]; narrative.Status != QuestActive || narrative.Objectives[0].Current != 2 ||
+		e.Narrative.GetVariable("quest.wolves") != "active" {
+		t.Errorf("narrative mirror = %+v", narrative)
+	}
+
+	q.kill("wolf", q.player)
+
+	if e.Status("wolves") != QuestCompleted {
+		t.Fatalf("wolves = %s, progress %v", e.Status("wolves"), e.Progress("wolves"))
+	}
+
+	inv := ecs.NewMap[components.Inventory](&q.world).Get(q.player)
+	if inv.GetItemCount("pelt") != 1 || inv.GetItemCount("cloak") != 1 || inv.GetItem("cloak").Name != "Wool Cloak" {
+		t.Errorf("inventory after turn-in: pelt %d, cloak %d", inv.GetItemCount("pelt"), inv.GetItemCount("cloak"))
+	}
+
+	if gold := e.Economy.GetCurrency(&q.world, q.player, components.CurrencyGold); gold != 50 {
+		t.Errorf("gold = %d", gold)
+	}
+
+	if level := e.Progression.GetLevel(&q.world, q.player); level != 2 || e.CanStart("courier") != nil {
+		t.Errorf("level = %d, courier %v", level, e.CanStart("courier"))
+	}
+
+	if last := q.changes[len(q.changes)-1]; last != "wolves:completed:completed" {
+		t.Errorf("last change = %s", last)
+	}
+}
+
+func TestQuestFailures(t *testing.T) {
+	q := newQuestWorld(t)
+	e := q.engine
+
+	e.Notify(NewQuestEvent(ObjectiveReach, "village"))
+	_ = e.Start("wolves")
+	q.kill("shepherd", q.player)
+
+	if e.Status("wolves") != QuestFailed || !strings.HasSuffix(q.changes[len(q.changes)-1], "kill shepherd") {
+		t.Fatalf("wolves = %s, changes %v", e.Status("wolves"), q.changes)
+	}
+
+	if err := e.Reset("wolves"); err != nil || e.Status("wolves") != QuestNotStarted {
+		t.Errorf("reset = %v, %s", err, e.Status("wolves"))
+	}
+
+	e.Progression.AddExperience(&q.world, q.player, 100)
+
+	if err := e.Start("courier"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ordered: reaching the castle first does not count.
+	e.Notify(NewQuestEvent(ObjectiveReach, "castle"))
+	e.Notify(NewQuestEvent("deliver", "letter"))
+
+	if got := e.Progress("courier"); got[0] != 1 || got[1] != 0 {
+		t.Errorf("ordered progress = %v", got)
+	}
+
+	e.Update(6)
+
+	if r := e.Remaining("courier"); r != 4 {
+		t.Errorf("remaining = %v", r)
+	}
+
+	e.Update(4)
+
+	if e.Status("courier") != QuestFailed || e.Remaining("courier") != -1 {
+		t.Errorf("courier = %s after time limit", e.Status("courier"))
+	}
+}
+
+func TestQuestDialogue(t *testing.T) {
+	q := newQuestWorld(t)
+	e := q.engine
+	e.Notify(NewQuestEvent(ObjectiveReach, "village"))
+
+	script, err := ParseDialogue(`
+title: Shepherd
+---
+<<if $quest.wolves == "not_started">>
+    Shepherd: Wolves took my flock.
+    <<start_quest wolves>>
+<<elseif $quest.wolves == "active">>
+    <<quest_event kill wolf 3>>
+    Shepherd: Thank you!
+<<endif>>
+<<stop>>
+===
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewDialogueRunner(script, e.Narrative)
+	e.TrackDialogue(runner)
+
+	for range 2 {
+		_ = runner.Start("Shepherd")
+		for ev, err := runner.Next(); ev.Kind != DialogueEventEnd; ev, err = runner.Next() {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if got := e.Progress("wolves"); got[0] != 3 || got[2] != 1 {
+		t.Errorf("progress after dialogue = %v", got)
+	}
+
+	narrative := NewNarrativeController()
+	narrative.AddDialogue(DialogueNode{ID: "Shepherd", Text: "Baa."})
+	e.TrackNarrative(narrative)
+	_ = e.Reset("wolves")
+	_ = e.Start("wolves")
+	narrative.StartDialogue("Shepherd")
+
+	if e.Progress("wolves")[2] != 1 {
+		t.Errorf("narrative talk not tracked: %v", e.Progress("wolves"))
+	}
+}
+
+func TestQuestSaveLoad(t *testing.T) {
+	q := newQuestWorld(t)
+	e := q.engine
+	e.Notify(NewQuestEvent(ObjectiveReach, "village"))
+	_ = e.Start("wolves")
+	q.kill("wolf", q.player)
+
+	save := game.NewSaveData("slot")
+	e.SaveTo(save)
+
+	data, err := json.Marshal(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded game.SaveData
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := newQuestWorld(t).engine
+	if err := fresh.LoadFrom(&loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if fresh.Status("arrival") != QuestCompleted || fresh.Status("wolves") != QuestActive || fresh.Progress("wolves")[0] != 1 {
+		t.Errorf("loaded: arrival %s, wolves %s %v", fresh.Status("arrival"), fresh.Status("wolves"), fresh.Progress("wolves"))
+	}
+
+	if fresh.Narrative.GetVariable("quest.wolves") != "active" {
+		t.Error("load did not sync the narrative")
+	}
+
+	bad := map[string]QuestProgress{"wolves": {Status: QuestActive, Progress: []int{1}}}
+	if err := fresh.Load(bad); !errors.Is(err, ErrQuestConfig) {
+		t.Errorf("mismatched objectives = %v", err)
+	}
+
+	if err := fresh.Load(map[string]QuestProgress{"gone": {}}); !errors.Is(err, ErrUnknownQuest) {
+		t.Errorf("unknown quest = %v", err)
+	}
+}
+
+func TestQuestGraphErrors(t *testing.T) {
+	obj := []ObjectiveDef{{Type: ObjectiveTalk}}
+
+	for name, defs := range map[string][]QuestDef{
+		"cycle":     {{ID: "a", Requires: []string{"b"}, Objectives: obj}, {ID: "b", Requires: []string{"a"}, Objectives: obj}},
+		"unknown":   {{ID: "a", Requires: []string{"z"}, Objectives: obj}},
+		"duplicate": {{ID: "a", Objectives: obj}, {ID: "a", Objectives: obj}},
+		"empty":     {{ID: "a"}},
+	} {
+		world := ecs.NewWorld()
+		if err := NewQuestEngine(&world, ecs.Entity{}).AddQuests(defs...); !errors.Is(err, ErrQuestConfig) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	world := ecs.NewWorld()
+	e := NewQuestEngine(&world, ecs.Entity{})
+	_ = e.AddQuests(QuestDef{ID: "paid", Objectives: obj, Rewards: QuestRewards{XP: 10}})
+
+	if err := e.Start("paid"); !errors.Is(err, ErrQuestConfig) {
+		t.Errorf("XP reward without ProgressionSystem = %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Learner is a Player that improves by playing an Env. Trained learners
+// can be handed to QASession.SetPlayer to reach states random play won't.
+type Learner interface {
+	Player
+
+	// Train plays episodes in env and updates the learner.
+	Train(env *Env, episodes int) (TrainStats, error)
+
+	// Save writes a checkpoint; Load restores one.
+	Save(path string) error
+	Load(path string) error
+}
+
+// TrainStats records the outcome of each training episode.
+type TrainStats struct {
+	Rewards []float64 `json:"rewards"`
+	Steps   []int     `json:"steps"`
+	Scores  []int     `json:"scores"`
+}
+
+func (s *TrainStats) add(reward float64, steps int, score int) {
+	s.Rewards = append(s.Rewards, reward)
+	s.Steps = append(s.Steps, steps)
+	s.Scores = append(s.Scores, score)
+}
+
+// MeanReward averages the reward of the last n episodes (all if n <= 0).
+func (s TrainStats) MeanReward(n int) float64 {
+	return meanLast(s.Rewards, n)
+}
+
+// MeanScore averages the final score of the last n episodes (all if n <= 0).
+func (s TrainStats) MeanScore(n int) float64 {
+	scores := make([]float64, len(s.Scores))
+	for i, v := range s.Scores {
+		scores[i] = float64(v)
+	}
+
+	return meanLast(scores, n)
+}
+
+func meanLast(values []float64, n int) float64 {
+	if n <= 0 || n > len(values) {
+		n = len(values)
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values[len(values)-n:] {
+		sum += v
+	}
+
+	return sum / float64(n)
+}
+
+// ============================================================================
+// Checkpoints
+// ============================================================================
+
+// Checkpoint kinds.
+const (
+	CheckpointQTable = "qtable"
+	CheckpointPolicy = "policy"
+)
+
+// Checkpoint is the saved form of a learner. Discretizers and encoders are
+// code and are not saved; a checkpoint must be loaded into a learner built
+// with the same ones.
+type Checkpoint struct {
+	Kind     string       `json:"kind"`
+	Actions  []ActionType `json:"actions"`
+	Episodes int          `json:"episodes"`
+
+	// Tabular
+	Table   map[string][]float64 `json:"table,omitempty"`
+	Epsilon float64              `json:"epsilon,omitempty"`
+
+	// Policy gradient
+	Algorithm  PolicyAlgorithm `json:"algorithm,omitempty"`
+	Policy     *MLP            `json:"policy,omitempty"`
+	Value      *MLP            `json:"value,omitempty"`
+	Normalizer *Normalizer     `json:"normalizer,omitempty"`
+}
+
+// Save writes the checkpoint as JSON.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadCheckpoint reads a checkpoint file.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+func loadCheckpointKind(path, kind string) (*Checkpoint, error) {
+	c, err := LoadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Kind != kind {
+		return nil, fmt.Errorf("checkpoint %s: kind %q, want %q", path, c.Kind, kind)
+	}
+
+	return c, nil
+}
+
+// ============================================================================
+// Discretizers
+// ============================================================================
+
+// Discretizer maps a game state to a key for tabular learning. Similar
+// states should share a key.
+type Discretizer interface {
+	Key(state GameState) string
+}
+
+// DiscretizerFunc adapts a function to Discretizer.
+type DiscretizerFunc func(state GameState) string
+
+// Key implements Discretizer.
+func (f DiscretizerFunc) Key(state GameState) string {
+	return f(state)
+}
+
+// Bin splits [Low, High] into Count equal buckets. Values outside the
+// range fall into the first or last bucket.
+type Bin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+// Index returns the bucket of v.
+func (b Bin) Index(v float64) int {
+	if b.Count <= 1 || b.High <= b.Low {
+		return 0
+	}
+
+	i := int(math.Floor((v - b.Low) / (b.High - b.Low) * float64(b.Count)))
+
+	return max(0, min(b.Count-1, i))
+}
+
+// BinDiscretizer bins each feature of an encoder's vector; the key joins
+// the bucket indices. Features without a Bin are ignored.
+type BinDiscretizer struct {
+	Encoder ObservationEncoder
+	Bins    []Bin
+}
+
+// NewBinDiscretizer bins the named features (see FeatureEncoder).
+func NewBinDiscretizer(keys []string, bins []Bin) *BinDiscretizer {
+	return &BinDiscretizer{Encoder: NewFeatureEncoder(keys...), Bins: bins}
+}
+
+// Key implements Discretizer.
+func (d *BinDiscretizer) Key(state GameState) string {
+	v := d.Encoder.Encode(state).Vector
+	parts := make([]string, 0, len(d.Bins))
+
+	for i, bin := range d.Bins {
+		if i >= len(v) {
+			break
+		}
+
+		parts = append(parts, strconv.Itoa(bin.Index(v[i])))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// ============================================================================
+// Tabular Q-learning
+// ============================================================================
+
+// QConfig configures a QLearner.
+type QConfig struct {
+	Alpha        float64 // Learning rate
+	Gamma        float64 // Discount
+	Epsilon      float64 // Initial exploration rate
+	EpsilonMin   float64
+	EpsilonDecay float64 // Multiplies Epsilon after each episode
+	Seed         int64   // Seeds exploration; episode i resets the env with Seed+i
+}
+
+// DefaultQConfig returns sensible defaults.
+func DefaultQConfig() QConfig {
+	return QConfig{
+		Alpha:        0.1,
+		Gamma:        0.99,
+		Epsilon:      1,
+		EpsilonMin:   0.01,
+		EpsilonDecay: 0.995,
+	}
+}
+
+// QLearner is a tabular Q-learning player over discretized states.
+type QLearner struct {
+	Config      QConfig
+	Discretizer Discretizer
+	Actions     []ActionType
+	Table       map[string][]float64
+
+	episodes int
+	rng      *rand.Rand
+}
+
+// NewQLearner creates a Q-learner.
+func NewQLearner(discretizer Discretizer, config QConfig) *QLearner {
+	return &QLearner{
+		Config:      config,
+		Discretizer: discretizer,
+		Table:       make(map[string][]float64),
+		rng:         rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Train runs episodes of epsilon-greedy Q-learning.
+func (q *QLearner) Train(env *Env, episodes int) (TrainStats, error) {
+	var stats TrainStats
+
+	for range episodes {
+		if _, err := env.Reset(q.Config.Seed + int64(q.episodes)); err != nil {
+			return stats, err
+		}
+
+		if q.Actions == nil {
+			q.Actions = env.Actions()
+		} else if !slices.Equal(q.Actions, env.Actions()) {
+			return stats, errors.New("qlearning: env actions differ from the learner's")
+		}
+
+		key := q.Discretizer.Key(env.State())
+		total, steps := 0.0, 0
+
+		for !env.Done() {
+			a := q.explore(key, env.ActionMask())
+
+			r, err := env.StepIndex(a)
+			if err != nil {
+				return stats, err
+			}
+
+			next := q.Discretizer.Key(env.State())
+
+			target := r.Reward
+			if !r.Done {
+				target += q.Config.Gamma * maxOf(q.values(next))
+			}
+
+			values := q.values(key)
+			values[a] += q.Config.Alpha * (target - values[a])
+
+			key = next
+			total += r.Reward
+			steps++
+		}
+
+		q.episodes++
+		q.Config.Epsilon = max(q.Config.EpsilonMin, q.Config.Epsilon*q.Config.EpsilonDecay)
+		stats.add(total, steps, env.State().Score)
+	}
+
+	return stats, nil
+}
+
+// DecideAction implements Player by picking the best available action.
+func (q *QLearner) DecideAction(state GameState, available []ActionType) ActionType {
+	values, ok := q.Table[q.Discretizer.Key(state)]
+	if !ok || len(available) == 0 {
+		if len(available) == 0 {
+			return ActionNone
+		}
+
+		return available[q.randIntn(len(available))]
+	}
+
+	best, bestValue := ActionNone, math.Inf(-1)
+
+	for i, a := range q.Actions {
+		if contains(available, a) && values[i] > bestValue {
+			best, bestValue = a, values[i]
+		}
+	}
+
+	if bestValue == math.Inf(-1) {
+		return available[0]
+	}
+
+	return best
+}
+
+// Save implements Learner.
+func (q *QLearner) Save(path string) error {
+	c := &Checkpoint{
+		Kind:     CheckpointQTable,
+		Actions:  q.Actions,
+		Episodes: q.episodes,
+		Table:    q.Table,
+		Epsilon:  q.Config.Epsilon,
+	}
+
+	return c.Save(path)
+}
+
+// Load implements Learner.
+func (q *QLearner) Load(path string) error {
+	c, err := loadCheckpointKind(path, CheckpointQTable)
+	if err != nil {
+		return err
+	}
+
+	q.Actions = c.Actions
+	q.Table = c.Table
+	q.episodes = c.Episodes
+	q.Config.Epsilon = c.Epsilon
+
+	if q.Table == nil {
+		q.Table = make(map[string][]float64)
+	}
+
+	return nil
+}
+
+// explore picks an epsilon-greedy action index among the masked ones.
+func (q *QLearner) explore(key string, mask []bool) int {
+	valid := make([]int, 0, len(mask))
+
+	for i, ok := range mask {
+		if ok {
+			valid = append(valid, i)
+		}
+	}
+
+	if len(valid) == 0 {
+		return 0
+	}
+
+	if q.random().Float64() < q.Config.Epsilon {
+		return valid[q.random().Intn(len(valid))]
+	}
+
+	values := q.values(key)
+	best := valid[0]
+
+	for _, i := range valid[1:] {
+		if values[i] > values[best] {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func (q *QLearner) values(key string) []float64 {
+	if q.Table == nil {
+		q.Table = make(map[string][]float64)
+	}
+
+	v, ok := q.Table[key]
+	if !ok {
+		v = make([]float64, len(q.Actions))
+		q.Table[key] = v
+	}
+
+	return v
+}
+
+func (q *QLearner) randIntn(n int) int {
+	return q.random().Intn(n)
+}
+
+// random returns the exploration source, seeding it from Config on first
+// use so a QLearner built as a struct literal works too.
+func (q *QLearner) random() *rand.Rand {
+	if q.rng == nil {
+		q.rng = rand.New(rand.NewSource(q.Config.Seed))
+	}
+
+	return q.rng
+}
+
+func maxOf(values []float64) float64 {
+	m := math.Inf(-1)
+	for _, v := range values {
+		m = max(m, v)
+	}
+
+	return m
+}
@@ -0,0 +1,675 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// ============================================================================
+// Multi-layer Perceptron
+// ============================================================================
+
+// MLP is a small fully connected network with tanh hidden layers and a
+// linear output layer. Weights[l] is row-major [out][in].
+type MLP struct {
+	Sizes   []int       `json:"sizes"`
+	Weights [][]float64 `json:"weights"`
+	Biases  [][]float64 `json:"biases"`
+}
+
+// NewMLP creates a network with the given layer sizes, e.g. (4, 32, 2),
+// with Xavier-initialized weights.
+func NewMLP(rng *rand.Rand, sizes ...int) *MLP {
+	m := &MLP{Sizes: sizes}
+
+	for l := range len(sizes) - 1 {
+		in, out := sizes[l], sizes[l+1]
+		scale := math.Sqrt(1 / float64(in))
+
+		w := make([]float64, in*out)
+		for i := range w {
+			w[i] = rng.NormFloat64() * scale
+		}
+
+		m.Weights = append(m.Weights, w)
+		m.Biases = append(m.Biases, make([]float64, out))
+	}
+
+	return m
+}
+
+// Forward returns the network output for x.
+func (m *MLP) Forward(x []float64) []float64 {
+	acts := m.forward(x)
+
+	return acts[len(acts)-1]
+}
+
+// forward returns the activations of every layer, input first.
+func (m *MLP) forward(x []float64) [][]float64 {
+	acts := [][]float64{x}
+
+	for l, w := range m.Weights {
+		in, out := m.Sizes[l], m.Sizes[l+1]
+		y := slices.Clone(m.Biases[l])
+
+		for o := range out {
+			row := w[o*in : (o+1)*in]
+			for i, v := range x {
+				y[o] += row[i] * v
+			}
+		}
+
+		if l < len(m.Weights)-1 {
+			for o := range y {
+				y[o] = math.Tanh(y[o])
+			}
+		}
+
+		acts = append(acts, y)
+		x = y
+	}
+
+	return acts
+}
+
+// backward accumulates into grad the gradient of a loss whose gradient
+// with respect to the output is dOut.
+func (m *MLP) backward(acts [][]float64, dOut []float64, grad *MLP) {
+	delta := dOut
+
+	for l := len(m.Weights) - 1; l >= 0; l-- {
+		in, out := m.Sizes[l], m.Sizes[l+1]
+		x := acts[l]
+
+		for o := range out {
+			grad.Biases[l][o] += delta[o]
+
+			row := grad.Weights[l][o*in : (o+1)*in]
+			for i, v := range x {
+				row[i] += delta[o] * v
+			}
+		}
+
+		if l == 0 {
+			break
+		}
+
+		prev := make([]float64, in)
+
+		for o := range out {
+			row := m.Weights[l][o*in : (o+1)*in]
+			for i := range prev {
+				prev[i] += row[i] * delta[o]
+			}
+		}
+
+		for i, a := range x {
+			prev[i] *= 1 - a*a // tanh'
+		}
+
+		delta = prev
+	}
+}
+
+// zeros returns a network of the same shape with all parameters zero.
+func (m *MLP) zeros() *MLP {
+	z := &MLP{Sizes: m.Sizes}
+	for l := range m.Weights {
+		z.Weights = append(z.Weights, make([]float64, len(m.Weights[l])))
+		z.Biases = append(z.Biases, make([]float64, len(m.Biases[l])))
+	}
+
+	return z
+}
+
+// params lists the parameter slices in a fixed order.
+func (m *MLP) params() [][]float64 {
+	out := make([][]float64, 0, 2*len(m.Weights))
+	for l := range m.Weights {
+		out = append(out, m.Weights[l], m.Biases[l])
+	}
+
+	return out
+}
+
+// adam is the Adam optimizer for one network.
+type adam struct {
+	rate   float64
+	m, v   [][]float64
+	step   int
+	b1, b2 float64
+}
+
+func newAdam(net *MLP, rate float64) *adam {
+	z1, z2 := net.zeros(), net.zeros()
+
+	return &adam{rate: rate, m: z1.params(), v: z2.params(), b1: 0.9, b2: 0.999}
+}
+
+// apply descends along grad scaled by 1/n.
+func (a *adam) apply(net *MLP, grad *MLP, n float64) {
+	a.step++
+	c1 := 1 - math.Pow(a.b1, float64(a.step))
+	c2 := 1 - math.Pow(a.b2, float64(a.step))
+
+	params, grads := net.params(), grad.params()
+
+	for p := range params {
+		for i := range params[p] {
+			g := grads[p][i] / n
+			a.m[p][i] = a.b1*a.m[p][i] + (1-a.b1)*g
+			a.v[p][i] = a.b2*a.v[p][i] + (1-a.b2)*g*g
+			params[p][i] -= a.rate * (a.m[p][i] / c1) / (math.Sqrt(a.v[p][i]/c2) + 1e-8)
+		}
+	}
+}
+
+// Normalizer standardizes features with running mean and variance.
+type Normalizer struct {
+	Count float64   `json:"count"`
+	Mean  []float64 `json:"mean"`
+	M2    []float64 `json:"m2"`
+}
+
+// Update adds a sample to the running statistics.
+func (n *Normalizer) Update(x []float64) {
+	if len(n.Mean) != len(x) {
+		n.Count, n.Mean, n.M2 = 0, make([]float64, len(x)), make([]float64, len(x))
+	}
+
+	n.Count++
+
+	for i, v := range x {
+		d := v - n.Mean[i]
+		n.Mean[i] += d / n.Count
+		n.M2[i] += d * (v - n.Mean[i])
+	}
+}
+
+// Apply returns the standardized features, clipped to [-5, 5].
+func (n *Normalizer) Apply(x []float64) []float64 {
+	out := make([]float64, len(x))
+
+	for i, v := range x {
+		if i >= len(n.Mean) || n.Count < 2 {
+			out[i] = v
+
+			continue
+		}
+
+		std := math.Sqrt(n.M2[i]/n.Count) + 1e-8
+		out[i] = max(-5, min(5, (v-n.Mean[i])/std))
+	}
+
+	return out
+}
+
+// ============================================================================
+// Policy Gradient
+// ============================================================================
+
+// PolicyAlgorithm selects the policy gradient update.
+type PolicyAlgorithm string
+
+// Policy gradient algorithms.
+const (
+	REINFORCE PolicyAlgorithm = "reinforce" // Monte Carlo returns with a value baseline
+	PPO       PolicyAlgorithm = "ppo"       // Clipped surrogate objective with GAE
+)
+
+// PolicyConfig configures a PolicyLearner.
+type PolicyConfig struct {
+	Algorithm     PolicyAlgorithm
+	Hidden        []int   // Hidden layer sizes
+	LearningRate  float64 // Adam step size
+	Gamma         float64 // Discount
+	Lambda        float64 // GAE lambda (PPO)
+	ClipRange     float64 // Ratio clip (PPO)
+	Epochs        int     // Passes over each batch (PPO)
+	BatchEpisodes int     // Episodes per update
+	EntropyCoef   float64 // Exploration bonus
+	Seed          int64   // Seeds weights and sampling; episode i resets the env with Seed+i
+}
+
+// DefaultPolicyConfig returns defaults for the given algorithm.
+func DefaultPolicyConfig(algorithm PolicyAlgorithm) PolicyConfig {
+	return PolicyConfig{
+		Algorithm:     algorithm,
+		Hidden:        []int{32},
+		LearningRate:  0.003,
+		Gamma:         0.99,
+		Lambda:        0.95,
+		ClipRange:     0.2,
+		Epochs:        4,
+		BatchEpisodes: 4,
+		EntropyCoef:   0.01,
+	}
+}
+
+// PolicyLearner is a player with an MLP softmax policy and an MLP value
+// function trained on CPU by REINFORCE or PPO. It reads states through
+// Encoder, not the env's observations, so it plays the same way in
+// training and in a QASession.
+type PolicyLearner struct {
+	Config     PolicyConfig
+	Encoder    ObservationEncoder
+	Actions    []ActionType
+	Policy     *MLP
+	Value      *MLP
+	Normalizer *Normalizer
+
+	// Sample makes DecideAction sample from the policy instead of taking
+	// the most likely action, giving varied QA runs.
+	Sample bool
+
+	episodes  int
+	rng       *rand.Rand
+	policyOpt *adam
+	valueOpt  *adam
+}
+
+// NewPolicyLearner creates a policy gradient learner. Networks are built
+// on the first Train or Load, once the action set is known.
+func NewPolicyLearner(encoder ObservationEncoder, config PolicyConfig) *PolicyLearner {
+	if config.Algorithm == "" {
+		config.Algorithm = REINFORCE
+	}
+
+	if config.BatchEpisodes <= 0 {
+		config.BatchEpisodes = 1
+	}
+
+	if config.Epochs <= 0 {
+		config.Epochs = 1
+	}
+
+	return &PolicyLearner{
+		Config:     config,
+		Encoder:    encoder,
+		Normalizer: &Normalizer{},
+		rng:        rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// transition is one recorded step.
+type transition struct {
+	x      []float64 // Normalized features
+	mask   []bool
+	action int
+	logP   float64
+	reward float64
+	value  float64
+}
+
+// Train plays episodes, updating the networks every BatchEpisodes
+// episodes. Episodes end when the game ends or at EnvConfig.MaxSteps.
+func (p *PolicyLearner) Train(env *Env, episodes int) (TrainStats, error) {
+	var (
+		stats TrainStats
+		batch [][]transition
+	)
+
+	for range episodes {
+		if _, err := env.Reset(p.Config.Seed + int64(p.episodes)); err != nil {
+			return stats, err
+		}
+
+		if err := p.init(env.Actions()); err != nil {
+			return stats, err
+		}
+
+		var (
+			episode []transition
+			total   float64
+			ended   bool
+		)
+
+		for !env.Done() {
+			raw := p.Encoder.Encode(env.State()).Vector
+			p.Normalizer.Update(raw)
+
+			t := transition{x: p.Normalizer.Apply(raw), mask: env.ActionMask()}
+			probs := p.probs(t.x, t.mask)
+			t.action = sampleIndex(p.rng, probs)
+			t.logP = math.Log(probs[t.action] + 1e-12)
+			t.value = p.Value.Forward(t.x)[0]
+
+			r, err := env.StepIndex(t.action)
+			if err != nil {
+				return stats, err
+			}
+
+			t.reward = r.Reward
+			episode = append(episode, t)
+			total += r.Reward
+			ended = r.Done
+		}
+
+		// A truncated episode bootstraps from the value of its last state
+		if !ended && len(episode) > 0 {
+			x := p.Normalizer.Apply(p.Encoder.Encode(env.State()).Vector)
+			episode[len(episode)-1].reward += p.Config.Gamma * p.Value.Forward(x)[0]
+		}
+
+		p.episodes++
+		stats.add(total, len(episode), env.State().Score)
+
+		batch = append(batch, episode)
+		if len(batch) >= p.Config.BatchEpisodes {
+			p.update(batch)
+			batch = nil
+		}
+	}
+
+	if len(batch) > 0 {
+		p.update(batch)
+	}
+
+	return stats, nil
+}
+
+// DecideAction implements Player.
+func (p *PolicyLearner) DecideAction(state GameState, available []ActionType) ActionType {
+	if len(available) == 0 {
+		return ActionNone
+	}
+
+	if p.Policy == nil {
+		return available[0]
+	}
+
+	mask := make([]bool, len(p.Actions))
+	for i, a := range p.Actions {
+		mask[i] = contains(available, a)
+	}
+
+	if !slices.Contains(mask, true) {
+		return available[0]
+	}
+
+	probs := p.probs(p.Normalizer.Apply(p.Encoder.Encode(state).Vector), mask)
+	if p.Sample {
+		return p.Actions[sampleIndex(p.rng, probs)]
+	}
+
+	best := 0
+	for i, v := range probs {
+		if v > probs[best] {
+			best = i
+		}
+	}
+
+	return p.Actions[best]
+}
+
+// Save implements Learner.
+func (p *PolicyLearner) Save(path string) error {
+	c := &Checkpoint{
+		Kind:       CheckpointPolicy,
+		Actions:    p.Actions,
+		Episodes:   p.episodes,
+		Algorithm:  p.Config.Algorithm,
+		Policy:     p.Policy,
+		Value:      p.Value,
+		Normalizer: p.Normalizer,
+	}
+
+	return c.Save(path)
+}
+
+// Load implements Learner.
+func (p *PolicyLearner) Load(path string) error {
+	c, err := loadCheckpointKind(path, CheckpointPolicy)
+	if err != nil {
+		return err
+	}
+
+	if c.Policy == nil || c.Value == nil {
+		return errors.New("policy: checkpoint has no networks")
+	}
+
+	p.Actions, p.Policy, p.Value, p.episodes = c.Actions, c.Policy, c.Value, c.Episodes
+	p.Normalizer = c.Normalizer
+
+	// Keep training with the update rule the networks were trained with.
+	if c.Algorithm != "" {
+		p.Config.Algorithm = c.Algorithm
+	}
+
+	if p.Normalizer == nil {
+		p.Normalizer = &Normalizer{}
+	}
+
+	p.policyOpt = newAdam(p.Policy, p.Config.LearningRate)
+	p.valueOpt = newAdam(p.Value, p.Config.LearningRate)
+
+	return nil
+}
+
+// init builds the networks for the action set.
+func (p *PolicyLearner) init(actions []ActionType) error {
+	if p.Policy != nil {
+		if !slices.Equal(p.Actions, actions) {
+			return errors.New("policy: env actions differ from the learner's")
+		}
+
+		return nil
+	}
+
+	inputs := p.Encoder.Space().Shape
+	if len(inputs) == 0 {
+		return errors.New("policy: encoder has no vector output")
+	}
+
+	in := shapeSize(inputs)
+	p.Actions = slices.Clone(actions)
+	p.Policy = NewMLP(p.rng, append(append([]int{in}, p.Config.Hidden...), len(actions))...)
+	p.Value = NewMLP(p.rng, append(append([]int{in}, p.Config.Hidden...), 1)...)
+	p.policyOpt = newAdam(p.Policy, p.Config.LearningRate)
+	p.valueOpt = newAdam(p.Value, p.Config.LearningRate)
+
+	return nil
+}
+
+// probs returns the masked softmax of the policy logits.
+func (p *PolicyLearner) probs(x []float64, mask []bool) []float64 {
+	return maskedSoftmax(p.Policy.Forward(x), mask)
+}
+
+// update applies one policy and value update from a batch of episodes.
+func (p *PolicyLearner) update(batch [][]transition) {
+	var (
+		steps      []transition
+		advantages []float64
+		returns    []float64
+	)
+
+	for _, episode := range batch {
+		adv, ret := p.advantages(episode)
+		steps = append(steps, episode...)
+		advantages = append(advantages, adv...)
+		returns = append(returns, ret...)
+	}
+
+	if len(steps) == 0 {
+		return
+	}
+
+	normalize(advantages)
+
+	epochs := 1
+	if p.Config.Algorithm == PPO {
+		epochs = p.Config.Epochs
+	}
+
+	n := float64(len(steps))
+
+	for range epochs {
+		pg, vg := p.Policy.zeros(), p.Value.zeros()
+
+		for i, t := range steps {
+			acts := p.Policy.forward(t.x)
+			probs := maskedSoftmax(acts[len(acts)-1], t.mask)
+			p.Policy.backward(acts, p.policyGrad(t, probs, advantages[i]), pg)
+
+			vacts := p.Value.forward(t.x)
+			p.Value.backward(vacts, []float64{vacts[len(vacts)-1][0] - returns[i]}, vg)
+		}
+
+		p.policyOpt.apply(p.Policy, pg, n)
+		p.valueOpt.apply(p.Value, vg, n)
+	}
+}
+
+// advantages returns per-step advantages and value targets. REINFORCE uses
+// discounted returns minus the value baseline; PPO uses GAE.
+func (p *PolicyLearner) advantages(episode []transition) ([]float64, []float64) {
+	adv := make([]float64, len(episode))
+	ret := make([]float64, len(episode))
+	gamma := p.Config.Gamma
+
+	if p.Config.Algorithm == PPO {
+		gae, nextValue := 0.0, 0.0
+
+		for i := len(episode) - 1; i >= 0; i-- {
+			t := episode[i]
+			delta := t.reward + gamma*nextValue - t.value
+			gae = delta + gamma*p.Config.Lambda*gae
+			adv[i] = gae
+			ret[i] = gae + t.value
+			nextValue = t.value
+		}
+
+		return adv, ret
+	}
+
+	g := 0.0
+
+	for i := len(episode) - 1; i >= 0; i-- {
+		g = episode[i].reward + gamma*g
+		ret[i] = g
+		adv[i] = g - episode[i].value
+	}
+
+	return adv, ret
+}
+
+// policyGrad is the gradient of the policy loss with respect to the logits.
+func (p *PolicyLearner) policyGrad(t transition, probs []float64, advantage float64) []float64 {
+	grad := make([]float64, len(probs))
+
+	// -log pi(a) * A, or PPO's clipped surrogate
+	scale := advantage
+
+	if p.Config.Algorithm == PPO {
+		ratio := math.Exp(math.Log(probs[t.action]+1e-12) - t.logP)
+		clip := p.Config.ClipRange
+
+		if (advantage > 0 && ratio > 1+clip) || (advantage < 0 && ratio < 1-clip) {
+			scale = 0 // Clipped: no gradient
+		} else {
+			scale = advantage * ratio
+		}
+	}
+
+	entropy := 0.0
+
+	for _, v := range probs {
+		if v > 0 {
+			entropy -= v * math.Log(v)
+		}
+	}
+
+	for i, v := range probs {
+		if !t.mask[i] {
+			continue
+		}
+
+		onehot := 0.0
+		if i == t.action {
+			onehot = 1
+		}
+
+		grad[i] = scale * (v - onehot)
+
+		if v > 0 {
+			grad[i] += p.Config.EntropyCoef * v * (math.Log(v) + entropy)
+		}
+	}
+
+	return grad
+}
+
+// maskedSoftmax is the softmax over the actions mask allows. With no
+// valid action the mask is ignored, so the result is never NaN.
+func maskedSoftmax(logits []float64, mask []bool) []float64 {
+	if !slices.Contains(mask, true) {
+		mask = nil
+	}
+
+	out := make([]float64, len(logits))
+	top := math.Inf(-1)
+
+	for i, v := range logits {
+		if mask == nil || mask[i] {
+			top = max(top, v)
+		}
+	}
+
+	sum := 0.0
+
+	for i, v := range logits {
+		if mask == nil || mask[i] {
+			out[i] = math.Exp(v - top)
+			sum += out[i]
+		}
+	}
+
+	for i := range out {
+		out[i] /= sum
+	}
+
+	return out
+}
+
+func sampleIndex(rng *rand.Rand, probs []float64) int {
+	r := rng.Float64()
+	last := 0
+
+	for i, v := range probs {
+		if v == 0 {
+			continue
+		}
+
+		last = i
+
+		r -= v
+		if r < 0 {
+			return i
+		}
+	}
+
+	return last
+}
+
+// normalize standardizes values in place.
+func normalize(values []float64) {
+	if len(values) < 2 {
+		return
+	}
+
+	mean := meanLast(values, 0)
+	variance := 0.0
+
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	std := math.Sqrt(variance/float64(len(values))) + 1e-8
+	for i := range values {
+		values[i] = (values[i] - mean) / std
+	}
+}
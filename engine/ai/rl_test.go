@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// corridorAdapter places the player somewhere in a corridor with the goal
+// at a random end. Reaching the goal scores 10; reaching the other
+// end scores nothing. Both end the game.
+type corridorAdapter struct {
+	rng    *rand.Rand
+	pos    int
+	goal   int
+	score  int
+	tick   int64
+	over   bool
+	length int
+}
+
+func newCorridorAdapter() *corridorAdapter {
+	return &corridorAdapter{rng: rand.New(rand.NewSource(1)), length: 8}
+}
+
+func (c *corridorAdapter) Name() string     { return "Corridor" }
+func (c *corridorAdapter) GetScore() int    { return c.score }
+func (c *corridorAdapter) IsGameOver() bool { return c.over }
+func (c *corridorAdapter) Seed(seed int64)  { c.rng.Seed(seed) }
+
+func (c *corridorAdapter) GetState() GameState {
+	return GameState{
+		Tick:       c.tick,
+		Score:      c.score,
+		PlayerPos:  [2]float64{float64(c.pos), 0},
+		CustomData: map[string]any{"offset": c.goal - c.pos},
+	}
+}
+
+func (c *corridorAdapter) AvailableActions() []ActionType {
+	return []ActionType{ActionMoveLeft, ActionMoveRight}
+}
+
+func (c *corridorAdapter) PerformAction(action ActionType) error {
+	switch action {
+	case ActionMoveLeft:
+		c.pos--
+	case ActionMoveRight:
+		c.pos++
+	}
+
+	return nil
+}
+
+func (c *corridorAdapter) Step() error {
+	c.tick++
+
+	if c.pos == c.goal {
+		c.score += 10
+	}
+
+	c.over = c.pos <= 0 || c.pos >= c.length
+
+	return nil
+}
+
+func (c *corridorAdapter) Reset() error {
+	c.pos, c.score, c.tick, c.over = 1+c.rng.Intn(c.length-1), 0, 0, false
+
+	c.goal = 0
+	if c.rng.Intn(2) == 1 {
+		c.goal = c.length
+	}
+
+	return nil
+}
+
+// evalPlayer returns the mean score of a player over episodes.
+func evalPlayer(t *testing.T, player Player, runs int) float64 {
+	t.Helper()
+
+	session := NewQASession(newCorridorAdapter())
+	session.SetPlayer(player)
+	session.SetConfig(SessionConfig{Runs: runs, MaxTicks: 50, RecordEvery: 1})
+
+	report := session.Run()
+
+	total := 0
+	for _, run := range report.Runs {
+		total += run.FinalScore
+	}
+
+	return float64(total) / float64(runs)
+}
+
+func TestQLearner(t *testing.T) {
+	env := NewEnv(newCorridorAdapter(), EnvConfig{MaxSteps: 50})
+	learner := NewQLearner(NewBinDiscretizer([]string{"offset"}, []Bin{{Low: -8, High: 9, Count: 17}}), DefaultQConfig())
+
+	stats, err := learner.Train(env, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats.Rewards) != 300 || learner.Config.Epsilon >= 1 {
+		t.Fatalf("stats = %d episodes, epsilon %v", len(stats.Rewards), learner.Config.Epsilon)
+	}
+
+	if mean := stats.MeanScore(50); mean < 9 {
+		t.Errorf("Late training score = %v, want close to 10", mean)
+	}
+
+	if score := evalPlayer(t, learner, 20); score != 10 {
+		t.Errorf("Trained QA score = %v, want 10", score)
+	}
+
+	path := filepath.Join(t.TempDir(), "q.json")
+	if err := learner.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewQLearner(learner.Discretizer, DefaultQConfig())
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Table) != len(learner.Table) || evalPlayer(t, loaded, 20) != 10 {
+		t.Error("Loaded checkpoint should play like the original")
+	}
+
+	if err := NewPolicyLearner(NewFeatureEncoder("offset"), PolicyConfig{}).Load(path); err == nil {
+		t.Error("Loading a Q-table into a policy learner should fail")
+	}
+}
+
+func TestQLearnerLiteral(t *testing.T) {
+	env := NewEnv(newCorridorAdapter(), EnvConfig{MaxSteps: 20})
+	learner := &QLearner{
+		Config:      DefaultQConfig(),
+		Discretizer: NewBinDiscretizer([]string{"offset"}, []Bin{{Low: -8, High: 9, Count: 17}}),
+	}
+
+	if _, err := learner.Train(env, 3); err != nil || len(learner.Table) == 0 {
+		t.Errorf("struct literal learner: %v, %d states", err, len(learner.Table))
+	}
+}
+
+func TestMaskedSoftmax(t *testing.T) {
+	logits := []float64{1, 2, 3}
+
+	if p := maskedSoftmax(logits, []bool{true, false, true}); p[1] != 0 || math.Abs(p[0]+p[2]-1) > 1e-9 {
+		t.Errorf("masked = %v", p)
+	}
+
+	// No valid action falls back to the unmasked softmax instead of NaN.
+	got, want := maskedSoftmax(logits, []bool{false, false, false}), maskedSoftmax(logits, nil)
+	if !slices.Equal(got, want) {
+		t.Errorf("all masked = %v, want %v", got, want)
+	}
+}
+
+func TestPolicyLearner(t *testing.T) {
+	for _, algorithm := range []PolicyAlgorithm{REINFORCE, PPO} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			config := DefaultPolicyConfig(algorithm)
+			config.LearningRate = 0.01
+			config.Seed = 3
+
+			env := NewEnv(newCorridorAdapter(), EnvConfig{MaxSteps: 50})
+			learner := NewPolicyLearner(NewFeatureEncoder("offset"), config)
+
+			stats, err := learner.Train(env, 600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if late := stats.MeanScore(50); late < 9 {
+				t.Errorf("Late training score = %v, want close to 10", late)
+			}
+
+			if score := evalPlayer(t, learner, 20); score != 10 {
+				t.Errorf("Trained QA score = %v, want 10", score)
+			}
+
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := learner.Save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			// The checkpoint's algorithm wins over the loader's config.
+			other := REINFORCE
+			if algorithm == REINFORCE {
+				other = PPO
+			}
+
+			loaded := NewPolicyLearner(NewFeatureEncoder("offset"), DefaultPolicyConfig(other))
+			if err := loaded.Load(path); err != nil {
+				t.Fatal(err)
+			}
+
+			if loaded.Config.Algorithm != algorithm {
+				t.Errorf("Loaded algorithm = %s, want %s", loaded.Config.Algorithm, algorithm)
+			}
+
+			state := GameState{CustomData: map[string]any{"offset": -3}}
+			if got := loaded.DecideAction(state, loaded.Actions); got != ActionMoveLeft {
+				t.Errorf("Loaded policy chose %s, want move_left", got)
+			}
+		})
+	}
+}
+
+func TestMLPGradient(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	net := NewMLP(rng, 3, 4, 2)
+	x := []float64{0.5, -1, 2}
+
+	// Loss = sum of outputs; compare backprop with finite differences
+	grad := net.zeros()
+	acts := net.forward(x)
+	net.backward(acts, []float64{1, 1}, grad)
+
+	loss := func() float64 {
+		out := net.Forward(x)
+
+		return out[0] + out[1]
+	}
+
+	params, grads := net.params(), grad.params()
+
+	for p := range params {
+		for i := range params[p] {
+			orig := params[p][i]
+			params[p][i] = orig + 1e-6
+			up := loss()
+			params[p][i] = orig - 1e-6
+			down := loss()
+			params[p][i] = orig
+
+			if numeric := (up - down) / 2e-6; abs(numeric-grads[p][i]) > 1e-5 {
+				t.Fatalf("param %d[%d]: backprop %v, numeric %v", p, i, grads[p][i], numeric)
+			}
+		}
+	}
+}
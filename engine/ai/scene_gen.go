@@ -1,155 +1,122 @@
 package ai
 
 import (
+	"context"
 	"fmt"
+	"go/format"
+	"slices"
 	"strings"
 )
 
-// EntitySpec describes an entity to generate.
-type EntitySpec struct {
-	Name       string
-	Type       string // "sprite", "player", "enemy", "collectible"
-	Position   [2]float64
-	Sprite     string
-	Properties map[string]string
-}
-
-// SceneSpec describes a scene to generate.
-type SceneSpec struct {
-	Name        string
-	Width       int
-	Height      int
-	Background  string
-	Entities    []EntitySpec
-	Description string
-}
-
-// SceneGenerator generates game code from specifications.
+// SceneGenerator turns prompts into SceneSpecs and SceneSpecs into Go code.
 type SceneGenerator struct {
-	templates map[string]string
+	// Client, if set, is used by GenerateSpec. Without one, prompts are
+	// parsed with keyword rules.
+	Client LLMClient
+	// Repairs is how many times GenerateSpec sends validation errors back
+	// to the model before giving up.
+	Repairs int
 }
 
 // NewSceneGenerator creates a new scene generator.
 func NewSceneGenerator() *SceneGenerator {
-	return &SceneGenerator{
-		templates: defaultTemplates(),
-	}
-}
-
-// defaultTemplates returns built-in code templates.
-func defaultTemplates() map[string]string {
-	return map[string]string{
-		"main": `package main
-
-import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/mlange-42/ark/ecs"
-)
-
-const (
-	screenWidth  = %d
-	screenHeight = %d
-)
-
-type Game struct {
-	world ecs.World
-}
-
-func (g *Game) Update() error {
-	return nil
-}
-
-func (g *Game) Draw(screen *ebiten.Image) {
-	// Draw entities
-}
-
-func (g *Game) Layout(w, h int) (int, int) {
-	return screenWidth, screenHeight
-}
-
-func main() {
-	game := &Game{}
-	game.world = ecs.NewWorld()
-	
-	// Initialize entities
-%s
-	
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("%s")
-	ebiten.RunGame(game)
-}
-`,
-		"entity": `	// Create %s
-	// Position: (%.0f, %.0f)
-	// Type: %s
-`,
-	}
+	return &SceneGenerator{Repairs: 2}
 }
 
-// GenerateCode generates Go code from a SceneSpec.
-func (g *SceneGenerator) GenerateCode(spec SceneSpec) string {
-	// Generate entity initialization code
-	var entityCode strings.Builder
-	for _, e := range spec.Entities {
-		entityCode.WriteString(fmt.Sprintf(g.templates["entity"],
-			e.Name, e.Position[0], e.Position[1], e.Type))
-	}
-
-	// Generate main code
-	code := fmt.Sprintf(g.templates["main"],
-		spec.Width, spec.Height,
-		entityCode.String(),
-		spec.Name)
+// ============================================================================
+// Prompt Parsing
+// ============================================================================
 
-	return code
-}
-
-// ParsePrompt extracts a SceneSpec from natural language.
-// This is a simplified parser - in production, use an LLM.
+// ParsePrompt extracts a SceneSpec from natural language with keyword rules.
+// Use GenerateSpec with an LLM client for anything more specific.
 func (g *SceneGenerator) ParsePrompt(prompt string) SceneSpec {
 	spec := SceneSpec{
+		Version:     SceneSpecVersion,
 		Name:        "Generated Game",
 		Width:       800,
 		Height:      600,
+		Background:  "#202030",
 		Entities:    make([]EntitySpec, 0),
 		Description: prompt,
 	}
 
 	promptLower := strings.ToLower(prompt)
+	has := func(words ...string) bool {
+		return slices.ContainsFunc(words, func(w string) bool { return strings.Contains(promptLower, w) })
+	}
 
-	// Extract entities from keywords
-	if strings.Contains(promptLower, "player") {
+	sprite := func(hex string, w, h int) ComponentSpec {
+		return ComponentSpec{Type: "sprite", Params: NodeParams{"color": hex, "width": w, "height": h}}
+	}
+	collider := func(w, h int) ComponentSpec {
+		return ComponentSpec{Type: "collider", Params: NodeParams{"width": w, "height": h}}
+	}
+
+	if has("player") {
 		spec.Entities = append(spec.Entities, EntitySpec{
 			Name:     "Player",
 			Type:     "player",
 			Position: [2]float64{400, 300},
+			Components: []ComponentSpec{
+				sprite("#40c040", 16, 16),
+				collider(16, 16),
+				{Type: "velocity"},
+				{Type: "movement", Params: NodeParams{"speed": 3}},
+				{Type: "health", Params: NodeParams{"max": 3}},
+			},
 		})
+		spec.Systems = append(spec.Systems, "player_control")
+		spec.Input = []InputSpec{
+			{Action: "move_left", Keys: []string{"ArrowLeft", "A"}},
+			{Action: "move_right", Keys: []string{"ArrowRight", "D"}},
+			{Action: "move_up", Keys: []string{"ArrowUp", "W"}},
+			{Action: "move_down", Keys: []string{"ArrowDown", "S"}},
+		}
+		spec.Lose = append(spec.Lose, ConditionSpec{Type: "health", Tag: "player", Op: "<=", Value: 0})
 	}
 
-	if strings.Contains(promptLower, "enemy") || strings.Contains(promptLower, "enemies") {
+	if has("enemy", "enemies") {
 		spec.Entities = append(spec.Entities, EntitySpec{
 			Name:     "Enemy",
 			Type:     "enemy",
 			Position: [2]float64{600, 300},
+			Components: []ComponentSpec{
+				sprite("#d04040", 16, 16),
+				collider(16, 16),
+				{Type: "velocity", Params: NodeParams{"x": -1}},
+				{Type: "health", Params: NodeParams{"max": 2}},
+				{Type: "combat", Params: NodeParams{"attack": 1}},
+			},
 		})
+		spec.Systems = append(spec.Systems, "health")
 	}
 
-	if strings.Contains(promptLower, "coin") || strings.Contains(promptLower, "collectible") {
+	if has("coin", "collectible") {
 		spec.Entities = append(spec.Entities, EntitySpec{
-			Name:     "Coin",
-			Type:     "collectible",
-			Position: [2]float64{200, 200},
+			Name:       "Coin",
+			Type:       "coin",
+			Position:   [2]float64{200, 200},
+			Components: []ComponentSpec{sprite("#f0d040", 8, 8), collider(8, 8)},
 		})
+		spec.Win = append(spec.Win, ConditionSpec{Type: "tag_count", Tag: "coin", Op: "==", Value: 0})
 	}
 
-	if strings.Contains(promptLower, "platform") {
+	if has("platform") {
 		spec.Entities = append(spec.Entities, EntitySpec{
-			Name:     "Platform",
-			Type:     "sprite",
-			Position: [2]float64{400, 500},
+			Name:       "Platform",
+			Type:       "platform",
+			Position:   [2]float64{300, 500},
+			Components: []ComponentSpec{sprite("#808080", 200, 16), collider(200, 16)},
 		})
 	}
 
+	spec.Systems = append(spec.Systems, "movement")
+	if len(spec.Entities) > 1 {
+		spec.Systems = append(spec.Systems, "collision")
+	}
+
+	spec.Systems = append(spec.Systems, "render")
+
 	// Extract name
 	if strings.Contains(promptLower, "platformer") {
 		spec.Name = "Platformer Game"
@@ -162,6 +129,64 @@ func (g *SceneGenerator) ParsePrompt(prompt string) SceneSpec {
 	return spec
 }
 
+// GenerateSpec asks the LLM client for a SceneSpec matching prompt. Replies
+// are constrained by SceneSpecSchema; invalid specs are sent back with the
+// validation errors up to Repairs times. Without a client it falls back to
+// ParsePrompt.
+func (g *SceneGenerator) GenerateSpec(ctx context.Context, prompt string) (*SceneSpec, error) {
+	if g.Client == nil {
+		spec := g.ParsePrompt(prompt)
+
+		return &spec, nil
+	}
+
+	req := ChatRequest{
+		Messages: Messages(sceneSpecPrompt(), prompt),
+		Schema:   &ResponseSchema{Name: "scene_spec", Schema: SceneSpecSchema()},
+	}
+
+	var lastErr error
+
+	for range g.Repairs + 1 {
+		resp, err := g.Client.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var spec SceneSpec
+		if lastErr = DecodeJSONReply(resp.Message.Content, &spec); lastErr == nil {
+			if _, lastErr = upgradeSceneSpec(&spec); lastErr == nil {
+				if lastErr = spec.Validate(); lastErr == nil {
+					return &spec, nil
+				}
+			}
+		}
+
+		req.Messages = append(req.Messages,
+			resp.Message,
+			ChatMessage{Role: RoleUser, Content: "The scene spec is invalid:\n" + lastErr.Error() +
+				"\nReply with the corrected spec."},
+		)
+	}
+
+	return nil, fmt.Errorf("generate scene spec: %w", lastErr)
+}
+
+// sceneSpecPrompt is the system prompt for GenerateSpec.
+func sceneSpecPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("You design 2D game scenes as JSON scene specs.\n")
+	sb.WriteString(fmt.Sprintf("Use version %d. Positions are pixels from the top-left corner.\n", SceneSpecVersion))
+	sb.WriteString("An entity's type becomes its tag; the player must have type \"player\".\n")
+	sb.WriteString("Components: " + strings.Join(SceneComponentTypes(), ", ") + ".\n")
+	sb.WriteString("Systems: " + strings.Join(SceneSystemNames(), ", ") +
+		". player_control reads the move_left, move_right, move_up and move_down actions.\n")
+	sb.WriteString("Input keys use ebiten key names such as ArrowLeft, A, Space.\n")
+	sb.WriteString("Win and lose conditions compare tag_count, health (of a tag) or ticks with a value.")
+
+	return sb.String()
+}
+
 // GenerateFromPrompt generates code directly from a prompt.
 func (g *SceneGenerator) GenerateFromPrompt(prompt string) string {
 	spec := g.ParsePrompt(prompt)
@@ -169,6 +194,416 @@ func (g *SceneGenerator) GenerateFromPrompt(prompt string) string {
 	return g.GenerateCode(spec)
 }
 
+// ============================================================================
+// Code Generation
+// ============================================================================
+
+// GenerateCode generates a runnable Go main package from a SceneSpec.
+// Invalid specs produce a comment listing the problems instead.
+func (g *SceneGenerator) GenerateCode(spec SceneSpec) string {
+	src, err := g.GenerateSource(spec)
+	if err != nil {
+		return "// Code generation failed:\n// " + strings.ReplaceAll(err.Error(), "\n", "\n// ") + "\n"
+	}
+
+	return string(src)
+}
+
+// GenerateSource validates spec and compiles it into gofmt-ed Go source for
+// a main package built on engine.Game, the systems package and archetypes.
+// The generated game behaves like a SceneLoader instance of the same spec.
+func (g *SceneGenerator) GenerateSource(spec SceneSpec) ([]byte, error) {
+	if _, err := upgradeSceneSpec(&spec); err != nil {
+		return nil, err
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("scene %q: %w", spec.Name, err)
+	}
+
+	var (
+		w          codeWriter
+		hasSprites bool
+		arches     []string // Component type lists of the archetypes in use
+	)
+
+	entities := make([][]ComponentSpec, len(spec.Entities))
+
+	for i, e := range spec.Entities {
+		entities[i] = entityComponents(e)
+		for _, c := range entities[i] {
+			hasSprites = hasSprites || c.Type == "sprite"
+		}
+
+		if len(entities[i]) > 0 {
+			if sig := archetypeSignature(entities[i]); !slices.Contains(arches, sig) {
+				arches = append(arches, sig)
+			}
+		}
+	}
+
+	usesSystems := slices.ContainsFunc(spec.Systems, func(s string) bool { return s != "player_control" })
+	usesComponents := len(spec.Entities) > 0 || slices.Contains(spec.Systems, "player_control") ||
+		slices.ContainsFunc(append(slices.Clone(spec.Win), spec.Lose...), func(c ConditionSpec) bool {
+			return c.Type != "ticks"
+		})
+
+	w.line("// Code generated by SceneGenerator from scene spec %q. DO NOT EDIT.", spec.Name)
+	w.line("")
+	w.line("package main")
+	w.line("")
+	w.line("import (")
+	w.line(`"image/color"`)
+	w.line(`"log"`)
+	w.line("")
+	w.line(`"github.com/hajimehoshi/ebiten/v2"`)
+
+	if hasSprites {
+		w.line(`"github.com/hajimehoshi/ebiten/v2/ebitenutil"`)
+	}
+
+	w.line(`"github.com/mlange-42/ark/ecs"`)
+
+	if len(arches) > 0 {
+		w.line(`"github.com/skyrocket-qy/NeuralWay/engine/archetypes"`)
+	}
+
+	if usesComponents {
+		w.line(`"github.com/skyrocket-qy/NeuralWay/engine/components"`)
+	}
+
+	w.line(`"github.com/skyrocket-qy/NeuralWay/engine/engine"`)
+
+	if usesSystems {
+		w.line(`"github.com/skyrocket-qy/NeuralWay/engine/systems"`)
+	}
+
+	w.line(")")
+	w.line("")
+
+	bg, _ := parseHexColor(spec.Background) // Transparent when unset
+
+	w.line("const (")
+	w.line("screenWidth = %d", spec.Width)
+	w.line("screenHeight = %d", spec.Height)
+	w.line("title = %q", spec.Name)
+	w.line(")")
+	w.line("")
+	w.line("var background = color.RGBA{R: %d, G: %d, B: %d, A: %d}", bg.R, bg.G, bg.B, bg.A)
+	w.line("")
+
+	// Game wrapper
+	w.line("// Game runs the scene and stops once it is won or lost.")
+	w.line("type Game struct {")
+	w.line("*engine.Game")
+	w.line("")
+	w.line("input *engine.InputManager")
+	w.line("tick int64")
+	w.line("}")
+	w.line("")
+	w.line("// Update reads input, runs the systems and checks the win and lose conditions.")
+	w.line("func (g *Game) Update() error {")
+	w.line("g.input.Update()")
+	w.line("")
+	w.line("if err := g.Game.Update(); err != nil {")
+	w.line("return err")
+	w.line("}")
+	w.line("")
+	w.line("g.tick++")
+	w.line("")
+	w.line("if outcome := checkOutcome(&g.World, g.tick); outcome != \"\" {")
+	w.line(`log.Printf("%%s: %%s after %%d ticks", title, outcome, g.tick)`)
+	w.line("")
+	w.line("return ebiten.Termination")
+	w.line("}")
+	w.line("")
+	w.line("return nil")
+	w.line("}")
+	w.line("")
+	w.line("// Draw clears the screen to the background color and runs the draw systems.")
+	w.line("func (g *Game) Draw(screen *ebiten.Image) {")
+	w.line("screen.Fill(background)")
+	w.line("g.Game.Draw(screen)")
+	w.line("}")
+	w.line("")
+
+	// main
+	w.line("func main() {")
+	w.line("game := engine.NewGame(screenWidth, screenHeight, title)")
+	w.line("input := engine.NewInputManager()")
+	w.line("w := &game.World")
+	w.line("")
+	w.line("bindInput(input)")
+	w.line("spawnEntities(w)")
+
+	if len(spec.Systems) > 0 {
+		w.line("")
+
+		for _, name := range spec.Systems {
+			w.line("%s", sceneSystems[name].code)
+		}
+	}
+
+	w.line("")
+	w.line("ebiten.SetWindowSize(screenWidth, screenHeight)")
+	w.line("ebiten.SetWindowTitle(title)")
+	w.line("")
+	w.line("if err := ebiten.RunGame(&Game{Game: game, input: input}); err != nil {")
+	w.line("log.Fatal(err)")
+	w.line("}")
+	w.line("}")
+	w.line("")
+
+	// Input
+	w.line("func bindInput(input *engine.InputManager) {")
+
+	for _, in := range spec.Input {
+		for _, name := range in.Keys {
+			key, _ := parseKey(name)
+			w.line("input.BindKey(%q, ebiten.Key%s)", in.Action, key.String())
+		}
+	}
+
+	w.line("}")
+	w.line("")
+
+	// Entities
+	w.line("func spawnEntities(w *ecs.World) {")
+
+	for i, sig := range arches {
+		types := strings.Split(sig, ",")
+		w.line("arch%d := archetypes.NewArchetype%d[%s](w)", i, len(types), strings.Join(types, ", "))
+	}
+
+	if len(arches) > 0 {
+		w.line("")
+	}
+
+	for i, e := range spec.Entities {
+		comps := entities[i]
+		values := []string{fmt.Sprintf("ptr(components.Position{X: %s, Y: %s})",
+			goFloat(e.Position[0]), goFloat(e.Position[1]))}
+
+		for _, c := range comps {
+			values = append(values, fmt.Sprintf("ptr(%s)", sceneComponents[c.Type].code(c.Params)))
+		}
+
+		if i > 0 {
+			w.line("")
+		}
+
+		w.line("// %s", e.Name)
+
+		if len(values) == 1 {
+			w.line("ecs.NewMap[components.Position](w).NewEntity(%s)", values[0])
+
+			continue
+		}
+
+		arch := slices.Index(arches, archetypeSignature(comps))
+		head := min(len(values), 4)
+
+		if len(values) == head {
+			w.line("arch%d.New(%s)", arch, strings.Join(values, ", "))
+
+			continue
+		}
+
+		w.line("{")
+		w.line("e := arch%d.New(%s)", arch, strings.Join(values[:head], ", "))
+
+		for j, c := range comps[head-1:] {
+			w.line("ecs.NewMap[%s](w).Add(e, %s)", sceneComponents[c.Type].goType, values[head+j])
+		}
+
+		w.line("}")
+	}
+
+	w.line("}")
+	w.line("")
+
+	// Conditions
+	w.line("// checkOutcome returns \"lose\" or \"win\" once a condition holds.")
+	w.line("func checkOutcome(w *ecs.World, tick int64) string {")
+	w.conditions("lose", spec.Lose)
+	w.conditions("win", spec.Win)
+	w.line("return \"\"")
+	w.line("}")
+	w.line("")
+	w.line("func ptr[T any](v T) *T { return &v }")
+
+	w.helpers(&spec, hasSprites)
+
+	src, err := format.Source([]byte(w.String()))
+	if err != nil {
+		return nil, fmt.Errorf("scene %q: format generated code: %w", spec.Name, err)
+	}
+
+	return src, nil
+}
+
+// archetypeSignature lists the Go types of the first four components of an
+// entity (Position first), which is the archetype it is created with.
+func archetypeSignature(comps []ComponentSpec) string {
+	types := []string{"components.Position"}
+	for _, c := range comps[:min(len(comps), 3)] {
+		types = append(types, sceneComponents[c.Type].goType)
+	}
+
+	return strings.Join(types, ",")
+}
+
+// codeWriter accumulates generated source; gofmt fixes indentation afterwards.
+type codeWriter struct {
+	strings.Builder
+}
+
+func (w *codeWriter) line(format string, args ...any) {
+	fmt.Fprintf(w, format, args...)
+	w.WriteByte('\n')
+}
+
+func (w *codeWriter) conditions(outcome string, conds []ConditionSpec) {
+	for _, c := range conds {
+		var value string
+
+		switch c.Type {
+		case "ticks":
+			value = "float64(tick)"
+		case "tag_count":
+			value = fmt.Sprintf("countTag(w, %q)", c.Tag)
+		case "health":
+			value = fmt.Sprintf("minHealth(w, %q)", c.Tag)
+		}
+
+		w.line("if %s %s %s {", value, c.Op, goFloat(c.Value))
+		w.line("return %q", outcome)
+		w.line("}")
+		w.line("")
+	}
+}
+
+// helpers writes the support functions the generated code refers to.
+func (w *codeWriter) helpers(spec *SceneSpec, hasSprites bool) {
+	conds := append(slices.Clone(spec.Win), spec.Lose...)
+	uses := func(t string) bool {
+		return slices.ContainsFunc(conds, func(c ConditionSpec) bool { return c.Type == t })
+	}
+
+	if hasSprites {
+		w.line("")
+		w.line("// imageAssets maps image asset names to files.")
+		w.line("var imageAssets = map[string]string{")
+
+		for _, a := range spec.Assets {
+			if a.Kind == "image" {
+				w.line("%q: %q,", a.Name, a.Path)
+			}
+		}
+
+		w.line("}")
+		w.line("")
+		w.line("var loadedImages = map[string]*ebiten.Image{}")
+		w.line("")
+		w.line(`// spriteImage loads an image asset, or returns a solid placeholder.
+func spriteImage(asset string, c color.RGBA, width, height int) *ebiten.Image {
+	if img, ok := loadedImages[asset]; ok {
+		return img
+	}
+
+	if path, ok := imageAssets[asset]; ok {
+		if img, _, err := ebitenutil.NewImageFromFile(path); err == nil {
+			loadedImages[asset] = img
+
+			return img
+		}
+	}
+
+	img := ebiten.NewImage(width, height)
+	img.Fill(c)
+
+	return img
+}`)
+	}
+
+	if uses("tag_count") {
+		w.line(`
+// countTag counts the entities with a tag.
+func countTag(w *ecs.World, tag string) float64 {
+	n := 0.0
+
+	query := ecs.NewFilter1[components.Tag](w).Query()
+	for query.Next() {
+		if query.Get().Name == tag {
+			n++
+		}
+	}
+
+	return n
+}`)
+	}
+
+	if uses("health") {
+		w.line(`
+// minHealth returns the lowest health among entities with a tag.
+func minHealth(w *ecs.World, tag string) float64 {
+	v, found := 0.0, false
+
+	query := ecs.NewFilter2[components.Tag, components.Health](w).Query()
+	for query.Next() {
+		t, h := query.Get()
+		if t.Name == tag && (!found || float64(h.Current) < v) {
+			v, found = float64(h.Current), true
+		}
+	}
+
+	return v
+}`)
+	}
+
+	if slices.Contains(spec.Systems, "player_control") {
+		w.line(`
+// playerControl moves entities tagged "player" with the move_* actions.
+type playerControl struct {
+	input    *engine.InputManager
+	filter   *ecs.Filter2[components.Tag, components.Velocity]
+	movement *ecs.Map[components.Movement]
+}
+
+func newPlayerControl(w *ecs.World, input *engine.InputManager) *playerControl {
+	return &playerControl{
+		input:    input,
+		filter:   ecs.NewFilter2[components.Tag, components.Velocity](w),
+		movement: ecs.NewMap[components.Movement](w),
+	}
+}
+
+func (s *playerControl) Update(_ *ecs.World) {
+	dx := s.input.GetAxis("move_left", "move_right")
+	dy := s.input.GetAxis("move_up", "move_down")
+
+	query := s.filter.Query()
+	for query.Next() {
+		tag, vel := query.Get()
+		if tag.Name != "player" {
+			continue
+		}
+
+		speed := 2.0
+		if e := query.Entity(); s.movement.Has(e) {
+			speed = s.movement.Get(e).GetMaxSpeed()
+		}
+
+		vel.X, vel.Y = dx*speed, dy*speed
+	}
+}`)
+	}
+}
+
+// ============================================================================
+// Documentation
+// ============================================================================
+
 // ExportMarkdown generates a markdown description of a scene.
 func (g *SceneGenerator) ExportMarkdown(spec SceneSpec) string {
 	var sb strings.Builder
@@ -182,12 +617,37 @@ func (g *SceneGenerator) ExportMarkdown(spec SceneSpec) string {
 
 	if len(spec.Entities) > 0 {
 		sb.WriteString("## Entities\n\n")
-		sb.WriteString("| Name | Type | Position |\n")
-		sb.WriteString("|------|------|----------|\n")
+		sb.WriteString("| Name | Type | Position | Components |\n")
+		sb.WriteString("|------|------|----------|------------|\n")
 
 		for _, e := range spec.Entities {
-			sb.WriteString(fmt.Sprintf("| %s | %s | (%.0f, %.0f) |\n",
-				e.Name, e.Type, e.Position[0], e.Position[1]))
+			var comps []string
+			for _, c := range e.Components {
+				comps = append(comps, c.Type)
+			}
+
+			sb.WriteString(fmt.Sprintf("| %s | %s | (%.0f, %.0f) | %s |\n",
+				e.Name, e.Type, e.Position[0], e.Position[1], strings.Join(comps, ", ")))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	if len(spec.Systems) > 0 {
+		sb.WriteString("**Systems**: " + strings.Join(spec.Systems, ", ") + "\n\n")
+	}
+
+	for _, group := range []struct {
+		title string
+		conds []ConditionSpec
+	}{{"Win", spec.Win}, {"Lose", spec.Lose}} {
+		for _, c := range group.conds {
+			subject := c.Type
+			if c.Tag != "" {
+				subject += "(" + c.Tag + ")"
+			}
+
+			sb.WriteString(fmt.Sprintf("- **%s** when %s %s %s\n", group.title, subject, c.Op, goFloat(c.Value)))
 		}
 	}
 
@@ -0,0 +1,542 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/engine"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+)
+
+// ============================================================================
+// Scene Components and Systems
+// ============================================================================
+
+// sceneComponent knows how to add one spec component at runtime and how to
+// write it as Go source.
+type sceneComponent struct {
+	params []string
+	goType string
+	add    func(b *sceneBuild, e ecs.Entity, p NodeParams)
+	code   func(p NodeParams) string
+}
+
+// sceneComponents maps ComponentSpec types to components.
+var sceneComponents = map[string]sceneComponent{
+	"tag": {
+		params: []string{"name"},
+		goType: "components.Tag",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.Tag{Name: p.String("name", "")})
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.Tag{Name: %q}", p.String("name", ""))
+		},
+	},
+	"velocity": {
+		params: []string{"x", "y"},
+		goType: "components.Velocity",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.Velocity{X: p.Float("x", 0), Y: p.Float("y", 0)})
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.Velocity{X: %s, Y: %s}", goFloat(p.Float("x", 0)), goFloat(p.Float("y", 0)))
+		},
+	},
+	"sprite": {
+		params: []string{"asset", "width", "height", "color"},
+		goType: "components.Sprite",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.NewSprite(b.image(p)))
+		},
+		code: func(p NodeParams) string {
+			c, _ := parseHexColor(p.String("color", "#ffffff"))
+
+			return fmt.Sprintf("components.NewSprite(spriteImage(%q, color.RGBA{R: %d, G: %d, B: %d, A: %d}, %d, %d))",
+				p.String("asset", ""), c.R, c.G, c.B, c.A, p.Int("width", 16), p.Int("height", 16))
+		},
+	},
+	"collider": {
+		params: []string{"width", "height", "layer", "mask"},
+		goType: "components.Collider",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.Collider{
+				Width:  p.Float("width", 16),
+				Height: p.Float("height", 16),
+				Layer:  uint32(p.Int("layer", 1)),
+				Mask:   uint32(p.Int("mask", 1)),
+			})
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.Collider{Width: %s, Height: %s, Layer: %d, Mask: %d}",
+				goFloat(p.Float("width", 16)), goFloat(p.Float("height", 16)),
+				uint32(p.Int("layer", 1)), uint32(p.Int("mask", 1)))
+		},
+	},
+	"health": {
+		params: []string{"max"},
+		goType: "components.Health",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.NewHealth(p.Int("max", 100)))
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.NewHealth(%d)", p.Int("max", 100))
+		},
+	},
+	"combat": {
+		params: []string{"attack", "defense"},
+		goType: "components.Combat",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.NewCombat(p.Float("attack", 10), p.Float("defense", 0)))
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.NewCombat(%s, %s)",
+				goFloat(p.Float("attack", 10)), goFloat(p.Float("defense", 0)))
+		},
+	},
+	"movement": {
+		params: []string{"speed"},
+		goType: "components.Movement",
+		add: func(b *sceneBuild, e ecs.Entity, p NodeParams) {
+			addComponent(b.world, e, components.NewMovement(p.Float("speed", 2)))
+		},
+		code: func(p NodeParams) string {
+			return fmt.Sprintf("components.NewMovement(%s)", goFloat(p.Float("speed", 2)))
+		},
+	},
+}
+
+// sceneSystem builds one spec system at runtime and writes it as Go source
+// registering on game with world w.
+type sceneSystem struct {
+	build func(b *sceneBuild) (engine.System, engine.DrawSystem)
+	code  string
+}
+
+// sceneSystems maps SceneSpec system names to systems.
+var sceneSystems = map[string]sceneSystem{
+	"player_control": {
+		build: func(b *sceneBuild) (engine.System, engine.DrawSystem) {
+			return NewPlayerControlSystem(b.world, b.input), nil
+		},
+		code: "game.AddSystem(newPlayerControl(w, input))",
+	},
+	"movement": {
+		build: func(b *sceneBuild) (engine.System, engine.DrawSystem) {
+			return systems.NewMovementSystem(b.world), nil
+		},
+		code: "game.AddSystem(systems.NewMovementSystem(w))",
+	},
+	"collision": {
+		build: func(b *sceneBuild) (engine.System, engine.DrawSystem) {
+			return systems.NewCollisionSystem(b.world, 64), nil
+		},
+		code: "game.AddSystem(systems.NewCollisionSystem(w, 64))",
+	},
+	"health": {
+		build: func(b *sceneBuild) (engine.System, engine.DrawSystem) {
+			return systems.NewHealthSystem(b.world), nil
+		},
+		code: "game.AddSystem(systems.NewHealthSystem(w))",
+	},
+	"render": {
+		build: func(b *sceneBuild) (engine.System, engine.DrawSystem) {
+			return nil, systems.NewRenderSystem(b.world)
+		},
+		code: "game.AddDrawSystem(systems.NewRenderSystem(w))",
+	},
+}
+
+// SceneComponentTypes returns the component types a SceneSpec may use.
+func SceneComponentTypes() []string {
+	return slices.Sorted(maps.Keys(sceneComponents))
+}
+
+// SceneSystemNames returns the systems a SceneSpec may register.
+func SceneSystemNames() []string {
+	return slices.Sorted(maps.Keys(sceneSystems))
+}
+
+// entityComponents returns every component of an entity except Position,
+// expanding the Type and Sprite shorthands into tag and sprite components.
+func entityComponents(e EntitySpec) []ComponentSpec {
+	comps := slices.Clone(e.Components)
+
+	hasType := func(t string) int {
+		return slices.IndexFunc(comps, func(c ComponentSpec) bool { return c.Type == t })
+	}
+
+	if e.Type != "" && hasType("tag") < 0 {
+		comps = slices.Insert(comps, 0, ComponentSpec{Type: "tag", Params: NodeParams{"name": e.Type}})
+	}
+
+	if e.Sprite != "" {
+		if i := hasType("sprite"); i < 0 {
+			comps = append(comps, ComponentSpec{Type: "sprite", Params: NodeParams{"asset": e.Sprite}})
+		} else if comps[i].Params.String("asset", "") == "" {
+			params := maps.Clone(comps[i].Params)
+			if params == nil {
+				params = NodeParams{}
+			}
+
+			params["asset"] = e.Sprite
+			comps[i] = ComponentSpec{Type: "sprite", Params: params}
+		}
+	}
+
+	return comps
+}
+
+func addComponent[T any](world *ecs.World, e ecs.Entity, comp T) {
+	ecs.NewMap[T](world).Add(e, &comp)
+}
+
+// goFloat formats a float as a Go literal.
+func goFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// parseHexColor parses "#rgb", "#rrggbb" or "#rrggbbaa".
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// ============================================================================
+// Player Control
+// ============================================================================
+
+// PlayerControlSystem sets the velocity of entities tagged "player" from
+// the move_left, move_right, move_up and move_down input actions. Speed
+// comes from the entity's Movement component, or 2 without one.
+type PlayerControlSystem struct {
+	Input *engine.InputManager
+
+	filter   *ecs.Filter2[components.Tag, components.Velocity]
+	movement *ecs.Map[components.Movement]
+}
+
+// NewPlayerControlSystem creates a player control system.
+func NewPlayerControlSystem(world *ecs.World, input *engine.InputManager) *PlayerControlSystem {
+	return &PlayerControlSystem{
+		Input:    input,
+		filter:   ecs.NewFilter2[components.Tag, components.Velocity](world),
+		movement: ecs.NewMap[components.Movement](world),
+	}
+}
+
+// Update implements engine.System.
+func (s *PlayerControlSystem) Update(_ *ecs.World) {
+	dx := s.Input.GetAxis("move_left", "move_right")
+	dy := s.Input.GetAxis("move_up", "move_down")
+
+	query := s.filter.Query()
+	for query.Next() {
+		tag, vel := query.Get()
+		if tag.Name != "player" {
+			continue
+		}
+
+		speed := 2.0
+		if e := query.Entity(); s.movement.Has(e) {
+			speed = s.movement.Get(e).GetMaxSpeed()
+		}
+
+		vel.X, vel.Y = dx*speed, dy*speed
+	}
+}
+
+// ============================================================================
+// Scene Loader
+// ============================================================================
+
+// SceneLoader instantiates a SceneSpec directly into a world, without
+// generating code.
+type SceneLoader struct {
+	// LoadImage loads image assets by path. When nil or failing, sprites
+	// use solid placeholder images.
+	LoadImage func(path string) (*ebiten.Image, error)
+}
+
+// NewSceneLoader creates a loader reading image assets from fsys.
+// Pass nil to always use placeholder images.
+func NewSceneLoader(fsys fs.FS) *SceneLoader {
+	l := &SceneLoader{}
+	if fsys != nil {
+		l.LoadImage = assets.NewLoader(fsys).LoadImage
+	}
+
+	return l
+}
+
+// SceneInstance is a spec instantiated into a world.
+type SceneInstance struct {
+	Spec        *SceneSpec
+	World       *ecs.World
+	Entities    map[string]ecs.Entity // By EntitySpec name
+	Input       *engine.InputManager
+	Systems     []engine.System
+	DrawSystems []engine.DrawSystem
+	Ticks       int64
+	Outcome     string // "", "win" or "lose"
+
+	background color.RGBA
+	tags       *ecs.Filter1[components.Tag]
+	health     *ecs.Filter2[components.Tag, components.Health]
+}
+
+// sceneBuild is the state shared by component and system builders.
+type sceneBuild struct {
+	loader *SceneLoader
+	spec   *SceneSpec
+	world  *ecs.World
+	input  *engine.InputManager
+	images map[string]*ebiten.Image
+}
+
+// image returns the image for a sprite component: its asset if it loads,
+// otherwise a solid placeholder.
+func (b *sceneBuild) image(p NodeParams) *ebiten.Image {
+	asset := p.String("asset", "")
+
+	if img, ok := b.images[asset]; ok {
+		return img
+	}
+
+	if asset != "" && b.loader.LoadImage != nil {
+		for _, a := range b.spec.Assets {
+			if a.Name != asset || a.Kind != "image" {
+				continue
+			}
+
+			if img, err := b.loader.LoadImage(a.Path); err == nil {
+				b.images[asset] = img
+
+				return img
+			}
+		}
+	}
+
+	c, err := parseHexColor(p.String("color", "#ffffff"))
+	if err != nil {
+		c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+
+	img := ebiten.NewImage(max(1, p.Int("width", 16)), max(1, p.Int("height", 16)))
+	img.Fill(c)
+
+	return img
+}
+
+// Instantiate validates spec and creates its entities, systems and input
+// bindings in world.
+func (l *SceneLoader) Instantiate(world *ecs.World, spec *SceneSpec) (*SceneInstance, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("scene %q: %w", spec.Name, err)
+	}
+
+	inst := &SceneInstance{
+		Spec:     spec,
+		World:    world,
+		Entities: make(map[string]ecs.Entity, len(spec.Entities)),
+		Input:    engine.NewInputManager(),
+		tags:     ecs.NewFilter1[components.Tag](world),
+		health:   ecs.NewFilter2[components.Tag, components.Health](world),
+	}
+
+	if spec.Background != "" {
+		inst.background, _ = parseHexColor(spec.Background)
+	}
+
+	for _, in := range spec.Input {
+		for _, name := range in.Keys {
+			key, _ := parseKey(name)
+			inst.Input.BindKey(in.Action, key)
+		}
+	}
+
+	b := &sceneBuild{
+		loader: l,
+		spec:   spec,
+		world:  world,
+		input:  inst.Input,
+		images: make(map[string]*ebiten.Image),
+	}
+
+	positions := ecs.NewMap[components.Position](world)
+
+	for _, es := range spec.Entities {
+		e := positions.NewEntity(&components.Position{X: es.Position[0], Y: es.Position[1]})
+
+		for _, c := range entityComponents(es) {
+			params := c.Params
+			if params == nil {
+				params = NodeParams{}
+			}
+
+			sceneComponents[c.Type].add(b, e, params)
+		}
+
+		inst.Entities[es.Name] = e
+	}
+
+	for _, name := range spec.Systems {
+		update, draw := sceneSystems[name].build(b)
+		if update != nil {
+			inst.Systems = append(inst.Systems, update)
+		}
+
+		if draw != nil {
+			inst.DrawSystems = append(inst.DrawSystems, draw)
+		}
+	}
+
+	return inst, nil
+}
+
+// Update reads input, runs the update systems and evaluates the win and
+// lose conditions. It does nothing once the scene has an outcome.
+func (s *SceneInstance) Update() {
+	if s.Outcome != "" {
+		return
+	}
+
+	s.Input.Update()
+
+	for _, sys := range s.Systems {
+		sys.Update(s.World)
+	}
+
+	s.Ticks++
+	s.Outcome = s.Evaluate()
+}
+
+// Draw clears the screen to the background color and runs the draw systems.
+func (s *SceneInstance) Draw(screen *ebiten.Image) {
+	screen.Fill(s.background)
+
+	for _, sys := range s.DrawSystems {
+		sys.Draw(s.World, screen)
+	}
+}
+
+// Evaluate returns "lose" if any lose condition holds, otherwise "win" if
+// any win condition holds, otherwise "".
+func (s *SceneInstance) Evaluate() string {
+	for _, c := range s.Spec.Lose {
+		if s.holds(c) {
+			return "lose"
+		}
+	}
+
+	for _, c := range s.Spec.Win {
+		if s.holds(c) {
+			return "win"
+		}
+	}
+
+	return ""
+}
+
+func (s *SceneInstance) holds(c ConditionSpec) bool {
+	var v float64
+
+	switch c.Type {
+	case "ticks":
+		v = float64(s.Ticks)
+	case "tag_count":
+		query := s.tags.Query()
+		for query.Next() {
+			if query.Get().Name == c.Tag {
+				v++
+			}
+		}
+	case "health":
+		found := false
+
+		query := s.health.Query()
+		for query.Next() {
+			tag, h := query.Get()
+			if tag.Name == c.Tag && (!found || float64(h.Current) < v) {
+				v, found = float64(h.Current), true
+			}
+		}
+	}
+
+	return compareCondition(v, c.Op, c.Value)
+}
+
+// SpecScene runs a SceneSpec as an engine.Scene, instantiating it into a
+// fresh world on every Load.
+type SpecScene struct {
+	Loader   *SceneLoader
+	Spec     *SceneSpec
+	Instance *SceneInstance
+}
+
+// NewSpecScene creates a scene for a spec.
+func NewSpecScene(loader *SceneLoader, spec *SceneSpec) *SpecScene {
+	return &SpecScene{Loader: loader, Spec: spec}
+}
+
+// Load implements engine.Scene.
+func (s *SpecScene) Load() error {
+	world := ecs.NewWorld()
+
+	inst, err := s.Loader.Instantiate(&world, s.Spec)
+	if err != nil {
+		return err
+	}
+
+	s.Instance = inst
+
+	return nil
+}
+
+// Unload implements engine.Scene.
+func (s *SpecScene) Unload() {
+	s.Instance = nil
+}
+
+// Update implements engine.Scene.
+func (s *SpecScene) Update() error {
+	if s.Instance == nil {
+		return errSceneNotLoaded
+	}
+
+	s.Instance.Update()
+
+	return nil
+}
+
+// Draw implements engine.Scene.
+func (s *SpecScene) Draw(screen *ebiten.Image) {
+	if s.Instance != nil {
+		s.Instance.Draw(screen)
+	}
+}
+
+var errSceneNotLoaded = errors.New("scene not loaded")
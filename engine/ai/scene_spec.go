@@ -0,0 +1,453 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// SceneSpecVersion is the current SceneSpec format version.
+const SceneSpecVersion = 1
+
+// ============================================================================
+// Scene Spec
+// ============================================================================
+
+// SceneSpec describes a scene in data. It can be written by hand, produced
+// by an LLM under SceneSpecSchema, turned into Go source by SceneGenerator
+// or instantiated directly by a SceneLoader.
+//
+//	version: 1
+//	name: Coin Run
+//	width: 320
+//	height: 240
+//	assets:
+//	  - {name: hero, kind: image, path: sprites/hero.png}
+//	entities:
+//	  - name: Player
+//	    type: player
+//	    position: [160, 120]
+//	    sprite: hero
+//	    components:
+//	      - {type: velocity}
+//	      - {type: health, params: {max: 3}}
+//	systems: [player_control, movement, render]
+//	input:
+//	  - {action: move_left, keys: [ArrowLeft, A]}
+//	win:
+//	  - {type: tag_count, tag: coin, op: "==", value: 0}
+type SceneSpec struct {
+	Version     int             `json:"version"               yaml:"version"`
+	Name        string          `json:"name"                  yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Width       int             `json:"width"                 yaml:"width"`
+	Height      int             `json:"height"                yaml:"height"`
+	Background  string          `json:"background,omitempty"  yaml:"background,omitempty"` // Hex color, e.g. "#202030"
+	Assets      []AssetSpec     `json:"assets,omitempty"      yaml:"assets,omitempty"`
+	Entities    []EntitySpec    `json:"entities"              yaml:"entities"`
+	Systems     []string        `json:"systems,omitempty"     yaml:"systems,omitempty"`
+	Input       []InputSpec     `json:"input,omitempty"       yaml:"input,omitempty"`
+	Win         []ConditionSpec `json:"win,omitempty"         yaml:"win,omitempty"`
+	Lose        []ConditionSpec `json:"lose,omitempty"        yaml:"lose,omitempty"`
+}
+
+// AssetSpec names a file used by the scene.
+type AssetSpec struct {
+	Name string `json:"name" yaml:"name"`
+	Kind string `json:"kind" yaml:"kind"` // "image" or "sound"
+	Path string `json:"path" yaml:"path"`
+}
+
+// EntitySpec describes an entity to generate.
+type EntitySpec struct {
+	Name       string            `json:"name"                 yaml:"name"`
+	Type       string            `json:"type,omitempty"       yaml:"type,omitempty"` // Becomes the entity's Tag, e.g. "player"
+	Position   [2]float64        `json:"position"             yaml:"position,flow"`
+	Sprite     string            `json:"sprite,omitempty"     yaml:"sprite,omitempty"` // Image asset name
+	Components []ComponentSpec   `json:"components,omitempty" yaml:"components,omitempty"`
+	Properties map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+}
+
+// ComponentSpec adds one component to an entity. See SceneComponentTypes.
+type ComponentSpec struct {
+	Type   string     `json:"type"             yaml:"type"`
+	Params NodeParams `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// InputSpec binds keyboard keys to an input action.
+type InputSpec struct {
+	Action string   `json:"action" yaml:"action"`
+	Keys   []string `json:"keys"   yaml:"keys,flow"` // ebiten key names, e.g. "ArrowLeft", "Space"
+}
+
+// ConditionSpec is a win or lose test evaluated after every update.
+//
+// Types:
+//   - tag_count: number of entities tagged Tag compared with Value
+//   - health: lowest health among entities tagged Tag compared with Value
+//   - ticks: updates since the scene loaded compared with Value
+type ConditionSpec struct {
+	Type  string  `json:"type"          yaml:"type"`
+	Tag   string  `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Op    string  `json:"op"            yaml:"op"`
+	Value float64 `json:"value"         yaml:"value"`
+}
+
+// sceneConditionTypes lists the supported ConditionSpec types.
+var sceneConditionTypes = []string{"tag_count", "health", "ticks"}
+
+// sceneConditionOps lists the supported ConditionSpec operators.
+var sceneConditionOps = []string{"==", "!=", "<", "<=", ">", ">="}
+
+// ============================================================================
+// Parsing and Serialization
+// ============================================================================
+
+// ParseSceneSpecJSON decodes and upgrades a JSON scene spec.
+func ParseSceneSpecJSON(data []byte) (*SceneSpec, error) {
+	var spec SceneSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse scene spec: %w", err)
+	}
+
+	return upgradeSceneSpec(&spec)
+}
+
+// ParseSceneSpecYAML decodes and upgrades a YAML scene spec.
+func ParseSceneSpecYAML(data []byte) (*SceneSpec, error) {
+	var spec SceneSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse scene spec: %w", err)
+	}
+
+	return upgradeSceneSpec(&spec)
+}
+
+// LoadSceneSpecFile reads a scene spec, choosing the format by extension
+// (.yaml/.yml or .json).
+func LoadSceneSpecFile(path string) (*SceneSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseSceneSpecYAML(data)
+	default:
+		return ParseSceneSpecJSON(data)
+	}
+}
+
+// JSON encodes the spec as indented JSON.
+func (s *SceneSpec) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// YAML encodes the spec as YAML.
+func (s *SceneSpec) YAML() ([]byte, error) {
+	return yaml.Marshal(s)
+}
+
+// Save writes the spec, choosing the format by extension like LoadSceneSpecFile.
+func (s *SceneSpec) Save(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = s.YAML()
+	default:
+		data, err = s.JSON()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// upgradeSceneSpec migrates older spec versions to SceneSpecVersion.
+// Version 0 is the unversioned format used before specs were serialized;
+// it only lacked the version field.
+func upgradeSceneSpec(spec *SceneSpec) (*SceneSpec, error) {
+	if spec.Version > SceneSpecVersion {
+		return nil, fmt.Errorf("scene spec %q: version %d is newer than supported version %d",
+			spec.Name, spec.Version, SceneSpecVersion)
+	}
+
+	if spec.Version == 0 {
+		spec.Version = SceneSpecVersion
+	}
+
+	return spec, nil
+}
+
+// ============================================================================
+// Validation
+// ============================================================================
+
+// Validate checks the spec against the supported components, systems, keys
+// and conditions. All problems are reported, each prefixed with its path,
+// e.g. "entities[2].components[0]: unknown component type \"foo\"".
+func (s *SceneSpec) Validate() error {
+	var errs []error
+
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if s.Version != SceneSpecVersion {
+		fail("version", "unsupported version %d (want %d)", s.Version, SceneSpecVersion)
+	}
+
+	if s.Name == "" {
+		fail("name", "required")
+	}
+
+	if s.Width <= 0 || s.Height <= 0 {
+		fail("size", "width and height must be positive, got %dx%d", s.Width, s.Height)
+	}
+
+	if s.Background != "" {
+		if _, err := parseHexColor(s.Background); err != nil {
+			fail("background", "%v", err)
+		}
+	}
+
+	images := make(map[string]bool)
+
+	for i, a := range s.Assets {
+		path := fmt.Sprintf("assets[%d]", i)
+
+		switch {
+		case a.Name == "":
+			fail(path, "name required")
+		case images[a.Name]:
+			fail(path, "duplicate asset %q", a.Name)
+		}
+
+		switch a.Kind {
+		case "image":
+			images[a.Name] = true
+		case "sound":
+		default:
+			fail(path, "unknown asset kind %q", a.Kind)
+		}
+
+		if a.Path == "" {
+			fail(path, "path required")
+		}
+	}
+
+	names := make(map[string]bool)
+
+	for i, e := range s.Entities {
+		path := fmt.Sprintf("entities[%d]", i)
+
+		if e.Name == "" {
+			fail(path, "name required")
+		} else if names[e.Name] {
+			fail(path, "duplicate entity %q", e.Name)
+		}
+
+		names[e.Name] = true
+
+		if e.Sprite != "" && !images[e.Sprite] {
+			fail(path, "unknown image asset %q", e.Sprite)
+		}
+
+		for j, c := range e.Components {
+			cpath := fmt.Sprintf("%s.components[%d]", path, j)
+
+			def, ok := sceneComponents[c.Type]
+			if !ok {
+				fail(cpath, "unknown component type %q", c.Type)
+
+				continue
+			}
+
+			for key := range c.Params {
+				if !slices.Contains(def.params, key) {
+					fail(cpath, "unknown %s param %q", c.Type, key)
+				}
+			}
+
+			if asset := c.Params.String("asset", ""); asset != "" && !images[asset] {
+				fail(cpath, "unknown image asset %q", asset)
+			}
+		}
+
+		seen := make(map[string]bool)
+
+		for _, c := range entityComponents(e) {
+			if seen[c.Type] {
+				fail(path, "duplicate %s component", c.Type)
+			}
+
+			seen[c.Type] = true
+		}
+	}
+
+	for i, name := range s.Systems {
+		if _, ok := sceneSystems[name]; !ok {
+			fail(fmt.Sprintf("systems[%d]", i), "unknown system %q", name)
+		}
+	}
+
+	for i, in := range s.Input {
+		path := fmt.Sprintf("input[%d]", i)
+
+		if in.Action == "" {
+			fail(path, "action required")
+		}
+
+		if len(in.Keys) == 0 {
+			fail(path, "at least one key required")
+		}
+
+		for _, k := range in.Keys {
+			if _, err := parseKey(k); err != nil {
+				fail(path, "%v", err)
+			}
+		}
+	}
+
+	for _, group := range []struct {
+		name  string
+		conds []ConditionSpec
+	}{{"win", s.Win}, {"lose", s.Lose}} {
+		for i, c := range group.conds {
+			path := fmt.Sprintf("%s[%d]", group.name, i)
+
+			if !slices.Contains(sceneConditionTypes, c.Type) {
+				fail(path, "unknown condition type %q", c.Type)
+			}
+
+			if !slices.Contains(sceneConditionOps, c.Op) {
+				fail(path, "unknown operator %q", c.Op)
+			}
+
+			if c.Type != "ticks" && c.Tag == "" {
+				fail(path, "%s condition needs a tag", c.Type)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseKey parses an ebiten key name such as "ArrowLeft" or "A".
+func parseKey(name string) (ebiten.Key, error) {
+	var k ebiten.Key
+	if err := k.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown key %q", name)
+	}
+
+	return k, nil
+}
+
+// compareCondition applies a ConditionSpec operator.
+func compareCondition(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+
+	return false
+}
+
+// ============================================================================
+// LLM Schema
+// ============================================================================
+
+// SceneSpecSchema returns a JSON schema for SceneSpec suitable for
+// structured LLM output. Component, system and condition names are
+// restricted to the supported sets.
+func SceneSpecSchema() JSONSchema {
+	str := map[string]any{"type": "string"}
+	num := map[string]any{"type": "number"}
+	enum := func(values []string) map[string]any {
+		e := make([]any, len(values))
+		for i, v := range values {
+			e[i] = v
+		}
+
+		return map[string]any{"type": "string", "enum": e}
+	}
+	object := func(props map[string]any, required ...string) map[string]any {
+		req := make([]any, len(required))
+		for i, r := range required {
+			req[i] = r
+		}
+
+		return map[string]any{"type": "object", "properties": props, "required": req}
+	}
+
+	var paramDocs []string
+
+	for _, name := range SceneComponentTypes() {
+		paramDocs = append(paramDocs, fmt.Sprintf("%s(%s)", name, strings.Join(sceneComponents[name].params, ", ")))
+	}
+
+	condition := object(map[string]any{
+		"type":  enum(sceneConditionTypes),
+		"tag":   str,
+		"op":    enum(sceneConditionOps),
+		"value": num,
+	}, "type", "op", "value")
+
+	return JSONSchema(object(map[string]any{
+		"version":     map[string]any{"type": "integer", "enum": []any{SceneSpecVersion}},
+		"name":        str,
+		"description": str,
+		"width":       map[string]any{"type": "integer", "minimum": 1},
+		"height":      map[string]any{"type": "integer", "minimum": 1},
+		"background":  map[string]any{"type": "string", "description": "hex color like #202030"},
+		"assets": map[string]any{"type": "array", "items": object(map[string]any{
+			"name": str,
+			"kind": enum([]string{"image", "sound"}),
+			"path": str,
+		}, "name", "kind", "path")},
+		"entities": map[string]any{"type": "array", "items": object(map[string]any{
+			"name":     str,
+			"type":     map[string]any{"type": "string", "description": "tag such as player, enemy, coin"},
+			"position": map[string]any{"type": "array", "items": num, "minItems": 2, "maxItems": 2},
+			"sprite":   map[string]any{"type": "string", "description": "name of an image asset"},
+			"components": map[string]any{"type": "array", "items": object(map[string]any{
+				"type": enum(SceneComponentTypes()),
+				"params": map[string]any{
+					"type":        "object",
+					"description": "component params: " + strings.Join(paramDocs, "; "),
+				},
+			}, "type")},
+		}, "name", "position")},
+		"systems": map[string]any{"type": "array", "items": enum(SceneSystemNames())},
+		"input": map[string]any{"type": "array", "items": object(map[string]any{
+			"action": str,
+			"keys":   map[string]any{"type": "array", "items": str, "minItems": 1},
+		}, "action", "keys")},
+		"win":  map[string]any{"type": "array", "items": condition},
+		"lose": map[string]any{"type": "array", "items": condition},
+	}, "version", "name", "width", "height", "entities"))
+}
@@ -34,6 +34,13 @@ type RunResult struct {
 	GameOver   bool          `json:"game_over"`
 	Anomalies  []Anomaly     `json:"anomalies"`
 	Stats      ObserverStats `json:"stats"`
+
+	// Violations holds the first failure of each invariant, with its
+	// counterexample.
+	Violations []InvariantViolation `json:"violations,omitempty"`
+
+	// Decisions holds model reasoning when the player is a ReasoningPlayer.
+	Decisions []AgentDecision `json:"decisions,omitempty"`
 }
 
 // QAReport is the final report from a QA session.
@@ -44,11 +51,12 @@ type QAReport struct {
 	Runs        []RunResult   `json:"runs"`
 
 	// Aggregated
-	TotalAnomalies int    `json:"total_anomalies"`
-	BestScore      int    `json:"best_score"`
-	WorstScore     int    `json:"worst_score"`
-	AvgScore       int    `json:"avg_score"`
-	Conclusion     string `json:"conclusion"`
+	TotalAnomalies  int    `json:"total_anomalies"`
+	TotalViolations int    `json:"total_violations"`
+	BestScore       int    `json:"best_score"`
+	WorstScore      int    `json:"worst_score"`
+	AvgScore        int    `json:"avg_score"`
+	Conclusion      string `json:"conclusion"`
 }
 
 // QASession orchestrates automated QA testing.
@@ -58,6 +66,9 @@ type QASession struct {
 	observer *Observer
 	detector *AnomalyDetector
 
+	invariants *InvariantSet
+	onStep     []func(run int, tick int64)
+
 	config SessionConfig
 	runs   []RunResult
 }
@@ -87,6 +98,17 @@ func (s *QASession) SetDetector(detector *AnomalyDetector) {
 	s.detector = detector
 }
 
+// SetInvariants checks the given invariants at every tick of every run.
+func (s *QASession) SetInvariants(invariants *InvariantSet) {
+	s.invariants = invariants
+}
+
+// OnStep registers a callback run after every game step, e.g. to capture
+// frames. run is the run index and tick the step within it.
+func (s *QASession) OnStep(fn func(run int, tick int64)) {
+	s.onStep = append(s.onStep, fn)
+}
+
 // Run executes the full QA session.
 func (s *QASession) Run() QAReport {
 	s.runs = make([]RunResult, 0, s.config.Runs)
@@ -116,6 +138,16 @@ func (s *QASession) runSingle(index int) RunResult {
 		player = NewRandomPlayer(time.Now().UnixNano())
 	}
 
+	var monitor *InvariantMonitor
+	if s.invariants != nil {
+		monitor = s.invariants.NewMonitor()
+	}
+
+	reasoner, _ := player.(ReasoningPlayer)
+	if reasoner != nil {
+		reasoner.TakeDecisions() // Drop decisions from a previous run
+	}
+
 	// Run game loop
 	var tick int64
 	for tick = 0; tick < int64(s.config.MaxTicks); tick++ {
@@ -125,18 +157,36 @@ func (s *QASession) runSingle(index int) RunResult {
 		// Decide and perform action
 		available := s.adapter.AvailableActions()
 		action := player.DecideAction(state, available)
+
+		// Check invariants while the world still matches state
+		if monitor != nil && len(monitor.Observe(tick, state, action)) > 0 && s.config.StopOnAnomaly {
+			break
+		}
+
 		s.adapter.PerformAction(action)
 
 		// Step game
 		s.adapter.Step()
 
+		for _, fn := range s.onStep {
+			fn(index, tick)
+		}
+
 		// Record observation
 		if s.config.RecordEvery <= 1 || tick%int64(s.config.RecordEvery) == 0 {
 			s.observer.Record(tick, state, action)
 		}
 
 		// Check game over
-		if s.adapter.IsGameOver() {
+		gameOver := s.adapter.IsGameOver()
+
+		// The loop ends here, so also check the state the last step made;
+		// otherwise a violation caused by the final step is never seen.
+		if monitor != nil && (gameOver || tick+1 == int64(s.config.MaxTicks)) {
+			monitor.Observe(tick+1, s.adapter.GetState(), ActionNone)
+		}
+
+		if gameOver {
 			result.GameOver = true
 
 			break
@@ -159,6 +209,14 @@ func (s *QASession) runSingle(index int) RunResult {
 	result.FinalScore = s.adapter.GetScore()
 	result.Stats = s.observer.Stats()
 
+	if reasoner != nil {
+		result.Decisions = reasoner.TakeDecisions()
+	}
+
+	if monitor != nil {
+		result.Violations = monitor.Violations()
+	}
+
 	// Full anomaly detection
 	if len(result.Anomalies) == 0 {
 		result.Anomalies = s.detector.Analyze(s.observer.History())
@@ -181,6 +239,7 @@ func (s *QASession) generateReport() QAReport {
 
 	for _, run := range s.runs {
 		report.TotalAnomalies += len(run.Anomalies)
+		report.TotalViolations += len(run.Violations)
 		totalScore += run.FinalScore
 
 		if run.FinalScore > report.BestScore {
@@ -197,7 +256,9 @@ func (s *QASession) generateReport() QAReport {
 	}
 
 	// Determine conclusion
-	if report.TotalAnomalies == 0 {
+	if report.TotalViolations > 0 {
+		report.Conclusion = "FAIL - Invariants violated"
+	} else if report.TotalAnomalies == 0 {
 		report.Conclusion = "PASS - No anomalies detected"
 	} else if report.TotalAnomalies <= 3 {
 		report.Conclusion = "WARNING - Minor issues found"
@@ -222,6 +283,11 @@ func (r *QAReport) GenerateMarkdown() string {
 	sb.WriteString("| Metric | Value |\n")
 	sb.WriteString("|--------|-------|\n")
 	sb.WriteString(fmt.Sprintf("| Total Anomalies | %d |\n", r.TotalAnomalies))
+
+	if r.TotalViolations > 0 {
+		sb.WriteString(fmt.Sprintf("| Invariant Violations | %d |\n", r.TotalViolations))
+	}
+
 	sb.WriteString(fmt.Sprintf("| Best Score | %d |\n", r.BestScore))
 	sb.WriteString(fmt.Sprintf("| Avg Score | %d |\n", r.AvgScore))
 	sb.WriteString(fmt.Sprintf("| Worst Score | %d |\n", r.WorstScore))
@@ -244,6 +310,32 @@ func (r *QAReport) GenerateMarkdown() string {
 			}
 		}
 
+		if len(run.Violations) > 0 {
+			sb.WriteString(fmt.Sprintf("- Invariant violations: %d\n\n", len(run.Violations)))
+
+			for _, v := range run.Violations {
+				v.WriteMarkdown(&sb)
+			}
+		}
+
+		if len(run.Decisions) > 0 {
+			sb.WriteString(fmt.Sprintf("- Agent decisions: %d\n", len(run.Decisions)))
+
+			for _, d := range run.Decisions {
+				sb.WriteString(fmt.Sprintf("  - tick %d: %v", d.Tick, d.Actions))
+
+				if d.Reasoning != "" {
+					sb.WriteString(" — " + d.Reasoning)
+				}
+
+				if d.Error != "" {
+					sb.WriteString(" (error: " + d.Error + ")")
+				}
+
+				sb.WriteString("\n")
+			}
+		}
+
 		sb.WriteString("\n")
 	}
 
@@ -0,0 +1,886 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+)
+
+// Permission scopes of the task verbs.
+const (
+	ScopeCombat   = "combat"   // attack
+	ScopeInteract = "interact" // pick_up, use, talk
+)
+
+// TaskStatus is the state of a multi-tick action.
+type TaskStatus string
+
+const (
+	TaskRunning   TaskStatus = "running"
+	TaskSucceeded TaskStatus = "succeeded"
+	TaskFailed    TaskStatus = "failed"
+	TaskCanceled  TaskStatus = "canceled"
+)
+
+// Done reports whether the task has finished.
+func (s TaskStatus) Done() bool { return s != TaskRunning }
+
+// TaskProgress reports a task back to the agent that started it.
+type TaskProgress struct {
+	ID       int        `json:"id"`
+	Verb     string     `json:"verb"`
+	Entity   uint32     `json:"entity"`
+	Status   TaskStatus `json:"status"`
+	Progress float64    `json:"progress"` // 0..1
+	Message  string     `json:"message,omitempty"`
+	Ticks    int        `json:"ticks"`
+}
+
+// Task is an action that runs over several ticks under a TaskRunner.
+type Task interface {
+	Name() string
+	// Tick advances the task one tick and returns its status, progress in
+	// [0, 1] and a short message for the agent.
+	Tick(ctx *TaskContext) (TaskStatus, float64, string)
+}
+
+// TaskStarter is implemented by tasks that validate or plan when started.
+// An error rejects the task before it runs.
+type TaskStarter interface {
+	Start(ctx *TaskContext) error
+}
+
+// TaskTimeouter is implemented by tasks whose time limit differs from the
+// runner's Timeout. Timeout returns the limit in ticks, 0 for none.
+type TaskTimeouter interface {
+	Timeout(r *TaskRunner) int
+}
+
+// TaskContext is what a task sees.
+type TaskContext struct {
+	World  *ecs.World
+	Runner *TaskRunner
+	Entity ecs.Entity
+	Ticks  int // Ticks run so far
+}
+
+// Position returns the position of e, or nil.
+func (c *TaskContext) Position(e ecs.Entity) *components.Position {
+	if !c.World.Alive(e) || !c.Runner.pos.Has(e) {
+		return nil
+	}
+
+	return c.Runner.pos.Get(e)
+}
+
+// ============================================================================
+// Runner
+// ============================================================================
+
+// TaskRunner runs agent tasks, one per entity. Add it to the game loop
+// with AddSystem; each Update advances every task one tick. Starting a
+// task on an entity that already has one cancels the old task.
+type TaskRunner struct {
+	// Pathfinder plans MoveTo and approach paths, e.g. a
+	// systems.PathfindingSystem or NavMesh. Nil walks straight lines.
+	Pathfinder systems.PathFinder
+	// Combat performs attacks. The game is responsible for advancing its
+	// clock with CombatSystem.Update.
+	Combat *systems.CombatSystem
+	// Narrative starts dialogues for Talk.
+	Narrative *NarrativeController
+
+	Speed          float64 // Pixels per tick (default 2)
+	Reach          float64 // Interaction distance (default 24)
+	Timeout        int     // Ticks before a task fails, unless it is a TaskTimeouter (default 600, 0 = none)
+	RepathDistance float64 // Target movement that triggers a replan (default 16)
+
+	// UseItem applies an item used by entity. The default heals Health by
+	// the item's "heal" stat.
+	UseItem func(world *ecs.World, entity ecs.Entity, item components.Item) error
+
+	// OnProgress is called when a task starts, finishes, or its message
+	// changes.
+	OnProgress func(TaskProgress)
+
+	world    *ecs.World
+	all      *ecs.Filter0
+	pos      *ecs.Map[components.Position]
+	health   *ecs.Map[components.Health]
+	inv      *ecs.Map[components.Inventory]
+	items    *ecs.Map[components.Item]
+	combat   *ecs.Map[components.Combat]
+	interact *ecs.Map[components.Interactable]
+
+	active  []*runningTask
+	records []*TaskProgress
+	nextID  int
+}
+
+type runningTask struct {
+	task   Task
+	entity ecs.Entity
+	record *TaskProgress
+}
+
+// maxTaskRecords bounds the finished tasks kept for Progress.
+const maxTaskRecords = 256
+
+// NewTaskRunner creates a task runner for world.
+func NewTaskRunner(world *ecs.World) *TaskRunner {
+	return &TaskRunner{
+		Speed:          2,
+		Reach:          24,
+		Timeout:        600,
+		RepathDistance: 16,
+		UseItem:        healItem,
+		world:          world,
+		all:            ecs.NewFilter0(world),
+		pos:            ecs.NewMap[components.Position](world),
+		health:         ecs.NewMap[components.Health](world),
+		inv:            ecs.NewMap[components.Inventory](world),
+		items:          ecs.NewMap[components.Item](world),
+		combat:         ecs.NewMap[components.Combat](world),
+		interact:       ecs.NewMap[components.Interactable](world),
+	}
+}
+
+// Start begins task for entity and returns its id.
+func (r *TaskRunner) Start(entity ecs.Entity, task Task) (int, error) {
+	if !r.world.Alive(entity) {
+		return 0, fmt.Errorf("entity %d is gone", entity.ID())
+	}
+
+	if starter, ok := task.(TaskStarter); ok {
+		if err := starter.Start(&TaskContext{World: r.world, Runner: r, Entity: entity}); err != nil {
+			return 0, fmt.Errorf("%s: %w", task.Name(), err)
+		}
+	}
+
+	for _, t := range r.active {
+		if t.entity == entity {
+			r.finish(t, TaskCanceled, "replaced by "+task.Name())
+		}
+	}
+
+	r.compact()
+
+	r.nextID++
+	record := &TaskProgress{ID: r.nextID, Verb: task.Name(), Entity: entity.ID(), Status: TaskRunning, Message: "started"}
+	r.active = append(r.active, &runningTask{task: task, entity: entity, record: record})
+	r.records = append(r.records, record)
+	r.report(record)
+
+	return record.ID, nil
+}
+
+// Cancel stops a running task. It reports whether the task was running.
+func (r *TaskRunner) Cancel(id int) bool {
+	for _, t := range r.active {
+		if t.record.ID == id && !t.record.Status.Done() {
+			r.finish(t, TaskCanceled, "canceled")
+			r.compact()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Progress returns the latest report of a task.
+func (r *TaskRunner) Progress(id int) (TaskProgress, bool) {
+	for _, rec := range r.records {
+		if rec.ID == id {
+			return *rec, true
+		}
+	}
+
+	return TaskProgress{}, false
+}
+
+// Tasks returns the reports of the tasks of entity, or of all entities
+// when entity is 0, oldest first.
+func (r *TaskRunner) Tasks(entity uint32) []TaskProgress {
+	out := make([]TaskProgress, 0, len(r.records))
+
+	for _, rec := range r.records {
+		if entity == 0 || rec.Entity == entity {
+			out = append(out, *rec)
+		}
+	}
+
+	return out
+}
+
+// Running reports whether entity has an unfinished task.
+func (r *TaskRunner) Running(entity ecs.Entity) bool {
+	for _, t := range r.active {
+		if t.entity == entity {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Update advances every task one tick.
+func (r *TaskRunner) Update(world *ecs.World) {
+	for _, t := range r.active {
+		if t.record.Status.Done() {
+			continue
+		}
+
+		if !world.Alive(t.entity) {
+			r.finish(t, TaskFailed, "entity is gone")
+
+			continue
+		}
+
+		ctx := &TaskContext{World: world, Runner: r, Entity: t.entity, Ticks: t.record.Ticks}
+		status, progress, msg := t.task.Tick(ctx)
+
+		t.record.Ticks++
+		t.record.Progress = math.Max(0, math.Min(1, progress))
+
+		if status == TaskSucceeded {
+			t.record.Progress = 1
+		}
+
+		if limit := r.timeout(t.task); status == TaskRunning && limit > 0 && t.record.Ticks >= limit {
+			status, msg = TaskFailed, fmt.Sprintf("timed out after %d ticks", t.record.Ticks)
+		}
+
+		if status.Done() {
+			r.finish(t, status, msg)
+
+			continue
+		}
+
+		if msg != t.record.Message {
+			t.record.Message = msg
+			r.report(t.record)
+		}
+	}
+
+	r.compact()
+}
+
+// timeout is the tick limit of task, 0 for none.
+func (r *TaskRunner) timeout(task Task) int {
+	if tt, ok := task.(TaskTimeouter); ok && r.Timeout > 0 {
+		return tt.Timeout(r)
+	}
+
+	return r.Timeout
+}
+
+// Entity finds the live entity with the given id.
+func (r *TaskRunner) Entity(id uint32) (ecs.Entity, bool) {
+	query := r.all.Query()
+	for query.Next() {
+		if e := query.Entity(); e.ID() == id {
+			query.Close()
+
+			return e, true
+		}
+	}
+
+	return ecs.Entity{}, false
+}
+
+func (r *TaskRunner) finish(t *runningTask, status TaskStatus, msg string) {
+	t.record.Status = status
+	t.record.Message = msg
+	r.report(t.record)
+}
+
+// compact drops finished tasks and old records.
+func (r *TaskRunner) compact() {
+	active := r.active[:0]
+
+	for _, t := range r.active {
+		if !t.record.Status.Done() {
+			active = append(active, t)
+		}
+	}
+
+	r.active = active
+
+	for len(r.records) > maxTaskRecords {
+		i := 0
+		for i < len(r.records)-1 && !r.records[i].Status.Done() {
+			i++
+		}
+
+		r.records = append(r.records[:i], r.records[i+1:]...)
+	}
+}
+
+func (r *TaskRunner) report(rec *TaskProgress) {
+	if r.OnProgress != nil {
+		r.OnProgress(*rec)
+	}
+}
+
+func (r *TaskRunner) findPath(x0, y0, x1, y1 float64) *systems.Path {
+	if r.Pathfinder == nil {
+		return &systems.Path{Points: [][2]float64{{x1, y1}}, Valid: true, Length: math.Hypot(x1-x0, y1-y0)}
+	}
+
+	path := r.Pathfinder.FindPath(x0, y0, x1, y1)
+	if path == nil || !path.Valid {
+		return &systems.Path{}
+	}
+
+	// Grid paths end at the goal cell's center; finish on the exact goal.
+	if n := len(path.Points); n == 0 || path.Points[n-1] != [2]float64{x1, y1} {
+		path.Points = append(path.Points, [2]float64{x1, y1})
+	}
+
+	return path
+}
+
+// reach returns the interaction distance for target.
+func (r *TaskRunner) reach(target ecs.Entity) float64 {
+	if r.interact.Has(target) {
+		if d := r.interact.Get(target).Range; d > 0 {
+			return d
+		}
+	}
+
+	return r.Reach
+}
+
+func healItem(world *ecs.World, entity ecs.Entity, item components.Item) error {
+	heal := item.Stats["heal"]
+	if heal <= 0 {
+		return fmt.Errorf("%s has no effect", item.ID)
+	}
+
+	health := ecs.NewMap[components.Health](world)
+	if !health.Has(entity) {
+		return errors.New("entity has no Health")
+	}
+
+	h := health.Get(entity)
+	h.Current = min(h.Max, h.Current+int(heal))
+
+	return nil
+}
+
+// ============================================================================
+// Navigation
+// ============================================================================
+
+// navigator walks an entity along a planned path, replanning when the
+// goal moves.
+type navigator struct {
+	follower *systems.PathFollower
+	goal     [2]float64
+}
+
+// step moves the entity one tick toward (x, y) until it is within stop.
+// It returns the remaining distance, or false when there is no path.
+func (n *navigator) step(ctx *TaskContext, x, y, stop float64) (float64, bool) {
+	pos := ctx.Position(ctx.Entity)
+	if pos == nil {
+		return 0, false
+	}
+
+	dist := math.Hypot(x-pos.X, y-pos.Y)
+	if dist <= stop {
+		return dist, true
+	}
+
+	r := ctx.Runner
+	if n.follower == nil || math.Hypot(x-n.goal[0], y-n.goal[1]) > r.RepathDistance {
+		path := r.findPath(pos.X, pos.Y, x, y)
+		if !path.Valid {
+			return dist, false
+		}
+
+		n.follower = systems.NewPathFollower(r.Speed)
+		n.follower.Threshold = r.Speed
+		n.follower.SetPath(path)
+		n.goal = [2]float64{x, y}
+	}
+
+	dx, dy, end := n.follower.GetNextMove(pos.X, pos.Y)
+	if end {
+		// Close the last stretch directly.
+		step := math.Min(r.Speed, dist)
+		dx, dy = (x-pos.X)/dist*step, (y-pos.Y)/dist*step
+	}
+
+	pos.X += dx
+	pos.Y += dy
+
+	return math.Hypot(x-pos.X, y-pos.Y), true
+}
+
+// approach moves toward target until within stop, reporting whether it
+// is in range.
+func (n *navigator) approach(ctx *TaskContext, target ecs.Entity, stop float64) (inRange bool, err error) {
+	tp := ctx.Position(target)
+	if tp == nil {
+		return false, errors.New("target is gone")
+	}
+
+	if pos := ctx.Position(ctx.Entity); pos != nil && math.Hypot(tp.X-pos.X, tp.Y-pos.Y) <= stop {
+		return true, nil
+	}
+
+	dist, ok := n.step(ctx, tp.X, tp.Y, stop)
+	if !ok {
+		return false, errors.New("no path to target")
+	}
+
+	return dist <= stop, nil
+}
+
+// ============================================================================
+// Tasks
+// ============================================================================
+
+// MoveToTask walks to a position along a planned path.
+type MoveToTask struct {
+	X, Y float64
+
+	nav    navigator
+	total  float64
+	length float64 // Planned path length
+}
+
+func (t *MoveToTask) Name() string { return "move_to" }
+
+// Timeout allows the time needed to walk the planned path on top of the
+// runner's Timeout.
+func (t *MoveToTask) Timeout(r *TaskRunner) int {
+	if r.Speed <= 0 {
+		return r.Timeout
+	}
+
+	return r.Timeout + int(math.Ceil(t.length/r.Speed))
+}
+
+func (t *MoveToTask) Start(ctx *TaskContext) error {
+	pos := ctx.Position(ctx.Entity)
+	if pos == nil {
+		return errors.New("entity has no Position")
+	}
+
+	t.total = math.Hypot(t.X-pos.X, t.Y-pos.Y)
+
+	path := ctx.Runner.findPath(pos.X, pos.Y, t.X, t.Y)
+	if !path.Valid {
+		return fmt.Errorf("no path to (%.0f, %.0f)", t.X, t.Y)
+	}
+
+	t.length = math.Max(path.Length, t.total)
+
+	return nil
+}
+
+func (t *MoveToTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	remaining, ok := t.nav.step(ctx, t.X, t.Y, 0.5)
+	if !ok {
+		return TaskFailed, 0, fmt.Sprintf("no path to (%.0f, %.0f)", t.X, t.Y)
+	}
+
+	if remaining <= 0.5 {
+		pos := ctx.Position(ctx.Entity)
+		pos.X, pos.Y = t.X, t.Y
+
+		return TaskSucceeded, 1, "arrived"
+	}
+
+	return TaskRunning, 1 - remaining/math.Max(t.total, remaining), "moving"
+}
+
+// FollowTask keeps within Distance of a target for Ticks ticks, or until
+// canceled when Ticks is 0.
+type FollowTask struct {
+	Target   ecs.Entity
+	Distance float64 // 0 = the runner's Reach
+	Ticks    int
+
+	nav navigator
+}
+
+func (t *FollowTask) Name() string { return "follow" }
+
+// Timeout is 0: following ends after Ticks or when canceled.
+func (t *FollowTask) Timeout(*TaskRunner) int { return 0 }
+
+func (t *FollowTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	dist := t.Distance
+	if dist <= 0 {
+		dist = ctx.Runner.Reach
+	}
+
+	inRange, err := t.nav.approach(ctx, t.Target, dist)
+	if err != nil {
+		return TaskFailed, 0, err.Error()
+	}
+
+	progress := 0.0
+	if t.Ticks > 0 {
+		if ctx.Ticks+1 >= t.Ticks {
+			return TaskSucceeded, 1, fmt.Sprintf("followed for %d ticks", t.Ticks)
+		}
+
+		progress = float64(ctx.Ticks+1) / float64(t.Ticks)
+	}
+
+	if inRange {
+		return TaskRunning, progress, "following"
+	}
+
+	return TaskRunning, progress, "catching up"
+}
+
+// AttackTask approaches a target and attacks it with the runner's
+// CombatSystem until its health reaches zero.
+type AttackTask struct {
+	Target ecs.Entity
+
+	nav  navigator
+	hits int
+}
+
+func (t *AttackTask) Name() string { return "attack" }
+
+func (t *AttackTask) Start(ctx *TaskContext) error {
+	switch {
+	case ctx.Runner.Combat == nil:
+		return errors.New("no combat system")
+	case !ctx.Runner.combat.Has(ctx.Entity):
+		return errors.New("entity has no Combat")
+	case ctx.Position(t.Target) == nil:
+		return errors.New("target is gone")
+	}
+
+	return nil
+}
+
+func (t *AttackTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	r := ctx.Runner
+
+	if !ctx.World.Alive(t.Target) {
+		return TaskSucceeded, 1, "target defeated"
+	}
+
+	progress := 0.0
+
+	if r.health.Has(t.Target) {
+		h := r.health.Get(t.Target)
+		if h.Current <= 0 {
+			return TaskSucceeded, 1, "target defeated"
+		}
+
+		if h.Max > 0 {
+			progress = 1 - float64(h.Current)/float64(h.Max)
+		}
+	}
+
+	if !r.combat.Has(ctx.Entity) {
+		return TaskFailed, progress, "entity has no Combat"
+	}
+
+	inRange, err := t.nav.approach(ctx, t.Target, r.combat.Get(ctx.Entity).Range)
+	if err != nil {
+		return TaskFailed, progress, err.Error()
+	}
+
+	if !inRange {
+		return TaskRunning, progress, "closing in"
+	}
+
+	if r.Combat.Attack(ctx.World, ctx.Entity, t.Target) {
+		t.hits++
+	}
+
+	return TaskRunning, progress, fmt.Sprintf("attacking (%d hits)", t.hits)
+}
+
+// PickUpTask walks to an item entity and moves it into the Inventory.
+type PickUpTask struct {
+	Item ecs.Entity
+
+	nav navigator
+}
+
+func (t *PickUpTask) Name() string { return "pick_up" }
+
+func (t *PickUpTask) Start(ctx *TaskContext) error {
+	switch {
+	case !ctx.Runner.inv.Has(ctx.Entity):
+		return errors.New("entity has no Inventory")
+	case ctx.Position(t.Item) == nil || !ctx.Runner.items.Has(t.Item):
+		return fmt.Errorf("entity %d is not an item", t.Item.ID())
+	}
+
+	return nil
+}
+
+func (t *PickUpTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	r := ctx.Runner
+	if !ctx.World.Alive(t.Item) {
+		return TaskFailed, 0, "item is gone"
+	}
+
+	inRange, err := t.nav.approach(ctx, t.Item, r.reach(t.Item))
+	if err != nil {
+		return TaskFailed, 0, err.Error()
+	}
+
+	if !inRange {
+		return TaskRunning, 0, "walking to item"
+	}
+
+	if !r.inv.Has(ctx.Entity) {
+		return TaskFailed, 0, "entity has no Inventory"
+	}
+
+	item := *r.items.Get(t.Item)
+	if !r.inv.Get(ctx.Entity).AddItem(item) {
+		return TaskFailed, 0, "inventory is full"
+	}
+
+	ctx.World.RemoveEntity(t.Item)
+
+	return TaskSucceeded, 1, "picked up " + item.Name
+}
+
+// UseTask uses one item from the Inventory through the runner's UseItem.
+type UseTask struct {
+	ItemID string
+}
+
+func (t *UseTask) Name() string { return "use" }
+
+func (t *UseTask) Start(ctx *TaskContext) error {
+	if !ctx.Runner.inv.Has(ctx.Entity) || ctx.Runner.inv.Get(ctx.Entity).GetItem(t.ItemID) == nil {
+		return fmt.Errorf("no %s in inventory", t.ItemID)
+	}
+
+	return nil
+}
+
+func (t *UseTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	r := ctx.Runner
+	if !r.inv.Has(ctx.Entity) {
+		return TaskFailed, 0, "entity has no Inventory"
+	}
+
+	item := r.inv.Get(ctx.Entity).GetItem(t.ItemID)
+	if item == nil {
+		return TaskFailed, 0, fmt.Sprintf("no %s in inventory", t.ItemID)
+	}
+
+	if err := r.UseItem(ctx.World, ctx.Entity, *item); err != nil {
+		return TaskFailed, 0, err.Error()
+	}
+
+	// UseItem may change the entity's components; look the Inventory up again.
+	if r.inv.Has(ctx.Entity) {
+		r.inv.Get(ctx.Entity).RemoveItem(t.ItemID, 1)
+	}
+
+	return TaskSucceeded, 1, "used " + t.ItemID
+}
+
+// TalkTask walks to an NPC and starts a dialogue on the runner's
+// NarrativeController: Dialogue, or the NPC's Interactable.DialogueID.
+type TalkTask struct {
+	NPC      ecs.Entity
+	Dialogue string
+
+	nav navigator
+}
+
+func (t *TalkTask) Name() string { return "talk" }
+
+func (t *TalkTask) Start(ctx *TaskContext) error {
+	r := ctx.Runner
+
+	if r.Narrative == nil {
+		return errors.New("no narrative controller")
+	}
+
+	if ctx.Position(t.NPC) == nil {
+		return errors.New("target is gone")
+	}
+
+	if t.Dialogue == "" && r.interact.Has(t.NPC) {
+		t.Dialogue = r.interact.Get(t.NPC).DialogueID
+	}
+
+	if t.Dialogue == "" {
+		return fmt.Errorf("entity %d has nothing to say", t.NPC.ID())
+	}
+
+	return nil
+}
+
+func (t *TalkTask) Tick(ctx *TaskContext) (TaskStatus, float64, string) {
+	inRange, err := t.nav.approach(ctx, t.NPC, ctx.Runner.reach(t.NPC))
+	if err != nil {
+		return TaskFailed, 0, err.Error()
+	}
+
+	if !inRange {
+		return TaskRunning, 0, "walking to speaker"
+	}
+
+	node := ctx.Runner.Narrative.StartDialogue(t.Dialogue)
+	if node == nil {
+		return TaskFailed, 0, fmt.Sprintf("dialogue %q is unavailable", t.Dialogue)
+	}
+
+	return TaskSucceeded, 1, node.Speaker + ": " + node.Text
+}
+
+// ============================================================================
+// Registry Verbs
+// ============================================================================
+
+// TaskAction starts a task when executed. ID is set once it started.
+type TaskAction struct {
+	Runner *TaskRunner
+	Entity ecs.Entity
+	Task   Task
+	ID     int
+}
+
+func (a *TaskAction) Name() string { return a.Task.Name() }
+
+func (a *TaskAction) Execute(*ecs.World) error {
+	id, err := a.Runner.Start(a.Entity, a.Task)
+	a.ID = id
+
+	return err
+}
+
+// RegisterTasks adds the task verbs to registry: move_to, follow, attack,
+// pick_up, use and talk. Targets are entity ids.
+func (r *TaskRunner) RegisterTasks(registry *ActionRegistry) {
+	hasPosition := requireComponent[components.Position]("Position")
+	target := IntegerParam("Target entity id")
+
+	start := func(build func(map[string]any) (Task, error)) func(ecs.Entity, map[string]any) (Action, error) {
+		return func(e ecs.Entity, p map[string]any) (Action, error) {
+			task, err := build(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return &TaskAction{Runner: r, Entity: e, Task: task}, nil
+		}
+	}
+
+	specs := []ActionSpec{
+		{
+			Verb:        "move_to",
+			Description: "Walk to a position along a planned path over several ticks.",
+			Scope:       ScopeMovement,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"x": NumberParam("Goal X in pixels"),
+				"y": NumberParam("Goal Y in pixels"),
+			}, "x", "y"),
+			Precondition: hasPosition,
+			Build: start(func(p map[string]any) (Task, error) {
+				return &MoveToTask{X: paramFloat(p, "x"), Y: paramFloat(p, "y")}, nil
+			}),
+		},
+		{
+			Verb:        "follow",
+			Description: "Stay near another entity for ticks ticks, or until replaced when ticks is 0.",
+			Scope:       ScopeMovement,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"target":   target,
+				"distance": NumberParam("Distance to keep in pixels"),
+				"ticks":    IntegerParam("Duration in ticks"),
+			}, "target"),
+			Precondition: hasPosition,
+			Build: start(func(p map[string]any) (Task, error) {
+				e, err := r.paramEntity(p, "target")
+
+				return &FollowTask{Target: e, Distance: paramFloat(p, "distance"), Ticks: int(paramFloat(p, "ticks"))}, err
+			}),
+		},
+		{
+			Verb:         "attack",
+			Description:  "Approach a target and attack it until it is defeated.",
+			Scope:        ScopeCombat,
+			Params:       ObjectParams(map[string]*ParamSchema{"target": target}, "target"),
+			Precondition: requireComponent[components.Combat]("Combat"),
+			Build: start(func(p map[string]any) (Task, error) {
+				e, err := r.paramEntity(p, "target")
+
+				return &AttackTask{Target: e}, err
+			}),
+		},
+		{
+			Verb:         "pick_up",
+			Description:  "Walk to an item and put it in the inventory.",
+			Scope:        ScopeInteract,
+			Params:       ObjectParams(map[string]*ParamSchema{"target": target}, "target"),
+			Precondition: requireComponent[components.Inventory]("Inventory"),
+			Build: start(func(p map[string]any) (Task, error) {
+				e, err := r.paramEntity(p, "target")
+
+				return &PickUpTask{Item: e}, err
+			}),
+		},
+		{
+			Verb:         "use",
+			Description:  "Use one item from the inventory.",
+			Scope:        ScopeInteract,
+			Params:       ObjectParams(map[string]*ParamSchema{"item": StringParam("Item id")}, "item"),
+			Precondition: requireComponent[components.Inventory]("Inventory"),
+			Build: start(func(p map[string]any) (Task, error) {
+				id, _ := p["item"].(string)
+
+				return &UseTask{ItemID: id}, nil
+			}),
+		},
+		{
+			Verb:        "talk",
+			Description: "Walk to an NPC and start its dialogue, or the given one.",
+			Scope:       ScopeInteract,
+			Params: ObjectParams(map[string]*ParamSchema{
+				"target":   target,
+				"dialogue": StringParam("Dialogue node id"),
+			}, "target"),
+			Precondition: hasPosition,
+			Build: start(func(p map[string]any) (Task, error) {
+				e, err := r.paramEntity(p, "target")
+				dialogue, _ := p["dialogue"].(string)
+
+				return &TalkTask{NPC: e, Dialogue: dialogue}, err
+			}),
+		},
+	}
+
+	for _, spec := range specs {
+		_ = registry.Register(spec)
+	}
+}
+
+func (r *TaskRunner) paramEntity(params map[string]any, name string) (ecs.Entity, error) {
+	id := uint32(paramFloat(params, name))
+
+	e, ok := r.Entity(id)
+	if !ok {
+		return ecs.Entity{}, fmt.Errorf("no entity %d", id)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,315 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/engine"
+	"github.com/skyrocket-qy/NeuralWay/engine/systems"
+)
+
+// combatClock advances combat time and applies queued damage each tick.
+type combatClock struct {
+	combat *systems.CombatSystem
+	health *systems.HealthSystem
+}
+
+func (c combatClock) Update(world *ecs.World) {
+	c.combat.Update(world, 1.0/60)
+	c.health.Update(world)
+}
+
+func runTask(t *testing.T, game *engine.HeadlessGame, runner *TaskRunner, id, maxTicks int) TaskProgress {
+	t.Helper()
+
+	for range maxTicks {
+		if p, _ := runner.Progress(id); p.Status.Done() {
+			return p
+		}
+
+		game.Step()
+	}
+
+	p, _ := runner.Progress(id)
+	t.Fatalf("task %d still %s after %d ticks: %s", id, p.Status, maxTicks, p.Message)
+
+	return p
+}
+
+func TestTaskMoveToAvoidsWalls(t *testing.T) {
+	game := engine.NewHeadlessGame()
+	world := &game.World
+
+	grid := systems.NewNavGrid(10, 10, 10)
+	for y := range 8 {
+		grid.SetWalkable(5, y, false)
+	}
+
+	runner := NewTaskRunner(world)
+	runner.Pathfinder = systems.NewPathfindingSystem(grid)
+	game.AddSystem(runner)
+
+	var reports []TaskProgress
+
+	runner.OnProgress = func(p TaskProgress) { reports = append(reports, p) }
+
+	agent := ecs.NewMap1[components.Position](world).NewEntity(&components.Position{X: 15, Y: 15})
+	pos := ecs.NewMap[components.Position](world).Get(agent)
+
+	if _, err := runner.Start(agent, &MoveToTask{X: 55, Y: 35}); err == nil {
+		t.Error("moving into a wall should be rejected")
+	}
+
+	id, err := runner.Start(agent, &MoveToTask{X: 85, Y: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 200 {
+		if gx, gy := grid.WorldToGrid(pos.X, pos.Y); !grid.IsWalkable(gx, gy) {
+			t.Fatalf("walked into wall at (%.1f, %.1f)", pos.X, pos.Y)
+		}
+
+		if p, _ := runner.Progress(id); p.Status.Done() {
+			break
+		}
+
+		game.Step()
+	}
+
+	p, _ := runner.Progress(id)
+	if p.Status != TaskSucceeded || pos.X != 85 || pos.Y != 15 {
+		t.Fatalf("move_to = %+v at (%.1f, %.1f)", p, pos.X, pos.Y)
+	}
+
+	if p.Ticks < 50 {
+		t.Errorf("arrived in %d ticks; the detour around the wall is longer", p.Ticks)
+	}
+
+	if first, last := reports[0], reports[len(reports)-1]; first.Message != "started" || last.Status != TaskSucceeded {
+		t.Errorf("reports = %+v", reports)
+	}
+}
+
+func TestTaskFollowAndReplace(t *testing.T) {
+	game, player := newAgentTestGame()
+	world := &game.World
+
+	runner := NewTaskRunner(world)
+	game.AddSystem(runner)
+
+	var enemy ecs.Entity
+
+	query := ecs.NewFilter2[components.Velocity, components.AIMetadata](world).Query()
+	for query.Next() {
+		if _, meta := query.Get(); meta.EntityType == "enemy" {
+			enemy = query.Entity()
+		}
+	}
+
+	follow, err := runner.Start(player, &FollowTask{Target: enemy, Distance: 10, Ticks: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := runTask(t, game, runner, follow, 100); p.Status != TaskSucceeded {
+		t.Fatalf("follow = %+v", p)
+	}
+
+	positions := ecs.NewMap[components.Position](world)
+	if a, b := positions.Get(player), positions.Get(enemy); b.X-a.X > 12 {
+		t.Errorf("player at %.0f trails enemy at %.0f", a.X, b.X)
+	}
+
+	first, _ := runner.Start(player, &FollowTask{Target: enemy})
+	second, _ := runner.Start(player, &MoveToTask{X: 0, Y: 0})
+
+	if p, _ := runner.Progress(first); p.Status != TaskCanceled || p.Message != "replaced by move_to" {
+		t.Errorf("replaced task = %+v", p)
+	}
+
+	if !runner.Cancel(second) || runner.Running(player) {
+		t.Error("Cancel should stop the running task")
+	}
+}
+
+type stuckTask struct{}
+
+func (stuckTask) Name() string { return "stuck" }
+
+func (stuckTask) Tick(*TaskContext) (TaskStatus, float64, string) { return TaskRunning, 0, "waiting" }
+
+func TestTaskTimeout(t *testing.T) {
+	game := engine.NewHeadlessGame()
+	world := &game.World
+
+	runner := NewTaskRunner(world)
+	game.AddSystem(runner)
+
+	positions := ecs.NewMap1[components.Position](world)
+	walker := positions.NewEntity(&components.Position{})
+	follower := positions.NewEntity(&components.Position{})
+	waiter := positions.NewEntity(&components.Position{})
+
+	// 2000 pixels at 2 per tick takes 1000 ticks, past the default Timeout.
+	move, _ := runner.Start(walker, &MoveToTask{X: 2000})
+	follow, _ := runner.Start(follower, &FollowTask{Target: waiter})
+	stuck, _ := runner.Start(waiter, stuckTask{})
+
+	if p := runTask(t, game, runner, move, 1100); p.Status != TaskSucceeded {
+		t.Errorf("move_to = %+v", p)
+	}
+
+	if p, _ := runner.Progress(follow); p.Status != TaskRunning || p.Ticks < 1000 {
+		t.Errorf("follow = %+v", p)
+	}
+
+	if p, _ := runner.Progress(stuck); p.Status != TaskFailed || p.Ticks != runner.Timeout {
+		t.Errorf("stuck = %+v", p)
+	}
+}
+
+func TestTaskAttack(t *testing.T) {
+	game := engine.NewHeadlessGame()
+	world := &game.World
+
+	health := systems.NewHealthSystem(world)
+	combat := systems.NewCombatSystem(world, health)
+	game.AddSystem(combatClock{combat, health})
+
+	runner := NewTaskRunner(world)
+	runner.Combat = combat
+	game.AddSystem(runner)
+
+	stats := components.NewCombat(10, 0)
+	stats.AttackSpeed = 10
+
+	hero := ecs.NewMap2[components.Position, components.Combat](world).
+		NewEntity(&components.Position{}, &stats)
+	slime := ecs.NewMap2[components.Position, components.Health](world).
+		NewEntity(&components.Position{X: 150}, &components.Health{Current: 30, Max: 30})
+
+	if _, err := runner.Start(slime, &AttackTask{Target: hero}); err == nil || !strings.Contains(err.Error(), "no Combat") {
+		t.Errorf("attack without Combat = %v", err)
+	}
+
+	id, err := runner.Start(hero, &AttackTask{Target: slime})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := runTask(t, game, runner, id, 300); p.Status != TaskSucceeded || p.Message != "target defeated" {
+		t.Fatalf("attack = %+v", p)
+	}
+
+	if hp := ecs.NewMap[components.Health](world).Get(slime); hp.Current > 0 {
+		t.Errorf("slime has %d hp", hp.Current)
+	}
+
+	// Attacking must not leave the world locked for spawns and despawns.
+	world.RemoveEntity(slime)
+	target := ecs.NewMap2[components.Position, components.Health](world).
+		NewEntity(&components.Position{X: 500}, &components.Health{Current: 30, Max: 30})
+
+	if id, err = runner.Start(hero, &AttackTask{Target: target}); err != nil {
+		t.Fatal(err)
+	}
+
+	game.Step()
+	ecs.NewMap[components.Combat](world).Remove(hero)
+
+	if p := runTask(t, game, runner, id, 10); p.Status != TaskFailed || !strings.Contains(p.Message, "no Combat") {
+		t.Errorf("attack after losing Combat = %+v", p)
+	}
+}
+
+func TestTaskPickUpUseTalk(t *testing.T) {
+	game := engine.NewHeadlessGame()
+	world := &game.World
+
+	narrative := NewNarrativeController()
+	narrative.AddDialogue(DialogueNode{ID: "greet", Speaker: "Elder", Text: "Welcome, traveler."})
+
+	runner := NewTaskRunner(world)
+	runner.Narrative = narrative
+	game.AddSystem(runner)
+
+	bag := components.NewInventory(4)
+	hero := ecs.NewMap3[components.Position, components.Inventory, components.Health](world).
+		NewEntity(&components.Position{}, &bag, &components.Health{Current: 50, Max: 100})
+
+	potion := components.NewItem("potion", "Potion", components.RarityCommon)
+	potion.Stats["heal"] = 20
+	item := ecs.NewMap2[components.Position, components.Item](world).NewEntity(&components.Position{X: 60}, &potion)
+	elder := ecs.NewMap2[components.Position, components.Interactable](world).
+		NewEntity(&components.Position{Y: 80}, &components.Interactable{Range: 30, DialogueID: "greet"})
+
+	if _, err := runner.Start(hero, &UseTask{ItemID: "potion"}); err == nil {
+		t.Error("using an item not in the inventory should be rejected")
+	}
+
+	id, err := runner.Start(hero, &PickUpTask{Item: item})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := runTask(t, game, runner, id, 100); p.Status != TaskSucceeded || p.Message != "picked up Potion" {
+		t.Fatalf("pick_up = %+v", p)
+	}
+
+	inv := ecs.NewMap[components.Inventory](world).Get(hero)
+	if world.Alive(item) || inv.GetItemCount("potion") != 1 {
+		t.Fatal("the potion should move into the inventory")
+	}
+
+	id, _ = runner.Start(hero, &UseTask{ItemID: "potion"})
+	if p := runTask(t, game, runner, id, 5); p.Status != TaskSucceeded {
+		t.Fatalf("use = %+v", p)
+	}
+
+	if hp := ecs.NewMap[components.Health](world).Get(hero); hp.Current != 70 || inv.GetItemCount("potion") != 0 {
+		t.Errorf("after use: hp %d, potions %d", hp.Current, inv.GetItemCount("potion"))
+	}
+
+	id, _ = runner.Start(hero, &TalkTask{NPC: elder})
+
+	p := runTask(t, game, runner, id, 100)
+	if p.Status != TaskSucceeded || p.Message != "Elder: Welcome, traveler." {
+		t.Fatalf("talk = %+v", p)
+	}
+
+	if narrative.GetCurrentDialogue().ID != "greet" {
+		t.Error("talk should start the elder's dialogue")
+	}
+}
+
+func TestAgentServerTasks(t *testing.T) {
+	game, player := newAgentTestGame()
+	server := NewAgentServer(game)
+	server.UseTasks(NewTaskRunner(&game.World))
+
+	id := jsonInt(player.ID())
+	responses := agentCall(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"execute","arguments":{"actions":[`+
+			`{"verb":"move_to","entity":`+id+`,"x":30,"y":10},{"verb":"attack","entity":`+id+`,"target":1}]}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"task_status","arguments":{"id":1}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"step","arguments":{"n":20}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"task_status","arguments":{"entity":`+id+`}}}`,
+	)
+
+	text, _ := toolText(t, responses[0])
+	if !strings.Contains(text, `"ok: task 1","error: action precondition failed: attack: entity `+id+` has no Combat"`) {
+		t.Errorf("execute = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[1]); !strings.Contains(text, `"status":"running"`) {
+		t.Errorf("task_status before stepping = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[3]); !strings.Contains(text, `"status":"succeeded","progress":1,"message":"arrived"`) {
+		t.Errorf("task_status after stepping = %s", text)
+	}
+}
@@ -0,0 +1,266 @@
+package ai
+
+import "math"
+
+// ============================================================================
+// Response Curves
+// ============================================================================
+
+// CurveType selects the shape of a response curve.
+type CurveType int
+
+const (
+	// CurveLinear is y = M*(x-C) + B.
+	CurveLinear CurveType = iota
+	// CurvePolynomial is y = M*(x-C)^K + B.
+	CurvePolynomial
+	// CurveLogistic is an S-curve with steepness K centered at C.
+	CurveLogistic
+	// CurveStep is 0 below C and 1 at or above it.
+	CurveStep
+)
+
+// ResponseCurve maps a normalized input in [0,1] to a score in [0,1].
+type ResponseCurve interface {
+	Evaluate(x float64) float64
+}
+
+// Curve is a parameterized response curve.
+type Curve struct {
+	Type   CurveType
+	M      float64 // Slope / scale
+	K      float64 // Exponent (polynomial) or steepness (logistic)
+	B      float64 // Vertical shift
+	C      float64 // Horizontal shift / midpoint / step threshold
+	Invert bool    // Use 1 - y
+}
+
+// NewLinearCurve creates y = slope*x + offset.
+func NewLinearCurve(slope, offset float64) Curve {
+	return Curve{Type: CurveLinear, M: slope, B: offset}
+}
+
+// NewPolynomialCurve creates y = x^exponent.
+func NewPolynomialCurve(exponent float64) Curve {
+	return Curve{Type: CurvePolynomial, M: 1, K: exponent}
+}
+
+// NewLogisticCurve creates an S-curve rising around midpoint.
+func NewLogisticCurve(steepness, midpoint float64) Curve {
+	return Curve{Type: CurveLogistic, M: 1, K: steepness, C: midpoint}
+}
+
+// NewStepCurve creates a curve that jumps from 0 to 1 at threshold.
+func NewStepCurve(threshold float64) Curve {
+	return Curve{Type: CurveStep, C: threshold}
+}
+
+// Inverted returns a copy of the curve with its output flipped.
+func (c Curve) Inverted() Curve {
+	c.Invert = !c.Invert
+
+	return c
+}
+
+// Evaluate returns the curve value for x, both clamped to [0,1].
+func (c Curve) Evaluate(x float64) float64 {
+	x = clamp(x, 0, 1)
+
+	var y float64
+
+	switch c.Type {
+	case CurveLinear:
+		y = c.M*(x-c.C) + c.B
+	case CurvePolynomial:
+		y = c.M*math.Pow(x-c.C, c.K) + c.B
+	case CurveLogistic:
+		y = c.M/(1+math.Exp(-c.K*(x-c.C))) + c.B
+	case CurveStep:
+		if x >= c.C {
+			y = 1
+		}
+	}
+
+	if math.IsNaN(y) {
+		y = 0
+	}
+
+	y = clamp(y, 0, 1)
+	if c.Invert {
+		y = 1 - y
+	}
+
+	return y
+}
+
+// CurveFunc adapts a plain function to a ResponseCurve.
+type CurveFunc func(x float64) float64
+
+// Evaluate calls the function.
+func (f CurveFunc) Evaluate(x float64) float64 {
+	return clamp(f(clamp(x, 0, 1)), 0, 1)
+}
+
+// ============================================================================
+// Considerations and Options
+// ============================================================================
+
+// Consideration scores one input that matters to a decision.
+type Consideration struct {
+	Name     string
+	Input    func(ctx *BTContext) float64 // Raw input value
+	Min, Max float64                      // Range used to normalize Input to [0,1]
+	Curve    ResponseCurve
+}
+
+// NewConsideration creates a consideration.
+func NewConsideration(
+	name string,
+	input func(ctx *BTContext) float64,
+	minVal, maxVal float64,
+	curve ResponseCurve,
+) *Consideration {
+	return &Consideration{Name: name, Input: input, Min: minVal, Max: maxVal, Curve: curve}
+}
+
+// Score returns the consideration score in [0,1].
+func (c *Consideration) Score(ctx *BTContext) float64 {
+	x := c.Input(ctx)
+	if c.Max != c.Min {
+		x = (x - c.Min) / (c.Max - c.Min)
+	}
+
+	if c.Curve == nil {
+		return clamp(x, 0, 1)
+	}
+
+	return c.Curve.Evaluate(x)
+}
+
+// UtilityOption is an action the utility selector can choose.
+type UtilityOption struct {
+	Name           string
+	Considerations []*Consideration
+	Weight         float64 // Multiplier applied to the final score
+	Action         Node
+}
+
+// NewUtilityOption creates an option with weight 1.
+func NewUtilityOption(name string, action Node, considerations ...*Consideration) *UtilityOption {
+	return &UtilityOption{
+		Name:           name,
+		Considerations: considerations,
+		Weight:         1,
+		Action:         action,
+	}
+}
+
+// Score multiplies consideration scores, compensating for the number of
+// considerations so options with many inputs aren't unfairly penalized.
+func (o *UtilityOption) Score(ctx *BTContext) float64 {
+	if len(o.Considerations) == 0 {
+		return o.Weight
+	}
+
+	score := 1.0
+	for _, c := range o.Considerations {
+		score *= c.Score(ctx)
+		if score == 0 {
+			return 0
+		}
+	}
+
+	modification := 1 - 1/float64(len(o.Considerations))
+	makeUp := (1 - score) * modification
+
+	return (score + makeUp*score) * o.Weight
+}
+
+// ============================================================================
+// Utility Selector Node
+// ============================================================================
+
+// UtilitySelector is a behavior tree node that runs the highest scoring option.
+// While an option is Running it receives an Inertia bonus, so the agent only
+// switches when another option is clearly better.
+type UtilitySelector struct {
+	Options   []*UtilityOption
+	Threshold float64 // Options scoring at or below this are never chosen
+	Inertia   float64 // Bonus added to the running option's score
+
+	// LastScores holds the most recent score for each option by name.
+	LastScores map[string]float64
+
+	running int
+}
+
+// NewUtilitySelector creates a utility selector node.
+func NewUtilitySelector(options ...*UtilityOption) *UtilitySelector {
+	return &UtilitySelector{
+		Options:    options,
+		Inertia:    0.1,
+		LastScores: make(map[string]float64),
+		running:    -1,
+	}
+}
+
+// Best returns the index and score of the best option, or -1.
+func (n *UtilitySelector) Best(ctx *BTContext) (int, float64) {
+	best, bestScore := -1, n.Threshold
+
+	for i, opt := range n.Options {
+		score := opt.Score(ctx)
+		n.LastScores[opt.Name] = score
+
+		if i == n.running {
+			score += n.Inertia
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best, bestScore
+}
+
+func (n *UtilitySelector) Tick(ctx *BTContext) NodeStatus {
+	best, _ := n.Best(ctx)
+	if best != n.running && n.running >= 0 {
+		// Interrupt the old option so it starts over when picked again.
+		ResetNode(n.Options[n.running].Action)
+	}
+
+	if best < 0 {
+		n.running = -1
+
+		return Failure
+	}
+
+	status := n.Options[best].Action.Tick(ctx)
+	if status == Running {
+		n.running = best
+	} else {
+		n.running = -1
+	}
+
+	return status
+}
+
+// Reset interrupts the running option.
+func (n *UtilitySelector) Reset() {
+	if n.running >= 0 {
+		ResetNode(n.Options[n.running].Action)
+	}
+
+	n.running = -1
+}
+
+// Current returns the name of the running option, or "".
+func (n *UtilitySelector) Current() string {
+	if n.running < 0 {
+		return ""
+	}
+
+	return n.Options[n.running].Name
+}
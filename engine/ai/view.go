@@ -0,0 +1,547 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// StateSchemaVersion is bumped whenever a view changes incompatibly.
+const StateSchemaVersion = 1
+
+// ============================================================================
+// Fields
+// ============================================================================
+
+// StateField is one field an exporter adds to every entity that has it.
+// Any component can contribute a field; see ComponentField.
+type StateField struct {
+	Name        string
+	Description string
+	Schema      *ParamSchema
+
+	// Export returns the value for entity, or false when it has none.
+	// Values must marshal to JSON.
+	Export func(world *ecs.World, entity ecs.Entity) (any, bool)
+}
+
+// ComponentField exports value(component) for entities that have T.
+func ComponentField[T any](name, description string, schema *ParamSchema, value func(*T) any) StateField {
+	var (
+		cached *ecs.World
+		m      *ecs.Map[T]
+	)
+
+	return StateField{
+		Name:        name,
+		Description: description,
+		Schema:      schema,
+		Export: func(world *ecs.World, entity ecs.Entity) (any, bool) {
+			if world != cached {
+				cached, m = world, ecs.NewMap[T](world)
+			}
+
+			if !m.Has(entity) {
+				return nil, false
+			}
+
+			return value(m.Get(entity)), true
+		},
+	}
+}
+
+// ExporterRegistry holds the fields exported per entity, in order.
+type ExporterRegistry struct {
+	fields []StateField
+}
+
+// NewExporterRegistry creates an empty registry.
+func NewExporterRegistry() *ExporterRegistry {
+	return &ExporterRegistry{}
+}
+
+// DefaultExporterRegistry exports the fields of StateExporter: description,
+// position, velocity, health, state, tags and effects.
+func DefaultExporterRegistry() *ExporterRegistry {
+	vec := &ParamSchema{Type: "array", Items: &ParamSchema{Type: "number"}}
+	strs := &ParamSchema{Type: "array", Items: &ParamSchema{Type: "string"}}
+	r := NewExporterRegistry()
+
+	r.Register(ComponentField("description", "Human-readable description", StringParam(""),
+		func(m *components.AIMetadata) any { return m.Description }))
+	r.Register(ComponentField("position", "[x, y] in pixels", vec,
+		func(p *components.Position) any { return [2]float64{round2(p.X), round2(p.Y)} }))
+	r.Register(ComponentField("velocity", "[vx, vy] in pixels per tick", vec,
+		func(v *components.Velocity) any { return [2]float64{round2(v.X), round2(v.Y)} }))
+	r.Register(ComponentField("health", "[current, max]", &ParamSchema{Type: "array", Items: IntegerParam("")},
+		func(h *components.Health) any { return [2]int{h.Current, h.Max} }))
+	r.Register(ComponentField("state", "Visual state, e.g. idle, moving", StringParam(""),
+		func(m *components.AIMetadata) any { return m.VisualState }))
+	r.Register(ComponentField("tags", "Filterable labels", strs,
+		func(m *components.AIMetadata) any { return m.Tags }))
+	r.Register(ComponentField("effects", "Active visual effects", strs,
+		func(m *components.AIMetadata) any { return m.ActiveEffects }))
+
+	return r
+}
+
+// Register adds a field, replacing one with the same name in place.
+func (r *ExporterRegistry) Register(field StateField) {
+	for i, f := range r.fields {
+		if f.Name == field.Name {
+			r.fields[i] = field
+
+			return
+		}
+	}
+
+	r.fields = append(r.fields, field)
+}
+
+// Fields returns the registered fields.
+func (r *ExporterRegistry) Fields() []StateField {
+	return r.fields
+}
+
+// Schema returns the JSON Schema of a ViewSnapshot with these fields.
+// Agents can rely on it until StateSchemaVersion changes.
+func (r *ExporterRegistry) Schema() map[string]any {
+	props := map[string]any{
+		"id":   map[string]any{"type": "integer", "description": "Entity id, used by actions"},
+		"type": map[string]any{"type": "string", "description": "AIMetadata entity type"},
+	}
+
+	for _, f := range r.fields {
+		s := map[string]any{"description": f.Description}
+		if f.Schema != nil {
+			data, _ := json.Marshal(f.Schema)
+			_ = json.Unmarshal(data, &s)
+
+			if f.Description != "" {
+				s["description"] = f.Description
+			}
+		}
+
+		props[f.Name] = s
+	}
+
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "NeuralWay state view",
+		"type":    "object",
+		"properties": map[string]any{
+			"version": map[string]any{"const": StateSchemaVersion},
+			"tick":    map[string]any{"type": "integer"},
+			"viewer":  map[string]any{"type": "integer"},
+			"entities": map[string]any{
+				"type":        "object",
+				"description": "Entities keyed by id",
+				"additionalProperties": map[string]any{
+					"type":       "object",
+					"properties": props,
+					"required":   []string{"id", "type"},
+				},
+			},
+			"omitted": map[string]any{"type": "integer", "description": "Entities left out by the token budget"},
+		},
+		"required": []string{"version", "tick", "entities"},
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ============================================================================
+// Views
+// ============================================================================
+
+// Visibility reports whether a world position can be seen. game.FogOfWar
+// implements it.
+type Visibility interface {
+	IsVisible(x, y float64) bool
+}
+
+// EntityState is one exported entity: "id", "type" and the field values.
+type EntityState map[string]any
+
+// ViewSnapshot is what one agent sees at a tick.
+type ViewSnapshot struct {
+	Version  int                    `json:"version"`
+	Tick     int64                  `json:"tick"`
+	Viewer   uint32                 `json:"viewer,omitempty"`
+	Entities map[string]EntityState `json:"entities"`
+	Omitted  int                    `json:"omitted,omitempty"`
+}
+
+// ViewOptions shapes a view for one agent.
+type ViewOptions struct {
+	// Viewer is the agent's entity. It is always included and is the
+	// center for Radius and distance priority.
+	Viewer ecs.Entity
+	// Radius limits positioned entities to this distance from the viewer
+	// (0 = unlimited).
+	Radius float64
+	// Fog hides positioned entities that are not visible (nil = none).
+	Fog Visibility
+	// MaxTokens drops the lowest-priority entities until the view fits
+	// (0 = unlimited). Tokens are estimated like TokenEstimate.
+	MaxTokens int
+	// Fields limits the exported fields (empty = all).
+	Fields []string
+	// Priority ranks entities for the budget, higher first. Nil ranks by
+	// TypePriority, then by distance to the viewer.
+	Priority func(EntityState) float64
+}
+
+// TypePriority ranks entity types for token budgets.
+var TypePriority = map[string]float64{
+	"player": 100, "enemy": 60, "npc": 50, "item": 40, "projectile": 20, "effect": 5, "ui": 1,
+}
+
+// ViewExporter exports per-agent views through an ExporterRegistry.
+type ViewExporter struct {
+	Registry *ExporterRegistry
+
+	world *ecs.World
+	meta  *ecs.Filter1[components.AIMetadata]
+	pos   *ecs.Map[components.Position]
+}
+
+// NewViewExporter creates a view exporter with DefaultExporterRegistry.
+func NewViewExporter(world *ecs.World) *ViewExporter {
+	return &ViewExporter{
+		Registry: DefaultExporterRegistry(),
+		world:    world,
+		meta:     ecs.NewFilter1[components.AIMetadata](world),
+		pos:      ecs.NewMap[components.Position](world),
+	}
+}
+
+// Export returns the view of the entities with AIMetadata at tick.
+func (v *ViewExporter) Export(tick int64, opts ViewOptions) ViewSnapshot {
+	snap := ViewSnapshot{Version: StateSchemaVersion, Tick: tick, Entities: make(map[string]EntityState)}
+
+	hasViewer := opts.Viewer != (ecs.Entity{}) && v.world.Alive(opts.Viewer)
+
+	var vx, vy float64
+
+	if hasViewer {
+		snap.Viewer = opts.Viewer.ID()
+
+		if v.pos.Has(opts.Viewer) {
+			p := v.pos.Get(opts.Viewer)
+			vx, vy = p.X, p.Y
+		}
+	}
+
+	type candidate struct {
+		entity     ecs.Entity
+		entityType string
+		state      EntityState
+		priority   float64
+		dist       float64
+		viewer     bool
+	}
+
+	var candidates []candidate
+
+	query := v.meta.Query()
+	for query.Next() {
+		e := query.Entity()
+		isViewer := hasViewer && e == opts.Viewer
+		dist := 0.0
+
+		if v.pos.Has(e) && !isViewer {
+			p := v.pos.Get(e)
+			dist = math.Hypot(p.X-vx, p.Y-vy)
+
+			if hasViewer && opts.Radius > 0 && dist > opts.Radius {
+				continue
+			}
+
+			if opts.Fog != nil && !opts.Fog.IsVisible(p.X, p.Y) {
+				continue
+			}
+		}
+
+		candidates = append(candidates, candidate{entity: e, entityType: query.Get().EntityType, dist: dist, viewer: isViewer})
+	}
+
+	// Fields run outside the query: they may create component maps, which
+	// a locked world does not allow.
+	for i := range candidates {
+		c := &candidates[i]
+		c.state = v.entity(c.entity, c.entityType, opts.Fields)
+
+		if opts.Priority != nil {
+			c.priority = opts.Priority(c.state)
+		} else {
+			c.priority = TypePriority[c.entityType]
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.viewer != b.viewer {
+			return a.viewer
+		}
+
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+
+		return a.dist < b.dist
+	})
+
+	// Base cost: the envelope without entities.
+	used := TokenEstimate(fmt.Sprintf(`{"version":%d,"tick":%d,"viewer":%d,"entities":{},"omitted":0}`,
+		snap.Version, tick, snap.Viewer))
+
+	for _, c := range candidates {
+		key := strconv.FormatUint(uint64(c.state["id"].(uint32)), 10)
+
+		if opts.MaxTokens > 0 && !c.viewer {
+			data, _ := json.Marshal(c.state)
+			cost := TokenEstimate(key) + TokenEstimate(string(data)) + 1
+
+			if used+cost > opts.MaxTokens {
+				snap.Omitted++
+
+				continue
+			}
+
+			used += cost
+		}
+
+		snap.Entities[key] = c.state
+	}
+
+	return snap
+}
+
+func (v *ViewExporter) entity(e ecs.Entity, entityType string, fields []string) EntityState {
+	state := EntityState{"id": e.ID(), "type": entityType}
+
+	for _, f := range v.Registry.fields {
+		if len(fields) > 0 && !slices.Contains(fields, f.Name) {
+			continue
+		}
+
+		if val, ok := f.Export(v.world, e); ok && !emptyValue(val) {
+			state[f.Name] = val
+		}
+	}
+
+	return state
+}
+
+// emptyValue reports values left out like omitempty would.
+func emptyValue(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+// ============================================================================
+// JSON Patch
+// ============================================================================
+
+// PatchOp is one RFC 6902 JSON Patch operation.
+type PatchOp struct {
+	Op    string `json:"op"` // add, remove or replace
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// DiffViews returns the JSON Patch turning prev into next. Entities are
+// keyed by id, so paths stay stable between ticks, e.g.
+// /entities/12/position.
+func DiffViews(prev, next ViewSnapshot) ([]PatchOp, error) {
+	a, err := toJSONValue(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := toJSONValue(next)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]PatchOp, 0)
+	diffJSON("", a, b, &ops)
+
+	return ops, nil
+}
+
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out any
+	err = json.Unmarshal(data, &out)
+
+	return out, err
+}
+
+func diffJSON(path string, a, b any, ops *[]PatchOp) {
+	am, aok := a.(map[string]any)
+	bm, bok := b.(map[string]any)
+
+	if !aok || !bok {
+		if !jsonEqual(a, b) {
+			*ops = append(*ops, PatchOp{Op: "replace", Path: path, Value: b})
+		}
+
+		return
+	}
+
+	for _, k := range sortedKeys(am) {
+		if _, ok := bm[k]; !ok {
+			*ops = append(*ops, PatchOp{Op: "remove", Path: path + "/" + escapePointer(k)})
+		}
+	}
+
+	for _, k := range sortedKeys(bm) {
+		p := path + "/" + escapePointer(k)
+
+		if av, ok := am[k]; ok {
+			diffJSON(p, av, bm[k], ops)
+		} else {
+			*ops = append(*ops, PatchOp{Op: "add", Path: p, Value: bm[k]})
+		}
+	}
+}
+
+func jsonEqual(a, b any) bool {
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+
+	return string(da) == string(db)
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
+
+// ApplyPatch applies add, remove and replace operations to a decoded JSON
+// document (maps and slices from encoding/json) and returns the result.
+func ApplyPatch(doc any, ops []PatchOp) (any, error) {
+	for _, op := range ops {
+		if op.Path == "" {
+			if op.Op == "remove" {
+				return nil, errors.New("patch: cannot remove the root")
+			}
+
+			doc = op.Value
+
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		parent := doc
+
+		for _, p := range parts[:len(parts)-1] {
+			m, ok := parent.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("patch: %s: not an object", op.Path)
+			}
+
+			if parent, ok = m[unescapePointer(p)]; !ok {
+				return nil, fmt.Errorf("patch: %s: no such path", op.Path)
+			}
+		}
+
+		m, ok := parent.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("patch: %s: not an object", op.Path)
+		}
+
+		key := unescapePointer(parts[len(parts)-1])
+
+		switch op.Op {
+		case "add":
+			m[key] = op.Value
+		case "replace", "remove":
+			if _, ok := m[key]; !ok {
+				return nil, fmt.Errorf("patch: %s: no such path", op.Path)
+			}
+
+			if op.Op == "remove" {
+				delete(m, key)
+			} else {
+				m[key] = op.Value
+			}
+		default:
+			return nil, fmt.Errorf("patch: unsupported op %q", op.Op)
+		}
+	}
+
+	return doc, nil
+}
+
+// ViewStream produces a full view once and JSON Patch deltas after, for
+// one agent.
+type ViewStream struct {
+	Exporter *ViewExporter
+	Options  ViewOptions
+
+	last *ViewSnapshot
+}
+
+// ViewDelta is a patch from view at tick Base to view at tick Tick. Full
+// is set instead of Patch for the first view or after Reset.
+type ViewDelta struct {
+	Tick  int64         `json:"tick"`
+	Base  int64         `json:"base"`
+	Full  *ViewSnapshot `json:"full,omitempty"`
+	Patch []PatchOp     `json:"patch,omitempty"`
+}
+
+// Next exports the view at tick and returns the change since the last
+// call.
+func (s *ViewStream) Next(tick int64) (ViewDelta, error) {
+	snap := s.Exporter.Export(tick, s.Options)
+	if s.last == nil {
+		s.last = &snap
+
+		return ViewDelta{Tick: tick, Base: tick, Full: &snap}, nil
+	}
+
+	ops, err := DiffViews(*s.last, snap)
+	if err != nil {
+		return ViewDelta{}, err
+	}
+
+	delta := ViewDelta{Tick: tick, Base: s.last.Tick, Patch: ops}
+	s.last = &snap
+
+	return delta, nil
+}
+
+// Reset makes the next call send a full view.
+func (s *ViewStream) Reset() {
+	s.last = nil
+}
@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+	"github.com/skyrocket-qy/NeuralWay/engine/game"
+)
+
+func newViewWorld() (*ecs.World, ecs.Entity) {
+	world := ecs.NewWorld()
+
+	actors := ecs.NewMap2[components.Position, components.AIMetadata](&world)
+	hero := actors.NewEntity(&components.Position{X: 10, Y: 10}, &components.AIMetadata{EntityType: "player"})
+	actors.NewEntity(&components.Position{X: 40, Y: 10}, &components.AIMetadata{EntityType: "enemy", Description: "near"})
+	actors.NewEntity(&components.Position{X: 300, Y: 10}, &components.AIMetadata{EntityType: "enemy", Description: "far"})
+
+	for i := range 5 {
+		actors.NewEntity(&components.Position{X: 20, Y: float64(20 + i)}, &components.AIMetadata{EntityType: "effect"})
+	}
+
+	return &world, hero
+}
+
+func viewTypes(snap ViewSnapshot) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range snap.Entities {
+		counts[e["type"].(string)]++
+	}
+
+	return counts
+}
+
+func TestViewExporterFields(t *testing.T) {
+	world, hero := newViewWorld()
+	ecs.NewMap[components.Inventory](world).Add(hero, &components.Inventory{Gold: 42})
+
+	views := NewViewExporter(world)
+	views.Registry.Register(ComponentField("gold", "Carried gold", IntegerParam(""),
+		func(inv *components.Inventory) any { return inv.Gold }))
+
+	snap := views.Export(7, ViewOptions{Viewer: hero, Fields: []string{"position", "gold"}})
+
+	me := snap.Entities[strconv.Itoa(int(hero.ID()))]
+	if me["gold"] != int64(42) || me["position"] != [2]float64{10, 10} || me["description"] != nil {
+		t.Errorf("hero = %v", me)
+	}
+
+	if snap.Version != StateSchemaVersion || snap.Viewer != hero.ID() || len(snap.Entities) != 8 {
+		t.Errorf("snapshot = %+v", snap)
+	}
+
+	data, _ := json.Marshal(views.Registry.Schema())
+	if !strings.Contains(string(data), `"gold":{"description":"Carried gold","type":"integer"}`) {
+		t.Errorf("schema = %s", data)
+	}
+}
+
+func TestViewExporterFiltering(t *testing.T) {
+	world, hero := newViewWorld()
+	views := NewViewExporter(world)
+
+	if got := viewTypes(views.Export(0, ViewOptions{Viewer: hero, Radius: 100})); got["enemy"] != 1 || got["player"] != 1 {
+		t.Errorf("radius view = %v", got)
+	}
+
+	fog := game.NewFogOfWar(40, 4, 10)
+	fog.AddVisionSource(300, 10, 20, ecs.Entity{})
+	fog.Update()
+
+	// The viewer is always included, even in the fog.
+	if got := viewTypes(views.Export(0, ViewOptions{Viewer: hero, Fog: fog})); got["enemy"] != 1 || got["player"] != 1 ||
+		got["effect"] != 0 {
+		t.Errorf("fog view = %v", got)
+	}
+
+	full := views.Export(0, ViewOptions{Viewer: hero})
+	data, _ := json.Marshal(full)
+
+	limit := TokenEstimate(string(data)) / 2
+
+	budget := views.Export(0, ViewOptions{Viewer: hero, MaxTokens: limit})
+	if got := viewTypes(budget); got["enemy"] != 2 || got["player"] != 1 || got["effect"] == 5 {
+		t.Errorf("budget view = %v", got)
+	}
+
+	if budget.Omitted != 5-viewTypes(budget)["effect"] {
+		t.Errorf("omitted = %d", budget.Omitted)
+	}
+
+	// Estimates are per entity, so allow a little rounding slack.
+	data, _ = json.Marshal(budget)
+	if tokens := TokenEstimate(string(data)); tokens > limit+2 {
+		t.Errorf("budget view is %d tokens, limit %d", tokens, limit)
+	}
+}
+
+func TestViewDeltas(t *testing.T) {
+	world, hero := newViewWorld()
+	stream := &ViewStream{Exporter: NewViewExporter(world), Options: ViewOptions{Viewer: hero, Radius: 100}}
+
+	first, err := stream.Next(1)
+	if err != nil || first.Full == nil {
+		t.Fatalf("first delta = %+v, %v", first, err)
+	}
+
+	doc, _ := toJSONValue(first.Full)
+
+	ecs.NewMap[components.Position](world).Get(hero).X = 12
+
+	var gone ecs.Entity
+
+	query := ecs.NewFilter1[components.AIMetadata](world).Query()
+	for query.Next() {
+		if query.Get().Description == "near" {
+			gone = query.Entity()
+		}
+	}
+
+	world.RemoveEntity(gone)
+
+	delta, err := stream.Next(2)
+	if err != nil || delta.Full != nil || delta.Base != 1 {
+		t.Fatalf("delta = %+v, %v", delta, err)
+	}
+
+	heroPath := "/entities/" + strconv.Itoa(int(hero.ID())) + "/position"
+	goneKey := strconv.Itoa(int(gone.ID()))
+
+	want := map[string]bool{"replace /tick": false, "replace " + heroPath: false, "remove /entities/" + goneKey: false}
+	for _, op := range delta.Patch {
+		want[op.Op+" "+op.Path] = true
+	}
+
+	for k, seen := range want {
+		if !seen {
+			t.Errorf("patch %+v lacks %s", delta.Patch, k)
+		}
+	}
+
+	patched, err := ApplyPatch(doc, delta.Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now, _ := toJSONValue(stream.Exporter.Export(2, stream.Options))
+	if !jsonEqual(patched, now) {
+		t.Errorf("patched view differs:\n%v\n%v", patched, now)
+	}
+}
+
+func TestAgentServerViews(t *testing.T) {
+	game, player := newAgentTestGame()
+	server := NewAgentServer(game)
+
+	id := jsonInt(player.ID())
+	responses := agentCall(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"state_schema"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_state","arguments":{"format":"view","entity":`+id+`,"radius":50}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_state","arguments":{"format":"delta","fields":["position"]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"step","arguments":{"n":2}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_state","arguments":{"format":"delta","fields":["position"]}}}`,
+	)
+
+	if text, _ := toolText(t, responses[0]); !strings.Contains(text, `"version":{"const":1}`) {
+		t.Errorf("state_schema = %s", text)
+	}
+
+	var view ViewSnapshot
+
+	text, _ := toolText(t, responses[1])
+	if err := json.Unmarshal([]byte(text), &view); err != nil || len(view.Entities) != 2 {
+		t.Errorf("view = %s", text)
+	}
+
+	if text, _ := toolText(t, responses[2]); !strings.Contains(text, `"full":`) {
+		t.Errorf("first delta = %s", text)
+	}
+
+	// Only the drifting enemy moved.
+	text, _ = toolText(t, responses[4])
+	if !strings.Contains(text, `"base":0`) || strings.Count(text, `"op":"replace"`) != 2 {
+		t.Errorf("second delta = %s", text)
+	}
+}
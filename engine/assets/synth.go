@@ -0,0 +1,436 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// Waveform selects the oscillator of a synthesized sound.
+type Waveform int
+
+const (
+	WaveSquare Waveform = iota
+	WaveSaw
+	WaveSine
+	WaveNoise
+)
+
+// String returns the waveform name.
+func (w Waveform) String() string {
+	switch w {
+	case WaveSquare:
+		return "square"
+	case WaveSaw:
+		return "saw"
+	case WaveSine:
+		return "sine"
+	case WaveNoise:
+		return "noise"
+	default:
+		return fmt.Sprintf("Waveform(%d)", int(w))
+	}
+}
+
+// SFXParams describes an sfxr-style sound effect in physical units.
+// Times are in seconds and frequencies in Hz. Zero values disable the
+// optional stages (vibrato, arpeggio, repeat and filters).
+type SFXParams struct {
+	Wave Waveform `json:"wave"`
+
+	// Envelope: linear attack, sustain with an initial punch, linear decay.
+	Attack  float64 `json:"attack"`
+	Sustain float64 `json:"sustain"`
+	Punch   float64 `json:"punch"` // 0-1, extra volume at the start of sustain
+	Decay   float64 `json:"decay"`
+
+	// Pitch. Slide is in octaves per second and DeltaSlide in octaves per
+	// second squared. The sound stops when the pitch drops below
+	// MinFrequency.
+	Frequency    float64 `json:"frequency"`
+	MinFrequency float64 `json:"min_frequency"`
+	Slide        float64 `json:"slide"`
+	DeltaSlide   float64 `json:"delta_slide"`
+
+	VibratoDepth float64 `json:"vibrato_depth"` // Fraction of the pitch
+	VibratoSpeed float64 `json:"vibrato_speed"` // Hz
+
+	// Arpeggio multiplies the pitch by ArpMod once ArpTime has passed.
+	ArpMod  float64 `json:"arp_mod"`
+	ArpTime float64 `json:"arp_time"`
+
+	// Square wave duty cycle (0-1, default 0.5) and its change per second.
+	Duty      float64 `json:"duty"`
+	DutySweep float64 `json:"duty_sweep"`
+
+	// Repeat restarts pitch, slide and arpeggio every Repeat seconds.
+	Repeat float64 `json:"repeat"`
+
+	LowPass          float64 `json:"low_pass"`           // Cutoff Hz
+	LowPassResonance float64 `json:"low_pass_resonance"` // 0-1
+	HighPass         float64 `json:"high_pass"`          // Cutoff Hz
+
+	Volume float64 `json:"volume"` // 0-1
+	Seed   int64   `json:"seed"`   // Noise source
+}
+
+// DefaultSFXParams returns a short 440 Hz square blip.
+func DefaultSFXParams() SFXParams {
+	return SFXParams{
+		Wave:      WaveSquare,
+		Sustain:   0.1,
+		Decay:     0.15,
+		Frequency: 440,
+		Duty:      0.5,
+		Volume:    0.5,
+	}
+}
+
+// Duration returns the envelope length in seconds. Sounds that slide
+// below MinFrequency end sooner.
+func (p SFXParams) Duration() float64 {
+	return max(p.Attack, 0) + max(p.Sustain, 0) + max(p.Decay, 0)
+}
+
+// noiseTable is the number of random values per noise period, as in sfxr.
+const noiseTable = 32
+
+// Synthesize renders mono samples in [-1, 1].
+func (p SFXParams) Synthesize(sampleRate int) []float64 {
+	if sampleRate <= 0 {
+		sampleRate = DefaultSampleRate
+	}
+
+	rate := float64(sampleRate)
+	dt := 1 / rate
+	attack, sustain, decay := max(p.Attack, 0), max(p.Sustain, 0), max(p.Decay, 0)
+	n := int(p.Duration() * rate)
+	out := make([]float64, 0, n)
+
+	rng := rand.New(rand.NewSource(p.Seed))
+	noise := make([]float64, noiseTable)
+
+	for i := range noise {
+		noise[i] = rng.Float64()*2 - 1
+	}
+
+	var (
+		freq, slide, phase float64
+		arpDone            bool
+		sinceRepeat        float64
+		lpLow, lpBand      float64
+		hpOut, hpPrev      float64
+	)
+
+	reset := func() {
+		freq, slide, arpDone, sinceRepeat = p.Frequency, p.Slide, false, 0
+	}
+	reset()
+
+	// Chamberlin state-variable low-pass; stable below a sixth of the rate.
+	lpF := 2 * math.Sin(math.Pi*min(p.LowPass, rate/6)/rate)
+	lpDamp := 2 * (1 - 0.95*min(max(p.LowPassResonance, 0), 1))
+	hpA := 0.0
+
+	if p.HighPass > 0 {
+		rc := 1 / (2 * math.Pi * p.HighPass)
+		hpA = rc / (rc + dt)
+	}
+
+	duty := p.Duty
+	if duty <= 0 {
+		duty = 0.5
+	}
+
+	for i := range n {
+		t := float64(i) * dt
+
+		if p.Repeat > 0 && sinceRepeat >= p.Repeat {
+			reset()
+		}
+
+		if p.ArpMod > 0 && !arpDone && sinceRepeat >= p.ArpTime {
+			freq *= p.ArpMod
+			arpDone = true
+		}
+
+		slide += p.DeltaSlide * dt
+		freq *= math.Exp2(slide * dt)
+		sinceRepeat += dt
+
+		if p.MinFrequency > 0 && freq < p.MinFrequency {
+			break
+		}
+
+		f := freq
+		if p.VibratoDepth > 0 {
+			f *= 1 + p.VibratoDepth*math.Sin(2*math.Pi*p.VibratoSpeed*t)
+		}
+
+		f = min(max(f, 0), rate/2)
+
+		phase += f * dt
+		if phase >= 1 {
+			phase -= math.Floor(phase)
+
+			if p.Wave == WaveNoise {
+				for j := range noise {
+					noise[j] = rng.Float64()*2 - 1
+				}
+			}
+		}
+
+		var s float64
+
+		switch p.Wave {
+		case WaveSquare:
+			s = 1
+			if phase >= min(max(duty+p.DutySweep*t, 0.01), 0.99) {
+				s = -1
+			}
+		case WaveSaw:
+			s = 1 - 2*phase
+		case WaveSine:
+			s = math.Sin(2 * math.Pi * phase)
+		case WaveNoise:
+			s = noise[int(phase*noiseTable)%noiseTable]
+		}
+
+		if p.LowPass > 0 {
+			lpLow += lpF * lpBand
+			lpBand += lpF * (s - lpLow - lpDamp*lpBand)
+			s = lpLow
+		}
+
+		if hpA > 0 {
+			hpOut = hpA * (hpOut + s - hpPrev)
+			hpPrev = s
+			s = hpOut
+		}
+
+		out = append(out, min(max(s*p.envelope(t, attack, sustain, decay)*p.Volume, -1), 1))
+	}
+
+	return out
+}
+
+func (p SFXParams) envelope(t, attack, sustain, decay float64) float64 {
+	switch {
+	case t < attack:
+		return t / attack
+	case t < attack+sustain:
+		return 1 + max(p.Punch, 0)*(1-(t-attack)/sustain)
+	case decay > 0:
+		return max(1-(t-attack-sustain)/decay, 0)
+	default:
+		return 0
+	}
+}
+
+// PCM renders 16-bit signed little-endian stereo PCM, the sample format
+// of ebiten's audio streams.
+func (p SFXParams) PCM(sampleRate int) []byte {
+	samples := p.Synthesize(sampleRate)
+	out := make([]byte, 0, len(samples)*4)
+
+	for _, s := range samples {
+		v := uint16(int16(s * math.MaxInt16))
+		out = append(out, byte(v), byte(v>>8), byte(v), byte(v>>8))
+	}
+
+	return out
+}
+
+// WAV renders the sound as a 16-bit stereo WAV file at DefaultSampleRate,
+// ready for AudioManager.LoadSoundFromBytes with format ".wav".
+func (p SFXParams) WAV() []byte {
+	var buf bytes.Buffer
+
+	_ = WriteWAV(&buf, p.PCM(DefaultSampleRate), DefaultSampleRate)
+
+	return buf.Bytes()
+}
+
+// WriteWAV writes 16-bit stereo PCM with a WAV header.
+func WriteWAV(w io.Writer, pcm []byte, sampleRate int) error {
+	const channels, bits = 2, 16
+
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE, Fmt     [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF: [4]byte{'R', 'I', 'F', 'F'}, Size: uint32(36 + len(pcm)),
+		WAVE: [4]byte{'W', 'A', 'V', 'E'}, Fmt: [4]byte{'f', 'm', 't', ' '},
+		FmtSize: 16, Format: 1, Channels: channels, SampleRate: uint32(sampleRate),
+		ByteRate: uint32(sampleRate * channels * bits / 8), BlockAlign: channels * bits / 8, BitsPerSample: bits,
+		Data: [4]byte{'d', 'a', 't', 'a'}, DataSize: uint32(len(pcm)),
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(pcm)
+
+	return err
+}
+
+// LoadSynthSound synthesizes p and registers it as a sound effect.
+func (m *AudioManager) LoadSynthSound(name string, p SFXParams) error {
+	return m.LoadSoundFromBytes(name, p.WAV(), ".wav")
+}
+
+// ============================================================================
+// Presets
+// ============================================================================
+
+// SFXPreset names a family of generated sounds.
+type SFXPreset string
+
+const (
+	PresetPickup    SFXPreset = "pickup"
+	PresetLaser     SFXPreset = "laser"
+	PresetExplosion SFXPreset = "explosion"
+	PresetJump      SFXPreset = "jump"
+	PresetHit       SFXPreset = "hit"
+)
+
+// SFXPresets lists every preset.
+var SFXPresets = []SFXPreset{PresetPickup, PresetLaser, PresetExplosion, PresetJump, PresetHit}
+
+// NewSFXPreset generates a sound of the preset's family. The same seed
+// always gives the same sound; different seeds give variations, like the
+// generator buttons of sfxr.
+func NewSFXPreset(preset SFXPreset, seed int64) (SFXParams, error) {
+	rng := rand.New(rand.NewSource(seed))
+	between := func(lo, hi float64) float64 { return lo + rng.Float64()*(hi-lo) }
+	p := DefaultSFXParams()
+	p.Seed = seed
+
+	switch preset {
+	case PresetPickup:
+		p.Frequency = between(700, 1400)
+		p.Sustain = between(0.02, 0.1)
+		p.Punch = between(0.3, 0.6)
+		p.Decay = between(0.1, 0.3)
+
+		if rng.Intn(2) == 0 {
+			p.ArpMod = between(1.25, 1.6)
+			p.ArpTime = between(0.03, 0.08)
+		}
+	case PresetLaser:
+		p.Wave = Waveform(rng.Intn(3))
+		p.Frequency = between(600, 1600)
+		p.MinFrequency = between(60, p.Frequency/4)
+		p.Slide = -between(3, 8)
+		p.Duty = between(0.2, 0.5)
+		p.DutySweep = between(-1, 1)
+		p.Sustain = between(0.05, 0.15)
+		p.Decay = between(0.05, 0.3)
+
+		if rng.Intn(3) == 0 {
+			p.HighPass = between(100, 600)
+		}
+	case PresetExplosion:
+		p.Wave = WaveNoise
+		p.Frequency = between(40, 250)
+		p.Slide = between(-1.5, 0.5)
+		p.Sustain = between(0.1, 0.3)
+		p.Punch = between(0.2, 0.8)
+		p.Decay = between(0.3, 0.7)
+
+		if rng.Intn(2) == 0 {
+			p.VibratoDepth = between(0.05, 0.3)
+			p.VibratoSpeed = between(5, 20)
+		}
+
+		if rng.Intn(2) == 0 {
+			p.LowPass = between(800, 4000)
+			p.LowPassResonance = between(0, 0.5)
+		}
+	case PresetJump:
+		p.Frequency = between(250, 500)
+		p.Slide = between(2, 4)
+		p.Duty = between(0.3, 0.5)
+		p.Sustain = between(0.05, 0.15)
+		p.Decay = between(0.1, 0.25)
+
+		if rng.Intn(2) == 0 {
+			p.LowPass = between(2000, 6000)
+		}
+	case PresetHit:
+		p.Wave = []Waveform{WaveSquare, WaveSaw, WaveNoise}[rng.Intn(3)]
+		p.Frequency = between(150, 600)
+		p.Slide = -between(3, 7)
+		p.Sustain = between(0.02, 0.06)
+		p.Decay = between(0.05, 0.2)
+
+		if rng.Intn(2) == 0 {
+			p.HighPass = between(100, 400)
+		}
+	default:
+		return p, fmt.Errorf("unknown sfx preset: %q", preset)
+	}
+
+	return p, nil
+}
+
+// RandomSFX generates unconstrained parameters, the "randomize" button of
+// sfxr.
+func RandomSFX(seed int64) SFXParams {
+	rng := rand.New(rand.NewSource(seed))
+	between := func(lo, hi float64) float64 { return lo + rng.Float64()*(hi-lo) }
+	chance := func() bool { return rng.Intn(3) == 0 }
+
+	p := SFXParams{
+		Wave:      Waveform(rng.Intn(4)),
+		Attack:    math.Pow(rng.Float64(), 3) * 0.3,
+		Sustain:   between(0.02, 0.3),
+		Punch:     math.Pow(rng.Float64(), 2) * 0.6,
+		Decay:     between(0.05, 0.5),
+		Frequency: 60 * math.Exp2(between(0, 5)),
+		Slide:     math.Pow(between(-1, 1), 3) * 8,
+		Duty:      between(0.1, 0.5),
+		Volume:    0.5,
+		Seed:      seed,
+	}
+
+	if chance() {
+		p.DeltaSlide = between(-4, 4)
+	}
+
+	if chance() {
+		p.VibratoDepth, p.VibratoSpeed = between(0, 0.3), between(2, 20)
+	}
+
+	if chance() {
+		p.ArpMod, p.ArpTime = between(0.5, 2), between(0, 0.2)
+	}
+
+	if chance() {
+		p.Repeat = between(0.05, 0.3)
+	}
+
+	if chance() {
+		p.LowPass, p.LowPassResonance = between(500, 6000), rng.Float64()
+	}
+
+	if chance() {
+		p.HighPass = between(50, 1000)
+	}
+
+	return p
+}
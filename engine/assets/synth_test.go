@@ -0,0 +1,119 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+func TestSynthesize(t *testing.T) {
+	p := DefaultSFXParams()
+	p.Frequency, p.Sustain, p.Decay, p.Volume = 1000, 0.1, 0.1, 1
+
+	samples := p.Synthesize(8000)
+	if len(samples) != 1600 {
+		t.Fatalf("len = %d", len(samples))
+	}
+
+	// A 1 kHz square at 8 kHz alternates every 4 samples.
+	if samples[1] != 1 || samples[5] != -1 || samples[1599] > 0.01 {
+		t.Errorf("square = %v ... %v", samples[:8], samples[1599])
+	}
+
+	crossings := func(s []float64) int {
+		n := 0
+		for i := 1; i < len(s); i++ {
+			if (s[i-1] < 0) != (s[i] < 0) {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	// A rising slide crosses zero more often in its second half.
+	p.Wave, p.Slide = WaveSine, 2
+	samples = p.Synthesize(8000)
+
+	if first, second := crossings(samples[:800]), crossings(samples[800:]); second <= first {
+		t.Errorf("slide crossings %d then %d", first, second)
+	}
+
+	// The sound ends once the pitch slides below MinFrequency.
+	p.Slide, p.MinFrequency = -10, 500
+	if n := len(p.Synthesize(8000)); n >= 1600 || n == 0 {
+		t.Errorf("min frequency cut = %d samples", n)
+	}
+
+	p = DefaultSFXParams()
+	p.Frequency, p.ArpMod, p.ArpTime, p.Volume = 500, 2, 0.1, 1
+	samples = p.Synthesize(8000)
+
+	if before, after := crossings(samples[:800]), crossings(samples[800:1600]); math.Abs(float64(after)/float64(before)-2) > 0.1 {
+		t.Errorf("arpeggio crossings %d then %d", before, after)
+	}
+
+	p.LowPass = 200
+	if filtered := p.Synthesize(8000); slices.Max(filtered[:800]) > 0.5 {
+		t.Errorf("low pass peak = %v", slices.Max(filtered[:800]))
+	}
+}
+
+func TestSFXPresets(t *testing.T) {
+	for _, preset := range SFXPresets {
+		a, err := NewSFXPreset(preset, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := NewSFXPreset(preset, 1)
+		c, _ := NewSFXPreset(preset, 2)
+
+		if a != b || a == c {
+			t.Errorf("%s: seed does not control the variation", preset)
+		}
+
+		samples := a.Synthesize(DefaultSampleRate)
+		if len(samples) == 0 || slices.Max(samples) <= 0 || slices.Min(samples) < -1 {
+			t.Errorf("%s: %d samples, max %v", preset, len(samples), slices.Max(samples))
+		}
+
+		if !slices.Equal(samples, b.Synthesize(DefaultSampleRate)) {
+			t.Errorf("%s: not deterministic", preset)
+		}
+	}
+
+	if _, err := NewSFXPreset("whistle", 1); err == nil {
+		t.Error("unknown preset accepted")
+	}
+
+	for seed := range int64(20) {
+		for _, s := range RandomSFX(seed).Synthesize(8000) {
+			if math.IsNaN(s) || s < -1 || s > 1 {
+				t.Fatalf("random %d produced %v", seed, s)
+			}
+		}
+	}
+}
+
+func TestSFXWAV(t *testing.T) {
+	p, _ := NewSFXPreset(PresetPickup, 3)
+	data := p.WAV()
+
+	if !bytes.HasPrefix(data, []byte("RIFF")) || binary.LittleEndian.Uint32(data[24:]) != DefaultSampleRate {
+		t.Fatalf("header = %q", data[:44])
+	}
+
+	stream, err := wav.DecodeWithSampleRate(DefaultSampleRate, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := int64(len(p.PCM(DefaultSampleRate))); stream.Length() != want {
+		t.Errorf("decoded length = %d, want %d", stream.Length(), want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io/fs"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -38,6 +39,8 @@ type TiledLayer struct {
 }
 
 // Object represents an object in an object layer.
+// Rectangles use X/Y/Width/Height; polygons and polylines hold points
+// relative to X/Y.
 type Object struct {
 	ID         int            `json:"id"`
 	Name       string         `json:"name"`
@@ -46,9 +49,62 @@ type Object struct {
 	Y          float64        `json:"y"`
 	Width      float64        `json:"width"`
 	Height     float64        `json:"height"`
+	Rotation   float64        `json:"rotation,omitempty"`
+	Ellipse    bool           `json:"ellipse,omitempty"`
+	Point      bool           `json:"point,omitempty"`
+	Polygon    []ObjectPoint  `json:"polygon,omitempty"`
+	Polyline   []ObjectPoint  `json:"polyline,omitempty"`
 	Properties map[string]any `json:"properties,omitempty"`
 }
 
+// ObjectPoint is a vertex of a polygon or polyline object.
+type ObjectPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// WorldPolygon returns the object's outline in map coordinates.
+// Rectangles return 4 points, ellipses are approximated with 16 points,
+// and point objects return nil. Rotation is applied around X/Y.
+func (o *Object) WorldPolygon() [][2]float64 {
+	var local [][2]float64
+
+	switch {
+	case o.Point:
+		return nil
+	case len(o.Polygon) > 0:
+		for _, p := range o.Polygon {
+			local = append(local, [2]float64{p.X, p.Y})
+		}
+	case len(o.Polyline) > 0:
+		for _, p := range o.Polyline {
+			local = append(local, [2]float64{p.X, p.Y})
+		}
+	case o.Ellipse:
+		const segments = 16
+
+		rx, ry := o.Width/2, o.Height/2
+		for i := range segments {
+			a := 2 * math.Pi * float64(i) / segments
+			local = append(local, [2]float64{rx + rx*math.Cos(a), ry + ry*math.Sin(a)})
+		}
+	default:
+		local = [][2]float64{{0, 0}, {o.Width, 0}, {o.Width, o.Height}, {0, o.Height}}
+	}
+
+	sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+
+	result := make([][2]float64, len(local))
+	for i, p := range local {
+		result[i] = [2]float64{
+			o.X + p[0]*cos - p[1]*sin,
+			o.Y + p[0]*sin + p[1]*cos,
+		}
+	}
+
+	return result
+}
+
 // Tileset represents a tileset definition.
 type Tileset struct {
 	FirstGID    int    `json:"firstgid"`
@@ -109,6 +165,19 @@ func LoadTiledMap(filesystem fs.FS, mapPath string) (*TiledMap, error) {
 	return &tiledMap, nil
 }
 
+// ObjectLayers returns all object layers in the map.
+func (m *TiledMap) ObjectLayers() []*TiledLayer {
+	var layers []*TiledLayer
+
+	for i := range m.Layers {
+		if m.Layers[i].Type == "objectgroup" {
+			layers = append(layers, &m.Layers[i])
+		}
+	}
+
+	return layers
+}
+
 // GetLayer returns a layer by name.
 func (m *TiledMap) GetLayer(name string) *TiledLayer {
 	for i := range m.Layers {
@@ -177,6 +246,7 @@ type TMXMap struct {
 	TileWidth  int          `xml:"tilewidth,attr"`
 	TileHeight int          `xml:"tileheight,attr"`
 	Layers     []TMXLayer   `xml:"layer"`
+	Objects    []TMXObjects `xml:"objectgroup"`
 	Tilesets   []TMXTileset `xml:"tileset"`
 }
 
@@ -193,6 +263,39 @@ type TMXData struct {
 	Content  string `xml:",chardata"`
 }
 
+type TMXObjects struct {
+	ID      int         `xml:"id,attr"`
+	Name    string      `xml:"name,attr"`
+	Objects []TMXObject `xml:"object"`
+}
+
+type TMXObject struct {
+	ID       int           `xml:"id,attr"`
+	Name     string        `xml:"name,attr"`
+	Type     string        `xml:"type,attr"`
+	Class    string        `xml:"class,attr"`
+	X        float64       `xml:"x,attr"`
+	Y        float64       `xml:"y,attr"`
+	Width    float64       `xml:"width,attr"`
+	Height   float64       `xml:"height,attr"`
+	Rotation float64       `xml:"rotation,attr"`
+	Ellipse  *struct{}     `xml:"ellipse"`
+	Point    *struct{}     `xml:"point"`
+	Polygon  *TMXPoints    `xml:"polygon"`
+	Polyline *TMXPoints    `xml:"polyline"`
+	Props    []TMXProperty `xml:"properties>property"`
+}
+
+type TMXPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type TMXProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+}
+
 type TMXTileset struct {
 	FirstGID   int      `xml:"firstgid,attr"`
 	Name       string   `xml:"name,attr"`
@@ -283,6 +386,22 @@ func LoadTMXMap(filesystem fs.FS, mapPath string) (*TiledMap, error) {
 		tiledMap.Layers = append(tiledMap.Layers, tl)
 	}
 
+	// Convert object groups
+	for _, group := range tmx.Objects {
+		tl := TiledLayer{
+			ID:      group.ID,
+			Name:    group.Name,
+			Type:    "objectgroup",
+			Visible: true,
+		}
+
+		for _, obj := range group.Objects {
+			tl.Objects = append(tl.Objects, obj.toObject())
+		}
+
+		tiledMap.Layers = append(tiledMap.Layers, tl)
+	}
+
 	// Load tileset images
 	loader := NewLoader(filesystem)
 	mapDir := filepath.Dir(mapPath)
@@ -322,3 +441,76 @@ func LoadTMXMap(filesystem fs.FS, mapPath string) (*TiledMap, error) {
 
 	return tiledMap, nil
 }
+
+// toObject converts a TMX object to the JSON object representation.
+func (o TMXObject) toObject() Object {
+	obj := Object{
+		ID:       o.ID,
+		Name:     o.Name,
+		Type:     o.Type,
+		X:        o.X,
+		Y:        o.Y,
+		Width:    o.Width,
+		Height:   o.Height,
+		Rotation: o.Rotation,
+		Ellipse:  o.Ellipse != nil,
+		Point:    o.Point != nil,
+	}
+
+	if obj.Type == "" {
+		obj.Type = o.Class
+	}
+
+	if o.Polygon != nil {
+		obj.Polygon = parseTMXPoints(o.Polygon.Points)
+	}
+
+	if o.Polyline != nil {
+		obj.Polyline = parseTMXPoints(o.Polyline.Points)
+	}
+
+	if len(o.Props) > 0 {
+		obj.Properties = make(map[string]any, len(o.Props))
+		for _, p := range o.Props {
+			obj.Properties[p.Name] = parseTMXValue(p.Type, p.Value)
+		}
+	}
+
+	return obj
+}
+
+// parseTMXPoints parses a TMX point list ("x1,y1 x2,y2 ...").
+func parseTMXPoints(s string) []ObjectPoint {
+	var points []ObjectPoint
+
+	for pair := range strings.FieldsSeq(s) {
+		xs, ys, ok := strings.Cut(pair, ",")
+		if !ok {
+			continue
+		}
+
+		x, _ := strconv.ParseFloat(xs, 64)
+		y, _ := strconv.ParseFloat(ys, 64)
+		points = append(points, ObjectPoint{X: x, Y: y})
+	}
+
+	return points
+}
+
+// parseTMXValue converts a typed TMX property value.
+func parseTMXValue(typ, value string) any {
+	switch typ {
+	case "int":
+		v, _ := strconv.Atoi(value)
+
+		return v
+	case "float":
+		v, _ := strconv.ParseFloat(value, 64)
+
+		return v
+	case "bool":
+		return value == "true"
+	default:
+		return value
+	}
+}
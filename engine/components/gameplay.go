@@ -251,3 +251,9 @@ func (bc *BuffContainer) RemoveExpired() {
 
 	bc.Buffs = active
 }
+
+// Interactable marks an entity agents can pick up or talk to.
+type Interactable struct {
+	Range      float64 // Interaction distance (0 = the agent's default)
+	DialogueID string  // Dialogue started when talked to
+}
@@ -1,5 +1,7 @@
 package components
 
+import "math/rand"
+
 // ItemRarity represents the rarity tier of an item.
 type ItemRarity int
 
@@ -320,6 +322,56 @@ func (lt *LootTable) AddEntry(entry LootEntry) {
 	lt.Entries = append(lt.Entries, entry)
 }
 
+// Roll draws one set of drops, returning item ID -> count. Guaranteed items
+// always drop; the rest are picked by weight.
+func (lt *LootTable) Roll(rng *rand.Rand) map[string]int {
+	drops := make(map[string]int)
+	for _, id := range lt.Guaranteed {
+		drops[id]++
+	}
+
+	if len(lt.Entries) == 0 || rng.Float64() < lt.NothingChance {
+		return drops
+	}
+
+	total := 0.0
+	for _, entry := range lt.Entries {
+		total += entry.Weight
+	}
+
+	if total <= 0 {
+		return drops
+	}
+
+	count := lt.MinDrops
+	if lt.MaxDrops > lt.MinDrops {
+		count += rng.Intn(lt.MaxDrops - lt.MinDrops + 1)
+	}
+
+	for range count {
+		pick := rng.Float64() * total
+
+		entry := lt.Entries[len(lt.Entries)-1]
+		for _, e := range lt.Entries {
+			pick -= e.Weight
+			if pick < 0 {
+				entry = e
+
+				break
+			}
+		}
+
+		n := max(entry.MinCount, 1)
+		if entry.MaxCount > n {
+			n += rng.Intn(entry.MaxCount - n + 1)
+		}
+
+		drops[entry.ItemID] += n
+	}
+
+	return drops
+}
+
 // CraftingRecipe defines how to craft an item.
 type CraftingRecipe struct {
 	ID          string
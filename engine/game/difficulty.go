@@ -1,5 +1,7 @@
 package game
 
+import "math"
+
 // DifficultyLevel represents a preset difficulty.
 type DifficultyLevel int
 
@@ -126,10 +128,23 @@ func (d *DifficultySettings) SetLevel(level DifficultyLevel) {
 	}
 }
 
+// DifficultyModel is a pluggable dynamic difficulty estimator, such as a
+// per-player model from the ai package's DifficultyEngine.
+type DifficultyModel interface {
+	RecordWin()
+	RecordLoss()
+	// Scale returns the enemy multiplier for the current difficulty.
+	Scale() float64
+}
+
 // DifficultyManager manages difficulty and dynamic adjustment.
 type DifficultyManager struct {
 	Settings *DifficultySettings
 
+	// Model replaces the built-in win/loss counters when set; WinsRecent
+	// and LossesRecent then stay at zero.
+	Model DifficultyModel
+
 	// Dynamic difficulty tracking
 	WinsRecent    int     // Recent wins
 	LossesRecent  int     // Recent losses
@@ -159,13 +174,23 @@ func (dm *DifficultyManager) SetDifficulty(level DifficultyLevel) {
 
 // RecordWin records a player win for dynamic adjustment.
 func (dm *DifficultyManager) RecordWin() {
-	dm.WinsRecent++
+	if dm.Model != nil {
+		dm.Model.RecordWin()
+	} else {
+		dm.WinsRecent++
+	}
+
 	dm.evaluate()
 }
 
 // RecordLoss records a player loss for dynamic adjustment.
 func (dm *DifficultyManager) RecordLoss() {
-	dm.LossesRecent++
+	if dm.Model != nil {
+		dm.Model.RecordLoss()
+	} else {
+		dm.LossesRecent++
+	}
+
 	dm.evaluate()
 }
 
@@ -175,6 +200,12 @@ func (dm *DifficultyManager) evaluate() {
 		return
 	}
 
+	if dm.Model != nil {
+		dm.CurrentScale = math.Max(dm.MinMultiplier, math.Min(dm.MaxMultiplier, dm.Model.Scale()))
+
+		return
+	}
+
 	total := dm.WinsRecent + dm.LossesRecent
 	if total < dm.HistorySize/2 {
 		return // Not enough data
@@ -0,0 +1,64 @@
+package graphics
+
+import (
+	"image"
+	"image/draw"
+)
+
+// SliceSheet cuts a sprite sheet into frameW x frameH frames, left to
+// right and top to bottom. Partial frames at the edges are dropped. Each
+// frame is a copy whose bounds start at (0, 0).
+func SliceSheet(src image.Image, frameW, frameH int) []*image.RGBA {
+	bounds := src.Bounds()
+	if frameW <= 0 || frameH <= 0 {
+		return nil
+	}
+
+	var frames []*image.RGBA
+
+	for y := bounds.Min.Y; y+frameH <= bounds.Max.Y; y += frameH {
+		for x := bounds.Min.X; x+frameW <= bounds.Max.X; x += frameW {
+			frame := image.NewRGBA(image.Rect(0, 0, frameW, frameH))
+			draw.Draw(frame, frame.Bounds(), src, image.Pt(x, y), draw.Src)
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames
+}
+
+// OpaqueBounds returns the smallest rectangle holding every pixel that is
+// not fully transparent, or an empty rectangle.
+func OpaqueBounds(src image.Image) image.Rectangle {
+	bounds := src.Bounds()
+	box := image.Rectangle{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			//nolint:dogsled // We only need alpha here to check transparency
+			if _, _, _, a := src.At(x, y).RGBA(); a == 0 {
+				continue
+			}
+
+			box = box.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+
+	return box
+}
+
+// ScaleNearest resizes src to w x h with nearest-neighbor sampling, which
+// keeps pixel art and chroma key colors exact.
+func ScaleNearest(src image.Image, w, h int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		sy := bounds.Min.Y + y*bounds.Dy()/h
+		for x := range w {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/w, sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,123 @@
+package prefab
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+)
+
+// LevelEntity is an entity spawned from a Tiled object.
+type LevelEntity struct {
+	Layer  string
+	Object *assets.Object
+	Prefab string
+	Entity ecs.Entity
+}
+
+// Level is the result of loading a Tiled map's object layers.
+type Level struct {
+	Entities []LevelEntity
+	Named    map[string]ecs.Entity // Entities of objects with a name
+	Skipped  []*assets.Object      // Objects without a prefab
+}
+
+// LoadLevel instantiates the objects in a Tiled map's object layers. Pass
+// layer names to load only those layers.
+//
+// An object's prefab is its "prefab" property, or else its type (class).
+// The object's position overrides Position, its size overrides Collider
+// width and height when the prefab has a Collider, and properties named
+// "Component.Field" (e.g. "Health.Max") override single fields of
+// registered components; other properties are ignored. Objects without a
+// prefab are skipped; unknown prefabs and bad fields are errors.
+func (l *Library) LoadLevel(world *ecs.World, m *assets.TiledMap, layers ...string) (*Level, error) {
+	level := &Level{Named: make(map[string]ecs.Entity)}
+
+	var errs []error
+
+	for _, layer := range m.ObjectLayers() {
+		if len(layers) > 0 && !slices.Contains(layers, layer.Name) {
+			continue
+		}
+
+		for i := range layer.Objects {
+			obj := &layer.Objects[i]
+
+			name, _ := obj.Properties["prefab"].(string)
+			if name == "" {
+				name = obj.Type
+			}
+
+			if name == "" {
+				level.Skipped = append(level.Skipped, obj)
+
+				continue
+			}
+
+			overrides, err := l.objectOverrides(name, obj)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("layer %q object %d: %w", layer.Name, obj.ID, err))
+
+				continue
+			}
+
+			e, err := l.Instantiate(world, name, overrides)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("layer %q object %d: %w", layer.Name, obj.ID, err))
+
+				continue
+			}
+
+			level.Entities = append(level.Entities, LevelEntity{
+				Layer:  layer.Name,
+				Object: obj,
+				Prefab: name,
+				Entity: e,
+			})
+
+			if obj.Name != "" {
+				level.Named[obj.Name] = e
+			}
+		}
+	}
+
+	return level, errors.Join(errs...)
+}
+
+// objectOverrides builds the overrides for one Tiled object.
+func (l *Library) objectOverrides(prefab string, obj *assets.Object) (Overrides, error) {
+	comps, err := l.Resolve(prefab)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := Overrides{"Position": {"X": obj.X, "Y": obj.Y}}
+
+	if _, ok := comps["Collider"]; ok && obj.Width > 0 && obj.Height > 0 {
+		overrides["Collider"] = Fields{"Width": obj.Width, "Height": obj.Height}
+	}
+
+	for key, value := range obj.Properties {
+		comp, field, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+
+		// Other dotted properties belong to the editor or the game.
+		if _, known := l.Registry.Lookup(comp); !known {
+			continue
+		}
+
+		if overrides[comp] == nil {
+			overrides[comp] = Fields{}
+		}
+
+		overrides[comp][field] = value
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,408 @@
+// Package prefab defines entities in data files.
+//
+// A prefab is a named set of components with default field values. Prefabs
+// can extend other prefabs, and each instance can override fields:
+//
+//	name: goblin
+//	extends: enemy
+//	components:
+//	  Health: {Current: 30, Max: 30}
+//	  Sprite: {Image: sprites/goblin.png}
+//	  Tag: {Name: goblin}
+//
+// Components are looked up by name in a Registry, which maps names to the
+// types in the components package.
+package prefab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefab is a reusable entity template. Components are merged over those of
+// the Extends prefab like Overrides, so a nil entry drops an inherited
+// component.
+type Prefab struct {
+	Name       string            `json:"name"              yaml:"name"`
+	Extends    string            `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Components map[string]Fields `json:"components"        yaml:"components"`
+}
+
+// Overrides change component fields of one instance, keyed by component
+// name. Fields are merged over the prefab's; a nil Fields value removes the
+// component, and a component the prefab lacks is added.
+type Overrides map[string]Fields
+
+// ============================================================================
+// Parsing
+// ============================================================================
+
+// prefabFile is the on-disk format: one prefab, or a list under "prefabs".
+type prefabFile struct {
+	Prefab  `yaml:",inline"`
+	Prefabs []*Prefab `json:"prefabs,omitempty" yaml:"prefabs,omitempty"`
+}
+
+func (f *prefabFile) list() []*Prefab {
+	if len(f.Prefabs) > 0 {
+		return f.Prefabs
+	}
+
+	p := f.Prefab
+
+	return []*Prefab{&p}
+}
+
+// ParseJSON decodes a JSON file holding one prefab or {"prefabs": [...]}.
+func ParseJSON(data []byte) ([]*Prefab, error) {
+	var f prefabFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse prefab: %w", err)
+	}
+
+	return f.list(), nil
+}
+
+// ParseYAML decodes a YAML file holding one prefab or a "prefabs" list.
+func ParseYAML(data []byte) ([]*Prefab, error) {
+	var f prefabFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse prefab: %w", err)
+	}
+
+	return f.list(), nil
+}
+
+// parseFile decodes prefab data, choosing the format by extension.
+func parseFile(path string, data []byte) ([]*Prefab, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	default:
+		return ParseJSON(data)
+	}
+}
+
+// ============================================================================
+// Library
+// ============================================================================
+
+// Library holds prefabs and instantiates them.
+type Library struct {
+	Registry  *Registry
+	Resources Resources
+
+	prefabs map[string]*Prefab
+}
+
+// NewLibrary creates an empty library. A nil registry uses DefaultRegistry.
+func NewLibrary(registry *Registry) *Library {
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+
+	return &Library{Registry: registry, prefabs: make(map[string]*Prefab)}
+}
+
+// Add adds or replaces a prefab.
+func (l *Library) Add(p *Prefab) error {
+	if p == nil || p.Name == "" {
+		return errors.New("prefab: missing name")
+	}
+
+	l.prefabs[p.Name] = p
+
+	return nil
+}
+
+// Get returns a prefab by name.
+func (l *Library) Get(name string) (*Prefab, bool) {
+	p, ok := l.prefabs[name]
+
+	return p, ok
+}
+
+// Names returns the prefab names, sorted.
+func (l *Library) Names() []string {
+	return slices.Sorted(maps.Keys(l.prefabs))
+}
+
+// LoadFile adds the prefabs in a JSON or YAML file.
+func (l *Library) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return l.load(path, data)
+}
+
+// LoadFS adds every .json, .yaml and .yml file under dir in fsys.
+func (l *Library) LoadFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json", ".yaml", ".yml":
+		default:
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		return l.load(path, data)
+	})
+}
+
+func (l *Library) load(path string, data []byte) error {
+	prefabs, err := parseFile(path, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, p := range prefabs {
+		if err := l.Add(p); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns the components of a prefab with inheritance applied:
+// each prefab's fields are merged over those of the prefab it extends.
+func (l *Library) Resolve(name string) (map[string]Fields, error) {
+	return l.resolve(name, nil)
+}
+
+func (l *Library) resolve(name string, chain []string) (map[string]Fields, error) {
+	if slices.Contains(chain, name) {
+		return nil, fmt.Errorf("prefab %q: inheritance cycle %s", name, strings.Join(append(chain, name), " -> "))
+	}
+
+	p, ok := l.prefabs[name]
+	if !ok {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("prefab %q: extends unknown prefab %q", chain[len(chain)-1], name)
+		}
+
+		return nil, fmt.Errorf("unknown prefab %q", name)
+	}
+
+	comps := make(map[string]Fields)
+
+	if p.Extends != "" {
+		parent, err := l.resolve(p.Extends, append(chain, name))
+		if err != nil {
+			return nil, err
+		}
+
+		comps = parent
+	}
+
+	return applyOverrides(comps, Overrides(p.Components)), nil
+}
+
+// Validate resolves and decodes every prefab, reporting all errors.
+func (l *Library) Validate() error {
+	var errs []error
+
+	for _, name := range l.Names() {
+		comps, err := l.Resolve(name)
+		if err == nil {
+			_, err = l.decode(comps)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("prefab %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Instantiate creates an entity from a prefab in the library.
+func (l *Library) Instantiate(world *ecs.World, name string, overrides Overrides) (ecs.Entity, error) {
+	comps, err := l.Resolve(name)
+	if err != nil {
+		return ecs.Entity{}, err
+	}
+
+	e, err := l.spawn(world, applyOverrides(comps, overrides))
+	if err != nil {
+		return ecs.Entity{}, fmt.Errorf("prefab %q: %w", name, err)
+	}
+
+	return e, nil
+}
+
+// Instantiate creates an entity from a standalone prefab using
+// DefaultRegistry. Prefabs that extend others need a Library.
+func Instantiate(world *ecs.World, p *Prefab, overrides Overrides) (ecs.Entity, error) {
+	if p.Extends != "" {
+		return ecs.Entity{}, fmt.Errorf("prefab %q: extends %q, use a Library", p.Name, p.Extends)
+	}
+
+	lib := NewLibrary(nil)
+
+	e, err := lib.spawn(world, applyOverrides(applyOverrides(nil, Overrides(p.Components)), overrides))
+	if err != nil {
+		return ecs.Entity{}, fmt.Errorf("prefab %q: %w", p.Name, err)
+	}
+
+	return e, nil
+}
+
+// decoded is one component ready to add.
+type decoded struct {
+	typ   *ComponentType
+	value any
+}
+
+// decode turns resolved components into values, sorted by name.
+func (l *Library) decode(comps map[string]Fields) ([]decoded, error) {
+	out := make([]decoded, 0, len(comps))
+
+	for _, name := range slices.Sorted(maps.Keys(comps)) {
+		typ, ok := l.Registry.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown component %q", name)
+		}
+
+		v, err := typ.Decode(comps[name], &l.Resources)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, decoded{typ: typ, value: v})
+	}
+
+	return out, nil
+}
+
+// spawn decodes all components first so a bad field creates no entity.
+func (l *Library) spawn(world *ecs.World, comps map[string]Fields) (ecs.Entity, error) {
+	values, err := l.decode(comps)
+	if err != nil {
+		return ecs.Entity{}, err
+	}
+
+	e := world.NewEntity()
+	for _, d := range values {
+		d.typ.Add(world, e, d.value)
+	}
+
+	return e, nil
+}
+
+// ============================================================================
+// Merging
+// ============================================================================
+
+// applyOverrides returns comps with overrides merged in. Neither input is
+// modified.
+func applyOverrides(comps map[string]Fields, overrides Overrides) map[string]Fields {
+	out := make(map[string]Fields, len(comps))
+	for name, fields := range comps {
+		out[name] = deepCopy(fields)
+	}
+
+	for name, fields := range overrides {
+		if fields == nil {
+			delete(out, name)
+
+			continue
+		}
+
+		out[name] = mergeFields(out[name], fields)
+	}
+
+	return out
+}
+
+// mergeFields deep-merges src over a copy of dst. Nested objects merge;
+// everything else is replaced. Keys match case-insensitively, like Go
+// field names, and the src spelling wins.
+func mergeFields(dst, src Fields) Fields {
+	out := deepCopy(dst)
+	if out == nil {
+		out = make(Fields, len(src))
+	}
+
+	for k, v := range src {
+		for existing, old := range out {
+			if existing != k && strings.EqualFold(existing, k) {
+				delete(out, existing)
+				out[k] = old
+			}
+		}
+
+		sub, isMap := asFields(v)
+		cur, curIsMap := asFields(out[k])
+
+		if isMap && curIsMap {
+			out[k] = map[string]any(mergeFields(cur, sub))
+		} else {
+			out[k] = deepCopyValue(v)
+		}
+	}
+
+	return out
+}
+
+func asFields(v any) (Fields, bool) {
+	switch m := v.(type) {
+	case Fields:
+		return m, true
+	case map[string]any:
+		return m, true
+	}
+
+	return nil, false
+}
+
+func deepCopy(f Fields) Fields {
+	if f == nil {
+		return nil
+	}
+
+	out := make(Fields, len(f))
+	for k, v := range f {
+		out[k] = deepCopyValue(v)
+	}
+
+	return out
+}
+
+func deepCopyValue(v any) any {
+	switch t := v.(type) {
+	case Fields:
+		return map[string]any(deepCopy(t))
+	case map[string]any:
+		return map[string]any(deepCopy(t))
+	case []any:
+		out := make([]any, len(t))
+		for i, x := range t {
+			out[i] = deepCopyValue(x)
+		}
+
+		return out
+	}
+
+	return v
+}
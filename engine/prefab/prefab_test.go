@@ -0,0 +1,261 @@
+package prefab
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+var testPrefabs = fstest.MapFS{
+	"prefabs/enemies.yaml": {Data: []byte(`
+prefabs:
+  - name: enemy
+    components:
+      Position: {}
+      Velocity: {X: -1}
+      Health: {Current: 10, Max: 10}
+      Collider: {Width: 16, Height: 16}
+      Tag: {Name: enemy}
+  - name: goblin
+    extends: enemy
+    components:
+      Health: {Current: 30, Max: 30}
+      Sprite: {Image: goblin.png, ScaleX: 2}
+  - name: ghost
+    extends: goblin
+    components:
+      Collider: null
+`)},
+	"prefabs/player.json": {Data: []byte(`{
+  "name": "player",
+  "components": {"Position": {}, "Health": {"Max": 5, "Current": 5}, "Tag": {"Name": "player"}}
+}`)},
+	"prefabs/readme.txt": {Data: []byte("not a prefab")},
+}
+
+func newTestLibrary(t *testing.T) *Library {
+	t.Helper()
+
+	lib := NewLibrary(nil)
+	lib.Resources.LoadImage = func(path string) (*ebiten.Image, error) {
+		if path != "goblin.png" {
+			return nil, errors.New("missing image " + path)
+		}
+
+		return ebiten.NewImage(8, 8), nil
+	}
+
+	if err := lib.LoadFS(testPrefabs, "prefabs"); err != nil {
+		t.Fatal(err)
+	}
+
+	return lib
+}
+
+func TestLibraryInheritanceAndOverrides(t *testing.T) {
+	lib := newTestLibrary(t)
+
+	if got := strings.Join(lib.Names(), ","); got != "enemy,ghost,goblin,player" {
+		t.Fatalf("Names = %s", got)
+	}
+
+	if err := lib.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	world := ecs.NewWorld()
+
+	e, err := lib.Instantiate(&world, "goblin", Overrides{
+		"Position": {"X": 5, "Y": 6},
+		"Health":   {"Current": 12},
+		"Tag":      nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos := ecs.NewMap[components.Position](&world).Get(e); pos.X != 5 || pos.Y != 6 {
+		t.Errorf("Position = %+v", *pos)
+	}
+
+	if h := ecs.NewMap[components.Health](&world).Get(e); h.Current != 12 || h.Max != 30 {
+		t.Errorf("Health = %+v, want inherited Max 30 and overridden Current 12", *h)
+	}
+
+	if v := ecs.NewMap[components.Velocity](&world).Get(e); v.X != -1 {
+		t.Errorf("Velocity should be inherited from enemy, got %+v", *v)
+	}
+
+	sprite := ecs.NewMap[components.Sprite](&world).Get(e)
+	if sprite.Image == nil || sprite.ScaleX != 2 || sprite.ScaleY != 1 || !sprite.Visible {
+		t.Errorf("Sprite = %+v", *sprite)
+	}
+
+	if ecs.NewMap[components.Tag](&world).Has(e) {
+		t.Error("A nil override should remove the Tag component")
+	}
+
+	ghost, err := lib.Instantiate(&world, "ghost", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ecs.NewMap[components.Collider](&world).Has(ghost) {
+		t.Error("ghost should drop the inherited Collider")
+	}
+
+	enemy, _ := lib.Get("enemy")
+	if enemy.Components["Health"]["Current"] != 10 {
+		t.Error("Instantiating must not modify the base prefab")
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	lib := NewLibrary(nil)
+	_ = lib.Add(&Prefab{Name: "a", Extends: "b"})
+	_ = lib.Add(&Prefab{Name: "b", Extends: "a"})
+	_ = lib.Add(&Prefab{Name: "typo", Components: map[string]Fields{"Health": {"Maximum": 3}}})
+	_ = lib.Add(&Prefab{Name: "unknown", Components: map[string]Fields{"Jetpack": {}}})
+	_ = lib.Add(&Prefab{Name: "orphan", Extends: "missing"})
+
+	err := lib.Validate()
+	if err == nil {
+		t.Fatal("Validate should fail")
+	}
+
+	for _, want := range []string{"inheritance cycle", `"Maximum"`, `unknown component "Jetpack"`, `unknown prefab "missing"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("errors should mention %s, got:\n%v", want, err)
+		}
+	}
+
+	world := ecs.NewWorld()
+	before := world.Stats().Entities.Used
+
+	if _, err := lib.Instantiate(&world, "typo", nil); err == nil {
+		t.Error("Instantiate should fail on unknown fields")
+	}
+
+	if world.Stats().Entities.Used != before {
+		t.Error("A failed Instantiate should not create an entity")
+	}
+}
+
+func TestInstantiateStandalone(t *testing.T) {
+	prefabs, err := ParseJSON([]byte(`{"name": "coin", "components": {"Position": {"X": 1}, "Score": {}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world := ecs.NewWorld()
+
+	e, err := Instantiate(&world, prefabs[0], Overrides{"Position": {"Y": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos := ecs.NewMap[components.Position](&world).Get(e); pos.X != 1 || pos.Y != 2 {
+		t.Errorf("Position = %+v", *pos)
+	}
+
+	if !ecs.NewMap[components.Score](&world).Has(e) {
+		t.Error("coin should have a Score")
+	}
+}
+
+func TestOverrideFieldCase(t *testing.T) {
+	prefabs, err := ParseJSON([]byte(`{"name": "slime", "components": {"Health": {"current": 10, "max": 10}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world := ecs.NewWorld()
+
+	e, err := Instantiate(&world, prefabs[0], Overrides{"Health": {"Current": 99}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hp := ecs.NewMap[components.Health](&world).Get(e); hp.Current != 99 || hp.Max != 10 {
+		t.Errorf("Health = %+v, want the override to replace current", *hp)
+	}
+}
+
+func TestCustomRegistry(t *testing.T) {
+	type Speed struct{ Value float64 }
+
+	reg := NewRegistry()
+	Register(reg, "Speed", func() Speed { return Speed{Value: 3} })
+
+	lib := NewLibrary(reg)
+	_ = lib.Add(&Prefab{Name: "fast", Components: map[string]Fields{"Speed": {}}})
+
+	world := ecs.NewWorld()
+
+	e, err := lib.Instantiate(&world, "fast", Overrides{"Speed": {"value": 7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := ecs.NewMap[Speed](&world).Get(e); s.Value != 7 {
+		t.Errorf("Speed = %v, want 7", s.Value)
+	}
+
+	if _, err := lib.Instantiate(&world, "fast", Overrides{"Position": {}}); err == nil {
+		t.Error("Components missing from the registry should fail")
+	}
+}
+
+func TestLoadLevel(t *testing.T) {
+	lib := newTestLibrary(t)
+	m := &assets.TiledMap{Layers: []assets.TiledLayer{
+		{Name: "ground", Type: "tilelayer"},
+		{Name: "spawns", Type: "objectgroup", Objects: []assets.Object{
+			{ID: 1, Name: "boss", Type: "goblin", X: 32, Y: 48, Width: 24, Height: 20,
+				Properties: map[string]any{"Health.Max": 99.0, "ai.mode": "patrol", "editor.note": "guards the gate"}},
+			{ID: 2, X: 10, Y: 10, Properties: map[string]any{"prefab": "player"}},
+			{ID: 3, Name: "trigger", X: 0, Y: 0},
+		}},
+		{Name: "decor", Type: "objectgroup", Objects: []assets.Object{{ID: 4, Type: "ghost"}}},
+	}}
+
+	world := ecs.NewWorld()
+
+	level, err := lib.LoadLevel(&world, m, "spawns")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(level.Entities) != 2 || len(level.Skipped) != 1 {
+		t.Fatalf("Entities = %d, skipped = %d", len(level.Entities), len(level.Skipped))
+	}
+
+	boss := level.Named["boss"]
+
+	if pos := ecs.NewMap[components.Position](&world).Get(boss); pos.X != 32 || pos.Y != 48 {
+		t.Errorf("boss Position = %+v", *pos)
+	}
+
+	if c := ecs.NewMap[components.Collider](&world).Get(boss); c.Width != 24 || c.Height != 20 {
+		t.Errorf("boss Collider = %+v, want object size", *c)
+	}
+
+	if h := ecs.NewMap[components.Health](&world).Get(boss); h.Max != 99 || h.Current != 30 {
+		t.Errorf("boss Health = %+v", *h)
+	}
+
+	if level.Entities[1].Prefab != "player" {
+		t.Errorf("Object with a prefab property should use it, got %q", level.Entities[1].Prefab)
+	}
+
+	m.Layers[1].Objects[0].Properties["Health.Maximum"] = 1.0
+	if _, err := lib.LoadLevel(&world, m); err == nil || !strings.Contains(err.Error(), "object 1") {
+		t.Errorf("Bad properties should be reported with the object, got %v", err)
+	}
+}
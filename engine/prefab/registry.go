@@ -0,0 +1,206 @@
+package prefab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// Fields holds component field values decoded from JSON or YAML.
+// Keys match Go field names case-insensitively.
+type Fields map[string]any
+
+// Resources gives component decoders access to external data.
+type Resources struct {
+	// LoadImage loads an image by path, e.g. for Sprite.Image.
+	LoadImage func(path string) (*ebiten.Image, error)
+}
+
+// ComponentType is a component registered under a name.
+type ComponentType struct {
+	Name string
+	Type reflect.Type
+
+	decode func(fields Fields, res *Resources) (any, error)
+	add    func(world *ecs.World, e ecs.Entity, value any)
+}
+
+// Decode builds a component value from fields. The result is a pointer to
+// the component type.
+func (c *ComponentType) Decode(fields Fields, res *Resources) (any, error) {
+	if res == nil {
+		res = &Resources{}
+	}
+
+	v, err := c.decode(fields, res)
+	if err != nil {
+		return nil, fmt.Errorf("component %s: %w", c.Name, err)
+	}
+
+	return v, nil
+}
+
+// Add adds or replaces a decoded component on an entity.
+func (c *ComponentType) Add(world *ecs.World, e ecs.Entity, value any) {
+	c.add(world, e, value)
+}
+
+// Registry maps component names to component types.
+type Registry struct {
+	types map[string]*ComponentType
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]*ComponentType)}
+}
+
+// Register adds a component type whose fields are decoded over the value
+// returned by defaults. Unknown fields are an error.
+func Register[T any](r *Registry, name string, defaults func() T) {
+	RegisterDecoder(r, name, func(fields Fields, _ *Resources) (T, error) {
+		v := defaults()
+
+		return v, DecodeFields(fields, &v)
+	})
+}
+
+// RegisterDecoder adds a component type with a custom decoder, for
+// components with fields that cannot be written as plain data.
+func RegisterDecoder[T any](r *Registry, name string, decode func(fields Fields, res *Resources) (T, error)) {
+	r.types[name] = &ComponentType{
+		Name: name,
+		Type: reflect.TypeFor[T](),
+		decode: func(fields Fields, res *Resources) (any, error) {
+			v, err := decode(fields, res)
+			if err != nil {
+				return nil, err
+			}
+
+			return &v, nil
+		},
+		add: func(world *ecs.World, e ecs.Entity, value any) {
+			m := ecs.NewMap[T](world)
+			if m.Has(e) {
+				*m.Get(e) = *value.(*T)
+
+				return
+			}
+
+			m.Add(e, value.(*T))
+		},
+	}
+}
+
+// Lookup returns a registered component type.
+func (r *Registry) Lookup(name string) (*ComponentType, bool) {
+	c, ok := r.types[name]
+
+	return c, ok
+}
+
+// Names returns the registered component names, sorted.
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.types))
+}
+
+// DecodeFields decodes fields over the existing value of out, rejecting
+// fields out does not have.
+func DecodeFields(fields Fields, out any) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(out)
+}
+
+// ============================================================================
+// Default Registry
+// ============================================================================
+
+// DefaultRegistry returns a registry with the engine's data components.
+// Defaults match the components' constructors.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	Register(r, "Position", func() components.Position { return components.Position{} })
+	Register(r, "Velocity", func() components.Velocity { return components.Velocity{} })
+	Register(r, "Collider", func() components.Collider { return components.Collider{Layer: 1, Mask: 1} })
+	Register(r, "Health", func() components.Health { return components.NewHealth(100) })
+	Register(r, "Tag", func() components.Tag { return components.Tag{} })
+	Register(r, "AIMetadata", func() components.AIMetadata { return components.AIMetadata{} })
+	Register(r, "Combat", func() components.Combat { return components.NewCombat(10, 0) })
+	Register(r, "Cooldown", func() components.Cooldown { return components.NewCooldown(1) })
+	Register(r, "CriticalHit", func() components.CriticalHit { return components.NewCriticalHit(0.05, 2) })
+	Register(r, "Mana", func() components.Mana { return components.NewMana(100) })
+	Register(r, "Movement", func() components.Movement { return components.NewMovement(2) })
+	Register(r, "Jump", func() components.Jump { return components.NewJump(10) })
+	Register(r, "Dash", func() components.Dash { return components.NewDash(10, 0.2, 1) })
+	Register(r, "Flight", func() components.Flight { return components.NewFlight(3) })
+	Register(r, "PlatformerPhysics", components.NewPlatformerPhysics)
+	Register(r, "ZIndex", func() components.ZIndex { return components.ZIndex{} })
+	Register(r, "Elevation", func() components.Elevation { return components.Elevation{} })
+	Register(r, "Shadow", components.NewShadow)
+	Register(r, "SortLayer", func() components.SortLayer { return components.SortLayer{} })
+	Register(r, "Lives", func() components.Lives { return components.NewLives(3) })
+	Register(r, "Timer", func() components.Timer { return components.NewTimer(1) })
+	Register(r, "Currency", components.NewCurrency)
+	Register(r, "Score", func() components.Score { return components.NewScore(1) })
+	Register(r, "Experience", func() components.Experience { return components.NewExperience(100, 1.5) })
+	Register(r, "Level", func() components.Level { return components.NewLevel(99) })
+	Register(r, "Inventory", func() components.Inventory { return components.NewInventory(20) })
+	RegisterDecoder(r, "Sprite", decodeSprite)
+
+	return r
+}
+
+// decodeSprite decodes a Sprite whose Image field is an image path.
+func decodeSprite(fields Fields, res *Resources) (components.Sprite, error) {
+	sprite := components.NewSprite(nil)
+	rest := maps.Clone(fields)
+
+	var path string
+
+	for k, v := range fields {
+		if k == "image" || k == "Image" {
+			p, ok := v.(string)
+			if !ok {
+				return sprite, fmt.Errorf("image must be a path, got %T", v)
+			}
+
+			path = p
+
+			delete(rest, k)
+		}
+	}
+
+	if err := DecodeFields(rest, &sprite); err != nil {
+		return sprite, err
+	}
+
+	if path != "" && res.LoadImage != nil {
+		img, err := res.LoadImage(path)
+		if err != nil {
+			return sprite, err
+		}
+
+		sprite.Image = img
+	}
+
+	return sprite, nil
+}
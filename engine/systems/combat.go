@@ -54,6 +54,9 @@ func (s *CombatSystem) CanAttack(world *ecs.World, attacker ecs.Entity) bool {
 		}
 
 		combat := query.Get()
+
+		query.Close()
+
 		if !combat.CanAttack {
 			return false
 		}
@@ -85,6 +88,8 @@ func (s *CombatSystem) Attack(world *ecs.World, attacker, target ecs.Entity) boo
 		if entity == attacker {
 			attackerCombat = query.Get()
 
+			query.Close()
+
 			break
 		}
 	}
@@ -100,6 +105,8 @@ func (s *CombatSystem) Attack(world *ecs.World, attacker, target ecs.Entity) boo
 		if entity == attacker {
 			attackerCrit = critQuery.Get()
 
+			critQuery.Close()
+
 			break
 		}
 	}
@@ -111,6 +118,8 @@ func (s *CombatSystem) Attack(world *ecs.World, attacker, target ecs.Entity) boo
 		if entity == attacker {
 			attackerBuffs = buffQuery.Get()
 
+			buffQuery.Close()
+
 			break
 		}
 	}
@@ -165,7 +174,11 @@ func (s *CombatSystem) GetCombatStats(world *ecs.World, entity ecs.Entity) *comp
 	for query.Next() {
 		e := query.Entity()
 		if e == entity {
-			return query.Get()
+			combat := query.Get()
+
+			query.Close()
+
+			return combat
 		}
 	}
 
@@ -202,6 +215,8 @@ func (s *CombatSystem) ApplyBuff(world *ecs.World, target ecs.Entity, buff compo
 			buffs := query.Get()
 			buffs.AddBuff(buff)
 
+			query.Close()
+
 			return true
 		}
 	}
@@ -88,6 +88,8 @@ func (s *EconomySystem) AddCurrency(
 				s.onTransaction(event)
 			}
 
+			query.Close()
+
 			return true
 		}
 	}
@@ -108,6 +110,8 @@ func (s *EconomySystem) RemoveCurrency(
 		if e == entity {
 			currency := query.Get()
 			if !currency.Remove(currencyType, amount) {
+				query.Close()
+
 				return false
 			}
 
@@ -124,6 +128,8 @@ func (s *EconomySystem) RemoveCurrency(
 				s.onTransaction(event)
 			}
 
+			query.Close()
+
 			return true
 		}
 	}
@@ -143,6 +149,8 @@ func (s *EconomySystem) GetCurrency(
 		if e == entity {
 			currency := query.Get()
 
+			query.Close()
+
 			return currency.Get(currencyType)
 		}
 	}
@@ -176,6 +184,8 @@ func (s *EconomySystem) Purchase(
 		if e == entity {
 			currency = query.Get()
 
+			query.Close()
+
 			break
 		}
 	}
@@ -245,6 +255,8 @@ func (s *EconomySystem) PurchaseUpgrade(world *ecs.World, entity ecs.Entity, upg
 		if e == entity {
 			currency = currQuery.Get()
 
+			currQuery.Close()
+
 			break
 		}
 	}
@@ -255,6 +267,8 @@ func (s *EconomySystem) PurchaseUpgrade(world *ecs.World, entity ecs.Entity, upg
 		if e == entity {
 			upgrades = upgradeQuery.Get()
 
+			upgradeQuery.Close()
+
 			break
 		}
 	}
@@ -283,6 +297,8 @@ func (s *EconomySystem) AddScore(world *ecs.World, entity ecs.Entity, units int6
 		if e == entity {
 			score := query.Get()
 
+			query.Close()
+
 			return score.AddScore(units)
 		}
 	}
@@ -298,6 +314,8 @@ func (s *EconomySystem) GetScore(world *ecs.World, entity ecs.Entity) (current,
 		if e == entity {
 			score := query.Get()
 
+			query.Close()
+
 			return score.Current, score.Best
 		}
 	}
@@ -314,6 +332,8 @@ func (s *EconomySystem) BreakStreak(world *ecs.World, entity ecs.Entity) {
 			score := query.Get()
 			score.BreakStreak()
 
+			query.Close()
+
 			return
 		}
 	}
@@ -328,6 +348,8 @@ func (s *EconomySystem) SetScoreMultiplier(world *ecs.World, entity ecs.Entity,
 			score := query.Get()
 			score.Multiplier = multiplier
 
+			query.Close()
+
 			return
 		}
 	}
@@ -0,0 +1,43 @@
+package systems
+
+import (
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// TestEconomyRepeatedLookups guards against lookups leaking query locks:
+// ark panics once 64 queries are left open.
+func TestEconomyRepeatedLookups(t *testing.T) {
+	world := ecs.NewWorld()
+	economy := NewEconomySystem(&world)
+	progression := NewProgressionSystem(&world)
+
+	currency := components.NewCurrency()
+	exp := components.NewExperience(10, 1)
+	level := components.NewLevel(0)
+	mapper := ecs.NewMap3[components.Currency, components.Experience, components.Level](&world)
+
+	// A second entity after the player keeps the queries from finishing.
+	player := mapper.NewEntity(&currency, &exp, &level)
+	other := components.NewCurrency()
+	mapper.NewEntity(&other, &exp, &level)
+
+	for range 100 {
+		economy.AddCurrency(&world, player, components.CurrencyGold, 2)
+		economy.RemoveCurrency(&world, player, components.CurrencyGold, 1)
+		progression.AddExperience(&world, player, 5)
+	}
+
+	if got := economy.GetCurrency(&world, player, components.CurrencyGold); got != 100 {
+		t.Errorf("gold = %d, want 100", got)
+	}
+
+	if got := progression.GetLevel(&world, player); got != 51 {
+		t.Errorf("level = %d, want 51", got)
+	}
+
+	// Structural changes still work after all those lookups.
+	world.RemoveEntity(player)
+}
@@ -0,0 +1,1057 @@
+package systems
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// ============================================================================
+// NavMesh Builder
+// ============================================================================
+
+// NavMeshBuilder collects obstacle geometry and builds a triangulated NavMesh.
+//
+// Obstacles are inflated by AgentRadius so that paths keep agents clear of
+// walls. Triangulation is a conforming Delaunay triangulation: obstacle and
+// boundary edges are split at intersections, subdivided to MaxEdgeLength and
+// refined until they appear in the mesh; triangles inside obstacles are then
+// discarded.
+type NavMeshBuilder struct {
+	MinX, MinY, MaxX, MaxY float64
+	AgentRadius            float64
+	MaxEdgeLength          float64 // Boundary/obstacle edge subdivision length
+
+	obstacles [][][2]float64
+}
+
+// NewNavMeshBuilder creates a builder for a walkable area of width x height.
+func NewNavMeshBuilder(width, height, agentRadius float64) *NavMeshBuilder {
+	return &NavMeshBuilder{
+		MaxX:          width,
+		MaxY:          height,
+		AgentRadius:   agentRadius,
+		MaxEdgeLength: 32,
+	}
+}
+
+// AddObstacle adds a polygonal obstacle in world coordinates.
+func (b *NavMeshBuilder) AddObstacle(polygon [][2]float64) {
+	if len(polygon) < 3 {
+		return
+	}
+
+	poly := make([][2]float64, len(polygon))
+	copy(poly, polygon)
+
+	// Normalize to counter-clockwise winding
+	if polygonArea(poly) < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+
+	b.obstacles = append(b.obstacles, poly)
+}
+
+// AddRect adds an axis-aligned rectangular obstacle (top-left origin).
+func (b *NavMeshBuilder) AddRect(x, y, w, h float64) {
+	b.AddObstacle([][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+}
+
+// AddSegment adds a thin wall between two points.
+func (b *NavMeshBuilder) AddSegment(x1, y1, x2, y2 float64) {
+	nx, ny := normalize(y1-y2, x2-x1)
+	if nx == 0 && ny == 0 {
+		return
+	}
+
+	const halfWidth = 0.5
+
+	nx, ny = nx*halfWidth, ny*halfWidth
+	b.AddObstacle([][2]float64{
+		{x1 - nx, y1 - ny}, {x2 - nx, y2 - ny}, {x2 + nx, y2 + ny}, {x1 + nx, y1 + ny},
+	})
+}
+
+// AddTiledObjects adds all shapes of a Tiled object layer as obstacles.
+// Polylines become thin walls and point objects are ignored.
+func (b *NavMeshBuilder) AddTiledObjects(layer *assets.TiledLayer) {
+	for i := range layer.Objects {
+		obj := &layer.Objects[i]
+
+		poly := obj.WorldPolygon()
+		if len(obj.Polyline) > 0 {
+			for j := 1; j < len(poly); j++ {
+				b.AddSegment(poly[j-1][0], poly[j-1][1], poly[j][0], poly[j][1])
+			}
+
+			continue
+		}
+
+		b.AddObstacle(poly)
+	}
+}
+
+// AddTiledMap sets the bounds to the map size and adds object layers as
+// obstacles. If no layer names are given, all object layers are used.
+func (b *NavMeshBuilder) AddTiledMap(m *assets.TiledMap, layerNames ...string) {
+	b.MinX, b.MinY = 0, 0
+	b.MaxX, b.MaxY = float64(m.PixelWidth()), float64(m.PixelHeight())
+
+	if len(layerNames) == 0 {
+		for _, layer := range m.ObjectLayers() {
+			b.AddTiledObjects(layer)
+		}
+
+		return
+	}
+
+	for _, name := range layerNames {
+		if layer := m.GetLayer(name); layer != nil {
+			b.AddTiledObjects(layer)
+		}
+	}
+}
+
+// AddColliders adds every collider entity whose layer matches layerMask as a
+// rectangular obstacle.
+func (b *NavMeshBuilder) AddColliders(world *ecs.World, layerMask uint32) {
+	filter := ecs.NewFilter2[components.Position, components.Collider](world)
+
+	query := filter.Query()
+	for query.Next() {
+		pos, col := query.Get()
+		if col.Layer&layerMask == 0 {
+			continue
+		}
+
+		b.AddRect(pos.X, pos.Y, col.Width, col.Height)
+	}
+}
+
+// ErrEmptyNavMesh is returned when no walkable area remains after erosion.
+var ErrEmptyNavMesh = errors.New("navmesh: no walkable area")
+
+// Build triangulates the walkable area.
+func (b *NavMeshBuilder) Build() (*NavMesh, error) {
+	r := b.AgentRadius
+	minX, minY := b.MinX+r, b.MinY+r
+	maxX, maxY := b.MaxX-r, b.MaxY-r
+
+	if maxX <= minX || maxY <= minY {
+		return nil, ErrEmptyNavMesh
+	}
+
+	step := b.MaxEdgeLength
+	if step <= 0 {
+		step = 32
+	}
+
+	// Inflate obstacles by the agent radius
+	inflated := make([][][2]float64, 0, len(b.obstacles))
+	for _, poly := range b.obstacles {
+		inflated = append(inflated, inflatePolygon(poly, r))
+	}
+
+	bounds := [][2]float64{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}}
+	inBounds := func(p [2]float64) bool {
+		const eps = 1e-6
+
+		return p[0] >= minX-eps && p[0] <= maxX+eps && p[1] >= minY-eps && p[1] <= maxY+eps
+	}
+
+	segments := buildConstraints(bounds, inflated, inBounds, step)
+	points, tris := conformingDelaunay(segments)
+
+	// Keep triangles that are inside bounds and clear of obstacles
+	kept := tris[:0]
+
+	for _, t := range tris {
+		a, bb, c := points[t[0]], points[t[1]], points[t[2]]
+		if math.Abs(triArea2(a, bb, c)) < 1e-9 {
+			continue
+		}
+
+		if !inBounds(a) || !inBounds(bb) || !inBounds(c) {
+			continue
+		}
+
+		if triangleBlocked(a, bb, c, inflated) {
+			continue
+		}
+
+		kept = append(kept, t)
+	}
+
+	if len(kept) == 0 {
+		return nil, ErrEmptyNavMesh
+	}
+
+	return newNavMesh(points, kept), nil
+}
+
+// triangleBlocked reports whether a triangle overlaps any obstacle. Edges
+// lying along obstacle outlines are allowed; crossing them is not.
+func triangleBlocked(a, b, c [2]float64, obstacles [][][2]float64) bool {
+	centroid := [2]float64{(a[0] + b[0] + c[0]) / 3, (a[1] + b[1] + c[1]) / 3}
+	edges := [3][2][2]float64{{a, b}, {b, c}, {c, a}}
+
+	for _, poly := range obstacles {
+		if pointInPolygon(centroid, poly) {
+			return true
+		}
+
+		for i := range poly {
+			p, q := poly[i], poly[(i+1)%len(poly)]
+			for _, e := range edges {
+				if segmentsCrossProperly(e[0], e[1], p, q) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// inflatePolygon offsets a counter-clockwise polygon outward by r, beveling
+// sharp corners so the offset never exceeds 2r.
+func inflatePolygon(poly [][2]float64, r float64) [][2]float64 {
+	if r <= 0 {
+		return poly
+	}
+
+	n := len(poly)
+	result := make([][2]float64, 0, n*2)
+
+	for i := range poly {
+		prev, cur, next := poly[(i+n-1)%n], poly[i], poly[(i+1)%n]
+
+		// Outward normals of the two edges (polygon is CCW in math coords)
+		n1x, n1y := normalize(cur[1]-prev[1], prev[0]-cur[0])
+		n2x, n2y := normalize(next[1]-cur[1], cur[0]-next[0])
+
+		mx, my := normalize(n1x+n2x, n1y+n2y)
+
+		cosHalf := mx*n1x + my*n1y
+		if cosHalf < 0.5 {
+			// Sharp corner: bevel with two points
+			result = append(result,
+				[2]float64{cur[0] + n1x*r, cur[1] + n1y*r},
+				[2]float64{cur[0] + n2x*r, cur[1] + n2y*r},
+			)
+
+			continue
+		}
+
+		d := r / cosHalf
+		result = append(result, [2]float64{cur[0] + mx*d, cur[1] + my*d})
+	}
+
+	return result
+}
+
+// pointSet deduplicates points by position.
+type pointSet struct {
+	list  [][2]float64
+	index map[[2]int64]int
+}
+
+func newPointSet() *pointSet {
+	return &pointSet{index: make(map[[2]int64]int)}
+}
+
+func pointKey(p [2]float64) [2]int64 {
+	return [2]int64{int64(math.Round(p[0] * 1e4)), int64(math.Round(p[1] * 1e4))}
+}
+
+func (s *pointSet) add(p [2]float64) {
+	key := pointKey(p)
+	if _, ok := s.index[key]; ok {
+		return
+	}
+
+	s.index[key] = len(s.list)
+	s.list = append(s.list, p)
+}
+
+func (s *pointSet) indexOf(p [2]float64) int {
+	if i, ok := s.index[pointKey(p)]; ok {
+		return i
+	}
+
+	return -1
+}
+
+// navSegment is a constraint edge that must appear in the triangulation.
+type navSegment struct {
+	a, b  [2]float64
+	owner int // Obstacle index, or -1 for the boundary
+}
+
+// buildConstraints splits boundary and obstacle edges at their mutual
+// intersections, drops pieces that are outside the bounds or buried inside
+// another obstacle, and subdivides the rest to at most step.
+func buildConstraints(
+	bounds [][2]float64,
+	obstacles [][][2]float64,
+	inBounds func([2]float64) bool,
+	step float64,
+) []navSegment {
+	var raw []navSegment
+
+	for i := range bounds {
+		raw = append(raw, navSegment{bounds[i], bounds[(i+1)%len(bounds)], -1})
+	}
+
+	for k, poly := range obstacles {
+		for i := range poly {
+			raw = append(raw, navSegment{poly[i], poly[(i+1)%len(poly)], k})
+		}
+	}
+
+	// Collect split parameters from pairwise intersections
+	splits := make([][]float64, len(raw))
+
+	for i := range raw {
+		for j := i + 1; j < len(raw); j++ {
+			p, ok := segmentIntersection(raw[i].a, raw[i].b, raw[j].a, raw[j].b)
+			if !ok {
+				continue
+			}
+
+			splits[i] = append(splits[i], segmentParam(raw[i], p))
+			splits[j] = append(splits[j], segmentParam(raw[j], p))
+		}
+	}
+
+	var result []navSegment
+
+	for i, seg := range raw {
+		ts := append([]float64{0, 1}, splits[i]...)
+		sort.Float64s(ts)
+
+		for k := 1; k < len(ts); k++ {
+			if ts[k]-ts[k-1] < 1e-9 {
+				continue
+			}
+
+			piece := navSegment{segmentPoint(seg, ts[k-1]), segmentPoint(seg, ts[k]), seg.owner}
+			mid := segmentPoint(piece, 0.5)
+
+			if !inBounds(mid) || buriedInObstacle(mid, obstacles, seg.owner) {
+				continue
+			}
+
+			pts := subdivide(piece.a, piece.b, step)
+			pts = append(pts, piece.b)
+
+			for j := 1; j < len(pts); j++ {
+				result = append(result, navSegment{pts[j-1], pts[j], seg.owner})
+			}
+		}
+	}
+
+	return result
+}
+
+// buriedInObstacle reports whether p is inside any obstacle except skip.
+func buriedInObstacle(p [2]float64, obstacles [][][2]float64, skip int) bool {
+	for k, poly := range obstacles {
+		if k != skip && pointInPolygon(p, poly) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// conformingDelaunay triangulates segment endpoints, splitting any segment
+// that is missing from the triangulation until all are present or the
+// refinement budget runs out.
+func conformingDelaunay(segments []navSegment) ([][2]float64, [][3]int) {
+	const (
+		maxRounds = 12
+		minLength = 0.5
+	)
+
+	points := newPointSet()
+	for _, seg := range segments {
+		points.add(seg.a)
+		points.add(seg.b)
+	}
+
+	var tris [][3]int
+
+	for range maxRounds {
+		tris = delaunay(points.list)
+
+		edges := make(map[[2]int]bool, len(tris)*3)
+		for _, t := range tris {
+			for k := range 3 {
+				a, b := t[k], t[(k+1)%3]
+				edges[[2]int{min(a, b), max(a, b)}] = true
+			}
+		}
+
+		var next []navSegment
+
+		split := false
+
+		for _, seg := range segments {
+			a, b := points.indexOf(seg.a), points.indexOf(seg.b)
+			if edges[[2]int{min(a, b), max(a, b)}] ||
+				math.Hypot(seg.b[0]-seg.a[0], seg.b[1]-seg.a[1]) < minLength {
+				next = append(next, seg)
+
+				continue
+			}
+
+			mid := segmentPoint(seg, 0.5)
+			points.add(mid)
+			next = append(next, navSegment{seg.a, mid, seg.owner}, navSegment{mid, seg.b, seg.owner})
+			split = true
+		}
+
+		segments = next
+
+		if !split {
+			break
+		}
+	}
+
+	return points.list, tris
+}
+
+// segmentParam returns the parameter t of point p along a segment.
+func segmentParam(seg navSegment, p [2]float64) float64 {
+	dx, dy := seg.b[0]-seg.a[0], seg.b[1]-seg.a[1]
+	if math.Abs(dx) > math.Abs(dy) {
+		return (p[0] - seg.a[0]) / dx
+	}
+
+	return (p[1] - seg.a[1]) / dy
+}
+
+// segmentPoint returns the point at parameter t along a segment.
+func segmentPoint(seg navSegment, t float64) [2]float64 {
+	return [2]float64{
+		seg.a[0] + (seg.b[0]-seg.a[0])*t,
+		seg.a[1] + (seg.b[1]-seg.a[1])*t,
+	}
+}
+
+// subdivide returns points from a (inclusive) to b (exclusive) spaced <= step.
+func subdivide(a, b [2]float64, step float64) [][2]float64 {
+	length := math.Hypot(b[0]-a[0], b[1]-a[1])
+	n := max(1, int(math.Ceil(length/step)))
+
+	points := make([][2]float64, n)
+	for i := range n {
+		t := float64(i) / float64(n)
+		points[i] = [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+	}
+
+	return points
+}
+
+// delaunay triangulates points with the Bowyer-Watson algorithm.
+// Returned triangles are counter-clockwise index triples into points.
+func delaunay(points [][2]float64) [][3]int {
+	if len(points) < 3 {
+		return nil
+	}
+
+	minX, minY := points[0][0], points[0][1]
+	maxX, maxY := minX, minY
+
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+
+	size := math.Max(maxX-minX, maxY-minY) * 20
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+
+	// Work on a copy with the super triangle appended
+	n := len(points)
+	all := make([][2]float64, n, n+3)
+	copy(all, points)
+	all = append(all,
+		[2]float64{cx - size, cy - size},
+		[2]float64{cx + size, cy - size},
+		[2]float64{cx, cy + size},
+	)
+
+	type tri struct {
+		v      [3]int
+		cx, cy float64
+		r2     float64
+	}
+
+	makeTri := func(a, b, c int) tri {
+		if triArea2(all[a], all[b], all[c]) < 0 {
+			b, c = c, b
+		}
+
+		ccx, ccy, r2 := circumcircle(all[a], all[b], all[c])
+
+		return tri{v: [3]int{a, b, c}, cx: ccx, cy: ccy, r2: r2}
+	}
+
+	tris := []tri{makeTri(n, n+1, n+2)}
+
+	for i := range n {
+		p := all[i]
+
+		type edge struct{ a, b int }
+
+		edgeCount := make(map[edge]int)
+		var edges []edge
+
+		keep := tris[:0]
+
+		for _, t := range tris {
+			dx, dy := p[0]-t.cx, p[1]-t.cy
+			if dx*dx+dy*dy <= t.r2*(1+1e-12) {
+				for k := range 3 {
+					a, b := t.v[k], t.v[(k+1)%3]
+					if a > b {
+						a, b = b, a
+					}
+
+					e := edge{a, b}
+					if edgeCount[e] == 0 {
+						edges = append(edges, e)
+					}
+
+					edgeCount[e]++
+				}
+
+				continue
+			}
+
+			keep = append(keep, t)
+		}
+
+		tris = keep
+
+		for _, e := range edges {
+			if edgeCount[e] != 1 {
+				continue
+			}
+
+			if math.Abs(triArea2(all[e.a], all[e.b], p)) < 1e-12 {
+				continue
+			}
+
+			tris = append(tris, makeTri(e.a, e.b, i))
+		}
+	}
+
+	result := make([][3]int, 0, len(tris))
+
+	for _, t := range tris {
+		if t.v[0] >= n || t.v[1] >= n || t.v[2] >= n {
+			continue
+		}
+
+		result = append(result, t.v)
+	}
+
+	return result
+}
+
+// circumcircle returns the center and squared radius of a triangle's circumcircle.
+func circumcircle(a, b, c [2]float64) (float64, float64, float64) {
+	d := 2 * (a[0]*(b[1]-c[1]) + b[0]*(c[1]-a[1]) + c[0]*(a[1]-b[1]))
+	if math.Abs(d) < 1e-12 {
+		return 0, 0, math.Inf(1)
+	}
+
+	a2 := a[0]*a[0] + a[1]*a[1]
+	b2 := b[0]*b[0] + b[1]*b[1]
+	c2 := c[0]*c[0] + c[1]*c[1]
+
+	ux := (a2*(b[1]-c[1]) + b2*(c[1]-a[1]) + c2*(a[1]-b[1])) / d
+	uy := (a2*(c[0]-b[0]) + b2*(a[0]-c[0]) + c2*(b[0]-a[0])) / d
+	dx, dy := a[0]-ux, a[1]-uy
+
+	return ux, uy, dx*dx + dy*dy
+}
+
+// ============================================================================
+// NavMesh
+// ============================================================================
+
+// NavTriangle is a walkable triangle in a NavMesh.
+type NavTriangle struct {
+	Verts     [3]int // Vertex indices, counter-clockwise
+	Neighbors [3]int // Triangle across edge Verts[i]->Verts[i+1], or -1
+	CX, CY    float64
+}
+
+// NavMesh is a triangulated walkable area. It implements PathFinder and can
+// be used anywhere a PathfindingSystem is accepted.
+type NavMesh struct {
+	Vertices  [][2]float64
+	Triangles []NavTriangle
+	MaxNodes  int // Maximum triangles to explore per search
+
+	// Point location acceleration grid
+	cellSize   float64
+	minX, minY float64
+	cols, rows int
+	buckets    [][]int
+}
+
+// newNavMesh builds adjacency and the point location grid.
+func newNavMesh(points [][2]float64, tris [][3]int) *NavMesh {
+	// Compact vertices to those used by triangles
+	remap := make(map[int]int)
+	mesh := &NavMesh{MaxNodes: 5000}
+
+	for _, t := range tris {
+		var nt NavTriangle
+
+		for k, v := range t {
+			idx, ok := remap[v]
+			if !ok {
+				idx = len(mesh.Vertices)
+				remap[v] = idx
+				mesh.Vertices = append(mesh.Vertices, points[v])
+			}
+
+			nt.Verts[k] = idx
+		}
+
+		a, b, c := mesh.Vertices[nt.Verts[0]], mesh.Vertices[nt.Verts[1]], mesh.Vertices[nt.Verts[2]]
+		nt.CX, nt.CY = (a[0]+b[0]+c[0])/3, (a[1]+b[1]+c[1])/3
+		nt.Neighbors = [3]int{-1, -1, -1}
+		mesh.Triangles = append(mesh.Triangles, nt)
+	}
+
+	// Adjacency via shared edges
+	type edgeRef struct{ tri, edge int }
+
+	edges := make(map[[2]int]edgeRef)
+
+	for ti := range mesh.Triangles {
+		t := &mesh.Triangles[ti]
+		for k := range 3 {
+			a, b := t.Verts[k], t.Verts[(k+1)%3]
+			key := [2]int{min(a, b), max(a, b)}
+
+			if other, ok := edges[key]; ok {
+				t.Neighbors[k] = other.tri
+				mesh.Triangles[other.tri].Neighbors[other.edge] = ti
+
+				continue
+			}
+
+			edges[key] = edgeRef{ti, k}
+		}
+	}
+
+	mesh.buildGrid()
+
+	return mesh
+}
+
+// buildGrid buckets triangles by bounding box for fast point location.
+func (m *NavMesh) buildGrid() {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, v := range m.Vertices {
+		minX, maxX = math.Min(minX, v[0]), math.Max(maxX, v[0])
+		minY, maxY = math.Min(minY, v[1]), math.Max(maxY, v[1])
+	}
+
+	area := (maxX - minX) * (maxY - minY)
+	m.cellSize = math.Max(1, math.Sqrt(area/float64(len(m.Triangles)))*2)
+	m.minX, m.minY = minX, minY
+	m.cols = int((maxX-minX)/m.cellSize) + 1
+	m.rows = int((maxY-minY)/m.cellSize) + 1
+	m.buckets = make([][]int, m.cols*m.rows)
+
+	for ti, t := range m.Triangles {
+		tMinX, tMinY := math.Inf(1), math.Inf(1)
+		tMaxX, tMaxY := math.Inf(-1), math.Inf(-1)
+
+		for _, vi := range t.Verts {
+			v := m.Vertices[vi]
+			tMinX, tMaxX = math.Min(tMinX, v[0]), math.Max(tMaxX, v[0])
+			tMinY, tMaxY = math.Min(tMinY, v[1]), math.Max(tMaxY, v[1])
+		}
+
+		c0, r0 := m.cell(tMinX, tMinY)
+		c1, r1 := m.cell(tMaxX, tMaxY)
+
+		for r := r0; r <= r1; r++ {
+			for c := c0; c <= c1; c++ {
+				m.buckets[r*m.cols+c] = append(m.buckets[r*m.cols+c], ti)
+			}
+		}
+	}
+}
+
+// cell returns the clamped grid cell for a point.
+func (m *NavMesh) cell(x, y float64) (int, int) {
+	c := int((x - m.minX) / m.cellSize)
+	r := int((y - m.minY) / m.cellSize)
+
+	return max(0, min(c, m.cols-1)), max(0, min(r, m.rows-1))
+}
+
+// FindTriangle returns the index of the triangle containing (x, y), or -1.
+func (m *NavMesh) FindTriangle(x, y float64) int {
+	if len(m.Triangles) == 0 {
+		return -1
+	}
+
+	c, r := m.cell(x, y)
+	p := [2]float64{x, y}
+
+	for _, ti := range m.buckets[r*m.cols+c] {
+		t := m.Triangles[ti]
+		if pointInTriangle(p, m.Vertices[t.Verts[0]], m.Vertices[t.Verts[1]], m.Vertices[t.Verts[2]]) {
+			return ti
+		}
+	}
+
+	return -1
+}
+
+// IsWalkable returns true if the point lies on the mesh.
+func (m *NavMesh) IsWalkable(x, y float64) bool {
+	return m.FindTriangle(x, y) >= 0
+}
+
+// ClosestPoint returns the closest point on the mesh and its triangle.
+func (m *NavMesh) ClosestPoint(x, y float64) (float64, float64, int) {
+	if ti := m.FindTriangle(x, y); ti >= 0 {
+		return x, y, ti
+	}
+
+	best := -1
+	bestDist := math.Inf(1)
+
+	var bx, by float64
+
+	p := [2]float64{x, y}
+
+	for ti, t := range m.Triangles {
+		for k := range 3 {
+			a, b := m.Vertices[t.Verts[k]], m.Vertices[t.Verts[(k+1)%3]]
+			qx, qy := closestOnSegment(p, a, b)
+
+			d := (qx-x)*(qx-x) + (qy-y)*(qy-y)
+			if d < bestDist {
+				bestDist = d
+				best = ti
+				bx, by = qx, qy
+			}
+		}
+	}
+
+	return bx, by, best
+}
+
+// navNode is an A* node over triangles.
+type navNode struct {
+	tri    int
+	g, f   float64
+	parent *navNode
+	index  int
+}
+
+type navHeap []*navNode
+
+func (h navHeap) Len() int           { return len(h) }
+func (h navHeap) Less(i, j int) bool { return h[i].f < h[j].f }
+func (h navHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *navHeap) Push(x any) {
+	n, ok := x.(*navNode)
+	if !ok {
+		return
+	}
+
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+
+func (h *navHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+
+	return item
+}
+
+// FindPath finds a smoothed path across the mesh using A* over triangles
+// followed by the funnel algorithm. Points off the mesh are snapped to it.
+func (m *NavMesh) FindPath(startX, startY, endX, endY float64) *Path {
+	sx, sy, startTri := m.ClosestPoint(startX, startY)
+	ex, ey, endTri := m.ClosestPoint(endX, endY)
+
+	if startTri < 0 || endTri < 0 {
+		return &Path{Valid: false}
+	}
+
+	corridor := m.findCorridor(startTri, endTri, ex, ey)
+	if corridor == nil {
+		return &Path{Valid: false}
+	}
+
+	points := m.funnel(corridor, [2]float64{sx, sy}, [2]float64{ex, ey})
+
+	return &Path{Valid: true, Points: points, Length: pathLength(points)}
+}
+
+// findCorridor returns the triangle sequence from start to end, or nil.
+func (m *NavMesh) findCorridor(startTri, endTri int, ex, ey float64) []int {
+	if startTri == endTri {
+		return []int{startTri}
+	}
+
+	open := &navHeap{}
+	nodes := make(map[int]*navNode)
+	closed := make(map[int]bool)
+
+	start := &navNode{tri: startTri}
+	start.f = math.Hypot(m.Triangles[startTri].CX-ex, m.Triangles[startTri].CY-ey)
+	heap.Push(open, start)
+	nodes[startTri] = start
+
+	explored := 0
+
+	for open.Len() > 0 && explored < m.MaxNodes {
+		explored++
+
+		current, ok := heap.Pop(open).(*navNode)
+		if !ok {
+			continue
+		}
+
+		if current.tri == endTri {
+			var corridor []int
+			for n := current; n != nil; n = n.parent {
+				corridor = append(corridor, n.tri)
+			}
+
+			for i, j := 0, len(corridor)-1; i < j; i, j = i+1, j-1 {
+				corridor[i], corridor[j] = corridor[j], corridor[i]
+			}
+
+			return corridor
+		}
+
+		closed[current.tri] = true
+		ct := m.Triangles[current.tri]
+
+		for _, nb := range ct.Neighbors {
+			if nb < 0 || closed[nb] {
+				continue
+			}
+
+			nt := m.Triangles[nb]
+			g := current.g + math.Hypot(nt.CX-ct.CX, nt.CY-ct.CY)
+
+			node, exists := nodes[nb]
+			if !exists {
+				node = &navNode{tri: nb, g: g, parent: current}
+				node.f = g + math.Hypot(nt.CX-ex, nt.CY-ey)
+				nodes[nb] = node
+				heap.Push(open, node)
+			} else if g < node.g {
+				node.f += g - node.g
+				node.g = g
+				node.parent = current
+				heap.Fix(open, node.index)
+			}
+		}
+	}
+
+	return nil
+}
+
+// portal returns the left and right endpoints of the edge from tri a to b.
+func (m *NavMesh) portal(a, b int) ([2]float64, [2]float64) {
+	t := m.Triangles[a]
+	for k, nb := range t.Neighbors {
+		if nb == b {
+			return m.Vertices[t.Verts[(k+1)%3]], m.Vertices[t.Verts[k]]
+		}
+	}
+
+	return [2]float64{t.CX, t.CY}, [2]float64{t.CX, t.CY}
+}
+
+// funnel runs the simple stupid funnel algorithm over a triangle corridor.
+func (m *NavMesh) funnel(corridor []int, start, end [2]float64) [][2]float64 {
+	lefts := [][2]float64{start}
+	rights := [][2]float64{start}
+
+	for i := 0; i+1 < len(corridor); i++ {
+		l, r := m.portal(corridor[i], corridor[i+1])
+		lefts = append(lefts, l)
+		rights = append(rights, r)
+	}
+
+	lefts = append(lefts, end)
+	rights = append(rights, end)
+
+	points := [][2]float64{start}
+	apex, portalLeft, portalRight := start, start, start
+	apexIndex, leftIndex, rightIndex := 0, 0, 0
+
+	for i := 1; i < len(lefts); i++ {
+		left, right := lefts[i], rights[i]
+
+		// Tighten the right side
+		if triArea2(apex, portalRight, right) >= 0 {
+			if apex == portalRight || triArea2(apex, portalLeft, right) < 0 {
+				portalRight = right
+				rightIndex = i
+			} else {
+				// Right crossed over left: left becomes the new apex
+				points = append(points, portalLeft)
+				apex = portalLeft
+				apexIndex = leftIndex
+				portalLeft, portalRight = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+
+				continue
+			}
+		}
+
+		// Tighten the left side
+		if triArea2(apex, portalLeft, left) <= 0 {
+			if apex == portalLeft || triArea2(apex, portalRight, left) > 0 {
+				portalLeft = left
+				leftIndex = i
+			} else {
+				// Left crossed over right: right becomes the new apex
+				points = append(points, portalRight)
+				apex = portalRight
+				apexIndex = rightIndex
+				portalLeft, portalRight = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+
+				continue
+			}
+		}
+	}
+
+	if points[len(points)-1] != end {
+		points = append(points, end)
+	}
+
+	return points
+}
+
+// ============================================================================
+// Geometry Helpers
+// ============================================================================
+
+// triArea2 returns twice the signed area of triangle abc (positive if CCW).
+func triArea2(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (c[0]-a[0])*(b[1]-a[1])
+}
+
+// polygonArea returns the signed area of a polygon (positive if CCW).
+func polygonArea(poly [][2]float64) float64 {
+	area := 0.0
+
+	for i := range poly {
+		j := (i + 1) % len(poly)
+		area += poly[i][0]*poly[j][1] - poly[j][0]*poly[i][1]
+	}
+
+	return area / 2
+}
+
+// pointInTriangle tests if p lies inside or on the CCW triangle abc.
+func pointInTriangle(p, a, b, c [2]float64) bool {
+	const eps = -1e-7
+
+	return triArea2(a, b, p) >= eps && triArea2(b, c, p) >= eps && triArea2(c, a, p) >= eps
+}
+
+// pointInPolygon tests if p lies strictly inside a polygon (even-odd rule).
+func pointInPolygon(p [2]float64, poly [][2]float64) bool {
+	inside := false
+
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// segmentsCrossProperly tests if segments ab and cd cross at an interior point.
+func segmentsCrossProperly(a, b, c, d [2]float64) bool {
+	const eps = 1e-9
+
+	d1 := triArea2(c, d, a)
+	d2 := triArea2(c, d, b)
+	d3 := triArea2(a, b, c)
+	d4 := triArea2(a, b, d)
+
+	return ((d1 > eps && d2 < -eps) || (d1 < -eps && d2 > eps)) &&
+		((d3 > eps && d4 < -eps) || (d3 < -eps && d4 > eps))
+}
+
+// segmentIntersection returns the intersection point of segments ab and cd.
+func segmentIntersection(a, b, c, d [2]float64) ([2]float64, bool) {
+	rx, ry := b[0]-a[0], b[1]-a[1]
+	sx, sy := d[0]-c[0], d[1]-c[1]
+
+	denom := rx*sy - ry*sx
+	if math.Abs(denom) < 1e-12 {
+		return [2]float64{}, false
+	}
+
+	t := ((c[0]-a[0])*sy - (c[1]-a[1])*sx) / denom
+	u := ((c[0]-a[0])*ry - (c[1]-a[1])*rx) / denom
+
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return [2]float64{}, false
+	}
+
+	return [2]float64{a[0] + t*rx, a[1] + t*ry}, true
+}
+
+// closestOnSegment returns the closest point to p on segment ab.
+func closestOnSegment(p, a, b [2]float64) (float64, float64) {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		return a[0], a[1]
+	}
+
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / l2
+	t = math.Max(0, math.Min(1, t))
+
+	return a[0] + t*dx, a[1] + t*dy
+}
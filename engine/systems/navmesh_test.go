@@ -0,0 +1,158 @@
+package systems
+
+import (
+	"math"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/assets"
+)
+
+// buildTestMesh builds a 400x300 mesh with a wall in the middle that leaves
+// a gap at the bottom.
+func buildTestMesh(t *testing.T) *NavMesh {
+	t.Helper()
+
+	b := NewNavMeshBuilder(400, 300, 5)
+	b.AddRect(180, 0, 40, 220)
+
+	mesh, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	return mesh
+}
+
+// TestNavMeshBuild tests navmesh construction and point location.
+func TestNavMeshBuild(t *testing.T) {
+	mesh := buildTestMesh(t)
+
+	if len(mesh.Triangles) == 0 {
+		t.Fatal("mesh has no triangles")
+	}
+
+	t.Run("open area is walkable", func(t *testing.T) {
+		if !mesh.IsWalkable(50, 50) {
+			t.Error("(50, 50) should be walkable")
+		}
+	})
+
+	t.Run("obstacle is not walkable", func(t *testing.T) {
+		if mesh.IsWalkable(200, 100) {
+			t.Error("(200, 100) is inside the wall")
+		}
+	})
+
+	t.Run("agent radius erodes obstacle border", func(t *testing.T) {
+		if mesh.IsWalkable(177, 100) {
+			t.Error("(177, 100) is within agent radius of the wall")
+		}
+
+		if mesh.IsWalkable(2, 150) {
+			t.Error("(2, 150) is within agent radius of the map edge")
+		}
+	})
+
+	t.Run("every triangle is counter-clockwise", func(t *testing.T) {
+		for i, tri := range mesh.Triangles {
+			a, b, c := mesh.Vertices[tri.Verts[0]], mesh.Vertices[tri.Verts[1]], mesh.Vertices[tri.Verts[2]]
+			if triArea2(a, b, c) <= 0 {
+				t.Fatalf("triangle %d is not CCW", i)
+			}
+		}
+	})
+
+	t.Run("empty area fails", func(t *testing.T) {
+		if _, err := NewNavMeshBuilder(8, 8, 5).Build(); err == nil {
+			t.Error("expected error for area smaller than agent")
+		}
+	})
+}
+
+// TestNavMeshFindPath tests path search and funnel smoothing.
+func TestNavMeshFindPath(t *testing.T) {
+	mesh := buildTestMesh(t)
+
+	t.Run("path goes around the wall", func(t *testing.T) {
+		path := mesh.FindPath(50, 50, 350, 50)
+		if !path.Valid {
+			t.Fatal("path should be valid")
+		}
+
+		if len(path.Points) < 3 {
+			t.Fatalf("path has %d points, expected corners around the wall", len(path.Points))
+		}
+
+		for i := 1; i < len(path.Points); i++ {
+			a, b := path.Points[i-1], path.Points[i]
+			mid := [2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+
+			if !mesh.IsWalkable(mid[0], mid[1]) {
+				t.Errorf("segment %d midpoint (%v, %v) is off the mesh", i, mid[0], mid[1])
+			}
+		}
+
+		// The corner must be below the wall plus the agent radius
+		for _, p := range path.Points[1 : len(path.Points)-1] {
+			if p[1] < 220 {
+				t.Errorf("corner (%v, %v) cuts through the wall", p[0], p[1])
+			}
+		}
+
+		last := path.Points[len(path.Points)-1]
+		if last != [2]float64{350, 50} {
+			t.Errorf("path ends at %v, want (350, 50)", last)
+		}
+	})
+
+	t.Run("straight line in open space", func(t *testing.T) {
+		path := mesh.FindPath(20, 250, 380, 280)
+		if !path.Valid || len(path.Points) != 2 {
+			t.Fatalf("expected direct 2-point path, got %+v", path)
+		}
+
+		want := math.Hypot(360, 30)
+		if math.Abs(path.Length-want) > 1e-6 {
+			t.Errorf("Length = %v, want %v", path.Length, want)
+		}
+	})
+
+	t.Run("implements PathFinder", func(t *testing.T) {
+		var _ PathFinder = mesh
+	})
+}
+
+// TestNavMeshFromTiled tests building from Tiled object layers.
+func TestNavMeshFromTiled(t *testing.T) {
+	m := &assets.TiledMap{
+		Width: 20, Height: 10, TileWidth: 16, TileHeight: 16,
+		Layers: []assets.TiledLayer{{
+			Name: "walls",
+			Type: "objectgroup",
+			Objects: []assets.Object{
+				{X: 100, Y: 20, Width: 40, Height: 40},
+				{X: 200, Y: 80, Polygon: []assets.ObjectPoint{{X: 0, Y: 0}, {X: 40, Y: 0}, {X: 20, Y: 40}}},
+			},
+		}},
+	}
+
+	b := NewNavMeshBuilder(0, 0, 2)
+	b.AddTiledMap(m)
+
+	mesh, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if mesh.IsWalkable(120, 40) {
+		t.Error("rectangle object should be blocked")
+	}
+
+	if mesh.IsWalkable(220, 90) {
+		t.Error("polygon object should be blocked")
+	}
+
+	if !mesh.IsWalkable(20, 140) {
+		t.Error("open area should be walkable")
+	}
+}
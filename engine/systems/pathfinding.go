@@ -111,6 +111,12 @@ func (g *NavGrid) GridToWorld(gx, gy int) (float64, float64) {
 	return (float64(gx) + 0.5) * g.CellSize, (float64(gy) + 0.5) * g.CellSize
 }
 
+// PathFinder plans paths between world positions.
+// PathfindingSystem (grid A*) and NavMesh both implement it.
+type PathFinder interface {
+	FindPath(startX, startY, endX, endY float64) *Path
+}
+
 // PathfindingSystem provides A* pathfinding.
 type PathfindingSystem struct {
 	Grid     *NavGrid
@@ -265,16 +271,24 @@ func (p *PathfindingSystem) reconstructPath(goal *PathNode) *Path {
 		path.Points[i] = [2]float64{wx, wy}
 	}
 
-	// Calculate length
-	for i := 1; i < len(path.Points); i++ {
-		dx := path.Points[i][0] - path.Points[i-1][0]
-		dy := path.Points[i][1] - path.Points[i-1][1]
-		path.Length += math.Sqrt(dx*dx + dy*dy)
-	}
+	path.Length = pathLength(path.Points)
 
 	return path
 }
 
+// pathLength returns the total length of a polyline.
+func pathLength(points [][2]float64) float64 {
+	length := 0.0
+
+	for i := 1; i < len(points); i++ {
+		dx := points[i][0] - points[i-1][0]
+		dy := points[i][1] - points[i-1][1]
+		length += math.Sqrt(dx*dx + dy*dy)
+	}
+
+	return length
+}
+
 func heuristic(x1, y1, x2, y2 int) float64 {
 	dx := float64(x2 - x1)
 	dy := float64(y2 - y1)
@@ -412,6 +426,51 @@ func (pf *PathFollower) GetNextMove(currentX, currentY float64) (dx, dy float64,
 	return dx, dy, false
 }
 
+// GetSteeredMove returns a velocity toward the next waypoint that arrives
+// smoothly at the final point and avoids the given neighbors using RVO.
+// Unlike GetNextMove, the result is a velocity in units per second.
+func (pf *PathFollower) GetSteeredMove(
+	agent SteeringAgent,
+	neighbors []SteeringAgent,
+	slowingRadius, timeHorizon float64,
+) (vx, vy float64, reachedEnd bool) {
+	if pf.Path == nil || !pf.Path.Valid || pf.CurrentIndex >= len(pf.Path.Points) {
+		return 0, 0, true
+	}
+
+	if agent.MaxSpeed == 0 {
+		agent.MaxSpeed = pf.Speed
+	}
+
+	target := pf.Path.Points[pf.CurrentIndex]
+	last := pf.CurrentIndex == len(pf.Path.Points)-1
+
+	dist := math.Hypot(target[0]-agent.X, target[1]-agent.Y)
+	if dist <= pf.Threshold && !last {
+		pf.CurrentIndex++
+
+		return pf.GetSteeredMove(agent, neighbors, slowingRadius, timeHorizon)
+	}
+
+	var fx, fy float64
+	if last {
+		fx, fy = Arrive(agent, target[0], target[1], slowingRadius)
+	} else {
+		fx, fy = Seek(agent, target[0], target[1])
+	}
+
+	// Forces are desired - current velocity, so add current back for the desired
+	vx, vy = AvoidVelocity(agent, fx+agent.VX, fy+agent.VY, neighbors, timeHorizon)
+
+	if last && dist <= pf.Threshold {
+		pf.CurrentIndex++
+
+		return vx, vy, true
+	}
+
+	return vx, vy, false
+}
+
 // IsFinished returns true if path is complete.
 func (pf *PathFollower) IsFinished() bool {
 	return pf.Path == nil || pf.CurrentIndex >= len(pf.Path.Points)
@@ -426,19 +485,31 @@ type Navigation struct {
 	RecalcInterval   float64 // How often to recalculate path
 	RecalcTimer      float64
 	Stopped          bool
+	Pending          bool // A path request is queued with a PathRequestService
 }
 
 // NavigationSystem manages entity pathfinding.
+// Entities that also carry a Steering component are not moved directly;
+// instead their steering target is driven along the path (seek toward
+// intermediate waypoints, arrive at the last one) and SteeringSystem moves
+// them, adding local avoidance if enabled.
+//
+// If Requests is set, path recalculation is queued on the request service
+// instead of running synchronously, and entities keep following their old
+// path until the new one is delivered.
 type NavigationSystem struct {
-	Pathfinding *PathfindingSystem
+	Pathfinding PathFinder
+	Requests    *PathRequestService
 	navFilter   *ecs.Filter2[components.Position, Navigation]
+	steerMap    *ecs.Map[Steering]
 }
 
 // NewNavigationSystem creates a navigation system.
-func NewNavigationSystem(world *ecs.World, pathfinding *PathfindingSystem) *NavigationSystem {
+func NewNavigationSystem(world *ecs.World, pathfinding PathFinder) *NavigationSystem {
 	return &NavigationSystem{
 		Pathfinding: pathfinding,
 		navFilter:   ecs.NewFilter2[components.Position, Navigation](world),
+		steerMap:    ecs.NewMap[Steering](world),
 	}
 }
 
@@ -448,15 +519,30 @@ func (s *NavigationSystem) Update(world *ecs.World, dt float64) {
 	for query.Next() {
 		pos, nav := query.Get()
 
+		var steer *Steering
+		if s.steerMap.Has(query.Entity()) {
+			steer = s.steerMap.Get(query.Entity())
+		}
+
 		if nav.Stopped {
+			if steer != nil {
+				steerToward(steer, pos.X, pos.Y, true)
+			}
+
 			continue
 		}
 
 		// Recalculate path if needed
 		nav.RecalcTimer -= dt
-		if nav.Path == nil || nav.RecalcTimer <= 0 {
-			nav.Path = s.Pathfinding.FindPath(pos.X, pos.Y, nav.TargetX, nav.TargetY)
-			nav.CurrentWaypoint = 0
+		if (nav.Path == nil || nav.RecalcTimer <= 0) && !nav.Pending {
+			if s.Requests != nil {
+				s.Requests.Request(query.Entity(), pos.X, pos.Y, nav.TargetX, nav.TargetY)
+				nav.Pending = true
+			} else {
+				nav.Path = s.Pathfinding.FindPath(pos.X, pos.Y, nav.TargetX, nav.TargetY)
+				nav.CurrentWaypoint = 0
+			}
+
 			nav.RecalcTimer = nav.RecalcInterval
 		}
 
@@ -470,6 +556,21 @@ func (s *NavigationSystem) Update(world *ecs.World, dt float64) {
 		dy := target[1] - pos.Y
 		dist := math.Sqrt(dx*dx + dy*dy)
 
+		if steer != nil {
+			if nav.Speed > 0 {
+				steer.MaxSpeed = nav.Speed
+			}
+
+			last := nav.CurrentWaypoint == len(nav.Path.Points)-1
+			steerToward(steer, target[0], target[1], last)
+
+			if dist < 5.0 {
+				nav.CurrentWaypoint++
+			}
+
+			continue
+		}
+
 		if dist < 5.0 {
 			nav.CurrentWaypoint++
 
@@ -483,3 +584,17 @@ func (s *NavigationSystem) Update(world *ecs.World, dt float64) {
 		pos.Y += moveY
 	}
 }
+
+// steerToward points a steering component at a waypoint, seeking toward
+// intermediate points and arriving at the final one.
+func steerToward(steer *Steering, x, y float64, arrive bool) {
+	steer.SetTarget(x, y)
+
+	if arrive {
+		steer.Disable(SteerSeek)
+		steer.Enable(SteerArrive)
+	} else {
+		steer.Disable(SteerArrive)
+		steer.Enable(SteerSeek)
+	}
+}
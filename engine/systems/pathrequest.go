@@ -0,0 +1,460 @@
+package systems
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// MultiPathFinder can solve several requests that share a goal in one search.
+// PathRequestService uses it to coalesce requests when available.
+type MultiPathFinder interface {
+	PathFinder
+	FindPaths(starts [][2]float64, endX, endY float64) []*Path
+}
+
+// PathRequest asks for a path for an entity.
+type PathRequest struct {
+	Entity         ecs.Entity
+	StartX, StartY float64
+	EndX, EndY     float64
+
+	seq uint64
+}
+
+// PathRequestStats counts request service activity.
+type PathRequestStats struct {
+	Requested int // Requests received
+	Coalesced int // Requests merged into an existing job
+	Searches  int // Path searches actually run
+	Delivered int // Paths written to Navigation components
+	Stale     int // Results dropped because a newer request superseded them
+}
+
+// pathJob is a group of requests sharing the same goal.
+type pathJob struct {
+	key        [2]int64
+	endX, endY float64
+	requests   []PathRequest
+	paths      []*Path
+	searches   int
+}
+
+// PathRequestService resolves path requests off the main loop.
+//
+// Agents enqueue requests with Request; Update dispatches queued jobs to a
+// worker pool (or solves them inline within FrameBudget when there are no
+// workers) and delivers finished paths to Navigation components on the next
+// Update. Requests whose goals fall within GoalTolerance of each other are
+// merged into a single job, and a newer request from the same entity
+// supersedes older ones.
+//
+// The underlying PathFinder must be safe for concurrent reads; avoid mutating
+// a NavGrid while workers are running.
+type PathRequestService struct {
+	Finder        PathFinder
+	MaxPerFrame   int           // Max jobs dispatched per Update (0 = unlimited)
+	FrameBudget   time.Duration // Inline solve budget per Update when there are no workers
+	GoalTolerance float64       // Goals closer than this are coalesced
+
+	navMap *ecs.Map[Navigation]
+
+	queue   []*pathJob
+	byGoal  map[[2]int64]*pathJob
+	latest  map[ecs.Entity]uint64
+	nextSeq uint64
+
+	workers  int
+	jobs     chan *pathJob
+	done     chan *pathJob
+	ready    []*pathJob
+	inFlight int
+	wg       sync.WaitGroup
+	closed   bool
+
+	Stats PathRequestStats
+}
+
+// NewPathRequestService creates a request service. With workers > 0, searches
+// run on that many goroutines; with 0 they run inline during Update.
+func NewPathRequestService(world *ecs.World, finder PathFinder, workers int) *PathRequestService {
+	s := &PathRequestService{
+		Finder:        finder,
+		MaxPerFrame:   32,
+		FrameBudget:   2 * time.Millisecond,
+		GoalTolerance: 1,
+		navMap:        ecs.NewMap[Navigation](world),
+		byGoal:        make(map[[2]int64]*pathJob),
+		latest:        make(map[ecs.Entity]uint64),
+		workers:       workers,
+	}
+
+	if workers > 0 {
+		s.jobs = make(chan *pathJob, workers*2)
+		s.done = make(chan *pathJob, workers*4)
+
+		for range workers {
+			s.wg.Add(1)
+
+			go s.work()
+		}
+	}
+
+	return s
+}
+
+// Request enqueues a path request. A newer request from the same entity
+// replaces any older one still pending.
+func (s *PathRequestService) Request(entity ecs.Entity, startX, startY, endX, endY float64) {
+	s.Stats.Requested++
+	s.nextSeq++
+	s.latest[entity] = s.nextSeq
+
+	req := PathRequest{
+		Entity: entity,
+		StartX: startX, StartY: startY,
+		EndX: endX, EndY: endY,
+		seq: s.nextSeq,
+	}
+
+	key := s.goalKey(endX, endY)
+	if job, ok := s.byGoal[key]; ok {
+		job.requests = append(job.requests, req)
+		s.Stats.Coalesced++
+
+		return
+	}
+
+	job := &pathJob{key: key, endX: endX, endY: endY, requests: []PathRequest{req}}
+	s.byGoal[key] = job
+	s.queue = append(s.queue, job)
+}
+
+// Pending returns true if the entity has a request that has not been delivered.
+func (s *PathRequestService) Pending(entity ecs.Entity) bool {
+	_, ok := s.latest[entity]
+
+	return ok
+}
+
+// QueueLength returns the number of jobs waiting to be dispatched.
+func (s *PathRequestService) QueueLength() int {
+	return len(s.queue)
+}
+
+// Update delivers finished paths and dispatches queued jobs.
+func (s *PathRequestService) Update(world *ecs.World) {
+	// Deliver results finished since the last tick
+	for _, job := range s.ready {
+		s.deliver(world, job)
+	}
+
+	s.ready = s.ready[:0]
+
+	if s.workers > 0 {
+		s.collect()
+		s.dispatch()
+
+		return
+	}
+
+	s.solveInline()
+}
+
+// Close stops the worker pool. Queued requests are discarded.
+func (s *PathRequestService) Close() {
+	if s.closed || s.workers == 0 {
+		return
+	}
+
+	s.closed = true
+	close(s.jobs)
+	s.wg.Wait()
+}
+
+// work is the worker goroutine loop.
+func (s *PathRequestService) work() {
+	defer s.wg.Done()
+
+	for job := range s.jobs {
+		s.solve(job)
+		s.done <- job
+	}
+}
+
+// collect gathers finished jobs from workers without blocking.
+func (s *PathRequestService) collect() {
+	for {
+		select {
+		case job := <-s.done:
+			s.inFlight--
+			s.ready = append(s.ready, job)
+		default:
+			return
+		}
+	}
+}
+
+// dispatch hands queued jobs to workers without blocking.
+func (s *PathRequestService) dispatch() {
+	if s.closed {
+		return
+	}
+
+	sent := 0
+
+	for len(s.queue) > 0 && (s.MaxPerFrame <= 0 || sent < s.MaxPerFrame) {
+		// Keep room in the results channel so workers never block on it
+		if s.inFlight >= cap(s.done) {
+			return
+		}
+
+		job := s.queue[0]
+
+		select {
+		case s.jobs <- job:
+			s.popQueue()
+			s.inFlight++
+			sent++
+		default:
+			return
+		}
+	}
+}
+
+// solveInline solves queued jobs on the caller's goroutine within budget.
+func (s *PathRequestService) solveInline() {
+	start := time.Now()
+	solved := 0
+
+	for len(s.queue) > 0 {
+		if s.MaxPerFrame > 0 && solved >= s.MaxPerFrame {
+			return
+		}
+
+		if solved > 0 && s.FrameBudget > 0 && time.Since(start) >= s.FrameBudget {
+			return
+		}
+
+		job := s.popQueue()
+		s.solve(job)
+		s.ready = append(s.ready, job)
+		solved++
+	}
+}
+
+// popQueue removes and returns the first queued job.
+func (s *PathRequestService) popQueue() *pathJob {
+	job := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.byGoal, job.key)
+
+	return job
+}
+
+// solve runs the search(es) for a job.
+func (s *PathRequestService) solve(job *pathJob) {
+	// Only the newest request per entity within the job matters
+	job.requests = newestPerEntity(job.requests)
+	job.paths = make([]*Path, len(job.requests))
+
+	if multi, ok := s.Finder.(MultiPathFinder); ok && len(job.requests) > 1 {
+		starts := make([][2]float64, len(job.requests))
+		for i, r := range job.requests {
+			starts[i] = [2]float64{r.StartX, r.StartY}
+		}
+
+		copy(job.paths, multi.FindPaths(starts, job.endX, job.endY))
+		job.searches = 1
+
+		return
+	}
+
+	for i, r := range job.requests {
+		job.paths[i] = s.Finder.FindPath(r.StartX, r.StartY, r.EndX, r.EndY)
+	}
+
+	job.searches = len(job.requests)
+}
+
+// deliver writes job results to Navigation components.
+func (s *PathRequestService) deliver(world *ecs.World, job *pathJob) {
+	s.Stats.Searches += job.searches
+
+	for i, req := range job.requests {
+		if s.latest[req.Entity] != req.seq {
+			s.Stats.Stale++
+
+			continue
+		}
+
+		delete(s.latest, req.Entity)
+
+		if !world.Alive(req.Entity) || !s.navMap.Has(req.Entity) {
+			continue
+		}
+
+		nav := s.navMap.Get(req.Entity)
+		nav.Path = job.paths[i]
+		nav.CurrentWaypoint = 0
+		nav.Pending = false
+		s.Stats.Delivered++
+	}
+}
+
+// goalKey quantizes a goal position for coalescing.
+func (s *PathRequestService) goalKey(x, y float64) [2]int64 {
+	tol := s.GoalTolerance
+	if tol <= 0 {
+		tol = 1
+	}
+
+	return [2]int64{int64(math.Floor(x / tol)), int64(math.Floor(y / tol))}
+}
+
+// newestPerEntity keeps the highest sequence request for each entity.
+func newestPerEntity(reqs []PathRequest) []PathRequest {
+	index := make(map[ecs.Entity]int, len(reqs))
+	result := reqs[:0]
+
+	for _, r := range reqs {
+		if i, ok := index[r.Entity]; ok {
+			if r.seq > result[i].seq {
+				result[i] = r
+			}
+
+			continue
+		}
+
+		index[r.Entity] = len(result)
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// ============================================================================
+// Multi-start grid search
+// ============================================================================
+
+// FindPaths finds paths from several starts to one goal with a single
+// reverse Dijkstra search from the goal. Unreachable starts get invalid paths.
+func (p *PathfindingSystem) FindPaths(starts [][2]float64, endX, endY float64) []*Path {
+	grid := p.Grid
+	paths := make([]*Path, len(starts))
+
+	ex, ey := grid.WorldToGrid(endX, endY)
+	if !grid.IsWalkable(ex, ey) {
+		for i := range paths {
+			paths[i] = &Path{Valid: false}
+		}
+
+		return paths
+	}
+
+	// Cells still waiting to be reached
+	remaining := make(map[int64]bool)
+
+	for _, st := range starts {
+		sx, sy := grid.WorldToGrid(st[0], st[1])
+		if grid.IsWalkable(sx, sy) {
+			remaining[coordKey(sx, sy)] = true
+		}
+	}
+
+	open := &PathHeap{}
+	nodes := make(map[int64]*PathNode)
+	closed := make(map[int64]bool)
+
+	goal := &PathNode{X: ex, Y: ey}
+	heap.Push(open, goal)
+	nodes[coordKey(ex, ey)] = goal
+
+	budget := p.MaxNodes * max(1, len(remaining))
+	explored := 0
+
+	for open.Len() > 0 && len(remaining) > 0 && explored < budget {
+		explored++
+
+		current, ok := heap.Pop(open).(*PathNode)
+		if !ok {
+			continue
+		}
+
+		key := coordKey(current.X, current.Y)
+		closed[key] = true
+		delete(remaining, key)
+
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+
+				// Predecessor cell that would step onto current
+				nx, ny := current.X+dx, current.Y+dy
+				if !grid.IsWalkable(nx, ny) {
+					continue
+				}
+
+				if dx != 0 && dy != 0 {
+					if !grid.IsWalkable(nx, current.Y) || !grid.IsWalkable(current.X, ny) {
+						continue
+					}
+				}
+
+				nkey := coordKey(nx, ny)
+				if closed[nkey] {
+					continue
+				}
+
+				moveCost := 1.0
+				if dx != 0 && dy != 0 {
+					moveCost = 1.414
+				}
+
+				// Forward cost is charged for the cell being entered
+				g := current.G + moveCost*grid.Costs[current.Y][current.X]
+
+				neighbor, exists := nodes[nkey]
+				if !exists {
+					neighbor = &PathNode{X: nx, Y: ny, G: g, F: g, Parent: current}
+					nodes[nkey] = neighbor
+					heap.Push(open, neighbor)
+				} else if g < neighbor.G {
+					neighbor.G = g
+					neighbor.F = g
+					neighbor.Parent = current
+					heap.Fix(open, neighbor.index)
+				}
+			}
+		}
+	}
+
+	for i, st := range starts {
+		sx, sy := grid.WorldToGrid(st[0], st[1])
+
+		node, ok := nodes[coordKey(sx, sy)]
+		if !ok || !closed[coordKey(sx, sy)] {
+			paths[i] = &Path{Valid: false}
+
+			continue
+		}
+
+		// Parents point toward the goal, so walking them yields start -> goal
+		path := &Path{Valid: true}
+		for n := node; n != nil; n = n.Parent {
+			wx, wy := grid.GridToWorld(n.X, n.Y)
+			path.Points = append(path.Points, [2]float64{wx, wy})
+		}
+
+		path.Length = pathLength(path.Points)
+		paths[i] = path
+	}
+
+	return paths
+}
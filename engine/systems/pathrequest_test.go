@@ -0,0 +1,184 @@
+package systems
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// newRequestTestGrid creates a 20x20 grid with a vertical wall.
+func newRequestTestGrid() *NavGrid {
+	grid := NewNavGrid(20, 20, 10)
+	for y := range 15 {
+		grid.SetWalkable(10, y, false)
+	}
+
+	return grid
+}
+
+// TestFindPaths tests the multi-start grid search.
+func TestFindPaths(t *testing.T) {
+	pf := NewPathfindingSystem(newRequestTestGrid())
+	starts := [][2]float64{{15, 15}, {55, 25}, {35, 105}}
+
+	paths := pf.FindPaths(starts, 185, 15)
+
+	for i, st := range starts {
+		single := pf.FindPath(st[0], st[1], 185, 15)
+
+		if !paths[i].Valid {
+			t.Fatalf("path %d should be valid", i)
+		}
+
+		first := paths[i].Points[0]
+		if math.Hypot(first[0]-st[0], first[1]-st[1]) > 10 {
+			t.Errorf("path %d starts at %v, want near %v", i, first, st)
+		}
+
+		if math.Abs(paths[i].Length-single.Length) > 1 {
+			t.Errorf("path %d length = %v, single search = %v", i, paths[i].Length, single.Length)
+		}
+	}
+}
+
+// TestPathRequestService tests queuing, coalescing and delivery.
+func TestPathRequestService(t *testing.T) {
+	newWorld := func(n int) (*ecs.World, []ecs.Entity, *ecs.Map[Navigation]) {
+		world := ecs.NewWorld()
+		mapper := ecs.NewMap2[components.Position, Navigation](&world)
+
+		entities := make([]ecs.Entity, n)
+		for i := range entities {
+			entities[i] = mapper.NewEntity(&components.Position{}, &Navigation{})
+		}
+
+		return &world, entities, ecs.NewMap[Navigation](&world)
+	}
+
+	t.Run("coalesces requests to the same goal", func(t *testing.T) {
+		world, entities, navMap := newWorld(3)
+		svc := NewPathRequestService(world, NewPathfindingSystem(newRequestTestGrid()), 0)
+
+		for i, e := range entities {
+			svc.Request(e, 15, float64(15+i*20), 185, 15)
+		}
+
+		if svc.QueueLength() != 1 {
+			t.Errorf("QueueLength = %d, want 1", svc.QueueLength())
+		}
+
+		svc.Update(world)
+
+		if navMap.Get(entities[0]).Path != nil {
+			t.Error("paths should be delivered on the next update, not immediately")
+		}
+
+		svc.Update(world)
+
+		for _, e := range entities {
+			if nav := navMap.Get(e); nav.Path == nil || !nav.Path.Valid {
+				t.Error("expected a valid delivered path")
+			}
+		}
+
+		if svc.Stats.Searches != 1 || svc.Stats.Coalesced != 2 {
+			t.Errorf("Stats = %+v, want 1 search and 2 coalesced", svc.Stats)
+		}
+	})
+
+	t.Run("newer request supersedes older one", func(t *testing.T) {
+		world, entities, navMap := newWorld(1)
+		svc := NewPathRequestService(world, NewPathfindingSystem(newRequestTestGrid()), 0)
+		svc.FrameBudget = 0
+
+		svc.Request(entities[0], 15, 15, 185, 15)
+		svc.Request(entities[0], 15, 15, 15, 185)
+		svc.Update(world)
+		svc.Update(world)
+
+		path := navMap.Get(entities[0]).Path
+		if path == nil {
+			t.Fatal("expected a delivered path")
+		}
+
+		last := path.Points[len(path.Points)-1]
+		if last != [2]float64{15, 185} {
+			t.Errorf("path ends at %v, want newest goal (15, 185)", last)
+		}
+
+		if svc.Pending(entities[0]) {
+			t.Error("entity should no longer be pending")
+		}
+	})
+
+	t.Run("respects per-frame limit", func(t *testing.T) {
+		world, entities, _ := newWorld(4)
+		svc := NewPathRequestService(world, NewPathfindingSystem(newRequestTestGrid()), 0)
+		svc.MaxPerFrame = 1
+
+		for i, e := range entities {
+			svc.Request(e, 15, 15, 185, float64(15+i*20))
+		}
+
+		svc.Update(world)
+
+		if svc.QueueLength() != 3 {
+			t.Errorf("QueueLength = %d, want 3 after one frame", svc.QueueLength())
+		}
+	})
+
+	t.Run("worker pool delivers paths", func(t *testing.T) {
+		world, entities, navMap := newWorld(8)
+		svc := NewPathRequestService(world, NewPathfindingSystem(newRequestTestGrid()), 2)
+		defer svc.Close()
+
+		for i, e := range entities {
+			svc.Request(e, 15, 15, 185, float64(15+i*20))
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for svc.Stats.Delivered < len(entities) && time.Now().Before(deadline) {
+			svc.Update(world)
+			time.Sleep(time.Millisecond)
+		}
+
+		for _, e := range entities {
+			if navMap.Get(e).Path == nil {
+				t.Fatal("worker pool did not deliver all paths")
+			}
+		}
+	})
+}
+
+// TestNavigationWithRequests tests NavigationSystem using the request service.
+func TestNavigationWithRequests(t *testing.T) {
+	world := ecs.NewWorld()
+	pf := NewPathfindingSystem(newRequestTestGrid())
+	svc := NewPathRequestService(&world, pf, 0)
+
+	nav := NewNavigationSystem(&world, pf)
+	nav.Requests = svc
+
+	mapper := ecs.NewMap2[components.Position, Navigation](&world)
+	entity := mapper.NewEntity(
+		&components.Position{X: 15, Y: 15},
+		&Navigation{TargetX: 185, TargetY: 15, Speed: 200, RecalcInterval: 100},
+	)
+
+	for range 300 {
+		svc.Update(&world)
+		nav.Update(&world, 1.0/60)
+	}
+
+	pos, n := mapper.Get(entity)
+	if math.Hypot(pos.X-185, pos.Y-15) > 6 {
+		t.Errorf("Position = (%v, %v), want ~(185, 15)", pos.X, pos.Y)
+	}
+
+	if svc.Stats.Requested != 1 || n.Pending {
+		t.Errorf("expected one delivered request, stats %+v pending %v", svc.Stats, n.Pending)
+	}
+}
@@ -58,6 +58,8 @@ func (s *ProgressionSystem) AddExperience(world *ecs.World, entity ecs.Entity, a
 		if e == entity {
 			exp = expQuery.Get()
 
+			expQuery.Close()
+
 			break
 		}
 	}
@@ -73,6 +75,8 @@ func (s *ProgressionSystem) AddExperience(world *ecs.World, entity ecs.Entity, a
 		if e == entity {
 			level = levelQuery.Get()
 
+			levelQuery.Close()
+
 			break
 		}
 	}
@@ -112,6 +116,8 @@ func (s *ProgressionSystem) GetLevel(world *ecs.World, entity ecs.Entity) int {
 		if e == entity {
 			level := query.Get()
 
+			query.Close()
+
 			return level.Current
 		}
 	}
@@ -132,6 +138,8 @@ func (s *ProgressionSystem) GetExperience(world *ecs.World, entity ecs.Entity) (
 		if e == entity {
 			exp = expQuery.Get()
 
+			expQuery.Close()
+
 			break
 		}
 	}
@@ -142,6 +150,8 @@ func (s *ProgressionSystem) GetExperience(world *ecs.World, entity ecs.Entity) (
 		if e == entity {
 			level = levelQuery.Get()
 
+			levelQuery.Close()
+
 			break
 		}
 	}
@@ -178,6 +188,8 @@ func (s *ProgressionSystem) SpendSkillPoints(
 		if e == entity {
 			level = query.Get()
 
+			query.Close()
+
 			break
 		}
 	}
@@ -220,6 +232,8 @@ func (s *ProgressionSystem) ResetSkillTree(
 		if e == entity {
 			level = query.Get()
 
+			query.Close()
+
 			break
 		}
 	}
@@ -0,0 +1,609 @@
+package systems
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// SteeringBehavior is a bitmask of steering behaviors enabled on an entity.
+type SteeringBehavior uint32
+
+const (
+	SteerSeek SteeringBehavior = 1 << iota
+	SteerFlee
+	SteerArrive
+	SteerWander
+	SteerPursue
+	SteerEvade
+	SteerSeparation
+	SteerCohesion
+	SteerAlignment
+	SteerAvoidance // RVO local collision avoidance, applied after the weighted sum
+
+	// SteerFlocking enables the three classic boids behaviors.
+	SteerFlocking = SteerSeparation | SteerCohesion | SteerAlignment
+)
+
+// SteeringWeights scales the contribution of each behavior to the final force.
+type SteeringWeights struct {
+	Seek       float64
+	Flee       float64
+	Arrive     float64
+	Wander     float64
+	Pursue     float64
+	Evade      float64
+	Separation float64
+	Cohesion   float64
+	Alignment  float64
+}
+
+// DefaultSteeringWeights returns weights that work well for most agents.
+func DefaultSteeringWeights() SteeringWeights {
+	return SteeringWeights{
+		Seek:       1.0,
+		Flee:       1.0,
+		Arrive:     1.0,
+		Wander:     0.5,
+		Pursue:     1.0,
+		Evade:      1.0,
+		Separation: 1.5,
+		Cohesion:   0.5,
+		Alignment:  0.8,
+	}
+}
+
+// Steering component composes steering behaviors for an entity.
+// Entities with Position and Steering are moved by SteeringSystem.
+type Steering struct {
+	Behaviors SteeringBehavior
+	Weights   SteeringWeights
+
+	// Physical limits
+	MaxSpeed float64 // Units per second
+	MaxForce float64 // Max change in speed per second (acceleration)
+	Radius   float64 // Agent radius used by separation and avoidance
+
+	// Targets
+	TargetX, TargetY float64    // Point target for seek/flee/arrive
+	TargetEntity     ecs.Entity // Entity target for pursue/evade
+	SlowingRadius    float64    // Arrive starts decelerating inside this radius
+	FleeRadius       float64    // Flee/evade only react inside this radius (0 = always)
+
+	// Neighborhood
+	NeighborRadius float64 // Range for flocking and avoidance neighbors
+	TimeHorizon    float64 // Seconds ahead RVO looks for collisions
+
+	// Wander settings
+	WanderDistance float64 // Distance of the wander circle ahead of the agent
+	WanderRadius   float64 // Radius of the wander circle
+	WanderJitter   float64 // Max random angle change per second (radians)
+	wanderAngle    float64
+
+	// State
+	VelX, VelY float64 // Current velocity (units per second)
+}
+
+// NewSteering creates a steering component with sensible defaults.
+func NewSteering(maxSpeed, maxForce float64) Steering {
+	return Steering{
+		Weights:        DefaultSteeringWeights(),
+		MaxSpeed:       maxSpeed,
+		MaxForce:       maxForce,
+		Radius:         8,
+		SlowingRadius:  64,
+		NeighborRadius: 64,
+		TimeHorizon:    1.5,
+		WanderDistance: 40,
+		WanderRadius:   20,
+		WanderJitter:   math.Pi,
+	}
+}
+
+// Enable turns on the given behaviors.
+func (s *Steering) Enable(b SteeringBehavior) {
+	s.Behaviors |= b
+}
+
+// Disable turns off the given behaviors.
+func (s *Steering) Disable(b SteeringBehavior) {
+	s.Behaviors &^= b
+}
+
+// Has returns true if all given behaviors are enabled.
+func (s *Steering) Has(b SteeringBehavior) bool {
+	return s.Behaviors&b == b
+}
+
+// SetTarget sets the point target for seek/flee/arrive.
+func (s *Steering) SetTarget(x, y float64) {
+	s.TargetX = x
+	s.TargetY = y
+}
+
+// Speed returns the current speed.
+func (s *Steering) Speed() float64 {
+	return math.Hypot(s.VelX, s.VelY)
+}
+
+// SteeringAgent is a snapshot of an agent used by the steering functions.
+type SteeringAgent struct {
+	X, Y     float64
+	VX, VY   float64
+	MaxSpeed float64
+	Radius   float64
+}
+
+// ============================================================================
+// Individual Behaviors
+// ============================================================================
+//
+// Each behavior returns a steering force (desired velocity - current velocity).
+
+// Seek steers toward a target at full speed.
+func Seek(a SteeringAgent, tx, ty float64) (float64, float64) {
+	dx, dy := normalize(tx-a.X, ty-a.Y)
+
+	return dx*a.MaxSpeed - a.VX, dy*a.MaxSpeed - a.VY
+}
+
+// Flee steers away from a point. If panicRadius > 0, only reacts inside it.
+func Flee(a SteeringAgent, tx, ty, panicRadius float64) (float64, float64) {
+	dx, dy := a.X-tx, a.Y-ty
+	if panicRadius > 0 && dx*dx+dy*dy > panicRadius*panicRadius {
+		return 0, 0
+	}
+
+	dx, dy = normalize(dx, dy)
+
+	return dx*a.MaxSpeed - a.VX, dy*a.MaxSpeed - a.VY
+}
+
+// Arrive steers toward a target, decelerating inside slowingRadius.
+func Arrive(a SteeringAgent, tx, ty, slowingRadius float64) (float64, float64) {
+	dx, dy := tx-a.X, ty-a.Y
+
+	dist := math.Hypot(dx, dy)
+	if dist < 1e-6 {
+		return -a.VX, -a.VY
+	}
+
+	speed := a.MaxSpeed
+	if slowingRadius > 0 && dist < slowingRadius {
+		speed = a.MaxSpeed * dist / slowingRadius
+	}
+
+	return dx/dist*speed - a.VX, dy/dist*speed - a.VY
+}
+
+// Pursue seeks the predicted future position of a moving target.
+func Pursue(a SteeringAgent, target SteeringAgent) (float64, float64) {
+	px, py := predictPosition(a, target)
+
+	return Seek(a, px, py)
+}
+
+// Evade flees from the predicted future position of a moving target.
+func Evade(a SteeringAgent, target SteeringAgent, panicRadius float64) (float64, float64) {
+	px, py := predictPosition(a, target)
+
+	return Flee(a, px, py, panicRadius)
+}
+
+// Wander produces a jittery forward-biased force. jitter is the max angle
+// change for this call; angle is updated in place so motion stays smooth.
+func Wander(
+	a SteeringAgent,
+	angle *float64,
+	distance, radius, jitter float64,
+	rng *rand.Rand,
+) (float64, float64) {
+	*angle += (rng.Float64()*2 - 1) * jitter
+
+	hx, hy := normalize(a.VX, a.VY)
+	if hx == 0 && hy == 0 {
+		hx = 1
+	}
+
+	cx := a.X + hx*distance
+	cy := a.Y + hy*distance
+	tx := cx + math.Cos(*angle)*radius
+	ty := cy + math.Sin(*angle)*radius
+
+	return Seek(a, tx, ty)
+}
+
+// Separation pushes away from neighbors, weighted by inverse distance.
+func Separation(a SteeringAgent, neighbors []SteeringAgent) (float64, float64) {
+	var fx, fy float64
+
+	count := 0
+
+	for _, n := range neighbors {
+		dx, dy := a.X-n.X, a.Y-n.Y
+
+		dist := math.Hypot(dx, dy)
+		if dist < 1e-6 {
+			continue
+		}
+
+		fx += dx / dist / dist
+		fy += dy / dist / dist
+		count++
+	}
+
+	if count == 0 {
+		return 0, 0
+	}
+
+	fx, fy = normalize(fx, fy)
+
+	return fx*a.MaxSpeed - a.VX, fy*a.MaxSpeed - a.VY
+}
+
+// Cohesion steers toward the center of mass of neighbors.
+func Cohesion(a SteeringAgent, neighbors []SteeringAgent) (float64, float64) {
+	if len(neighbors) == 0 {
+		return 0, 0
+	}
+
+	var cx, cy float64
+	for _, n := range neighbors {
+		cx += n.X
+		cy += n.Y
+	}
+
+	n := float64(len(neighbors))
+
+	return Seek(a, cx/n, cy/n)
+}
+
+// Alignment steers toward the average heading of neighbors.
+func Alignment(a SteeringAgent, neighbors []SteeringAgent) (float64, float64) {
+	if len(neighbors) == 0 {
+		return 0, 0
+	}
+
+	var vx, vy float64
+	for _, n := range neighbors {
+		vx += n.VX
+		vy += n.VY
+	}
+
+	vx, vy = normalize(vx, vy)
+	if vx == 0 && vy == 0 {
+		return 0, 0
+	}
+
+	return vx*a.MaxSpeed - a.VX, vy*a.MaxSpeed - a.VY
+}
+
+// predictPosition estimates where target will be when a reaches it.
+func predictPosition(a, target SteeringAgent) (float64, float64) {
+	dist := math.Hypot(target.X-a.X, target.Y-a.Y)
+
+	lookAhead := 0.0
+	if a.MaxSpeed > 0 {
+		lookAhead = dist / a.MaxSpeed
+	}
+
+	return target.X + target.VX*lookAhead, target.Y + target.VY*lookAhead
+}
+
+// ============================================================================
+// RVO Local Avoidance
+// ============================================================================
+
+// rvoSamples is the number of candidate velocities sampled per ring.
+const rvoSamples = 16
+
+// AvoidVelocity selects a collision-free velocity close to the preferred one.
+// It implements sampling-based Reciprocal Velocity Obstacles: each neighbor is
+// assumed to take half the responsibility for avoiding a collision, and the
+// candidate minimizing deviation plus time-to-collision penalty is chosen.
+func AvoidVelocity(
+	a SteeringAgent,
+	prefVX, prefVY float64,
+	neighbors []SteeringAgent,
+	timeHorizon float64,
+) (float64, float64) {
+	if len(neighbors) == 0 || timeHorizon <= 0 {
+		return prefVX, prefVY
+	}
+
+	const collisionWeight = 2.0
+
+	bestVX, bestVY := prefVX, prefVY
+	bestPenalty := math.Inf(1)
+
+	evaluate := func(vx, vy float64) {
+		tc := math.Inf(1)
+
+		for _, n := range neighbors {
+			// Reciprocal: relative velocity is measured against the average of our
+			// candidate and our current velocity.
+			rvx := 2*vx - a.VX - n.VX
+			rvy := 2*vy - a.VY - n.VY
+
+			t := timeToCollision(n.X-a.X, n.Y-a.Y, rvx, rvy, a.Radius+n.Radius)
+			if t < tc {
+				tc = t
+			}
+		}
+
+		penalty := math.Hypot(vx-prefVX, vy-prefVY)
+		if tc < timeHorizon {
+			penalty += collisionWeight * a.MaxSpeed / math.Max(tc, 1e-3)
+		}
+
+		if penalty < bestPenalty {
+			bestPenalty = penalty
+			bestVX, bestVY = vx, vy
+		}
+	}
+
+	evaluate(prefVX, prefVY)
+
+	if bestPenalty == 0 {
+		return bestVX, bestVY
+	}
+
+	evaluate(0, 0)
+
+	for _, frac := range [...]float64{1.0, 0.66, 0.33} {
+		speed := a.MaxSpeed * frac
+		for i := range rvoSamples {
+			angle := 2 * math.Pi * float64(i) / rvoSamples
+			evaluate(math.Cos(angle)*speed, math.Sin(angle)*speed)
+		}
+	}
+
+	return bestVX, bestVY
+}
+
+// timeToCollision returns the time until two discs collide, given the
+// relative position (px,py), relative velocity (vx,vy) and combined radius.
+// Returns +Inf if they never collide and 0 if already overlapping.
+func timeToCollision(px, py, vx, vy, radius float64) float64 {
+	c := px*px + py*py - radius*radius
+	if c < 0 {
+		return 0
+	}
+
+	a := vx*vx + vy*vy
+	b := px*vx + py*vy
+
+	if a < 1e-9 || b <= 0 {
+		return math.Inf(1)
+	}
+
+	disc := b*b - a*c
+	if disc <= 0 {
+		return math.Inf(1)
+	}
+
+	return (b - math.Sqrt(disc)) / a
+}
+
+// ============================================================================
+// Steering System
+// ============================================================================
+
+// SteeringSystem combines enabled behaviors into a velocity and moves entities.
+type SteeringSystem struct {
+	filter   *ecs.Filter2[components.Position, Steering]
+	posMap   *ecs.Map[components.Position]
+	steerMap *ecs.Map[Steering]
+	hash     *SpatialHash
+	rng      *rand.Rand
+
+	// Scratch buffers reused across updates
+	entities []ecs.Entity
+	agents   map[ecs.Entity]SteeringAgent
+}
+
+// NewSteeringSystem creates a steering system.
+func NewSteeringSystem(world *ecs.World, seed int64) *SteeringSystem {
+	return &SteeringSystem{
+		filter:   ecs.NewFilter2[components.Position, Steering](world),
+		posMap:   ecs.NewMap[components.Position](world),
+		steerMap: ecs.NewMap[Steering](world),
+		hash:     NewSpatialHash(64),
+		rng:      rand.New(rand.NewSource(seed)),
+		agents:   make(map[ecs.Entity]SteeringAgent),
+	}
+}
+
+// Update computes steering forces and integrates velocity and position.
+func (s *SteeringSystem) Update(world *ecs.World, dt float64) {
+	if dt <= 0 {
+		return
+	}
+
+	s.snapshot()
+
+	velocities := make([][2]float64, len(s.entities))
+
+	for i, e := range s.entities {
+		steer := s.steerMap.Get(e)
+		agent := s.agents[e]
+		neighbors := s.neighbors(e, agent, steer.NeighborRadius)
+
+		// Forces are velocity corrections; MaxForce limits how fast they apply
+		fx, fy := s.computeForce(world, steer, agent, neighbors, dt)
+		fx, fy = truncate(fx, fy, steer.MaxForce*dt)
+
+		vx := agent.VX + fx
+		vy := agent.VY + fy
+		vx, vy = truncate(vx, vy, steer.MaxSpeed)
+
+		if steer.Has(SteerAvoidance) {
+			vx, vy = AvoidVelocity(agent, vx, vy, neighbors, steer.TimeHorizon)
+		}
+
+		velocities[i] = [2]float64{vx, vy}
+	}
+
+	// Apply after all agents decided, so the update is order independent
+	for i, e := range s.entities {
+		steer := s.steerMap.Get(e)
+		pos := s.posMap.Get(e)
+		steer.VelX, steer.VelY = velocities[i][0], velocities[i][1]
+		pos.X += steer.VelX * dt
+		pos.Y += steer.VelY * dt
+	}
+}
+
+// snapshot records agent state and rebuilds the neighbor hash.
+func (s *SteeringSystem) snapshot() {
+	s.entities = s.entities[:0]
+	s.hash.Clear()
+
+	for e := range s.agents {
+		delete(s.agents, e)
+	}
+
+	query := s.filter.Query()
+	for query.Next() {
+		pos, steer := query.Get()
+		e := query.Entity()
+
+		s.entities = append(s.entities, e)
+		s.agents[e] = SteeringAgent{
+			X: pos.X, Y: pos.Y,
+			VX: steer.VelX, VY: steer.VelY,
+			MaxSpeed: steer.MaxSpeed,
+			Radius:   steer.Radius,
+		}
+		s.hash.Insert(e, pos.X, pos.Y, 0, 0)
+	}
+}
+
+// neighbors returns agents within radius of the given agent.
+func (s *SteeringSystem) neighbors(self ecs.Entity, a SteeringAgent, radius float64) []SteeringAgent {
+	if radius <= 0 {
+		return nil
+	}
+
+	var result []SteeringAgent
+
+	for _, e := range s.hash.Query(a.X-radius, a.Y-radius, radius*2, radius*2) {
+		if e == self {
+			continue
+		}
+
+		n := s.agents[e]
+		if math.Hypot(n.X-a.X, n.Y-a.Y) <= radius {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
+
+// computeForce sums the weighted forces of all enabled behaviors.
+func (s *SteeringSystem) computeForce(
+	world *ecs.World,
+	steer *Steering,
+	a SteeringAgent,
+	neighbors []SteeringAgent,
+	dt float64,
+) (float64, float64) {
+	var fx, fy float64
+
+	add := func(w, x, y float64) {
+		fx += x * w
+		fy += y * w
+	}
+
+	w := steer.Weights
+
+	if steer.Has(SteerSeek) {
+		x, y := Seek(a, steer.TargetX, steer.TargetY)
+		add(w.Seek, x, y)
+	}
+
+	if steer.Has(SteerFlee) {
+		x, y := Flee(a, steer.TargetX, steer.TargetY, steer.FleeRadius)
+		add(w.Flee, x, y)
+	}
+
+	if steer.Has(SteerArrive) {
+		x, y := Arrive(a, steer.TargetX, steer.TargetY, steer.SlowingRadius)
+		add(w.Arrive, x, y)
+	}
+
+	if steer.Has(SteerWander) {
+		x, y := Wander(a, &steer.wanderAngle, steer.WanderDistance, steer.WanderRadius,
+			steer.WanderJitter*dt, s.rng)
+		add(w.Wander, x, y)
+	}
+
+	if steer.Has(SteerPursue) || steer.Has(SteerEvade) {
+		if target, ok := s.targetAgent(world, steer.TargetEntity); ok {
+			if steer.Has(SteerPursue) {
+				x, y := Pursue(a, target)
+				add(w.Pursue, x, y)
+			}
+
+			if steer.Has(SteerEvade) {
+				x, y := Evade(a, target, steer.FleeRadius)
+				add(w.Evade, x, y)
+			}
+		}
+	}
+
+	if steer.Has(SteerSeparation) {
+		x, y := Separation(a, neighbors)
+		add(w.Separation, x, y)
+	}
+
+	if steer.Has(SteerCohesion) {
+		x, y := Cohesion(a, neighbors)
+		add(w.Cohesion, x, y)
+	}
+
+	if steer.Has(SteerAlignment) {
+		x, y := Alignment(a, neighbors)
+		add(w.Alignment, x, y)
+	}
+
+	return fx, fy
+}
+
+// targetAgent returns a snapshot of a pursue/evade target.
+func (s *SteeringSystem) targetAgent(world *ecs.World, e ecs.Entity) (SteeringAgent, bool) {
+	if agent, ok := s.agents[e]; ok {
+		return agent, true
+	}
+
+	if e.IsZero() || !world.Alive(e) || !s.posMap.Has(e) {
+		return SteeringAgent{}, false
+	}
+
+	pos := s.posMap.Get(e)
+
+	return SteeringAgent{X: pos.X, Y: pos.Y}, true
+}
+
+// normalize returns the unit vector of (x, y), or (0, 0) for a zero vector.
+func normalize(x, y float64) (float64, float64) {
+	l := math.Hypot(x, y)
+	if l < 1e-9 {
+		return 0, 0
+	}
+
+	return x / l, y / l
+}
+
+// truncate limits the length of (x, y) to maxLen.
+func truncate(x, y, maxLen float64) (float64, float64) {
+	l := math.Hypot(x, y)
+	if maxLen <= 0 || l <= maxLen {
+		return x, y
+	}
+
+	return x / l * maxLen, y / l * maxLen
+}
@@ -0,0 +1,174 @@
+package systems
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/skyrocket-qy/NeuralWay/engine/components"
+)
+
+// TestSteeringBehaviors tests the individual steering functions.
+func TestSteeringBehaviors(t *testing.T) {
+	agent := SteeringAgent{X: 0, Y: 0, MaxSpeed: 10, Radius: 5}
+
+	t.Run("Seek steers toward target at max speed", func(t *testing.T) {
+		fx, fy := Seek(agent, 100, 0)
+		if fx != 10 || fy != 0 {
+			t.Errorf("Seek = (%v, %v), want (10, 0)", fx, fy)
+		}
+	})
+
+	t.Run("Flee ignores targets outside panic radius", func(t *testing.T) {
+		fx, fy := Flee(agent, 100, 0, 50)
+		if fx != 0 || fy != 0 {
+			t.Errorf("Flee = (%v, %v), want (0, 0)", fx, fy)
+		}
+
+		fx, _ = Flee(agent, 10, 0, 50)
+		if fx != -10 {
+			t.Errorf("Flee.X = %v, want -10", fx)
+		}
+	})
+
+	t.Run("Arrive slows inside slowing radius", func(t *testing.T) {
+		fx, _ := Arrive(agent, 25, 0, 50)
+		if math.Abs(fx-5) > 1e-9 {
+			t.Errorf("Arrive.X = %v, want 5", fx)
+		}
+	})
+
+	t.Run("Pursue leads a moving target", func(t *testing.T) {
+		target := SteeringAgent{X: 100, Y: 0, VY: 10}
+
+		_, fy := Pursue(agent, target)
+		if fy <= 0 {
+			t.Errorf("Pursue.Y = %v, want > 0 to intercept", fy)
+		}
+	})
+
+	t.Run("Separation pushes away from neighbors", func(t *testing.T) {
+		neighbors := []SteeringAgent{{X: 5, Y: 0}}
+
+		fx, _ := Separation(agent, neighbors)
+		if fx >= 0 {
+			t.Errorf("Separation.X = %v, want < 0", fx)
+		}
+	})
+}
+
+// TestAvoidVelocity tests RVO avoidance.
+func TestAvoidVelocity(t *testing.T) {
+	t.Run("keeps preferred velocity when clear", func(t *testing.T) {
+		agent := SteeringAgent{MaxSpeed: 10, Radius: 5}
+
+		vx, vy := AvoidVelocity(agent, 10, 0, nil, 2)
+		if vx != 10 || vy != 0 {
+			t.Errorf("AvoidVelocity = (%v, %v), want (10, 0)", vx, vy)
+		}
+	})
+
+	t.Run("deviates from head-on collision", func(t *testing.T) {
+		agent := SteeringAgent{X: 0, Y: 0, VX: 10, MaxSpeed: 10, Radius: 5}
+		other := SteeringAgent{X: 30, Y: 0, VX: -10, MaxSpeed: 10, Radius: 5}
+
+		vx, vy := AvoidVelocity(agent, 10, 0, []SteeringAgent{other}, 2)
+		if vx == 10 && vy == 0 {
+			t.Error("AvoidVelocity should change a colliding velocity")
+		}
+
+		rvx, rvy := 2*vx-agent.VX-other.VX, 2*vy-agent.VY-other.VY
+		if tc := timeToCollision(other.X, other.Y, rvx, rvy, 10); tc < 1 {
+			t.Errorf("time to collision = %v, want >= 1", tc)
+		}
+	})
+}
+
+// TestSteeringSystem tests the steering system.
+func TestSteeringSystem(t *testing.T) {
+	t.Run("Arrive moves entity to target and stops", func(t *testing.T) {
+		world := ecs.NewWorld()
+		ss := NewSteeringSystem(&world, 1)
+
+		steer := NewSteering(100, 400)
+		steer.Enable(SteerArrive)
+		steer.SetTarget(100, 0)
+
+		mapper := ecs.NewMap2[components.Position, Steering](&world)
+		entity := mapper.NewEntity(&components.Position{}, &steer)
+
+		for range 300 {
+			ss.Update(&world, 1.0/60)
+		}
+
+		pos, st := mapper.Get(entity)
+		if math.Abs(pos.X-100) > 2 {
+			t.Errorf("Position.X = %v, want ~100", pos.X)
+		}
+
+		if st.Speed() > 5 {
+			t.Errorf("Speed = %v, want near 0", st.Speed())
+		}
+	})
+
+	t.Run("Avoidance keeps agents apart", func(t *testing.T) {
+		world := ecs.NewWorld()
+		ss := NewSteeringSystem(&world, 1)
+		mapper := ecs.NewMap2[components.Position, Steering](&world)
+
+		a := NewSteering(60, 600)
+		a.Enable(SteerSeek | SteerAvoidance)
+		a.SetTarget(200, 0)
+
+		b := NewSteering(60, 600)
+		b.Enable(SteerSeek | SteerAvoidance)
+		b.SetTarget(-200, 0)
+
+		ea := mapper.NewEntity(&components.Position{X: -100}, &a)
+		eb := mapper.NewEntity(&components.Position{X: 100}, &b)
+
+		minDist := math.Inf(1)
+
+		for range 240 {
+			ss.Update(&world, 1.0/60)
+
+			pa, _ := mapper.Get(ea)
+			pb, _ := mapper.Get(eb)
+			minDist = math.Min(minDist, math.Hypot(pa.X-pb.X, pa.Y-pb.Y))
+		}
+
+		if minDist < a.Radius+b.Radius-1 {
+			t.Errorf("agents overlapped: min distance %v", minDist)
+		}
+	})
+}
+
+// TestNavigationWithSteering tests that navigation drives steering.
+func TestNavigationWithSteering(t *testing.T) {
+	world := ecs.NewWorld()
+	grid := NewNavGrid(10, 10, 10)
+	nav := NewNavigationSystem(&world, NewPathfindingSystem(grid))
+	ss := NewSteeringSystem(&world, 1)
+
+	mapper := ecs.NewMap3[components.Position, Navigation, Steering](&world)
+	steer := NewSteering(80, 400)
+	entity := mapper.NewEntity(
+		&components.Position{X: 5, Y: 5},
+		&Navigation{TargetX: 85, TargetY: 85, Speed: 80, RecalcInterval: 10},
+		&steer,
+	)
+
+	for range 300 {
+		nav.Update(&world, 1.0/60)
+		ss.Update(&world, 1.0/60)
+	}
+
+	pos, _, st := mapper.Get(entity)
+	if math.Hypot(pos.X-85, pos.Y-85) > 3 {
+		t.Errorf("Position = (%v, %v), want ~(85, 85)", pos.X, pos.Y)
+	}
+
+	if !st.Has(SteerArrive) {
+		t.Error("navigation should switch to arrive on the final waypoint")
+	}
+}
@@ -0,0 +1,181 @@
+package visual
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Canvas is a Surface backed by an image.RGBA. It rasterizes without
+// antialiasing, sampling pixel centers, so output is identical on every
+// machine. Text uses a built-in 3x5 pixel font at twice the size.
+type Canvas struct {
+	img *image.RGBA
+}
+
+// NewCanvas creates a transparent canvas.
+func NewCanvas(width, height int) *Canvas {
+	return &Canvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+// Image returns the canvas pixels.
+func (c *Canvas) Image() *image.RGBA {
+	return c.img
+}
+
+// Size implements Surface.
+func (c *Canvas) Size() (int, int) {
+	return c.img.Rect.Dx(), c.img.Rect.Dy()
+}
+
+// Fill implements Surface. Like ebiten, it replaces rather than blends.
+func (c *Canvas) Fill(col color.Color) {
+	r, g, b, a := col.RGBA()
+	px := [4]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+
+	for i := 0; i < len(c.img.Pix); i += 4 {
+		copy(c.img.Pix[i:i+4], px[:])
+	}
+}
+
+// FillRect implements Surface.
+func (c *Canvas) FillRect(x, y, width, height float32, col color.Color) {
+	c.fill(x, y, x+width, y+height, col, func(float32, float32) bool { return true })
+}
+
+// StrokeRect implements Surface. The stroke is centered on the edges.
+func (c *Canvas) StrokeRect(x, y, width, height, strokeWidth float32, col color.Color) {
+	hw := strokeWidth / 2
+
+	c.fill(x-hw, y-hw, x+width+hw, y+height+hw, col, func(px, py float32) bool {
+		return px < x+hw || py < y+hw || px >= x+width-hw || py >= y+height-hw
+	})
+}
+
+// FillCircle implements Surface.
+func (c *Canvas) FillCircle(cx, cy, r float32, col color.Color) {
+	c.fill(cx-r, cy-r, cx+r, cy+r, col, func(px, py float32) bool {
+		dx, dy := px-cx, py-cy
+
+		return dx*dx+dy*dy <= r*r
+	})
+}
+
+// StrokeLine implements Surface.
+func (c *Canvas) StrokeLine(x0, y0, x1, y1, strokeWidth float32, col color.Color) {
+	hw := strokeWidth / 2
+	dx, dy := x1-x0, y1-y0
+	length2 := dx*dx + dy*dy
+
+	c.fill(min(x0, x1)-hw, min(y0, y1)-hw, max(x0, x1)+hw, max(y0, y1)+hw, col, func(px, py float32) bool {
+		t := float32(0)
+		if length2 > 0 {
+			t = max(0, min(1, ((px-x0)*dx+(py-y0)*dy)/length2))
+		}
+
+		ex, ey := px-(x0+t*dx), py-(y0+t*dy)
+
+		return ex*ex+ey*ey <= hw*hw
+	})
+}
+
+// Text implements Surface.
+func (c *Canvas) Text(s string, x, y int) {
+	const scale = 2
+
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	cx, cy := x, y
+
+	for _, r := range s {
+		if r == '\n' {
+			cx, cy = x, cy+16
+
+			continue
+		}
+
+		glyph, ok := font3x5[unicode.ToUpper(r)]
+		if !ok && !unicode.IsSpace(r) {
+			glyph = strings.Repeat("1", 15)
+		}
+
+		for i, bit := range glyph {
+			if bit == '1' {
+				gx, gy := float32(cx+i%3*scale), float32(cy+3+i/3*scale)
+				c.FillRect(gx, gy, scale, scale, white)
+			}
+		}
+
+		cx += 4 * scale
+	}
+}
+
+// fill blends col into pixels of the box whose centers pass inside.
+func (c *Canvas) fill(x0, y0, x1, y1 float32, col color.Color, inside func(px, py float32) bool) {
+	r, g, b, a := col.RGBA()
+	if a == 0 {
+		return
+	}
+
+	bounds := c.img.Rect
+	minX := max(bounds.Min.X, int(math.Floor(float64(x0))))
+	minY := max(bounds.Min.Y, int(math.Floor(float64(y0))))
+	maxX := min(bounds.Max.X, int(math.Ceil(float64(x1))))
+	maxY := min(bounds.Max.Y, int(math.Ceil(float64(y1))))
+
+	for py := minY; py < maxY; py++ {
+		fy := float32(py) + 0.5
+		if fy < y0 || fy >= y1 {
+			continue
+		}
+
+		for px := minX; px < maxX; px++ {
+			fx := float32(px) + 0.5
+			if fx < x0 || fx >= x1 || !inside(fx, fy) {
+				continue
+			}
+
+			// Source-over with premultiplied colors
+			i := c.img.PixOffset(px, py)
+			p := c.img.Pix[i : i+4 : i+4]
+			p[0] = over(r, p[0], a)
+			p[1] = over(g, p[1], a)
+			p[2] = over(b, p[2], a)
+			p[3] = over(a, p[3], a)
+		}
+	}
+}
+
+// over composites a 16-bit premultiplied source channel over an 8-bit
+// destination channel, saturating like the GPU does for colors brighter
+// than their alpha.
+func over(src uint32, dst uint8, srcAlpha uint32) uint8 {
+	v := src + uint32(dst)*0x101*(0xffff-srcAlpha)/0xffff
+
+	return uint8(min(v, 0xffff) >> 8)
+}
+
+// font3x5 holds glyphs as 15 bits, three per row from the top.
+var font3x5 = map[rune]string{
+	'0': "111101101101111", '1': "010110010010111", '2': "111001111100111",
+	'3': "111001111001111", '4': "101101111001001", '5': "111100111001111",
+	'6': "111100111101111", '7': "111001001001001", '8': "111101111101111",
+	'9': "111101111001111", 'A': "010101111101101", 'B': "110101110101110",
+	'C': "011100100100011", 'D': "110101101101110", 'E': "111100110100111",
+	'F': "111100110100100", 'G': "011100101101011", 'H': "101101111101101",
+	'I': "111010010010111", 'J': "001001001101010", 'K': "101101110101101",
+	'L': "100100100100111", 'M': "101111111101101", 'N': "110101101101101",
+	'O': "010101101101010", 'P': "110101110100100", 'Q': "010101101110011",
+	'R': "110101110101101", 'S': "011100010001110", 'T': "111010010010010",
+	'U': "101101101101111", 'V': "101101101101010", 'W': "101101111111101",
+	'X': "101101010101101", 'Y': "101101010010010", 'Z': "111001010100111",
+	':': "000010000010000", '.': "000000000000010", ',': "000000000010100",
+	'!': "010010010000010", '?': "110001010000010", '-': "000000111000000",
+	'+': "000010111010000", '/': "001001010100100", '(': "010100100100010",
+	')': "010001001001010", '%': "101001010100101", '\'': "010010000000000",
+	'=': "000111000111000", '>': "100010001010100", '<': "001010100010001",
+	'*': "000101010101000", '#': "101111101111101", '_': "000000000000111",
+	'[': "110100100100110", ']': "011001001001011", '|': "010010010010010",
+	'"': "101101000000000", '&': "010101010101011", '@': "111101111100111",
+}
@@ -0,0 +1,132 @@
+package visual
+
+import (
+	"image"
+	"image/color"
+)
+
+// maxYIQDelta is the largest possible perceptual distance between colors.
+const maxYIQDelta = 35215.0
+
+// DiffOptions controls when two frames count as equal.
+type DiffOptions struct {
+	// Threshold is the per-pixel perceptual distance, 0..1, below which
+	// pixels match. 0.1 ignores antialiasing noise but not real changes.
+	Threshold float64
+
+	MaxDiffPixels int     // Differing pixels allowed
+	MaxDiffRatio  float64 // Fraction of compared pixels allowed to differ
+
+	Masks []image.Rectangle // Regions that are not compared, e.g. timers
+}
+
+// DefaultDiffOptions returns a strict comparison tolerant of color noise.
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{Threshold: 0.1}
+}
+
+// DiffResult describes how two frames differ.
+type DiffResult struct {
+	Pass         bool            `json:"pass"`
+	SizeMismatch bool            `json:"size_mismatch,omitempty"`
+	DiffPixels   int             `json:"diff_pixels"`
+	Compared     int             `json:"compared"` // Unmasked pixels
+	Ratio        float64         `json:"ratio"`
+	Bounds       image.Rectangle `json:"bounds"` // Box around differing pixels
+
+	// Image shows the expected frame faded, differing pixels in red and
+	// masked regions tinted blue.
+	Image *image.RGBA `json:"-"`
+}
+
+// Compare diffs got against want with a YIQ perceptual color distance.
+func Compare(want, got image.Image, opts DiffOptions) DiffResult {
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Dx() != gb.Dx() || wb.Dy() != gb.Dy() {
+		return DiffResult{SizeMismatch: true}
+	}
+
+	limit := maxYIQDelta * opts.Threshold * opts.Threshold
+	diff := image.NewRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy()))
+	result := DiffResult{Image: diff}
+
+	for y := range wb.Dy() {
+		for x := range wb.Dx() {
+			w := want.At(wb.Min.X+x, wb.Min.Y+y)
+
+			if masked(opts.Masks, x, y) {
+				diff.Set(x, y, tint(w, color.RGBA{B: 255, A: 255}))
+
+				continue
+			}
+
+			result.Compared++
+
+			if yiqDelta(w, got.At(gb.Min.X+x, gb.Min.Y+y)) <= limit {
+				diff.Set(x, y, tint(w, color.RGBA{R: 255, G: 255, B: 255, A: 255}))
+
+				continue
+			}
+
+			diff.Set(x, y, color.RGBA{R: 255, A: 255})
+			result.DiffPixels++
+			result.Bounds = result.Bounds.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+
+	if result.Compared > 0 {
+		result.Ratio = float64(result.DiffPixels) / float64(result.Compared)
+	}
+
+	result.Pass = result.DiffPixels <= opts.MaxDiffPixels || (opts.MaxDiffRatio > 0 && result.Ratio <= opts.MaxDiffRatio)
+
+	return result
+}
+
+func masked(masks []image.Rectangle, x, y int) bool {
+	p := image.Pt(x, y)
+	for _, m := range masks {
+		if p.In(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// yiqDelta is the squared perceptual distance after blending on white.
+func yiqDelta(a, b color.Color) float64 {
+	ar, ag, ab := onWhite(a)
+	br, bg, bb := onWhite(b)
+
+	y := yiqY(ar, ag, ab) - yiqY(br, bg, bb)
+	i := yiqI(ar, ag, ab) - yiqI(br, bg, bb)
+	q := yiqQ(ar, ag, ab) - yiqQ(br, bg, bb)
+
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+func onWhite(c color.Color) (r, g, b float64) {
+	cr, cg, cb, ca := c.RGBA()
+	white := float64(0xffff - ca)
+
+	return (float64(cr) + white) / 257, (float64(cg) + white) / 257, (float64(cb) + white) / 257
+}
+
+func yiqY(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func yiqI(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func yiqQ(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// tint fades c to a quarter of its brightness mixed with hue.
+func tint(c, hue color.Color) color.RGBA {
+	r, g, b := onWhite(c)
+	luma := yiqY(r, g, b) / 4
+	hr, hg, hb, _ := hue.RGBA()
+
+	return color.RGBA{
+		R: uint8(luma + float64(hr>>8)*0.15),
+		G: uint8(luma + float64(hg>>8)*0.15),
+		B: uint8(luma + float64(hb>>8)*0.15),
+		A: 255,
+	}
+}
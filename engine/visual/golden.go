@@ -0,0 +1,277 @@
+package visual
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+// Status is the outcome of one golden check.
+type Status string
+
+const (
+	StatusPass    Status = "pass"
+	StatusFail    Status = "fail"
+	StatusNew     Status = "new"     // No golden existed; it was written in update mode
+	StatusUpdated Status = "updated" // Golden rewritten in update mode
+	StatusMissing Status = "missing" // No golden and not in update mode
+	StatusError   Status = "error"
+)
+
+// Result is the outcome of comparing one frame with its golden.
+type Result struct {
+	Name    string     `json:"name"`
+	Status  Status     `json:"status"`
+	Backend Backend    `json:"backend"`
+	Diff    DiffResult `json:"diff"`
+	Error   string     `json:"error,omitempty"`
+
+	Actual   *image.RGBA `json:"-"`
+	Expected image.Image `json:"-"`
+}
+
+// Harness renders frames and compares them with PNG goldens in Dir.
+// Goldens are only written with Update set, which rewrites every golden;
+// otherwise a missing golden fails, so a deleted or renamed one cannot turn
+// the check into a no-op.
+type Harness struct {
+	Dir     string
+	Width   int
+	Height  int
+	Backend Backend // Default BackendCPU, so goldens match on any machine
+	Options DiffOptions
+	Update  bool // Defaults to the UPDATE_GOLDENS environment variable
+
+	masks   map[string][]image.Rectangle
+	results []Result
+}
+
+// NewHarness creates a harness for width x height frames.
+func NewHarness(dir string, width, height int) *Harness {
+	return &Harness{
+		Dir:     dir,
+		Width:   width,
+		Height:  height,
+		Backend: BackendCPU,
+		Options: DefaultDiffOptions(),
+		Update:  os.Getenv("UPDATE_GOLDENS") != "",
+		masks:   make(map[string][]image.Rectangle),
+	}
+}
+
+// Mask excludes regions from one golden's comparison, on top of
+// Options.Masks.
+func (h *Harness) Mask(name string, rects ...image.Rectangle) {
+	h.masks[name] = append(h.masks[name], rects...)
+}
+
+// GoldenPath returns where the named golden is stored.
+func (h *Harness) GoldenPath(name string) string {
+	return filepath.Join(h.Dir, name+".png")
+}
+
+// Check renders d and compares it with the named golden.
+func (h *Harness) Check(name string, d Drawable) Result {
+	result := Result{Name: name}
+
+	actual, backend, err := Render(d, h.Width, h.Height, h.Backend)
+	result.Actual, result.Backend = actual, backend
+
+	if err == nil {
+		err = h.compare(&result)
+	}
+
+	if err != nil {
+		result.Status, result.Error = StatusError, err.Error()
+	}
+
+	h.results = append(h.results, result)
+
+	return result
+}
+
+func (h *Harness) compare(result *Result) error {
+	path := h.GoldenPath(result.Name)
+
+	expected, err := readPNG(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !h.Update {
+			result.Status, result.Error = StatusMissing, "no golden at "+path+"; run with UPDATE_GOLDENS=1 to create it"
+
+			return nil
+		}
+
+		result.Status = StatusNew
+
+		return writePNG(path, result.Actual)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	opts := h.Options
+	opts.Masks = slices.Concat(opts.Masks, h.masks[result.Name])
+
+	result.Expected = expected
+	result.Diff = Compare(expected, result.Actual, opts)
+
+	switch {
+	case h.Update:
+		result.Status = StatusUpdated
+
+		return writePNG(path, result.Actual)
+	case result.Diff.Pass:
+		result.Status = StatusPass
+	default:
+		result.Status = StatusFail
+	}
+
+	return nil
+}
+
+// Results returns every check so far.
+func (h *Harness) Results() []Result {
+	return h.results
+}
+
+// Failed returns checks that failed, errored or had no golden.
+func (h *Harness) Failed() []Result {
+	var failed []Result
+
+	for _, r := range h.results {
+		if r.Status == StatusFail || r.Status == StatusError || r.Status == StatusMissing {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+// Attach checks frames during a QA session: after the listed ticks of the
+// first run, d is captured as "<name>_<tick>". Seed the game and use a
+// deterministic player so the run repeats exactly.
+func (h *Harness) Attach(session *ai.QASession, name string, d Drawable, ticks ...int64) {
+	session.OnStep(func(run int, tick int64) {
+		if run == 0 && slices.Contains(ticks, tick) {
+			h.Check(fmt.Sprintf("%s_%d", name, tick), d)
+		}
+	})
+}
+
+// ============================================================================
+// Report
+// ============================================================================
+
+// WriteReport writes index.html and the expected, actual and diff PNGs
+// of every check into dir.
+func (h *Harness) WriteReport(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	type row struct {
+		Result
+
+		ExpectedPNG, ActualPNG, DiffPNG string
+		Percent                         string
+	}
+
+	rows := make([]row, 0, len(h.results))
+
+	for _, r := range h.results {
+		rw := row{Result: r, Percent: fmt.Sprintf("%.3f%%", r.Diff.Ratio*100)}
+
+		var err error
+
+		if r.Expected != nil {
+			rw.ExpectedPNG, err = writeReportPNG(dir, r.Name, "expected", r.Expected)
+		}
+
+		if r.Actual != nil && err == nil {
+			rw.ActualPNG, err = writeReportPNG(dir, r.Name, "actual", r.Actual)
+		}
+
+		if r.Diff.Image != nil && err == nil {
+			rw.DiffPNG, err = writeReportPNG(dir, r.Name, "diff", r.Diff.Image)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, rw)
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, rows); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "index.html"), buf.Bytes(), 0o644)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Visual Regression Report</title>
+<style>
+body { font-family: sans-serif; background: #1b1f27; color: #ddd; }
+.check { margin: 1em 0; padding: 1em; background: #262b36; border-left: 6px solid #888; }
+.pass { border-color: #3c3; } .fail, .error { border-color: #e33; } .new, .updated { border-color: #39f; }
+img { max-width: 32%; image-rendering: pixelated; border: 1px solid #444; }
+</style>
+</head>
+<body>
+<h1>Visual Regression Report</h1>
+{{range .}}
+<div class="check {{.Status}}">
+<h2>{{.Name}} — {{.Status}}</h2>
+<p>Backend: {{.Backend}} · Differing pixels: {{.Diff.DiffPixels}} of {{.Diff.Compared}} ({{.Percent}}){{if .Diff.SizeMismatch}} · size mismatch{{end}}{{if .Error}} · {{.Error}}{{end}}</p>
+{{if .ExpectedPNG}}<img src="{{.ExpectedPNG}}" alt="expected" title="expected">{{end}}
+{{if .ActualPNG}}<img src="{{.ActualPNG}}" alt="actual" title="actual">{{end}}
+{{if .DiffPNG}}<img src="{{.DiffPNG}}" alt="diff" title="diff">{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+func writeReportPNG(dir, name, kind string, img image.Image) (string, error) {
+	file := name + "." + kind + ".png"
+
+	return file, writePNG(filepath.Join(dir, file), img)
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
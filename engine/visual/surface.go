@@ -0,0 +1,159 @@
+// Package visual renders games offscreen and compares the frames with
+// golden PNG images.
+//
+// Games draw through a Surface instead of calling ebiten directly. The
+// same drawing code then renders to the screen with EbitenSurface, or to
+// a Canvas software rasterizer in headless CI where no GPU is available:
+//
+//	func (g *Game) Draw(screen *ebiten.Image) {
+//		g.DrawTo(visual.NewEbitenSurface(screen))
+//	}
+//
+//	h := visual.NewHarness("testdata/golden", 640, 480)
+//	h.Check("title", game)
+package visual
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Surface is the set of drawing primitives games render with. Colors are
+// premultiplied as in ebiten.
+type Surface interface {
+	Size() (width, height int)
+	Fill(c color.Color)
+	FillRect(x, y, width, height float32, c color.Color)
+	StrokeRect(x, y, width, height, strokeWidth float32, c color.Color)
+	FillCircle(cx, cy, r float32, c color.Color)
+	StrokeLine(x0, y0, x1, y1, strokeWidth float32, c color.Color)
+	Text(s string, x, y int) // Debug text, white
+}
+
+// Drawable is anything that can draw a frame to a Surface.
+type Drawable interface {
+	DrawTo(dst Surface)
+}
+
+// DrawFunc adapts a function to Drawable.
+type DrawFunc func(dst Surface)
+
+// DrawTo implements Drawable.
+func (f DrawFunc) DrawTo(dst Surface) {
+	f(dst)
+}
+
+// ============================================================================
+// Ebiten
+// ============================================================================
+
+// EbitenSurface draws to an ebiten image with the vector package.
+type EbitenSurface struct {
+	Image     *ebiten.Image
+	Antialias bool
+}
+
+// NewEbitenSurface creates a surface for an ebiten image.
+func NewEbitenSurface(img *ebiten.Image) *EbitenSurface {
+	return &EbitenSurface{Image: img}
+}
+
+// Size implements Surface.
+func (s *EbitenSurface) Size() (int, int) {
+	b := s.Image.Bounds()
+
+	return b.Dx(), b.Dy()
+}
+
+// Fill implements Surface.
+func (s *EbitenSurface) Fill(c color.Color) {
+	s.Image.Fill(c)
+}
+
+// FillRect implements Surface.
+func (s *EbitenSurface) FillRect(x, y, width, height float32, c color.Color) {
+	vector.FillRect(s.Image, x, y, width, height, c, s.Antialias)
+}
+
+// StrokeRect implements Surface.
+func (s *EbitenSurface) StrokeRect(x, y, width, height, strokeWidth float32, c color.Color) {
+	vector.StrokeRect(s.Image, x, y, width, height, strokeWidth, c, s.Antialias)
+}
+
+// FillCircle implements Surface.
+func (s *EbitenSurface) FillCircle(cx, cy, r float32, c color.Color) {
+	vector.FillCircle(s.Image, cx, cy, r, c, s.Antialias)
+}
+
+// StrokeLine implements Surface.
+func (s *EbitenSurface) StrokeLine(x0, y0, x1, y1, strokeWidth float32, c color.Color) {
+	vector.StrokeLine(s.Image, x0, y0, x1, y1, strokeWidth, c, s.Antialias)
+}
+
+// Text implements Surface.
+func (s *EbitenSurface) Text(str string, x, y int) {
+	ebitenutil.DebugPrintAt(s.Image, str, x, y)
+}
+
+// ============================================================================
+// Rendering
+// ============================================================================
+
+// Backend selects how frames are rendered offscreen.
+type Backend string
+
+const (
+	BackendAuto   Backend = ""       // Ebiten when the game loop runs, else CPU
+	BackendEbiten Backend = "ebiten" // GPU via an offscreen ebiten.Image
+	BackendCPU    Backend = "cpu"    // Canvas software rasterizer
+)
+
+// Render draws d offscreen and returns the pixels and the backend used.
+// Ebiten can only read pixels back once its game loop runs, so
+// BackendAuto falls back to the CPU in tests and headless CI.
+func Render(d Drawable, width, height int, backend Backend) (*image.RGBA, Backend, error) {
+	if backend != BackendCPU {
+		img, err := renderEbiten(d, width, height)
+		if err == nil || backend == BackendEbiten {
+			return img, BackendEbiten, err
+		}
+	}
+
+	canvas := NewCanvas(width, height)
+	d.DrawTo(canvas)
+
+	return canvas.Image(), BackendCPU, nil
+}
+
+func renderEbiten(d Drawable, width, height int) (img *image.RGBA, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, fmt.Errorf("visual: ebiten offscreen render: %v", r)
+		}
+	}()
+
+	offscreen := ebiten.NewImage(width, height)
+	defer offscreen.Deallocate()
+
+	d.DrawTo(NewEbitenSurface(offscreen))
+
+	img = image.NewRGBA(image.Rect(0, 0, width, height))
+	offscreen.ReadPixels(img.Pix)
+
+	return img, nil
+}
+
+// Screenshot returns a function rendering d on the CPU, for tools that
+// take screenshots such as ai.AgentServer.
+func Screenshot(d Drawable, width, height int) func() (image.Image, error) {
+	return func() (image.Image, error) {
+		img, _, err := Render(d, width, height, BackendCPU)
+
+		return img, err
+	}
+}
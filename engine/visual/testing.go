@@ -0,0 +1,63 @@
+package visual
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+// CheckSession runs session once with d attached after the given ticks
+// (see Attach) and asserts every frame matched its golden.
+//
+// Games implement Drawable with the same DrawTo they use for the screen,
+// so a seeded session renders identical frames on the CPU canvas of any
+// machine. Run with UPDATE_GOLDENS=1 to accept intended changes.
+func (h *Harness) CheckSession(t testing.TB, session *ai.QASession, name string, d Drawable, ticks ...int64) {
+	t.Helper()
+
+	before := len(h.results)
+
+	h.Attach(session, name, d, ticks...)
+	session.Run()
+
+	if n := len(h.results) - before; n != len(ticks) {
+		t.Fatalf("captured %d frames, want %d", n, len(ticks))
+	}
+
+	h.Assert(t, name)
+}
+
+// Assert logs goldens written in update mode and reports every failed
+// check as a test error, with an HTML diff report in a temporary
+// directory named after prefix.
+func (h *Harness) Assert(t testing.TB, prefix string) {
+	t.Helper()
+
+	for _, r := range h.results {
+		if r.Status == StatusNew || r.Status == StatusUpdated {
+			t.Logf("wrote golden %s", h.GoldenPath(r.Name))
+		}
+	}
+
+	failed := h.Failed()
+	if len(failed) == 0 {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", prefix+"-visual-")
+	if err == nil {
+		err = h.WriteReport(dir)
+	}
+
+	t.Logf("diff report: %s (%v)", filepath.Join(dir, "index.html"), err)
+
+	for _, r := range failed {
+		if r.Error != "" {
+			t.Errorf("%s: %s: %s", r.Name, r.Status, r.Error)
+		} else {
+			t.Errorf("%s: %s, %d pixels differ", r.Name, r.Status, r.Diff.DiffPixels)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package visual
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+var (
+	black = color.RGBA{A: 255}
+	red   = color.RGBA{R: 255, A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+func TestCanvasPrimitives(t *testing.T) {
+	c := NewCanvas(40, 40)
+	c.Fill(black)
+	c.FillRect(2, 2, 4, 3, red)
+	c.FillCircle(20, 20, 5, white)
+	c.StrokeRect(30, 30, 6, 6, 2, red)
+	c.StrokeLine(0, 39.5, 10, 39.5, 1, white)
+	c.FillRect(10, 2, 4, 4, color.RGBA{A: 128}) // Half-transparent black over black
+
+	img := c.Image()
+
+	cases := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{2, 2, red}, {5, 4, red}, {6, 4, black}, {5, 5, black},
+		{20, 20, white}, {24, 20, white}, {25, 25, black},
+		{29, 29, red}, {33, 33, black}, {36, 36, red},
+		{5, 39, white}, {5, 38, black},
+		{11, 3, black},
+	}
+
+	for _, tc := range cases {
+		if got := img.RGBAAt(tc.x, tc.y); got != tc.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+
+	w, h := c.Size()
+	if w != 40 || h != 40 {
+		t.Errorf("Size = %d x %d", w, h)
+	}
+}
+
+func TestCanvasText(t *testing.T) {
+	c := NewCanvas(20, 20)
+	c.Text("1", 0, 0)
+
+	// "1" has its top-middle pixel set and top-left clear
+	if c.Image().RGBAAt(2, 3) != white || c.Image().RGBAAt(0, 3) != (color.RGBA{}) {
+		t.Error("Glyph should be drawn at 2x scale below a 3px margin")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a, b := NewCanvas(10, 10), NewCanvas(10, 10)
+	a.Fill(black)
+	b.Fill(black)
+	b.FillRect(2, 2, 2, 2, red)
+	b.FillRect(8, 8, 1, 1, color.RGBA{R: 3, G: 3, B: 3, A: 255}) // Below threshold
+
+	d := Compare(a.Image(), b.Image(), DefaultDiffOptions())
+	if d.Pass || d.DiffPixels != 4 || d.Bounds != image.Rect(2, 2, 4, 4) || d.Compared != 100 {
+		t.Errorf("Compare = %+v", d)
+	}
+
+	if d.Image.RGBAAt(2, 2) != red {
+		t.Error("Diff image should mark differing pixels red")
+	}
+
+	opts := DefaultDiffOptions()
+	opts.Masks = []image.Rectangle{image.Rect(0, 0, 4, 4)}
+
+	if d := Compare(a.Image(), b.Image(), opts); !d.Pass || d.Compared != 84 {
+		t.Errorf("Masked compare = %+v", d)
+	}
+
+	opts = DefaultDiffOptions()
+	opts.MaxDiffRatio = 0.05
+
+	if d := Compare(a.Image(), b.Image(), opts); !d.Pass {
+		t.Error("4% difference should pass a 5% ratio")
+	}
+
+	if d := Compare(a.Image(), NewCanvas(5, 5).Image(), opts); d.Pass || !d.SizeMismatch {
+		t.Error("Different sizes should fail")
+	}
+}
+
+func TestHarness(t *testing.T) {
+	dir := t.TempDir()
+	x := float32(10)
+	scene := DrawFunc(func(dst Surface) {
+		dst.Fill(black)
+		dst.FillRect(x, 10, 8, 8, red)
+		dst.Text("SCORE", 0, 30)
+	})
+
+	h := NewHarness(filepath.Join(dir, "golden"), 64, 48)
+	h.Update = false
+
+	// A missing golden fails unless goldens are being updated.
+	if r := h.Check("frame", scene); r.Status != StatusMissing || !strings.Contains(r.Error, "UPDATE_GOLDENS") {
+		t.Fatalf("check without golden = %s (%s)", r.Status, r.Error)
+	}
+
+	if _, err := os.Stat(h.GoldenPath("frame")); err == nil {
+		t.Fatal("golden written outside update mode")
+	}
+
+	h.Update = true
+	if r := h.Check("frame", scene); r.Status != StatusNew {
+		t.Fatalf("first check in update mode = %s (%s)", r.Status, r.Error)
+	}
+
+	h.Update = false
+
+	if r := h.Check("frame", scene); r.Status != StatusPass {
+		t.Errorf("unchanged frame = %s", r.Status)
+	}
+
+	x = 30
+	if r := h.Check("frame", scene); r.Status != StatusFail || r.Diff.DiffPixels != 128 {
+		t.Errorf("moved frame = %s with %d pixels", r.Status, r.Diff.DiffPixels)
+	}
+
+	h.Mask("frame", image.Rect(0, 0, 64, 20))
+
+	if r := h.Check("frame", scene); r.Status != StatusPass {
+		t.Errorf("masked frame = %s", r.Status)
+	}
+
+	if len(h.Failed()) != 2 || len(h.Results()) != 5 {
+		t.Errorf("Failed = %d, Results = %d", len(h.Failed()), len(h.Results()))
+	}
+
+	report := filepath.Join(dir, "report")
+	if err := h.WriteReport(report); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := os.ReadFile(filepath.Join(report, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(html), "frame — fail") || !strings.Contains(string(html), "frame.diff.png") {
+		t.Errorf("report missing failure:\n%s", html)
+	}
+
+	if _, err := os.Stat(filepath.Join(report, "frame.actual.png")); err != nil {
+		t.Error(err)
+	}
+}
+
+// boxAdapter moves a box right one pixel per action.
+type boxAdapter struct {
+	x    int
+	tick int64
+}
+
+func (b *boxAdapter) Name() string                      { return "Box" }
+func (b *boxAdapter) GetScore() int                     { return b.x }
+func (b *boxAdapter) IsGameOver() bool                  { return false }
+func (b *boxAdapter) AvailableActions() []ai.ActionType { return []ai.ActionType{ai.ActionMoveRight} }
+func (b *boxAdapter) PerformAction(ai.ActionType) error { b.x++; return nil }
+func (b *boxAdapter) Step() error                       { b.tick++; return nil }
+func (b *boxAdapter) Reset() error                      { b.x, b.tick = 0, 0; return nil }
+func (b *boxAdapter) DrawTo(dst Surface)                { dst.Fill(black); dst.FillRect(float32(b.x), 0, 4, 4, red) }
+func (b *boxAdapter) GetState() ai.GameState            { return ai.GameState{Tick: b.tick, Score: b.x} }
+
+func TestAttach(t *testing.T) {
+	game := &boxAdapter{}
+	h := NewHarness(t.TempDir(), 16, 8)
+
+	session := ai.NewQASession(game)
+	session.SetPlayer(ai.NewReplayPlayer(nil))
+	session.SetConfig(ai.SessionConfig{Runs: 2, MaxTicks: 10, RecordEvery: 1})
+	h.Attach(session, "box", game, 2, 5)
+	session.Run()
+
+	results := h.Results()
+	if len(results) != 2 || results[0].Name != "box_2" || results[1].Name != "box_5" {
+		t.Fatalf("results = %+v", results)
+	}
+
+	if got := results[1].Actual.RGBAAt(6, 0); got != red {
+		t.Errorf("box after 6 moves should cover x=6, got %v", got)
+	}
+}
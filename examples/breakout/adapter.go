@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+// paddleSpeed is how far the paddle moves per frame under agent control.
+const paddleSpeed = 8
+
+// BreakoutAdapter implements ai.GameAdapter. Each Step is one frame; a
+// ball resting on the paddle is launched automatically.
+type BreakoutAdapter struct {
+	game *Breakout
+	tick int64
+	move float64
+}
+
+// NewBreakoutAdapter creates an adapter for the given game.
+func NewBreakoutAdapter(game *Breakout) *BreakoutAdapter {
+	return &BreakoutAdapter{game: game}
+}
+
+// Name returns the game's identifier.
+func (a *BreakoutAdapter) Name() string {
+	return "Breakout"
+}
+
+// Seed makes launch angles reproducible.
+func (a *BreakoutAdapter) Seed(seed int64) {
+	a.game.rng = rand.New(rand.NewSource(seed))
+}
+
+// GetState returns the current game state. Health is lives; CustomData
+// holds the features the learners use: the ball's offset from the paddle
+// center, its height and its velocity.
+func (a *BreakoutAdapter) GetState() ai.GameState {
+	b := a.game
+
+	bricks := 0
+
+	for _, brick := range b.bricks {
+		if brick.Alive {
+			bricks++
+		}
+	}
+
+	return ai.GameState{
+		Tick:         a.tick,
+		Score:        b.score,
+		PlayerPos:    [2]float64{b.paddle.X, b.paddle.Y},
+		PlayerHealth: [2]int{b.lives, 3},
+		EntityCount:  bricks + 2,
+		CustomData: map[string]any{
+			"ball_dx": b.ball.X + b.ball.Size/2 - (b.paddle.X + b.paddle.Width/2),
+			"ball_y":  b.ball.Y,
+			"ball_vx": b.ball.VX,
+			"ball_vy": b.ball.VY,
+			"bricks":  bricks,
+		},
+	}
+}
+
+// IsGameOver returns true when all lives are lost or all bricks cleared.
+func (a *BreakoutAdapter) IsGameOver() bool {
+	return a.game.state == StateGameOver || a.game.state == StateVictory
+}
+
+// GetScore returns the current score.
+func (a *BreakoutAdapter) GetScore() int {
+	return a.game.score
+}
+
+// AvailableActions returns paddle moves.
+func (a *BreakoutAdapter) AvailableActions() []ai.ActionType {
+	if a.game.state != StatePlaying {
+		return []ai.ActionType{ai.ActionNone}
+	}
+
+	return []ai.ActionType{ai.ActionNone, ai.ActionMoveLeft, ai.ActionMoveRight}
+}
+
+// PerformAction sets the paddle movement for the next frame.
+func (a *BreakoutAdapter) PerformAction(action ai.ActionType) error {
+	switch action {
+	case ai.ActionMoveLeft:
+		a.move = -paddleSpeed
+	case ai.ActionMoveRight:
+		a.move = paddleSpeed
+	default:
+		a.move = 0
+	}
+
+	return nil
+}
+
+// Step advances one frame.
+func (a *BreakoutAdapter) Step() error {
+	a.tick++
+
+	if a.game.state == StatePlaying {
+		a.game.updatePlaying(a.game.paddle.X+a.move, true)
+	}
+
+	a.move = 0
+
+	return nil
+}
+
+// Reset starts a new game.
+func (a *BreakoutAdapter) Reset() error {
+	a.tick = 0
+	a.move = 0
+	a.game.startGame()
+
+	return nil
+}
+
+// BreakoutDiscretizer keys states by where the ball is relative to the
+// paddle and where it is heading, for tabular learners.
+func BreakoutDiscretizer() ai.Discretizer {
+	return ai.NewBinDiscretizer(
+		[]string{"ball_dx", "ball_y", "ball_vx", "ball_vy"},
+		[]ai.Bin{
+			{Low: -200, High: 200, Count: 20},
+			{Low: 0, High: screenHeight, Count: 6},
+			{Low: -6, High: 6, Count: 4},
+			{Low: -1, High: 1, Count: 2},
+		},
+	)
+}
@@ -6,11 +6,12 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
 )
 
 const (
@@ -90,6 +91,7 @@ type Breakout struct {
 	titlePulse float64
 	hitFlash   float64
 	trails     []struct{ X, Y, A float64 }
+	rng        *rand.Rand
 }
 
 func NewBreakout() *Breakout {
@@ -104,6 +106,7 @@ func NewBreakout() *Breakout {
 		lives: 3,
 		level: 1,
 		state: StateTitle,
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 
 	return b
@@ -130,7 +133,7 @@ func (b *Breakout) resetBall() {
 
 func (b *Breakout) launchBall() {
 	if !b.launched {
-		angle := (rand.Float64()*60 - 30) * math.Pi / 180
+		angle := (b.rng.Float64()*60 - 30) * math.Pi / 180
 		speed := 5.0 + float64(b.level)*0.3
 		b.ball.VX = math.Sin(angle) * speed
 		b.ball.VY = -math.Cos(angle) * speed
@@ -166,12 +169,12 @@ func (b *Breakout) createBricks() {
 
 func (b *Breakout) spawnBrickParticles(brick *Brick) {
 	for range 15 {
-		angle := rand.Float64() * math.Pi * 2
-		speed := 2 + rand.Float64()*3
+		angle := b.rng.Float64() * math.Pi * 2
+		speed := 2 + b.rng.Float64()*3
 		b.particles = append(b.particles, Particle{
 			X: brick.X + brick.Width/2, Y: brick.Y + brick.Height/2,
 			VX: math.Cos(angle) * speed, VY: math.Sin(angle) * speed,
-			Life: 1.0, Color: brick.Color, Size: 3 + rand.Float64()*3,
+			Life: 1.0, Color: brick.Color, Size: 3 + b.rng.Float64()*3,
 		})
 	}
 }
@@ -233,167 +236,179 @@ func (b *Breakout) Update() error {
 
 	case StatePlaying:
 		mx, _ := ebiten.CursorPosition()
-		b.paddle.X = clamp(float64(mx)-b.paddle.Width/2, 0, float64(screenWidth)-b.paddle.Width)
-
-		if !b.launched {
-			b.ball.X = b.paddle.X + b.paddle.Width/2 - b.ball.Size/2
-
-			b.ball.Y = b.paddle.Y - b.ball.Size - 2
-			if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) ||
-				inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-				b.launchBall()
-			}
+		launch := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) ||
+			inpututil.IsKeyJustPressed(ebiten.KeySpace)
+		b.updatePlaying(float64(mx)-b.paddle.Width/2, launch)
 
-			return nil
+	case StateGameOver, StateVictory:
+		if inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			b.startGame()
 		}
 
-		// Ball trail
-		b.trails = append(
-			b.trails,
-			struct{ X, Y, A float64 }{b.ball.X + b.ball.Size/2, b.ball.Y + b.ball.Size/2, 0.7},
-		)
-		if len(b.trails) > 15 {
-			b.trails = b.trails[1:]
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			b.state = StateTitle
 		}
+	}
+
+	return nil
+}
 
-		b.ball.X += b.ball.VX
-		b.ball.Y += b.ball.VY
+// updatePlaying advances one frame of play with the paddle's left edge
+// moved to paddleX, launching a held ball if launch is set.
+func (b *Breakout) updatePlaying(paddleX float64, launch bool) {
+	b.paddle.X = clamp(paddleX, 0, float64(screenWidth)-b.paddle.Width)
 
-		// Walls
-		if b.ball.X <= 0 || b.ball.X+b.ball.Size >= float64(screenWidth) {
-			b.ball.VX = -b.ball.VX
-			b.ball.X = clamp(b.ball.X, 0, float64(screenWidth)-b.ball.Size)
-		}
+	if !b.launched {
+		b.ball.X = b.paddle.X + b.paddle.Width/2 - b.ball.Size/2
 
-		if b.ball.Y <= 0 {
-			b.ball.VY = -b.ball.VY
-			b.ball.Y = 0
+		b.ball.Y = b.paddle.Y - b.ball.Size - 2
+		if launch {
+			b.launchBall()
 		}
 
-		// Fall
-		if b.ball.Y > float64(screenHeight) {
-			b.lives--
+		return
+	}
 
-			b.combo = 0
-			if b.lives <= 0 {
-				if b.score > b.highscore {
-					b.highscore = b.score
-				}
+	// Ball trail
+	b.trails = append(
+		b.trails,
+		struct{ X, Y, A float64 }{b.ball.X + b.ball.Size/2, b.ball.Y + b.ball.Size/2, 0.7},
+	)
+	if len(b.trails) > 15 {
+		b.trails = b.trails[1:]
+	}
 
-				b.state = StateGameOver
-			} else {
-				b.resetBall()
-			}
+	b.ball.X += b.ball.VX
+	b.ball.Y += b.ball.VY
 
-			return nil
-		}
+	// Walls
+	if b.ball.X <= 0 || b.ball.X+b.ball.Size >= float64(screenWidth) {
+		b.ball.VX = -b.ball.VX
+		b.ball.X = clamp(b.ball.X, 0, float64(screenWidth)-b.ball.Size)
+	}
 
-		// Paddle
-		if b.ball.Y+b.ball.Size >= b.paddle.Y && b.ball.Y <= b.paddle.Y+b.paddle.Height &&
-			b.ball.X+b.ball.Size >= b.paddle.X && b.ball.X <= b.paddle.X+b.paddle.Width && b.ball.VY > 0 {
-			hitPos := (b.ball.X + b.ball.Size/2 - b.paddle.X) / b.paddle.Width
-			angle := (hitPos - 0.5) * math.Pi * 0.6
-			speed := math.Sqrt(b.ball.VX*b.ball.VX + b.ball.VY*b.ball.VY)
-			b.ball.VX = math.Sin(angle) * speed
-			b.ball.VY = -math.Abs(math.Cos(angle) * speed)
-			b.ball.Y = b.paddle.Y - b.ball.Size
-			b.hitFlash = 1.0
-		}
+	if b.ball.Y <= 0 {
+		b.ball.VY = -b.ball.VY
+		b.ball.Y = 0
+	}
 
-		// Bricks
-		for _, brick := range b.bricks {
-			if !brick.Alive {
-				continue
-			}
+	// Fall
+	if b.ball.Y > float64(screenHeight) {
+		b.lives--
 
-			if b.ball.X+b.ball.Size >= brick.X && b.ball.X <= brick.X+brick.Width &&
-				b.ball.Y+b.ball.Size >= brick.Y && b.ball.Y <= brick.Y+brick.Height {
-				brick.Alive = false
-				b.combo++
-				b.comboTimer = 2.0
-				points := brick.Points * b.combo
-				b.score += points
-				b.spawnBrickParticles(brick)
-
-				popText := fmt.Sprintf("+%d", points)
-				if b.combo > 1 {
-					popText = fmt.Sprintf("+%d x%d", points, b.combo)
-				}
-
-				b.addPopup(brick.X+brick.Width/2, brick.Y, popText, brick.Color)
-
-				overlapL := b.ball.X + b.ball.Size - brick.X
-				overlapR := brick.X + brick.Width - b.ball.X
-				overlapT := b.ball.Y + b.ball.Size - brick.Y
-
-				overlapB := brick.Y + brick.Height - b.ball.Y
-				if math.Min(overlapL, overlapR) < math.Min(overlapT, overlapB) {
-					b.ball.VX = -b.ball.VX
-				} else {
-					b.ball.VY = -b.ball.VY
-				}
-
-				break
+		b.combo = 0
+		if b.lives <= 0 {
+			if b.score > b.highscore {
+				b.highscore = b.score
 			}
+
+			b.state = StateGameOver
+		} else {
+			b.resetBall()
 		}
 
-		// Victory check
-		allDead := true
+		return
+	}
 
-		for _, brick := range b.bricks {
-			if brick.Alive {
-				allDead = false
+	// Paddle
+	if b.ball.Y+b.ball.Size >= b.paddle.Y && b.ball.Y <= b.paddle.Y+b.paddle.Height &&
+		b.ball.X+b.ball.Size >= b.paddle.X && b.ball.X <= b.paddle.X+b.paddle.Width && b.ball.VY > 0 {
+		hitPos := (b.ball.X + b.ball.Size/2 - b.paddle.X) / b.paddle.Width
+		angle := (hitPos - 0.5) * math.Pi * 0.6
+		speed := math.Sqrt(b.ball.VX*b.ball.VX + b.ball.VY*b.ball.VY)
+		b.ball.VX = math.Sin(angle) * speed
+		b.ball.VY = -math.Abs(math.Cos(angle) * speed)
+		b.ball.Y = b.paddle.Y - b.ball.Size
+		b.hitFlash = 1.0
+	}
 
-				break
-			}
+	// Bricks
+	for _, brick := range b.bricks {
+		if !brick.Alive {
+			continue
 		}
 
-		if allDead {
-			if b.score > b.highscore {
-				b.highscore = b.score
+		if b.ball.X+b.ball.Size >= brick.X && b.ball.X <= brick.X+brick.Width &&
+			b.ball.Y+b.ball.Size >= brick.Y && b.ball.Y <= brick.Y+brick.Height {
+			brick.Alive = false
+			b.combo++
+			b.comboTimer = 2.0
+			points := brick.Points * b.combo
+			b.score += points
+			b.spawnBrickParticles(brick)
+
+			popText := fmt.Sprintf("+%d", points)
+			if b.combo > 1 {
+				popText = fmt.Sprintf("+%d x%d", points, b.combo)
 			}
 
-			b.state = StateVictory
-		}
+			b.addPopup(brick.X+brick.Width/2, brick.Y, popText, brick.Color)
 
-	case StateGameOver, StateVictory:
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-			b.startGame()
+			overlapL := b.ball.X + b.ball.Size - brick.X
+			overlapR := brick.X + brick.Width - b.ball.X
+			overlapT := b.ball.Y + b.ball.Size - brick.Y
+
+			overlapB := brick.Y + brick.Height - b.ball.Y
+			if math.Min(overlapL, overlapR) < math.Min(overlapT, overlapB) {
+				b.ball.VX = -b.ball.VX
+			} else {
+				b.ball.VY = -b.ball.VY
+			}
+
+			break
 		}
+	}
 
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-			b.state = StateTitle
+	// Victory check
+	allDead := true
+
+	for _, brick := range b.bricks {
+		if brick.Alive {
+			allDead = false
+
+			break
 		}
 	}
 
-	return nil
+	if allDead {
+		if b.score > b.highscore {
+			b.highscore = b.score
+		}
+
+		b.state = StateVictory
+	}
 }
 
 func (b *Breakout) Draw(screen *ebiten.Image) {
-	screen.Fill(color.RGBA{R: 10, G: 8, B: 22, A: 255})
+	b.DrawTo(visual.NewEbitenSurface(screen))
+}
+
+// DrawTo draws the screen for the current state onto dst.
+func (b *Breakout) DrawTo(dst visual.Surface) {
+	dst.Fill(color.RGBA{R: 10, G: 8, B: 22, A: 255})
 
 	// Particles
 	for _, p := range b.particles {
 		alpha := uint8(p.Life * 255)
 		c := color.RGBA{R: p.Color.R, G: p.Color.G, B: p.Color.B, A: alpha}
-		vector.FillCircle(screen, float32(p.X), float32(p.Y), float32(p.Size*p.Life), c, false)
+		dst.FillCircle(float32(p.X), float32(p.Y), float32(p.Size*p.Life), c)
 	}
 
 	switch b.state {
 	case StateTitle:
-		b.drawTitle(screen)
+		b.drawTitle(dst)
 	case StatePlaying:
-		b.drawGame(screen)
+		b.drawGame(dst)
 	case StateGameOver:
-		b.drawGame(screen)
-		b.drawOverlay(screen, "GAME OVER", color.RGBA{R: 255, G: 80, B: 80, A: 255})
+		b.drawGame(dst)
+		b.drawOverlay(dst, "GAME OVER", color.RGBA{R: 255, G: 80, B: 80, A: 255})
 	case StateVictory:
-		b.drawGame(screen)
-		b.drawOverlay(screen, "VICTORY!", color.RGBA{R: 80, G: 255, B: 100, A: 255})
+		b.drawGame(dst)
+		b.drawOverlay(dst, "VICTORY!", color.RGBA{R: 80, G: 255, B: 100, A: 255})
 	}
 }
 
-func (b *Breakout) drawTitle(screen *ebiten.Image) {
+func (b *Breakout) drawTitle(dst visual.Surface) {
 	// Demo bricks
 	for col := range 8 {
 		x := float32(160 + col*40)
@@ -408,30 +423,27 @@ func (b *Breakout) drawTitle(screen *ebiten.Image) {
 			{50, 255, 255, 255},
 			{255, 255, 255, 255},
 		}
-		vector.FillRect(screen, x, y, 35, 18, colors[col], false)
+		dst.FillRect(x, y, 35, 18, colors[col])
 	}
 
 	boxW, boxH := float32(380), float32(220)
 	boxX, boxY := float32(screenWidth-380)/2, float32(screenHeight-220)/2
 
 	pulse := float32(0.7 + 0.3*math.Sin(b.titlePulse*2))
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		boxX-4,
 		boxY-4,
 		boxW+8,
 		boxH+8,
 		color.RGBA{R: 100, G: 200, B: 255, A: uint8(40 * pulse)},
-		false,
 	)
-	vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 15, G: 20, B: 35, A: 240}, false)
-	vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 3, color.RGBA{R: 100, G: 200, B: 255, A: 255}, false)
+	dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 15, G: 20, B: 35, A: 240})
+	dst.StrokeRect(boxX, boxY, boxW, boxH, 3, color.RGBA{R: 100, G: 200, B: 255, A: 255})
 
-	ebitenutil.DebugPrintAt(screen, "B R E A K O U T", int(boxX)+110, int(boxY)+30)
+	dst.Text("B R E A K O U T", int(boxX)+110, int(boxY)+30)
 
 	if b.highscore > 0 {
-		ebitenutil.DebugPrintAt(
-			screen,
+		dst.Text(
 			fmt.Sprintf("High Score: %d", b.highscore),
 			int(boxX)+120,
 			int(boxY)+65,
@@ -439,14 +451,14 @@ func (b *Breakout) drawTitle(screen *ebiten.Image) {
 	}
 
 	if int(b.titlePulse*2)%2 == 0 {
-		ebitenutil.DebugPrintAt(screen, "Click or SPACE to Start", int(boxX)+95, int(boxY)+105)
+		dst.Text("Click or SPACE to Start", int(boxX)+95, int(boxY)+105)
 	}
 
-	ebitenutil.DebugPrintAt(screen, "Move: Mouse | Launch: Click/Space", int(boxX)+60, int(boxY)+150)
-	ebitenutil.DebugPrintAt(screen, "Break all bricks! Chain hits for combos!", int(boxX)+40, int(boxY)+180)
+	dst.Text("Move: Mouse | Launch: Click/Space", int(boxX)+60, int(boxY)+150)
+	dst.Text("Break all bricks! Chain hits for combos!", int(boxX)+40, int(boxY)+180)
 }
 
-func (b *Breakout) drawGame(screen *ebiten.Image) {
+func (b *Breakout) drawGame(dst visual.Surface) {
 	// Bricks
 	for _, brick := range b.bricks {
 		if !brick.Alive {
@@ -454,36 +466,30 @@ func (b *Breakout) drawGame(screen *ebiten.Image) {
 		}
 
 		glow := color.RGBA{R: brick.Color.R, G: brick.Color.G, B: brick.Color.B, A: 40}
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			float32(brick.X)-1,
 			float32(brick.Y)-1,
 			float32(brick.Width)+2,
 			float32(brick.Height)+2,
 			glow,
-			false,
 		)
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			float32(brick.X),
 			float32(brick.Y),
 			float32(brick.Width),
 			float32(brick.Height),
 			brick.Color,
-			false,
 		)
 	}
 
 	// Ball trail
 	for _, t := range b.trails {
 		alpha := uint8(t.A * 100)
-		vector.FillCircle(
-			screen,
+		dst.FillCircle(
 			float32(t.X),
 			float32(t.Y),
 			float32(ballSize/2),
 			color.RGBA{R: 255, G: 255, B: 255, A: alpha},
-			false,
 		)
 	}
 
@@ -493,112 +499,98 @@ func (b *Breakout) drawGame(screen *ebiten.Image) {
 		paddleGlow = uint8(60 + b.hitFlash*100)
 	}
 
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(b.paddle.X)-2,
 		float32(b.paddle.Y)-2,
 		float32(b.paddle.Width)+4,
 		float32(b.paddle.Height)+4,
 		color.RGBA{R: 100, G: 200, B: 255, A: paddleGlow},
-		false,
 	)
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(b.paddle.X),
 		float32(b.paddle.Y),
 		float32(b.paddle.Width),
 		float32(b.paddle.Height),
 		color.RGBA{R: 100, G: 200, B: 255, A: 255},
-		false,
 	)
 
 	// Ball
 	ballX, ballY := float32(b.ball.X+b.ball.Size/2), float32(b.ball.Y+b.ball.Size/2)
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		ballX,
 		ballY,
 		float32(b.ball.Size/2)+3,
 		color.RGBA{R: 255, G: 255, B: 255, A: 60},
-		false,
 	)
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		ballX,
 		ballY,
 		float32(b.ball.Size/2),
 		color.RGBA{R: 255, G: 255, B: 255, A: 255},
-		false,
 	)
 
 	// UI
-	vector.FillRect(screen, 0, 0, screenWidth, 40, color.RGBA{R: 0, G: 0, B: 0, A: 180}, false)
-	ebitenutil.DebugPrintAt(screen, "LIVES:", 10, 12)
+	dst.FillRect(0, 0, screenWidth, 40, color.RGBA{R: 0, G: 0, B: 0, A: 180})
+	dst.Text("LIVES:", 10, 12)
 
 	for i := 0; i < b.lives; i++ {
-		vector.FillCircle(
-			screen,
+		dst.FillCircle(
 			float32(70+i*20),
 			20,
 			7,
 			color.RGBA{R: 255, G: 60, B: 100, A: 255},
-			false,
 		)
 	}
 
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("SCORE: %d", b.score), 200, 12)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("HIGH: %d", b.highscore), 350, 12)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("LVL %d", b.level), screenWidth-60, 12)
+	dst.Text(fmt.Sprintf("SCORE: %d", b.score), 200, 12)
+	dst.Text(fmt.Sprintf("HIGH: %d", b.highscore), 350, 12)
+	dst.Text(fmt.Sprintf("LVL %d", b.level), screenWidth-60, 12)
 
 	// Combo
 	if b.combo > 1 && b.comboTimer > 0 {
 		comboAlpha := uint8(min(int(b.comboTimer*127+128), 255))
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			screenWidth/2-50,
 			45,
 			100,
 			25,
 			color.RGBA{R: 255, G: 200, B: 50, A: comboAlpha / 2},
-			false,
 		)
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("COMBO x%d!", b.combo), screenWidth/2-35, 50)
+		dst.Text(fmt.Sprintf("COMBO x%d!", b.combo), screenWidth/2-35, 50)
 	}
 
 	// Popups
 	for _, pop := range b.popups {
-		ebitenutil.DebugPrintAt(screen, pop.Text, int(pop.X)-15, int(pop.Y))
+		dst.Text(pop.Text, int(pop.X)-15, int(pop.Y))
 	}
 
 	if !b.launched {
-		ebitenutil.DebugPrintAt(screen, "Click or SPACE to launch", screenWidth/2-80, screenHeight/2+60)
+		dst.Text("Click or SPACE to launch", screenWidth/2-80, screenHeight/2+60)
 	}
 }
 
-func (b *Breakout) drawOverlay(screen *ebiten.Image, title string, titleColor color.RGBA) {
-	vector.FillRect(
-		screen,
+func (b *Breakout) drawOverlay(dst visual.Surface, title string, titleColor color.RGBA) {
+	dst.FillRect(
 		0,
 		0,
 		screenWidth,
 		screenHeight,
 		color.RGBA{R: 0, G: 0, B: 0, A: 180},
-		false,
 	)
 
 	boxW, boxH := float32(280), float32(150)
 	boxX, boxY := float32(screenWidth-280)/2, float32(screenHeight-150)/2
-	vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 20, G: 25, B: 40, A: 255}, false)
-	vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 3, titleColor, false)
+	dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 20, G: 25, B: 40, A: 255})
+	dst.StrokeRect(boxX, boxY, boxW, boxH, 3, titleColor)
 
-	ebitenutil.DebugPrintAt(screen, title, int(boxX)+95, int(boxY)+25)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Final Score: %d", b.score), int(boxX)+85, int(boxY)+55)
+	dst.Text(title, int(boxX)+95, int(boxY)+25)
+	dst.Text(fmt.Sprintf("Final Score: %d", b.score), int(boxX)+85, int(boxY)+55)
 
 	if b.score >= b.highscore && b.score > 0 {
-		ebitenutil.DebugPrintAt(screen, "NEW HIGH SCORE!", int(boxX)+85, int(boxY)+80)
+		dst.Text("NEW HIGH SCORE!", int(boxX)+85, int(boxY)+80)
 	}
 
-	ebitenutil.DebugPrintAt(screen, "SPACE: Retry  ESC: Menu", int(boxX)+55, int(boxY)+115)
+	dst.Text("SPACE: Retry  ESC: Menu", int(boxX)+55, int(boxY)+115)
 }
 
 func (b *Breakout) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
+)
+
+// TestTrainedAgent trains a Q-learner to keep the ball in play and checks
+// it clears more bricks than random play.
+func TestTrainedAgent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping training in short mode")
+	}
+
+	env := ai.NewEnv(NewBreakoutAdapter(NewBreakout()), ai.EnvConfig{
+		MaxSteps: 3000,
+		Reward:   bricksCleared,
+		Shaping:  []ai.RewardFunc{ai.HealthPenalty(10)},
+	})
+
+	learner := ai.NewQLearner(BreakoutDiscretizer(), ai.DefaultQConfig())
+
+	stats, err := learner.Train(env, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("Training: last 20 episodes avg score %.0f", stats.MeanScore(20))
+
+	run := func(player ai.Player) ai.QAReport {
+		adapter := NewBreakoutAdapter(NewBreakout())
+		adapter.Seed(1)
+
+		session := ai.NewQASession(adapter)
+		session.SetPlayer(player)
+		session.SetConfig(ai.SessionConfig{Runs: 5, MaxTicks: 3000, RecordEvery: 10})
+
+		return session.Run()
+	}
+
+	trained := run(learner)
+	random := run(ai.NewRandomPlayer(1))
+
+	t.Logf("Trained avg %d, best %d; random avg %d", trained.AvgScore, trained.BestScore, random.AvgScore)
+
+	if trained.AvgScore < 2*random.AvgScore {
+		t.Errorf("Trained agent should beat random play, got %d vs %d", trained.AvgScore, random.AvgScore)
+	}
+}
+
+// bricksCleared rewards each brick destroyed, ignoring combo multipliers.
+func bricksCleared(prev, next ai.GameState, _ ai.ActionType, _ bool) float64 {
+	before, _ := prev.CustomData["bricks"].(int)
+	after, _ := next.CustomData["bricks"].(int)
+
+	return float64(before - after)
+}
+
+// TestVisualRegression checks three frames of a seeded random rally.
+func TestVisualRegression(t *testing.T) {
+	game := NewBreakout()
+	adapter := NewBreakoutAdapter(game)
+	adapter.Seed(1)
+
+	h := visual.NewHarness("testdata/golden", screenWidth, screenHeight)
+
+	session := ai.NewQASession(adapter)
+	session.SetPlayer(ai.NewRandomPlayer(1))
+	session.SetConfig(ai.SessionConfig{Runs: 1, MaxTicks: 100, RecordEvery: 1})
+	h.CheckSession(t, session, "breakout", game, 0, 45, 90)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+// FlappyAdapter implements ai.GameAdapter. Each Step is one frame.
+type FlappyAdapter struct {
+	game *Game
+	tick int64
+	flap bool
+}
+
+// NewFlappyAdapter creates an adapter for the given game.
+func NewFlappyAdapter(game *Game) *FlappyAdapter {
+	return &FlappyAdapter{game: game}
+}
+
+// Name returns the game's identifier.
+func (a *FlappyAdapter) Name() string {
+	return "Flappy"
+}
+
+// Seed makes pipe gaps reproducible.
+func (a *FlappyAdapter) Seed(seed int64) {
+	a.game.rng = rand.New(rand.NewSource(seed))
+}
+
+// GetState returns the current game state. CustomData holds the features
+// the learners use: the bird's offset from the next gap, its vertical
+// velocity and the horizontal distance to the end of the next pipe.
+func (a *FlappyAdapter) GetState() ai.GameState {
+	g := a.game
+	bird := g.bird
+
+	gapDY, pipeDX := 0.0, float64(screenWidth)
+
+	for _, pipe := range g.pipes {
+		if pipe.X+pipeWidth > bird.X {
+			gapDY = bird.Y + birdSize/2 - pipe.GapY
+			pipeDX = pipe.X + pipeWidth - bird.X
+
+			break
+		}
+	}
+
+	return ai.GameState{
+		Tick:        a.tick,
+		Score:       g.score,
+		PlayerPos:   [2]float64{bird.X, bird.Y},
+		EntityCount: len(g.pipes) + 1,
+		CustomData: map[string]any{
+			"gap_dy":   gapDY,
+			"velocity": bird.VelocityY,
+			"pipe_dx":  pipeDX,
+		},
+	}
+}
+
+// IsGameOver returns true if the bird crashed.
+func (a *FlappyAdapter) IsGameOver() bool {
+	return a.game.state == StateGameOver
+}
+
+// GetScore returns the number of pipes passed.
+func (a *FlappyAdapter) GetScore() int {
+	return a.game.score
+}
+
+// AvailableActions returns glide (none) and flap (jump).
+func (a *FlappyAdapter) AvailableActions() []ai.ActionType {
+	if a.game.state != StatePlaying {
+		return []ai.ActionType{ai.ActionNone}
+	}
+
+	return []ai.ActionType{ai.ActionNone, ai.ActionJump}
+}
+
+// PerformAction sets whether the bird flaps on the next frame.
+func (a *FlappyAdapter) PerformAction(action ai.ActionType) error {
+	a.flap = action == ai.ActionJump
+
+	return nil
+}
+
+// Step advances one frame.
+func (a *FlappyAdapter) Step() error {
+	a.tick++
+
+	if a.game.state == StatePlaying {
+		a.game.updatePlaying(a.flap)
+	}
+
+	a.flap = false
+
+	return nil
+}
+
+// Reset starts a new game.
+func (a *FlappyAdapter) Reset() error {
+	a.tick = 0
+	a.flap = false
+	a.game.startGame()
+
+	return nil
+}
+
+// FlappyDiscretizer keys states by gap offset, velocity and pipe distance,
+// for tabular learners.
+func FlappyDiscretizer() ai.Discretizer {
+	return ai.NewBinDiscretizer(
+		[]string{"gap_dy", "velocity", "pipe_dx"},
+		[]ai.Bin{
+			{Low: -300, High: 300, Count: 40},
+			{Low: -10, High: 10, Count: 10},
+			{Low: 0, High: 400, Count: 10},
+		},
+	)
+}
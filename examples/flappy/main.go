@@ -7,11 +7,12 @@ import (
 	"math"
 	"math/rand"
 	"strconv"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
 )
 
 const (
@@ -70,6 +71,7 @@ type Game struct {
 	titlePulse float64
 	deathTimer float64
 	groundX    float64 // For scrolling ground
+	rng        *rand.Rand
 }
 
 func NewGame() *Game {
@@ -77,6 +79,7 @@ func NewGame() *Game {
 		bird:  &Bird{X: 100, Y: float64(screenHeight) / 2},
 		pipes: make([]*Pipe, 0),
 		state: StateTitle,
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -95,19 +98,19 @@ func (g *Game) startGame() {
 func (g *Game) spawnPipe() {
 	minGap := float64(pipeGap/2 + 50)
 	maxGap := float64(screenHeight - pipeGap/2 - 100)
-	gapY := minGap + rand.Float64()*(maxGap-minGap)
+	gapY := minGap + g.rng.Float64()*(maxGap-minGap)
 	g.pipes = append(g.pipes, &Pipe{X: float64(screenWidth), GapY: gapY})
 }
 
 func (g *Game) spawnDeathParticles() {
 	for range 20 {
-		angle := rand.Float64() * math.Pi * 2
-		speed := 2 + rand.Float64()*4
+		angle := g.rng.Float64() * math.Pi * 2
+		speed := 2 + g.rng.Float64()*4
 		g.particles = append(g.particles, Particle{
 			X: g.bird.X + birdSize/2, Y: g.bird.Y + birdSize/2,
 			VX: math.Cos(angle) * speed, VY: math.Sin(angle) * speed,
-			Life: 1.0, Color: color.RGBA{R: 255, G: uint8(180 + rand.Intn(75)), B: 50, A: 255},
-			Size: 4 + rand.Float64()*4,
+			Life: 1.0, Color: color.RGBA{R: 255, G: uint8(180 + g.rng.Intn(75)), B: 50, A: 255},
+			Size: 4 + g.rng.Float64()*4,
 		})
 	}
 }
@@ -158,94 +161,101 @@ func (g *Game) Update() error {
 		}
 
 	case StatePlaying:
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) ||
-			inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-			g.bird.VelocityY = jumpForce
-		}
+		g.updatePlaying(inpututil.IsKeyJustPressed(ebiten.KeySpace) ||
+			inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft))
 
+	case StateGameOver:
+		g.deathTimer += dt
+		// Bird falls
 		g.bird.VelocityY += gravity
+
 		g.bird.Y += g.bird.VelocityY
+		if g.bird.Y > float64(screenHeight-50-birdSize) {
+			g.bird.Y = float64(screenHeight - 50 - birdSize)
+			g.bird.VelocityY = 0
+		}
 
-		g.bird.Rotation = g.bird.VelocityY * 3
-		if g.bird.Rotation > 90 {
-			g.bird.Rotation = 90
+		if g.deathTimer > 0.5 &&
+			(inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)) {
+			g.startGame()
 		}
 
-		if g.bird.Rotation < -30 {
-			g.bird.Rotation = -30
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			g.state = StateTitle
+			g.bird = &Bird{X: 100, Y: float64(screenHeight) / 2}
+			g.pipes = nil
 		}
+	}
 
-		// Ground/ceiling
-		if g.bird.Y < 0 || g.bird.Y > float64(screenHeight-50-birdSize) {
-			g.spawnDeathParticles()
+	return nil
+}
 
-			if g.score > g.highscore {
-				g.highscore = g.score
-			}
+// updatePlaying advances one frame of play, flapping if flap is set.
+func (g *Game) updatePlaying(flap bool) {
+	dt := 1.0 / 60.0
 
-			g.state = StateGameOver
+	if flap {
+		g.bird.VelocityY = jumpForce
+	}
 
-			return nil
-		}
+	g.bird.VelocityY += gravity
+	g.bird.Y += g.bird.VelocityY
 
-		// Pipes
-		g.pipeTimer += dt
-		if g.pipeTimer >= 1.5 {
-			g.spawnPipe()
-			g.pipeTimer = 0
-		}
+	g.bird.Rotation = g.bird.VelocityY * 3
+	if g.bird.Rotation > 90 {
+		g.bird.Rotation = 90
+	}
 
-		for i := len(g.pipes) - 1; i >= 0; i-- {
-			pipe := g.pipes[i]
-			pipe.X -= pipeSpeed
+	if g.bird.Rotation < -30 {
+		g.bird.Rotation = -30
+	}
 
-			if pipe.X < -pipeWidth {
-				g.pipes = append(g.pipes[:i], g.pipes[i+1:]...)
+	// Ground/ceiling
+	if g.bird.Y < 0 || g.bird.Y > float64(screenHeight-50-birdSize) {
+		g.spawnDeathParticles()
 
-				continue
-			}
+		if g.score > g.highscore {
+			g.highscore = g.score
+		}
 
-			if !pipe.Passed && pipe.X+pipeWidth < g.bird.X {
-				pipe.Passed = true
-				g.score++
-				g.addScorePopup()
-			}
+		g.state = StateGameOver
 
-			if g.checkCollision(pipe) {
-				g.spawnDeathParticles()
+		return
+	}
 
-				if g.score > g.highscore {
-					g.highscore = g.score
-				}
+	// Pipes
+	g.pipeTimer += dt
+	if g.pipeTimer >= 1.5 {
+		g.spawnPipe()
+		g.pipeTimer = 0
+	}
 
-				g.state = StateGameOver
-			}
-		}
+	for i := len(g.pipes) - 1; i >= 0; i-- {
+		pipe := g.pipes[i]
+		pipe.X -= pipeSpeed
 
-	case StateGameOver:
-		g.deathTimer += dt
-		// Bird falls
-		g.bird.VelocityY += gravity
+		if pipe.X < -pipeWidth {
+			g.pipes = append(g.pipes[:i], g.pipes[i+1:]...)
 
-		g.bird.Y += g.bird.VelocityY
-		if g.bird.Y > float64(screenHeight-50-birdSize) {
-			g.bird.Y = float64(screenHeight - 50 - birdSize)
-			g.bird.VelocityY = 0
+			continue
 		}
 
-		if g.deathTimer > 0.5 &&
-			(inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)) {
-			g.startGame()
+		if !pipe.Passed && pipe.X+pipeWidth < g.bird.X {
+			pipe.Passed = true
+			g.score++
+			g.addScorePopup()
 		}
 
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-			g.state = StateTitle
-			g.bird = &Bird{X: 100, Y: float64(screenHeight) / 2}
-			g.pipes = nil
+		if g.checkCollision(pipe) {
+			g.spawnDeathParticles()
+
+			if g.score > g.highscore {
+				g.highscore = g.score
+			}
+
+			g.state = StateGameOver
 		}
 	}
-
-	return nil
 }
 
 func (g *Game) checkCollision(pipe *Pipe) bool {
@@ -264,192 +274,177 @@ func (g *Game) checkCollision(pipe *Pipe) bool {
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	g.DrawTo(visual.NewEbitenSurface(screen))
+}
+
+// DrawTo draws the sky, pipes, bird and score onto dst.
+func (g *Game) DrawTo(dst visual.Surface) {
 	// Sky gradient
 	for y := range screenHeight - 50 {
 		t := float64(y) / float64(screenHeight-50)
 		r := uint8(100 - t*50)
 		gr := uint8(180 - t*80)
 		b := uint8(255 - t*55)
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			0,
 			float32(y),
 			float32(screenWidth),
 			1,
 			color.RGBA{R: r, G: gr, B: b, A: 255},
-			false,
 		)
 	}
 
 	// Pipes
 	for _, pipe := range g.pipes {
-		g.drawPipe(screen, pipe)
+		g.drawPipe(dst, pipe)
 	}
 
 	// Particles
 	for _, p := range g.particles {
 		alpha := uint8(p.Life * 255)
 		c := color.RGBA{R: p.Color.R, G: p.Color.G, B: p.Color.B, A: alpha}
-		vector.FillCircle(screen, float32(p.X), float32(p.Y), float32(p.Size*p.Life), c, false)
+		dst.FillCircle(float32(p.X), float32(p.Y), float32(p.Size*p.Life), c)
 	}
 
 	// Ground (scrolling)
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		0,
 		float32(screenHeight-50),
 		float32(screenWidth),
 		50,
 		color.RGBA{R: 139, G: 119, B: 101, A: 255},
-		false,
 	)
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		0,
 		float32(screenHeight-50),
 		float32(screenWidth),
 		5,
 		color.RGBA{R: 34, G: 139, B: 34, A: 255},
-		false,
 	)
 	// Grass pattern
 	for x := int(g.groundX); x < screenWidth+40; x += 20 {
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			float32(x),
 			float32(screenHeight-48),
 			2,
 			8,
 			color.RGBA{R: 50, G: 160, B: 50, A: 255},
-			false,
 		)
 	}
 
 	// Bird
-	g.drawBird(screen)
+	g.drawBird(dst)
 
 	// Popups
 	for _, pop := range g.popups {
-		ebitenutil.DebugPrintAt(screen, "+1", int(pop.X), int(pop.Y))
+		dst.Text("+1", int(pop.X), int(pop.Y))
 	}
 
 	switch g.state {
 	case StateTitle:
-		g.drawTitle(screen)
+		g.drawTitle(dst)
 	case StatePlaying:
-		g.drawHUD(screen)
+		g.drawHUD(dst)
 	case StateGameOver:
-		g.drawHUD(screen)
-		g.drawGameOver(screen)
+		g.drawHUD(dst)
+		g.drawGameOver(dst)
 	}
 }
 
-func (g *Game) drawTitle(screen *ebiten.Image) {
+func (g *Game) drawTitle(dst visual.Surface) {
 	boxW, boxH := float32(300), float32(220)
 	boxX, boxY := float32(screenWidth-300)/2, float32(screenHeight-220)/2
 
 	pulse := float32(0.7 + 0.3*math.Sin(g.titlePulse*2))
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		boxX-4,
 		boxY-4,
 		boxW+8,
 		boxH+8,
 		color.RGBA{R: 255, G: 220, B: 50, A: uint8(50 * pulse)},
-		false,
 	)
-	vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 50, G: 50, B: 70, A: 240}, false)
-	vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 3, color.RGBA{R: 255, G: 220, B: 50, A: 255}, false)
+	dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 50, G: 50, B: 70, A: 240})
+	dst.StrokeRect(boxX, boxY, boxW, boxH, 3, color.RGBA{R: 255, G: 220, B: 50, A: 255})
 
-	ebitenutil.DebugPrintAt(screen, "FLAPPY BIRD", int(boxX)+95, int(boxY)+25)
+	dst.Text("FLAPPY BIRD", int(boxX)+95, int(boxY)+25)
 
 	if g.highscore > 0 {
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Best: %d", g.highscore), int(boxX)+120, int(boxY)+60)
+		dst.Text(fmt.Sprintf("Best: %d", g.highscore), int(boxX)+120, int(boxY)+60)
 	}
 
 	if int(g.titlePulse*2)%2 == 0 {
-		ebitenutil.DebugPrintAt(screen, "Tap or SPACE to Start", int(boxX)+70, int(boxY)+100)
+		dst.Text("Tap or SPACE to Start", int(boxX)+70, int(boxY)+100)
 	}
 
-	ebitenutil.DebugPrintAt(screen, "Tap/Click or SPACE to flap", int(boxX)+50, int(boxY)+150)
-	ebitenutil.DebugPrintAt(screen, "Avoid the pipes!", int(boxX)+90, int(boxY)+180)
+	dst.Text("Tap/Click or SPACE to flap", int(boxX)+50, int(boxY)+150)
+	dst.Text("Avoid the pipes!", int(boxX)+90, int(boxY)+180)
 }
 
-func (g *Game) drawHUD(screen *ebiten.Image) {
+func (g *Game) drawHUD(dst visual.Surface) {
 	// Score with shadow
 	scoreText := strconv.Itoa(g.score)
-	ebitenutil.DebugPrintAt(screen, scoreText, screenWidth/2-len(scoreText)*3+1, 31)
-	ebitenutil.DebugPrintAt(screen, scoreText, screenWidth/2-len(scoreText)*3, 30)
+	dst.Text(scoreText, screenWidth/2-len(scoreText)*3+1, 31)
+	dst.Text(scoreText, screenWidth/2-len(scoreText)*3, 30)
 }
 
-func (g *Game) drawGameOver(screen *ebiten.Image) {
+func (g *Game) drawGameOver(dst visual.Surface) {
 	alpha := uint8(min(int(g.deathTimer*300), 180))
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		0,
 		0,
 		screenWidth,
 		screenHeight,
 		color.RGBA{R: 0, G: 0, B: 0, A: alpha},
-		false,
 	)
 
 	if g.deathTimer > 0.3 {
 		boxW, boxH := float32(280), float32(160)
 		boxX, boxY := float32(screenWidth-280)/2, float32(screenHeight-160)/2
-		vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 40, G: 40, B: 60, A: 250}, false)
-		vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 3, color.RGBA{R: 255, G: 80, B: 80, A: 255}, false)
+		dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 40, G: 40, B: 60, A: 250})
+		dst.StrokeRect(boxX, boxY, boxW, boxH, 3, color.RGBA{R: 255, G: 80, B: 80, A: 255})
 
-		ebitenutil.DebugPrintAt(screen, "GAME OVER", int(boxX)+95, int(boxY)+25)
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Score: %d", g.score), int(boxX)+105, int(boxY)+55)
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Best: %d", g.highscore), int(boxX)+110, int(boxY)+80)
+		dst.Text("GAME OVER", int(boxX)+95, int(boxY)+25)
+		dst.Text(fmt.Sprintf("Score: %d", g.score), int(boxX)+105, int(boxY)+55)
+		dst.Text(fmt.Sprintf("Best: %d", g.highscore), int(boxX)+110, int(boxY)+80)
 
 		if g.score == g.highscore && g.score > 0 {
-			ebitenutil.DebugPrintAt(screen, "NEW BEST!", int(boxX)+100, int(boxY)+105)
+			dst.Text("NEW BEST!", int(boxX)+100, int(boxY)+105)
 		}
 
 		if g.deathTimer > 0.5 {
-			ebitenutil.DebugPrintAt(screen, "Tap: Retry  ESC: Menu", int(boxX)+60, int(boxY)+135)
+			dst.Text("Tap: Retry  ESC: Menu", int(boxX)+60, int(boxY)+135)
 		}
 	}
 }
 
-func (g *Game) drawBird(screen *ebiten.Image) {
+func (g *Game) drawBird(dst visual.Surface) {
 	// Body
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		float32(g.bird.X+birdSize/2),
 		float32(g.bird.Y+birdSize/2),
 		birdSize/2,
 		color.RGBA{R: 255, G: 220, B: 50, A: 255},
-		false,
 	)
 	// Eye
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		float32(g.bird.X+birdSize*0.7),
 		float32(g.bird.Y+birdSize*0.3),
 		5,
 		color.RGBA{R: 255, G: 255, B: 255, A: 255},
-		false,
 	)
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		float32(g.bird.X+birdSize*0.75),
 		float32(g.bird.Y+birdSize*0.35),
 		2,
 		color.RGBA{R: 0, G: 0, B: 0, A: 255},
-		false,
 	)
 	// Beak
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(g.bird.X+birdSize*0.8),
 		float32(g.bird.Y+birdSize*0.45),
 		10,
 		6,
 		color.RGBA{R: 255, G: 150, B: 0, A: 255},
-		false,
 	)
 	// Wing
 	wingY := g.bird.Y + birdSize*0.5
@@ -457,17 +452,15 @@ func (g *Game) drawBird(screen *ebiten.Image) {
 		wingY -= 5
 	}
 
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		float32(g.bird.X+birdSize*0.3),
 		float32(wingY),
 		8,
 		color.RGBA{R: 255, G: 180, B: 50, A: 255},
-		false,
 	)
 }
 
-func (g *Game) drawPipe(screen *ebiten.Image, pipe *Pipe) {
+func (g *Game) drawPipe(dst visual.Surface, pipe *Pipe) {
 	pipeColor := color.RGBA{R: 50, G: 180, B: 50, A: 255}
 	pipeEdge := color.RGBA{R: 30, G: 140, B: 30, A: 255}
 	pipeHighlight := color.RGBA{R: 80, G: 220, B: 80, A: 255}
@@ -476,45 +469,37 @@ func (g *Game) drawPipe(screen *ebiten.Image, pipe *Pipe) {
 	gapBottom := pipe.GapY + pipeGap/2
 
 	// Top pipe
-	vector.FillRect(screen, float32(pipe.X), 0, float32(pipeWidth), float32(gapTop), pipeColor, false)
-	vector.FillRect(screen, float32(pipe.X), 0, 5, float32(gapTop), pipeHighlight, false)
-	vector.FillRect(
-		screen,
+	dst.FillRect(float32(pipe.X), 0, float32(pipeWidth), float32(gapTop), pipeColor)
+	dst.FillRect(float32(pipe.X), 0, 5, float32(gapTop), pipeHighlight)
+	dst.FillRect(
 		float32(pipe.X-5),
 		float32(gapTop-30),
 		float32(pipeWidth+10),
 		30,
 		pipeEdge,
-		false,
 	)
 
 	// Bottom pipe
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(pipe.X),
 		float32(gapBottom),
 		float32(pipeWidth),
 		float32(screenHeight-50)-float32(gapBottom),
 		pipeColor,
-		false,
 	)
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(pipe.X),
 		float32(gapBottom),
 		5,
 		float32(screenHeight-50)-float32(gapBottom),
 		pipeHighlight,
-		false,
 	)
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		float32(pipe.X-5),
 		float32(gapBottom),
 		float32(pipeWidth+10),
 		30,
 		pipeEdge,
-		false,
 	)
 }
 
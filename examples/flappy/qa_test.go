@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
+)
+
+// TestTrainedAgent trains a Q-learner and checks it passes pipes that
+// random play never reaches.
+func TestTrainedAgent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping training in short mode")
+	}
+
+	env := ai.NewEnv(NewFlappyAdapter(NewGame()), ai.EnvConfig{
+		MaxSteps: 3000,
+		Shaping:  []ai.RewardFunc{ai.SurvivalBonus(0.1), ai.TerminalPenalty(10)},
+	})
+
+	// Random flaps crash the bird, so learn greedily from the shaped rewards
+	config := ai.DefaultQConfig()
+	config.Alpha = 0.5
+	config.Gamma = 0.9
+	config.Epsilon = 0
+	config.EpsilonMin = 0
+
+	learner := ai.NewQLearner(FlappyDiscretizer(), config)
+
+	stats, err := learner.Train(env, 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("Training: last 50 episodes avg score %.1f", stats.MeanScore(50))
+
+	run := func(player ai.Player) ai.QAReport {
+		adapter := NewFlappyAdapter(NewGame())
+		adapter.Seed(1)
+
+		session := ai.NewQASession(adapter)
+		session.SetPlayer(player)
+		session.SetConfig(ai.SessionConfig{Runs: 5, MaxTicks: 3000, RecordEvery: 10})
+
+		return session.Run()
+	}
+
+	trained := run(learner)
+	random := run(ai.NewRandomPlayer(1))
+
+	t.Logf("Trained avg %d, best %d; random avg %d", trained.AvgScore, trained.BestScore, random.AvgScore)
+
+	if trained.BestScore < 5 || trained.AvgScore <= random.AvgScore {
+		t.Errorf("Trained agent should pass pipes, got best %d", trained.BestScore)
+	}
+}
+
+// TestVisualRegression checks frames of a seeded flight.
+func TestVisualRegression(t *testing.T) {
+	game := NewGame()
+	adapter := NewFlappyAdapter(game)
+	adapter.Seed(1)
+
+	h := visual.NewHarness("testdata/golden", screenWidth, screenHeight)
+
+	session := ai.NewQASession(adapter)
+	session.SetPlayer(ai.NewRandomPlayer(1))
+	session.SetConfig(ai.SessionConfig{Runs: 1, MaxTicks: 40, RecordEvery: 1})
+	h.CheckSession(t, session, "flappy", game, 0, 15, 30)
+}
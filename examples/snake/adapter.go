@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+)
+
+// SnakeAdapter implements ai.GameAdapter. Each Step moves the snake one
+// cell, so agents decide once per move rather than once per frame.
+type SnakeAdapter struct {
+	game *Snake
+	tick int64
+}
+
+// NewSnakeAdapter creates an adapter for the given game.
+func NewSnakeAdapter(game *Snake) *SnakeAdapter {
+	return &SnakeAdapter{game: game}
+}
+
+// Name returns the game's identifier.
+func (a *SnakeAdapter) Name() string {
+	return "Snake"
+}
+
+// Seed makes food placement reproducible.
+func (a *SnakeAdapter) Seed(seed int64) {
+	a.game.rng = rand.New(rand.NewSource(seed))
+}
+
+// GetState returns the current game state. CustomData holds the features
+// the learners use: food direction and whether each neighbour cell is body.
+func (a *SnakeAdapter) GetState() ai.GameState {
+	g := a.game
+	state := ai.GameState{
+		Tick:       a.tick,
+		Score:      g.score,
+		CustomData: map[string]any{"length": len(g.body)},
+	}
+
+	if len(g.body) == 0 {
+		return state
+	}
+
+	head := g.body[0]
+	state.PlayerPos = [2]float64{float64(head.X * gridSize), float64(head.Y * gridSize)}
+	state.EntityCount = len(g.body) + 1
+
+	state.CustomData["food_dx"] = sign(wrapDelta(g.food.X-head.X, gridWidth))
+	state.CustomData["food_dy"] = sign(wrapDelta(g.food.Y-head.Y, gridHeight))
+	state.CustomData["danger_up"] = a.occupied(g.nextHead(DirUp))
+	state.CustomData["danger_down"] = a.occupied(g.nextHead(DirDown))
+	state.CustomData["danger_left"] = a.occupied(g.nextHead(DirLeft))
+	state.CustomData["danger_right"] = a.occupied(g.nextHead(DirRight))
+
+	return state
+}
+
+// IsGameOver returns true if the snake hit itself.
+func (a *SnakeAdapter) IsGameOver() bool {
+	return a.game.state == StateGameOver
+}
+
+// GetScore returns the current score.
+func (a *SnakeAdapter) GetScore() int {
+	return a.game.score
+}
+
+// AvailableActions returns the four turns.
+func (a *SnakeAdapter) AvailableActions() []ai.ActionType {
+	if a.game.state != StatePlaying {
+		return []ai.ActionType{ai.ActionNone}
+	}
+
+	return []ai.ActionType{ai.ActionMoveUp, ai.ActionMoveDown, ai.ActionMoveLeft, ai.ActionMoveRight}
+}
+
+// PerformAction queues a turn.
+func (a *SnakeAdapter) PerformAction(action ai.ActionType) error {
+	switch action {
+	case ai.ActionMoveUp:
+		a.game.turn(DirUp)
+	case ai.ActionMoveDown:
+		a.game.turn(DirDown)
+	case ai.ActionMoveLeft:
+		a.game.turn(DirLeft)
+	case ai.ActionMoveRight:
+		a.game.turn(DirRight)
+	}
+
+	return nil
+}
+
+// Step moves the snake one cell.
+func (a *SnakeAdapter) Step() error {
+	a.tick++
+
+	if a.game.state == StatePlaying {
+		a.game.advance()
+	}
+
+	return nil
+}
+
+// Reset starts a new game.
+func (a *SnakeAdapter) Reset() error {
+	a.tick = 0
+	a.game.startGame()
+
+	return nil
+}
+
+// occupied reports whether moving into p would hit the body.
+func (a *SnakeAdapter) occupied(p Point) bool {
+	for _, b := range a.game.body {
+		if b == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SnakeDiscretizer keys states by food direction and nearby danger, for
+// tabular learners.
+func SnakeDiscretizer() ai.Discretizer {
+	sign := ai.Bin{Low: -1.5, High: 1.5, Count: 3}
+	flag := ai.Bin{Low: 0, High: 1.5, Count: 2}
+
+	return ai.NewBinDiscretizer(
+		[]string{"food_dx", "food_dy", "danger_up", "danger_down", "danger_left", "danger_right"},
+		[]ai.Bin{sign, sign, flag, flag, flag, flag},
+	)
+}
+
+// wrapDelta returns the shortest signed distance on a wrapping axis.
+func wrapDelta(d, size int) int {
+	if d > size/2 {
+		return d - size
+	}
+
+	if d < -size/2 {
+		return d + size
+	}
+
+	return d
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+}
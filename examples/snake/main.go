@@ -6,11 +6,12 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
 )
 
 const (
@@ -69,6 +70,7 @@ type Snake struct {
 	foodPulse  float64 // For food animation
 	titlePulse float64 // For title animation
 	deathTimer float64 // For death animation
+	rng        *rand.Rand
 }
 
 // NewSnake creates a new snake game.
@@ -76,6 +78,7 @@ func NewSnake() *Snake {
 	return &Snake{
 		state:     StateTitle,
 		moveDelay: 0.1,
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -99,8 +102,8 @@ func (s *Snake) startGame() {
 func (s *Snake) spawnFood() {
 	for {
 		s.food = Point{
-			X: rand.Intn(gridWidth),
-			Y: rand.Intn(gridHeight),
+			X: s.rng.Intn(gridWidth),
+			Y: s.rng.Intn(gridHeight),
 		}
 		onSnake := false
 
@@ -124,14 +127,14 @@ func (s *Snake) spawnFoodParticles() {
 
 	for i := range 12 {
 		angle := float64(i) * math.Pi * 2 / 12
-		speed := 2.0 + rand.Float64()*2
+		speed := 2.0 + s.rng.Float64()*2
 		s.particles = append(s.particles, Particle{
 			X: fx, Y: fy,
 			VX:    math.Cos(angle) * speed,
 			VY:    math.Sin(angle) * speed,
 			Life:  1.0,
-			Color: color.RGBA{R: 255, G: uint8(100 + rand.Intn(100)), B: 50, A: 255},
-			Size:  4 + rand.Float64()*3,
+			Color: color.RGBA{R: 255, G: uint8(100 + s.rng.Intn(100)), B: 50, A: 255},
+			Size:  4 + s.rng.Float64()*3,
 		})
 	}
 }
@@ -142,20 +145,20 @@ func (s *Snake) spawnDeathParticles() {
 		py := float64(p.Y*gridSize + gridSize/2)
 
 		for range 4 {
-			angle := rand.Float64() * math.Pi * 2
-			speed := 1.0 + rand.Float64()*3
+			angle := s.rng.Float64() * math.Pi * 2
+			speed := 1.0 + s.rng.Float64()*3
 			s.particles = append(s.particles, Particle{
 				X: px, Y: py,
 				VX:   math.Cos(angle) * speed,
 				VY:   math.Sin(angle) * speed,
 				Life: 1.0,
 				Color: color.RGBA{
-					R: uint8(50 + rand.Intn(50)),
-					G: uint8(150 + rand.Intn(100)),
-					B: uint8(50 + rand.Intn(50)),
+					R: uint8(50 + s.rng.Intn(50)),
+					G: uint8(150 + s.rng.Intn(100)),
+					B: uint8(50 + s.rng.Intn(50)),
 					A: 255,
 				},
-				Size: 3 + rand.Float64()*4,
+				Size: 3 + s.rng.Float64()*4,
 			})
 		}
 	}
@@ -188,35 +191,26 @@ func (s *Snake) Update() error {
 	case StatePlaying:
 		// Handle input
 		if inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
-			if s.direction != DirDown {
-				s.nextDir = DirUp
-			}
+			s.turn(DirUp)
 		}
 
 		if inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
-			if s.direction != DirUp {
-				s.nextDir = DirDown
-			}
+			s.turn(DirDown)
 		}
 
 		if inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA) {
-			if s.direction != DirRight {
-				s.nextDir = DirLeft
-			}
+			s.turn(DirLeft)
 		}
 
 		if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
-			if s.direction != DirLeft {
-				s.nextDir = DirRight
-			}
+			s.turn(DirRight)
 		}
 
 		// Update movement timer
 		s.moveTimer += dt
 		if s.moveTimer >= s.moveDelay {
 			s.moveTimer = 0
-			s.direction = s.nextDir
-			s.move()
+			s.advance()
 		}
 
 	case StateGameOver:
@@ -242,12 +236,32 @@ func (s *Snake) Update() error {
 	return nil
 }
 
-func (s *Snake) move() {
+// turn queues a direction change. Reversing onto the body is ignored.
+func (s *Snake) turn(dir Direction) {
+	switch {
+	case dir == DirUp && s.direction == DirDown,
+		dir == DirDown && s.direction == DirUp,
+		dir == DirLeft && s.direction == DirRight,
+		dir == DirRight && s.direction == DirLeft:
+		return
+	}
+
+	s.nextDir = dir
+}
+
+// advance moves the snake one cell in the queued direction.
+func (s *Snake) advance() {
+	s.direction = s.nextDir
+	s.move()
+}
+
+// nextHead returns the cell the head would enter moving in dir.
+func (s *Snake) nextHead(dir Direction) Point {
 	head := s.body[0]
 
 	var newHead Point
 
-	switch s.direction {
+	switch dir {
 	case DirUp:
 		newHead = Point{X: head.X, Y: head.Y - 1}
 	case DirDown:
@@ -275,6 +289,12 @@ func (s *Snake) move() {
 		newHead.Y = 0
 	}
 
+	return newHead
+}
+
+func (s *Snake) move() {
+	newHead := s.nextHead(s.direction)
+
 	// Check self collision
 	for _, p := range s.body {
 		if p.X == newHead.X && p.Y == newHead.Y {
@@ -303,34 +323,35 @@ func (s *Snake) move() {
 }
 
 func (s *Snake) Draw(screen *ebiten.Image) {
+	s.DrawTo(visual.NewEbitenSurface(screen))
+}
+
+// DrawTo draws the board, snake and HUD onto dst.
+func (s *Snake) DrawTo(dst visual.Surface) {
 	// Background
-	screen.Fill(color.RGBA{R: 12, G: 18, B: 30, A: 255})
+	dst.Fill(color.RGBA{R: 12, G: 18, B: 30, A: 255})
 
 	// Draw grid lines (subtle)
 	gridColor := color.RGBA{R: 22, G: 28, B: 42, A: 255}
 	for x := 0; x <= gridWidth; x++ {
-		vector.StrokeLine(
-			screen,
+		dst.StrokeLine(
 			float32(x*gridSize),
 			0,
 			float32(x*gridSize),
 			screenHeight,
 			1,
 			gridColor,
-			false,
 		)
 	}
 
 	for y := 0; y <= gridHeight; y++ {
-		vector.StrokeLine(
-			screen,
+		dst.StrokeLine(
 			0,
 			float32(y*gridSize),
 			screenWidth,
 			float32(y*gridSize),
 			1,
 			gridColor,
-			false,
 		)
 	}
 
@@ -338,34 +359,32 @@ func (s *Snake) Draw(screen *ebiten.Image) {
 	for _, p := range s.particles {
 		alpha := uint8(p.Life * 255)
 		c := color.RGBA{R: p.Color.R, G: p.Color.G, B: p.Color.B, A: alpha}
-		vector.FillCircle(screen, float32(p.X), float32(p.Y), float32(p.Size*p.Life), c, false)
+		dst.FillCircle(float32(p.X), float32(p.Y), float32(p.Size*p.Life), c)
 	}
 
 	switch s.state {
 	case StateTitle:
-		s.drawTitle(screen)
+		s.drawTitle(dst)
 	case StatePlaying:
-		s.drawGame(screen)
+		s.drawGame(dst)
 	case StateGameOver:
-		s.drawGame(screen)
-		s.drawGameOver(screen)
+		s.drawGame(dst)
+		s.drawGameOver(dst)
 	}
 }
 
-func (s *Snake) drawTitle(screen *ebiten.Image) {
+func (s *Snake) drawTitle(dst visual.Surface) {
 	// Animated background snake
 	for i := range 20 {
 		x := float32(50 + i*30)
 		y := float32(200 + math.Sin(s.titlePulse+float64(i)*0.3)*30)
 		radius := float32(12 - i/3)
 		alpha := uint8(200 - i*8)
-		vector.FillCircle(
-			screen,
+		dst.FillCircle(
 			x,
 			y,
 			radius,
 			color.RGBA{R: 50, G: uint8(180 - i*5), B: 50, A: alpha},
-			false,
 		)
 	}
 
@@ -375,25 +394,22 @@ func (s *Snake) drawTitle(screen *ebiten.Image) {
 
 	// Glow effect
 	glowPulse := float32(0.7 + 0.3*math.Sin(s.titlePulse*2))
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		boxX-5,
 		boxY-5,
 		boxW+10,
 		boxH+10,
 		color.RGBA{R: 50, G: 200, B: 50, A: uint8(40 * glowPulse)},
-		false,
 	)
-	vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 20, G: 30, B: 45, A: 240}, false)
-	vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 3, color.RGBA{R: 80, G: 220, B: 80, A: 255}, false)
+	dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 20, G: 30, B: 45, A: 240})
+	dst.StrokeRect(boxX, boxY, boxW, boxH, 3, color.RGBA{R: 80, G: 220, B: 80, A: 255})
 
 	// Title
-	ebitenutil.DebugPrintAt(screen, "S N A K E", int(boxX)+130, int(boxY)+30)
+	dst.Text("S N A K E", int(boxX)+130, int(boxY)+30)
 
 	// High score
 	if s.highscore > 0 {
-		ebitenutil.DebugPrintAt(
-			screen,
+		dst.Text(
 			fmt.Sprintf("High Score: %d", s.highscore),
 			int(boxX)+115,
 			int(boxY)+70,
@@ -402,34 +418,30 @@ func (s *Snake) drawTitle(screen *ebiten.Image) {
 
 	// Pulsing start prompt
 	if int(s.titlePulse*2)%2 == 0 {
-		ebitenutil.DebugPrintAt(screen, "Press SPACE to Start", int(boxX)+95, int(boxY)+110)
+		dst.Text("Press SPACE to Start", int(boxX)+95, int(boxY)+110)
 	}
 
 	// Controls
-	ebitenutil.DebugPrintAt(screen, "Controls: WASD or Arrow Keys", int(boxX)+70, int(boxY)+150)
-	ebitenutil.DebugPrintAt(screen, "Eat food, grow longer, don't crash!", int(boxX)+50, int(boxY)+170)
+	dst.Text("Controls: WASD or Arrow Keys", int(boxX)+70, int(boxY)+150)
+	dst.Text("Eat food, grow longer, don't crash!", int(boxX)+50, int(boxY)+170)
 }
 
-func (s *Snake) drawGame(screen *ebiten.Image) {
+func (s *Snake) drawGame(dst visual.Surface) {
 	// Draw food with pulsing glow
 	foodX := float32(s.food.X*gridSize + gridSize/2)
 	foodY := float32(s.food.Y*gridSize + gridSize/2)
 	pulseSize := float32(2 + 2*math.Sin(s.foodPulse))
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		foodX,
 		foodY,
 		float32(gridSize/2)+pulseSize,
 		color.RGBA{R: 255, G: 100, B: 100, A: 50},
-		false,
 	)
-	vector.FillCircle(
-		screen,
+	dst.FillCircle(
 		foodX,
 		foodY,
 		float32(gridSize/2-2),
 		color.RGBA{R: 255, G: 60, B: 60, A: 255},
-		false,
 	)
 
 	// Draw snake
@@ -440,43 +452,41 @@ func (s *Snake) drawGame(screen *ebiten.Image) {
 
 		if i == 0 {
 			// Head with glow
-			vector.FillCircle(screen, x, y, radius+3, color.RGBA{R: 100, G: 255, B: 100, A: 60}, false)
-			vector.FillCircle(screen, x, y, radius, color.RGBA{R: 80, G: 220, B: 80, A: 255}, false)
+			dst.FillCircle(x, y, radius+3, color.RGBA{R: 100, G: 255, B: 100, A: 60})
+			dst.FillCircle(x, y, radius, color.RGBA{R: 80, G: 220, B: 80, A: 255})
 			// Eyes
 			ex1, ey1 := x-4, y-2
 			ex2, ey2 := x+4, y-2
 
-			vector.FillCircle(screen, ex1, ey1, 2, color.RGBA{R: 255, G: 255, B: 255, A: 255}, false)
-			vector.FillCircle(screen, ex2, ey2, 2, color.RGBA{R: 255, G: 255, B: 255, A: 255}, false)
+			dst.FillCircle(ex1, ey1, 2, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			dst.FillCircle(ex2, ey2, 2, color.RGBA{R: 255, G: 255, B: 255, A: 255})
 		} else {
 			// Body gradient
 			t := float64(i) / float64(len(s.body))
 			g := uint8(200 - t*80)
-			vector.FillCircle(screen, x, y, radius, color.RGBA{R: 40, G: g, B: 40, A: 255}, false)
+			dst.FillCircle(x, y, radius, color.RGBA{R: 40, G: g, B: 40, A: 255})
 		}
 	}
 
 	// Score panel
-	vector.FillRect(screen, 5, 5, 140, 35, color.RGBA{R: 0, G: 0, B: 0, A: 180}, false)
-	vector.StrokeRect(screen, 5, 5, 140, 35, 2, color.RGBA{R: 80, G: 220, B: 80, A: 200}, false)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("SCORE: %d", s.score), 15, 8)
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("HIGH:  %d", s.highscore), 15, 22)
+	dst.FillRect(5, 5, 140, 35, color.RGBA{R: 0, G: 0, B: 0, A: 180})
+	dst.StrokeRect(5, 5, 140, 35, 2, color.RGBA{R: 80, G: 220, B: 80, A: 200})
+	dst.Text(fmt.Sprintf("SCORE: %d", s.score), 15, 8)
+	dst.Text(fmt.Sprintf("HIGH:  %d", s.highscore), 15, 22)
 
 	// Length indicator
-	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Length: %d", len(s.body)), screenWidth-90, 10)
+	dst.Text(fmt.Sprintf("Length: %d", len(s.body)), screenWidth-90, 10)
 }
 
-func (s *Snake) drawGameOver(screen *ebiten.Image) {
+func (s *Snake) drawGameOver(dst visual.Surface) {
 	// Fade in effect
 	alpha := uint8(min(int(s.deathTimer*400), 180))
-	vector.FillRect(
-		screen,
+	dst.FillRect(
 		0,
 		0,
 		screenWidth,
 		screenHeight,
 		color.RGBA{R: 0, G: 0, B: 0, A: alpha},
-		false,
 	)
 
 	if s.deathTimer > 0.2 {
@@ -484,33 +494,30 @@ func (s *Snake) drawGameOver(screen *ebiten.Image) {
 		boxX, boxY := float32(screenWidth-280)/2, float32(screenHeight-150)/2
 
 		// Box with red glow
-		vector.FillRect(
-			screen,
+		dst.FillRect(
 			boxX-3,
 			boxY-3,
 			boxW+6,
 			boxH+6,
 			color.RGBA{R: 255, G: 50, B: 50, A: 80},
-			false,
 		)
-		vector.FillRect(screen, boxX, boxY, boxW, boxH, color.RGBA{R: 25, G: 25, B: 40, A: 250}, false)
-		vector.StrokeRect(screen, boxX, boxY, boxW, boxH, 2, color.RGBA{R: 255, G: 80, B: 80, A: 255}, false)
+		dst.FillRect(boxX, boxY, boxW, boxH, color.RGBA{R: 25, G: 25, B: 40, A: 250})
+		dst.StrokeRect(boxX, boxY, boxW, boxH, 2, color.RGBA{R: 255, G: 80, B: 80, A: 255})
 
-		ebitenutil.DebugPrintAt(screen, "GAME OVER", int(boxX)+95, int(boxY)+20)
-		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Final Score: %d", s.score), int(boxX)+85, int(boxY)+50)
-		ebitenutil.DebugPrintAt(
-			screen,
+		dst.Text("GAME OVER", int(boxX)+95, int(boxY)+20)
+		dst.Text(fmt.Sprintf("Final Score: %d", s.score), int(boxX)+85, int(boxY)+50)
+		dst.Text(
 			fmt.Sprintf("Snake Length: %d", len(s.body)),
 			int(boxX)+80,
 			int(boxY)+70,
 		)
 
 		if s.score > s.highscore {
-			ebitenutil.DebugPrintAt(screen, "NEW HIGH SCORE!", int(boxX)+85, int(boxY)+95)
+			dst.Text("NEW HIGH SCORE!", int(boxX)+85, int(boxY)+95)
 		}
 
 		if s.deathTimer > 0.5 {
-			ebitenutil.DebugPrintAt(screen, "SPACE: Retry  ESC: Menu", int(boxX)+55, int(boxY)+120)
+			dst.Text("SPACE: Retry  ESC: Menu", int(boxX)+55, int(boxY)+120)
 		}
 	}
 }
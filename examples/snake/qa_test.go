@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skyrocket-qy/NeuralWay/engine/ai"
+	"github.com/skyrocket-qy/NeuralWay/engine/visual"
+)
+
+// TestTrainedAgent trains a Q-learner and checks it plays far better than
+// random in a QA session.
+func TestTrainedAgent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping training in short mode")
+	}
+
+	env := ai.NewEnv(NewSnakeAdapter(NewSnake()), ai.EnvConfig{
+		MaxSteps: 500,
+		Shaping:  []ai.RewardFunc{ai.TerminalPenalty(100)},
+	})
+
+	config := ai.DefaultQConfig()
+	config.EpsilonDecay = 0.98
+
+	learner := ai.NewQLearner(SnakeDiscretizer(), config)
+
+	stats, err := learner.Train(env, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("Training: last 20 episodes avg score %.0f", stats.MeanScore(20))
+
+	run := func(player ai.Player) ai.QAReport {
+		adapter := NewSnakeAdapter(NewSnake())
+		adapter.Seed(1)
+
+		session := ai.NewQASession(adapter)
+		session.SetPlayer(player)
+		session.SetConfig(ai.SessionConfig{Runs: 5, MaxTicks: 1000, RecordEvery: 10})
+
+		return session.Run()
+	}
+
+	trained := run(learner)
+	random := run(ai.NewRandomPlayer(1))
+
+	t.Logf("Trained avg %d, best %d; random avg %d", trained.AvgScore, trained.BestScore, random.AvgScore)
+
+	if trained.AvgScore < 3*max(random.AvgScore, 10) {
+		t.Errorf("Trained agent should beat random play, got %d vs %d", trained.AvgScore, random.AvgScore)
+	}
+}
+
+// TestVisualRegression checks frames of a seeded random run.
+func TestVisualRegression(t *testing.T) {
+	game := NewSnake()
+	adapter := NewSnakeAdapter(game)
+	adapter.Seed(1)
+
+	h := visual.NewHarness("testdata/golden", screenWidth, screenHeight)
+
+	session := ai.NewQASession(adapter)
+	session.SetPlayer(ai.NewRandomPlayer(1))
+	session.SetConfig(ai.SessionConfig{Runs: 1, MaxTicks: 40, RecordEvery: 1})
+	h.CheckSession(t, session, "snake", game, 0, 15, 30)
+}